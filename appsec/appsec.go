@@ -14,14 +14,20 @@ package appsec
 import (
 	"context"
 
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo/instrumentation/httpsec"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/samplernames"
 )
 
 // MonitorParsedHTTPBody runs the security monitoring rules on the given *parsed*
 // HTTP request body. The given context must be the HTTP request context as returned
 // by the Context() method of an HTTP request. Calls to this function are ignored if
-// AppSec is disabled or the given context is incorrect.
+// AppSec is disabled or the given context is incorrect. They are also ignored when
+// the request body was already monitored by the HTTP integration, which
+// automatically parses JSON and URL-encoded form bodies of known length up to 64KB.
 // Note that passing the raw bytes of the HTTP request body is not expected and would
 // result in inaccurate attack detection.
 func MonitorParsedHTTPBody(ctx context.Context, body interface{}) {
@@ -30,3 +36,92 @@ func MonitorParsedHTTPBody(ctx context.Context, body interface{}) {
 	}
 	// bonus: use sync.Once to log a debug message once if AppSec is disabled
 }
+
+// SetUser wraps tracer.SetUser() and extends it with user blocking: it sets the
+// user information on the service entry span of the span held by ctx and, when
+// AppSec is enabled, monitors the user ID so that the requests of blocked users
+// can be blocked. A non-nil error is returned when the request is blocked, in
+// which case the request handler should immediately return without writing the
+// response, which is then written by the tracing middleware of the request.
+// The given context must be the HTTP request context as returned by the
+// Context() method of an HTTP request.
+func SetUser(ctx context.Context, id string, opts ...tracer.UserMonitoringOption) error {
+	s, ok := tracer.SpanFromContext(ctx)
+	if !ok {
+		log.Debug("appsec: could not find a span in the given context to set the user information")
+		return nil
+	}
+	tracer.SetUser(s, id, opts...)
+	if !appsec.Enabled() {
+		return nil
+	}
+	return httpsec.MonitorUser(ctx, id)
+}
+
+// TrackUserLoginSuccessEvent sets a successful user login event, with the given
+// user ID and optional metadata, as service entry span tags. It also calls
+// SetUser() to set the currently authenticated user, along with the given
+// tracer.UserMonitoringOption options, and therefore returns a non-nil error
+// when the user is blocked. The trace is kept so that the event is reported.
+func TrackUserLoginSuccessEvent(ctx context.Context, uid string, md map[string]string, opts ...tracer.UserMonitoringOption) error {
+	const tagPrefix = "appsec.events.users.login.success."
+	if !trackEvent(ctx, tagPrefix, md) {
+		return nil
+	}
+	return SetUser(ctx, uid, opts...)
+}
+
+// TrackUserLoginFailureEvent sets a failed user login event, with the given
+// user ID and optional metadata, as service entry span tags. The exists argument
+// tells whether the user ID exists in the application. The trace is kept so
+// that the event is reported.
+func TrackUserLoginFailureEvent(ctx context.Context, uid string, exists bool, md map[string]string) {
+	const tagPrefix = "appsec.events.users.login.failure."
+	if !trackEvent(ctx, tagPrefix, md) {
+		return
+	}
+	root := rootSpan(ctx)
+	root.SetTag(tagPrefix+"usr.id", uid)
+	root.SetTag(tagPrefix+"usr.exists", exists)
+}
+
+// TrackCustomEvent sets a custom event, with the given name and optional
+// metadata, as service entry span tags. The trace is kept so that the event is
+// reported.
+func TrackCustomEvent(ctx context.Context, name string, md map[string]string) {
+	if name == "" {
+		log.Error("appsec: ignoring custom event with an empty name")
+		return
+	}
+	trackEvent(ctx, "appsec.events."+name+".", md)
+}
+
+// trackEvent sets the tags of the event with the given tag prefix and metadata
+// on the service entry span of the span held by ctx, and keeps its trace. It
+// returns false when ctx holds no span.
+func trackEvent(ctx context.Context, tagPrefix string, md map[string]string) bool {
+	root := rootSpan(ctx)
+	if root == nil {
+		log.Error("appsec: could not find a span in the given context to track the event: the context is not the expected request context")
+		return false
+	}
+	root.SetTag(tagPrefix+"track", true)
+	for k, v := range md {
+		root.SetTag(tagPrefix+k, v)
+	}
+	root.SetTag(ext.ManualKeep, samplernames.AppSec)
+	return true
+}
+
+// rootSpan returns the service entry span of the span held by ctx, or nil if
+// there is none.
+func rootSpan(ctx context.Context) tracer.Span {
+	s, ok := tracer.SpanFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	if r, ok := s.(interface{ Root() tracer.Span }); ok {
+		return r.Root()
+	}
+	return s
+}
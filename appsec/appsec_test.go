@@ -0,0 +1,79 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022 Datadog, Inc.
+
+package appsec_test
+
+import (
+	"context"
+	"testing"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/appsec"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrackUserLoginSuccessEvent(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	root, ctx := tracer.StartSpanFromContext(context.Background(), "root")
+	child, ctx := tracer.StartSpanFromContext(ctx, "child")
+	err := appsec.TrackUserLoginSuccessEvent(ctx, "user id", map[string]string{"region": "us"}, tracer.WithUserName("username"))
+	require.NoError(t, err)
+	child.Finish()
+	root.Finish()
+
+	finished := mt.FinishedSpans()
+	require.Len(t, finished, 2)
+	require.Nil(t, finished[0].Tag("appsec.events.users.login.success.track"))
+	span := finished[1]
+	require.Equal(t, true, span.Tag("appsec.events.users.login.success.track"))
+	require.Equal(t, "us", span.Tag("appsec.events.users.login.success.region"))
+	require.Equal(t, "user id", span.Tag("usr.id"))
+	require.Equal(t, "username", span.Tag("usr.name"))
+}
+
+func TestTrackUserLoginFailureEvent(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	span, ctx := tracer.StartSpanFromContext(context.Background(), "root")
+	appsec.TrackUserLoginFailureEvent(ctx, "user id", false, map[string]string{"region": "us"})
+	span.Finish()
+
+	finished := mt.FinishedSpans()
+	require.Len(t, finished, 1)
+	require.Equal(t, true, finished[0].Tag("appsec.events.users.login.failure.track"))
+	require.Equal(t, "us", finished[0].Tag("appsec.events.users.login.failure.region"))
+	require.Equal(t, "user id", finished[0].Tag("appsec.events.users.login.failure.usr.id"))
+	require.Equal(t, false, finished[0].Tag("appsec.events.users.login.failure.usr.exists"))
+	require.Nil(t, finished[0].Tag("usr.id"))
+}
+
+func TestTrackCustomEvent(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	span, ctx := tracer.StartSpanFromContext(context.Background(), "root")
+	appsec.TrackCustomEvent(ctx, "my-event", map[string]string{"key": "value"})
+	appsec.TrackCustomEvent(ctx, "", map[string]string{"ignored": "value"})
+	span.Finish()
+
+	finished := mt.FinishedSpans()
+	require.Len(t, finished, 1)
+	require.Equal(t, true, finished[0].Tag("appsec.events.my-event.track"))
+	require.Equal(t, "value", finished[0].Tag("appsec.events.my-event.key"))
+	require.Nil(t, finished[0].Tag("appsec.events..ignored"))
+}
+
+func TestNoSpan(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, appsec.SetUser(ctx, "user id"))
+	require.NoError(t, appsec.TrackUserLoginSuccessEvent(ctx, "user id", nil))
+	appsec.TrackUserLoginFailureEvent(ctx, "user id", true, nil)
+	appsec.TrackCustomEvent(ctx, "my-event", nil)
+}
@@ -60,3 +60,35 @@ func ExampleMonitorParsedHTTPBody_customContext() {
 
 	r.Start(":8080")
 }
+
+// Monitor the authenticated user of a request and block it when needed
+func ExampleSetUser() {
+	mux := httptrace.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if err := appsec.SetUser(r.Context(), "user id"); err != nil {
+			// The user is blocked: return without writing the response, which is
+			// written by the tracing middleware.
+			return
+		}
+		w.Write([]byte("Hello user\n"))
+	})
+	http.ListenAndServe(":8080", mux)
+}
+
+// Track the successful and failed logins of the users
+func ExampleTrackUserLoginSuccessEvent() {
+	mux := httptrace.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		user, pass := r.FormValue("user"), r.FormValue("password")
+		if user != "admin" || pass != "secret" {
+			appsec.TrackUserLoginFailureEvent(r.Context(), user, user == "admin", nil)
+			http.Error(w, "invalid credentials", http.StatusUnauthorized)
+			return
+		}
+		if err := appsec.TrackUserLoginSuccessEvent(r.Context(), user, map[string]string{"method": "password"}); err != nil {
+			return
+		}
+		w.Write([]byte("Logged in\n"))
+	})
+	http.ListenAndServe(":8080", mux)
+}
@@ -0,0 +1,29 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022 Datadog, Inc.
+
+package ossec_test
+
+import (
+	"net/http"
+	"path/filepath"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/appsec/ossec"
+	httptrace "gopkg.in/DataDog/dd-trace-go.v1/contrib/net/http"
+)
+
+// Protect the files served by a handler against path traversals.
+func ExampleReadFile() {
+	mux := httptrace.NewServeMux()
+	mux.HandleFunc("/docs", func(w http.ResponseWriter, r *http.Request) {
+		b, err := ossec.ReadFile(r.Context(), filepath.Join("docs", r.URL.Query().Get("name")))
+		if err != nil {
+			// Return right away when the request is blocked, the blocking
+			// response being written by the tracing middleware.
+			return
+		}
+		w.Write(b)
+	})
+	http.ListenAndServe(":8080", mux)
+}
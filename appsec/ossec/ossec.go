@@ -0,0 +1,63 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022 Datadog, Inc.
+
+// Package ossec provides wrappers of the functions of the os package opening
+// files, which protect them against path traversals, or local file inclusions
+// (LFI), when Application Security is enabled: the paths of the files opened
+// while handling a request are monitored with the security rules before the
+// files get opened, so that the exploits can be detected and blocked at their
+// call site. They take the request context, as returned by the Context() method
+// of the HTTP request, and behave as their os counterpart otherwise.
+//
+// A non-nil error is returned when the request is blocked, in which case the
+// file is not opened, and the request handler should immediately return without
+// writing the response, which is then written by the tracing middleware of the
+// request.
+package ossec
+
+import (
+	"context"
+	"os"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo/instrumentation/httpsec"
+)
+
+// Open opens the named file for reading, as os.Open does, unless the request
+// held by ctx is blocked.
+func Open(ctx context.Context, name string) (*os.File, error) {
+	return OpenFile(ctx, name, os.O_RDONLY, 0)
+}
+
+// OpenFile opens the named file with the given flag and perm, as os.OpenFile
+// does, unless the request held by ctx is blocked.
+func OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (*os.File, error) {
+	if err := protect(ctx, name); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(name, flag, perm)
+}
+
+// ReadFile reads the named file and returns its contents, as os.ReadFile does,
+// unless the request held by ctx is blocked.
+func ReadFile(ctx context.Context, name string) ([]byte, error) {
+	if err := protect(ctx, name); err != nil {
+		return nil, err
+	}
+	return os.ReadFile(name)
+}
+
+// protect monitors the path of the file about to be opened with AppSec, when
+// enabled. It returns an *os.PathError wrapping the blocking error when the
+// request held by ctx was blocked.
+func protect(ctx context.Context, name string) error {
+	if !appsec.Enabled() {
+		return nil
+	}
+	if err := httpsec.ProtectOpenOperation(ctx, name); err != nil {
+		return &os.PathError{Op: "open", Path: name, Err: err}
+	}
+	return nil
+}
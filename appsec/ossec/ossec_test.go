@@ -0,0 +1,108 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022 Datadog, Inc.
+
+package ossec
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	httptrace "gopkg.in/DataDog/dd-trace-go.v1/contrib/net/http"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec"
+)
+
+func TestOpen(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "file.txt")
+
+	f, err := OpenFile(context.Background(), name, os.O_CREATE|os.O_WRONLY, 0600)
+	require.NoError(t, err)
+	_, err = f.Write([]byte("hello"))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	f, err = Open(context.Background(), name)
+	require.NoError(t, err)
+	b, err := io.ReadAll(f)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	assert.Equal(t, "hello", string(b))
+
+	b, err = ReadFile(context.Background(), name)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(b))
+
+	_, err = Open(context.Background(), filepath.Join(dir, "missing.txt"))
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+}
+
+func TestAppSec(t *testing.T) {
+	t.Setenv("DD_APPSEC_RULES", "../../internal/appsec/testdata/blocking.json")
+	appsec.Start()
+	defer appsec.Stop()
+
+	if !appsec.Enabled() {
+		t.Skip("appsec disabled")
+	}
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "file.txt"), []byte("hello"), 0600))
+
+	mux := httptrace.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		b, err := ReadFile(r.Context(), dir+"/"+r.URL.Query().Get("file"))
+		if err != nil {
+			var pathErr *os.PathError
+			require.True(t, errors.As(err, &pathErr))
+			// the request was blocked, the blocking response is written by the middleware
+			return
+		}
+		w.Write(b)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	for _, tc := range []struct {
+		file    string
+		blocked bool
+	}{
+		{file: "file.txt"},
+		{file: "../../etc/passwd", blocked: true},
+	} {
+		t.Run(tc.file, func(t *testing.T) {
+			mt := mocktracer.Start()
+			defer mt.Stop()
+
+			res, err := srv.Client().Get(srv.URL + "/?file=" + tc.file)
+			require.NoError(t, err)
+			defer res.Body.Close()
+			b, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+
+			spans := mt.FinishedSpans()
+			require.Len(t, spans, 1)
+			web := spans[0]
+			assert.Equal(t, float64(1), web.Tag("_dd.appsec.rasp.rule.eval"))
+			if !tc.blocked {
+				assert.Equal(t, http.StatusOK, res.StatusCode)
+				assert.Equal(t, "hello", string(b))
+				return
+			}
+			assert.Equal(t, http.StatusForbidden, res.StatusCode)
+			assert.Equal(t, true, web.Tag("appsec.blocked"))
+			assert.Contains(t, web.Tag("_dd.stack"), "net/http.HandlerFunc.ServeHTTP")
+		})
+	}
+}
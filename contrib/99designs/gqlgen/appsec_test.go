@@ -0,0 +1,139 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022 Datadog, Inc.
+
+package gqlgen
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+
+	httptrace "gopkg.in/DataDog/dd-trace-go.v1/contrib/net/http"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec"
+)
+
+func TestAppSec(t *testing.T) {
+	t.Setenv("DD_APPSEC_RULES", "../../../internal/appsec/testdata/blocking.json")
+	appsec.Start()
+	defer appsec.Stop()
+
+	if !appsec.Enabled() {
+		t.Skip("appsec disabled")
+	}
+
+	var resolved []string
+	h := handler.New(newFindSchema(func(name string) {
+		resolved = append(resolved, name)
+	}))
+	h.AddTransport(transport.POST{})
+	h.Use(NewTracer())
+	mux := httptrace.NewServeMux()
+	mux.Handle("/query", h)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	for _, tc := range []struct {
+		name    string
+		blocked bool
+	}{
+		{name: "bob"},
+		{name: "dd-test-graphql-block", blocked: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mt := mocktracer.Start()
+			defer mt.Stop()
+			resolved = nil
+
+			body, _ := json.Marshal(map[string]interface{}{
+				"query":     `query($name: String!) { find(name: $name) }`,
+				"variables": map[string]interface{}{"name": tc.name},
+			})
+			res, err := srv.Client().Post(srv.URL+"/query", "application/json", strings.NewReader(string(body)))
+			require.NoError(t, err)
+			defer res.Body.Close()
+			// blocked GraphQL requests are answered with GraphQL errors instead of a blocking response
+			assert.Equal(t, http.StatusOK, res.StatusCode)
+			var resp struct {
+				Errors []struct {
+					Message string
+				}
+			}
+			require.NoError(t, json.NewDecoder(res.Body).Decode(&resp))
+
+			var web mocktracer.Span
+			for _, s := range mt.FinishedSpans() {
+				if s.Tag(ext.SpanType) == ext.SpanTypeWeb {
+					web = s
+				}
+			}
+			require.NotNil(t, web)
+			if !tc.blocked {
+				assert.Empty(t, resp.Errors)
+				assert.Equal(t, []string{tc.name}, resolved)
+				assert.Nil(t, web.Tag("appsec.blocked"))
+				return
+			}
+			// the resolver is not called
+			assert.Empty(t, resolved)
+			require.Len(t, resp.Errors, 1)
+			assert.Equal(t, blockedErrorMessage, resp.Errors[0].Message)
+			assert.Equal(t, true, web.Tag("appsec.blocked"))
+			assert.Contains(t, web.Tag("_dd.appsec.json"), "gql-001")
+		})
+	}
+}
+
+// newFindSchema returns an executable schema with the query find(name), whose
+// resolver calls the given function, simulating the field execution of the
+// generated code.
+func newFindSchema(find func(name string)) graphql.ExecutableSchema {
+	schema := gqlparser.MustLoadSchema(&ast.Source{Input: `
+		type Query {
+			find(name: String!): String
+		}
+	`})
+	return &graphql.ExecutableSchemaMock{
+		ExecFunc: func(ctx context.Context) graphql.ResponseHandler {
+			oc := graphql.GetOperationContext(ctx)
+			field := oc.Operation.SelectionSet[0].(*ast.Field)
+			ctx = graphql.WithFieldContext(ctx, &graphql.FieldContext{
+				Object:     "Query",
+				Field:      graphql.CollectedField{Field: field},
+				Args:       field.ArgumentMap(oc.Variables),
+				IsResolver: true,
+			})
+			res, err := oc.ResolverMiddleware(ctx, func(ctx context.Context) (interface{}, error) {
+				name := graphql.GetFieldContext(ctx).Args["name"].(string)
+				find(name)
+				return name, nil
+			})
+			if err != nil {
+				graphql.AddError(ctx, err)
+				return graphql.OneShot(&graphql.Response{Data: []byte(`{"find":null}`)})
+			}
+			data, _ := json.Marshal(map[string]interface{}{"find": res})
+			return graphql.OneShot(&graphql.Response{Data: data})
+		},
+		SchemaFunc: func() *ast.Schema {
+			return schema
+		},
+		ComplexityFunc: func(typeName string, fieldName string, childComplexity int, args map[string]interface{}) (int, bool) {
+			return 0, false
+		},
+	}
+}
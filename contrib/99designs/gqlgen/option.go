@@ -9,20 +9,22 @@ import (
 	"math"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/namingschema"
 )
 
 const defaultServiceName = "graphql"
 
 type config struct {
-	serviceName   string
-	analyticsRate float64
+	serviceName                 string
+	analyticsRate               float64
+	withoutTrivialResolverSpans bool
 }
 
 // An Option configures the gqlgen integration.
 type Option func(t *config)
 
 func defaults(t *config) {
-	t.serviceName = defaultServiceName
+	t.serviceName = namingschema.ServiceName(defaultServiceName)
 	t.analyticsRate = globalconfig.AnalyticsRate()
 }
 
@@ -47,3 +49,12 @@ func WithServiceName(name string) Option {
 		t.serviceName = name
 	}
 }
+
+// WithoutTraceTrivialResolverSpans disables the spans of the trivial fields,
+// which are the fields read from the resolved objects, as opposed to the ones
+// resolved by a method or a user-specified resolver.
+func WithoutTraceTrivialResolverSpans() Option {
+	return func(t *config) {
+		t.withoutTrivialResolverSpans = true
+	}
+}
@@ -41,6 +41,7 @@ package gqlgen
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math"
 	"strings"
@@ -48,20 +49,40 @@ import (
 
 	"github.com/99designs/gqlgen/graphql"
 	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo/instrumentation/httpsec"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/namingschema"
 )
 
+const componentName = "gqlgen"
+
 const (
 	defaultGraphqlOperation = "graphql.request"
 
 	readOp       = "graphql.read"
 	parsingOp    = "graphql.parse"
 	validationOp = "graphql.validate"
+	fieldOp      = "graphql.field"
+)
+
+const (
+	tagGraphqlField         = "graphql.field"
+	tagGraphqlType          = "graphql.type"
+	tagGraphqlPath          = "graphql.path"
+	tagGraphqlOperationName = "graphql.operation.name"
+	tagGraphqlOperationType = "graphql.operation.type"
+	tagGraphqlErrorCodes    = "graphql.error.codes"
 )
 
+// blockedErrorMessage is the message of the GraphQL errors the fields resolve
+// to when the request is blocked by AppSec.
+const blockedErrorMessage = "request blocked by security rules"
+
 type gqlTracer struct {
 	cfg *config
 }
@@ -88,6 +109,7 @@ func (t *gqlTracer) Validate(schema graphql.ExecutableSchema) error {
 
 func (t *gqlTracer) InterceptResponse(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
 	opts := []ddtrace.StartSpanOption{
+		tracer.Tag(ext.Component, componentName),
 		tracer.SpanType(ext.SpanTypeGraphQL),
 		tracer.ServiceName(t.cfg.serviceName),
 	}
@@ -110,6 +132,10 @@ func (t *gqlTracer) InterceptResponse(ctx context.Context, next graphql.Response
 				return next(ctx)
 			}
 			name = fmt.Sprintf("%s.%s", ext.SpanTypeGraphQL, octx.Operation.Operation)
+			opts = append(opts, tracer.Tag(tagGraphqlOperationType, string(octx.Operation.Operation)))
+			if octx.Operation.Name != "" {
+				opts = append(opts, tracer.Tag(tagGraphqlOperationName, octx.Operation.Name))
+			}
 		}
 		if octx.RawQuery != "" {
 			opts = append(opts, tracer.ResourceName(octx.RawQuery))
@@ -117,16 +143,20 @@ func (t *gqlTracer) InterceptResponse(ctx context.Context, next graphql.Response
 		opts = append(opts, tracer.StartTime(octx.Stats.OperationStart))
 	}
 	var span ddtrace.Span
-	span, ctx = tracer.StartSpanFromContext(ctx, name, opts...)
+	span, ctx = tracer.StartSpanFromContext(ctx, namingschema.GraphQLServerOp(name), opts...)
 	defer func() {
+		gqlErrs := graphql.GetErrors(ctx)
 		var errs []string
-		for _, err := range graphql.GetErrors(ctx) {
+		for _, err := range gqlErrs {
 			errs = append(errs, err.Message)
 		}
 		var err error
 		if len(errs) > 0 {
 			err = fmt.Errorf(strings.Join(errs, ", "))
 		}
+		if codes := errorCodes(gqlErrs...); codes != "" {
+			span.SetTag(tagGraphqlErrorCodes, codes)
+		}
 		span.Finish(tracer.WithError(err))
 	}()
 
@@ -136,6 +166,7 @@ func (t *gqlTracer) InterceptResponse(ctx context.Context, next graphql.Response
 			var childOpts []ddtrace.StartSpanOption
 			childOpts = append(childOpts, tracer.StartTime(start))
 			childOpts = append(childOpts, tracer.ResourceName(name))
+			childOpts = append(childOpts, tracer.Tag(ext.Component, componentName))
 			var childSpan ddtrace.Span
 			childSpan, _ = tracer.StartSpanFromContext(ctx, name, childOpts...)
 			childSpan.Finish(tracer.FinishTime(finish))
@@ -147,8 +178,70 @@ func (t *gqlTracer) InterceptResponse(ctx context.Context, next graphql.Response
 	return next(ctx)
 }
 
+// InterceptField creates a span around the resolution of each field, unless
+// it is a trivial one and WithoutTraceTrivialResolverSpans was used. When
+// AppSec is enabled, the arguments of the field are monitored before resolving
+// it, and the field resolves to a GraphQL error when the request is blocked.
+func (t *gqlTracer) InterceptField(ctx context.Context, next graphql.Resolver) (res interface{}, err error) {
+	fc := graphql.GetFieldContext(ctx)
+	if fc == nil {
+		return next(ctx)
+	}
+	if appsec.Enabled() {
+		if err := httpsec.ProtectGraphQLResolverOperation(ctx, fc.Field.Name, fc.Args); err != nil {
+			return nil, gqlerror.ErrorPathf(fc.Path(), blockedErrorMessage)
+		}
+	}
+	if t.cfg.withoutTrivialResolverSpans && !fc.IsMethod && !fc.IsResolver {
+		return next(ctx)
+	}
+	opts := []ddtrace.StartSpanOption{
+		tracer.Tag(ext.Component, componentName),
+		tracer.SpanType(ext.SpanTypeGraphQL),
+		tracer.ServiceName(t.cfg.serviceName),
+		tracer.ResourceName(fc.Object + "." + fc.Field.Name),
+		tracer.Tag(tagGraphqlField, fc.Field.Name),
+		tracer.Tag(tagGraphqlType, fc.Object),
+		tracer.Tag(tagGraphqlPath, fc.Path().String()),
+	}
+	if !math.IsNaN(t.cfg.analyticsRate) {
+		opts = append(opts, tracer.Tag(ext.EventSampleRate, t.cfg.analyticsRate))
+	}
+	span, ctx := tracer.StartSpanFromContext(ctx, fieldOp, opts...)
+	defer func() {
+		var gqlErr *gqlerror.Error
+		if errors.As(err, &gqlErr) {
+			if codes := errorCodes(gqlErr); codes != "" {
+				span.SetTag(tagGraphqlErrorCodes, codes)
+			}
+		}
+		span.Finish(tracer.WithError(err))
+	}()
+	return next(ctx)
+}
+
+// errorCodes returns the comma-separated list of the distinct codes found in
+// the "code" extension of the given errors.
+func errorCodes(errs ...*gqlerror.Error) string {
+	var codes []string
+	seen := make(map[string]bool)
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		code, ok := err.Extensions["code"].(string)
+		if !ok || seen[code] {
+			continue
+		}
+		seen[code] = true
+		codes = append(codes, code)
+	}
+	return strings.Join(codes, ",")
+}
+
 // Ensure all of these interfaces are implemented.
 var _ interface {
 	graphql.HandlerExtension
 	graphql.ResponseInterceptor
+	graphql.FieldInterceptor
 } = &gqlTracer{}
@@ -6,6 +6,7 @@
 package gqlgen
 
 import (
+	"context"
 	"testing"
 
 	"github.com/99designs/gqlgen/client"
@@ -13,6 +14,8 @@ import (
 	"github.com/99designs/gqlgen/graphql/handler/testserver"
 	"github.com/99designs/gqlgen/graphql/handler/transport"
 	"github.com/stretchr/testify/assert"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
@@ -144,12 +147,89 @@ func TestChildSpans(t *testing.T) {
 		resNames = append(resNames, span.Tag(ext.ResourceName).(string))
 		opNames = append(opNames, span.OperationName())
 	}
-	assert.ElementsMatch(resNames, []string{readOp, validationOp, parsingOp, query})
-	assert.ElementsMatch(opNames, []string{readOp, validationOp, parsingOp, "graphql.query"})
+	assert.ElementsMatch(resNames, []string{readOp, validationOp, parsingOp, "Query.name", query})
+	assert.ElementsMatch(opNames, []string{readOp, validationOp, parsingOp, fieldOp, "graphql.query"})
 	assert.NotNil(root)
 	assert.Nil(root.Tag(ext.Error))
 }
 
+func TestFieldSpans(t *testing.T) {
+	query := `query Names { name }`
+	t.Run("default", func(t *testing.T) {
+		assert := assert.New(t)
+		mt := mocktracer.Start()
+		defer mt.Stop()
+		c := newTestClient(t, testserver.New(), NewTracer())
+		var resp struct {
+			Name string
+		}
+		assert.Nil(c.Post(query, &resp))
+		var root, field mocktracer.Span
+		for _, span := range mt.FinishedSpans() {
+			switch span.OperationName() {
+			case "graphql.query":
+				root = span
+			case fieldOp:
+				field = span
+			}
+		}
+		assert.NotNil(root)
+		assert.Equal("Names", root.Tag(tagGraphqlOperationName))
+		assert.Equal("query", root.Tag(tagGraphqlOperationType))
+		assert.NotNil(field)
+		assert.Equal(root.SpanID(), field.ParentID())
+		assert.Equal("Query.name", field.Tag(ext.ResourceName))
+		assert.Equal("name", field.Tag(tagGraphqlField))
+		assert.Equal("Query", field.Tag(tagGraphqlType))
+		assert.Equal("name", field.Tag(tagGraphqlPath))
+		assert.Equal(ext.SpanTypeGraphQL, field.Tag(ext.SpanType))
+	})
+
+	t.Run("WithoutTraceTrivialResolverSpans", func(t *testing.T) {
+		assert := assert.New(t)
+		mt := mocktracer.Start()
+		defer mt.Stop()
+		c := newTestClient(t, testserver.New(), NewTracer(WithoutTraceTrivialResolverSpans()))
+		var resp struct {
+			Name string
+		}
+		assert.Nil(c.Post(query, &resp))
+		for _, span := range mt.FinishedSpans() {
+			assert.NotEqual(fieldOp, span.OperationName())
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		assert := assert.New(t)
+		mt := mocktracer.Start()
+		defer mt.Stop()
+		tr := NewTracer().(*gqlTracer)
+		ctx := graphql.WithFieldContext(context.Background(), &graphql.FieldContext{
+			Object:     "Query",
+			Field:      graphql.CollectedField{Field: &ast.Field{Name: "user", Alias: "user"}},
+			IsResolver: true,
+		})
+		_, err := tr.InterceptField(ctx, func(ctx context.Context) (interface{}, error) {
+			return nil, &gqlerror.Error{Message: "not found", Extensions: map[string]interface{}{"code": "NOT_FOUND"}}
+		})
+		assert.NotNil(err)
+		spans := mt.FinishedSpans()
+		assert.Len(spans, 1)
+		assert.Equal("Query.user", spans[0].Tag(ext.ResourceName))
+		assert.Equal("NOT_FOUND", spans[0].Tag(tagGraphqlErrorCodes))
+		assert.NotNil(spans[0].Tag(ext.Error))
+	})
+}
+
+func TestErrorCodes(t *testing.T) {
+	code := func(c string) *gqlerror.Error {
+		return &gqlerror.Error{Extensions: map[string]interface{}{"code": c}}
+	}
+	assert.Equal(t, "", errorCodes())
+	assert.Equal(t, "", errorCodes(&gqlerror.Error{Message: "no code"}))
+	assert.Equal(t, "A,B", errorCodes(code("A"), code("B"), code("A"), nil))
+}
+
 func newTestClient(t *testing.T, h *testserver.TestServer, tracer graphql.HandlerExtension) *client.Client {
 	t.Helper()
 	h.AddTransport(transport.POST{})
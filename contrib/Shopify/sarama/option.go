@@ -10,16 +10,19 @@ import (
 
 	"gopkg.in/DataDog/dd-trace-go.v1/internal"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/namingschema"
 )
 
 type config struct {
 	consumerServiceName string
 	producerServiceName string
 	analyticsRate       float64
+	dataStreamsEnabled  bool
+	groupID             string
 }
 
 func defaults(cfg *config) {
-	cfg.producerServiceName = "kafka"
+	cfg.producerServiceName = namingschema.ServiceName("kafka")
 	cfg.consumerServiceName = "kafka"
 	if svc := globalconfig.ServiceName(); svc != "" {
 		cfg.consumerServiceName = svc
@@ -30,6 +33,7 @@ func defaults(cfg *config) {
 	} else {
 		cfg.analyticsRate = math.NaN()
 	}
+	cfg.dataStreamsEnabled = internal.BoolEnv("DD_DATA_STREAMS_ENABLED", false)
 }
 
 // An Option is used to customize the config for the sarama tracer.
@@ -65,3 +69,20 @@ func WithAnalyticsRate(rate float64) Option {
 		}
 	}
 }
+
+// WithDataStreams enables the Data Streams Monitoring product features: a
+// checkpoint is set on the pathway of every produced and consumed message. It
+// is enabled by default when DD_DATA_STREAMS_ENABLED is set to true.
+func WithDataStreams() Option {
+	return func(cfg *config) {
+		cfg.dataStreamsEnabled = true
+	}
+}
+
+// WithGroupID sets the consumer group of the wrapped consumers, which is used
+// to tag their Data Streams Monitoring checkpoints.
+func WithGroupID(groupID string) Option {
+	return func(cfg *config) {
+		cfg.groupID = groupID
+	}
+}
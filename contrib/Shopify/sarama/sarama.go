@@ -4,19 +4,36 @@
 // Copyright 2016 Datadog, Inc.
 
 // Package sarama provides functions to trace the Shopify/sarama package (https://github.com/Shopify/sarama).
+//
+// The Shopify/sarama package has moved to IBM/sarama, which is traced by the
+// gopkg.in/DataDog/dd-trace-go.v1/contrib/IBM/sarama.v1 package.
 package sarama // import "gopkg.in/DataDog/dd-trace-go.v1/contrib/Shopify/sarama"
 
 import (
+	"context"
 	"math"
+	"sync"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/datastreams"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/namingschema"
 
 	"github.com/Shopify/sarama"
 )
 
+const componentName = "sarama"
+
+const (
+	// tagRequiredAcks is the level of acknowledgement required from the brokers
+	// by the producers, see sarama.RequiredAcks.
+	tagRequiredAcks = "kafka.required_acks"
+	// tagIdempotent is whether the producers are idempotent.
+	tagIdempotent = "kafka.idempotent"
+)
+
 type partitionConsumer struct {
 	sarama.PartitionConsumer
 	messages chan *sarama.ConsumerMessage
@@ -47,6 +64,7 @@ func WrapPartitionConsumer(pc sarama.PartitionConsumer, opts ...Option) sarama.P
 		for msg := range msgs {
 			// create the next span from the message
 			opts := []tracer.StartSpanOption{
+				tracer.Tag(ext.Component, componentName),
 				tracer.ServiceName(cfg.consumerServiceName),
 				tracer.ResourceName("Consume Topic " + msg.Topic),
 				tracer.SpanType(ext.SpanTypeMessageConsumer),
@@ -62,9 +80,12 @@ func WrapPartitionConsumer(pc sarama.PartitionConsumer, opts ...Option) sarama.P
 			if spanctx, err := tracer.Extract(carrier); err == nil {
 				opts = append(opts, tracer.ChildOf(spanctx))
 			}
-			next := tracer.StartSpan("kafka.consume", opts...)
+			next := tracer.StartSpan(namingschema.MessagingInboundOp("kafka", "kafka.consume"), opts...)
 			// reinject the span context so consumers can pick it up
 			tracer.Inject(next.Context(), carrier)
+			if cfg.dataStreamsEnabled {
+				setConsumeCheckpoint(cfg.groupID, msg)
+			}
 
 			wrapped.messages <- msg
 
@@ -109,13 +130,13 @@ func WrapConsumer(c sarama.Consumer, opts ...Option) sarama.Consumer {
 
 type syncProducer struct {
 	sarama.SyncProducer
-	version sarama.KafkaVersion
-	cfg     *config
+	saramaConfig *sarama.Config
+	cfg          *config
 }
 
 // SendMessage calls sarama.SyncProducer.SendMessage and traces the request.
 func (p *syncProducer) SendMessage(msg *sarama.ProducerMessage) (partition int32, offset int64, err error) {
-	span := startProducerSpan(p.cfg, p.version, msg)
+	span := startProducerSpan(p.cfg, p.saramaConfig, msg)
 	partition, offset, err = p.SyncProducer.SendMessage(msg)
 	finishProducerSpan(span, partition, offset, err)
 	return partition, offset, err
@@ -127,7 +148,7 @@ func (p *syncProducer) SendMessages(msgs []*sarama.ProducerMessage) error {
 	// treated individually, so we create a span for each one
 	spans := make([]ddtrace.Span, len(msgs))
 	for i, msg := range msgs {
-		spans[i] = startProducerSpan(p.cfg, p.version, msg)
+		spans[i] = startProducerSpan(p.cfg, p.saramaConfig, msg)
 	}
 	err := p.SyncProducer.SendMessages(msgs)
 	for i, span := range spans {
@@ -150,7 +171,7 @@ func WrapSyncProducer(saramaConfig *sarama.Config, producer sarama.SyncProducer,
 	}
 	return &syncProducer{
 		SyncProducer: producer,
-		version:      saramaConfig.Version,
+		saramaConfig: saramaConfig,
 		cfg:          cfg,
 	}
 }
@@ -160,6 +181,9 @@ type asyncProducer struct {
 	input     chan *sarama.ProducerMessage
 	successes chan *sarama.ProducerMessage
 	errors    chan *sarama.ProducerError
+
+	mu    sync.Mutex
+	spans map[uint64]ddtrace.Span // spans waiting for an acknowledgement, by span ID
 }
 
 // Input returns the input channel.
@@ -180,8 +204,15 @@ func (p *asyncProducer) Errors() <-chan *sarama.ProducerError {
 // WrapAsyncProducer wraps a sarama.AsyncProducer so that all produced messages
 // are traced. It requires the underlying sarama Config so we can know whether
 // or not successes will be returned. Tracing requires at least sarama.V0_11_0_0
-// version which is the first version that supports headers. Only spans of
-// successfully published messages have partition and offset tags set.
+// version which is the first version that supports headers.
+//
+// When the successes are returned, which requires Producer.Return.Successes to
+// be set, the spans are finished once the messages are acknowledged by the
+// brokers, as set by Producer.RequiredAcks, or once they fail, so that they
+// measure the acknowledgement latency, and the spans of the messages which were
+// published successfully have partition and offset tags set. Otherwise, there
+// is no way to know when the messages are published, and the spans are finished
+// as soon as the messages are queued.
 func WrapAsyncProducer(saramaConfig *sarama.Config, p sarama.AsyncProducer, opts ...Option) sarama.AsyncProducer {
 	cfg := new(config)
 	defaults(cfg)
@@ -200,50 +231,51 @@ func WrapAsyncProducer(saramaConfig *sarama.Config, p sarama.AsyncProducer, opts
 		input:         make(chan *sarama.ProducerMessage),
 		successes:     make(chan *sarama.ProducerMessage),
 		errors:        make(chan *sarama.ProducerError),
+		spans:         make(map[uint64]ddtrace.Span),
 	}
+	// The messages are queued and their results are returned by different
+	// goroutines, so that the producer never blocks on returning a result while
+	// the wrapper waits for it to accept a message.
+	go func() {
+		for msg := range wrapped.input {
+			span := startProducerSpan(cfg, saramaConfig, msg)
+			if saramaConfig.Producer.Return.Successes {
+				wrapped.mu.Lock()
+				wrapped.spans[span.Context().SpanID()] = span
+				wrapped.mu.Unlock()
+			} else {
+				// if returning successes isn't enabled, we just finish the
+				// span right away because there's no way to know when it will
+				// be done
+				span.Finish()
+			}
+			p.Input() <- msg
+		}
+	}()
 	go func() {
-		spans := make(map[uint64]ddtrace.Span)
 		defer close(wrapped.input)
 		defer close(wrapped.successes)
 		defer close(wrapped.errors)
-		for {
+		successes, errors := p.Successes(), p.Errors()
+		// both channels are closed once the producer is closed
+		for successes != nil || errors != nil {
 			select {
-			case msg := <-wrapped.input:
-				span := startProducerSpan(cfg, saramaConfig.Version, msg)
-				p.Input() <- msg
-				if saramaConfig.Producer.Return.Successes {
-					spanID := span.Context().SpanID()
-					spans[spanID] = span
-				} else {
-					// if returning successes isn't enabled, we just finish the
-					// span right away because there's no way to know when it will
-					// be done
-					span.Finish()
-				}
-			case msg, ok := <-p.Successes():
+			case msg, ok := <-successes:
 				if !ok {
-					// producer was closed, so exit
-					return
+					successes = nil
+					continue
 				}
-				if spanctx, spanFound := getSpanContext(msg); spanFound {
-					spanID := spanctx.SpanID()
-					if span, ok := spans[spanID]; ok {
-						delete(spans, spanID)
-						finishProducerSpan(span, msg.Partition, msg.Offset, nil)
-					}
+				if span, ok := wrapped.pendingSpan(msg); ok {
+					finishProducerSpan(span, msg.Partition, msg.Offset, nil)
 				}
 				wrapped.successes <- msg
-			case err, ok := <-p.Errors():
+			case err, ok := <-errors:
 				if !ok {
-					// producer was closed
-					return
+					errors = nil
+					continue
 				}
-				if spanctx, spanFound := getSpanContext(err.Msg); spanFound {
-					spanID := spanctx.SpanID()
-					if span, ok := spans[spanID]; ok {
-						delete(spans, spanID)
-						span.Finish(tracer.WithError(err))
-					}
+				if span, ok := wrapped.pendingSpan(err.Msg); ok {
+					span.Finish(tracer.WithError(err))
 				}
 				wrapped.errors <- err
 			}
@@ -252,12 +284,31 @@ func WrapAsyncProducer(saramaConfig *sarama.Config, p sarama.AsyncProducer, opts
 	return wrapped
 }
 
-func startProducerSpan(cfg *config, version sarama.KafkaVersion, msg *sarama.ProducerMessage) ddtrace.Span {
+// pendingSpan removes and returns the span of msg waiting for its result, if
+// any.
+func (p *asyncProducer) pendingSpan(msg *sarama.ProducerMessage) (ddtrace.Span, bool) {
+	spanctx, ok := getSpanContext(msg)
+	if !ok {
+		return nil, false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	span, ok := p.spans[spanctx.SpanID()]
+	if ok {
+		delete(p.spans, spanctx.SpanID())
+	}
+	return span, ok
+}
+
+func startProducerSpan(cfg *config, saramaConfig *sarama.Config, msg *sarama.ProducerMessage) ddtrace.Span {
 	carrier := NewProducerMessageCarrier(msg)
 	opts := []tracer.StartSpanOption{
+		tracer.Tag(ext.Component, componentName),
 		tracer.ServiceName(cfg.producerServiceName),
 		tracer.ResourceName("Produce Topic " + msg.Topic),
 		tracer.SpanType(ext.SpanTypeMessageProducer),
+		tracer.Tag(tagRequiredAcks, int(saramaConfig.Producer.RequiredAcks)),
+		tracer.Tag(tagIdempotent, saramaConfig.Producer.Idempotent),
 	}
 	if !math.IsNaN(cfg.analyticsRate) {
 		opts = append(opts, tracer.Tag(ext.EventSampleRate, cfg.analyticsRate))
@@ -266,14 +317,45 @@ func startProducerSpan(cfg *config, version sarama.KafkaVersion, msg *sarama.Pro
 	if spanctx, err := tracer.Extract(carrier); err == nil {
 		opts = append(opts, tracer.ChildOf(spanctx))
 	}
-	span := tracer.StartSpan("kafka.produce", opts...)
-	if version.IsAtLeast(sarama.V0_11_0_0) {
+	span := tracer.StartSpan(namingschema.MessagingOutboundOp("kafka", "kafka.produce"), opts...)
+	if saramaConfig.Version.IsAtLeast(sarama.V0_11_0_0) {
 		// re-inject the span context so consumers can pick it up
 		tracer.Inject(span.Context(), carrier)
+		if cfg.dataStreamsEnabled {
+			setProduceCheckpoint(msg)
+		}
 	}
 	return span
 }
 
+// setProduceCheckpoint sets a Data Streams Monitoring checkpoint on the pathway
+// propagated by msg, if any, or on a new one, and injects the updated pathway so
+// consumers can pick it up.
+func setProduceCheckpoint(msg *sarama.ProducerMessage) {
+	edges := []string{"direction:out", "topic:" + msg.Topic, "type:kafka"}
+	carrier := NewProducerMessageCarrier(msg)
+	ctx, ok := tracer.SetDataStreamsCheckpoint(datastreams.ExtractFromCarrier(context.Background(), carrier), edges...)
+	if !ok {
+		return
+	}
+	datastreams.InjectToCarrier(ctx, carrier)
+}
+
+// setConsumeCheckpoint sets a Data Streams Monitoring checkpoint on the pathway
+// propagated by msg, and reinjects the updated pathway so consumers can pick it up.
+func setConsumeCheckpoint(groupID string, msg *sarama.ConsumerMessage) {
+	edges := []string{"direction:in", "topic:" + msg.Topic, "type:kafka"}
+	if groupID != "" {
+		edges = append(edges, "group:"+groupID)
+	}
+	carrier := NewConsumerMessageCarrier(msg)
+	ctx, ok := tracer.SetDataStreamsCheckpoint(datastreams.ExtractFromCarrier(context.Background(), carrier), edges...)
+	if !ok {
+		return
+	}
+	datastreams.InjectToCarrier(ctx, carrier)
+}
+
 func finishProducerSpan(span ddtrace.Span, partition int32, offset int64, err error) {
 	span.SetTag("partition", partition)
 	span.SetTag("offset", offset)
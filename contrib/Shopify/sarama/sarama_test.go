@@ -15,6 +15,7 @@ import (
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
 
 	"github.com/Shopify/sarama"
+	"github.com/Shopify/sarama/mocks"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -271,6 +272,65 @@ func TestAsyncProducer(t *testing.T) {
 			assert.Equal(t, int64(0), s.Tag("offset"))
 		}
 	})
+
+	// sarama.MockBroker can't be used here, the acknowledgements are returned
+	// by the mock producer of sarama/mocks instead
+	t.Run("Acknowledgement", func(t *testing.T) {
+		mt := mocktracer.Start()
+		defer mt.Stop()
+
+		cfg := sarama.NewConfig()
+		cfg.Version = sarama.V0_11_0_0
+		cfg.Producer.Return.Successes = true
+		cfg.Producer.RequiredAcks = sarama.WaitForAll
+
+		mp := mocks.NewAsyncProducer(t, cfg)
+		mp.ExpectInputAndSucceed()
+		producer := WrapAsyncProducer(cfg, mp)
+
+		producer.Input() <- &sarama.ProducerMessage{
+			Topic: "my_topic",
+			Value: sarama.StringEncoder("test 1"),
+		}
+		<-producer.Successes()
+
+		spans := mt.FinishedSpans()
+		assert.Len(t, spans, 1)
+		{
+			s := spans[0]
+			assert.Equal(t, "kafka.produce", s.OperationName())
+			assert.Equal(t, int(sarama.WaitForAll), s.Tag(tagRequiredAcks))
+			assert.Equal(t, false, s.Tag(tagIdempotent))
+			assert.Nil(t, s.Tag(ext.Error))
+		}
+		assert.NoError(t, producer.Close())
+	})
+
+	t.Run("Acknowledgement Error", func(t *testing.T) {
+		mt := mocktracer.Start()
+		defer mt.Stop()
+
+		cfg := sarama.NewConfig()
+		cfg.Version = sarama.V0_11_0_0
+		cfg.Producer.Return.Successes = true
+
+		mp := mocks.NewAsyncProducer(t, cfg)
+		mp.ExpectInputAndFail(sarama.ErrMessageSizeTooLarge)
+		producer := WrapAsyncProducer(cfg, mp)
+
+		producer.Input() <- &sarama.ProducerMessage{
+			Topic: "my_topic",
+			Value: sarama.StringEncoder("test 1"),
+		}
+		perr := <-producer.Errors()
+
+		spans := mt.FinishedSpans()
+		assert.Len(t, spans, 1)
+		assert.Equal(t, perr, spans[0].Tag(ext.Error))
+		producer.AsyncClose()
+		for range producer.Errors() {
+		}
+	})
 }
 
 func newMockBroker(t *testing.T) *sarama.MockBroker {
@@ -9,24 +9,35 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"reflect"
+	"strings"
 	"time"
 
+	"gopkg.in/DataDog/dd-trace-go.v1/contrib/internal/httptrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
 	"github.com/aws/smithy-go/middleware"
 	smithyhttp "github.com/aws/smithy-go/transport/http"
 )
 
+const componentName = "aws"
+
 const (
-	tagAWSAgent     = "aws.agent"
-	tagAWSService   = "aws.service"
-	tagAWSOperation = "aws.operation"
-	tagAWSRegion    = "aws.region"
-	tagAWSRequestID = "aws.request_id"
+	tagAWSAgent      = "aws.agent"
+	tagAWSService    = "aws.service"
+	tagAWSOperation  = "aws.operation"
+	tagAWSRegion     = "aws.region"
+	tagAWSRequestID  = "aws.request_id"
+	tagAWSRetryCount = "aws.retry_count"
+
+	tagS3BucketName      = "bucketname"
+	tagDynamoDBTableName = "tablename"
+	tagSQSQueueName      = "queuename"
 )
 
 type spanTimestampKey struct{}
@@ -70,15 +81,21 @@ func (mw *traceMiddleware) startTraceMiddleware(stack *middleware.Stack) error {
 		operation := awsmiddleware.GetOperationName(ctx)
 		serviceID := awsmiddleware.GetServiceID(ctx)
 
+		resource := fmt.Sprintf("%s.%s", serviceID, operation)
 		opts := []ddtrace.StartSpanOption{
+			tracer.Tag(ext.Component, componentName),
 			tracer.SpanType(ext.SpanTypeHTTP),
 			tracer.ServiceName(serviceName(mw.cfg, serviceID)),
-			tracer.ResourceName(fmt.Sprintf("%s.%s", serviceID, operation)),
 			tracer.Tag(tagAWSRegion, awsmiddleware.GetRegion(ctx)),
 			tracer.Tag(tagAWSOperation, operation),
 			tracer.Tag(tagAWSService, serviceID),
 			tracer.StartTime(ctx.Value(spanTimestampKey{}).(time.Time)),
 		}
+		if tag, name := resourceTag(serviceID, in.Parameters); name != "" {
+			resource = resource + " " + name
+			opts = append(opts, tracer.Tag(tag, name))
+		}
+		opts = append(opts, tracer.ResourceName(resource))
 		if !math.IsNaN(mw.cfg.analyticsRate) {
 			opts = append(opts, tracer.Tag(ext.EventSampleRate, mw.cfg.analyticsRate))
 		}
@@ -86,6 +103,13 @@ func (mw *traceMiddleware) startTraceMiddleware(stack *middleware.Stack) error {
 
 		// Handle initialize and continue through the middleware chain.
 		out, metadata, err = next.HandleInitialize(spanctx, in)
+		if attempts, ok := retry.GetAttemptResults(metadata); ok && len(attempts.Results) > 0 {
+			if s, ok := span.(ddtrace.SpanWithMetrics); ok {
+				s.SetMetric(tagAWSRetryCount, float64(len(attempts.Results)-1))
+			} else {
+				span.SetTag(tagAWSRetryCount, float64(len(attempts.Results)-1))
+			}
+		}
 		span.Finish(tracer.WithError(err))
 
 		return out, metadata, err
@@ -103,7 +127,7 @@ func (mw *traceMiddleware) deserializeTraceMiddleware(stack *middleware.Stack) e
 		// Get values out of the request.
 		if req, ok := in.Request.(*smithyhttp.Request); ok {
 			span.SetTag(ext.HTTPMethod, req.Method)
-			span.SetTag(ext.HTTPURL, req.URL.String())
+			span.SetTag(ext.HTTPURL, httptrace.URLTag(req.URL))
 			span.SetTag(tagAWSAgent, req.Header.Get("User-Agent"))
 		}
 
@@ -131,3 +155,46 @@ func serviceName(cfg *config, serviceID string) string {
 
 	return fmt.Sprintf("aws.%s", serviceID)
 }
+
+// resourceTag returns the tag naming the AWS resource targeted by the API call
+// with the given input parameters, e.g. the bucket of an S3 call, along with its
+// value. The value is empty for the services and operations not targeting a
+// single resource.
+func resourceTag(serviceID string, params interface{}) (tag, name string) {
+	switch serviceID {
+	case "S3":
+		return tagS3BucketName, stringParam(params, "Bucket")
+	case "DynamoDB":
+		return tagDynamoDBTableName, stringParam(params, "TableName")
+	case "SQS":
+		if url := stringParam(params, "QueueUrl"); url != "" {
+			// the queue name is the last segment of the queue URL
+			return tagSQSQueueName, url[strings.LastIndex(url, "/")+1:]
+		}
+		return tagSQSQueueName, stringParam(params, "QueueName")
+	}
+	return "", ""
+}
+
+// stringParam returns the value of the *string field of the given name of the
+// input parameters of an API call, or an empty string if there is none. The input
+// types of every service share the same field names, e.g. Bucket for S3, so that
+// they are read by reflection rather than by depending on every service module.
+func stringParam(params interface{}, field string) string {
+	v := reflect.ValueOf(params)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return ""
+	}
+	f := v.FieldByName(field)
+	if !f.IsValid() || !f.CanInterface() {
+		return ""
+	}
+	s, ok := f.Interface().(*string)
+	if !ok || s == nil {
+		return ""
+	}
+	return *s
+}
@@ -9,12 +9,15 @@ import (
 	"context"
 	"net/http"
 	"net/http/httptest"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
 	"github.com/stretchr/testify/assert"
 )
@@ -109,6 +112,105 @@ func TestAppendMiddleware_WithNoTracer(t *testing.T) {
 
 }
 
+func TestAppendMiddleware_Resource(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	server := mockAWS(200)
+	defer server.Close()
+
+	awsCfg := mockAWSConfig(server.URL)
+	AppendMiddleware(&awsCfg)
+
+	sqsClient := sqs.NewFromConfig(awsCfg)
+	sqsClient.SendMessage(context.Background(), &sqs.SendMessageInput{
+		QueueUrl:    aws.String(server.URL + "/123456789012/MyQueue"),
+		MessageBody: aws.String("hello"),
+	})
+
+	spans := mt.FinishedSpans()
+	assert.Len(t, spans, 1)
+	s := spans[0]
+	assert.Equal(t, "SQS.SendMessage MyQueue", s.Tag(ext.ResourceName))
+	assert.Equal(t, "MyQueue", s.Tag(tagSQSQueueName))
+	assert.Equal(t, 0.0, s.Tag(tagAWSRetryCount))
+}
+
+func TestAppendMiddleware_RetryCount(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Amz-RequestId", "test_req")
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	awsCfg := mockAWSConfig(server.URL)
+	awsCfg.Retryer = func() aws.Retryer {
+		return retry.NewStandard(func(o *retry.StandardOptions) {
+			o.Backoff = retry.BackoffDelayerFunc(func(int, error) (time.Duration, error) {
+				return 0, nil
+			})
+		})
+	}
+	AppendMiddleware(&awsCfg)
+
+	sqsClient := sqs.NewFromConfig(awsCfg)
+	sqsClient.ListQueues(context.Background(), &sqs.ListQueuesInput{})
+
+	spans := mt.FinishedSpans()
+	assert.Len(t, spans, 1)
+	s := spans[0]
+	assert.EqualValues(t, 2, atomic.LoadInt32(&calls))
+	assert.Equal(t, 1.0, s.Tag(tagAWSRetryCount))
+	assert.Equal(t, 200, s.Tag(ext.HTTPCode))
+}
+
+func TestResourceTag(t *testing.T) {
+	type s3Input struct{ Bucket *string }
+	type dynamoDBInput struct{ TableName *string }
+	type sqsInput struct{ QueueUrl, QueueName *string }
+	for _, tt := range []struct {
+		service string
+		params  interface{}
+		tag     string
+		name    string
+	}{
+		{"S3", &s3Input{Bucket: aws.String("my-bucket")}, tagS3BucketName, "my-bucket"},
+		{"S3", &s3Input{}, tagS3BucketName, ""},
+		{"DynamoDB", &dynamoDBInput{TableName: aws.String("my-table")}, tagDynamoDBTableName, "my-table"},
+		{"SQS", &sqsInput{QueueUrl: aws.String("https://sqs.eu-west-1.amazonaws.com/123/my-queue")}, tagSQSQueueName, "my-queue"},
+		{"SQS", &sqsInput{QueueName: aws.String("my-queue")}, tagSQSQueueName, "my-queue"},
+		{"SQS", nil, tagSQSQueueName, ""},
+		{"SQS", &s3Input{Bucket: aws.String("my-bucket")}, tagSQSQueueName, ""},
+		{"SQS", (*sqsInput)(nil), tagSQSQueueName, ""},
+		{"SNS", &s3Input{Bucket: aws.String("my-bucket")}, "", ""},
+	} {
+		tag, name := resourceTag(tt.service, tt.params)
+		assert.Equal(t, tt.tag, tag)
+		assert.Equal(t, tt.name, name)
+	}
+}
+
+func mockAWSConfig(url string) aws.Config {
+	return aws.Config{
+		Region:      "eu-west-1",
+		Credentials: aws.AnonymousCredentials{},
+		EndpointResolver: aws.EndpointResolverFunc(func(service, region string) (aws.Endpoint, error) {
+			return aws.Endpoint{
+				PartitionID:   "aws",
+				URL:           url,
+				SigningRegion: "eu-west-1",
+			}, nil
+		}),
+	}
+}
+
 func mockAWS(statusCode int) *httptest.Server {
 	return httptest.NewServer(http.HandlerFunc(
 		func(w http.ResponseWriter, r *http.Request) {
@@ -10,6 +10,7 @@ import (
 	"math"
 	"strconv"
 
+	"gopkg.in/DataDog/dd-trace-go.v1/contrib/internal/httptrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
@@ -19,6 +20,8 @@ import (
 	"github.com/aws/aws-sdk-go/aws/session"
 )
 
+const componentName = "aws"
+
 const (
 	tagAWSAgent      = "aws.agent"
 	tagAWSOperation  = "aws.operation"
@@ -61,6 +64,7 @@ func (h *handlers) Send(req *request.Request) {
 		return
 	}
 	opts := []ddtrace.StartSpanOption{
+		tracer.Tag(ext.Component, componentName),
 		tracer.SpanType(ext.SpanTypeHTTP),
 		tracer.ServiceName(h.serviceName(req)),
 		tracer.ResourceName(h.resourceName(req)),
@@ -68,7 +72,7 @@ func (h *handlers) Send(req *request.Request) {
 		tracer.Tag(tagAWSOperation, h.awsOperation(req)),
 		tracer.Tag(tagAWSRegion, h.awsRegion(req)),
 		tracer.Tag(ext.HTTPMethod, req.Operation.HTTPMethod),
-		tracer.Tag(ext.HTTPURL, req.HTTPRequest.URL.String()),
+		tracer.Tag(ext.HTTPURL, httptrace.URLTag(req.HTTPRequest.URL)),
 	}
 	if !math.IsNaN(h.cfg.analyticsRate) {
 		opts = append(opts, tracer.Tag(ext.EventSampleRate, h.cfg.analyticsRate))
@@ -82,7 +86,11 @@ func (h *handlers) Complete(req *request.Request) {
 	if !ok {
 		return
 	}
-	span.SetTag(tagAWSRetryCount, req.RetryCount)
+	if s, ok := span.(ddtrace.SpanWithMetrics); ok {
+		s.SetMetric(tagAWSRetryCount, float64(req.RetryCount))
+	} else {
+		span.SetTag(tagAWSRetryCount, float64(req.RetryCount))
+	}
 	span.SetTag(tagAWSRequestID, req.RequestID)
 	if req.HTTPResponse != nil {
 		span.SetTag(ext.HTTPCode, strconv.Itoa(req.HTTPResponse.StatusCode))
@@ -9,8 +9,10 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/sqs"
 
 	awstrace "gopkg.in/DataDog/dd-trace-go.v1/contrib/aws/aws-sdk-go/aws"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
 )
 
 // To start tracing requests, wrap the AWS session.Session by invoking
@@ -25,3 +27,39 @@ func Example() {
 		Bucket: aws.String("some-bucket-name"),
 	})
 }
+
+// To continue the trace of the producer of an SQS message in its consumer,
+// inject the trace context into the message attributes and extract it on receipt.
+func ExampleInjectSQS() {
+	sess := awstrace.WrapSession(session.Must(session.NewSession(aws.NewConfig().WithRegion("us-west-2"))))
+	sqsapi := sqs.New(sess)
+	queueURL := aws.String("https://sqs.us-west-2.amazonaws.com/123456789012/queue")
+
+	span := tracer.StartSpan("send.message")
+	defer span.Finish()
+	attrs := make(map[string]*sqs.MessageAttributeValue)
+	awstrace.InjectSQS(span.Context(), attrs)
+	sqsapi.SendMessage(&sqs.SendMessageInput{
+		QueueUrl:          queueURL,
+		MessageBody:       aws.String("hello"),
+		MessageAttributes: attrs,
+	})
+
+	// in the consumer
+	out, err := sqsapi.ReceiveMessage(&sqs.ReceiveMessageInput{
+		QueueUrl:              queueURL,
+		MessageAttributeNames: aws.StringSlice([]string{awstrace.TraceContextAttribute}),
+	})
+	if err != nil {
+		return
+	}
+	for _, msg := range out.Messages {
+		var opts []tracer.StartSpanOption
+		if sctx, err := awstrace.ExtractSQS(msg.MessageAttributes); err == nil {
+			opts = append(opts, tracer.ChildOf(sctx))
+		}
+		span := tracer.StartSpan("process.message", opts...)
+		// process the message
+		span.Finish()
+	}
+}
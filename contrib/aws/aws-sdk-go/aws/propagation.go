@@ -0,0 +1,146 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package aws
+
+import (
+	"encoding/json"
+	"errors"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// TraceContextAttribute is the name of the message attribute holding the trace
+// context of the SQS and SNS messages, as a JSON object mapping the propagation
+// headers to their values.
+const TraceContextAttribute = "tracecontext"
+
+// maxMessageAttributes is the maximum number of attributes of the SQS and SNS messages.
+const maxMessageAttributes = 10
+
+// ErrTooManyMessageAttributes is returned when injecting a trace context into
+// message attributes already holding the maximum number of attributes allowed
+// by SQS and SNS.
+var ErrTooManyMessageAttributes = errors.New("too many message attributes to inject the trace context")
+
+// A MessageAttributesCarrier injects and extracts traces from the propagation
+// headers held by the TraceContextAttribute of a message. It is the JSON
+// representation of the attribute value, so that it can also be decoded from
+// the attributes of the messages received by other means, e.g. in a Lambda
+// function handling SQS events.
+type MessageAttributesCarrier map[string]string
+
+var _ interface {
+	tracer.TextMapReader
+	tracer.TextMapWriter
+} = (MessageAttributesCarrier)(nil)
+
+// ForeachKey iterates over every propagation header.
+func (c MessageAttributesCarrier) ForeachKey(handler func(key, val string) error) error {
+	for k, v := range c {
+		if err := handler(k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Set sets a propagation header.
+func (c MessageAttributesCarrier) Set(key, val string) {
+	c[key] = val
+}
+
+// InjectSQS injects the given span context into the TraceContextAttribute of the
+// given SQS message attributes, which must not be nil. It returns
+// ErrTooManyMessageAttributes when there is no room left for the attribute.
+func InjectSQS(ctx ddtrace.SpanContext, attrs map[string]*sqs.MessageAttributeValue) error {
+	if _, ok := attrs[TraceContextAttribute]; !ok && len(attrs) >= maxMessageAttributes {
+		return ErrTooManyMessageAttributes
+	}
+	value, err := injectTraceContext(ctx)
+	if err != nil {
+		return err
+	}
+	attrs[TraceContextAttribute] = &sqs.MessageAttributeValue{
+		DataType:    aws.String("String"),
+		StringValue: aws.String(value),
+	}
+	return nil
+}
+
+// ExtractSQS extracts the span context held by the TraceContextAttribute of the
+// given SQS message attributes. It returns tracer.ErrSpanContextNotFound when
+// there is none.
+func ExtractSQS(attrs map[string]*sqs.MessageAttributeValue) (ddtrace.SpanContext, error) {
+	attr, ok := attrs[TraceContextAttribute]
+	if !ok || attr == nil {
+		return nil, tracer.ErrSpanContextNotFound
+	}
+	// SNS messages delivered to SQS hold the attribute as a binary value.
+	return extractTraceContext(attr.StringValue, attr.BinaryValue)
+}
+
+// InjectSNS injects the given span context into the TraceContextAttribute of the
+// given SNS message attributes, which must not be nil. It returns
+// ErrTooManyMessageAttributes when there is no room left for the attribute.
+func InjectSNS(ctx ddtrace.SpanContext, attrs map[string]*sns.MessageAttributeValue) error {
+	if _, ok := attrs[TraceContextAttribute]; !ok && len(attrs) >= maxMessageAttributes {
+		return ErrTooManyMessageAttributes
+	}
+	value, err := injectTraceContext(ctx)
+	if err != nil {
+		return err
+	}
+	attrs[TraceContextAttribute] = &sns.MessageAttributeValue{
+		DataType:    aws.String("String"),
+		StringValue: aws.String(value),
+	}
+	return nil
+}
+
+// ExtractSNS extracts the span context held by the TraceContextAttribute of the
+// given SNS message attributes. It returns tracer.ErrSpanContextNotFound when
+// there is none.
+func ExtractSNS(attrs map[string]*sns.MessageAttributeValue) (ddtrace.SpanContext, error) {
+	attr, ok := attrs[TraceContextAttribute]
+	if !ok || attr == nil {
+		return nil, tracer.ErrSpanContextNotFound
+	}
+	return extractTraceContext(attr.StringValue, attr.BinaryValue)
+}
+
+// injectTraceContext returns the value of the TraceContextAttribute holding the given span context.
+func injectTraceContext(ctx ddtrace.SpanContext) (string, error) {
+	carrier := make(MessageAttributesCarrier)
+	if err := tracer.Inject(ctx, carrier); err != nil {
+		return "", err
+	}
+	value, err := json.Marshal(carrier)
+	if err != nil {
+		return "", err
+	}
+	return string(value), nil
+}
+
+// extractTraceContext returns the span context held by the TraceContextAttribute
+// of the given string or binary value.
+func extractTraceContext(s *string, b []byte) (ddtrace.SpanContext, error) {
+	if s != nil {
+		b = []byte(*s)
+	}
+	if len(b) == 0 {
+		return nil, tracer.ErrSpanContextNotFound
+	}
+	var carrier MessageAttributesCarrier
+	if err := json.Unmarshal(b, &carrier); err != nil {
+		return nil, tracer.ErrSpanContextCorrupted
+	}
+	return tracer.Extract(carrier)
+}
@@ -0,0 +1,108 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package aws
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/stretchr/testify/assert"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+)
+
+func TestSQSPropagation(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	span := tracer.StartSpan("sqs.send")
+	attrs := map[string]*sqs.MessageAttributeValue{
+		"key": {DataType: aws.String("String"), StringValue: aws.String("value")},
+	}
+	assert.NoError(t, InjectSQS(span.Context(), attrs))
+	assert.Len(t, attrs, 2)
+	assert.Equal(t, "String", *attrs[TraceContextAttribute].DataType)
+
+	sctx, err := ExtractSQS(attrs)
+	assert.NoError(t, err)
+	assert.Equal(t, span.Context().TraceID(), sctx.TraceID())
+	assert.Equal(t, span.Context().SpanID(), sctx.SpanID())
+
+	t.Run("binary", func(t *testing.T) {
+		// SNS messages delivered to SQS hold the attribute as a binary value
+		attrs := map[string]*sqs.MessageAttributeValue{
+			TraceContextAttribute: {
+				DataType:    aws.String("Binary"),
+				BinaryValue: []byte(*attrs[TraceContextAttribute].StringValue),
+			},
+		}
+		sctx, err := ExtractSQS(attrs)
+		assert.NoError(t, err)
+		assert.Equal(t, span.Context().SpanID(), sctx.SpanID())
+	})
+
+	t.Run("not-found", func(t *testing.T) {
+		_, err := ExtractSQS(nil)
+		assert.Equal(t, tracer.ErrSpanContextNotFound, err)
+	})
+
+	t.Run("corrupted", func(t *testing.T) {
+		_, err := ExtractSQS(map[string]*sqs.MessageAttributeValue{
+			TraceContextAttribute: {DataType: aws.String("String"), StringValue: aws.String("{")},
+		})
+		assert.Equal(t, tracer.ErrSpanContextCorrupted, err)
+	})
+
+	t.Run("too-many-attributes", func(t *testing.T) {
+		attrs := make(map[string]*sqs.MessageAttributeValue)
+		for i := 0; i < maxMessageAttributes; i++ {
+			attrs[strconv.Itoa(i)] = &sqs.MessageAttributeValue{DataType: aws.String("String"), StringValue: aws.String("v")}
+		}
+		assert.Equal(t, ErrTooManyMessageAttributes, InjectSQS(span.Context(), attrs))
+		assert.Len(t, attrs, maxMessageAttributes)
+	})
+}
+
+func TestSNSPropagation(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	span := tracer.StartSpan("sns.publish")
+	attrs := make(map[string]*sns.MessageAttributeValue)
+	assert.NoError(t, InjectSNS(span.Context(), attrs))
+
+	sctx, err := ExtractSNS(attrs)
+	assert.NoError(t, err)
+	assert.Equal(t, span.Context().TraceID(), sctx.TraceID())
+	assert.Equal(t, span.Context().SpanID(), sctx.SpanID())
+
+	_, err = ExtractSNS(map[string]*sns.MessageAttributeValue{})
+	assert.Equal(t, tracer.ErrSpanContextNotFound, err)
+}
+
+func TestMessageAttributesCarrier(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	span := tracer.StartSpan("sqs.send")
+	carrier := make(MessageAttributesCarrier)
+	assert.NoError(t, tracer.Inject(span.Context(), carrier))
+
+	// the carrier is decoded from the attribute value of messages received
+	// by other means than the SDK
+	value, err := json.Marshal(carrier)
+	assert.NoError(t, err)
+	var decoded MessageAttributesCarrier
+	assert.NoError(t, json.Unmarshal(value, &decoded))
+	sctx, err := tracer.Extract(decoded)
+	assert.NoError(t, err)
+	assert.Equal(t, span.Context().SpanID(), sctx.SpanID())
+}
@@ -21,8 +21,11 @@ import (
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/namingschema"
 )
 
+const componentName = "memcache"
+
 // WrapClient wraps a memcache.Client so that all requests are traced using the
 // default tracer with the service name "memcached".
 func WrapClient(client *memcache.Client, opts ...ClientOption) *Client {
@@ -66,6 +69,7 @@ func (c *Client) WithContext(ctx context.Context) *Client {
 // startSpan starts a span from the context set with WithContext.
 func (c *Client) startSpan(resourceName string) ddtrace.Span {
 	opts := []ddtrace.StartSpanOption{
+		tracer.Tag(ext.Component, componentName),
 		tracer.SpanType(ext.SpanTypeMemcached),
 		tracer.ServiceName(c.cfg.serviceName),
 		tracer.ResourceName(resourceName),
@@ -73,7 +77,7 @@ func (c *Client) startSpan(resourceName string) ddtrace.Span {
 	if !math.IsNaN(c.cfg.analyticsRate) {
 		opts = append(opts, tracer.Tag(ext.EventSampleRate, c.cfg.analyticsRate))
 	}
-	span, _ := tracer.StartSpanFromContext(c.context, operationName, opts...)
+	span, _ := tracer.StartSpanFromContext(c.context, namingschema.CacheOp("memcached", operationName), opts...)
 	return span
 }
 
@@ -5,9 +5,14 @@
 
 package pubsub
 
+import (
+	"gopkg.in/DataDog/dd-trace-go.v1/internal"
+)
+
 type config struct {
-	serviceName string
-	measured    bool
+	serviceName        string
+	measured           bool
+	dataStreamsEnabled bool
 }
 
 // A Option is used to customize spans started by WrapReceiveHandler or Publish.
@@ -16,6 +21,16 @@ type Option func(cfg *config)
 // A ReceiveOption has been deprecated in favor of Option.
 type ReceiveOption = Option
 
+func newConfig(opts ...Option) *config {
+	cfg := &config{
+		dataStreamsEnabled: internal.BoolEnv("DD_DATA_STREAMS_ENABLED", false),
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
 // WithServiceName sets the service name tag for traces started by WrapReceiveHandler or Publish.
 func WithServiceName(serviceName string) Option {
 	return func(cfg *config) {
@@ -29,3 +44,14 @@ func WithMeasured() Option {
 		cfg.measured = true
 	}
 }
+
+// WithDataStreams enables the Data Streams Monitoring product features: a
+// checkpoint is set on the pathways of the messages published with Publish and
+// received by the handlers wrapped with WrapReceiveHandler, which is propagated
+// through their attributes. It can also be enabled with the
+// DD_DATA_STREAMS_ENABLED environment variable.
+func WithDataStreams() Option {
+	return func(cfg *config) {
+		cfg.dataStreamsEnabled = true
+	}
+}
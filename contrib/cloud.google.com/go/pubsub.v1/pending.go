@@ -0,0 +1,69 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package pubsub
+
+import (
+	"sync"
+
+	"cloud.google.com/go/pubsub"
+)
+
+// maxPendingResults is the maximum number of pending results tracked per topic,
+// which bounds the number of links of the pubsub.flush spans.
+const maxPendingResults = 1000
+
+// pendingResults holds the results of the publish calls which are not completed
+// yet, per topic, in order to link their spans to the flush spans started by Stop.
+var pendingResults = &pendingResultSet{
+	byTopic: make(map[*pubsub.Topic]map[*PublishResult]struct{}),
+}
+
+type pendingResultSet struct {
+	mu      sync.Mutex
+	byTopic map[*pubsub.Topic]map[*PublishResult]struct{}
+}
+
+// add adds r to the pending results of its topic, unless the topic already has
+// maxPendingResults of them.
+func (s *pendingResultSet) add(r *PublishResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	results, ok := s.byTopic[r.topic]
+	if !ok {
+		results = make(map[*PublishResult]struct{})
+		s.byTopic[r.topic] = results
+	}
+	if len(results) < maxPendingResults {
+		results[r] = struct{}{}
+	}
+}
+
+// remove removes r from the pending results of its topic.
+func (s *pendingResultSet) remove(r *PublishResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	results, ok := s.byTopic[r.topic]
+	if !ok {
+		return
+	}
+	delete(results, r)
+	if len(results) == 0 {
+		delete(s.byTopic, r.topic)
+	}
+}
+
+// take removes and returns the pending results of the topic t.
+func (s *pendingResultSet) take(t *pubsub.Topic) []*PublishResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	results := s.byTopic[t]
+	delete(s.byTopic, t)
+	taken := make([]*PublishResult, 0, len(results))
+	for r := range results {
+		taken = append(taken, r)
+	}
+	return taken
+}
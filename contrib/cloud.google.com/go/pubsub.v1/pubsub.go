@@ -4,6 +4,11 @@
 // Copyright 2016 Datadog, Inc.
 
 // Package pubsub provides functions to trace the cloud.google.com/pubsub/go package.
+//
+// The messages are published in batches by the topics. Publish starts a span
+// for each published message, which lasts until its batch is sent, and Stop
+// traces the sending of the pending batches within a span linked to the spans
+// of the messages it sends.
 package pubsub
 
 import (
@@ -13,11 +18,15 @@ import (
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/datastreams"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/namingschema"
 
 	"cloud.google.com/go/pubsub"
 )
 
+const componentName = "pubsub"
+
 // Publish publishes a message on the specified topic and returns a PublishResult.
 // This function is functionally equivalent to t.Publish(ctx, msg), but it also starts a publish
 // span and it ensures that the tracing metadata is propagated as attributes attached to
@@ -25,14 +34,11 @@ import (
 // It is required to call (*PublishResult).Get(ctx) on the value returned by Publish to complete
 // the span.
 func Publish(ctx context.Context, t *pubsub.Topic, msg *pubsub.Message, opts ...Option) *PublishResult {
-	var cfg config
-	for _, opt := range opts {
-		opt(&cfg)
-	}
+	cfg := newConfig(opts...)
 	spanOpts := []ddtrace.StartSpanOption{
+		tracer.Tag(ext.Component, componentName),
 		tracer.ResourceName(t.String()),
 		tracer.SpanType(ext.SpanTypeMessageProducer),
-		tracer.Tag("message_size", len(msg.Data)),
 		tracer.Tag("ordering_key", msg.OrderingKey),
 	}
 	if cfg.serviceName != "" {
@@ -43,27 +49,57 @@ func Publish(ctx context.Context, t *pubsub.Topic, msg *pubsub.Message, opts ...
 	}
 	span, ctx := tracer.StartSpanFromContext(
 		ctx,
-		"pubsub.publish",
+		namingschema.MessagingOutboundOp("gcp.pubsub", "pubsub.publish"),
 		spanOpts...,
 	)
+	setMetric(span, "message_size", float64(len(msg.Data)))
 	if msg.Attributes == nil {
 		msg.Attributes = make(map[string]string)
 	}
 	if err := tracer.Inject(span.Context(), tracer.TextMapCarrier(msg.Attributes)); err != nil {
 		log.Debug("contrib/cloud.google.com/go/pubsub.v1/: failed injecting tracing attributes: %v", err)
 	}
+	if cfg.dataStreamsEnabled {
+		setPublishCheckpoint(ctx, t, msg)
+	}
 	span.SetTag("num_attributes", len(msg.Attributes))
-	return &PublishResult{
+	r := &PublishResult{
 		PublishResult: t.Publish(ctx, msg),
 		span:          span,
+		topic:         t,
 	}
+	pendingResults.add(r)
+	return r
+}
+
+// setPublishCheckpoint sets a Data Streams Monitoring checkpoint on the pathway
+// propagated by msg, or else by ctx, if any, or on a new one, and injects the
+// updated pathway into the attributes of msg so subscribers can pick it up.
+func setPublishCheckpoint(ctx context.Context, t *pubsub.Topic, msg *pubsub.Message) {
+	edges := []string{"direction:out", "topic:" + t.ID(), "type:google-pubsub"}
+	carrier := tracer.TextMapCarrier(msg.Attributes)
+	ctx, ok := tracer.SetDataStreamsCheckpoint(datastreams.ExtractFromCarrier(ctx, carrier), edges...)
+	if !ok {
+		return
+	}
+	datastreams.InjectToCarrier(ctx, carrier)
+}
+
+// setReceiveCheckpoint sets a Data Streams Monitoring checkpoint on the pathway
+// propagated by msg and returns a copy of ctx holding the updated pathway, so
+// that the messages published by the handler continue it.
+func setReceiveCheckpoint(ctx context.Context, s *pubsub.Subscription, msg *pubsub.Message) context.Context {
+	edges := []string{"direction:in", "topic:" + s.ID(), "type:google-pubsub"}
+	ctx, _ = tracer.SetDataStreamsCheckpoint(datastreams.ExtractFromCarrier(ctx, tracer.TextMapCarrier(msg.Attributes)), edges...)
+	return ctx
 }
 
 // PublishResult wraps *pubsub.PublishResult
 type PublishResult struct {
 	*pubsub.PublishResult
-	once sync.Once
-	span tracer.Span
+	once  sync.Once
+	span  tracer.Span
+	topic *pubsub.Topic
 }
 
 // Get wraps (pubsub.PublishResult).Get(ctx). When this function returns the publish
@@ -71,27 +107,56 @@ type PublishResult struct {
 func (r *PublishResult) Get(ctx context.Context) (string, error) {
 	serverID, err := r.PublishResult.Get(ctx)
 	r.once.Do(func() {
+		pendingResults.remove(r)
 		r.span.SetTag("server_id", serverID)
 		r.span.Finish(tracer.WithError(err))
 	})
 	return serverID, err
 }
 
+// Stop sends all the messages published on t which are pending and stops t, as
+// t.Stop does, within a pubsub.flush span which is linked to the spans of the
+// publish calls of the messages which were still pending, up to 1000 of them.
+func Stop(ctx context.Context, t *pubsub.Topic, opts ...Option) {
+	cfg := newConfig(opts...)
+	pending := pendingResults.take(t)
+	links := make([]ddtrace.SpanLink, 0, len(pending))
+	for _, r := range pending {
+		links = append(links, ddtrace.SpanLink{
+			TraceID: r.span.Context().TraceID(),
+			SpanID:  r.span.Context().SpanID(),
+		})
+	}
+	spanOpts := []ddtrace.StartSpanOption{
+		tracer.Tag(ext.Component, componentName),
+		tracer.ResourceName(t.String()),
+		tracer.SpanType(ext.SpanTypeMessageProducer),
+		tracer.Tag("num_messages", len(pending)),
+		tracer.WithSpanLinks(links),
+	}
+	if cfg.serviceName != "" {
+		spanOpts = append(spanOpts, tracer.ServiceName(cfg.serviceName))
+	}
+	if cfg.measured {
+		spanOpts = append(spanOpts, tracer.Measured())
+	}
+	span, _ := tracer.StartSpanFromContext(ctx, "pubsub.flush", spanOpts...)
+	t.Stop()
+	span.Finish()
+}
+
 // WrapReceiveHandler returns a receive handler that wraps the supplied handler,
 // extracts any tracing metadata attached to the received message, and starts a
 // receive span.
 func WrapReceiveHandler(s *pubsub.Subscription, f func(context.Context, *pubsub.Message), opts ...Option) func(context.Context, *pubsub.Message) {
-	var cfg config
-	for _, opt := range opts {
-		opt(&cfg)
-	}
+	cfg := newConfig(opts...)
 	log.Debug("contrib/cloud.google.com/go/pubsub.v1: Wrapping Receive Handler: %#v", cfg)
 	return func(ctx context.Context, msg *pubsub.Message) {
 		parentSpanCtx, _ := tracer.Extract(tracer.TextMapCarrier(msg.Attributes))
 		opts := []ddtrace.StartSpanOption{
+			tracer.Tag(ext.Component, componentName),
 			tracer.ResourceName(s.String()),
 			tracer.SpanType(ext.SpanTypeMessageConsumer),
-			tracer.Tag("message_size", len(msg.Data)),
 			tracer.Tag("num_attributes", len(msg.Attributes)),
 			tracer.Tag("ordering_key", msg.OrderingKey),
 			tracer.Tag("message_id", msg.ID),
@@ -104,11 +169,25 @@ func WrapReceiveHandler(s *pubsub.Subscription, f func(context.Context, *pubsub.
 		if cfg.measured {
 			opts = append(opts, tracer.Measured())
 		}
-		span, ctx := tracer.StartSpanFromContext(ctx, "pubsub.receive", opts...)
+		span, ctx := tracer.StartSpanFromContext(ctx, namingschema.MessagingInboundOp("gcp.pubsub", "pubsub.receive"), opts...)
+		setMetric(span, "message_size", float64(len(msg.Data)))
 		if msg.DeliveryAttempt != nil {
 			span.SetTag("delivery_attempt", *msg.DeliveryAttempt)
 		}
+		if cfg.dataStreamsEnabled {
+			ctx = setReceiveCheckpoint(ctx, s, msg)
+		}
 		defer span.Finish()
 		f(ctx, msg)
 	}
 }
+
+// setMetric sets the given metric on span, or a tag holding its value when the
+// span can't hold metrics.
+func setMetric(span ddtrace.Span, key string, value float64) {
+	if s, ok := span.(ddtrace.SpanWithMetrics); ok {
+		s.SetMetric(key, value)
+		return
+	}
+	span.SetTag(key, value)
+}
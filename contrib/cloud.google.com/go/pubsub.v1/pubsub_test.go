@@ -63,7 +63,8 @@ func TestPropagation(t *testing.T) {
 	assert.Equal(spans[1].SpanID(), spans[0].ParentID())
 	assert.Equal(uint64(42), spans[0].TraceID())
 	assert.Equal(map[string]interface{}{
-		"message_size":   5,
+		"message_size":   5.0,
+		ext.Component:    "pubsub",
 		"num_attributes": 2, // 2 tracing attributes
 		"ordering_key":   "xxx",
 		ext.ResourceName: "projects/project/topics/topic",
@@ -76,7 +77,8 @@ func TestPropagation(t *testing.T) {
 	assert.Equal(uint64(42), spans[2].TraceID())
 	assert.Equal(spanID, spans[2].SpanID())
 	assert.Equal(map[string]interface{}{
-		"message_size":   5,
+		"message_size":   5.0,
+		ext.Component:    "pubsub",
 		"num_attributes": 2,
 		"ordering_key":   "xxx",
 		ext.ResourceName: "projects/project/subscriptions/subscription",
@@ -149,7 +151,8 @@ func TestPropagationNoParentSpan(t *testing.T) {
 	assert.Equal(spans[0].TraceID(), spans[0].SpanID())
 	assert.Equal(traceID, spans[0].TraceID())
 	assert.Equal(map[string]interface{}{
-		"message_size":   5,
+		"message_size":   5.0,
+		ext.Component:    "pubsub",
 		"num_attributes": 2,
 		"ordering_key":   "xxx",
 		ext.ResourceName: "projects/project/topics/topic",
@@ -161,7 +164,8 @@ func TestPropagationNoParentSpan(t *testing.T) {
 	assert.Equal(traceID, spans[1].TraceID())
 	assert.Equal(spanID, spans[1].SpanID())
 	assert.Equal(map[string]interface{}{
-		"message_size":   5,
+		"message_size":   5.0,
+		ext.Component:    "pubsub",
 		"num_attributes": 2,
 		"ordering_key":   "xxx",
 		ext.ResourceName: "projects/project/subscriptions/subscription",
@@ -211,7 +215,8 @@ func TestPropagationNoPubsliherSpan(t *testing.T) {
 	assert.Equal(traceID, spans[0].TraceID())
 	assert.Equal(spanID, spans[0].SpanID())
 	assert.Equal(map[string]interface{}{
-		"message_size":   5,
+		"message_size":   5.0,
+		ext.Component:    "pubsub",
 		"num_attributes": 0, // no attributes, since no publish middleware sent them
 		"ordering_key":   "xxx",
 		ext.ResourceName: "projects/project/subscriptions/subscription",
@@ -221,6 +226,37 @@ func TestPropagationNoPubsliherSpan(t *testing.T) {
 	}, spans[0].Tags())
 }
 
+func TestStop(t *testing.T) {
+	assert := assert.New(t)
+	ctx, topic, _, mt, cleanup := setup(t)
+	defer cleanup()
+
+	// keep the messages pending until the topic is stopped
+	topic.PublishSettings.DelayThreshold = time.Hour
+	topic.PublishSettings.CountThreshold = 100
+	r1 := Publish(ctx, topic, &pubsub.Message{Data: []byte("hello"), OrderingKey: "xxx"})
+	r2 := Publish(ctx, topic, &pubsub.Message{Data: []byte("world"), OrderingKey: "xxx"})
+	Stop(ctx, topic, WithServiceName("publisher"))
+	_, err := r1.Get(ctx)
+	assert.NoError(err)
+	_, err = r2.Get(ctx)
+	assert.NoError(err)
+
+	spans := mt.FinishedSpans()
+	assert.Len(spans, 3, "wrong number of spans")
+	flush := spans[0]
+	assert.Equal("pubsub.flush", flush.OperationName())
+	assert.Equal("publisher", flush.Tag(ext.ServiceName))
+	assert.Equal("projects/project/topics/topic", flush.Tag(ext.ResourceName))
+	assert.Equal(2, flush.Tag("num_messages"))
+	linked := make(map[uint64]bool)
+	for _, l := range flush.Links() {
+		linked[l.SpanID] = true
+	}
+	assert.Equal(map[uint64]bool{spans[1].SpanID(): true, spans[2].SpanID(): true}, linked)
+	assert.Empty(pendingResults.take(topic))
+}
+
 func setup(t *testing.T) (context.Context, *pubsub.Topic, *pubsub.Subscription, mocktracer.Tracer, func()) {
 	assert := assert.New(t)
 	mt := mocktracer.Start()
@@ -7,23 +7,32 @@
 package kafka // import "gopkg.in/DataDog/dd-trace-go.v1/contrib/confluentinc/confluent-kafka-go/kafka"
 
 import (
+	"context"
+	"hash/fnv"
 	"math"
+	"strconv"
+	"strings"
 	"time"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/datastreams"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/namingschema"
 
 	"github.com/confluentinc/confluent-kafka-go/kafka"
 )
 
+const componentName = "kafka"
+
 // NewConsumer calls kafka.NewConsumer and wraps the resulting Consumer.
 func NewConsumer(conf *kafka.ConfigMap, opts ...Option) (*Consumer, error) {
 	c, err := kafka.NewConsumer(conf)
 	if err != nil {
 		return nil, err
 	}
+	opts = append(opts, WithConfig(conf))
 	return WrapConsumer(c, opts...), nil
 }
 
@@ -91,6 +100,7 @@ func (c *Consumer) traceEventsChannel(in chan kafka.Event) chan kafka.Event {
 
 func (c *Consumer) startSpan(msg *kafka.Message) ddtrace.Span {
 	opts := []tracer.StartSpanOption{
+		tracer.Tag(ext.Component, componentName),
 		tracer.ServiceName(c.cfg.consumerServiceName),
 		tracer.ResourceName("Consume Topic " + *msg.TopicPartition.Topic),
 		tracer.SpanType(ext.SpanTypeMessageConsumer),
@@ -98,6 +108,9 @@ func (c *Consumer) startSpan(msg *kafka.Message) ddtrace.Span {
 		tracer.Tag("offset", msg.TopicPartition.Offset),
 		tracer.Measured(),
 	}
+	if len(msg.Key) > 0 {
+		opts = append(opts, tracer.Tag("message_key_hash", keyHash(msg.Key)))
+	}
 	if c.cfg.tagFns != nil {
 		for key, tagFn := range c.cfg.tagFns {
 			opts = append(opts, tracer.Tag(key, tagFn(msg)))
@@ -111,12 +124,30 @@ func (c *Consumer) startSpan(msg *kafka.Message) ddtrace.Span {
 	if spanctx, err := tracer.Extract(carrier); err == nil {
 		opts = append(opts, tracer.ChildOf(spanctx))
 	}
-	span, _ := tracer.StartSpanFromContext(c.cfg.ctx, "kafka.consume", opts...)
+	span, _ := tracer.StartSpanFromContext(c.cfg.ctx, namingschema.MessagingInboundOp("kafka", "kafka.consume"), opts...)
 	// reinject the span context so consumers can pick it up
 	tracer.Inject(span.Context(), carrier)
+	if c.cfg.dataStreamsEnabled {
+		setConsumeCheckpoint(c.cfg.groupID, msg)
+	}
 	return span
 }
 
+// setConsumeCheckpoint sets a Data Streams Monitoring checkpoint on the pathway
+// propagated by msg, and reinjects the updated pathway so consumers can pick it up.
+func setConsumeCheckpoint(groupID string, msg *kafka.Message) {
+	edges := []string{"direction:in", "topic:" + *msg.TopicPartition.Topic, "type:kafka"}
+	if groupID != "" {
+		edges = append(edges, "group:"+groupID)
+	}
+	carrier := NewMessageCarrier(msg)
+	ctx, ok := tracer.SetDataStreamsCheckpoint(datastreams.ExtractFromCarrier(context.Background(), carrier), edges...)
+	if !ok {
+		return
+	}
+	datastreams.InjectToCarrier(ctx, carrier)
+}
+
 // Close calls the underlying Consumer.Close and if polling is enabled, finishes
 // any remaining span.
 func (c *Consumer) Close() error {
@@ -165,6 +196,110 @@ func (c *Consumer) ReadMessage(timeout time.Duration) (*kafka.Message, error) {
 	return msg, nil
 }
 
+// Subscribe calls the underlying Consumer.Subscribe, tracing the calls to rebalanceCb.
+func (c *Consumer) Subscribe(topic string, rebalanceCb kafka.RebalanceCb) error {
+	return c.Consumer.Subscribe(topic, c.traceRebalanceCb(rebalanceCb))
+}
+
+// SubscribeTopics calls the underlying Consumer.SubscribeTopics, tracing the calls to rebalanceCb.
+func (c *Consumer) SubscribeTopics(topics []string, rebalanceCb kafka.RebalanceCb) error {
+	return c.Consumer.SubscribeTopics(topics, c.traceRebalanceCb(rebalanceCb))
+}
+
+// traceRebalanceCb returns a rebalance callback calling cb within a kafka.rebalance span, or nil when cb is nil.
+func (c *Consumer) traceRebalanceCb(cb kafka.RebalanceCb) kafka.RebalanceCb {
+	if cb == nil {
+		return nil
+	}
+	return func(consumer *kafka.Consumer, evt kafka.Event) error {
+		var (
+			resource   string
+			partitions []kafka.TopicPartition
+		)
+		switch evt := evt.(type) {
+		case kafka.AssignedPartitions:
+			resource, partitions = "Assign Partitions", evt.Partitions
+		case kafka.RevokedPartitions:
+			resource, partitions = "Revoke Partitions", evt.Partitions
+		default:
+			return cb(consumer, evt)
+		}
+		span, _ := tracer.StartSpanFromContext(c.cfg.ctx, "kafka.rebalance",
+			tracer.Tag(ext.Component, componentName),
+			tracer.ServiceName(c.cfg.consumerServiceName),
+			tracer.ResourceName(resource),
+			tracer.SpanType(ext.SpanTypeMessageConsumer),
+			tracer.Tag("partitions", formatTopicPartitions(partitions)),
+		)
+		err := cb(consumer, evt)
+		span.Finish(tracer.WithError(err))
+		return err
+	}
+}
+
+// Commit calls the underlying Consumer.Commit and traces the request.
+func (c *Consumer) Commit() ([]kafka.TopicPartition, error) {
+	span := c.startCommitSpan()
+	offsets, err := c.Consumer.Commit()
+	finishCommitSpan(span, offsets, err)
+	return offsets, err
+}
+
+// CommitMessage calls the underlying Consumer.CommitMessage and traces the request.
+func (c *Consumer) CommitMessage(msg *kafka.Message) ([]kafka.TopicPartition, error) {
+	span := c.startCommitSpan()
+	offsets, err := c.Consumer.CommitMessage(msg)
+	finishCommitSpan(span, offsets, err)
+	return offsets, err
+}
+
+// CommitOffsets calls the underlying Consumer.CommitOffsets and traces the request.
+func (c *Consumer) CommitOffsets(offsets []kafka.TopicPartition) ([]kafka.TopicPartition, error) {
+	span := c.startCommitSpan()
+	committed, err := c.Consumer.CommitOffsets(offsets)
+	finishCommitSpan(span, committed, err)
+	return committed, err
+}
+
+func (c *Consumer) startCommitSpan() ddtrace.Span {
+	opts := []tracer.StartSpanOption{
+		tracer.Tag(ext.Component, componentName),
+		tracer.ServiceName(c.cfg.consumerServiceName),
+		tracer.ResourceName("Commit Offsets"),
+		tracer.SpanType(ext.SpanTypeMessageConsumer),
+	}
+	if c.cfg.groupID != "" {
+		opts = append(opts, tracer.Tag("group_id", c.cfg.groupID))
+	}
+	span, _ := tracer.StartSpanFromContext(c.cfg.ctx, "kafka.commit", opts...)
+	return span
+}
+
+// finishCommitSpan tags span with the committed offsets and finishes it with err.
+func finishCommitSpan(span ddtrace.Span, offsets []kafka.TopicPartition, err error) {
+	if len(offsets) > 0 {
+		span.SetTag("offsets", formatTopicPartitions(offsets))
+	}
+	span.Finish(tracer.WithError(err))
+}
+
+// formatTopicPartitions returns the comma-separated list of the given partitions, formatted as topic[partition]@offset.
+func formatTopicPartitions(partitions []kafka.TopicPartition) string {
+	s := make([]string, len(partitions))
+	for i, p := range partitions {
+		s[i] = p.String()
+	}
+	return strings.Join(s, ",")
+}
+
+// keyHash returns the hexadecimal FNV-1a hash of the given message key, so that
+// messages with the same key can be correlated without tagging the key itself.
+func keyHash(key []byte) string {
+	h := fnv.New64a()
+	h.Write(key)
+	return strconv.FormatUint(h.Sum64(), 16)
+}
+
 // A Producer wraps a kafka.Producer.
 type Producer struct {
 	*kafka.Producer
@@ -202,11 +337,15 @@ func (p *Producer) traceProduceChannel(out chan *kafka.Message) chan *kafka.Mess
 
 func (p *Producer) startSpan(msg *kafka.Message) ddtrace.Span {
 	opts := []tracer.StartSpanOption{
+		tracer.Tag(ext.Component, componentName),
 		tracer.ServiceName(p.cfg.producerServiceName),
 		tracer.ResourceName("Produce Topic " + *msg.TopicPartition.Topic),
 		tracer.SpanType(ext.SpanTypeMessageProducer),
 		tracer.Tag("partition", msg.TopicPartition.Partition),
 	}
+	if len(msg.Key) > 0 {
+		opts = append(opts, tracer.Tag("message_key_hash", keyHash(msg.Key)))
+	}
 	if !math.IsNaN(p.cfg.analyticsRate) {
 		opts = append(opts, tracer.Tag(ext.EventSampleRate, p.cfg.analyticsRate))
 	}
@@ -216,12 +355,28 @@ func (p *Producer) startSpan(msg *kafka.Message) ddtrace.Span {
 		opts = append(opts, tracer.ChildOf(spanctx))
 	}
 
-	span, _ := tracer.StartSpanFromContext(p.cfg.ctx, "kafka.produce", opts...)
+	span, _ := tracer.StartSpanFromContext(p.cfg.ctx, namingschema.MessagingOutboundOp("kafka", "kafka.produce"), opts...)
 	// inject the span context so consumers can pick it up
 	tracer.Inject(span.Context(), carrier)
+	if p.cfg.dataStreamsEnabled {
+		setProduceCheckpoint(msg)
+	}
 	return span
 }
 
+// setProduceCheckpoint sets a Data Streams Monitoring checkpoint on the pathway
+// propagated by msg, if any, or on a new one, and injects the updated pathway so
+// consumers can pick it up.
+func setProduceCheckpoint(msg *kafka.Message) {
+	edges := []string{"direction:out", "topic:" + *msg.TopicPartition.Topic, "type:kafka"}
+	carrier := NewMessageCarrier(msg)
+	ctx, ok := tracer.SetDataStreamsCheckpoint(datastreams.ExtractFromCarrier(context.Background(), carrier), edges...)
+	if !ok {
+		return
+	}
+	datastreams.InjectToCarrier(ctx, carrier)
+}
+
 // Close calls the underlying Producer.Close and also closes the internal
 // wrapping producer channel.
 func (p *Producer) Close() {
@@ -244,6 +399,9 @@ func (p *Producer) Produce(msg *kafka.Message, deliveryChan chan kafka.Event) er
 			if msg, ok := evt.(*kafka.Message); ok {
 				// delivery errors are returned via TopicPartition.Error
 				err = msg.TopicPartition.Error
+				if err == nil {
+					span.SetTag("offset", msg.TopicPartition.Offset)
+				}
 			}
 			span.Finish(tracer.WithError(err))
 			oldDeliveryChan <- evt
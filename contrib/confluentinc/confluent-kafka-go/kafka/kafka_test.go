@@ -8,13 +8,18 @@ package kafka
 import (
 	"context"
 	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/datastreams"
 
 	"github.com/confluentinc/confluent-kafka-go/kafka"
 
@@ -85,9 +90,61 @@ func TestConsumerChannel(t *testing.T) {
 		assert.Equal(t, int32(1), s.Tag("partition"))
 		assert.Equal(t, 0.3, s.Tag(ext.EventSampleRate))
 		assert.Equal(t, kafka.Offset(i+1), s.Tag("offset"))
+		assert.Equal(t, keyHash([]byte(fmt.Sprintf("key%d", i+1))), s.Tag("message_key_hash"))
 	}
 }
 
+func TestRebalanceCb(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	c := &Consumer{cfg: newConfig(WithServiceName("kafka-test"))}
+	assert.Nil(t, c.traceRebalanceCb(nil))
+
+	cbErr := errors.New("rebalance error")
+	cb := c.traceRebalanceCb(func(_ *kafka.Consumer, evt kafka.Event) error {
+		if _, ok := evt.(kafka.RevokedPartitions); ok {
+			return cbErr
+		}
+		return nil
+	})
+	partitions := []kafka.TopicPartition{{Topic: &testTopic, Partition: 1, Offset: kafka.OffsetInvalid}}
+	assert.NoError(t, cb(nil, kafka.AssignedPartitions{Partitions: partitions}))
+	assert.Equal(t, cbErr, cb(nil, kafka.RevokedPartitions{Partitions: partitions}))
+
+	spans := mt.FinishedSpans()
+	assert.Len(t, spans, 2)
+	for i, resource := range []string{"Assign Partitions", "Revoke Partitions"} {
+		s := spans[i]
+		assert.Equal(t, "kafka.rebalance", s.OperationName())
+		assert.Equal(t, "kafka-test", s.Tag(ext.ServiceName))
+		assert.Equal(t, resource, s.Tag(ext.ResourceName))
+		assert.Equal(t, "gotest[1]@unset", s.Tag("partitions"))
+	}
+	assert.Nil(t, spans[0].Tag(ext.Error))
+	assert.Equal(t, cbErr, spans[1].Tag(ext.Error))
+}
+
+func TestCommitSpan(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	c := &Consumer{cfg: newConfig(WithConfig(&kafka.ConfigMap{"group.id": testGroupID}))}
+	offsets := []kafka.TopicPartition{
+		{Topic: &testTopic, Partition: 0, Offset: 3},
+		{Topic: &testTopic, Partition: 1, Offset: 5},
+	}
+	finishCommitSpan(c.startCommitSpan(), offsets, nil)
+
+	spans := mt.FinishedSpans()
+	assert.Len(t, spans, 1)
+	s := spans[0]
+	assert.Equal(t, "kafka.commit", s.OperationName())
+	assert.Equal(t, "Commit Offsets", s.Tag(ext.ResourceName))
+	assert.Equal(t, testGroupID, s.Tag("group_id"))
+	assert.Equal(t, "gotest[0]@3,gotest[1]@5", s.Tag("offsets"))
+}
+
 /*
 to run the integration test locally:
 
@@ -323,3 +380,28 @@ func TestCustomTags(t *testing.T) {
 	assert.Equal(t, "bar", s.Tag("foo"))
 	assert.Equal(t, []byte("key1"), s.Tag("key"))
 }
+
+func TestDataStreamsCheckpoints(t *testing.T) {
+	os.Setenv("DD_DATA_STREAMS_ENABLED", "true")
+	defer os.Unsetenv("DD_DATA_STREAMS_ENABLED")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/info" {
+			w.Write([]byte(`{"endpoints":["/v0.1/pipeline_stats"]}`))
+		}
+	}))
+	defer srv.Close()
+	tracer.Start(tracer.WithAgentAddr(strings.TrimPrefix(srv.URL, "http://")), tracer.WithLogStartup(false))
+	defer tracer.Stop()
+
+	msg := &kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &testTopic}}
+	setProduceCheckpoint(msg)
+	produced, ok := datastreams.PathwayFromContext(datastreams.ExtractFromCarrier(context.Background(), NewMessageCarrier(msg)))
+	assert.True(t, ok)
+
+	setConsumeCheckpoint(testGroupID, msg)
+	consumed, ok := datastreams.PathwayFromContext(datastreams.ExtractFromCarrier(context.Background(), NewMessageCarrier(msg)))
+	assert.True(t, ok)
+	assert.NotEqual(t, produced.GetHash(), consumed.GetHash())
+	assert.Equal(t, produced.PathwayStart(), consumed.PathwayStart())
+	assert.Len(t, msg.Headers, 1)
+}
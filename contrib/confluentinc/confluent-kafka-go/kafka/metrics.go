@@ -0,0 +1,116 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package kafka
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+// statsdClient is the subset of the statsd client used to report consumer lag metrics.
+type statsdClient interface {
+	Gauge(name string, value float64, tags []string, rate float64) error
+	Close() error
+}
+
+// offsetsSource is the subset of the kafka.Consumer methods used to compute the consumer lag.
+type offsetsSource interface {
+	Assignment() ([]kafka.TopicPartition, error)
+	Position([]kafka.TopicPartition) ([]kafka.TopicPartition, error)
+	GetWatermarkOffsets(topic string, partition int32) (low, high int64, err error)
+}
+
+// RecordConsumerLag starts a goroutine which reports the lag of every partition
+// assigned to c to Dogstatsd every interval, as the datadog.tracer.kafka.consumer.lag
+// metric tagged with the topic, partition and consumer group. The lag is the
+// difference between the high watermark offset of the partition, as cached by the
+// consumer on every fetch, and the current position of the consumer. The metrics are
+// sent to the same address as the runtime metrics of the tracer, so RecordConsumerLag
+// must be called after tracer.Start. It returns a function stopping the reporting.
+func RecordConsumerLag(c *Consumer, interval time.Duration) (stop func()) {
+	addr := globalconfig.DogstatsdAddr()
+	if addr == "" {
+		log.Warn("contrib/confluentinc/confluent-kafka-go/kafka: RecordConsumerLag called before tracer.Start; consumer lag metrics disabled")
+		return func() {}
+	}
+	client, err := statsd.New(addr)
+	if err != nil {
+		log.Warn("contrib/confluentinc/confluent-kafka-go/kafka: consumer lag metrics disabled: %v", err)
+		return func() {}
+	}
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer client.Close()
+		reportConsumerLag(client, c.Consumer, consumerLagTags(c.cfg), interval, done)
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			wg.Wait()
+		})
+	}
+}
+
+// consumerLagTags returns the tags common to the consumer lag metrics of a consumer with the given config.
+func consumerLagTags(cfg *config) []string {
+	tags := []string{"service:" + cfg.consumerServiceName}
+	if cfg.groupID != "" {
+		tags = append(tags, "consumer_group:"+cfg.groupID)
+	}
+	if env := globalconfig.Env(); env != "" {
+		tags = append(tags, "env:"+env)
+	}
+	return tags
+}
+
+// reportConsumerLag reports the lag of the partitions assigned to c every interval, until done is closed.
+func reportConsumerLag(client statsdClient, c offsetsSource, tags []string, interval time.Duration, done <-chan struct{}) {
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-tick.C:
+			assigned, err := c.Assignment()
+			if err != nil || len(assigned) == 0 {
+				continue
+			}
+			positions, err := c.Position(assigned)
+			if err != nil {
+				log.Debug("contrib/confluentinc/confluent-kafka-go/kafka: could not get the consumer position: %v", err)
+				continue
+			}
+			for _, p := range positions {
+				if p.Topic == nil || p.Offset < 0 {
+					// nothing consumed yet
+					continue
+				}
+				_, high, err := c.GetWatermarkOffsets(*p.Topic, p.Partition)
+				if err != nil || high < 0 {
+					continue
+				}
+				lag := high - int64(p.Offset)
+				if lag < 0 {
+					lag = 0
+				}
+				partitionTags := append(tags[:len(tags):len(tags)], "topic:"+*p.Topic, "partition:"+strconv.Itoa(int(p.Partition)))
+				client.Gauge("datadog.tracer.kafka.consumer.lag", float64(lag), partitionTags, 1)
+			}
+		case <-done:
+			return
+		}
+	}
+}
@@ -0,0 +1,84 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package kafka
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/stretchr/testify/assert"
+)
+
+type testStatsdClient struct {
+	mu     sync.Mutex
+	gauges map[string]float64
+}
+
+func (c *testStatsdClient) Gauge(name string, value float64, tags []string, _ float64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.gauges == nil {
+		c.gauges = make(map[string]float64)
+	}
+	key := name
+	for _, t := range tags {
+		key += "," + t
+	}
+	c.gauges[key] = value
+	return nil
+}
+
+func (c *testStatsdClient) Close() error { return nil }
+
+func (c *testStatsdClient) gauge(key string) (float64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.gauges[key]
+	return v, ok
+}
+
+// testOffsetsSource is an offsetsSource with two partitions: one at offset 7 out of 10
+// and one which was not consumed yet.
+type testOffsetsSource struct{}
+
+func (testOffsetsSource) Assignment() ([]kafka.TopicPartition, error) {
+	return []kafka.TopicPartition{{Topic: &testTopic, Partition: 0}, {Topic: &testTopic, Partition: 1}}, nil
+}
+
+func (testOffsetsSource) Position(partitions []kafka.TopicPartition) ([]kafka.TopicPartition, error) {
+	return []kafka.TopicPartition{
+		{Topic: &testTopic, Partition: 0, Offset: 7},
+		{Topic: &testTopic, Partition: 1, Offset: kafka.OffsetInvalid},
+	}, nil
+}
+
+func (testOffsetsSource) GetWatermarkOffsets(_ string, _ int32) (low, high int64, err error) {
+	return 0, 10, nil
+}
+
+func TestReportConsumerLag(t *testing.T) {
+	var client testStatsdClient
+	tags := consumerLagTags(newConfig(WithConfig(&kafka.ConfigMap{"group.id": testGroupID})))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		reportConsumerLag(&client, testOffsetsSource{}, tags, time.Millisecond, done)
+	}()
+	const key = "datadog.tracer.kafka.consumer.lag,service:kafka,consumer_group:gotest,topic:gotest,partition:0"
+	assert.Eventually(t, func() bool {
+		_, ok := client.gauge(key)
+		return ok
+	}, time.Second, time.Millisecond)
+	done <- struct{}{}
+
+	lag, _ := client.gauge(key)
+	assert.Equal(t, 3.0, lag)
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	assert.Len(t, client.gauges, 1)
+}
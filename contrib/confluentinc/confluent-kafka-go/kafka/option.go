@@ -11,6 +11,7 @@ import (
 
 	"gopkg.in/DataDog/dd-trace-go.v1/internal"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/namingschema"
 
 	"github.com/confluentinc/confluent-kafka-go/kafka"
 )
@@ -21,6 +22,8 @@ type config struct {
 	producerServiceName string
 	analyticsRate       float64
 	tagFns              map[string]func(msg *kafka.Message) interface{}
+	dataStreamsEnabled  bool
+	groupID             string
 }
 
 // An Option customizes the config.
@@ -30,13 +33,14 @@ func newConfig(opts ...Option) *config {
 	cfg := &config{
 		ctx:                 context.Background(),
 		consumerServiceName: "kafka",
-		producerServiceName: "kafka",
+		producerServiceName: namingschema.ServiceName("kafka"),
 		// analyticsRate: globalconfig.AnalyticsRate(),
 		analyticsRate: math.NaN(),
 	}
 	if internal.BoolEnv("DD_TRACE_KAFKA_ANALYTICS_ENABLED", false) {
 		cfg.analyticsRate = 1.0
 	}
+	cfg.dataStreamsEnabled = internal.BoolEnv("DD_DATA_STREAMS_ENABLED", false)
 	if svc := globalconfig.ServiceName(); svc != "" {
 		cfg.consumerServiceName = svc
 	}
@@ -96,3 +100,22 @@ func WithCustomTag(tag string, tagFn func(msg *kafka.Message) interface{}) Optio
 		cfg.tagFns[tag] = tagFn
 	}
 }
+
+// WithConfig extracts the config information for the client to be tagged.
+// NewConsumer applies it automatically.
+func WithConfig(cg *kafka.ConfigMap) Option {
+	return func(cfg *config) {
+		if groupID, err := cg.Get("group.id", ""); err == nil {
+			cfg.groupID, _ = groupID.(string)
+		}
+	}
+}
+
+// WithDataStreams enables the Data Streams Monitoring product features: a
+// checkpoint is set on the pathway of every produced and consumed message. It
+// is enabled by default when DD_DATA_STREAMS_ENABLED is set to true.
+func WithDataStreams() Option {
+	return func(cfg *config) {
+		cfg.dataStreamsEnabled = true
+	}
+}
@@ -7,8 +7,10 @@ package kafka
 
 import (
 	"math"
+	"os"
 	"testing"
 
+	"github.com/confluentinc/confluent-kafka-go/kafka"
 	"github.com/stretchr/testify/assert"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
@@ -44,3 +46,24 @@ func TestAnalyticsSettings(t *testing.T) {
 		assert.Equal(t, 0.2, cfg.analyticsRate)
 	})
 }
+
+func TestDataStreamsSettings(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		cfg := newConfig()
+		assert.False(t, cfg.dataStreamsEnabled)
+		assert.Empty(t, cfg.groupID)
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		cfg := newConfig(WithDataStreams(), WithConfig(&kafka.ConfigMap{"group.id": testGroupID}))
+		assert.True(t, cfg.dataStreamsEnabled)
+		assert.Equal(t, testGroupID, cfg.groupID)
+	})
+
+	t.Run("env", func(t *testing.T) {
+		os.Setenv("DD_DATA_STREAMS_ENABLED", "true")
+		defer os.Unsetenv("DD_DATA_STREAMS_ENABLED")
+		cfg := newConfig()
+		assert.True(t, cfg.dataStreamsEnabled)
+	})
+}
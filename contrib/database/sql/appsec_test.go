@@ -0,0 +1,104 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022 Datadog, Inc.
+
+package sql
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/contrib/database/sql/internal"
+	httptrace "gopkg.in/DataDog/dd-trace-go.v1/contrib/net/http"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec"
+)
+
+func TestAppSec(t *testing.T) {
+	t.Setenv("DD_APPSEC_RULES", "../../../internal/appsec/testdata/blocking.json")
+	appsec.Start()
+	defer appsec.Stop()
+
+	if !appsec.Enabled() {
+		t.Skip("appsec disabled")
+	}
+
+	d := &internal.MockDriver{}
+	Register("postgres", d)
+	defer unregister("postgres")
+	db, err := Open("postgres", "dn")
+	require.NoError(t, err)
+	defer db.Close()
+
+	mux := httptrace.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		rows, err := db.QueryContext(r.Context(), "SELECT * FROM users WHERE name = '"+r.URL.Query().Get("name")+"'")
+		if err != nil {
+			// the request was blocked, the blocking response is written by the middleware
+			return
+		}
+		rows.Close()
+		w.Write([]byte("Hello World!\n"))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	for _, tc := range []struct {
+		name    string
+		blocked bool
+	}{
+		{name: "bob"},
+		{name: "bob' OR '1'='1", blocked: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mt := mocktracer.Start()
+			defer mt.Stop()
+			d.Executed = nil
+
+			res, err := srv.Client().Get(srv.URL + "/?name=" + url.QueryEscape(tc.name))
+			require.NoError(t, err)
+			defer res.Body.Close()
+
+			var web mocktracer.Span
+			for _, s := range mt.FinishedSpans() {
+				if s.Tag(ext.SpanType) == ext.SpanTypeWeb {
+					web = s
+				}
+			}
+			require.NotNil(t, web)
+			assert.Equal(t, float64(1), web.Tag("_dd.appsec.rasp.rule.eval"))
+			if !tc.blocked {
+				assert.Equal(t, http.StatusOK, res.StatusCode)
+				assert.Len(t, d.Executed, 1)
+				assert.Nil(t, web.Tag("_dd.stack"))
+				return
+			}
+			// the query is not executed
+			assert.Equal(t, http.StatusForbidden, res.StatusCode)
+			assert.Empty(t, d.Executed)
+			assert.Equal(t, true, web.Tag("appsec.blocked"))
+			assert.NotNil(t, web.Tag("_dd.appsec.json"))
+			assert.Contains(t, web.Tag("_dd.stack"), "database/sql.(*DB).QueryContext")
+		})
+	}
+}
+
+func TestDBSystem(t *testing.T) {
+	for driverName, want := range map[string]string{
+		"postgres":  "postgresql",
+		"pgx":       "postgresql",
+		"mysql":     "mysql",
+		"sqlite3":   "sqlite",
+		"sqlserver": "mssql",
+		"other":     "other",
+	} {
+		assert.Equal(t, want, dbSystem(driverName), driverName)
+	}
+}
@@ -8,34 +8,63 @@ package sql // import "gopkg.in/DataDog/dd-trace-go.v1/contrib/database/sql"
 import (
 	"context"
 	"database/sql/driver"
+	"errors"
 	"fmt"
 	"math"
+	"strconv"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo/instrumentation/httpsec"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+
+	"github.com/DataDog/datadog-agent/pkg/obfuscate"
 )
 
 var _ driver.Conn = (*tracedConn)(nil)
 
-type queryType string
+// QueryType represents the different available traced db queries.
+type QueryType string
 
 const (
-	queryTypeConnect  queryType = "Connect"
-	queryTypeQuery              = "Query"
-	queryTypePing               = "Ping"
-	queryTypePrepare            = "Prepare"
-	queryTypeExec               = "Exec"
-	queryTypeBegin              = "Begin"
-	queryTypeClose              = "Close"
-	queryTypeCommit             = "Commit"
-	queryTypeRollback           = "Rollback"
+	// QueryTypeConnect is used for Connect traces.
+	QueryTypeConnect QueryType = "Connect"
+	// QueryTypeQuery is used for Query traces.
+	QueryTypeQuery QueryType = "Query"
+	// QueryTypePing is used for Ping traces.
+	QueryTypePing QueryType = "Ping"
+	// QueryTypePrepare is used for Prepare traces.
+	QueryTypePrepare QueryType = "Prepare"
+	// QueryTypeExec is used for Exec traces.
+	QueryTypeExec QueryType = "Exec"
+	// QueryTypeBegin is used for Begin traces.
+	QueryTypeBegin QueryType = "Begin"
+	// QueryTypeClose is used for Close traces.
+	QueryTypeClose QueryType = "Close"
+	// QueryTypeCommit is used for Commit traces.
+	QueryTypeCommit QueryType = "Commit"
+	// QueryTypeRollback is used for Rollback traces.
+	QueryTypeRollback QueryType = "Rollback"
 )
 
 const (
 	keyDBMTraceInjected = "_dd.dbm_trace_injected"
+	// keyQueryArgsPrefix prefixes the tags holding the arguments collected by WithCollectQueryArgs.
+	keyQueryArgsPrefix = "sql.args."
+	// keyQueryArgsDropped holds the number of arguments which were not collected because of maxQueryArgs.
+	keyQueryArgsDropped = "sql.args_dropped"
+)
+
+const (
+	// maxQueryArgs is the maximum number of arguments collected per call.
+	maxQueryArgs = 32
+	// maxQueryArgLength is the maximum length in bytes of a collected argument.
+	maxQueryArgLength = 256
 )
 
 type tracedConn struct {
@@ -47,14 +76,14 @@ func (tc *tracedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (tx dr
 	start := time.Now()
 	if connBeginTx, ok := tc.Conn.(driver.ConnBeginTx); ok {
 		tx, err = connBeginTx.BeginTx(ctx, opts)
-		tc.tryTrace(ctx, queryTypeBegin, "", start, err)
+		tc.tryTrace(ctx, QueryTypeBegin, "", start, err)
 		if err != nil {
 			return nil, err
 		}
 		return &tracedTx{tx, tc.traceParams, ctx}, nil
 	}
 	tx, err = tc.Conn.Begin()
-	tc.tryTrace(ctx, queryTypeBegin, "", start, err)
+	tc.tryTrace(ctx, QueryTypeBegin, "", start, err)
 	if err != nil {
 		return nil, err
 	}
@@ -63,22 +92,26 @@ func (tc *tracedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (tx dr
 
 func (tc *tracedConn) PrepareContext(ctx context.Context, query string) (stmt driver.Stmt, err error) {
 	start := time.Now()
-	mode := tc.cfg.commentInjectionMode
+	mode := tc.dbmPropagationMode(QueryTypePrepare, nil)
 	if mode == tracer.SQLInjectionModeFull {
 		// no context other than service in prepared statements
 		mode = tracer.SQLInjectionModeService
 	}
+	if err := tc.protect(ctx, query); err != nil {
+		tc.tryTrace(ctx, QueryTypePrepare, query, start, err)
+		return nil, err
+	}
 	cquery, spanID := tc.injectComments(ctx, query, mode)
 	if connPrepareCtx, ok := tc.Conn.(driver.ConnPrepareContext); ok {
 		stmt, err := connPrepareCtx.PrepareContext(ctx, cquery)
-		tc.tryTrace(ctx, queryTypePrepare, query, start, err, append(withDBMTraceInjectedTag(mode), tracer.WithSpanID(spanID))...)
+		tc.tryTrace(ctx, QueryTypePrepare, query, start, err, append(withDBMTraceInjectedTag(mode), tracer.WithSpanID(spanID))...)
 		if err != nil {
 			return nil, err
 		}
 		return &tracedStmt{Stmt: stmt, traceParams: tc.traceParams, ctx: ctx, query: query}, nil
 	}
 	stmt, err = tc.Prepare(cquery)
-	tc.tryTrace(ctx, queryTypePrepare, query, start, err, append(withDBMTraceInjectedTag(mode), tracer.WithSpanID(spanID))...)
+	tc.tryTrace(ctx, QueryTypePrepare, query, start, err, append(withDBMTraceInjectedTag(mode), tracer.WithSpanID(spanID))...)
 	if err != nil {
 		return nil, err
 	}
@@ -87,10 +120,16 @@ func (tc *tracedConn) PrepareContext(ctx context.Context, query string) (stmt dr
 
 func (tc *tracedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (r driver.Result, err error) {
 	start := time.Now()
+	mode := tc.dbmPropagationMode(QueryTypeExec, args)
+	if err := tc.protect(ctx, query); err != nil {
+		tc.tryTrace(ctx, QueryTypeExec, query, start, err)
+		return nil, err
+	}
 	if execContext, ok := tc.Conn.(driver.ExecerContext); ok {
-		cquery, spanID := tc.injectComments(ctx, query, tc.cfg.commentInjectionMode)
+		cquery, spanID := tc.injectComments(ctx, query, mode)
+		tc.propagate(ctx, spanID)
 		r, err := execContext.ExecContext(ctx, cquery, args)
-		tc.tryTrace(ctx, queryTypeExec, query, start, err, append(withDBMTraceInjectedTag(tc.cfg.commentInjectionMode), tracer.WithSpanID(spanID))...)
+		tc.tryTrace(ctx, QueryTypeExec, query, start, err, tc.queryOptions(mode, spanID, args)...)
 		return r, err
 	}
 	if execer, ok := tc.Conn.(driver.Execer); ok {
@@ -103,9 +142,10 @@ func (tc *tracedConn) ExecContext(ctx context.Context, query string, args []driv
 			return nil, ctx.Err()
 		default:
 		}
-		cquery, spanID := tc.injectComments(ctx, query, tc.cfg.commentInjectionMode)
+		cquery, spanID := tc.injectComments(ctx, query, mode)
+		tc.propagate(ctx, spanID)
 		r, err = execer.Exec(cquery, dargs)
-		tc.tryTrace(ctx, queryTypeExec, query, start, err, append(withDBMTraceInjectedTag(tc.cfg.commentInjectionMode), tracer.WithSpanID(spanID))...)
+		tc.tryTrace(ctx, QueryTypeExec, query, start, err, tc.queryOptions(mode, spanID, args)...)
 		return r, err
 	}
 	return nil, driver.ErrSkip
@@ -117,16 +157,22 @@ func (tc *tracedConn) Ping(ctx context.Context) (err error) {
 	if pinger, ok := tc.Conn.(driver.Pinger); ok {
 		err = pinger.Ping(ctx)
 	}
-	tc.tryTrace(ctx, queryTypePing, "", start, err)
+	tc.tryTrace(ctx, QueryTypePing, "", start, err)
 	return err
 }
 
 func (tc *tracedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (rows driver.Rows, err error) {
 	start := time.Now()
+	mode := tc.dbmPropagationMode(QueryTypeQuery, args)
+	if err := tc.protect(ctx, query); err != nil {
+		tc.tryTrace(ctx, QueryTypeQuery, query, start, err)
+		return nil, err
+	}
 	if queryerContext, ok := tc.Conn.(driver.QueryerContext); ok {
-		cquery, spanID := tc.injectComments(ctx, query, tc.cfg.commentInjectionMode)
+		cquery, spanID := tc.injectComments(ctx, query, mode)
+		tc.propagate(ctx, spanID)
 		rows, err := queryerContext.QueryContext(ctx, cquery, args)
-		tc.tryTrace(ctx, queryTypeQuery, query, start, err, append(withDBMTraceInjectedTag(tc.cfg.commentInjectionMode), tracer.WithSpanID(spanID))...)
+		tc.tryTrace(ctx, QueryTypeQuery, query, start, err, tc.queryOptions(mode, spanID, args)...)
 		return rows, err
 	}
 	if queryer, ok := tc.Conn.(driver.Queryer); ok {
@@ -139,9 +185,10 @@ func (tc *tracedConn) QueryContext(ctx context.Context, query string, args []dri
 			return nil, ctx.Err()
 		default:
 		}
-		cquery, spanID := tc.injectComments(ctx, query, tc.cfg.commentInjectionMode)
+		cquery, spanID := tc.injectComments(ctx, query, mode)
+		tc.propagate(ctx, spanID)
 		rows, err = queryer.Query(cquery, dargs)
-		tc.tryTrace(ctx, queryTypeQuery, query, start, err, append(withDBMTraceInjectedTag(tc.cfg.commentInjectionMode), tracer.WithSpanID(spanID))...)
+		tc.tryTrace(ctx, QueryTypeQuery, query, start, err, tc.queryOptions(mode, spanID, args)...)
 		return rows, err
 	}
 	return nil, driver.ErrSkip
@@ -165,11 +212,43 @@ func (tc *tracedConn) ResetSession(ctx context.Context) error {
 	return nil
 }
 
+// protect monitors query with AppSec, when enabled, in order to detect and block
+// the SQL injections before the query gets executed, at its call site (RASP). It
+// returns a non-nil error when the request executing the query was blocked, in
+// which case the query must not be executed.
+func (tp *traceParams) protect(ctx context.Context, query string) error {
+	if !appsec.Enabled() {
+		return nil
+	}
+	return httpsec.ProtectSQLOperation(ctx, query, dbSystem(tp.driverName))
+}
+
+// dbSystem returns the database system of the driver with the given name, as
+// expected by the AppSec address server.db.system, e.g. "postgresql" for the
+// "postgres" or "pgx" drivers.
+func dbSystem(driverName string) string {
+	switch driverName {
+	case "postgres", "pgx":
+		return "postgresql"
+	case "mysql":
+		return "mysql"
+	case "sqlite", "sqlite3":
+		return "sqlite"
+	case "sqlserver", "mssql":
+		return "mssql"
+	case "oracle", "godror", "oci8":
+		return "oracle"
+	}
+	return driverName
+}
+
 // traceParams stores all information related to tracing the driver.Conn
 type traceParams struct {
 	cfg        *config
 	driverName string
 	meta       map[string]string
+	// dbm holds the capabilities of the connection, detected when WithDBMPropagation is used.
+	dbm dbmCapabilities
 }
 
 type contextKey int
@@ -177,7 +256,8 @@ type contextKey int
 const spanTagsKey contextKey = 0 // map[string]string
 
 // WithSpanTags creates a new context containing the given set of tags. They will be added
-// to any query created with the returned context.
+// to any query created with the returned context. See tracer.ContextWithSpanTags to add
+// tags to all the spans started from a context.
 func WithSpanTags(ctx context.Context, tags map[string]string) context.Context {
 	return context.WithValue(ctx, spanTagsKey, tags)
 }
@@ -194,7 +274,8 @@ func (tc *tracedConn) injectComments(ctx context.Context, query string, mode tra
 	if span, ok := tracer.SpanFromContext(ctx); ok {
 		spanCtx = span.Context()
 	}
-	carrier := tracer.SQLCommentCarrier{Query: query, Mode: mode, DBServiceName: tc.cfg.serviceName}
+	carrier := tracer.SQLCommentCarrier{Query: query, Mode: mode, DBServiceName: tc.cfg.serviceName, SamplingMode: tc.cfg.commentSamplingMode}
+	tc.withPeerDB(&carrier)
 	if err := carrier.Inject(spanCtx); err != nil {
 		// this should never happen
 		log.Warn("contrib/database/sql: failed to inject query comments: %v", err)
@@ -210,8 +291,51 @@ func withDBMTraceInjectedTag(mode tracer.SQLCommentInjectionMode) []tracer.Start
 	return nil
 }
 
+// queryOptions returns the options of the span of a query executed with the given comment injection
+// mode, span ID and arguments.
+func (tc *tracedConn) queryOptions(mode tracer.SQLCommentInjectionMode, spanID uint64, args []driver.NamedValue) []tracer.StartSpanOption {
+	opts := append(withDBMTraceInjectedTag(mode), tracer.WithSpanID(spanID))
+	return append(opts, tc.withQueryArgs(args)...)
+}
+
+// withQueryArgs returns the options tagging a span with the given query arguments, when their
+// collection was enabled using WithCollectQueryArgs.
+func (tp *traceParams) withQueryArgs(args []driver.NamedValue) []tracer.StartSpanOption {
+	if tp.cfg.queryArgsRedactor == nil || len(args) == 0 {
+		return nil
+	}
+	n := len(args)
+	if n > maxQueryArgs {
+		n = maxQueryArgs
+	}
+	opts := make([]tracer.StartSpanOption, 0, n+1)
+	for _, arg := range args[:n] {
+		key := arg.Name
+		if key == "" {
+			key = strconv.Itoa(arg.Ordinal)
+		}
+		opts = append(opts, tracer.Tag(keyQueryArgsPrefix+key, truncateQueryArg(tp.cfg.queryArgsRedactor(arg))))
+	}
+	if dropped := len(args) - n; dropped > 0 {
+		opts = append(opts, tracer.Tag(keyQueryArgsDropped, dropped))
+	}
+	return opts
+}
+
+// truncateQueryArg truncates v to maxQueryArgLength bytes, without splitting UTF-8 characters.
+func truncateQueryArg(v string) string {
+	if len(v) <= maxQueryArgLength {
+		return v
+	}
+	i := maxQueryArgLength
+	for i > 0 && !utf8.RuneStart(v[i]) {
+		i--
+	}
+	return v[:i] + "..."
+}
+
 // tryTrace will create a span using the given arguments, but will act as a no-op when err is driver.ErrSkip.
-func (tp *traceParams) tryTrace(ctx context.Context, qtype queryType, query string, startTime time.Time, err error, spanOpts ...ddtrace.StartSpanOption) {
+func (tp *traceParams) tryTrace(ctx context.Context, qtype QueryType, query string, startTime time.Time, err error, spanOpts ...ddtrace.StartSpanOption) {
 	if err == driver.ErrSkip {
 		// Not a user error: driver is telling sql package that an
 		// optional interface method is not implemented. There is
@@ -224,6 +348,7 @@ func (tp *traceParams) tryTrace(ctx context.Context, qtype queryType, query stri
 	}
 	name := fmt.Sprintf("%s.query", tp.driverName)
 	opts := append(spanOpts,
+		tracer.Tag(ext.Component, componentName),
 		tracer.ServiceName(tp.cfg.serviceName),
 		tracer.SpanType(ext.SpanTypeSQL),
 		tracer.StartTime(startTime),
@@ -240,6 +365,9 @@ func (tp *traceParams) tryTrace(ctx context.Context, qtype queryType, query stri
 	resource := string(qtype)
 	if query != "" {
 		resource = query
+		if tp.cfg.queryObfuscation {
+			resource = obfuscateQuery(query)
+		}
 	}
 	span.SetTag("sql.query_type", string(qtype))
 	span.SetTag(ext.ResourceName, resource)
@@ -251,8 +379,65 @@ func (tp *traceParams) tryTrace(ctx context.Context, qtype queryType, query stri
 			span.SetTag(k, v)
 		}
 	}
-	if err != nil && (tp.cfg.errCheck == nil || tp.cfg.errCheck(err)) {
-		span.SetTag(ext.Error, err)
+	if deadline, ok := ctx.Deadline(); ok {
+		span.SetTag(keyDeadlineRemaining, float64(deadline.Sub(startTime))/float64(time.Millisecond))
+	}
+	if err != nil {
+		if cause := cancelCause(ctx, err); cause != "" {
+			span.SetTag(keyCancelCause, cause)
+		}
+		if tp.cfg.errCheck == nil || tp.cfg.errCheck(err) {
+			span.SetTag(ext.Error, err)
+		}
 	}
 	span.Finish()
 }
+
+const (
+	// keyCancelCause is the tag reporting why a call failed when it was
+	// interrupted by its context, as returned by cancelCause.
+	keyCancelCause = "db.cancel_cause"
+	// keyDeadlineRemaining is the tag holding the time, in milliseconds, which
+	// was left before the deadline of the context of a call when it started.
+	keyDeadlineRemaining = "db.deadline_remaining_ms"
+)
+
+// cancelCause returns "deadline_exceeded" when the call which failed with err
+// was interrupted because the deadline of ctx expired, "canceled" when ctx was
+// canceled, and "" otherwise. The drivers don't all return the errors of the
+// context when a call is interrupted, which is why the context is checked
+// when err isn't one of them.
+func cancelCause(ctx context.Context, err error) string {
+	if !errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, context.Canceled) {
+		err = ctx.Err()
+	}
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return "deadline_exceeded"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	default:
+		return ""
+	}
+}
+
+// textNonParsable is the resource name used for queries that can not be parsed by the obfuscator.
+const textNonParsable = "Non-parsable SQL query"
+
+var (
+	obfuscatorOnce sync.Once
+	obfuscator     *obfuscate.Obfuscator
+)
+
+// obfuscateQuery returns the given query with all literals replaced by placeholders.
+func obfuscateQuery(query string) string {
+	obfuscatorOnce.Do(func() {
+		obfuscator = obfuscate.NewObfuscator(obfuscate.Config{})
+	})
+	oq, err := obfuscator.ObfuscateSQLString(query)
+	if err != nil {
+		log.Debug("contrib/database/sql: failed to obfuscate query: %v", err)
+		return textNonParsable
+	}
+	return oq.Query
+}
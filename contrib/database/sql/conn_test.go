@@ -8,9 +8,12 @@ package sql
 import (
 	"context"
 	"database/sql/driver"
+	"errors"
+	"fmt"
 	"log"
 	"strings"
 	"testing"
+	"time"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
@@ -203,6 +206,60 @@ func TestWithErrorCheck(t *testing.T) {
 
 }
 
+func TestCancelCause(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	tp := &traceParams{cfg: new(config), driverName: "test"}
+	start := time.Now()
+	deadlineCtx, cancel := context.WithDeadline(context.Background(), start.Add(time.Second))
+	defer cancel()
+	canceledCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, tt := range []struct {
+		name      string
+		ctx       context.Context
+		err       error
+		cause     interface{}
+		remaining interface{}
+	}{
+		{
+			name:      "deadline",
+			ctx:       deadlineCtx,
+			err:       fmt.Errorf("query failed: %w", context.DeadlineExceeded),
+			cause:     "deadline_exceeded",
+			remaining: float64(1000),
+		},
+		{
+			name:  "canceled",
+			ctx:   canceledCtx,
+			err:   errors.New("pq: canceling statement due to user request"),
+			cause: "canceled",
+		},
+		{
+			name:      "other-error",
+			ctx:       deadlineCtx,
+			err:       errors.New("syntax error"),
+			remaining: float64(1000),
+		},
+		{
+			name: "no-error",
+			ctx:  canceledCtx,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			mt.Reset()
+			tp.tryTrace(tt.ctx, QueryTypeQuery, "SELECT 1", start, tt.err)
+
+			spans := mt.FinishedSpans()
+			require.Len(t, spans, 1)
+			assert.Equal(t, tt.cause, spans[0].Tag(keyCancelCause))
+			assert.Equal(t, tt.remaining, spans[0].Tag(keyDeadlineRemaining))
+		})
+	}
+}
+
 func TestWithCustomTag(t *testing.T) {
 	type sqlRegister struct {
 		name   string
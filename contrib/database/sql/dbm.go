@@ -0,0 +1,80 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package sql
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"strings"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+)
+
+// dbmCapabilities holds what a connection supports regarding the propagation of the trace context
+// through SQL comments, as detected when it is opened, see WithDBMPropagation.
+type dbmCapabilities struct {
+	// full reports whether the database supports the full propagation mode, which only the databases
+	// instrumented by Database Monitoring do.
+	full bool
+	// cachesStatements reports whether the driver implicitly prepares and caches the statements of
+	// the queries having arguments, keyed by their text, which the injection of the trace context
+	// would make unique, filling the cache of the driver and of the database.
+	cachesStatements bool
+}
+
+// detectDBMCapabilities returns the capabilities of the connection of the driver with the given name.
+func detectDBMCapabilities(driverName string, conn driver.Conn) dbmCapabilities {
+	t := reflect.TypeOf(conn)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return dbmCapabilitiesFor(dbSystem(driverName), t.PkgPath())
+}
+
+// dbmCapabilitiesFor returns the capabilities of the connections of the given database system,
+// implemented by the package with the given import path.
+func dbmCapabilitiesFor(system, pkgPath string) dbmCapabilities {
+	switch {
+	case strings.HasPrefix(pkgPath, "github.com/jackc/pgx/"):
+		// pgx prepares and caches the statements of the queries by default.
+		return dbmCapabilities{full: true, cachesStatements: true}
+	case strings.HasPrefix(pkgPath, "github.com/lib/pq"):
+		return dbmCapabilities{full: true}
+	case strings.HasPrefix(pkgPath, "github.com/go-sql-driver/mysql"):
+		return dbmCapabilities{full: true}
+	}
+	switch system {
+	case "postgresql", "mysql":
+		return dbmCapabilities{full: true}
+	}
+	return dbmCapabilities{}
+}
+
+// dbmPropagationMode returns the propagation mode to use for a query of the given type and arguments.
+// When the capabilities of the connection are detected, the full mode is downgraded to the service
+// mode for the connections which don't support it, and for the queries with arguments of the drivers
+// caching their statements.
+func (tc *tracedConn) dbmPropagationMode(qtype QueryType, args []driver.NamedValue) tracer.DBMPropagationMode {
+	mode := tc.cfg.commentInjectionModeFor(qtype)
+	if !tc.cfg.dbmDetection || mode != tracer.DBMPropagationModeFull {
+		return mode
+	}
+	if !tc.dbm.full || (len(args) > 0 && tc.dbm.cachesStatements) {
+		return tracer.DBMPropagationModeService
+	}
+	return mode
+}
+
+// withPeerDB sets the database host and name of the connection on carrier, when the capabilities of
+// the connection are detected.
+func (tp *traceParams) withPeerDB(carrier *tracer.SQLCommentCarrier) {
+	if !tp.cfg.dbmDetection {
+		return
+	}
+	carrier.PeerDBHostname = tp.meta[ext.TargetHost]
+	carrier.PeerDBName = tp.meta[ext.DBName]
+}
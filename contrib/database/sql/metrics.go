@@ -0,0 +1,130 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package sql
+
+import (
+	"database/sql"
+	"sync"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+)
+
+// statsdClient is the subset of the statsd client used to report connection pool metrics.
+type statsdClient interface {
+	Gauge(name string, value float64, tags []string, rate float64) error
+	Close() error
+}
+
+// defaultStatsInterval is the interval at which RecordStats reports the connection
+// pool metrics when given a non-positive one.
+const defaultStatsInterval = 10 * time.Second
+
+// StatsOption represents an option that can be passed to RecordStats.
+type StatsOption func(*statsConfig)
+
+// statsConfig holds the configuration of RecordStats.
+type statsConfig struct {
+	client statsd.ClientInterface
+}
+
+// WithStatsdClient sets the statsd client RecordStats reports the metrics with,
+// instead of a client to the Dogstatsd address of the tracer. The client is not
+// closed when the reporting stops.
+func WithStatsdClient(client statsd.ClientInterface) StatsOption {
+	return func(cfg *statsConfig) {
+		cfg.client = client
+	}
+}
+
+// RecordStats starts a goroutine which reports the connection pool statistics of db
+// to Dogstatsd every interval, as the datadog.tracer.sql.db.connections.* metrics,
+// tagged with the service and driver name of db. A non-positive interval defaults
+// to 10 seconds. It returns a function stopping the reporting.
+//
+// Unless WithStatsdClient is given, the metrics are sent to the Dogstatsd address
+// of the tracer, which is only known once tracer.Start has created the statsd
+// client of the tracer: RecordStats must then be called after tracer.Start, and
+// the metrics are disabled when the tracer was started with its own statsd client.
+func RecordStats(db *sql.DB, interval time.Duration, opts ...StatsOption) (stop func()) {
+	var cfg statsConfig
+	for _, fn := range opts {
+		fn(&cfg)
+	}
+	if interval <= 0 {
+		log.Warn("contrib/database/sql: invalid RecordStats interval %s, defaulting to %s", interval, defaultStatsInterval)
+		interval = defaultStatsInterval
+	}
+	var client statsdClient = cfg.client
+	if client == nil {
+		addr := globalconfig.DogstatsdAddr()
+		if addr == "" {
+			log.Warn("contrib/database/sql: no Dogstatsd address known, RecordStats must be called after tracer.Start; connection pool metrics disabled")
+			return func() {}
+		}
+		c, err := statsd.New(addr)
+		if err != nil {
+			log.Warn("contrib/database/sql: connection pool metrics disabled: %v", err)
+			return func() {}
+		}
+		client = c
+	}
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if cfg.client == nil {
+			defer client.Close()
+		}
+		reportDBStats(client, db, dbStatsTags(db), interval, done)
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			wg.Wait()
+		})
+	}
+}
+
+// dbStatsTags returns the tags of the connection pool metrics of db.
+func dbStatsTags(db *sql.DB) []string {
+	var tags []string
+	if name, ok := registeredDrivers.name(db.Driver()); ok {
+		tags = append(tags, "driver:"+name)
+		if cfg, ok := registeredDrivers.config(name); ok {
+			tags = append(tags, "service:"+cfg.serviceName)
+		}
+	}
+	if env := globalconfig.Env(); env != "" {
+		tags = append(tags, "env:"+env)
+	}
+	return tags
+}
+
+// reportDBStats reports the connection pool statistics of db every interval,
+// until done is closed.
+func reportDBStats(client statsdClient, db *sql.DB, tags []string, interval time.Duration, done <-chan struct{}) {
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-tick.C:
+			stats := db.Stats()
+			client.Gauge("datadog.tracer.sql.db.connections.open", float64(stats.OpenConnections), tags, 1)
+			client.Gauge("datadog.tracer.sql.db.connections.idle", float64(stats.Idle), tags, 1)
+			client.Gauge("datadog.tracer.sql.db.connections.in_use", float64(stats.InUse), tags, 1)
+			client.Gauge("datadog.tracer.sql.db.connections.wait_count", float64(stats.WaitCount), tags, 1)
+			client.Gauge("datadog.tracer.sql.db.connections.wait_duration", float64(stats.WaitDuration.Nanoseconds()), tags, 1)
+		case <-done:
+			return
+		}
+	}
+}
@@ -0,0 +1,116 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package sql
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/contrib/database/sql/internal"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testStatsdClient struct {
+	statsd.NoOpClient
+	mu     sync.Mutex
+	closed bool
+	gauges map[string]float64
+	tags   []string
+}
+
+func (c *testStatsdClient) Gauge(name string, value float64, tags []string, _ float64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.gauges == nil {
+		c.gauges = make(map[string]float64)
+	}
+	c.gauges[name] = value
+	c.tags = tags
+	return nil
+}
+
+func (c *testStatsdClient) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	return nil
+}
+
+func (c *testStatsdClient) gauge(name string) (float64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.gauges[name]
+	return v, ok
+}
+
+func TestRecordStats(t *testing.T) {
+	Register("test", &internal.MockDriver{}, WithServiceName("test-db"))
+	defer unregister("test")
+	db, err := Open("test", "dn")
+	require.NoError(t, err)
+	defer db.Close()
+	require.NoError(t, db.Ping())
+
+	t.Run("report", func(t *testing.T) {
+		globalconfig.SetEnv("test-env")
+		defer globalconfig.SetEnv("")
+
+		var client testStatsdClient
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			reportDBStats(&client, db, dbStatsTags(db), time.Millisecond, done)
+		}()
+		assert.Eventually(t, func() bool {
+			_, ok := client.gauge("datadog.tracer.sql.db.connections.wait_duration")
+			return ok
+		}, time.Second, time.Millisecond)
+		done <- struct{}{}
+
+		open, _ := client.gauge("datadog.tracer.sql.db.connections.open")
+		assert.Equal(t, 1.0, open)
+		idle, _ := client.gauge("datadog.tracer.sql.db.connections.idle")
+		assert.Equal(t, 1.0, idle)
+		inUse, _ := client.gauge("datadog.tracer.sql.db.connections.in_use")
+		assert.Equal(t, 0.0, inUse)
+		_, ok := client.gauge("datadog.tracer.sql.db.connections.wait_count")
+		assert.True(t, ok)
+		client.mu.Lock()
+		defer client.mu.Unlock()
+		assert.Equal(t, []string{"driver:test", "service:test-db", "env:test-env"}, client.tags)
+	})
+
+	t.Run("no-tracer", func(t *testing.T) {
+		stop := RecordStats(db, time.Millisecond)
+		stop()
+	})
+
+	t.Run("client", func(t *testing.T) {
+		var client testStatsdClient
+		stop := RecordStats(db, time.Millisecond, WithStatsdClient(&client))
+		assert.Eventually(t, func() bool {
+			_, ok := client.gauge("datadog.tracer.sql.db.connections.open")
+			return ok
+		}, time.Second, time.Millisecond)
+		stop()
+		stop()
+		client.mu.Lock()
+		defer client.mu.Unlock()
+		assert.False(t, client.closed)
+	})
+
+	t.Run("invalid-interval", func(t *testing.T) {
+		for _, interval := range []time.Duration{0, -time.Second} {
+			stop := RecordStats(db, interval, WithStatsdClient(&testStatsdClient{}))
+			stop()
+		}
+	})
+}
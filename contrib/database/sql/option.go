@@ -6,21 +6,36 @@
 package sql
 
 import (
+	"database/sql/driver"
+	"fmt"
 	"math"
 	"os"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
 )
 
 type config struct {
 	serviceName          string
+	driverName           string
 	analyticsRate        float64
 	dsn                  string
 	childSpansOnly       bool
 	errCheck             func(err error) bool
 	tags                 map[string]interface{}
 	commentInjectionMode tracer.SQLCommentInjectionMode
+	// commentSamplingMode sets how the sampling decision of the traces is injected into the comments.
+	commentSamplingMode tracer.SQLCommentSamplingMode
+	// dbmDetection enables the detection of the capabilities of the connections, see WithDBMPropagation.
+	dbmDetection bool
+	// commentInjectionDisabled holds the query types for which comment injection is disabled.
+	commentInjectionDisabled map[QueryType]bool
+	queryObfuscation         bool
+	// queryArgsRedactor converts query arguments to span tags; nil disables their collection.
+	queryArgsRedactor func(driver.NamedValue) string
+	// propagationCarrier propagates the trace context of the queries through their connection, if set.
+	propagationCarrier PropagationCarrier
 }
 
 // Option represents an option that can be passed to Register, Open or OpenDB.
@@ -39,7 +54,12 @@ func defaults(cfg *config) {
 	} else {
 		cfg.analyticsRate = math.NaN()
 	}
-	cfg.commentInjectionMode = tracer.SQLCommentInjectionMode(os.Getenv("DD_TRACE_SQL_COMMENT_INJECTION_MODE"))
+	if mode := os.Getenv("DD_DBM_PROPAGATION_MODE"); mode != "" {
+		cfg.commentInjectionMode = tracer.DBMPropagationMode(mode)
+		cfg.dbmDetection = true
+	} else {
+		cfg.commentInjectionMode = tracer.SQLCommentInjectionMode(os.Getenv("DD_TRACE_SQL_COMMENT_INJECTION_MODE"))
+	}
 }
 
 // WithServiceName sets the given service name when registering a driver,
@@ -82,6 +102,15 @@ func WithDSN(name string) Option {
 	}
 }
 
+// WithDriverName sets the name of the driver of the connector given to WrapConnector or OpenDB,
+// overriding its registered name, if any. It is used to parse the DSN, to name the spans
+// (<name>.query) and as the default service name (<name>.db).
+func WithDriverName(name string) Option {
+	return func(cfg *config) {
+		cfg.driverName = name
+	}
+}
+
 // WithChildSpansOnly causes spans to be created only when
 // there is an existing parent span in the Context.
 func WithChildSpansOnly() Option {
@@ -115,5 +144,105 @@ func WithCustomTag(key string, value interface{}) Option {
 func WithSQLCommentInjection(mode tracer.SQLCommentInjectionMode) Option {
 	return func(cfg *config) {
 		cfg.commentInjectionMode = mode
+		cfg.dbmDetection = false
+	}
+}
+
+// WithSQLCommentSamplingMode sets how the sampling decision of the traces is injected into the SQL
+// comments of the full mode, see WithSQLCommentInjection. With tracer.SQLCommentSamplingDeferred, the
+// queries are only marked as sampled once the sampling decision of their trace is final, rather than
+// whenever their trace is kept at the time they are executed. The sampling priority of the traces
+// is injected as well, once final, in both modes.
+func WithSQLCommentSamplingMode(mode tracer.SQLCommentSamplingMode) Option {
+	return func(cfg *config) {
+		cfg.commentSamplingMode = mode
+	}
+}
+
+// WithDBMPropagation enables the propagation of the trace context to Database Monitoring through SQL
+// comments injected into the traced queries, as WithSQLCommentInjection does, along with the host and
+// name of the database. Unlike WithSQLCommentInjection, the capabilities of each connection are detected
+// when it is opened, from its driver: DBMPropagationModeFull falls back to DBMPropagationModeService for
+// the databases which don't support it, and for the queries with arguments of the drivers which cache
+// their prepared statements by query text, such as pgx, as the injected trace context would make each
+// of them unique. Prepared statements are always injected with DBMPropagationModeService. It can also
+// be set with the DD_DBM_PROPAGATION_MODE environment variable, which takes precedence over
+// DD_TRACE_SQL_COMMENT_INJECTION_MODE.
+func WithDBMPropagation(mode tracer.DBMPropagationMode) Option {
+	return func(cfg *config) {
+		cfg.commentInjectionMode = mode
+		cfg.dbmDetection = true
+	}
+}
+
+// WithQueryObfuscation enables client-side obfuscation of the queries used as resource names.
+// Literals such as strings and numbers are replaced with "?" before the span is sent to the agent,
+// which prevents sensitive values from leaking when the agent runs with obfuscation disabled.
+func WithQueryObfuscation() Option {
+	return func(cfg *config) {
+		cfg.queryObfuscation = true
+	}
+}
+
+// WithCollectQueryArgs attaches the arguments of Exec and Query calls to their spans, as
+// "sql.args.<name>" tags, where name is the name of the argument or, for positional
+// arguments, its 1-based ordinal. Each argument is converted to a string by redactor,
+// which can be used to hash or redact sensitive values; when redactor is nil, the values
+// are tagged as is. At most 32 arguments are collected per call, and values longer than
+// 256 bytes are truncated. Query arguments often hold sensitive data: enable with care.
+func WithCollectQueryArgs(redactor func(driver.NamedValue) string) Option {
+	return func(cfg *config) {
+		if redactor == nil {
+			redactor = formatQueryArg
+		}
+		cfg.queryArgsRedactor = redactor
+	}
+}
+
+// WithPropagationCarrier propagates the trace context of the queries executed with Exec and Query
+// to the database using the given carrier, in addition to the SQL comments injected as configured
+// by WithSQLCommentInjection, e.g. PostgresApplicationNameCarrier to set the application_name of
+// the session. The carrier is called on the connection before executing each query, which may
+// require an additional round trip to the database. Statements prepared beforehand are not covered.
+func WithPropagationCarrier(carrier PropagationCarrier) Option {
+	return func(cfg *config) {
+		cfg.propagationCarrier = carrier
+	}
+}
+
+// formatQueryArg returns the value of the query argument, formatted as a string.
+func formatQueryArg(arg driver.NamedValue) string {
+	if b, ok := arg.Value.([]byte); ok {
+		return string(b)
+	}
+	return fmt.Sprint(arg.Value)
+}
+
+// WithCommentInjectionDisabledFor disables the injection of SQL comments for the given query types,
+// regardless of the mode set by WithSQLCommentInjection. Comments are only ever injected into
+// QueryTypePrepare, QueryTypeQuery and QueryTypeExec queries; other query types are ignored with a
+// warning. This is useful for drivers that cache prepared statements by query text, where the
+// injected comments would otherwise make each query unique.
+func WithCommentInjectionDisabledFor(queryTypes ...QueryType) Option {
+	return func(cfg *config) {
+		if cfg.commentInjectionDisabled == nil {
+			cfg.commentInjectionDisabled = make(map[QueryType]bool, len(queryTypes))
+		}
+		for _, qt := range queryTypes {
+			switch qt {
+			case QueryTypePrepare, QueryTypeQuery, QueryTypeExec:
+				cfg.commentInjectionDisabled[qt] = true
+			default:
+				log.Warn("contrib/database/sql: ignoring query type %q for WithCommentInjectionDisabledFor: comments are only injected into %s, %s and %s queries", qt, QueryTypePrepare, QueryTypeQuery, QueryTypeExec)
+			}
+		}
+	}
+}
+
+// commentInjectionModeFor returns the comment injection mode to use for the given query type.
+func (cfg *config) commentInjectionModeFor(qtype QueryType) tracer.SQLCommentInjectionMode {
+	if cfg.commentInjectionDisabled[qtype] {
+		return tracer.SQLInjectionDisabled
 	}
+	return cfg.commentInjectionMode
 }
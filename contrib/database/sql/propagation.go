@@ -0,0 +1,101 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package sql
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+)
+
+// A PropagationCarrier propagates the trace context of the queries to the database through the
+// connection executing them, e.g. by setting a session variable. Unlike SQL comments, which some
+// engines strip before logging the queries, session variables can be logged or queried on the
+// database side to correlate its activity with the traces.
+type PropagationCarrier interface {
+	// Propagate is called on the connection of the driver before it executes a query, with the
+	// context of the query and the trace context of its span, formatted as a W3C traceparent
+	// (see https://www.w3.org/TR/trace-context/#traceparent-header).
+	Propagate(ctx context.Context, conn driver.Conn, traceParent string) error
+}
+
+// PostgresApplicationNameCarrier is a PropagationCarrier for PostgreSQL, setting the
+// application_name of the session to the traceparent of the queries. It is reported by
+// pg_stat_activity and can be logged using %a in log_line_prefix.
+type PostgresApplicationNameCarrier struct{}
+
+// Propagate implements PropagationCarrier.
+func (PostgresApplicationNameCarrier) Propagate(ctx context.Context, conn driver.Conn, traceParent string) error {
+	return execSessionStatement(ctx, conn, "SET application_name = '"+strings.ReplaceAll(traceParent, "'", "''")+"'")
+}
+
+// execSessionStatement executes the given statement, with no arguments, on the given connection.
+func execSessionStatement(ctx context.Context, conn driver.Conn, query string) error {
+	if execer, ok := conn.(driver.ExecerContext); ok {
+		_, err := execer.ExecContext(ctx, query, nil)
+		if err != driver.ErrSkip {
+			return err
+		}
+	}
+	var (
+		stmt driver.Stmt
+		err  error
+	)
+	if preparer, ok := conn.(driver.ConnPrepareContext); ok {
+		stmt, err = preparer.PrepareContext(ctx, query)
+	} else {
+		stmt, err = conn.Prepare(query)
+	}
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	if execer, ok := stmt.(driver.StmtExecContext); ok {
+		_, err = execer.ExecContext(ctx, nil)
+		return err
+	}
+	_, err = stmt.Exec(nil)
+	return err
+}
+
+// propagate propagates the trace context of the query with the given context and span ID using the
+// PropagationCarrier set with WithPropagationCarrier, if any.
+func (tc *tracedConn) propagate(ctx context.Context, spanID uint64) {
+	if tc.cfg.propagationCarrier == nil {
+		return
+	}
+	var spanCtx ddtrace.SpanContext
+	if span, ok := tracer.SpanFromContext(ctx); ok {
+		spanCtx = span.Context()
+	}
+	if err := tc.cfg.propagationCarrier.Propagate(ctx, tc.Conn, traceParent(spanCtx, spanID)); err != nil {
+		log.Warn("contrib/database/sql: failed to propagate the trace context: %v", err)
+	}
+}
+
+// traceParent returns the W3C traceparent of the span with the given ID, child of the given span
+// context. When spanCtx is nil, the span is the root of its trace.
+func traceParent(spanCtx ddtrace.SpanContext, spanID uint64) string {
+	traceID := fmt.Sprintf("%032x", spanID)
+	var sampled int
+	if spanCtx != nil {
+		traceID = fmt.Sprintf("%032x", spanCtx.TraceID())
+		if ctx, ok := spanCtx.(interface{ TraceID128() string }); ok {
+			traceID = ctx.TraceID128()
+		}
+		if ctx, ok := spanCtx.(interface{ SamplingPriority() (int, bool) }); ok {
+			if p, ok := ctx.SamplingPriority(); ok && p > 0 {
+				sampled = 1
+			}
+		}
+	}
+	return fmt.Sprintf("00-%s-%016x-%02x", traceID, spanID, sampled)
+}
@@ -0,0 +1,112 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package sql
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/contrib/database/sql/internal"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+)
+
+// recordingCarrier records the trace contexts it propagates.
+type recordingCarrier struct {
+	traceParents []string
+}
+
+func (c *recordingCarrier) Propagate(_ context.Context, _ driver.Conn, traceParent string) error {
+	c.traceParents = append(c.traceParents, traceParent)
+	return nil
+}
+
+func TestPropagationCarrier(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	carrier := new(recordingCarrier)
+	d := &internal.MockDriver{}
+	Register("test", d, WithPropagationCarrier(carrier))
+	defer unregister("test")
+
+	db, err := Open("test", "dn")
+	require.NoError(t, err)
+	defer db.Close()
+
+	s, ctx := tracer.StartSpanFromContext(context.Background(), "test.call")
+	_, err = db.QueryContext(ctx, "SELECT 1 from DUAL")
+	require.NoError(t, err)
+	_, err = db.ExecContext(ctx, "SELECT 2 from DUAL")
+	require.NoError(t, err)
+	_, err = db.PrepareContext(ctx, "SELECT 3 from DUAL")
+	require.NoError(t, err)
+	s.Finish()
+
+	// the queries are executed as is, the trace context being propagated by the carrier
+	assert.Equal(t, []string{"SELECT 1 from DUAL", "SELECT 2 from DUAL"}, d.Executed)
+	spans := append(spansOfType(mt.FinishedSpans(), QueryTypeQuery), spansOfType(mt.FinishedSpans(), QueryTypeExec)...)
+	require.Len(t, spans, 2)
+	require.Len(t, carrier.traceParents, 2)
+	for i, span := range spans {
+		assert.Equal(t, fmt.Sprintf("00-%032x-%016x-00", s.Context().TraceID(), span.SpanID()), carrier.traceParents[i])
+	}
+
+	t.Run("open-db", func(t *testing.T) {
+		// the carrier of the registered driver is used unless overridden
+		override := new(recordingCarrier)
+		db := OpenDB(&mockConnector{driver: d}, WithPropagationCarrier(override))
+		defer db.Close()
+		_, err := db.ExecContext(context.Background(), "SELECT 4 from DUAL")
+		require.NoError(t, err)
+		assert.Len(t, carrier.traceParents, 2)
+		assert.Len(t, override.traceParents, 1)
+	})
+}
+
+func TestPostgresApplicationNameCarrier(t *testing.T) {
+	tracer.Start(tracer.WithService("test-service"))
+	defer tracer.Stop()
+
+	d := &internal.MockDriver{}
+	Register("test", d, WithPropagationCarrier(PostgresApplicationNameCarrier{}))
+	defer unregister("test")
+
+	db, err := Open("test", "dn")
+	require.NoError(t, err)
+	defer db.Close()
+
+	s, ctx := tracer.StartSpanFromContext(context.Background(), "test.call", tracer.WithSpanID(1))
+	_, err = db.ExecContext(ctx, "SELECT 1 from DUAL")
+	require.NoError(t, err)
+	s.Finish()
+
+	require.Len(t, d.Executed, 2)
+	assert.Regexp(t, "^SET application_name = '00-00000000000000000000000000000001-[\\da-f]{16}-01'$", d.Executed[0])
+	assert.Equal(t, "SELECT 1 from DUAL", d.Executed[1])
+}
+
+func TestTraceParent(t *testing.T) {
+	// queries with no parent span are the root of their trace
+	assert.Equal(t, "00-0000000000000000000000000000002a-000000000000002a-00", traceParent(nil, 42))
+
+	t.Run("128-bit", func(t *testing.T) {
+		tracer.Start(tracer.WithTraceID128Bit(true))
+		defer tracer.Stop()
+
+		s := tracer.StartSpan("test.call", tracer.Tag(ext.SamplingPriority, ext.PriorityUserKeep))
+		defer s.Finish()
+		ctx := s.Context().(interface{ TraceID128() string })
+		require.NotEqual(t, fmt.Sprintf("%032x", s.Context().TraceID()), ctx.TraceID128())
+		assert.Equal(t, "00-"+ctx.TraceID128()+"-000000000000002a-01", traceParent(s.Context(), 42))
+	})
+}
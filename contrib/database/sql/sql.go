@@ -21,6 +21,7 @@ import (
 	"database/sql/driver"
 	"errors"
 	"math"
+	"path"
 	"reflect"
 	"sync"
 	"time"
@@ -28,8 +29,11 @@ import (
 	"gopkg.in/DataDog/dd-trace-go.v1/contrib/database/sql/internal"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/namingschema"
 )
 
+const componentName = "sql"
+
 // registeredDrivers holds a registry of all drivers registered via the sqltrace package.
 var registeredDrivers = &driverRegistry{
 	keys:    make(map[reflect.Type]string),
@@ -121,7 +125,7 @@ func Register(driverName string, driver driver.Driver, opts ...RegisterOption) {
 		fn(cfg)
 	}
 	if cfg.serviceName == "" {
-		cfg.serviceName = driverName + ".db"
+		cfg.serviceName = namingschema.ServiceName(driverName + ".db")
 	}
 	log.Debug("contrib/database/sql: Registering driver: %s %#v", driverName, cfg)
 	registeredDrivers.add(driverName, driver, cfg)
@@ -156,10 +160,13 @@ func (t *tracedConnector) Connect(ctx context.Context) (driver.Conn, error) {
 	}
 	start := time.Now()
 	conn, err := t.connector.Connect(ctx)
-	tp.tryTrace(ctx, queryTypeConnect, "", start, err)
+	tp.tryTrace(ctx, QueryTypeConnect, "", start, err)
 	if err != nil {
 		return nil, err
 	}
+	if t.cfg.dbmDetection {
+		tp.dbm = detectDBMCapabilities(t.driverName, conn)
+	}
 	return &tracedConn{conn, tp}, err
 }
 
@@ -184,36 +191,87 @@ func (t dsnConnector) Driver() driver.Driver {
 // OpenDB returns connection to a DB using the traced version of the given driver. In order for OpenDB
 // to work, the driver must first be registered using Register. If this did not occur, OpenDB will panic.
 func OpenDB(c driver.Connector, opts ...Option) *sql.DB {
-	name, ok := registeredDrivers.name(c.Driver())
-	if !ok {
+	if _, ok := registeredDrivers.name(c.Driver()); !ok {
 		panic("sqltrace.OpenDB: driver is not registered via sqltrace.Register")
 	}
-	rc, _ := registeredDrivers.config(name)
+	return sql.OpenDB(newTracedConnector(c, opts...))
+}
+
+// WrapConnector returns a traced version of the given connector, to be opened with sql.OpenDB. Unlike
+// OpenDB, it does not require the driver of the connector to be registered using Register, which suits
+// the connectors that are constructed directly, such as the ones of proxied or Cloud SQL databases.
+// When the driver is registered, its registration options are used for the options that are not given.
+// Otherwise, the driver name defaults to the name of the package of the driver and can be set using
+// WithDriverName.
+func WrapConnector(c driver.Connector, opts ...Option) driver.Connector {
+	return newTracedConnector(c, opts...)
+}
+
+// newTracedConnector returns a traced version of the given connector, configured with the given options
+// along with the registration options of its driver, if it was registered.
+func newTracedConnector(c driver.Connector, opts ...Option) *tracedConnector {
 	cfg := new(config)
 	defaults(cfg)
 	for _, fn := range opts {
 		fn(cfg)
 	}
-	// use registered config for unset options
-	if cfg.serviceName == "" {
-		cfg.serviceName = rc.serviceName
-	}
-	if math.IsNaN(cfg.analyticsRate) {
-		cfg.analyticsRate = rc.analyticsRate
+	name, registered := registeredDrivers.name(c.Driver())
+	if registered {
+		rc, _ := registeredDrivers.config(name)
+		// use registered config for unset options
+		if cfg.serviceName == "" {
+			cfg.serviceName = rc.serviceName
+		}
+		if math.IsNaN(cfg.analyticsRate) {
+			cfg.analyticsRate = rc.analyticsRate
+		}
+		if cfg.errCheck == nil {
+			cfg.errCheck = rc.errCheck
+		}
+		if cfg.commentInjectionMode == tracer.SQLInjectionUndefined {
+			cfg.commentInjectionMode = rc.commentInjectionMode
+			cfg.dbmDetection = rc.dbmDetection
+		}
+		if cfg.commentSamplingMode == tracer.SQLCommentSamplingImmediate {
+			cfg.commentSamplingMode = rc.commentSamplingMode
+		}
+		if cfg.commentInjectionDisabled == nil {
+			cfg.commentInjectionDisabled = rc.commentInjectionDisabled
+		}
+		if !cfg.queryObfuscation {
+			cfg.queryObfuscation = rc.queryObfuscation
+		}
+		if cfg.queryArgsRedactor == nil {
+			cfg.queryArgsRedactor = rc.queryArgsRedactor
+		}
+		if cfg.propagationCarrier == nil {
+			cfg.propagationCarrier = rc.propagationCarrier
+		}
+		cfg.childSpansOnly = rc.childSpansOnly
 	}
-	if cfg.errCheck == nil {
-		cfg.errCheck = rc.errCheck
+	if cfg.driverName != "" {
+		name = cfg.driverName
+	} else if !registered {
+		name = driverPackageName(c.Driver())
 	}
-	if cfg.commentInjectionMode == tracer.SQLInjectionUndefined {
-		cfg.commentInjectionMode = rc.commentInjectionMode
+	if cfg.serviceName == "" {
+		cfg.serviceName = namingschema.ServiceName(name + ".db")
 	}
-	cfg.childSpansOnly = rc.childSpansOnly
-	tc := &tracedConnector{
+	return &tracedConnector{
 		connector:  c,
 		driverName: name,
 		cfg:        cfg,
 	}
-	return sql.OpenDB(tc)
+}
+
+// driverPackageName returns the name of the package defining the type of the given driver,
+// e.g. "pq" for github.com/lib/pq.
+func driverPackageName(d driver.Driver) string {
+	t := reflect.TypeOf(d)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return path.Base(t.PkgPath())
 }
 
 // Open returns connection to a DB using the traced version of the given driver. In order for Open
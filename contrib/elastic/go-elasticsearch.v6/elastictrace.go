@@ -23,6 +23,8 @@ import (
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
 )
 
+const componentName = "elastic"
+
 // NewRoundTripper returns a new http.Client which traces requests under the given service name.
 func NewRoundTripper(opts ...ClientOption) http.RoundTripper {
 	cfg := new(clientConfig)
@@ -51,6 +53,7 @@ func (t *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
 	method := req.Method
 	resource := t.config.resourceNamer(url, method)
 	opts := []ddtrace.StartSpanOption{
+		tracer.Tag(ext.Component, componentName),
 		tracer.ServiceName(t.config.serviceName),
 		tracer.SpanType(ext.SpanTypeElasticSearch),
 		tracer.ResourceName(resource),
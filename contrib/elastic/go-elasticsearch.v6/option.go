@@ -10,6 +10,7 @@ import (
 	"net/http"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/internal"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/namingschema"
 )
 
 type clientConfig struct {
@@ -23,7 +24,7 @@ type clientConfig struct {
 type ClientOption func(*clientConfig)
 
 func defaults(cfg *clientConfig) {
-	cfg.serviceName = "elastic.client"
+	cfg.serviceName = namingschema.ServiceName("elastic.client")
 	cfg.transport = http.DefaultTransport
 	cfg.resourceNamer = quantize
 	if internal.BoolEnv("DD_TRACE_ELASTIC_ANALYTICS_ENABLED", false) {
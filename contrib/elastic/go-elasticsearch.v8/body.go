@@ -0,0 +1,163 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package elastic
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// level describes an object or an array opened in the body being obfuscated.
+type level struct {
+	delim json.Delim // '{' or '['
+	n     int        // number of tokens read in it, keys included
+}
+
+// obfuscateBody returns the given JSON request body with all its values
+// replaced by "?", its keys being kept, e.g. the query DSL
+//
+//	{"query":{"match":{"user":"kimchy"}}}
+//
+// becomes {"query":{"match":{"user":"?"}}}. The NDJSON bodies of the bulk and
+// multi search APIs are obfuscated document by document. As the body may have
+// been truncated, the obfuscation stops at the first malformed token.
+func obfuscateBody(body string) string {
+	var (
+		out   strings.Builder
+		stack []level
+		docs  int
+	)
+	dec := json.NewDecoder(strings.NewReader(body))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			break
+		}
+		d, isDelim := tok.(json.Delim)
+		closing := isDelim && (d == '}' || d == ']')
+		key := false
+		switch {
+		case closing:
+		case len(stack) == 0:
+			// new document
+			if docs > 0 {
+				out.WriteByte('\n')
+			}
+			docs++
+		default:
+			l := &stack[len(stack)-1]
+			switch {
+			case l.delim == '{' && l.n%2 == 1:
+				out.WriteByte(':')
+			case l.n > 0:
+				out.WriteByte(',')
+			}
+			key = l.delim == '{' && l.n%2 == 0
+			l.n++
+		}
+		switch {
+		case isDelim:
+			out.WriteByte(byte(d))
+			if closing {
+				stack = stack[:len(stack)-1]
+			} else {
+				stack = append(stack, level{delim: d})
+			}
+		case key:
+			k, _ := json.Marshal(tok)
+			out.Write(k)
+		default:
+			out.WriteString(`"?"`)
+		}
+	}
+	if docs == 0 {
+		// not JSON
+		return "?"
+	}
+	return out.String()
+}
+
+// responseMetrics returns the metrics read from the given JSON response
+// body: the time Elasticsearch took to execute the request and the number of
+// documents found by a search or counted by a count request.
+func responseMetrics(body string) map[string]float64 {
+	dec := json.NewDecoder(strings.NewReader(body))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil
+	}
+	metrics := make(map[string]float64, 2)
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			break
+		}
+		switch tok {
+		case "took":
+			var took float64
+			err = dec.Decode(&took)
+			if err == nil {
+				metrics[tagTook] = took
+			}
+		case "count":
+			var count float64
+			err = dec.Decode(&count)
+			if err == nil {
+				metrics[tagDocCount] = count
+			}
+		case "hits":
+			if total, err := hitsTotal(dec); err == nil {
+				metrics[tagDocCount] = total
+			}
+			// the decoder is left within the hits, which come last
+			return metrics
+		default:
+			var skip json.RawMessage
+			err = dec.Decode(&skip)
+		}
+		if err != nil {
+			// the body may have been truncated
+			break
+		}
+	}
+	return metrics
+}
+
+// hitsTotal reads the total number of hits of a search from the hits object
+// the given decoder is positioned on. The total is an object holding it as
+// its value since Elasticsearch 7, a number before. It stops reading after it,
+// as the hits which follow may have been truncated.
+func hitsTotal(dec *json.Decoder) (float64, error) {
+	if tok, err := dec.Token(); err != nil {
+		return 0, err
+	} else if tok != json.Delim('{') {
+		return 0, errors.New("hits is not an object")
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return 0, err
+		}
+		var v json.RawMessage
+		if err := dec.Decode(&v); err != nil {
+			return 0, err
+		}
+		if tok != "total" {
+			continue
+		}
+		var total struct {
+			Value float64 `json:"value"`
+		}
+		if err := json.Unmarshal(v, &total.Value); err == nil {
+			return total.Value, nil
+		}
+		if err := json.Unmarshal(v, &total); err != nil {
+			return 0, err
+		}
+		return total.Value, nil
+	}
+	return 0, errors.New("hits total not found")
+}
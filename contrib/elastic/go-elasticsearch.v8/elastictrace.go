@@ -0,0 +1,201 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+// Package elastic provides functions to trace the github.com/elastic/go-elasticsearch/v8 package.
+//
+// The client is traced by giving it the round tripper returned by NewRoundTripper as its transport:
+//
+//	es, err := elasticsearch.NewClient(elasticsearch.Config{
+//		Transport: elastictrace.NewRoundTripper(elastictrace.WithServiceName("my-es-service")),
+//	})
+package elastic // import "gopkg.in/DataDog/dd-trace-go.v1/contrib/elastic/go-elasticsearch.v8"
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+)
+
+const componentName = "elastic"
+
+const (
+	// tagTook holds the time in milliseconds Elasticsearch took to execute
+	// the request, as reported in its response.
+	tagTook = "elasticsearch.took"
+	// tagDocCount holds the number of documents found by a search or
+	// counted by a count request.
+	tagDocCount = "elasticsearch.doc_count"
+)
+
+// NewRoundTripper returns a new http.RoundTripper which traces the Elasticsearch requests it sends.
+// It is meant to be given to the client as the Transport of its elasticsearch.Config.
+func NewRoundTripper(opts ...ClientOption) http.RoundTripper {
+	cfg := new(clientConfig)
+	defaults(cfg)
+	for _, fn := range opts {
+		fn(cfg)
+	}
+	return &roundTripper{config: *cfg}
+}
+
+// bodyCutoff specifies the maximum number of bytes that will be stored as a tag
+// value obtained from an HTTP request or response body.
+var bodyCutoff = 5 * 1024
+
+// roundTripper is an implementation of http.RoundTripper that captures Elasticsearch spans.
+type roundTripper struct {
+	config clientConfig
+}
+
+var _ http.RoundTripper = &roundTripper{}
+
+// RoundTrip satisfies the RoundTripper interface, wraps the sub Transport and
+// captures a span of the Elasticsearch request.
+func (t *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	url := req.URL.Path
+	method := req.Method
+	resource := t.config.resourceNamer(url, method)
+	opts := []ddtrace.StartSpanOption{
+		tracer.Tag(ext.Component, componentName),
+		tracer.ServiceName(t.config.serviceName),
+		tracer.SpanType(ext.SpanTypeElasticSearch),
+		tracer.ResourceName(resource),
+		tracer.Tag(ext.SpanKind, ext.SpanKindClient),
+		tracer.Tag(ext.TargetHost, req.URL.Hostname()),
+		tracer.Tag("elasticsearch.method", method),
+		tracer.Tag("elasticsearch.url", url),
+		tracer.Tag("elasticsearch.params", req.URL.Query().Encode()),
+	}
+	if !math.IsNaN(t.config.analyticsRate) {
+		opts = append(opts, tracer.Tag(ext.EventSampleRate, t.config.analyticsRate))
+	}
+	span, _ := tracer.StartSpanFromContext(req.Context(), "elasticsearch.query", opts...)
+	defer span.Finish()
+
+	snip, rc, err := peek(req.Body, req.Header.Get("Content-Encoding"), int(req.ContentLength), bodyCutoff)
+	if err == nil {
+		if t.config.obfuscateBody && snip != "" {
+			snip = obfuscateBody(snip)
+		}
+		span.SetTag("elasticsearch.body", snip)
+	}
+	req.Body = rc
+	// process using the standard transport
+	res, err := t.config.transport.RoundTrip(req)
+	if err != nil {
+		// roundtrip error
+		span.SetTag(ext.Error, err)
+	} else if res.StatusCode < 200 || res.StatusCode > 299 {
+		// HTTP error
+		snip, rc, err := peek(res.Body, res.Header.Get("Content-Encoding"), int(res.ContentLength), bodyCutoff)
+		if err != nil {
+			snip = http.StatusText(res.StatusCode)
+		}
+		span.SetTag(ext.Error, errors.New(snip))
+		res.Body = rc
+	} else if strings.Contains(res.Header.Get("Content-Type"), "json") {
+		snip, rc, err := peek(res.Body, res.Header.Get("Content-Encoding"), int(res.ContentLength), bodyCutoff)
+		if err == nil {
+			for k, v := range responseMetrics(snip) {
+				if s, ok := span.(ddtrace.SpanWithMetrics); ok {
+					s.SetMetric(k, v)
+				} else {
+					span.SetTag(k, v)
+				}
+			}
+		}
+		res.Body = rc
+	}
+	if res != nil {
+		span.SetTag(ext.HTTPCode, strconv.Itoa(res.StatusCode))
+	}
+	return res, err
+}
+
+var (
+	idRegexp    = regexp.MustCompile("^[0-9]+$")
+	indexRegexp = regexp.MustCompile("[0-9]{2,}")
+)
+
+// docEndpoints holds the endpoints which are followed by a document ID in the
+// request paths, e.g. /{index}/_doc/{id}.
+var docEndpoints = map[string]bool{
+	"_doc":         true,
+	"_create":      true,
+	"_update":      true,
+	"_source":      true,
+	"_explain":     true,
+	"_termvectors": true,
+}
+
+// quantize quantizes an Elasticsearch request to extract a meaningful resource from it.
+// We quantize based on the method+endpoint: the API endpoints (segments starting with
+// an underscore) are kept, document IDs are replaced by "?" as are the numbers of
+// (potential) timestamped indices.
+func quantize(url, method string) string {
+	segments := strings.Split(url, "/")
+	for i, s := range segments {
+		switch {
+		case strings.HasPrefix(s, "_"):
+			// API endpoint
+		case i > 0 && docEndpoints[segments[i-1]], idRegexp.MatchString(s):
+			segments[i] = "?"
+		default:
+			segments[i] = indexRegexp.ReplaceAllString(s, "?")
+		}
+	}
+	return method + " " + strings.Join(segments, "/")
+}
+
+// peek attempts to return the first n bytes, as a string, from the provided io.ReadCloser.
+// It returns a new io.ReadCloser which points to the same underlying stream and can be read
+// from to access the entire data including the snippet. max is used to specify the length
+// of the stream contained in the reader. If unknown, it should be -1. If 0 < max < n it
+// will override n.
+func peek(rc io.ReadCloser, encoding string, max, n int) (string, io.ReadCloser, error) {
+	if rc == nil || rc == http.NoBody {
+		return "", rc, errors.New("empty stream")
+	}
+	if max > 0 && max < n {
+		n = max
+	}
+	r := bufio.NewReaderSize(rc, n)
+	rc2 := struct {
+		io.Reader
+		io.Closer
+	}{
+		Reader: r,
+		Closer: rc,
+	}
+	snip, err := r.Peek(n)
+	if err == io.EOF {
+		err = nil
+	}
+	if err != nil {
+		return string(snip), rc2, err
+	}
+	if encoding == "gzip" {
+		// unpack the snippet
+		gzr, err2 := gzip.NewReader(bytes.NewReader(snip))
+		if err2 != nil {
+			// snip wasn't gzip; return it as is
+			return string(snip), rc2, nil
+		}
+		defer gzr.Close()
+		snip, err = io.ReadAll(gzr)
+	}
+	return string(snip), rc2, err
+}
@@ -0,0 +1,265 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package elastic
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
+)
+
+// newServer returns a server answering all requests with the given status
+// code and JSON body, after having checked their body was left intact.
+func newServer(t *testing.T, code int, body string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, r.Header.Get("X-Expected-Body"), string(b))
+		w.Header().Set("Content-Type", "application/vnd.elasticsearch+json; compatible-with=8")
+		w.WriteHeader(code)
+		fmt.Fprint(w, body)
+	}))
+}
+
+func doRequest(t *testing.T, rt http.RoundTripper, method, url, body string) string {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Expected-Body", body)
+	res, err := (&http.Client{Transport: rt}).Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	b, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	return string(b)
+}
+
+func TestSearch(t *testing.T) {
+	assert := assert.New(t)
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	resp := `{"took":3,"timed_out":false,"_shards":{"total":1,"successful":1,"skipped":0,"failed":0},` +
+		`"hits":{"total":{"value":2,"relation":"eq"},"max_score":1.0,"hits":[{"_id":"1"},{"_id":"2"}]}}`
+	srv := newServer(t, http.StatusOK, resp)
+	defer srv.Close()
+
+	rt := NewRoundTripper(WithServiceName("my-es-service"))
+	body := doRequest(t, rt, "POST", srv.URL+"/twitter/_search?size=10", `{"query":{"match":{"user":"kimchy"}},"size":10}`)
+	assert.Equal(resp, body)
+
+	spans := mt.FinishedSpans()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal("elasticsearch.query", span.OperationName())
+	assert.Equal("my-es-service", span.Tag(ext.ServiceName))
+	assert.Equal(ext.SpanTypeElasticSearch, span.Tag(ext.SpanType))
+	assert.Equal("POST /twitter/_search", span.Tag(ext.ResourceName))
+	assert.Equal(ext.SpanKindClient, span.Tag(ext.SpanKind))
+	assert.Equal("127.0.0.1", span.Tag(ext.TargetHost))
+	assert.Equal("POST", span.Tag("elasticsearch.method"))
+	assert.Equal("/twitter/_search", span.Tag("elasticsearch.url"))
+	assert.Equal("size=10", span.Tag("elasticsearch.params"))
+	assert.Equal(`{"query":{"match":{"user":"?"}},"size":"?"}`, span.Tag("elasticsearch.body"))
+	assert.Equal("200", span.Tag(ext.HTTPCode))
+	assert.Equal(3.0, span.Tag(tagTook))
+	assert.Equal(2.0, span.Tag(tagDocCount))
+	assert.Nil(span.Tag(ext.Error))
+}
+
+func TestCount(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	srv := newServer(t, http.StatusOK, `{"count":42,"_shards":{"total":1,"successful":1,"skipped":0,"failed":0}}`)
+	defer srv.Close()
+
+	doRequest(t, NewRoundTripper(), "GET", srv.URL+"/twitter/_count", "")
+
+	spans := mt.FinishedSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "GET /twitter/_count", spans[0].Tag(ext.ResourceName))
+	assert.Equal(t, 42.0, spans[0].Tag(tagDocCount))
+	assert.Nil(t, spans[0].Tag(tagTook))
+}
+
+func TestBodyObfuscationDisabled(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	srv := newServer(t, http.StatusCreated, `{"_index":"twitter","_id":"1","result":"created"}`)
+	defer srv.Close()
+
+	doRequest(t, NewRoundTripper(WithBodyObfuscation(false)), "PUT", srv.URL+"/twitter/_doc/1", `{"user":"test"}`)
+
+	spans := mt.FinishedSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "PUT /twitter/_doc/?", spans[0].Tag(ext.ResourceName))
+	assert.Equal(t, `{"user":"test"}`, spans[0].Tag("elasticsearch.body"))
+	assert.Nil(t, spans[0].Tag(tagDocCount))
+}
+
+func TestClientError(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	resp := `{"error":{"type":"index_not_found_exception"},"status":404}`
+	srv := newServer(t, http.StatusNotFound, resp)
+	defer srv.Close()
+
+	body := doRequest(t, NewRoundTripper(), "GET", srv.URL+"/not-real-index/_doc/abc-def", "")
+	assert.Equal(t, resp, body)
+
+	spans := mt.FinishedSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "GET /not-real-index/_doc/?", spans[0].Tag(ext.ResourceName))
+	assert.Equal(t, "404", spans[0].Tag(ext.HTTPCode))
+	assert.EqualError(t, spans[0].Tag(ext.Error).(error), resp)
+}
+
+func TestQuantize(t *testing.T) {
+	for _, tc := range []struct {
+		url, method string
+		expected    string
+	}{
+		{
+			url:      "/_search",
+			method:   "POST",
+			expected: "POST /_search",
+		},
+		{
+			url:      "/logs_2016_05/_search",
+			method:   "GET",
+			expected: "GET /logs_?_?/_search",
+		},
+		{
+			url:      "/twitter/_doc/2f6d2cb1-0c57-4a63-b6f8-ba1b2bcf5e1f",
+			method:   "GET",
+			expected: "GET /twitter/_doc/?",
+		},
+		{
+			url:      "/twitter/_update/user_1",
+			method:   "POST",
+			expected: "POST /twitter/_update/?",
+		},
+		{
+			url:      "/twitter/tweet/123",
+			method:   "PUT",
+			expected: "PUT /twitter/tweet/?",
+		},
+		{
+			url:      "/_bulk",
+			method:   "POST",
+			expected: "POST /_bulk",
+		},
+	} {
+		assert.Equal(t, tc.expected, quantize(tc.url, tc.method))
+	}
+}
+
+func TestObfuscateBody(t *testing.T) {
+	for name, tc := range map[string]struct {
+		body, expected string
+	}{
+		"query": {
+			body:     `{"query": {"bool": {"must": [{"term": {"user": "kimchy"}}, {"range": {"age": {"gte": 10}}}]}}}`,
+			expected: `{"query":{"bool":{"must":[{"term":{"user":"?"}},{"range":{"age":{"gte":"?"}}}]}}}`,
+		},
+		"values": {
+			body:     `{"a":null,"b":true,"c":1.5,"d":["x","y"],"e":{}}`,
+			expected: `{"a":"?","b":"?","c":"?","d":["?","?"],"e":{}}`,
+		},
+		"ndjson": {
+			body:     "{\"index\":{\"_index\":\"twitter\",\"_id\":\"1\"}}\n{\"user\":\"kimchy\"}\n",
+			expected: "{\"index\":{\"_index\":\"?\",\"_id\":\"?\"}}\n{\"user\":\"?\"}",
+		},
+		"truncated": {
+			body:     `{"query":{"match":{"user":"kim`,
+			expected: `{"query":{"match":{"user"`,
+		},
+		"invalid": {
+			body:     `user:kimchy`,
+			expected: `?`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, obfuscateBody(tc.body))
+		})
+	}
+}
+
+func TestResponseMetrics(t *testing.T) {
+	for name, tc := range map[string]struct {
+		body     string
+		expected map[string]float64
+	}{
+		"search": {
+			body:     `{"took":5,"hits":{"total":{"value":7,"relation":"eq"},"hits":[{"_id":"1"`,
+			expected: map[string]float64{tagTook: 5, tagDocCount: 7},
+		},
+		"search-v6": {
+			body:     `{"took":5,"hits":{"total":7,"max_score":1.0,"hits":[]}}`,
+			expected: map[string]float64{tagTook: 5, tagDocCount: 7},
+		},
+		"count": {
+			body:     `{"count":3,"_shards":{"total":1}}`,
+			expected: map[string]float64{tagDocCount: 3},
+		},
+		"bulk": {
+			body:     `{"took":30,"errors":false,"items":[{"index":{"_id":"1"}}]}`,
+			expected: map[string]float64{tagTook: 30},
+		},
+		"truncated": {
+			body:     `{"_shards":{"total":1},"took":`,
+			expected: map[string]float64{},
+		},
+		"array": {
+			body: `[{"took":1}]`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, responseMetrics(tc.body))
+		})
+	}
+}
+
+func TestAnalyticsSettings(t *testing.T) {
+	assertRate := func(t *testing.T, mt mocktracer.Tracer, rate interface{}, opts ...ClientOption) {
+		srv := newServer(t, http.StatusOK, `{}`)
+		defer srv.Close()
+		doRequest(t, NewRoundTripper(opts...), "GET", srv.URL+"/_cluster/health", "")
+		spans := mt.FinishedSpans()
+		require.Len(t, spans, 1)
+		assert.Equal(t, rate, spans[0].Tag(ext.EventSampleRate))
+	}
+
+	t.Run("defaults", func(t *testing.T) {
+		mt := mocktracer.Start()
+		defer mt.Stop()
+		assertRate(t, mt, nil)
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		mt := mocktracer.Start()
+		defer mt.Stop()
+		assertRate(t, mt, 1.0, WithAnalytics(true))
+	})
+
+	t.Run("override", func(t *testing.T) {
+		mt := mocktracer.Start()
+		defer mt.Stop()
+		assertRate(t, mt, 0.23, WithAnalyticsRate(0.23))
+	})
+}
@@ -18,6 +18,8 @@ import (
 	"github.com/emicklei/go-restful"
 )
 
+const componentName = "restful"
+
 // FilterFunc returns a restful.FilterFunction which will automatically trace incoming request.
 func FilterFunc(configOpts ...Option) restful.FilterFunction {
 	cfg := newConfig()
@@ -25,7 +27,10 @@ func FilterFunc(configOpts ...Option) restful.FilterFunction {
 		opt(cfg)
 	}
 	log.Debug("contrib/emicklei/go-restful: Creating tracing filter: %#v", cfg)
-	spanOpts := []ddtrace.StartSpanOption{tracer.ServiceName(cfg.serviceName)}
+	spanOpts := []ddtrace.StartSpanOption{
+		tracer.Tag(ext.Component, componentName),
+		tracer.ServiceName(cfg.serviceName),
+	}
 	return func(req *restful.Request, resp *restful.Response, chain *restful.FilterChain) {
 		spanOpts := append(spanOpts, tracer.ResourceName(req.SelectedRoutePath()))
 		if !math.IsNaN(cfg.analyticsRate) {
@@ -33,6 +38,7 @@ func FilterFunc(configOpts ...Option) restful.FilterFunction {
 		}
 		span, ctx := httptrace.StartRequestSpan(req.Request, spanOpts...)
 		defer func() {
+			httptrace.SetResponseHeaderTags(span, resp.Header(), httptrace.EnvHeaderTags())
 			httptrace.FinishRequestSpan(span, resp.StatusCode(), tracer.WithError(resp.Error()))
 		}()
 
@@ -44,8 +50,9 @@ func FilterFunc(configOpts ...Option) restful.FilterFunction {
 
 // Filter is deprecated. Please use FilterFunc.
 func Filter(req *restful.Request, resp *restful.Response, chain *restful.FilterChain) {
-	span, ctx := httptrace.StartRequestSpan(req.Request, tracer.ResourceName(req.SelectedRoutePath()))
+	span, ctx := httptrace.StartRequestSpan(req.Request, tracer.Tag(ext.Component, componentName), tracer.ResourceName(req.SelectedRoutePath()))
 	defer func() {
+		httptrace.SetResponseHeaderTags(span, resp.Header(), httptrace.EnvHeaderTags())
 		httptrace.FinishRequestSpan(span, resp.StatusCode(), tracer.WithError(resp.Error()))
 	}()
 
@@ -9,6 +9,7 @@ import (
 	"math"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/internal"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/namingschema"
 )
 
 type dialConfig struct {
@@ -20,7 +21,7 @@ type dialConfig struct {
 type DialOption func(*dialConfig)
 
 func defaults(cfg *dialConfig) {
-	cfg.serviceName = "redis.conn"
+	cfg.serviceName = namingschema.ServiceName("redis.conn")
 	// cfg.analyticsRate = globalconfig.AnalyticsRate()
 	if internal.BoolEnv("DD_TRACE_REDIGO_ANALYTICS_ENABLED", false) {
 		cfg.analyticsRate = 1.0
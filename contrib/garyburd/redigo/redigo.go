@@ -23,6 +23,8 @@ import (
 	redis "github.com/garyburd/redigo/redis"
 )
 
+const componentName = "redigo"
+
 // Conn is an implementation of the redis.Conn interface that supports tracing
 type Conn struct {
 	redis.Conn
@@ -101,6 +103,7 @@ func DialURL(rawurl string, options ...interface{}) (redis.Conn, error) {
 func (tc Conn) newChildSpan(ctx context.Context) ddtrace.Span {
 	p := tc.params
 	opts := []ddtrace.StartSpanOption{
+		tracer.Tag(ext.Component, componentName),
 		tracer.SpanType(ext.SpanTypeRedis),
 		tracer.ServiceName(p.config.serviceName),
 	}
@@ -16,7 +16,9 @@ import (
 )
 
 // useAppSec executes the AppSec logic related to the operation start and
-// returns the  function to be executed upon finishing the operation
+// returns the  function to be executed upon finishing the operation. When
+// AppSec blocks the request, the blocking response is written and the
+// remaining handlers are aborted.
 func useAppSec(c *gin.Context, span tracer.Span) func() {
 	req := c.Request
 	instrumentation.SetAppSecEnabledTags(span)
@@ -30,8 +32,13 @@ func useAppSec(c *gin.Context, span tracer.Span) func() {
 	args := httpsec.MakeHandlerOperationArgs(req, params)
 	ctx, op := httpsec.StartOperation(req.Context(), args)
 	c.Request = req.WithContext(ctx)
+	httpsec.MonitorRequestBody(ctx, c.Request)
+	if op.Err() != nil {
+		httpsec.WriteBlockingResponse(c.Writer, c.Request)
+		c.Abort()
+	}
 	return func() {
-		events := op.Finish(httpsec.HandlerOperationRes{Status: c.Writer.Status()})
+		events := op.Finish(httpsec.HandlerOperationRes{Status: c.Writer.Status(), Headers: httpsec.MakeResponseHeaders(c.Writer.Header())})
 		if len(events) > 0 {
 			remoteIP, _, err := net.SplitHostPort(req.RemoteAddr)
 			if err != nil {
@@ -19,6 +19,8 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+const componentName = "gin"
+
 // Middleware returns middleware that will trace incoming requests. If service is empty then the
 // default service name will be used.
 func Middleware(service string, opts ...Option) gin.HandlerFunc {
@@ -29,6 +31,7 @@ func Middleware(service string, opts ...Option) gin.HandlerFunc {
 	}
 	log.Debug("contrib/gin-gonic/gin: Configuring Middleware: Service: %s, %#v", cfg.serviceName, cfg)
 	spanOpts := []tracer.StartSpanOption{
+		tracer.Tag(ext.Component, componentName),
 		tracer.ServiceName(cfg.serviceName),
 	}
 	return func(c *gin.Context) {
@@ -41,7 +44,11 @@ func Middleware(service string, opts ...Option) gin.HandlerFunc {
 		}
 		opts = append(opts, tracer.Tag(ext.HTTPRoute, c.FullPath()))
 		span, ctx := httptrace.StartRequestSpan(c.Request, opts...)
+		if cfg.serverTiming {
+			httptrace.SetServerTimingHeader(c.Writer.Header(), span.Context())
+		}
 		defer func() {
+			httptrace.SetResponseHeaderTags(span, c.Writer.Header(), httptrace.EnvHeaderTags())
 			httptrace.FinishRequestSpan(span, c.Writer.Status())
 		}()
 
@@ -65,7 +72,7 @@ func Middleware(service string, opts ...Option) gin.HandlerFunc {
 
 // HTML will trace the rendering of the template as a child of the span in the given context.
 func HTML(c *gin.Context, code int, name string, obj interface{}) {
-	span, _ := tracer.StartSpanFromContext(c.Request.Context(), "gin.render.html")
+	span, _ := tracer.StartSpanFromContext(c.Request.Context(), "gin.render.html", tracer.Tag(ext.Component, componentName))
 	span.SetTag("go.template", name)
 	defer func() {
 		if r := recover(); r != nil {
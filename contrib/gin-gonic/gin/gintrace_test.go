@@ -88,6 +88,26 @@ func TestTrace200(t *testing.T) {
 	assert.Equal("http://example.com/user/123", span.Tag(ext.HTTPURL))
 }
 
+func TestClientIP(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	router := gin.New()
+	router.Use(Middleware("foobar"))
+	router.GET("/user/:id", func(c *gin.Context) {
+		c.Writer.Write([]byte(c.Param("id")))
+	})
+
+	r := httptest.NewRequest("GET", "/user/123", nil)
+	r.Header.Set("X-Forwarded-For", "8.8.8.8")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+
+	spans := mt.FinishedSpans()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "8.8.8.8", spans[0].Tag(ext.HTTPClientIP))
+}
+
 func TestTraceDefaultResponse(t *testing.T) {
 	assert := assert.New(t)
 	mt := mocktracer.Start()
@@ -448,6 +468,27 @@ func TestIgnoreRequestSettings(t *testing.T) {
 	}
 }
 
+func TestServerTiming(t *testing.T) {
+	assert := assert.New(t)
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	router := gin.New()
+	router.Use(Middleware("foobar", WithServerTiming()))
+	router.GET("/user/:id", func(c *gin.Context) {
+		c.Writer.Write([]byte("OK"))
+	})
+
+	r := httptest.NewRequest("GET", "/user/123", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+
+	spans := mt.FinishedSpans()
+	assert.Len(spans, 1)
+	want := fmt.Sprintf(`traceparent;desc="00-%032x-%016x-00"`, spans[0].TraceID(), spans[0].SpanID())
+	assert.Equal(want, w.Header().Get("Server-Timing"))
+}
+
 func TestServiceName(t *testing.T) {
 	t.Run("default", func(t *testing.T) {
 		assert := assert.New(t)
@@ -559,6 +600,13 @@ func TestAppSec(t *testing.T) {
 		pappsec.MonitorParsedHTTPBody(c.Request.Context(), "$globals")
 		c.String(200, "Hello Body!\n")
 	})
+	r.POST("/json", func(c *gin.Context) {
+		var body map[string]interface{}
+		if err := c.BindJSON(&body); err != nil {
+			return
+		}
+		c.String(200, "Hello JSON!\n")
+	})
 
 	srv := httptest.NewServer(r)
 	defer srv.Close()
@@ -660,4 +708,77 @@ func TestAppSec(t *testing.T) {
 		require.NotNil(t, event)
 		require.True(t, strings.Contains(event.(string), "crs-933-130"))
 	})
+
+	// Test a PHP injection attack via the automatically parsed JSON request body
+	t.Run("json-body", func(t *testing.T) {
+		mt := mocktracer.Start()
+		defer mt.Stop()
+
+		req, err := http.NewRequest("POST", srv.URL+"/json", strings.NewReader(`{"key":"$globals"}`))
+		if err != nil {
+			panic(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		res, err := srv.Client().Do(req)
+		require.NoError(t, err)
+
+		// Check that the handler could still read the body
+		b, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.Equal(t, "Hello JSON!\n", string(b))
+
+		finished := mt.FinishedSpans()
+		require.Len(t, finished, 1)
+
+		event := finished[0].Tag("_dd.appsec.json")
+		require.NotNil(t, event)
+		require.True(t, strings.Contains(event.(string), "crs-933-130"))
+		require.True(t, strings.Contains(event.(string), "server.request.body"))
+	})
+}
+
+func TestAppSecBlocking(t *testing.T) {
+	t.Setenv("DD_APPSEC_RULES", "../../../internal/appsec/testdata/blocking.json")
+	appsec.Start()
+	defer appsec.Stop()
+	if !appsec.Enabled() {
+		t.Skip("appsec disabled")
+	}
+
+	r := gin.New()
+	r.Use(Middleware("appsec"))
+	r.Any("/", func(c *gin.Context) {
+		c.String(200, "Hello World!\n")
+	})
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	for _, tc := range []struct {
+		name      string
+		userAgent string
+		status    int
+	}{
+		{name: "no-block", userAgent: "dd-test-scanner-log", status: http.StatusOK},
+		{name: "block", userAgent: "dd-test-scanner-log-block", status: http.StatusForbidden},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mt := mocktracer.Start()
+			defer mt.Stop()
+			req, err := http.NewRequest("GET", srv.URL+"/", nil)
+			require.NoError(t, err)
+			req.Header.Set("User-Agent", tc.userAgent)
+			res, err := srv.Client().Do(req)
+			require.NoError(t, err)
+			res.Body.Close()
+			require.Equal(t, tc.status, res.StatusCode)
+
+			finished := mt.FinishedSpans()
+			require.Len(t, finished, 1)
+			if tc.status == http.StatusForbidden {
+				require.Equal(t, true, finished[0].Tag("appsec.blocked"))
+			} else {
+				require.Nil(t, finished[0].Tag("appsec.blocked"))
+			}
+		})
+	}
 }
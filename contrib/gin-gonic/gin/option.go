@@ -20,6 +20,7 @@ type config struct {
 	resourceNamer func(c *gin.Context) string
 	serviceName   string
 	ignoreRequest func(c *gin.Context) bool
+	serverTiming  bool
 }
 
 func newConfig(service string) *config {
@@ -83,6 +84,15 @@ func WithIgnoreRequest(f func(c *gin.Context) bool) Option {
 	}
 }
 
+// WithServerTiming adds a Server-Timing response header holding the W3C traceparent
+// of the request span, so that browser clients, such as the RUM SDK, can link their
+// traces to the backend ones.
+func WithServerTiming() Option {
+	return func(cfg *config) {
+		cfg.serverTiming = true
+	}
+}
+
 func defaultResourceNamer(c *gin.Context) string {
 	// getName is a hacky way to check whether *gin.Context implements the FullPath()
 	// method introduced in v1.4.0, falling back to the previous implementation otherwise.
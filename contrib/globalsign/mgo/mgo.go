@@ -18,6 +18,8 @@ import (
 	"github.com/globalsign/mgo"
 )
 
+const componentName = "mgo"
+
 // Dial opens a connection to a MongoDB server and configures it
 // for tracing.
 func Dial(url string, opts ...DialOption) (*Session, error) {
@@ -53,6 +55,7 @@ type Session struct {
 
 func newChildSpanFromContext(cfg *mongoConfig, tags map[string]string) ddtrace.Span {
 	opts := []ddtrace.StartSpanOption{
+		tracer.Tag(ext.Component, componentName),
 		tracer.SpanType(ext.SpanTypeMongoDB),
 		tracer.ServiceName(cfg.serviceName),
 		tracer.ResourceName("mongodb.query"),
@@ -10,6 +10,7 @@ import (
 	"math"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/internal"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/namingschema"
 )
 
 type mongoConfig struct {
@@ -24,7 +25,7 @@ func newConfig() *mongoConfig {
 		rate = 1.0
 	}
 	return &mongoConfig{
-		serviceName: "mongodb",
+		serviceName: namingschema.ServiceName("mongodb"),
 		ctx:         context.Background(),
 		// analyticsRate: globalconfig.AnalyticsRate(),
 		analyticsRate: rate,
@@ -104,6 +104,26 @@ func TestTrace200(t *testing.T) {
 	})
 }
 
+func TestClientIP(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	router := chi.NewRouter()
+	router.Use(Middleware(WithServiceName("foobar")))
+	router.Get("/user/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(chi.URLParam(r, "id")))
+	})
+
+	r := httptest.NewRequest("GET", "/user/123", nil)
+	r.Header.Set("X-Forwarded-For", "8.8.8.8")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+
+	spans := mt.FinishedSpans()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "8.8.8.8", spans[0].Tag(ext.HTTPClientIP))
+}
+
 func TestError(t *testing.T) {
 	assertSpan := func(assert *assert.Assertions, spans []mocktracer.Span, code int) {
 		assert.Len(spans, 1)
@@ -306,6 +326,26 @@ func TestIgnoreRequest(t *testing.T) {
 	}
 }
 
+func TestServerTiming(t *testing.T) {
+	assert := assert.New(t)
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	router := chi.NewRouter()
+	router.Use(Middleware(WithServerTiming()))
+	router.Get("/ok", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	r := httptest.NewRequest("GET", "/ok", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+
+	spans := mt.FinishedSpans()
+	assert.Len(spans, 1)
+	want := fmt.Sprintf(`traceparent;desc="00-%032x-%016x-00"`, spans[0].TraceID(), spans[0].SpanID())
+	assert.Equal(want, w.Header().Get("Server-Timing"))
+}
+
 func TestAppSec(t *testing.T) {
 	appsec.Start()
 	defer appsec.Stop()
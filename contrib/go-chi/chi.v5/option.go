@@ -20,6 +20,7 @@ type config struct {
 	analyticsRate float64
 	isStatusError func(statusCode int) bool
 	ignoreRequest func(r *http.Request) bool
+	serverTiming  bool
 }
 
 // Option represents an option that can be passed to NewRouter.
@@ -96,3 +97,12 @@ func WithIgnoreRequest(fn func(r *http.Request) bool) Option {
 		cfg.ignoreRequest = fn
 	}
 }
+
+// WithServerTiming adds a Server-Timing response header holding the W3C traceparent
+// of the request span, so that browser clients, such as the RUM SDK, can link their
+// traces to the backend ones.
+func WithServerTiming() Option {
+	return func(cfg *config) {
+		cfg.serverTiming = true
+	}
+}
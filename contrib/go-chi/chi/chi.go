@@ -21,6 +21,8 @@ import (
 	"github.com/go-chi/chi/middleware"
 )
 
+const componentName = "chi"
+
 // Middleware returns middleware that will trace incoming requests.
 func Middleware(opts ...Option) func(next http.Handler) http.Handler {
 	cfg := new(config)
@@ -29,7 +31,7 @@ func Middleware(opts ...Option) func(next http.Handler) http.Handler {
 		fn(cfg)
 	}
 	log.Debug("contrib/go-chi/chi: Configuring Middleware: %#v", cfg)
-	spanOpts := append(cfg.spanOpts, tracer.ServiceName(cfg.serviceName))
+	spanOpts := append(cfg.spanOpts, tracer.Tag(ext.Component, componentName), tracer.ServiceName(cfg.serviceName))
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			if cfg.ignoreRequest(r) {
@@ -43,6 +45,7 @@ func Middleware(opts ...Option) func(next http.Handler) http.Handler {
 			span, ctx := httptrace.StartRequestSpan(r, opts...)
 			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
 			defer func() {
+				httptrace.SetResponseHeaderTags(span, ww.Header(), httptrace.EnvHeaderTags())
 				status := ww.Status()
 				var opts []tracer.FinishOption
 				if cfg.isStatusError(status) {
@@ -13,10 +13,13 @@ import (
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/namingschema"
 
 	"github.com/go-pg/pg/v10"
 )
 
+const componentName = "gopg"
+
 // Wrap augments the given DB with tracing.
 func Wrap(db *pg.DB, opts ...Option) {
 	cfg := new(config)
@@ -40,6 +43,7 @@ func (h *queryHook) BeforeQuery(ctx context.Context, qe *pg.QueryEvent) (context
 	}
 
 	opts := []ddtrace.StartSpanOption{
+		tracer.Tag(ext.Component, componentName),
 		tracer.SpanType(ext.SpanTypeSQL),
 		tracer.ResourceName(string(query)),
 		tracer.ServiceName(h.cfg.serviceName),
@@ -47,7 +51,7 @@ func (h *queryHook) BeforeQuery(ctx context.Context, qe *pg.QueryEvent) (context
 	if !math.IsNaN(h.cfg.analyticsRate) {
 		opts = append(opts, tracer.Tag(ext.EventSampleRate, h.cfg.analyticsRate))
 	}
-	_, ctx = tracer.StartSpanFromContext(ctx, "go-pg", opts...)
+	_, ctx = tracer.StartSpanFromContext(ctx, namingschema.DBOp("postgresql", "go-pg"), opts...)
 	return ctx, qe.Err
 }
 
@@ -22,6 +22,8 @@ import (
 	"github.com/go-redis/redis/v7"
 )
 
+const componentName = "redis"
+
 type datadogHook struct {
 	*params
 }
@@ -103,6 +105,7 @@ func (ddh *datadogHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (con
 	length := len(parts) - 1
 	p := ddh.params
 	opts := []ddtrace.StartSpanOption{
+		tracer.Tag(ext.Component, componentName),
 		tracer.SpanType(ext.SpanTypeRedis),
 		tracer.ServiceName(p.config.serviceName),
 		tracer.ResourceName(parts[0]),
@@ -135,6 +138,7 @@ func (ddh *datadogHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.
 	length := len(parts) - 1
 	p := ddh.params
 	opts := []ddtrace.StartSpanOption{
+		tracer.Tag(ext.Component, componentName),
 		tracer.SpanType(ext.SpanTypeRedis),
 		tracer.ServiceName(p.config.serviceName),
 		tracer.ResourceName(parts[0]),
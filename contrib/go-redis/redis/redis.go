@@ -24,6 +24,8 @@ import (
 	"github.com/go-redis/redis"
 )
 
+const componentName = "redis"
+
 // Client is used to trace requests to a redis server.
 type Client struct {
 	*redis.Client
@@ -117,6 +119,7 @@ func (c *Pipeliner) Exec() ([]redis.Cmder, error) {
 func (c *Pipeliner) execWithContext(ctx context.Context) ([]redis.Cmder, error) {
 	p := c.params
 	opts := []ddtrace.StartSpanOption{
+		tracer.Tag(ext.Component, componentName),
 		tracer.SpanType(ext.SpanTypeRedis),
 		tracer.ServiceName(p.config.serviceName),
 		tracer.ResourceName("redis"),
@@ -185,6 +188,7 @@ func createWrapperFromClient(tc *Client) func(oldProcess func(cmd redis.Cmder) e
 			length := len(parts) - 1
 			p := tc.params
 			opts := []ddtrace.StartSpanOption{
+				tracer.Tag(ext.Component, componentName),
 				tracer.SpanType(ext.SpanTypeRedis),
 				tracer.ServiceName(p.config.serviceName),
 				tracer.ResourceName(parts[0]),
@@ -38,3 +38,15 @@ func Example() {
 		}},
 	})
 }
+
+func ExampleNewMonitors() {
+	// connect to MongoDB, adding the server selection and the connection
+	// checkout of each command to its span
+	opts := mongotrace.NewMonitors().ApplyTo(options.Client())
+	opts.ApplyURI("mongodb://localhost:27017")
+	client, err := mongo.Connect(context.Background(), opts)
+	if err != nil {
+		panic(err)
+	}
+	client.Database("example").Collection("inventory").FindOne(context.Background(), bson.D{{Key: "item", Value: "canvas"}})
+}
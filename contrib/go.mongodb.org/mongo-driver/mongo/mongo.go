@@ -7,24 +7,35 @@
 // It support v0.2.0 of github.com/mongodb/mongo-go-driver
 //
 // `NewMonitor` will return an event.CommandMonitor which is used to trace requests.
+// `NewMonitors` will also return the event.PoolMonitor and event.ServerMonitor adding
+// the server selection and the connection checkout preceding each request to its span.
 package mongo
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"math"
 	"strings"
 	"sync"
+	"time"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
 
+	"github.com/DataDog/datadog-agent/pkg/obfuscate"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+const componentName = "mongo"
+
+// keySpanEvents is the tag holding the span events of a span, as a JSON array.
+const keySpanEvents = "events"
+
 type spanKey struct {
 	ConnectionID string
 	RequestID    int64
@@ -34,24 +45,72 @@ type monitor struct {
 	sync.Mutex
 	spans map[spanKey]ddtrace.Span
 	cfg   *config
+
+	// checkouts holds the last connection checkout of each server address which
+	// was not yet followed by a command, and servers the kind of each server
+	// address, as last described by the server monitor.
+	checkouts map[string]checkout
+	servers   map[string]string
+}
+
+// checkout is a connection checked out of the pool of a server.
+type checkout struct {
+	time         time.Time
+	connectionID uint64
+}
+
+// spanEvent is an event of a span, encoded the way the span events are sent to
+// Datadog.
+type spanEvent struct {
+	Name         string            `json:"name"`
+	TimeUnixNano int64             `json:"time_unix_nano"`
+	Attributes   map[string]string `json:"attributes,omitempty"`
+}
+
+func newMonitor(opts ...Option) *monitor {
+	cfg := new(config)
+	defaults(cfg)
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	log.Debug("contrib/go.mongodb.org/mongo-driver/mongo: Creating Monitor: %#v", cfg)
+	return &monitor{
+		spans:     make(map[spanKey]ddtrace.Span),
+		cfg:       cfg,
+		checkouts: make(map[string]checkout),
+		servers:   make(map[string]string),
+	}
 }
 
 func (m *monitor) Started(ctx context.Context, evt *event.CommandStartedEvent) {
 	hostname, port := peerInfo(evt)
 	b, _ := bson.MarshalExtJSON(evt.Command, false, false)
+	query := string(b)
+	if m.cfg.obfuscateCommand {
+		query = obfuscateCommand(query)
+	}
 	opts := []ddtrace.StartSpanOption{
+		tracer.Tag(ext.Component, componentName),
 		tracer.SpanType(ext.SpanTypeMongoDB),
 		tracer.ServiceName(m.cfg.serviceName),
 		tracer.ResourceName("mongo." + evt.CommandName),
 		tracer.Tag(ext.DBInstance, evt.DatabaseName),
-		tracer.Tag("mongodb.query", string(b)),
+		tracer.Tag(ext.DBName, evt.DatabaseName),
+		tracer.Tag(ext.DBOperation, evt.CommandName),
+		tracer.Tag("mongodb.query", query),
 		tracer.Tag(ext.DBType, "mongo"),
 		tracer.Tag(ext.PeerHostname, hostname),
 		tracer.Tag(ext.PeerPort, port),
 	}
+	if c := collection(evt); c != "" {
+		opts = append(opts, tracer.Tag(ext.MongoDBCollection, c))
+	}
 	if !math.IsNaN(m.cfg.analyticsRate) {
 		opts = append(opts, tracer.Tag(ext.EventSampleRate, m.cfg.analyticsRate))
 	}
+	if events := m.spanEvents(serverAddress(evt.ConnectionID)); events != "" {
+		opts = append(opts, tracer.Tag(keySpanEvents, events))
+	}
 	span, _ := tracer.StartSpanFromContext(ctx, "mongodb.query", opts...)
 	key := spanKey{
 		ConnectionID: evt.ConnectionID,
@@ -62,6 +121,75 @@ func (m *monitor) Started(ctx context.Context, evt *event.CommandStartedEvent) {
 	m.Unlock()
 }
 
+// spanEvents returns the span events of the server selection and the connection
+// checkout preceding a command sent to the server with the given address, as a
+// JSON array. It returns an empty string when no connection checkout of the
+// server is pending, e.g. when the pool monitor is not used. The checkout is
+// consumed by the command.
+func (m *monitor) spanEvents(address string) string {
+	m.Lock()
+	c, ok := m.checkouts[address]
+	delete(m.checkouts, address)
+	kind := m.servers[address]
+	m.Unlock()
+	if !ok {
+		return ""
+	}
+	// the server selection completes right before the connection checkout, which
+	// is the first event reporting the selected server
+	selection := spanEvent{
+		Name:         "mongodb.server_selection",
+		TimeUnixNano: c.time.UnixNano(),
+		Attributes:   map[string]string{"server.address": address},
+	}
+	if kind != "" {
+		selection.Attributes["server.type"] = kind
+	}
+	b, err := json.Marshal([]spanEvent{selection, {
+		Name:         "mongodb.connection_checkout",
+		TimeUnixNano: c.time.UnixNano(),
+		Attributes: map[string]string{
+			"server.address":        address,
+			"db.connection.pool_id": fmt.Sprint(c.connectionID),
+		},
+	}})
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+// PoolEvent records the connections checked out of the connection pools, to be
+// added to the span of the next command sent to their server.
+func (m *monitor) PoolEvent(evt *event.PoolEvent) {
+	switch evt.Type {
+	case event.GetSucceeded:
+		m.Lock()
+		m.checkouts[evt.Address] = checkout{time: time.Now(), connectionID: evt.ConnectionID}
+		m.Unlock()
+	case event.PoolClosedEvent:
+		m.Lock()
+		delete(m.checkouts, evt.Address)
+		m.Unlock()
+	}
+}
+
+// ServerDescriptionChanged records the kind of the servers, e.g. RSPrimary.
+func (m *monitor) ServerDescriptionChanged(evt *event.ServerDescriptionChangedEvent) {
+	m.Lock()
+	m.servers[evt.Address.String()] = evt.NewDescription.Kind.String()
+	m.Unlock()
+}
+
+// ServerClosed forgets the closed servers.
+func (m *monitor) ServerClosed(evt *event.ServerClosedEvent) {
+	addr := evt.Address.String()
+	m.Lock()
+	delete(m.servers, addr)
+	delete(m.checkouts, addr)
+	m.Unlock()
+}
+
 func (m *monitor) Succeeded(ctx context.Context, evt *event.CommandSucceededEvent) {
 	m.Finished(&evt.CommandFinishedEvent, nil)
 }
@@ -89,16 +217,10 @@ func (m *monitor) Finished(evt *event.CommandFinishedEvent, err error) {
 
 // NewMonitor creates a new mongodb event CommandMonitor.
 func NewMonitor(opts ...Option) *event.CommandMonitor {
-	cfg := new(config)
-	defaults(cfg)
-	for _, opt := range opts {
-		opt(cfg)
-	}
-	log.Debug("contrib/go.mongodb.org/mongo-driver/mongo: Creating Monitor: %#v", cfg)
-	m := &monitor{
-		spans: make(map[spanKey]ddtrace.Span),
-		cfg:   cfg,
-	}
+	return newMonitor(opts...).commandMonitor()
+}
+
+func (m *monitor) commandMonitor() *event.CommandMonitor {
 	return &event.CommandMonitor{
 		Started:   m.Started,
 		Succeeded: m.Succeeded,
@@ -106,6 +228,75 @@ func NewMonitor(opts ...Option) *event.CommandMonitor {
 	}
 }
 
+// Monitors holds the monitors tracing the commands of a client. Along with the
+// commands, traced as by NewMonitor, the Pool and Server monitors observe the
+// server selection and the connection checkout preceding each command, which
+// are added to its span as span events. As the driver does not relate these to
+// the commands, the checkout of a connection to a server is attributed to the
+// next command sent to that server: with concurrent commands to the same server,
+// their events may be swapped.
+type Monitors struct {
+	Command *event.CommandMonitor
+	Pool    *event.PoolMonitor
+	Server  *event.ServerMonitor
+}
+
+// NewMonitors creates the mongodb event monitors tracing the commands of a
+// client, and the server selections and connection checkouts preceding them.
+func NewMonitors(opts ...Option) *Monitors {
+	m := newMonitor(opts...)
+	return &Monitors{
+		Command: m.commandMonitor(),
+		Pool:    &event.PoolMonitor{Event: m.PoolEvent},
+		Server: &event.ServerMonitor{
+			ServerDescriptionChanged: m.ServerDescriptionChanged,
+			ServerClosed:             m.ServerClosed,
+		},
+	}
+}
+
+// ApplyTo sets the monitors on the given client options, replacing the command,
+// pool and server monitors they hold, and returns them.
+func (ms *Monitors) ApplyTo(opts *options.ClientOptions) *options.ClientOptions {
+	return opts.SetMonitor(ms.Command).SetPoolMonitor(ms.Pool).SetServerMonitor(ms.Server)
+}
+
+// collection returns the name of the collection the given command operates on,
+// which is the string value of its first element, named after the command, for
+// the commands operating on a collection, e.g. {"find": "users", ...}. It returns
+// an empty string otherwise.
+func collection(evt *event.CommandStartedEvent) string {
+	elem, err := evt.Command.IndexErr(0)
+	if err != nil || elem.Key() != evt.CommandName {
+		return ""
+	}
+	c, _ := elem.Value().StringValueOK()
+	return c
+}
+
+var (
+	obfuscatorOnce sync.Once
+	obfuscator     *obfuscate.Obfuscator
+)
+
+// obfuscateCommand returns the given command, encoded as extended JSON, with all
+// its values replaced by "?".
+func obfuscateCommand(cmd string) string {
+	obfuscatorOnce.Do(func() {
+		obfuscator = obfuscate.NewObfuscator(obfuscate.Config{Mongo: obfuscate.JSONConfig{Enabled: true}})
+	})
+	return obfuscator.ObfuscateMongoDBString(cmd)
+}
+
+// serverAddress returns the address of the server of the connection with the
+// given ID, e.g. "localhost:27017" for "localhost:27017[-1]".
+func serverAddress(connectionID string) string {
+	if idx := strings.IndexByte(connectionID, '['); idx >= 0 {
+		return connectionID[:idx]
+	}
+	return connectionID
+}
+
 func peerInfo(evt *event.CommandStartedEvent) (hostname, port string) {
 	hostname = evt.ConnectionID
 	port = "27017"
@@ -7,6 +7,7 @@ package mongo
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"testing"
@@ -18,10 +19,14 @@ import (
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/address"
+	"go.mongodb.org/mongo-driver/mongo/description"
 	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestMain(m *testing.M) {
@@ -75,9 +80,131 @@ func Test(t *testing.T) {
 	assert.Equal(t, port, s.Tag(ext.PeerPort))
 	assert.Contains(t, s.Tag("mongodb.query"), `"test-item":"test-value"`)
 	assert.Equal(t, "test-database", s.Tag(ext.DBInstance))
+	assert.Equal(t, "test-database", s.Tag(ext.DBName))
+	assert.Equal(t, "insert", s.Tag(ext.DBOperation))
+	assert.Equal(t, "test-collection", s.Tag(ext.MongoDBCollection))
 	assert.Equal(t, "mongo", s.Tag(ext.DBType))
 }
 
+func TestCommandTags(t *testing.T) {
+	command := func(t *testing.T, doc bson.D) bson.Raw {
+		b, err := bson.Marshal(doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	for name, tc := range map[string]struct {
+		opts       []Option
+		cmd        string
+		doc        bson.D
+		query      string
+		collection interface{}
+	}{
+		"find": {
+			cmd:        "find",
+			doc:        bson.D{{Key: "find", Value: "users"}, {Key: "filter", Value: bson.D{{Key: "name", Value: "bob"}}}},
+			query:      `{"find":"users","filter":{"name":"bob"}}`,
+			collection: "users",
+		},
+		"obfuscated": {
+			opts:       []Option{WithCommandObfuscation(true)},
+			cmd:        "find",
+			doc:        bson.D{{Key: "find", Value: "users"}, {Key: "filter", Value: bson.D{{Key: "name", Value: "bob"}}}},
+			query:      `{"find":"?","filter":{"name":"?"}}`,
+			collection: "users",
+		},
+		"no-collection": {
+			cmd:   "ping",
+			doc:   bson.D{{Key: "ping", Value: 1}},
+			query: `{"ping":1}`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			mt := mocktracer.Start()
+			defer mt.Stop()
+
+			m := NewMonitor(tc.opts...)
+			m.Started(context.Background(), &event.CommandStartedEvent{
+				Command:      command(t, tc.doc),
+				DatabaseName: "test-database",
+				CommandName:  tc.cmd,
+				RequestID:    1,
+				ConnectionID: "localhost:27017[-1]",
+			})
+			m.Succeeded(context.Background(), &event.CommandSucceededEvent{
+				CommandFinishedEvent: event.CommandFinishedEvent{
+					CommandName:  tc.cmd,
+					RequestID:    1,
+					ConnectionID: "localhost:27017[-1]",
+				},
+			})
+
+			spans := mt.FinishedSpans()
+			assert.Len(t, spans, 1)
+			s := spans[0]
+			assert.Equal(t, "mongo."+tc.cmd, s.Tag(ext.ResourceName))
+			assert.Equal(t, tc.query, s.Tag("mongodb.query"))
+			assert.Equal(t, "test-database", s.Tag(ext.DBName))
+			assert.Equal(t, tc.cmd, s.Tag(ext.DBOperation))
+			assert.Equal(t, tc.collection, s.Tag(ext.MongoDBCollection))
+		})
+	}
+}
+
+func TestSpanEvents(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	ms := NewMonitors()
+	addr := address.Address("localhost:27017")
+	ms.Server.ServerDescriptionChanged(&event.ServerDescriptionChangedEvent{
+		Address:        addr,
+		NewDescription: description.Server{Addr: addr, Kind: description.RSPrimary},
+	})
+	ms.Pool.Event(&event.PoolEvent{Type: event.GetSucceeded, Address: addr.String(), ConnectionID: 3})
+	run := func(requestID int64) {
+		doc, err := bson.Marshal(bson.D{{Key: "ping", Value: 1}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ms.Command.Started(context.Background(), &event.CommandStartedEvent{
+			Command:      doc,
+			DatabaseName: "test-database",
+			CommandName:  "ping",
+			RequestID:    requestID,
+			ConnectionID: "localhost:27017[-1]",
+		})
+		ms.Command.Succeeded(context.Background(), &event.CommandSucceededEvent{
+			CommandFinishedEvent: event.CommandFinishedEvent{
+				CommandName:  "ping",
+				RequestID:    requestID,
+				ConnectionID: "localhost:27017[-1]",
+			},
+		})
+	}
+	run(1)
+	// the checkout is consumed by the first command
+	run(2)
+
+	spans := mt.FinishedSpans()
+	require.Len(t, spans, 2)
+	var events []spanEvent
+	require.NoError(t, json.Unmarshal([]byte(spans[0].Tag(keySpanEvents).(string)), &events))
+	require.Len(t, events, 2)
+	assert.Equal(t, "mongodb.server_selection", events[0].Name)
+	assert.Equal(t, map[string]string{"server.address": "localhost:27017", "server.type": "RSPrimary"}, events[0].Attributes)
+	assert.Equal(t, "mongodb.connection_checkout", events[1].Name)
+	assert.Equal(t, map[string]string{"server.address": "localhost:27017", "db.connection.pool_id": "3"}, events[1].Attributes)
+	assert.NotZero(t, events[1].TimeUnixNano)
+	assert.Nil(t, spans[1].Tag(keySpanEvents))
+
+	opts := ms.ApplyTo(options.Client())
+	assert.Same(t, ms.Command, opts.Monitor)
+	assert.Same(t, ms.Pool, opts.PoolMonitor)
+	assert.Same(t, ms.Server, opts.ServerMonitor)
+}
+
 func TestAnalyticsSettings(t *testing.T) {
 	assertRate := func(t *testing.T, mt mocktracer.Tracer, rate interface{}, opts ...Option) {
 		ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
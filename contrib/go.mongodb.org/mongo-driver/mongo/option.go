@@ -9,18 +9,20 @@ import (
 	"math"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/internal"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/namingschema"
 )
 
 type config struct {
-	serviceName   string
-	analyticsRate float64
+	serviceName      string
+	analyticsRate    float64
+	obfuscateCommand bool
 }
 
 // Option represents an option that can be passed to Dial.
 type Option func(*config)
 
 func defaults(cfg *config) {
-	cfg.serviceName = "mongo"
+	cfg.serviceName = namingschema.ServiceName("mongo")
 	// cfg.analyticsRate = globalconfig.AnalyticsRate()
 	if internal.BoolEnv("DD_TRACE_MONGO_ANALYTICS_ENABLED", false) {
 		cfg.analyticsRate = 1.0
@@ -60,3 +62,14 @@ func WithAnalyticsRate(rate float64) Option {
 		}
 	}
 }
+
+// WithCommandObfuscation specifies whether the commands captured as the
+// mongodb.query tag are obfuscated, i.e. have all their values replaced by "?",
+// their keys being preserved. It prevents sensitive data, such as the values
+// of the filters or of the inserted documents, from being sent to Datadog. It
+// is disabled by default.
+func WithCommandObfuscation(enabled bool) Option {
+	return func(cfg *config) {
+		cfg.obfuscateCommand = enabled
+	}
+}
@@ -0,0 +1,41 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022 Datadog, Inc.
+
+package sdk_test
+
+import (
+	"log"
+
+	temporaltrace "gopkg.in/DataDog/dd-trace-go.v1/contrib/go.temporal.io/sdk"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/interceptor"
+	"go.temporal.io/sdk/worker"
+)
+
+func Example() {
+	tracer.Start()
+	defer tracer.Stop()
+
+	// The interceptor traces both the workflows started by the client and the
+	// executions of the worker, whose spans are connected through the headers.
+	i := temporaltrace.NewInterceptor(temporaltrace.WithServiceName("my-workers"))
+	c, err := client.Dial(client.Options{
+		Interceptors: []interceptor.ClientInterceptor{i},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer c.Close()
+
+	w := worker.New(c, "my-task-queue", worker.Options{
+		Interceptors: []interceptor.WorkerInterceptor{i},
+	})
+	// Register the workflows and the activities, then run the worker.
+	if err := w.Run(worker.InterruptCh()); err != nil {
+		log.Fatal(err)
+	}
+}
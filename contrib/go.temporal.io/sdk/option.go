@@ -0,0 +1,78 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022 Datadog, Inc.
+
+package sdk
+
+import (
+	"math"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
+)
+
+type config struct {
+	serviceName          string
+	analyticsRate        float64
+	disableSignalTracing bool
+	disableQueryTracing  bool
+}
+
+// Option represents an option that can be passed to NewInterceptor.
+type Option func(*config)
+
+func defaults(cfg *config) {
+	cfg.serviceName = "temporal"
+	if svc := globalconfig.ServiceName(); svc != "" {
+		cfg.serviceName = svc
+	}
+	if internal.BoolEnv("DD_TRACE_TEMPORAL_ANALYTICS_ENABLED", false) {
+		cfg.analyticsRate = 1.0
+	} else {
+		cfg.analyticsRate = globalconfig.AnalyticsRate()
+	}
+}
+
+// WithServiceName sets the given service name for the spans of the interceptor.
+func WithServiceName(name string) Option {
+	return func(cfg *config) {
+		cfg.serviceName = name
+	}
+}
+
+// WithAnalytics enables Trace Analytics for all started spans.
+func WithAnalytics(on bool) Option {
+	if on {
+		return WithAnalyticsRate(1.0)
+	}
+	return WithAnalyticsRate(math.NaN())
+}
+
+// WithAnalyticsRate sets the sampling rate for Trace Analytics events
+// correlated to started spans.
+func WithAnalyticsRate(rate float64) Option {
+	return func(cfg *config) {
+		if rate >= 0.0 && rate <= 1.0 {
+			cfg.analyticsRate = rate
+		} else {
+			cfg.analyticsRate = math.NaN()
+		}
+	}
+}
+
+// WithoutSignalTracing disables the spans of the workflow signals, which are
+// traced by default.
+func WithoutSignalTracing() Option {
+	return func(cfg *config) {
+		cfg.disableSignalTracing = true
+	}
+}
+
+// WithoutQueryTracing disables the spans of the workflow queries, which are
+// traced by default.
+func WithoutQueryTracing() Option {
+	return func(cfg *config) {
+		cfg.disableQueryTracing = true
+	}
+}
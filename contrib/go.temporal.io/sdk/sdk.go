@@ -0,0 +1,189 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022 Datadog, Inc.
+
+// Package sdk provides an interceptor of the Temporal Go SDK (https://github.com/temporalio/sdk-go)
+// tracing the workflow executions, the activities, the child workflows, the
+// signals and the queries of the clients and the workers.
+//
+// The span contexts are propagated through the Temporal headers, so that the
+// workflows started by a client, their activities and their child workflows,
+// even when executed by other workers, show up in the same trace. The
+// propagation is deterministic: no span is started while a workflow is replayed.
+package sdk // import "gopkg.in/DataDog/dd-trace-go.v1/contrib/go.temporal.io/sdk"
+
+import (
+	"context"
+	"math"
+	"strings"
+	"unicode"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+
+	"go.temporal.io/sdk/interceptor"
+)
+
+const componentName = "temporal"
+
+const (
+	// headerKey is the key of the Temporal header holding the span context.
+	headerKey = "_datadog"
+
+	// Temporal tags.
+	tagWorkflowID = "temporal.workflow.id"
+	tagRunID      = "temporal.run.id"
+	tagActivityID = "temporal.activity.id"
+	tagOperation  = "temporal.operation"
+)
+
+// spanContextKey is the key of the span in the workflow contexts.
+type spanContextKey struct{}
+
+// temporalTags maps the tags set by the SDK to their name on the spans.
+var temporalTags = map[string]string{
+	"temporalWorkflowID": tagWorkflowID,
+	"temporalRunID":      tagRunID,
+	"temporalActivityID": tagActivityID,
+}
+
+// spanKinds holds the span kind of the operations traced by the SDK.
+var spanKinds = map[string]string{
+	"StartWorkflow":              ext.SpanKindProducer,
+	"SignalWithStartWorkflow":    ext.SpanKindProducer,
+	"SignalWorkflow":             ext.SpanKindProducer,
+	"SignalChildWorkflow":        ext.SpanKindProducer,
+	"SignalExternalWorkflow":     ext.SpanKindProducer,
+	"StartActivity":              ext.SpanKindProducer,
+	"StartChildWorkflow":         ext.SpanKindProducer,
+	"StartContinueAsNewWorkflow": ext.SpanKindProducer,
+	"QueryWorkflow":              ext.SpanKindClient,
+	"UpdateWorkflow":             ext.SpanKindClient,
+	"RunWorkflow":                ext.SpanKindConsumer,
+	"RunActivity":                ext.SpanKindConsumer,
+	"HandleSignal":               ext.SpanKindConsumer,
+	"HandleQuery":                ext.SpanKindServer,
+	"ValidateUpdate":             ext.SpanKindServer,
+	"HandleUpdate":               ext.SpanKindServer,
+}
+
+// NewInterceptor returns a Temporal interceptor tracing the workflows, the
+// activities, the signals and the queries. It must be set both in the
+// Interceptors of the client.Options, to trace the workflows started by the
+// client, and in the ones of the worker.Options, to trace their executions.
+func NewInterceptor(opts ...Option) interceptor.Interceptor {
+	cfg := new(config)
+	defaults(cfg)
+	for _, fn := range opts {
+		fn(cfg)
+	}
+	log.Debug("contrib/go.temporal.io/sdk: Configuring Interceptor: %#v", cfg)
+	return interceptor.NewTracingInterceptor(&ddTracer{cfg: cfg})
+}
+
+// ddTracer implements the tracer of the tracing interceptor of the SDK, which
+// starts the spans, propagates them through the headers and skips the
+// workflow replays.
+type ddTracer struct {
+	interceptor.BaseTracer
+	cfg *config
+}
+
+// span implements interceptor.TracerSpan.
+type span struct {
+	ddtrace.Span
+}
+
+// Finish finishes the span, with the error of the operation.
+func (s *span) Finish(opts *interceptor.TracerFinishSpanOptions) {
+	if opts != nil && opts.Error != nil {
+		s.Span.Finish(tracer.WithError(opts.Error))
+		return
+	}
+	s.Span.Finish()
+}
+
+func (t *ddTracer) Options() interceptor.TracerOptions {
+	return interceptor.TracerOptions{
+		SpanContextKey:       spanContextKey{},
+		HeaderKey:            headerKey,
+		DisableSignalTracing: t.cfg.disableSignalTracing,
+		DisableQueryTracing:  t.cfg.disableQueryTracing,
+	}
+}
+
+func (t *ddTracer) UnmarshalSpan(m map[string]string) (interceptor.TracerSpanRef, error) {
+	return tracer.Extract(tracer.TextMapCarrier(m))
+}
+
+func (t *ddTracer) MarshalSpan(s interceptor.TracerSpan) (map[string]string, error) {
+	carrier := tracer.TextMapCarrier{}
+	if err := tracer.Inject(s.(*span).Context(), carrier); err != nil {
+		return nil, err
+	}
+	return carrier, nil
+}
+
+func (t *ddTracer) SpanFromContext(ctx context.Context) interceptor.TracerSpan {
+	s, ok := tracer.SpanFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	return &span{Span: s}
+}
+
+func (t *ddTracer) ContextWithSpan(ctx context.Context, s interceptor.TracerSpan) context.Context {
+	return tracer.ContextWithSpan(ctx, s.(*span).Span)
+}
+
+func (t *ddTracer) StartSpan(opts *interceptor.TracerStartSpanOptions) (interceptor.TracerSpan, error) {
+	startOpts := []tracer.StartSpanOption{
+		tracer.Tag(ext.Component, componentName),
+		tracer.ServiceName(t.cfg.serviceName),
+		tracer.ResourceName(opts.Name),
+		tracer.Tag(tagOperation, opts.Operation),
+		tracer.Measured(),
+	}
+	if kind, ok := spanKinds[opts.Operation]; ok {
+		startOpts = append(startOpts, tracer.Tag(ext.SpanKind, kind))
+	}
+	switch parent := opts.Parent.(type) {
+	case *span:
+		startOpts = append(startOpts, tracer.ChildOf(parent.Context()))
+	case ddtrace.SpanContext:
+		startOpts = append(startOpts, tracer.ChildOf(parent))
+	}
+	if !opts.Time.IsZero() {
+		startOpts = append(startOpts, tracer.StartTime(opts.Time))
+	}
+	for k, v := range opts.Tags {
+		if name, ok := temporalTags[k]; ok {
+			k = name
+		}
+		startOpts = append(startOpts, tracer.Tag(k, v))
+	}
+	if !math.IsNaN(t.cfg.analyticsRate) {
+		startOpts = append(startOpts, tracer.Tag(ext.EventSampleRate, t.cfg.analyticsRate))
+	}
+	return &span{Span: tracer.StartSpan(spanName(opts.Operation), startOpts...)}, nil
+}
+
+// spanName returns the name of the spans of the given operation, e.g.
+// temporal.run_workflow for RunWorkflow.
+func spanName(operation string) string {
+	var sb strings.Builder
+	sb.WriteString("temporal.")
+	for i, r := range operation {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				sb.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
@@ -0,0 +1,115 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022 Datadog, Inc.
+
+package sdk
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/interceptor"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/worker"
+	"go.temporal.io/sdk/workflow"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
+)
+
+func greet(_ context.Context, name string) (string, error) {
+	if name == "" {
+		return "", errors.New("no name")
+	}
+	return "Hello " + name, nil
+}
+
+func childWorkflow(ctx workflow.Context, name string) (string, error) {
+	ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: time.Minute,
+		RetryPolicy:         &temporal.RetryPolicy{MaximumAttempts: 1},
+	})
+	var greeting string
+	err := workflow.ExecuteActivity(ctx, greet, name).Get(ctx, &greeting)
+	return greeting, err
+}
+
+func parentWorkflow(ctx workflow.Context, name string) (string, error) {
+	var greeting string
+	err := workflow.ExecuteChildWorkflow(ctx, childWorkflow, name).Get(ctx, &greeting)
+	return greeting, err
+}
+
+func runWorkflow(t *testing.T, name string, opts ...Option) error {
+	var s testsuite.WorkflowTestSuite
+	env := s.NewTestWorkflowEnvironment()
+	env.SetWorkerOptions(worker.Options{
+		Interceptors: []interceptor.WorkerInterceptor{NewInterceptor(opts...)},
+	})
+	env.RegisterWorkflow(parentWorkflow)
+	env.RegisterWorkflow(childWorkflow)
+	env.RegisterActivity(greet)
+	env.ExecuteWorkflow(parentWorkflow, name)
+	require.True(t, env.IsWorkflowCompleted())
+	return env.GetWorkflowError()
+}
+
+func TestInterceptor(t *testing.T) {
+	assert := assert.New(t)
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	require.NoError(t, runWorkflow(t, "bob", WithServiceName("workers")))
+
+	spans := mt.FinishedSpans()
+	byName := make(map[string]mocktracer.Span)
+	for _, s := range spans {
+		assert.Equal("workers", s.Tag(ext.ServiceName))
+		assert.Equal(spans[0].TraceID(), s.TraceID())
+		byName[s.OperationName()+" "+s.Tag(ext.ResourceName).(string)] = s
+	}
+	parent := byName["temporal.run_workflow parentWorkflow"]
+	startChild := byName["temporal.start_child_workflow childWorkflow"]
+	child := byName["temporal.run_workflow childWorkflow"]
+	startActivity := byName["temporal.start_activity greet"]
+	activity := byName["temporal.run_activity greet"]
+	for _, s := range []mocktracer.Span{parent, startChild, child, startActivity, activity} {
+		require.NotNil(t, s)
+	}
+	assert.Equal(ext.SpanKindConsumer, parent.Tag(ext.SpanKind))
+	assert.NotEmpty(parent.Tag(tagWorkflowID))
+	assert.NotEmpty(parent.Tag(tagRunID))
+	assert.Equal(parent.SpanID(), startChild.ParentID())
+	assert.Equal(startChild.SpanID(), child.ParentID())
+	assert.Equal(child.SpanID(), startActivity.ParentID())
+	assert.Equal(startActivity.SpanID(), activity.ParentID())
+	assert.Equal(ext.SpanKindProducer, startActivity.Tag(ext.SpanKind))
+	assert.NotEmpty(activity.Tag(tagActivityID))
+}
+
+func TestInterceptorError(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	require.Error(t, runWorkflow(t, ""))
+
+	var activity mocktracer.Span
+	for _, s := range mt.FinishedSpans() {
+		if s.OperationName() == "temporal.run_activity" {
+			activity = s
+		}
+	}
+	require.NotNil(t, activity)
+	assert.Contains(t, activity.Tag(ext.Error).(error).Error(), "no name")
+}
+
+func TestSpanName(t *testing.T) {
+	assert.Equal(t, "temporal.run_workflow", spanName("RunWorkflow"))
+	assert.Equal(t, "temporal.signal_with_start_workflow", spanName("SignalWithStartWorkflow"))
+}
@@ -36,3 +36,24 @@ func Example() {
 	// Execute your query as usual
 	tracedQuery.Exec()
 }
+
+// To trace every attempt at executing the queries and batches of a session,
+// including retries and the fetches of the pages of the results, register
+// an Observer on its cluster.
+func ExampleNewObserver() {
+	hosts := []string{"127.0.0.1"}
+	cluster := gocql.NewCluster(hosts...)
+	obs := gocqltrace.NewObserver(
+		gocqltrace.WithServiceName("ServiceName"),
+		gocqltrace.WithContactPoints(hosts...),
+	)
+	cluster.QueryObserver = obs
+	cluster.BatchObserver = obs
+	session, _ := cluster.CreateSession()
+
+	// Use context to pass information down the call chain
+	_, ctx := tracer.StartSpanFromContext(context.Background(), "parent.request")
+
+	// Queries and batches are traced as children of the span held by their context
+	session.Query("SELECT * FROM trace.person").WithContext(ctx).Exec()
+}
@@ -7,11 +7,13 @@
 package gocql // import "gopkg.in/DataDog/dd-trace-go.v1/contrib/gocql/gocql"
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"math"
 	"strconv"
 	"strings"
+	"time"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
@@ -21,6 +23,8 @@ import (
 	"github.com/gocql/gocql"
 )
 
+const componentName = "gocql"
+
 // Query inherits from gocql.Query, it keeps the tracer and the context.
 type Query struct {
 	*gocql.Query
@@ -31,7 +35,8 @@ type Query struct {
 // Iter inherits from gocql.Iter and contains a span.
 type Iter struct {
 	*gocql.Iter
-	span ddtrace.Span
+	span  ddtrace.Span
+	query *Query
 }
 
 // Scanner inherits from a gocql.Scanner derived from an Iter
@@ -50,7 +55,6 @@ type Batch struct {
 // params containes fields and metadata useful for command tracing
 type params struct {
 	config    *queryConfig
-	keyspace  string
 	paginated bool
 }
 
@@ -94,19 +98,24 @@ func (tq *Query) PageState(state []byte) *Query {
 }
 
 // NewChildSpan creates a new span from the params and the context.
-func (tq *Query) newChildSpan(ctx context.Context) ddtrace.Span {
+func (tq *Query) newChildSpan(ctx context.Context, extraOpts ...ddtrace.StartSpanOption) ddtrace.Span {
 	p := tq.params
 	opts := []ddtrace.StartSpanOption{
+		tracer.Tag(ext.Component, componentName),
 		tracer.SpanType(ext.SpanTypeCassandra),
 		tracer.ServiceName(p.config.serviceName),
 		tracer.ResourceName(p.config.resourceName),
 		tracer.Tag(ext.CassandraPaginated, fmt.Sprintf("%t", p.paginated)),
-		tracer.Tag(ext.CassandraKeyspace, p.keyspace),
+		tracer.Tag(ext.CassandraKeyspace, tq.Query.Keyspace()),
+		tracer.Tag(ext.CassandraConsistencyLevel, tq.GetConsistency().String()),
+	}
+	if p.config.contactPoints != "" {
+		opts = append(opts, tracer.Tag(ext.CassandraContactPoints, p.config.contactPoints))
 	}
 	if !math.IsNaN(p.config.analyticsRate) {
 		opts = append(opts, tracer.Tag(ext.EventSampleRate, p.config.analyticsRate))
 	}
-	span, _ := tracer.StartSpanFromContext(ctx, ext.CassandraQuery, opts...)
+	span, _ := tracer.StartSpanFromContext(ctx, ext.CassandraQuery, append(opts, extraOpts...)...)
 	return span
 }
 
@@ -154,20 +163,50 @@ func (tq *Query) ScanCAS(dest ...interface{}) (applied bool, err error) {
 func (tq *Query) Iter() *Iter {
 	span := tq.newChildSpan(tq.ctx)
 	iter := tq.Query.Iter()
-	span.SetTag(ext.CassandraRowCount, strconv.Itoa(iter.NumRows()))
-	span.SetTag(ext.CassandraConsistencyLevel, tq.GetConsistency().String())
+	setIterTags(span, iter)
+	return &Iter{Iter: iter, span: span, query: tq}
+}
 
+// setIterTags sets the tags describing the results held by iter on span.
+func setIterTags(span ddtrace.Span, iter *gocql.Iter) {
+	span.SetTag(ext.CassandraRowCount, strconv.Itoa(iter.NumRows()))
 	columns := iter.Columns()
 	if len(columns) > 0 {
 		span.SetTag(ext.CassandraKeyspace, columns[0].Keyspace)
 	}
-	tIter := &Iter{iter, span}
-	if tIter.Host() != nil {
-		tIter.span.SetTag(ext.TargetHost, tIter.Iter.Host().HostID())
-		tIter.span.SetTag(ext.TargetPort, strconv.Itoa(tIter.Iter.Host().Port()))
-		tIter.span.SetTag(ext.CassandraCluster, tIter.Iter.Host().DataCenter())
+	if host := iter.Host(); host != nil {
+		span.SetTag(ext.TargetHost, host.HostID())
+		span.SetTag(ext.TargetPort, strconv.Itoa(host.Port()))
+		span.SetTag(ext.CassandraCluster, host.DataCenter())
 	}
-	return tIter
+}
+
+// Scan wraps Iter.Scan. When the current page of the results is exhausted and
+// Scan fetches the next one, the fetch is traced by a span of its own, child of
+// the span of the Iter. Note that only the pages fetched by Scan are traced,
+// unlike those fetched through MapScan, SliceMap or a Scanner.
+func (tIter *Iter) Scan(dest ...interface{}) bool {
+	state := tIter.Iter.PageState()
+	if len(state) == 0 {
+		// last page, there is nothing left to fetch
+		return tIter.Iter.Scan(dest...)
+	}
+	start := time.Now()
+	ok := tIter.Iter.Scan(dest...)
+	if !bytes.Equal(state, tIter.Iter.PageState()) {
+		// the next page was fetched
+		tIter.tracePage(start)
+	}
+	return ok
+}
+
+// tracePage traces the fetch of the current page of the results, started at the given time.
+func (tIter *Iter) tracePage(start time.Time) {
+	ctx := tracer.ContextWithSpan(tIter.query.ctx, tIter.span)
+	span := tIter.query.newChildSpan(ctx, tracer.StartTime(start))
+	span.SetTag(ext.CassandraPaginated, "true")
+	setIterTags(span, tIter.Iter)
+	span.Finish()
 }
 
 // Close closes the Iter and finish the span created on Iter call.
@@ -244,16 +283,38 @@ func (tb *Batch) ExecuteBatch(session *gocql.Session) error {
 	return err
 }
 
+// ExecuteBatchCAS calls session.ExecuteBatchCAS on the Batch, tracing the execution.
+// The returned iterator is not traced.
+func (tb *Batch) ExecuteBatchCAS(session *gocql.Session, dest ...interface{}) (applied bool, iter *gocql.Iter, err error) {
+	span := tb.newChildSpan(tb.ctx)
+	applied, iter, err = session.ExecuteBatchCAS(tb.Batch, dest...)
+	tb.finishSpan(span, err)
+	return applied, iter, err
+}
+
+// MapExecuteBatchCAS calls session.MapExecuteBatchCAS on the Batch, tracing the execution.
+// The returned iterator is not traced.
+func (tb *Batch) MapExecuteBatchCAS(session *gocql.Session, dest map[string]interface{}) (applied bool, iter *gocql.Iter, err error) {
+	span := tb.newChildSpan(tb.ctx)
+	applied, iter, err = session.MapExecuteBatchCAS(tb.Batch, dest)
+	tb.finishSpan(span, err)
+	return applied, iter, err
+}
+
 // newChildSpan creates a new span from the params and the context.
 func (tb *Batch) newChildSpan(ctx context.Context) ddtrace.Span {
 	p := tb.params
 	opts := []ddtrace.StartSpanOption{
+		tracer.Tag(ext.Component, componentName),
 		tracer.SpanType(ext.SpanTypeCassandra),
 		tracer.ServiceName(p.config.serviceName),
 		tracer.ResourceName(p.config.resourceName),
 		tracer.Tag(ext.CassandraConsistencyLevel, tb.Cons.String()),
 		tracer.Tag(ext.CassandraKeyspace, tb.Keyspace()),
 	}
+	if p.config.contactPoints != "" {
+		opts = append(opts, tracer.Tag(ext.CassandraContactPoints, p.config.contactPoints))
+	}
 	if !math.IsNaN(p.config.analyticsRate) {
 		opts = append(opts, tracer.Tag(ext.EventSampleRate, p.config.analyticsRate))
 	}
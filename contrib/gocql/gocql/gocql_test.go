@@ -7,6 +7,7 @@ package gocql
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"math"
@@ -348,3 +349,136 @@ func TestBatch(t *testing.T) {
 	assert.Equal(childSpan.Tag(ext.ResourceName), "BatchInsert")
 	assert.Equal(childSpan.Tag(ext.CassandraKeyspace), "trace")
 }
+
+func TestBatchCAS(t *testing.T) {
+	assert := assert.New(t)
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	cluster := newCassandraCluster()
+	cluster.Keyspace = "trace"
+	session, err := cluster.CreateSession()
+	assert.NoError(err)
+
+	tb := WrapBatch(session.NewBatch(gocql.LoggedBatch), WithResourceName("BatchCAS"), WithContactPoints(cassandraHost))
+	tb.Query("INSERT INTO trace.person (name, age, description) VALUES (?, ?, ?) IF NOT EXISTS", "Cassandra", 100, "A cruel mistress")
+	applied, _, err := tb.ExecuteBatchCAS(session)
+	assert.NoError(err)
+	assert.False(applied)
+
+	spans := mt.FinishedSpans()
+	assert.Len(spans, 1)
+	span := spans[0]
+	assert.Equal(ext.CassandraBatch, span.OperationName())
+	assert.Equal("BatchCAS", span.Tag(ext.ResourceName))
+	assert.Equal("trace", span.Tag(ext.CassandraKeyspace))
+	assert.Equal(cassandraHost, span.Tag(ext.CassandraContactPoints))
+	assert.Nil(span.Tag(ext.Error))
+}
+
+func TestIterPages(t *testing.T) {
+	assert := assert.New(t)
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	cluster := newCassandraCluster()
+	session, err := cluster.CreateSession()
+	assert.NoError(err)
+	stmt := "INSERT INTO trace.person (name, age, description) VALUES (?, ?, ?)"
+	assert.NoError(session.Query(stmt, "Kate", 80, "Cassandra's sister").Exec())
+	assert.NoError(session.Query(stmt, "Lucas", 60, "Another person").Exec())
+
+	q := session.Query("SELECT name FROM trace.person").PageSize(1)
+	iter := WrapQuery(q, WithResourceName("SelectNames")).Iter()
+	var name string
+	rows := 0
+	for iter.Scan(&name) {
+		rows++
+	}
+	assert.NoError(iter.Close())
+	assert.True(rows >= 3)
+
+	// the last page may be empty
+	spans := mt.FinishedSpans()
+	assert.True(len(spans) >= rows)
+	root := spans[len(spans)-1]
+	assert.Equal(ext.CassandraQuery, root.OperationName())
+	assert.Equal("1", root.Tag(ext.CassandraRowCount))
+	assert.Equal("QUORUM", root.Tag(ext.CassandraConsistencyLevel))
+	for _, page := range spans[:len(spans)-1] {
+		assert.Equal(ext.CassandraQuery, page.OperationName())
+		assert.Equal("SelectNames", page.Tag(ext.ResourceName))
+		assert.Equal("true", page.Tag(ext.CassandraPaginated))
+		assert.Equal(root.SpanID(), page.ParentID())
+	}
+}
+
+func TestObserver(t *testing.T) {
+	assert := assert.New(t)
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	cluster := newCassandraCluster()
+	obs := NewObserver(WithServiceName("TestServiceName"), WithContactPoints(cassandraHost))
+	cluster.QueryObserver = obs
+	cluster.BatchObserver = obs
+	session, err := cluster.CreateSession()
+	assert.NoError(err)
+
+	parentSpan, ctx := tracer.StartSpanFromContext(context.Background(), "parentSpan")
+	assert.NoError(session.Query("SELECT * FROM trace.person").WithContext(ctx).Exec())
+	b := session.NewBatch(gocql.UnloggedBatch).WithContext(ctx)
+	b.Query("INSERT INTO trace.person (name, age, description) VALUES (?, ?, ?)", "Kate", 80, "Cassandra's sister")
+	assert.NoError(session.ExecuteBatch(b))
+	parentSpan.Finish()
+
+	spans := mt.FinishedSpans()
+	assert.Len(spans, 3)
+	query, batch := spans[0], spans[1]
+
+	assert.Equal(ext.CassandraQuery, query.OperationName())
+	assert.Equal("SELECT * FROM trace.person", query.Tag(ext.ResourceName))
+	assert.Equal("TestServiceName", query.Tag(ext.ServiceName))
+	assert.Equal(0, query.Tag(ext.CassandraAttempt))
+	assert.Equal(cassandraHost, query.Tag(ext.CassandraContactPoints))
+	assert.Equal("9042", query.Tag(ext.TargetPort))
+	assert.Equal(parentSpan.Context().SpanID(), query.ParentID())
+
+	assert.Equal(ext.CassandraBatch, batch.OperationName())
+	assert.Equal(parentSpan.Context().SpanID(), batch.ParentID())
+	assert.Nil(batch.Tag(ext.Error))
+}
+
+func TestObserverErrorCheck(t *testing.T) {
+	assert := assert.New(t)
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	errTimeout := errors.New("timeout")
+	obs := NewObserver(WithErrorCheck(func(err error) bool { return err != gocql.ErrNotFound }))
+	start := time.Now()
+	obs.ObserveQuery(context.Background(), gocql.ObservedQuery{
+		Keyspace:  "trace",
+		Statement: "SELECT * FROM trace.person",
+		Start:     start,
+		End:       start.Add(time.Second),
+		Err:       errTimeout,
+		Attempt:   1,
+	})
+	obs.ObserveQuery(context.Background(), gocql.ObservedQuery{
+		Statement: "SELECT * FROM trace.person",
+		Start:     start,
+		End:       start.Add(time.Second),
+		Err:       gocql.ErrNotFound,
+		Attempt:   2,
+	})
+
+	spans := mt.FinishedSpans()
+	assert.Len(spans, 2)
+	assert.Equal(errTimeout, spans[0].Tag(ext.Error))
+	assert.Equal(1, spans[0].Tag(ext.CassandraAttempt))
+	assert.Equal("trace", spans[0].Tag(ext.CassandraKeyspace))
+	assert.Equal(start, spans[0].StartTime())
+	assert.Equal(time.Second, spans[0].FinishTime().Sub(spans[0].StartTime()))
+	assert.Nil(spans[1].Tag(ext.Error))
+}
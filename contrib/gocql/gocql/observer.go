@@ -0,0 +1,109 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package gocql
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+
+	"github.com/gocql/gocql"
+)
+
+// Observer implements gocql.QueryObserver and gocql.BatchObserver to trace the
+// queries and batches of a session without wrapping them. Unlike WrapQuery and
+// WrapBatch, it traces every attempt at executing them, so that retries and
+// the fetches of the pages of the results each get a span of their own,
+// timed by gocql.
+type Observer struct {
+	cfg *queryConfig
+}
+
+var (
+	_ gocql.QueryObserver = (*Observer)(nil)
+	_ gocql.BatchObserver = (*Observer)(nil)
+)
+
+// NewObserver returns an Observer tracing the queries and batches it observes.
+// The resource name of the query spans is their statement unless WithResourceName
+// is used. It is registered on a cluster before creating its sessions:
+//
+//	cluster := gocql.NewCluster(hosts...)
+//	obs := gocqltrace.NewObserver(gocqltrace.WithContactPoints(hosts...))
+//	cluster.QueryObserver = obs
+//	cluster.BatchObserver = obs
+//
+// The spans are children of the spans held by the contexts of the queries and batches.
+func NewObserver(opts ...WrapOption) *Observer {
+	cfg := new(queryConfig)
+	defaults(cfg)
+	for _, fn := range opts {
+		fn(cfg)
+	}
+	log.Debug("contrib/gocql/gocql: Creating Observer: %#v", cfg)
+	return &Observer{cfg: cfg}
+}
+
+// ObserveQuery implements gocql.QueryObserver.
+func (o *Observer) ObserveQuery(ctx context.Context, q gocql.ObservedQuery) {
+	resource := o.cfg.resourceName
+	if resource == "" {
+		resource = q.Statement
+	}
+	span := o.startSpan(ctx, ext.CassandraQuery, resource, q.Keyspace, q.Host, q.Attempt, q.Start)
+	span.SetTag(ext.CassandraRowCount, strconv.Itoa(q.Rows))
+	o.finishSpan(span, q.Err, q.End)
+}
+
+// ObserveBatch implements gocql.BatchObserver.
+func (o *Observer) ObserveBatch(ctx context.Context, b gocql.ObservedBatch) {
+	span := o.startSpan(ctx, ext.CassandraBatch, o.cfg.resourceName, b.Keyspace, b.Host, b.Attempt, b.Start)
+	o.finishSpan(span, b.Err, b.End)
+}
+
+func (o *Observer) startSpan(ctx context.Context, operation, resource, keyspace string, host *gocql.HostInfo, attempt int, start time.Time) ddtrace.Span {
+	opts := []ddtrace.StartSpanOption{
+		tracer.Tag(ext.Component, componentName),
+		tracer.SpanType(ext.SpanTypeCassandra),
+		tracer.ServiceName(o.cfg.serviceName),
+		tracer.ResourceName(resource),
+		tracer.StartTime(start),
+		tracer.Tag(ext.CassandraKeyspace, keyspace),
+		tracer.Tag(ext.CassandraAttempt, attempt),
+	}
+	if o.cfg.contactPoints != "" {
+		opts = append(opts, tracer.Tag(ext.CassandraContactPoints, o.cfg.contactPoints))
+	}
+	if host != nil {
+		opts = append(opts,
+			tracer.Tag(ext.TargetHost, host.HostID()),
+			tracer.Tag(ext.TargetPort, strconv.Itoa(host.Port())),
+			tracer.Tag(ext.CassandraCluster, host.DataCenter()),
+		)
+	}
+	if !math.IsNaN(o.cfg.analyticsRate) {
+		opts = append(opts, tracer.Tag(ext.EventSampleRate, o.cfg.analyticsRate))
+	}
+	span, _ := tracer.StartSpanFromContext(ctx, operation, opts...)
+	return span
+}
+
+func (o *Observer) finishSpan(span ddtrace.Span, err error, end time.Time) {
+	if err != nil && o.cfg.shouldIgnoreError(err) {
+		err = nil
+	}
+	opts := []ddtrace.FinishOption{tracer.WithError(err), tracer.FinishTime(end)}
+	if o.cfg.noDebugStack {
+		opts = append(opts, tracer.NoDebugStack())
+	}
+	span.Finish(opts...)
+}
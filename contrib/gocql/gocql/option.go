@@ -7,8 +7,10 @@ package gocql
 
 import (
 	"math"
+	"strings"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/internal"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/namingschema"
 )
 
 type queryConfig struct {
@@ -16,13 +18,14 @@ type queryConfig struct {
 	noDebugStack              bool
 	analyticsRate             float64
 	errCheck                  func(err error) bool
+	contactPoints             string
 }
 
 // WrapOption represents an option that can be passed to WrapQuery.
 type WrapOption func(*queryConfig)
 
 func defaults(cfg *queryConfig) {
-	cfg.serviceName = "gocql.query"
+	cfg.serviceName = namingschema.ServiceName("gocql.query")
 	// cfg.analyticsRate = globalconfig.AnalyticsRate()
 	if internal.BoolEnv("DD_TRACE_GOCQL_ANALYTICS_ENABLED", false) {
 		cfg.analyticsRate = 1.0
@@ -74,6 +77,14 @@ func WithAnalyticsRate(rate float64) WrapOption {
 	}
 }
 
+// WithContactPoints sets the contact points of the cluster the queries are sent to,
+// usually the hosts of its gocql.ClusterConfig, as a tag of the started spans.
+func WithContactPoints(hosts ...string) WrapOption {
+	return func(cfg *queryConfig) {
+		cfg.contactPoints = strings.Join(hosts, ",")
+	}
+}
+
 // NoDebugStack prevents stack traces from being attached to spans finishing
 // with an error. This is useful in situations where errors are frequent and
 // performance is critical.
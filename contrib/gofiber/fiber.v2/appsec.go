@@ -0,0 +1,57 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package fiber
+
+import (
+	"net"
+	"net/http"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo/instrumentation"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo/instrumentation/httpsec"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp/fasthttpadaptor"
+)
+
+// useAppSec executes the AppSec logic related to the operation start and
+// returns the function to be executed upon finishing the operation with the
+// response status. The fasthttp request is converted into a net/http one for
+// AppSec to monitor it. When AppSec blocks the request, the blocking response
+// is written and blocked is true: the request must not be handled.
+func useAppSec(c *fiber.Ctx, span tracer.Span) (afterMiddleware func(status int), blocked bool) {
+	var req http.Request
+	if err := fasthttpadaptor.ConvertRequest(c.Context(), &req, true); err != nil {
+		log.Debug("contrib/gofiber/fiber.v2: appsec: could not convert the request: %v", err)
+		return func(int) {}, false
+	}
+	instrumentation.SetAppSecEnabledTags(span)
+	// the route parameters are not known yet when the middleware is called
+	args := httpsec.MakeHandlerOperationArgs(&req, nil)
+	ctx, op := httpsec.StartOperation(c.UserContext(), args)
+	c.SetUserContext(ctx)
+	httpsec.MonitorRequestBody(ctx, &req)
+	if op.Err() != nil {
+		fasthttpadaptor.NewFastHTTPHandlerFunc(httpsec.WriteBlockingResponse)(c.Context())
+		blocked = true
+	}
+	return func(status int) {
+		headers := make(http.Header)
+		c.Response().Header.VisitAll(func(k, v []byte) {
+			headers.Add(string(k), string(v))
+		})
+		events := op.Finish(httpsec.HandlerOperationRes{Status: status, Headers: httpsec.MakeResponseHeaders(headers)})
+		if len(events) > 0 {
+			remoteIP, _, err := net.SplitHostPort(req.RemoteAddr)
+			if err != nil {
+				remoteIP = req.RemoteAddr
+			}
+			httpsec.SetSecurityEventTags(span, events, remoteIP, args.Headers, headers)
+		}
+		instrumentation.SetTags(span, op.Tags())
+	}, blocked
+}
@@ -15,13 +15,18 @@ import (
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/namingschema"
 
 	"github.com/gofiber/fiber/v2"
 )
 
+const componentName = "fiber"
+
 // Middleware returns middleware that will trace incoming requests.
 func Middleware(opts ...Option) func(c *fiber.Ctx) error {
+	appsecEnabled := appsec.Enabled()
 	cfg := new(config)
 	defaults(cfg)
 	for _, fn := range opts {
@@ -29,7 +34,11 @@ func Middleware(opts ...Option) func(c *fiber.Ctx) error {
 	}
 	log.Debug("gofiber/fiber.v2: Middleware: %#v", cfg)
 	return func(c *fiber.Ctx) error {
+		if cfg.ignoreRequestFunc != nil && cfg.ignoreRequestFunc(c) {
+			return c.Next()
+		}
 		opts := []ddtrace.StartSpanOption{
+			tracer.Tag(ext.Component, componentName),
 			tracer.SpanType(ext.SpanTypeWeb),
 			tracer.ServiceName(cfg.serviceName),
 			tracer.Tag(ext.HTTPMethod, c.Method()),
@@ -41,19 +50,37 @@ func Middleware(opts ...Option) func(c *fiber.Ctx) error {
 		}
 
 		opts = append(opts, cfg.spanOpts...)
-		span, ctx := tracer.StartSpanFromContext(c.Context(), "http.request", opts...)
+		span, ctx := tracer.StartSpanFromContext(c.Context(), namingschema.HTTPServerOp("http.request"), opts...)
 
 		defer span.Finish()
 
 		// pass the span through the request UserContext
 		c.SetUserContext(ctx)
 
-		// pass the execution down the line
-		err := c.Next()
+		var (
+			afterAppSec func(status int)
+			blocked     bool
+			err         error
+		)
+		if appsecEnabled {
+			afterAppSec, blocked = useAppSec(c, span)
+		}
+		if !blocked {
+			// pass the execution down the line
+			err = c.Next()
+		}
 
 		span.SetTag(ext.ResourceName, cfg.resourceNamer(c))
 
 		status := c.Response().StatusCode()
+		if err != nil {
+			// the error handler of the app writes the response once the
+			// middleware returned, with the status of the error by default
+			status = fiber.StatusInternalServerError
+			if ferr, ok := err.(*fiber.Error); ok {
+				status = ferr.Code
+			}
+		}
 		// on the off chance we don't yet have a status after the rest of the things have run
 		if status == 0 {
 			// 0 - means we do not have a status code at this point
@@ -61,6 +88,9 @@ func Middleware(opts ...Option) func(c *fiber.Ctx) error {
 			status = http.StatusOK
 		}
 		span.SetTag(ext.HTTPCode, strconv.Itoa(status))
+		if afterAppSec != nil {
+			afterAppSec(status)
+		}
 
 		if err != nil {
 			span.SetTag(ext.Error, err)
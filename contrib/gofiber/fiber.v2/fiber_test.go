@@ -6,18 +6,22 @@
 package fiber
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestChildSpan(t *testing.T) {
@@ -277,3 +281,138 @@ func TestAnalyticsSettings(t *testing.T) {
 		assertRate(t, mt, 0.23, WithAnalyticsRate(0.23))
 	})
 }
+
+func TestErrorStatus(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{name: "fiber-error", err: fiber.ErrNotFound, status: http.StatusNotFound},
+		{name: "error", err: errors.New("oops"), status: http.StatusInternalServerError},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mt := mocktracer.Start()
+			defer mt.Stop()
+
+			router := fiber.New()
+			router.Use(Middleware())
+			router.Get("/err", func(c *fiber.Ctx) error {
+				return tc.err
+			})
+
+			response, err := router.Test(httptest.NewRequest("GET", "/err", nil))
+			require.NoError(t, err)
+			// the status of the span is the one written by the error handler
+			assert.Equal(t, tc.status, response.StatusCode)
+
+			spans := mt.FinishedSpans()
+			require.Len(t, spans, 1)
+			assert.Equal(t, fmt.Sprint(tc.status), spans[0].Tag(ext.HTTPCode))
+			assert.Equal(t, tc.err, spans[0].Tag(ext.Error))
+		})
+	}
+}
+
+func TestIgnoreRequest(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	router := fiber.New()
+	router.Use(Middleware(WithIgnoreRequest(func(c *fiber.Ctx) bool {
+		return c.Path() == "/health"
+	})))
+	router.Get("/health", func(c *fiber.Ctx) error {
+		return c.SendString("ok")
+	})
+	router.Get("/user/:id", func(c *fiber.Ctx) error {
+		return c.SendString(c.Params("id"))
+	})
+
+	response, err := router.Test(httptest.NewRequest("GET", "/health", nil))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Len(t, mt.FinishedSpans(), 0)
+
+	_, err = router.Test(httptest.NewRequest("GET", "/user/123", nil))
+	require.NoError(t, err)
+	assert.Len(t, mt.FinishedSpans(), 1)
+}
+
+func TestAppSec(t *testing.T) {
+	appsec.Start()
+	defer appsec.Stop()
+	if !appsec.Enabled() {
+		t.Skip("appsec disabled")
+	}
+
+	router := fiber.New()
+	router.Use(Middleware())
+	router.Post("/", func(c *fiber.Ctx) error {
+		return c.SendString("Hello World!\n")
+	})
+
+	t.Run("request-uri", func(t *testing.T) {
+		mt := mocktracer.Start()
+		defer mt.Stop()
+		// Send an LFI attack (according to appsec rule id crs-930-110)
+		req := httptest.NewRequest("POST", "/../../../secret.txt", nil)
+		res, err := router.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusNotFound, res.StatusCode)
+
+		finished := mt.FinishedSpans()
+		require.Len(t, finished, 1)
+		event, ok := finished[0].Tag("_dd.appsec.json").(string)
+		require.True(t, ok)
+		require.True(t, strings.Contains(event, "crs-930-110"))
+		require.True(t, strings.Contains(event, "server.request.uri.raw"))
+	})
+}
+
+func TestAppSecBlocking(t *testing.T) {
+	t.Setenv("DD_APPSEC_RULES", "../../../internal/appsec/testdata/blocking.json")
+	appsec.Start()
+	defer appsec.Stop()
+	if !appsec.Enabled() {
+		t.Skip("appsec disabled")
+	}
+
+	var handled bool
+	router := fiber.New()
+	router.Use(Middleware())
+	router.Get("/", func(c *fiber.Ctx) error {
+		handled = true
+		return c.SendString("Hello World!\n")
+	})
+
+	for _, tc := range []struct {
+		name      string
+		userAgent string
+		status    int
+	}{
+		{name: "no-block", userAgent: "dd-test-scanner-log", status: http.StatusOK},
+		{name: "block", userAgent: "dd-test-scanner-log-block", status: http.StatusForbidden},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mt := mocktracer.Start()
+			defer mt.Stop()
+			handled = false
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("User-Agent", tc.userAgent)
+			res, err := router.Test(req)
+			require.NoError(t, err)
+			require.Equal(t, tc.status, res.StatusCode)
+
+			finished := mt.FinishedSpans()
+			require.Len(t, finished, 1)
+			if tc.status == http.StatusForbidden {
+				require.False(t, handled)
+				require.Equal(t, true, finished[0].Tag("appsec.blocked"))
+			} else {
+				require.True(t, handled)
+				require.Nil(t, finished[0].Tag("appsec.blocked"))
+			}
+		})
+	}
+}
@@ -21,11 +21,16 @@ type config struct {
 	spanOpts      []ddtrace.StartSpanOption // additional span options to be applied
 	analyticsRate float64
 	resourceNamer func(*fiber.Ctx) string
+	// ignoreRequestFunc determines whether a request is not traced
+	ignoreRequestFunc IgnoreRequestFunc
 }
 
 // Option represents an option that can be passed to NewRouter.
 type Option func(*config)
 
+// IgnoreRequestFunc determines if tracing will be skipped for a request.
+type IgnoreRequestFunc func(c *fiber.Ctx) bool
+
 func defaults(cfg *config) {
 	cfg.serviceName = "fiber"
 	cfg.isStatusError = isServerError
@@ -95,6 +100,14 @@ func WithResourceNamer(fn func(*fiber.Ctx) string) Option {
 	}
 }
 
+// WithIgnoreRequest sets a function which determines if tracing will be
+// skipped for a given request, e.g. health checks.
+func WithIgnoreRequest(fn IgnoreRequestFunc) Option {
+	return func(cfg *config) {
+		cfg.ignoreRequestFunc = fn
+	}
+}
+
 func defaultResourceNamer(c *fiber.Ctx) string {
 	r := c.Route()
 	return r.Method + " " + r.Path
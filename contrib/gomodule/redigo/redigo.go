@@ -24,6 +24,8 @@ import (
 	redis "github.com/gomodule/redigo/redis"
 )
 
+const componentName = "redigo"
+
 // Conn is an implementation of the redis.Conn interface that supports tracing
 type Conn struct {
 	redis.Conn
@@ -115,6 +117,7 @@ func DialURL(rawurl string, options ...interface{}) (redis.Conn, error) {
 // newChildSpan creates a span inheriting from the given context. It adds to the span useful metadata about the traced Redis connection
 func newChildSpan(ctx context.Context, p *params) ddtrace.Span {
 	opts := []ddtrace.StartSpanOption{
+		tracer.Tag(ext.Component, componentName),
 		tracer.SpanType(ext.SpanTypeRedis),
 		tracer.ServiceName(p.config.serviceName),
 	}
@@ -18,6 +18,7 @@ import (
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/namingschema"
 
 	context "golang.org/x/net/context"
 	"google.golang.org/grpc"
@@ -25,6 +26,8 @@ import (
 	"google.golang.org/grpc/peer"
 )
 
+const componentName = "grpc"
+
 // UnaryServerInterceptor will trace requests to the given grpc server.
 func UnaryServerInterceptor(opts ...InterceptorOption) grpc.UnaryServerInterceptor {
 	cfg := new(interceptorConfig)
@@ -49,6 +52,7 @@ func UnaryServerInterceptor(opts ...InterceptorOption) grpc.UnaryServerIntercept
 
 func startSpanFromContext(ctx context.Context, method, service string, rate float64) (ddtrace.Span, context.Context) {
 	opts := []ddtrace.StartSpanOption{
+		tracer.Tag(ext.Component, componentName),
 		tracer.ServiceName(service),
 		tracer.ResourceName(method),
 		tracer.Tag(tagMethod, method),
@@ -62,7 +66,7 @@ func startSpanFromContext(ctx context.Context, method, service string, rate floa
 	if sctx, err := tracer.Extract(grpcutil.MDCarrier(md)); err == nil {
 		opts = append(opts, tracer.ChildOf(sctx))
 	}
-	return tracer.StartSpanFromContext(ctx, "grpc.server", opts...)
+	return tracer.StartSpanFromContext(ctx, namingschema.GRPCServerOp("grpc.server"), opts...)
 }
 
 // UnaryClientInterceptor will add tracing to a grpc client.
@@ -73,7 +77,7 @@ func UnaryClientInterceptor(opts ...InterceptorOption) grpc.UnaryClientIntercept
 		fn(cfg)
 	}
 	if cfg.serviceName == "" {
-		cfg.serviceName = "grpc.client"
+		cfg.serviceName = namingschema.ServiceName("grpc.client")
 	}
 	log.Debug("contrib/google.golang.org/grpc.v12: Configuring UnaryClientInterceptor: %#v", cfg)
 	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
@@ -82,13 +86,14 @@ func UnaryClientInterceptor(opts ...InterceptorOption) grpc.UnaryClientIntercept
 			p    peer.Peer
 		)
 		spanopts := []ddtrace.StartSpanOption{
+			tracer.Tag(ext.Component, componentName),
 			tracer.Tag(tagMethod, method),
 			tracer.SpanType(ext.AppTypeRPC),
 		}
 		if !math.IsNaN(cfg.analyticsRate) {
 			spanopts = append(spanopts, tracer.Tag(ext.EventSampleRate, cfg.analyticsRate))
 		}
-		span, ctx = tracer.StartSpanFromContext(ctx, "grpc.client", spanopts...)
+		span, ctx = tracer.StartSpanFromContext(ctx, namingschema.GRPCClientOp("grpc.client"), spanopts...)
 		md, ok := metadata.FromContext(ctx)
 		if !ok {
 			md = metadata.MD{}
@@ -24,6 +24,7 @@ func appsecUnaryHandlerMiddleware(span ddtrace.Span, handler grpc.UnaryHandler)
 	instrumentation.SetAppSecEnabledTags(span)
 	return func(ctx context.Context, req interface{}) (interface{}, error) {
 		md, _ := metadata.FromIncomingContext(ctx)
+		grpcsec.SetIPTags(span, md, peerAddr(ctx))
 		op := grpcsec.StartHandlerOperation(grpcsec.HandlerOperationArgs{Metadata: md}, nil)
 		defer func() {
 			events := op.Finish(grpcsec.HandlerOperationRes{})
@@ -43,6 +44,7 @@ func appsecStreamHandlerMiddleware(span ddtrace.Span, handler grpc.StreamHandler
 	instrumentation.SetAppSecEnabledTags(span)
 	return func(srv interface{}, stream grpc.ServerStream) error {
 		md, _ := metadata.FromIncomingContext(stream.Context())
+		grpcsec.SetIPTags(span, md, peerAddr(stream.Context()))
 		op := grpcsec.StartHandlerOperation(grpcsec.HandlerOperationArgs{Metadata: md}, nil)
 		defer func() {
 			events := op.Finish(grpcsec.HandlerOperationRes{})
@@ -74,9 +76,13 @@ func (ss appsecServerStream) RecvMsg(m interface{}) error {
 // Set the AppSec tags when security events were found.
 func setAppSecTags(ctx context.Context, span ddtrace.Span, events []json.RawMessage) {
 	md, _ := metadata.FromIncomingContext(ctx)
-	var addr net.Addr
+	grpcsec.SetSecurityEventTags(span, events, peerAddr(ctx), md)
+}
+
+// peerAddr returns the network address of the peer of the RPC, if any.
+func peerAddr(ctx context.Context) net.Addr {
 	if p, ok := peer.FromContext(ctx); ok {
-		addr = p.Addr
+		return p.Addr
 	}
-	grpcsec.SetSecurityEventTags(span, events, addr, md)
+	return nil
 }
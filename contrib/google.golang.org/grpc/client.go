@@ -9,10 +9,12 @@ import (
 	"net"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/contrib/google.golang.org/internal/grpcutil"
+	"gopkg.in/DataDog/dd-trace-go.v1/contrib/internal/grpcgateway"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/namingschema"
 
 	context "golang.org/x/net/context"
 	"google.golang.org/grpc"
@@ -168,13 +170,14 @@ func doClientRequest(
 	span, ctx := startSpanFromContext(
 		ctx,
 		method,
-		"grpc.client",
+		namingschema.GRPCClientOp("grpc.client"),
 		cfg.clientServiceName(),
 		cfg.startSpanOptions()...,
 	)
 	if methodKind != "" {
 		span.SetTag(tagMethodKind, methodKind)
 	}
+	grpcgateway.SetMethod(ctx, method)
 
 	// fill in the peer so we can add it to the tags
 	var p peer.Peer
@@ -216,9 +219,15 @@ func injectSpanIntoContext(ctx context.Context) context.Context {
 	} else {
 		md = metadata.MD{}
 	}
-	if err := tracer.Inject(span.Context(), grpcutil.MDCarrier(md)); err != nil {
+	carrier := metadata.MD{}
+	if err := tracer.Inject(span.Context(), grpcutil.MDCarrier(carrier)); err != nil {
 		// in practice this error should never really happen
 		grpclog.Warningf("ddtrace: failed to inject the span context into the gRPC metadata: %v", err)
 	}
+	// the propagation metadata of the span replaces any forwarded from upstream,
+	// e.g. by a grpc-gateway passing the HTTP request headers through
+	for k, v := range carrier {
+		md[k] = v
+	}
 	return metadata.NewOutgoingContext(ctx, md)
 }
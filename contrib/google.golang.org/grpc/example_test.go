@@ -51,3 +51,40 @@ func Example_server() {
 		log.Fatalf("failed to serve: %v", err)
 	}
 }
+
+func Example_clientStatsHandler() {
+	// Create the client stats handler using the grpc trace package, leaving
+	// the interceptors available for other uses.
+	sh := grpctrace.NewClientStatsHandler(grpctrace.WithServiceName("my-grpc-client"))
+
+	// Dial in using the created stats handler...
+	conn, err := grpc.Dial("localhost:50051", grpc.WithInsecure(), grpc.WithStatsHandler(sh))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer conn.Close()
+
+	// And continue using the connection as normal.
+}
+
+func Example_serverStatsHandler() {
+	// Create a listener for the server.
+	ln, err := net.Listen("tcp", ":50051")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Create the server stats handler using the grpc trace package, leaving
+	// the interceptors available for other uses.
+	sh := grpctrace.NewServerStatsHandler(grpctrace.WithServiceName("my-grpc-server"))
+
+	// Initialize the grpc server as normal, using the tracing stats handler.
+	s := grpc.NewServer(grpc.StatsHandler(sh))
+
+	// ... register your services
+
+	// Start serving incoming connections.
+	if err := s.Serve(ln); err != nil {
+		log.Fatalf("failed to serve: %v", err)
+	}
+}
@@ -24,6 +24,8 @@ import (
 	"google.golang.org/grpc/status"
 )
 
+const componentName = "grpc"
+
 // cache a constant option: saves one allocation per call
 var spanTypeRPC = tracer.SpanType(ext.AppTypeRPC)
 
@@ -47,6 +49,7 @@ func startSpanFromContext(
 	ctx context.Context, method, operation, service string, opts ...tracer.StartSpanOption,
 ) (ddtrace.Span, context.Context) {
 	opts = append(opts,
+		tracer.Tag(ext.Component, componentName),
 		tracer.ServiceName(service),
 		tracer.ResourceName(method),
 		tracer.Tag(tagMethodName, method),
@@ -20,6 +20,7 @@ import (
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/namingschema"
 
 	"github.com/stretchr/testify/assert"
 	context "golang.org/x/net/context"
@@ -426,6 +427,35 @@ func TestPass(t *testing.T) {
 	assert.True(s.FinishTime().Sub(s.StartTime()) >= 0)
 }
 
+func TestSchemaV1(t *testing.T) {
+	namingschema.SetVersion(namingschema.SchemaV1)
+	defer namingschema.SetVersion(namingschema.SchemaV0)
+	assert := assert.New(t)
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	rig, err := newRig(true)
+	if err != nil {
+		t.Fatalf("error setting up rig: %s", err)
+	}
+	defer rig.Close()
+
+	_, err = rig.client.Ping(context.Background(), &FixtureRequest{Name: "pass"})
+	assert.Nil(err)
+
+	spans := mt.FinishedSpans()
+	assert.Len(spans, 2)
+	var names []string
+	for _, s := range spans {
+		names = append(names, s.OperationName())
+	}
+	assert.ElementsMatch([]string{"grpc.server.request", "grpc.client.request"}, names)
+
+	globalconfig.SetServiceName("my-app")
+	defer globalconfig.SetServiceName("")
+	assert.Equal("my-app", new(config).clientServiceName())
+}
+
 func TestPreservesMetadata(t *testing.T) {
 	mt := mocktracer.Start()
 	defer mt.Stop()
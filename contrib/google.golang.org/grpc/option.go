@@ -10,6 +10,7 @@ import (
 
 	"gopkg.in/DataDog/dd-trace-go.v1/internal"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/namingschema"
 
 	"google.golang.org/grpc/codes"
 )
@@ -44,7 +45,7 @@ func (cfg *config) serverServiceName() string {
 
 func (cfg *config) clientServiceName() string {
 	if cfg.serviceName == "" {
-		return "grpc.client"
+		return namingschema.ServiceName("grpc.client")
 	}
 	return cfg.serviceName
 }
@@ -87,8 +88,8 @@ func WithStreamCalls(enabled bool) Option {
 	}
 }
 
-// WithStreamMessages enables or disables tracing of streaming messages. This option does not apply
-// to the stats handler.
+// WithStreamMessages enables or disables tracing of streaming messages. With the stats handlers,
+// the messages of an RPC are traced once it exchanged several messages in the same direction.
 func WithStreamMessages(enabled bool) Option {
 	return func(cfg *config) {
 		cfg.traceStreamMessages = enabled
@@ -137,7 +138,7 @@ func WithAnalyticsRate(rate float64) Option {
 	}
 }
 
-// WithIgnoredMethods specifies full methods to be ignored by the server side interceptor.
+// WithIgnoredMethods specifies full methods to be ignored by the server side interceptor and stats handler.
 // When an incoming request's full method is in ms, no spans will be created.
 func WithIgnoredMethods(ms ...string) Option {
 	ims := make(map[string]struct{}, len(ms))
@@ -10,6 +10,7 @@ import (
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/namingschema"
 
 	"github.com/golang/protobuf/jsonpb"
 	"github.com/golang/protobuf/proto"
@@ -83,7 +84,7 @@ func StreamServerInterceptor(opts ...Option) grpc.StreamServerInterceptor {
 			span, ctx = startSpanFromContext(
 				ctx,
 				info.FullMethod,
-				"grpc.server",
+				namingschema.GRPCServerOp("grpc.server"),
 				cfg.serverServiceName(),
 				cfg.startSpanOptions(tracer.Measured())...,
 			)
@@ -127,7 +128,7 @@ func UnaryServerInterceptor(opts ...Option) grpc.UnaryServerInterceptor {
 		span, ctx := startSpanFromContext(
 			ctx,
 			info.FullMethod,
-			"grpc.server",
+			namingschema.GRPCServerOp("grpc.server"),
 			cfg.serverServiceName(),
 			cfg.startSpanOptions(tracer.Measured())...,
 		)
@@ -135,19 +136,10 @@ func UnaryServerInterceptor(opts ...Option) grpc.UnaryServerInterceptor {
 
 		if cfg.withMetadataTags {
 			md, _ := metadata.FromIncomingContext(ctx) // nil is ok
-			for k, v := range md {
-				if _, ok := cfg.ignoredMetadata[k]; !ok {
-					span.SetTag(tagMetadataPrefix+k, v)
-				}
-			}
+			setMetadataTags(span, cfg, md)
 		}
 		if cfg.withRequestTags {
-			var m jsonpb.Marshaler
-			if p, ok := req.(proto.Message); ok {
-				if s, err := m.MarshalToString(p); err == nil {
-					span.SetTag(tagRequest, s)
-				}
-			}
+			setRequestTag(span, req)
 		}
 		if appsec.Enabled() {
 			handler = appsecUnaryHandlerMiddleware(span, handler)
@@ -157,3 +149,22 @@ func UnaryServerInterceptor(opts ...Option) grpc.UnaryServerInterceptor {
 		return resp, err
 	}
 }
+
+// setMetadataTags sets the given metadata as span tags, except the ignored ones.
+func setMetadataTags(span ddtrace.Span, cfg *config, md metadata.MD) {
+	for k, v := range md {
+		if _, ok := cfg.ignoredMetadata[k]; !ok {
+			span.SetTag(tagMetadataPrefix+k, v)
+		}
+	}
+}
+
+// setRequestTag sets the JSON representation of the given request as a span tag when it is a protobuf message.
+func setRequestTag(span ddtrace.Span, req interface{}) {
+	var m jsonpb.Marshaler
+	if p, ok := req.(proto.Message); ok {
+		if s, err := m.MarshalToString(p); err == nil {
+			span.SetTag(tagRequest, s)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package grpc
+
+import (
+	"sync"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+
+	context "golang.org/x/net/context"
+)
+
+// rpcStatsKey is the context key of the rpcStats of the RPCs traced by the stats handlers.
+type rpcStatsKey struct{}
+
+// rpcStats holds the span of an RPC traced by a stats handler along with the
+// statistics of its messages.
+//
+// Unlike the interceptors, the stats handlers are not told whether an RPC is
+// a stream. Its messages are therefore traced as grpc.message spans once it
+// exchanged more than one message in the same direction, which is when it is
+// known to be a stream. The first message of each direction is traced then.
+type rpcStats struct {
+	span    ddtrace.Span
+	cfg     *config
+	method  string
+	service string
+
+	mu       sync.Mutex // guards the fields below
+	received messagesStats
+	sent     messagesStats
+}
+
+// messagesStats holds the statistics of the messages of an RPC in one direction.
+type messagesStats struct {
+	count int
+	size  int
+	// first is the time and size of the first message, kept until the RPC is known to be a stream.
+	firstTime time.Time
+	firstSize int
+}
+
+// startRPCStats starts the span of the given RPC and returns a context holding it along with its rpcStats.
+func startRPCStats(ctx context.Context, cfg *config, method, operation, service string, opts ...tracer.StartSpanOption) context.Context {
+	span, ctx := startSpanFromContext(ctx, method, operation, service, cfg.startSpanOptions(opts...)...)
+	return context.WithValue(ctx, rpcStatsKey{}, &rpcStats{
+		span:    span,
+		cfg:     cfg,
+		method:  method,
+		service: service,
+	})
+}
+
+// rpcStatsFromContext returns the rpcStats held by ctx, if any.
+func rpcStatsFromContext(ctx context.Context) (*rpcStats, bool) {
+	s, ok := ctx.Value(rpcStatsKey{}).(*rpcStats)
+	return s, ok
+}
+
+// handlePayload records a message of the given size received or sent at time
+// t. It returns true when it is the first message of its direction.
+func (s *rpcStats) handlePayload(ctx context.Context, received bool, size int, t time.Time) (first bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := &s.sent
+	if received {
+		stats = &s.received
+	}
+	stats.count++
+	stats.size += size
+	if !s.cfg.traceStreamMessages {
+		return stats.count == 1
+	}
+	switch stats.count {
+	case 1:
+		stats.firstTime, stats.firstSize = t, size
+		return true
+	case 2:
+		s.traceMessage(ctx, stats.firstSize, stats.firstTime)
+	}
+	s.traceMessage(ctx, size, t)
+	return false
+}
+
+// traceMessage creates the grpc.message span of a stream message of the given size received or sent at time t.
+func (s *rpcStats) traceMessage(ctx context.Context, size int, t time.Time) {
+	span, _ := startSpanFromContext(
+		ctx,
+		s.method,
+		"grpc.message",
+		s.service,
+		s.cfg.startSpanOptions(tracer.Measured(), tracer.StartTime(t))...,
+	)
+	setMetric(span, tagMessageSize, float64(size))
+	span.Finish(tracer.FinishTime(t))
+}
+
+// finish sets the message size tags of the RPC span and finishes it with the given error.
+func (s *rpcStats) finish(err error) {
+	s.mu.Lock()
+	setMetric(s.span, tagReceivedSize, float64(s.received.size))
+	setMetric(s.span, tagSentSize, float64(s.sent.size))
+	s.mu.Unlock()
+	finishWithError(s.span, err, s.cfg)
+}
+
+// setMetric sets the given metric on span, or a tag holding its value when the
+// span can't hold metrics.
+func setMetric(span ddtrace.Span, key string, value float64) {
+	if s, ok := span.(ddtrace.SpanWithMetrics); ok {
+		s.SetMetric(key, value)
+		return
+	}
+	span.SetTag(key, value)
+}
@@ -11,8 +11,9 @@ import (
 	context "golang.org/x/net/context"
 	"google.golang.org/grpc/stats"
 
+	"gopkg.in/DataDog/dd-trace-go.v1/contrib/internal/grpcgateway"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
-	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/namingschema"
 )
 
 // NewClientStatsHandler returns a gRPC client stats.Handler to trace RPC calls.
@@ -31,20 +32,22 @@ type clientStatsHandler struct{ cfg *config }
 
 // TagRPC starts a new span for the initiated RPC request.
 func (h *clientStatsHandler) TagRPC(ctx context.Context, rti *stats.RPCTagInfo) context.Context {
-	_, ctx = startSpanFromContext(
+	ctx = startRPCStats(
 		ctx,
+		h.cfg,
 		rti.FullMethodName,
-		"grpc.client",
+		namingschema.GRPCClientOp("grpc.client"),
 		h.cfg.clientServiceName(),
-		tracer.AnalyticsRate(h.cfg.analyticsRate),
 	)
+	grpcgateway.SetMethod(ctx, rti.FullMethodName)
 	ctx = injectSpanIntoContext(ctx)
 	return ctx
 }
 
-// HandleRPC processes the RPC ending event by finishing the span from the context.
+// HandleRPC processes the RPC events: it sets the target tags, traces the
+// stream messages and finishes the span when the RPC ends.
 func (h *clientStatsHandler) HandleRPC(ctx context.Context, rs stats.RPCStats) {
-	span, ok := tracer.SpanFromContext(ctx)
+	s, ok := rpcStatsFromContext(ctx)
 	if !ok {
 		return
 	}
@@ -53,12 +56,16 @@ func (h *clientStatsHandler) HandleRPC(ctx context.Context, rs stats.RPCStats) {
 		host, port, err := net.SplitHostPort(rs.RemoteAddr.String())
 		if err == nil {
 			if host != "" {
-				span.SetTag(ext.TargetHost, host)
+				s.span.SetTag(ext.TargetHost, host)
 			}
-			span.SetTag(ext.TargetPort, port)
+			s.span.SetTag(ext.TargetPort, port)
 		}
+	case *stats.InPayload:
+		s.handlePayload(ctx, true, rs.Length, rs.RecvTime)
+	case *stats.OutPayload:
+		s.handlePayload(ctx, false, rs.Length, rs.SentTime)
 	case *stats.End:
-		finishWithError(span, rs.Error, h.cfg)
+		s.finish(rs.Error)
 	}
 }
 
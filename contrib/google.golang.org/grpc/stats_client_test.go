@@ -7,8 +7,10 @@ package grpc
 
 import (
 	"fmt"
+	"io"
 	"net"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	context "golang.org/x/net/context"
@@ -56,6 +58,42 @@ func TestClientStatsHandler(t *testing.T) {
 	assert.Equal("/grpc.Fixture/Ping", tags[tagMethodName])
 	assert.Equal("127.0.0.1", tags[ext.TargetHost])
 	assert.Equal(server.port, tags[ext.TargetPort])
+	assert.NotZero(tags[tagSentSize])
+	assert.NotZero(tags[tagReceivedSize])
+}
+
+func TestClientStatsHandlerStream(t *testing.T) {
+	assert := assert.New(t)
+
+	statsHandler := NewClientStatsHandler(WithServiceName("grpc-service"))
+	server, err := newClientStatsHandlerTestServer(statsHandler)
+	if err != nil {
+		t.Fatalf("failed to start test server: %s", err)
+	}
+	defer server.Close()
+
+	mt := mocktracer.Start()
+	defer mt.Stop()
+	stream, err := server.client.StreamPing(context.Background())
+	assert.NoError(err)
+	for _, name := range []string{"pass", "break"} {
+		assert.NoError(stream.Send(&FixtureRequest{Name: name}))
+		_, err = stream.Recv()
+		assert.NoError(err)
+	}
+	_, err = stream.Recv()
+	assert.Equal(io.EOF, err)
+
+	// 1 grpc.client span and 4 grpc.message spans
+	waitForSpans(mt, 5, 5*time.Second)
+	spans := mt.FinishedSpans()
+	assert.Len(spans, 5)
+	clientSpan := spans[len(spans)-1]
+	assert.Equal("grpc.client", clientSpan.OperationName())
+	for _, span := range spans[:len(spans)-1] {
+		assert.Equal("grpc.message", span.OperationName())
+		assert.Equal(clientSpan.SpanID(), span.ParentID())
+	}
 }
 
 func newClientStatsHandlerTestServer(statsHandler stats.Handler) (*rig, error) {
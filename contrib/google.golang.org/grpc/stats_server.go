@@ -7,6 +7,7 @@ package grpc
 
 import (
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/namingschema"
 
 	context "golang.org/x/net/context"
 	"google.golang.org/grpc/stats"
@@ -30,25 +31,40 @@ type serverStatsHandler struct {
 
 // TagRPC starts a new span for the initiated RPC request.
 func (h *serverStatsHandler) TagRPC(ctx context.Context, rti *stats.RPCTagInfo) context.Context {
-	_, ctx = startSpanFromContext(
+	if _, ok := h.cfg.ignoredMethods[rti.FullMethodName]; ok {
+		return ctx
+	}
+	return startRPCStats(
 		ctx,
+		h.cfg,
 		rti.FullMethodName,
-		"grpc.server",
+		namingschema.GRPCServerOp("grpc.server"),
 		h.cfg.serverServiceName(),
-		tracer.AnalyticsRate(h.cfg.analyticsRate),
 		tracer.Measured(),
 	)
-	return ctx
 }
 
-// HandleRPC processes the RPC ending event by finishing the span from the context.
+// HandleRPC processes the RPC events: it sets the metadata and request tags
+// when enabled, traces the stream messages and finishes the span when the RPC
+// ends.
 func (h *serverStatsHandler) HandleRPC(ctx context.Context, rs stats.RPCStats) {
-	span, ok := tracer.SpanFromContext(ctx)
+	s, ok := rpcStatsFromContext(ctx)
 	if !ok {
 		return
 	}
-	if v, ok := rs.(*stats.End); ok {
-		finishWithError(span, v.Error, h.cfg)
+	switch rs := rs.(type) {
+	case *stats.InHeader:
+		if h.cfg.withMetadataTags {
+			setMetadataTags(s.span, h.cfg, rs.Header)
+		}
+	case *stats.InPayload:
+		if first := s.handlePayload(ctx, true, rs.Length, rs.RecvTime); first && h.cfg.withRequestTags {
+			setRequestTag(s.span, rs.Payload)
+		}
+	case *stats.OutPayload:
+		s.handlePayload(ctx, false, rs.Length, rs.SentTime)
+	case *stats.End:
+		s.finish(rs.Error)
 	}
 }
 
@@ -7,13 +7,17 @@ package grpc
 
 import (
 	"fmt"
+	"io"
 	"net"
 	"testing"
+	"time"
 
+	"github.com/golang/protobuf/proto"
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/stats"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
@@ -51,6 +55,95 @@ func TestServerStatsHandler(t *testing.T) {
 	assert.Equal("/grpc.Fixture/Ping", tags["resource.name"])
 	assert.Equal("/grpc.Fixture/Ping", tags[tagMethodName])
 	assert.Equal(1, tags["_dd.measured"])
+	assert.Equal(float64(proto.Size(&FixtureRequest{Name: "name"})), tags[tagReceivedSize])
+	assert.NotZero(tags[tagSentSize])
+}
+
+func TestServerStatsHandlerStream(t *testing.T) {
+	assert := assert.New(t)
+
+	statsHandler := NewServerStatsHandler(WithServiceName("grpc-service"))
+	server, err := newServerStatsHandlerTestServer(statsHandler)
+	if err != nil {
+		t.Fatalf("failed to start test server: %s", err)
+	}
+	defer server.Close()
+
+	mt := mocktracer.Start()
+	defer mt.Stop()
+	stream, err := server.client.StreamPing(context.Background())
+	assert.NoError(err)
+	for _, name := range []string{"pass", "pass", "break"} {
+		assert.NoError(stream.Send(&FixtureRequest{Name: name}))
+		_, err = stream.Recv()
+		assert.NoError(err)
+	}
+	_, err = stream.Recv()
+	assert.Equal(io.EOF, err)
+
+	// 1 grpc.server span and 6 grpc.message spans
+	waitForSpans(mt, 7, 5*time.Second)
+	spans := mt.FinishedSpans()
+	assert.Len(spans, 7)
+
+	var serverSpan mocktracer.Span
+	var messageSpans []mocktracer.Span
+	for _, span := range spans {
+		switch span.OperationName() {
+		case "grpc.server":
+			serverSpan = span
+		case "grpc.message":
+			messageSpans = append(messageSpans, span)
+		}
+	}
+	if !assert.NotNil(serverSpan) {
+		return
+	}
+	assert.Len(messageSpans, 6)
+	var received float64
+	for _, span := range messageSpans {
+		assert.Equal(serverSpan.SpanID(), span.ParentID())
+		assert.Equal("/grpc.Fixture/StreamPing", span.Tag(ext.ResourceName))
+		assert.NotZero(span.Tag(tagMessageSize))
+		received += span.Tag(tagMessageSize).(float64)
+	}
+	assert.Equal(received, serverSpan.Tag(tagReceivedSize).(float64)+serverSpan.Tag(tagSentSize).(float64))
+	assert.Equal(codes.OK.String(), serverSpan.Tag(tagCode))
+}
+
+func TestServerStatsHandlerOptions(t *testing.T) {
+	assert := assert.New(t)
+
+	statsHandler := NewServerStatsHandler(
+		WithIgnoredMethods("/grpc.Fixture/StreamPing"),
+		WithMetadataTags(),
+		WithRequestTags(),
+	)
+	server, err := newServerStatsHandlerTestServer(statsHandler)
+	if err != nil {
+		t.Fatalf("failed to start test server: %s", err)
+	}
+	defer server.Close()
+
+	mt := mocktracer.Start()
+	defer mt.Stop()
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "test-key", "test-value")
+	_, err = server.client.Ping(ctx, &FixtureRequest{Name: "name"})
+	assert.NoError(err)
+	stream, err := server.client.StreamPing(context.Background())
+	assert.NoError(err)
+	assert.NoError(stream.Send(&FixtureRequest{Name: "break"}))
+	_, err = stream.Recv()
+	assert.NoError(err)
+	_, err = stream.Recv()
+	assert.Equal(io.EOF, err)
+
+	spans := mt.FinishedSpans()
+	assert.Len(spans, 1)
+	span := spans[0]
+	assert.Equal("/grpc.Fixture/Ping", span.Tag(ext.ResourceName))
+	assert.Equal([]string{"test-value"}, span.Tag(tagMetadataPrefix+"test-key"))
+	assert.Equal(`{"name":"name"}`, span.Tag(tagRequest))
 }
 
 func newServerStatsHandlerTestServer(statsHandler stats.Handler) (*rig, error) {
@@ -12,6 +12,9 @@ const (
 	tagCode           = "grpc.code"
 	tagMetadataPrefix = "grpc.metadata."
 	tagRequest        = "grpc.request"
+	tagMessageSize    = "grpc.message.size"
+	tagReceivedSize   = "grpc.received.size"
+	tagSentSize       = "grpc.sent.size"
 )
 
 const (
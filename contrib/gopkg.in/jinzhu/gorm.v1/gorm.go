@@ -20,6 +20,8 @@ import (
 	"gopkg.in/jinzhu/gorm.v1"
 )
 
+const componentName = "gorm"
+
 const (
 	gormContextKey       = "dd-trace-go:context"
 	gormConfigKey        = "dd-trace-go:config"
@@ -118,6 +120,7 @@ func after(scope *gorm.Scope, operationName string) {
 	t, ok := v.(time.Time)
 
 	opts := []ddtrace.StartSpanOption{
+		tracer.Tag(ext.Component, componentName),
 		tracer.StartTime(t),
 		tracer.ServiceName(cfg.serviceName),
 		tracer.SpanType(ext.SpanTypeSQL),
@@ -18,6 +18,8 @@ import (
 	"github.com/gorilla/mux"
 )
 
+const componentName = "mux"
+
 // Router registers routes to be matched and dispatches a handler.
 type Router struct {
 	*mux.Router
@@ -104,13 +106,17 @@ func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	}
 	resource := r.config.resourceNamer(r, req)
 	httptrace.TraceAndServe(r.Router, w, req, &httptrace.ServeConfig{
-		Service:     r.config.serviceName,
-		Resource:    resource,
-		FinishOpts:  r.config.finishOpts,
-		SpanOpts:    spanopts,
-		QueryParams: r.config.queryParams,
-		RouteParams: match.Vars,
-		Route:       route,
+		Service:           r.config.serviceName,
+		Resource:          resource,
+		Component:         componentName,
+		FinishOpts:        r.config.finishOpts,
+		SpanOpts:          spanopts,
+		QueryParams:       r.config.queryParams,
+		RouteParams:       match.Vars,
+		RouteParamsTags:   r.config.routeParams,
+		ContentLengthTags: r.config.contentLength,
+		ServerTiming:      r.config.serverTiming,
+		Route:             route,
 	})
 }
 
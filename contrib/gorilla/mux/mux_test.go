@@ -133,6 +133,22 @@ func TestWithQueryParams(t *testing.T) {
 	assert.Equal("http://localhost/200?<redacted>&id=3&name=5", mt.FinishedSpans()[0].Tags()[ext.HTTPURL])
 }
 
+func TestWithRouteParams(t *testing.T) {
+	assert := assert.New(t)
+	mt := mocktracer.Start()
+	defer mt.Stop()
+	mux := NewRouter(WithRouteParams(), WithContentLengthTags())
+	mux.Handle("/user/{id}", okHandler())
+	r := httptest.NewRequest("POST", "/user/123", strings.NewReader("body"))
+
+	mux.ServeHTTP(httptest.NewRecorder(), r)
+
+	span := mt.FinishedSpans()[0]
+	assert.Equal("123", span.Tag(ext.HTTPRouteParams+".id"))
+	assert.EqualValues(4, span.Tag(ext.HTTPRequestContentLength))
+	assert.EqualValues(len("200!\n"), span.Tag(ext.HTTPResponseContentLength))
+}
+
 func TestSpanOptions(t *testing.T) {
 	assert := assert.New(t)
 	mt := mocktracer.Start()
@@ -148,6 +164,22 @@ func TestSpanOptions(t *testing.T) {
 	assert.Equal(2, spans[0].Tag(ext.SamplingPriority))
 }
 
+func TestServerTiming(t *testing.T) {
+	assert := assert.New(t)
+	mt := mocktracer.Start()
+	defer mt.Stop()
+	mux := NewRouter(WithServerTiming())
+	mux.Handle("/200", okHandler())
+	r := httptest.NewRequest("GET", "/200", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+
+	spans := mt.FinishedSpans()
+	assert.Equal(1, len(spans))
+	want := fmt.Sprintf(`traceparent;desc="00-%032x-%016x-00"`, spans[0].TraceID(), spans[0].SpanID())
+	assert.Equal(want, w.Header().Get("Server-Timing"))
+}
+
 func TestNoDebugStack(t *testing.T) {
 	assert := assert.New(t)
 	mt := mocktracer.Start()
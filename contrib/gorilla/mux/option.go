@@ -25,6 +25,9 @@ type routerConfig struct {
 	ignoreRequest func(*http.Request) bool
 	headerTags    bool
 	queryParams   bool
+	routeParams   bool
+	contentLength bool
+	serverTiming  bool
 }
 
 // RouterOption represents an option that can be passed to NewRouter.
@@ -137,3 +140,30 @@ func WithQueryParams() RouterOption {
 		cfg.queryParams = true
 	}
 }
+
+// WithRouteParams specifies that the integration should attach the route variables of the
+// requests as http.route.params.<name> tags, e.g. http.route.params.id for the route /user/{id}.
+// Warning: using this feature can risk exposing sensitive data to Datadog.
+func WithRouteParams() RouterOption {
+	return func(cfg *routerConfig) {
+		cfg.routeParams = true
+	}
+}
+
+// WithContentLengthTags specifies that the integration should attach the sizes in bytes of
+// the bodies of the requests and of the responses as the http.request.content_length and
+// http.response.content_length tags.
+func WithContentLengthTags() RouterOption {
+	return func(cfg *routerConfig) {
+		cfg.contentLength = true
+	}
+}
+
+// WithServerTiming specifies that the integration should add a Server-Timing response
+// header holding the W3C traceparent of the request span, so that browser clients, such
+// as the RUM SDK, can link their traces to the backend ones.
+func WithServerTiming() RouterOption {
+	return func(cfg *routerConfig) {
+		cfg.serverTiming = true
+	}
+}
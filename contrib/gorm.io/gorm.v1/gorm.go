@@ -8,8 +8,12 @@ package gorm
 
 import (
 	"context"
+	"database/sql"
 	"math"
+	"math/rand"
+	"strings"
 	"time"
+	"unicode"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
@@ -19,12 +23,23 @@ import (
 	"gorm.io/gorm"
 )
 
+const componentName = "gorm"
+
 type key string
 
 const (
 	gormSpanStartTimeKey = key("dd-trace-go:span")
 )
 
+const (
+	// tagTable is the name of the table of the operations.
+	tagTable = "db.sql.table"
+	// tagRowCount is the number of rows affected or returned by the operations.
+	tagRowCount = "db.row_count"
+	// tagQueryPlan is the query plan of the operations, see WithQueryPlans.
+	tagQueryPlan = "db.query_plan"
+)
+
 // Open opens a new (traced) database connection. The used driver must be formerly registered
 // using (gopkg.in/DataDog/dd-trace-go.v1/contrib/database/sql).Register.
 func Open(dialector gorm.Dialector, cfg *gorm.Config, opts ...Option) (*gorm.DB, error) {
@@ -82,11 +97,19 @@ func withCallbacks(db *gorm.DB, opts ...Option) (*gorm.DB, error) {
 	if err != nil {
 		return db, err
 	}
-	err = cb.Row().Before("gorm:query").Register("dd-trace-go:before_row_query", before)
+	err = cb.Row().Before("gorm:row").Register("dd-trace-go:before_row_query", before)
+	if err != nil {
+		return db, err
+	}
+	err = cb.Row().After("gorm:row").Register("dd-trace-go:after_row_query", afterFunc("gorm.row_query"))
+	if err != nil {
+		return db, err
+	}
+	err = cb.Raw().Before("gorm:raw").Register("dd-trace-go:before_raw_query", before)
 	if err != nil {
 		return db, err
 	}
-	err = cb.Row().After("gorm:query").Register("dd-trace-go:after_row_query", afterFunc("gorm.row_query"))
+	err = cb.Raw().After("gorm:raw").Register("dd-trace-go:after_raw_query", afterFunc("gorm.raw_query"))
 	if err != nil {
 		return db, err
 	}
@@ -111,6 +134,7 @@ func after(db *gorm.DB, operationName string, cfg *config) {
 	}
 
 	opts := []ddtrace.StartSpanOption{
+		tracer.Tag(ext.Component, componentName),
 		tracer.StartTime(t),
 		tracer.ServiceName(cfg.serviceName),
 		tracer.SpanType(ext.SpanTypeSQL),
@@ -119,16 +143,100 @@ func after(db *gorm.DB, operationName string, cfg *config) {
 	if !math.IsNaN(cfg.analyticsRate) {
 		opts = append(opts, tracer.Tag(ext.EventSampleRate, cfg.analyticsRate))
 	}
+	if db.Statement.Table != "" {
+		opts = append(opts, tracer.Tag(tagTable, db.Statement.Table))
+	}
 	for key, tagFn := range cfg.tagFns {
 		if tagFn != nil {
 			opts = append(opts, tracer.Tag(key, tagFn(db)))
 		}
 	}
 
-	span, _ := tracer.StartSpanFromContext(ctx, operationName, opts...)
+	span, ctx := tracer.StartSpanFromContext(ctx, operationName, opts...)
+	if db.Error == nil && db.RowsAffected >= 0 {
+		// the row count is unknown (-1) for row queries, whose rows are yet to be read
+		if s, ok := span.(ddtrace.SpanWithMetrics); ok {
+			s.SetMetric(tagRowCount, float64(db.RowsAffected))
+		} else {
+			span.SetTag(tagRowCount, float64(db.RowsAffected))
+		}
+	}
+	if cfg.planRate > 0 && time.Since(t) >= cfg.planThreshold && rand.Float64() < cfg.planRate {
+		setQueryPlan(ctx, span, db)
+	}
 	var dbErr error
 	if cfg.errCheck(db.Error) {
 		dbErr = db.Error
 	}
 	span.Finish(tracer.WithError(dbErr))
 }
+
+// setQueryPlan tags span with the plan of the query executed by db, as returned
+// by running it prefixed with EXPLAIN. The query plan is left out when the query
+// failed or was not executed, as running EXPLAIN would then fail as well, and
+// could abort the ongoing transaction, and for row queries, whose rows may still
+// hold the connection.
+func setQueryPlan(ctx context.Context, span ddtrace.Span, db *gorm.DB) {
+	query := strings.TrimSpace(db.Statement.SQL.String())
+	if db.Error != nil || db.DryRun || db.RowsAffected < 0 || !explainable(query) {
+		return
+	}
+	rows, err := db.Statement.ConnPool.QueryContext(ctx, "EXPLAIN "+query, db.Statement.Vars...)
+	if err != nil {
+		log.Debug("contrib/gorm.io/gorm.v1: Failed to get the query plan: %v", err)
+		return
+	}
+	defer rows.Close()
+	plan, err := formatQueryPlan(rows)
+	if err != nil {
+		log.Debug("contrib/gorm.io/gorm.v1: Failed to read the query plan: %v", err)
+		return
+	}
+	span.SetTag(tagQueryPlan, plan)
+}
+
+// explainable reports whether the plan of query can be returned by EXPLAIN.
+func explainable(query string) bool {
+	i := strings.IndexFunc(query, unicode.IsSpace)
+	if i < 0 {
+		i = len(query)
+	}
+	switch strings.ToUpper(query[:i]) {
+	case "SELECT", "INSERT", "UPDATE", "DELETE", "WITH":
+		return true
+	}
+	return false
+}
+
+// formatQueryPlan returns the rows of a query plan, one per line. The columns
+// of the rows are separated by tabs and preceded by their names when there are
+// several of them, as with MySQL.
+func formatQueryPlan(rows *sql.Rows) (string, error) {
+	cols, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+	var lines []string
+	if len(cols) > 1 {
+		lines = append(lines, strings.Join(cols, "\t"))
+	}
+	values := make([]sql.NullString, len(cols))
+	dest := make([]interface{}, len(cols))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	fields := make([]string, len(cols))
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return "", err
+		}
+		for i, v := range values {
+			fields[i] = v.String
+		}
+		lines = append(lines, strings.Join(fields, "\t"))
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	return strings.Join(lines, "\n"), nil
+}
@@ -11,6 +11,7 @@ import (
 	"log"
 	"os"
 	"testing"
+	"time"
 
 	sqltrace "gopkg.in/DataDog/dd-trace-go.v1/contrib/database/sql"
 	"gopkg.in/DataDog/dd-trace-go.v1/contrib/internal/sqltest"
@@ -199,6 +200,8 @@ func TestCallbacks(t *testing.T) {
 		a.Equal("gorm.create", span.OperationName())
 		a.Equal(ext.SpanTypeSQL, span.Tag(ext.SpanType))
 		a.Equal(queryText, span.Tag(ext.ResourceName))
+		a.Equal("products", span.Tag(tagTable))
+		a.Equal(1.0, span.Tag(tagRowCount))
 	})
 
 	t.Run("query", func(t *testing.T) {
@@ -252,6 +255,27 @@ func TestCallbacks(t *testing.T) {
 		a.Equal(queryText, span.Tag(ext.ResourceName))
 	})
 
+	t.Run("raw", func(t *testing.T) {
+		parentSpan, ctx := tracer.StartSpanFromContext(context.Background(), "http.request",
+			tracer.ServiceName("fake-http-server"),
+			tracer.SpanType(ext.SpanTypeWeb),
+		)
+
+		db = db.WithContext(ctx)
+		db.Exec("UPDATE products SET price = ? WHERE code = ?", 3000, "L1212")
+
+		parentSpan.Finish()
+
+		spans := mt.FinishedSpans()
+		a.True(len(spans) >= 2)
+
+		span := spans[len(spans)-2]
+		a.Equal("gorm.raw_query", span.OperationName())
+		a.Equal(ext.SpanTypeSQL, span.Tag(ext.SpanType))
+		a.Equal("UPDATE products SET price = $1 WHERE code = $2", span.Tag(ext.ResourceName))
+		a.Equal(1.0, span.Tag(tagRowCount))
+	})
+
 	t.Run("delete", func(t *testing.T) {
 		parentSpan, ctx := tracer.StartSpanFromContext(context.Background(), "http.request",
 			tracer.ServiceName("fake-http-server"),
@@ -466,3 +490,58 @@ func TestCustomTags(t *testing.T) {
 
 	assert.Equal("bar", s.Tag("foo"))
 }
+
+func TestQueryPlans(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	sqltrace.Register("pgx", &stdlib.Driver{}, sqltrace.WithChildSpansOnly())
+	sqlDb, err := sqltrace.Open("pgx", pgConnString, sqltrace.WithChildSpansOnly())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	openDB := func(opts ...Option) *gorm.DB {
+		db, err := Open(postgres.New(postgres.Config{Conn: sqlDb}), &gorm.Config{}, opts...)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := db.AutoMigrate(&Product{}); err != nil {
+			log.Fatal(err)
+		}
+		return db.WithContext(context.Background())
+	}
+	lastSpan := func(t *testing.T) mocktracer.Span {
+		spans := mt.FinishedSpans()
+		if !assert.NotEmpty(t, spans) {
+			t.FailNow()
+		}
+		return spans[len(spans)-1]
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		mt.Reset()
+		openDB().First(&Product{}, Product{Code: "L1210"})
+		assert.Nil(t, lastSpan(t).Tag(tagQueryPlan))
+	})
+
+	t.Run("slow", func(t *testing.T) {
+		mt.Reset()
+		openDB(WithQueryPlans(1, 0)).Find(&[]Product{}, "code = ?", "L1210")
+		s := lastSpan(t)
+		assert.Equal(t, "gorm.query", s.OperationName())
+		assert.Contains(t, s.Tag(tagQueryPlan), "Scan on products")
+	})
+
+	t.Run("fast", func(t *testing.T) {
+		mt.Reset()
+		openDB(WithQueryPlans(1, time.Hour)).Find(&[]Product{}, "code = ?", "L1210")
+		assert.Nil(t, lastSpan(t).Tag(tagQueryPlan))
+	})
+
+	t.Run("not-explainable", func(t *testing.T) {
+		mt.Reset()
+		openDB(WithQueryPlans(1, 0)).Exec("SET search_path TO public")
+		assert.Nil(t, lastSpan(t).Tag(tagQueryPlan))
+	})
+}
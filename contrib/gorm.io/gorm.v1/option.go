@@ -7,6 +7,7 @@ package gorm
 
 import (
 	"math"
+	"time"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/internal"
 
@@ -19,6 +20,8 @@ type config struct {
 	dsn           string
 	errCheck      func(err error) bool
 	tagFns        map[string]func(db *gorm.DB) interface{}
+	planRate      float64
+	planThreshold time.Duration
 }
 
 // Option represents an option that can be passed to Register, Open or OpenDB.
@@ -87,3 +90,20 @@ func WithCustomTag(tag string, tagFn func(db *gorm.DB) interface{}) Option {
 		}
 	}
 }
+
+// WithQueryPlans makes the spans of the given rate, between 0 and 1, of the
+// operations lasting at least threshold carry the plan of their query, tagged
+// as db.query_plan. The query plans are returned by running the queries
+// prefixed with EXPLAIN on the connection of the operations, which the
+// database must support, as MySQL, PostgreSQL and SQLite do. The queries are
+// not executed again, but the planning adds up to their duration, hence the
+// rate should be kept low on busy databases. It is disabled by default.
+func WithQueryPlans(rate float64, threshold time.Duration) Option {
+	return func(cfg *config) {
+		if rate < 0 || rate > 1 {
+			rate = 0
+		}
+		cfg.planRate = rate
+		cfg.planThreshold = threshold
+	}
+}
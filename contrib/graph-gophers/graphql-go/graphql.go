@@ -20,12 +20,15 @@ import (
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/namingschema"
 
 	"github.com/graph-gophers/graphql-go/errors"
 	"github.com/graph-gophers/graphql-go/introspection"
 	"github.com/graph-gophers/graphql-go/trace"
 )
 
+const componentName = "graphql"
+
 const (
 	tagGraphqlField         = "graphql.field"
 	tagGraphqlQuery         = "graphql.query"
@@ -44,6 +47,7 @@ var _ trace.Tracer = (*Tracer)(nil)
 // TraceQuery traces a GraphQL query.
 func (t *Tracer) TraceQuery(ctx context.Context, queryString string, operationName string, variables map[string]interface{}, varTypes map[string]*introspection.Type) (context.Context, trace.TraceQueryFinishFunc) {
 	opts := []ddtrace.StartSpanOption{
+		tracer.Tag(ext.Component, componentName),
 		tracer.ServiceName(t.cfg.serviceName),
 		tracer.Tag(tagGraphqlQuery, queryString),
 		tracer.Tag(tagGraphqlOperationName, operationName),
@@ -52,7 +56,7 @@ func (t *Tracer) TraceQuery(ctx context.Context, queryString string, operationNa
 	if !math.IsNaN(t.cfg.analyticsRate) {
 		opts = append(opts, tracer.Tag(ext.EventSampleRate, t.cfg.analyticsRate))
 	}
-	span, ctx := tracer.StartSpanFromContext(ctx, "graphql.request", opts...)
+	span, ctx := tracer.StartSpanFromContext(ctx, namingschema.GraphQLServerOp("graphql.request"), opts...)
 
 	return ctx, func(errs []*errors.QueryError) {
 		var err error
@@ -74,6 +78,7 @@ func (t *Tracer) TraceField(ctx context.Context, label string, typeName string,
 		return ctx, func(queryError *errors.QueryError) {}
 	}
 	opts := []ddtrace.StartSpanOption{
+		tracer.Tag(ext.Component, componentName),
 		tracer.ServiceName(t.cfg.serviceName),
 		tracer.Tag(tagGraphqlField, fieldName),
 		tracer.Tag(tagGraphqlType, typeName),
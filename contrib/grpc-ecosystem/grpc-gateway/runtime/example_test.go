@@ -0,0 +1,38 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package runtime_test
+
+import (
+	"net/http"
+
+	grpctrace "gopkg.in/DataDog/dd-trace-go.v1/contrib/google.golang.org/grpc"
+	gatewaytrace "gopkg.in/DataDog/dd-trace-go.v1/contrib/grpc-ecosystem/grpc-gateway/runtime"
+
+	"google.golang.org/grpc"
+)
+
+func Example() {
+	// Trace the gRPC calls of the gateway with the interceptors of the gRPC integration...
+	conn, err := grpc.Dial("localhost:50051",
+		grpc.WithInsecure(),
+		grpc.WithUnaryInterceptor(grpctrace.UnaryClientInterceptor()),
+		grpc.WithStreamInterceptor(grpctrace.StreamClientInterceptor()),
+	)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	// ...and register the handlers of the services with the connection on a
+	// gateway runtime.ServeMux, e.g.:
+	//
+	//	mux := runtime.NewServeMux()
+	//	pb.RegisterGreeterHandler(ctx, mux, conn)
+	var mux http.Handler = http.NewServeMux()
+
+	// Then serve the mux wrapped to trace its requests.
+	http.ListenAndServe(":8080", gatewaytrace.WrapServeMux(mux, gatewaytrace.WithServiceName("my-gateway")))
+}
@@ -0,0 +1,64 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+// Package runtime provides functions to trace the runtime.ServeMux of the
+// grpc-ecosystem/grpc-gateway package (https://github.com/grpc-ecosystem/grpc-gateway).
+//
+// The span of every HTTP request served by the mux is the parent of the spans
+// of the gRPC calls it is translated into by the gateway, which must be traced
+// with the interceptors or stats handlers of the
+// gopkg.in/DataDog/dd-trace-go.v1/contrib/google.golang.org/grpc package. Once
+// the gRPC method of a request is known, its span is tagged with it and named
+// after it, e.g. "POST /helloworld.Greeter/SayHello".
+package runtime // import "gopkg.in/DataDog/dd-trace-go.v1/contrib/grpc-ecosystem/grpc-gateway/runtime"
+
+import (
+	"math"
+	"net/http"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/contrib/internal/grpcgateway"
+	httptrace "gopkg.in/DataDog/dd-trace-go.v1/contrib/net/http"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+)
+
+const componentName = "grpc-gateway"
+
+// WrapServeMux returns a handler tracing the HTTP requests served by the given
+// grpc-gateway runtime.ServeMux, of any major version of the gateway.
+func WrapServeMux(mux http.Handler, opts ...Option) http.Handler {
+	cfg := new(config)
+	defaults(cfg)
+	for _, fn := range opts {
+		fn(cfg)
+	}
+	if !math.IsNaN(cfg.analyticsRate) {
+		cfg.spanOpts = append(cfg.spanOpts, tracer.Tag(ext.EventSampleRate, cfg.analyticsRate))
+	}
+	log.Debug("contrib/grpc-ecosystem/grpc-gateway/runtime: Wrapping ServeMux: %#v", cfg)
+	return &serveMux{mux: mux, cfg: cfg}
+}
+
+type serveMux struct {
+	mux http.Handler
+	cfg *config
+}
+
+// ServeHTTP implements http.Handler.
+func (m *serveMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	httptrace.TraceAndServe(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the gateway calls the gRPC methods with the context of the request
+		if span, ok := tracer.SpanFromContext(r.Context()); ok {
+			r = r.WithContext(grpcgateway.ContextWithRequest(r.Context(), span, r.Method))
+		}
+		m.mux.ServeHTTP(w, r)
+	}), w, r, &httptrace.ServeConfig{
+		Service:   m.cfg.serviceName,
+		Resource:  r.Method + " " + r.URL.Path,
+		SpanOpts:  m.cfg.spanOpts,
+		Component: componentName,
+	})
+}
@@ -0,0 +1,135 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package runtime
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	grpctrace "gopkg.in/DataDog/dd-trace-go.v1/contrib/google.golang.org/grpc"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+)
+
+// newGateway returns a handler translating its requests into calls of the health
+// service, like a grpc-gateway mux, and a function stopping the service.
+func newGateway(t *testing.T) (http.Handler, func()) {
+	srv := grpc.NewServer(grpc.UnaryInterceptor(grpctrace.UnaryServerInterceptor()))
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	li, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go srv.Serve(li)
+	conn, err := grpc.Dial(li.Addr().String(), grpc.WithInsecure(), grpc.WithUnaryInterceptor(grpctrace.UnaryClientInterceptor()))
+	require.NoError(t, err)
+	client := healthpb.NewHealthClient(conn)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/health", func(w http.ResponseWriter, r *http.Request) {
+		// the gateway forwards the HTTP headers as gRPC metadata, including the
+		// propagation ones when configured to pass all of them through
+		md := metadata.Pairs(tracer.DefaultTraceIDHeader, "1", tracer.DefaultParentIDHeader, "1")
+		ctx := metadata.NewOutgoingContext(r.Context(), md)
+		if _, err := client.Check(ctx, &healthpb.HealthCheckRequest{}); err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+		}
+	})
+	return mux, func() {
+		conn.Close()
+		srv.Stop()
+	}
+}
+
+func TestWrapServeMux(t *testing.T) {
+	gateway, stop := newGateway(t)
+	defer stop()
+	mux := WrapServeMux(gateway, WithServiceName("gateway"))
+
+	t.Run("grpc-call", func(t *testing.T) {
+		mt := mocktracer.Start()
+		defer mt.Stop()
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", "/v1/health", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		spans := mt.FinishedSpans()
+		require.Len(t, spans, 3)
+		var httpSpan, clientSpan, serverSpan mocktracer.Span
+		for _, s := range spans {
+			switch s.OperationName() {
+			case "http.request":
+				httpSpan = s
+			case "grpc.client":
+				clientSpan = s
+			case "grpc.server":
+				serverSpan = s
+			}
+		}
+		require.NotNil(t, httpSpan)
+		require.NotNil(t, clientSpan)
+		require.NotNil(t, serverSpan)
+		assert.Equal(t, "gateway", httpSpan.Tag(ext.ServiceName))
+		assert.Equal(t, "/grpc.health.v1.Health/Check", httpSpan.Tag("grpc.method.name"))
+		assert.Equal(t, "GET /grpc.health.v1.Health/Check", httpSpan.Tag(ext.ResourceName))
+		assert.Equal(t, "200", httpSpan.Tag(ext.HTTPCode))
+		// a single trace rooted in the gateway, regardless of the forwarded metadata
+		assert.Zero(t, httpSpan.ParentID())
+		assert.Equal(t, httpSpan.SpanID(), clientSpan.ParentID())
+		assert.Equal(t, clientSpan.SpanID(), serverSpan.ParentID())
+		assert.Equal(t, httpSpan.TraceID(), serverSpan.TraceID())
+	})
+
+	t.Run("no-grpc-call", func(t *testing.T) {
+		mt := mocktracer.Start()
+		defer mt.Stop()
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", "/v1/unknown", nil))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		spans := mt.FinishedSpans()
+		require.Len(t, spans, 1)
+		assert.Equal(t, "GET /v1/unknown", spans[0].Tag(ext.ResourceName))
+		assert.Nil(t, spans[0].Tag("grpc.method.name"))
+	})
+}
+
+func TestAnalyticsSettings(t *testing.T) {
+	assertRate := func(t *testing.T, mt mocktracer.Tracer, rate interface{}, opts ...Option) {
+		mux := WrapServeMux(http.NotFoundHandler(), opts...)
+		mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+		spans := mt.FinishedSpans()
+		require.Len(t, spans, 1)
+		assert.Equal(t, rate, spans[0].Tag(ext.EventSampleRate))
+	}
+
+	t.Run("defaults", func(t *testing.T) {
+		mt := mocktracer.Start()
+		defer mt.Stop()
+		assertRate(t, mt, nil)
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		mt := mocktracer.Start()
+		defer mt.Stop()
+		assertRate(t, mt, 1.0, WithAnalytics(true))
+	})
+
+	t.Run("override", func(t *testing.T) {
+		mt := mocktracer.Start()
+		defer mt.Stop()
+		assertRate(t, mt, 0.23, WithAnalyticsRate(0.23))
+	})
+}
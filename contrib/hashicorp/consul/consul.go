@@ -17,6 +17,8 @@ import (
 	consul "github.com/hashicorp/consul/api"
 )
 
+const componentName = "consul"
+
 // Client wraps the regular *consul.Client and augments it with tracing. Use NewClient to initialize it.
 type Client struct {
 	*consul.Client
@@ -66,6 +68,7 @@ func (c *Client) KV() *KV {
 
 func (k *KV) startSpan(resourceName string, key string) ddtrace.Span {
 	opts := []ddtrace.StartSpanOption{
+		tracer.Tag(ext.Component, componentName),
 		tracer.ResourceName(resourceName),
 		tracer.ServiceName(k.config.serviceName),
 		tracer.SpanType(ext.SpanTypeConsul),
@@ -19,6 +19,7 @@ package vault
 import (
 	"fmt"
 	"net/http"
+	"strings"
 
 	httptrace "gopkg.in/DataDog/dd-trace-go.v1/contrib/net/http"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
@@ -38,7 +39,9 @@ func NewHTTPClient(opts ...Option) *http.Client {
 }
 
 // WrapHTTPClient takes an existing http.Client and wraps the underlying
-// transport with tracing.
+// transport with tracing. The spans are tagged with the path of the requests,
+// from which the tokens, token accessors and lease IDs are removed, and with
+// their Vault namespace, if any.
 func WrapHTTPClient(c *http.Client, opts ...Option) *http.Client {
 	if c.Transport == nil {
 		c.Transport = http.DefaultTransport
@@ -51,10 +54,11 @@ func WrapHTTPClient(c *http.Client, opts ...Option) *http.Client {
 	c.Transport = httptrace.WrapRoundTripper(c.Transport,
 		httptrace.RTWithAnalyticsRate(conf.analyticsRate),
 		httptrace.WithBefore(func(r *http.Request, s ddtrace.Span) {
+			path := sanitizePath(r.URL.Path)
 			s.SetTag(ext.ServiceName, conf.serviceName)
-			s.SetTag(ext.HTTPURL, r.URL.Path)
+			s.SetTag(ext.HTTPURL, path)
 			s.SetTag(ext.HTTPMethod, r.Method)
-			s.SetTag(ext.ResourceName, r.Method+" "+r.URL.Path)
+			s.SetTag(ext.ResourceName, r.Method+" "+path)
 			s.SetTag(ext.SpanType, ext.SpanTypeHTTP)
 			if ns := r.Header.Get(consts.NamespaceHeaderName); ns != "" {
 				s.SetTag("vault.namespace", ns)
@@ -74,3 +78,40 @@ func WrapHTTPClient(c *http.Client, opts ...Option) *http.Client {
 	)
 	return c
 }
+
+// sensitivePathPrefixes holds the prefixes of the paths of the Vault API
+// endpoints taking a token, a token accessor or a lease ID in their path, after
+// the API version.
+var sensitivePathPrefixes = []string{
+	"auth/token/lookup/",
+	"auth/token/lookup-accessor/",
+	"auth/token/renew/",
+	"auth/token/revoke/",
+	"auth/token/revoke-accessor/",
+	"auth/token/revoke-orphan/",
+	"sys/leases/lookup/",
+	"sys/leases/renew/",
+	"sys/leases/revoke/",
+	"sys/renew/",
+	"sys/revoke/",
+}
+
+// sanitizePath returns the path of a request to the Vault API with the tokens,
+// token accessors and lease IDs it holds replaced with "?", so that they don't
+// end up in the tags of the spans, e.g. "/v1/auth/token/lookup-accessor/?" for
+// "/v1/auth/token/lookup-accessor/8609694a-cdbc-db9b-d345-e782dbb562ed".
+func sanitizePath(path string) string {
+	trimmed := strings.TrimPrefix(path, "/")
+	i := strings.IndexByte(trimmed, '/')
+	if i < 0 {
+		return path
+	}
+	// skip the API version, e.g. "/v1/"
+	version, rest := path[:len(path)-len(trimmed)+i+1], trimmed[i+1:]
+	for _, prefix := range sensitivePathPrefixes {
+		if strings.HasPrefix(rest, prefix) && len(rest) > len(prefix) {
+			return version + prefix + "?"
+		}
+	}
+	return path
+}
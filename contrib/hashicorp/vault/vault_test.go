@@ -390,3 +390,44 @@ func TestOption(t *testing.T) {
 		})
 	}
 }
+
+func TestSanitizePath(t *testing.T) {
+	for path, want := range map[string]string{
+		"/v1/secret/data/my/app":                     "/v1/secret/data/my/app",
+		"/v1/auth/token/lookup-self":                 "/v1/auth/token/lookup-self",
+		"/v1/auth/token/lookup/s.abc123":             "/v1/auth/token/lookup/?",
+		"/v1/auth/token/lookup-accessor/8609694a-cd": "/v1/auth/token/lookup-accessor/?",
+		"/v1/auth/token/revoke-orphan/s.abc123":      "/v1/auth/token/revoke-orphan/?",
+		"/v1/auth/token/revoke/":                     "/v1/auth/token/revoke/",
+		"/v1/sys/leases/renew/aws/creds/deploy/abcd": "/v1/sys/leases/renew/?",
+		"/v1/sys/revoke/aws/creds/deploy/abcd":       "/v1/sys/revoke/?",
+		"/v1":                                        "/v1",
+	} {
+		assert.Equal(t, want, sanitizePath(path), path)
+	}
+}
+
+func TestSanitizedPathTags(t *testing.T) {
+	assert := assert.New(t)
+	ts, cleanup := setupServer(t)
+	defer cleanup()
+	client, err := setupClient(ts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	const accessor = "8609694a-cdbc-db9b-d345-e782dbb562ed"
+	client.Logical().Read("auth/token/lookup-accessor/" + accessor)
+	spans := mt.FinishedSpans()
+	assert.Len(spans, 1)
+	span := spans[0]
+
+	assert.Equal("/v1/auth/token/lookup-accessor/?", span.Tag(ext.HTTPURL))
+	assert.Equal(http.MethodGet+" /v1/auth/token/lookup-accessor/?", span.Tag(ext.ResourceName))
+	for k, v := range span.Tags() {
+		assert.NotContains(fmt.Sprint(v), accessor, k)
+	}
+}
@@ -0,0 +1,159 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022 Datadog, Inc.
+
+// Package asynq provides functions to trace the hibiken/asynq package (https://github.com/hibiken/asynq).
+//
+// The tasks created with NewTask carry the span context of the context they
+// were created with in their payload, so that the spans of their processing
+// by the handlers wrapped with WrapHandler, or by the ServeMux using
+// Middleware, are connected to the trace which enqueued them. The original
+// payload is passed to the handlers, which must then all be traced.
+package asynq // import "gopkg.in/DataDog/dd-trace-go.v1/contrib/hibiken/asynq"
+
+import (
+	"context"
+	"math"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+
+	"github.com/hibiken/asynq"
+)
+
+const componentName = "asynq"
+
+const (
+	tagTaskType   = "asynq.task.type"
+	tagTaskID     = "asynq.task.id"
+	tagQueue      = "asynq.queue"
+	tagRetryCount = "asynq.retry_count"
+	tagMaxRetry   = "asynq.max_retry"
+)
+
+// NewTask returns a new asynq.Task, as asynq.NewTask does, whose payload also
+// holds the span context of ctx, if any, in order to connect the spans of the
+// task processing to the trace of ctx.
+func NewTask(ctx context.Context, typename string, payload []byte, opts ...asynq.Option) *asynq.Task {
+	if s, ok := tracer.SpanFromContext(ctx); ok {
+		carrier := tracer.TextMapCarrier{}
+		if err := tracer.Inject(s.Context(), carrier); err != nil {
+			log.Debug("contrib/hibiken/asynq: Failed to inject span context into the task payload, %v", err)
+		} else {
+			payload = wrapPayload(carrier, payload)
+		}
+	}
+	return asynq.NewTask(typename, payload, opts...)
+}
+
+// WrapClient wraps an asynq.Client so that the enqueued tasks are traced.
+func WrapClient(c *asynq.Client, opts ...Option) *Client {
+	cfg := newConfig(opts...)
+	log.Debug("contrib/hibiken/asynq: Wrapping Client: %#v", cfg)
+	return &Client{Client: c, cfg: cfg}
+}
+
+// A Client wraps an asynq.Client.
+type Client struct {
+	*asynq.Client
+	cfg *config
+}
+
+// Enqueue enqueues the given task, as asynq.Client.Enqueue does, and traces it.
+func (c *Client) Enqueue(task *asynq.Task, opts ...asynq.Option) (*asynq.TaskInfo, error) {
+	return c.EnqueueContext(context.Background(), task, opts...)
+}
+
+// EnqueueContext enqueues the given task, as asynq.Client.EnqueueContext does,
+// and traces it.
+func (c *Client) EnqueueContext(ctx context.Context, task *asynq.Task, opts ...asynq.Option) (*asynq.TaskInfo, error) {
+	startOpts := []tracer.StartSpanOption{
+		tracer.Tag(ext.Component, componentName),
+		tracer.ServiceName(c.cfg.producerServiceName),
+		tracer.ResourceName(task.Type()),
+		tracer.SpanType(ext.SpanTypeMessageProducer),
+		tracer.Tag(ext.SpanKind, ext.SpanKindProducer),
+		tracer.Tag(tagTaskType, task.Type()),
+	}
+	if !math.IsNaN(c.cfg.analyticsRate) {
+		startOpts = append(startOpts, tracer.Tag(ext.EventSampleRate, c.cfg.analyticsRate))
+	}
+	span, ctx := tracer.StartSpanFromContext(ctx, "asynq.enqueue", startOpts...)
+	info, err := c.Client.EnqueueContext(ctx, task, opts...)
+	if info != nil {
+		span.SetTag(tagTaskID, info.ID)
+		span.SetTag(tagQueue, info.Queue)
+		if s, ok := span.(ddtrace.SpanWithMetrics); ok {
+			s.SetMetric(tagMaxRetry, float64(info.MaxRetry))
+		} else {
+			span.SetTag(tagMaxRetry, float64(info.MaxRetry))
+		}
+	}
+	span.Finish(tracer.WithError(err))
+	return info, err
+}
+
+// Middleware returns an asynq.MiddlewareFunc tracing the processing of the
+// tasks by the handlers of an asynq.ServeMux.
+func Middleware(opts ...Option) asynq.MiddlewareFunc {
+	cfg := newConfig(opts...)
+	log.Debug("contrib/hibiken/asynq: Configuring Middleware: %#v", cfg)
+	return func(h asynq.Handler) asynq.Handler {
+		return &handler{Handler: h, cfg: cfg}
+	}
+}
+
+// WrapHandler wraps an asynq.Handler so that the processing of the tasks is
+// traced.
+func WrapHandler(h asynq.Handler, opts ...Option) asynq.Handler {
+	return Middleware(opts...)(h)
+}
+
+type handler struct {
+	asynq.Handler
+	cfg *config
+}
+
+// ProcessTask processes the task with the wrapped handler, within a span which
+// is a child of the span context held by the task payload, if any. The wrapped
+// handler gets a copy of the task with its original payload, which has no
+// ResultWriter.
+func (h *handler) ProcessTask(ctx context.Context, task *asynq.Task) (err error) {
+	startOpts := []tracer.StartSpanOption{
+		tracer.Tag(ext.Component, componentName),
+		tracer.ServiceName(h.cfg.consumerServiceName),
+		tracer.ResourceName(task.Type()),
+		tracer.SpanType(ext.SpanTypeMessageConsumer),
+		tracer.Tag(ext.SpanKind, ext.SpanKindConsumer),
+		tracer.Tag(tagTaskType, task.Type()),
+		tracer.Measured(),
+	}
+	if !math.IsNaN(h.cfg.analyticsRate) {
+		startOpts = append(startOpts, tracer.Tag(ext.EventSampleRate, h.cfg.analyticsRate))
+	}
+	if id, ok := asynq.GetTaskID(ctx); ok {
+		startOpts = append(startOpts, tracer.Tag(tagTaskID, id))
+	}
+	if queue, ok := asynq.GetQueueName(ctx); ok {
+		startOpts = append(startOpts, tracer.Tag(tagQueue, queue))
+	}
+	if n, ok := asynq.GetRetryCount(ctx); ok {
+		startOpts = append(startOpts, tracer.Tag(tagRetryCount, n))
+	}
+	if n, ok := asynq.GetMaxRetry(ctx); ok {
+		startOpts = append(startOpts, tracer.Tag(tagMaxRetry, n))
+	}
+	if carrier, payload, ok := unwrapPayload(task.Payload()); ok {
+		if spanctx, err := tracer.Extract(carrier); err == nil {
+			startOpts = append(startOpts, tracer.ChildOf(spanctx))
+		}
+		task = asynq.NewTask(task.Type(), payload)
+	}
+	var span ddtrace.Span
+	span, ctx = tracer.StartSpanFromContext(ctx, "asynq.process", startOpts...)
+	defer func() { span.Finish(tracer.WithError(err)) }()
+	return h.Handler.ProcessTask(ctx, task)
+}
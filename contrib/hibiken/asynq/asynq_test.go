@@ -0,0 +1,115 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022 Datadog, Inc.
+
+package asynq
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+
+	"github.com/hibiken/asynq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessTask(t *testing.T) {
+	assert := assert.New(t)
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	parent, ctx := tracer.StartSpanFromContext(context.Background(), "request")
+	task := NewTask(ctx, "email:send", []byte("hello"))
+	parent.Finish()
+	assert.NotEqual([]byte("hello"), task.Payload())
+
+	var payload []byte
+	h := WrapHandler(asynq.HandlerFunc(func(ctx context.Context, task *asynq.Task) error {
+		payload = task.Payload()
+		_, ok := tracer.SpanFromContext(ctx)
+		assert.True(ok)
+		return nil
+	}), WithServiceName("workers"))
+	require.NoError(t, h.ProcessTask(context.Background(), task))
+	assert.Equal([]byte("hello"), payload)
+
+	spans := mt.FinishedSpans()
+	require.Len(t, spans, 2)
+	s := spans[1]
+	assert.Equal("asynq.process", s.OperationName())
+	assert.Equal("email:send", s.Tag(ext.ResourceName))
+	assert.Equal("workers", s.Tag(ext.ServiceName))
+	assert.Equal(ext.SpanTypeMessageConsumer, s.Tag(ext.SpanType))
+	assert.Equal(ext.SpanKindConsumer, s.Tag(ext.SpanKind))
+	assert.Equal(parent.Context().SpanID(), s.ParentID())
+	assert.Equal(parent.Context().TraceID(), s.TraceID())
+}
+
+func TestProcessTaskError(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	// a task without span context is processed within a new trace
+	task := asynq.NewTask("email:send", []byte("hello"))
+	var payload []byte
+	err := WrapHandler(asynq.HandlerFunc(func(_ context.Context, task *asynq.Task) error {
+		payload = task.Payload()
+		return errors.New("failed")
+	})).ProcessTask(context.Background(), task)
+	assert.EqualError(t, err, "failed")
+	assert.Equal(t, []byte("hello"), payload)
+
+	spans := mt.FinishedSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, uint64(0), spans[0].ParentID())
+	assert.Equal(t, err, spans[0].Tag(ext.Error))
+}
+
+func TestPayload(t *testing.T) {
+	assert := assert.New(t)
+	carrier := tracer.TextMapCarrier{"x-datadog-trace-id": "1", "x-datadog-parent-id": "2"}
+	for _, payload := range [][]byte{nil, []byte("hello"), []byte(payloadPrefix)} {
+		got, p, ok := unwrapPayload(wrapPayload(carrier, payload))
+		assert.True(ok)
+		assert.Equal(carrier, got)
+		assert.Equal(string(payload), string(p))
+	}
+	for _, payload := range [][]byte{nil, []byte("hello"), []byte(payloadPrefix), []byte(payloadPrefix + "\x10{}")} {
+		_, p, ok := unwrapPayload(payload)
+		assert.False(ok)
+		assert.Equal(payload, p)
+	}
+}
+
+func TestEnqueue(t *testing.T) {
+	if _, ok := os.LookupEnv("INTEGRATION"); !ok {
+		t.Skip("🚧 Skipping integration test (INTEGRATION environment variable is not set)")
+	}
+	assert := assert.New(t)
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	c := WrapClient(asynq.NewClient(asynq.RedisClientOpt{Addr: "127.0.0.1:6379"}))
+	defer c.Close()
+	parent, ctx := tracer.StartSpanFromContext(context.Background(), "request")
+	info, err := c.EnqueueContext(ctx, NewTask(ctx, "email:send", []byte("hello")), asynq.Queue("emails"))
+	require.NoError(t, err)
+	parent.Finish()
+
+	spans := mt.FinishedSpans()
+	require.Len(t, spans, 2)
+	s := spans[0]
+	assert.Equal("asynq.enqueue", s.OperationName())
+	assert.Equal("email:send", s.Tag(ext.ResourceName))
+	assert.Equal(ext.SpanKindProducer, s.Tag(ext.SpanKind))
+	assert.Equal(info.ID, s.Tag(tagTaskID))
+	assert.Equal("emails", s.Tag(tagQueue))
+	assert.Equal(parent.Context().SpanID(), s.ParentID())
+}
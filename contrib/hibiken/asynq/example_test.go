@@ -0,0 +1,43 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022 Datadog, Inc.
+
+package asynq_test
+
+import (
+	"context"
+	"log"
+
+	asynqtrace "gopkg.in/DataDog/dd-trace-go.v1/contrib/hibiken/asynq"
+
+	"github.com/hibiken/asynq"
+)
+
+func Example_client() {
+	redis := asynq.RedisClientOpt{Addr: "127.0.0.1:6379"}
+	c := asynqtrace.WrapClient(asynq.NewClient(redis))
+	defer c.Close()
+
+	// The context of the request enqueuing the task, to which its processing is connected.
+	ctx := context.Background()
+	task := asynqtrace.NewTask(ctx, "email:send", []byte(`{"to":"bob@example.com"}`))
+	if _, err := c.EnqueueContext(ctx, task); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func Example_server() {
+	redis := asynq.RedisClientOpt{Addr: "127.0.0.1:6379"}
+	srv := asynq.NewServer(redis, asynq.Config{Concurrency: 10})
+
+	mux := asynq.NewServeMux()
+	mux.Use(asynqtrace.Middleware(asynqtrace.WithServiceName("email-workers")))
+	mux.HandleFunc("email:send", func(ctx context.Context, task *asynq.Task) error {
+		// Send the email of task.Payload().
+		return nil
+	})
+	if err := srv.Run(mux); err != nil {
+		log.Fatal(err)
+	}
+}
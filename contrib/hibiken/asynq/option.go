@@ -0,0 +1,71 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022 Datadog, Inc.
+
+package asynq
+
+import (
+	"math"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
+)
+
+type config struct {
+	consumerServiceName string
+	producerServiceName string
+	analyticsRate       float64
+}
+
+// An Option customizes the config.
+type Option func(cfg *config)
+
+func newConfig(opts ...Option) *config {
+	cfg := &config{
+		consumerServiceName: "asynq",
+		producerServiceName: "asynq",
+		analyticsRate:       math.NaN(),
+	}
+	if internal.BoolEnv("DD_TRACE_ASYNQ_ANALYTICS_ENABLED", false) {
+		cfg.analyticsRate = 1.0
+	}
+	if svc := globalconfig.ServiceName(); svc != "" {
+		cfg.consumerServiceName = svc
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// WithServiceName sets the config service name to serviceName.
+func WithServiceName(serviceName string) Option {
+	return func(cfg *config) {
+		cfg.consumerServiceName = serviceName
+		cfg.producerServiceName = serviceName
+	}
+}
+
+// WithAnalytics enables Trace Analytics for all started spans.
+func WithAnalytics(on bool) Option {
+	return func(cfg *config) {
+		if on {
+			cfg.analyticsRate = 1.0
+		} else {
+			cfg.analyticsRate = math.NaN()
+		}
+	}
+}
+
+// WithAnalyticsRate sets the sampling rate for Trace Analytics events
+// correlated to started spans.
+func WithAnalyticsRate(rate float64) Option {
+	return func(cfg *config) {
+		if rate >= 0.0 && rate <= 1.0 {
+			cfg.analyticsRate = rate
+		} else {
+			cfg.analyticsRate = math.NaN()
+		}
+	}
+}
@@ -0,0 +1,53 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022 Datadog, Inc.
+
+package asynq
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+)
+
+// payloadPrefix starts the payloads holding a span context, which are made of
+// the prefix, the uvarint length of the JSON-encoded carrier of the span
+// context, the carrier, and the original payload.
+const payloadPrefix = "\x00ddtrace\x00"
+
+// wrapPayload returns payload prefixed with the given carrier.
+func wrapPayload(carrier tracer.TextMapCarrier, payload []byte) []byte {
+	header, err := json.Marshal(carrier)
+	if err != nil {
+		return payload
+	}
+	var n [binary.MaxVarintLen64]byte
+	l := binary.PutUvarint(n[:], uint64(len(header)))
+	p := make([]byte, 0, len(payloadPrefix)+l+len(header)+len(payload))
+	p = append(p, payloadPrefix...)
+	p = append(p, n[:l]...)
+	p = append(p, header...)
+	return append(p, payload...)
+}
+
+// unwrapPayload returns the carrier and the original payload of p, or false
+// when p was not returned by wrapPayload.
+func unwrapPayload(p []byte) (tracer.TextMapCarrier, []byte, bool) {
+	if !bytes.HasPrefix(p, []byte(payloadPrefix)) {
+		return nil, p, false
+	}
+	rest := p[len(payloadPrefix):]
+	size, l := binary.Uvarint(rest)
+	if l <= 0 || uint64(len(rest)-l) < size {
+		return nil, p, false
+	}
+	rest = rest[l:]
+	var carrier tracer.TextMapCarrier
+	if err := json.Unmarshal(rest[:size], &carrier); err != nil {
+		return nil, p, false
+	}
+	return carrier, rest[size:], true
+}
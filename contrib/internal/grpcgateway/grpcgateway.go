@@ -0,0 +1,43 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+// Package grpcgateway links the spans of the HTTP requests served by a
+// grpc-gateway mux with the gRPC calls they are translated into.
+package grpcgateway
+
+import (
+	"context"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+)
+
+// TagMethodName is the tag of the gRPC method of a gateway request span.
+const TagMethodName = "grpc.method.name"
+
+type contextKey struct{}
+
+// request holds the span of an HTTP request served by a gateway mux.
+type request struct {
+	span   ddtrace.Span
+	method string // HTTP method
+}
+
+// ContextWithRequest returns a copy of ctx holding the span of the HTTP request
+// of the given method served by a gateway mux.
+func ContextWithRequest(ctx context.Context, span ddtrace.Span, method string) context.Context {
+	return context.WithValue(ctx, contextKey{}, &request{span: span, method: method})
+}
+
+// SetMethod tags the span of the gateway request held by ctx, if any, with the
+// gRPC method it is translated into, and names its resource after it.
+func SetMethod(ctx context.Context, method string) {
+	r, ok := ctx.Value(contextKey{}).(*request)
+	if !ok {
+		return
+	}
+	r.span.SetTag(TagMethodName, method)
+	r.span.SetTag(ext.ResourceName, r.method+" "+method)
+}
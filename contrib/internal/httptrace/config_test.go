@@ -41,6 +41,18 @@ func TestConfig(t *testing.T) {
 				queryStringRegexp: defaultQueryStringRegexp,
 			},
 		},
+		{
+			name: "header-tags",
+			env:  map[string]string{envHeaderTags: "x-request-id, User-Agent:ua"},
+			cfg: config{
+				queryString:       true,
+				queryStringRegexp: defaultQueryStringRegexp,
+				headerTags: map[string]string{
+					"X-Request-Id": "",
+					"User-Agent":   "ua",
+				},
+			},
+		},
 		{
 			name: "disable-query-obf",
 			env:  map[string]string{envQueryStringRegexp: ""},
@@ -57,6 +69,7 @@ func TestConfig(t *testing.T) {
 			c := newConfig()
 			require.Equal(t, tc.cfg.queryStringRegexp, c.queryStringRegexp)
 			require.Equal(t, tc.cfg.queryString, c.queryString)
+			require.Equal(t, tc.cfg.headerTags, c.headerTags)
 		})
 	}
 }
@@ -65,6 +78,7 @@ func cleanEnv() func() {
 	env := map[string]string{
 		envQueryStringDisabled: os.Getenv(envQueryStringDisabled),
 		envQueryStringRegexp:   os.Getenv(envQueryStringRegexp),
+		envHeaderTags:          os.Getenv(envHeaderTags),
 	}
 	for k := range env {
 		os.Unsetenv(k)
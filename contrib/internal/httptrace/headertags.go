@@ -0,0 +1,99 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022 Datadog, Inc.
+
+package httptrace
+
+import (
+	"net/http"
+	"strings"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
+)
+
+// ParseHeaderTags returns the headers to set as span tags, keyed by their canonical
+// name. Each entry is either a header name, which is then set as the
+// http.request.headers.<header> or http.response.headers.<header> tag, or a
+// header:tag pair specifying the tag name used for both the request and the
+// response header. This is the format of the entries of the DD_TRACE_HEADER_TAGS
+// environment variable. Empty entries are ignored.
+func ParseHeaderTags(headers []string) map[string]string {
+	var tags map[string]string
+	for _, h := range headers {
+		h = strings.TrimSpace(h)
+		if h == "" {
+			continue
+		}
+		tag := ""
+		if i := strings.LastIndex(h, ":"); i >= 0 {
+			h, tag = strings.TrimSpace(h[:i]), strings.TrimSpace(h[i+1:])
+		}
+		if h == "" {
+			continue
+		}
+		if tags == nil {
+			tags = make(map[string]string)
+		}
+		// an empty tag stands for the default tag names, which depend on whether
+		// the header is a request or a response one
+		tags[http.CanonicalHeaderKey(h)] = tag
+	}
+	return tags
+}
+
+// EnvHeaderTags returns the headers to set as span tags according to the
+// DD_TRACE_HEADER_TAGS environment variable, in the format of ParseHeaderTags.
+func EnvHeaderTags() map[string]string {
+	return cfg.headerTags
+}
+
+// RequestHeaderTags returns a span start option setting the values of the request
+// headers listed in headerTags, as returned by ParseHeaderTags, as span tags.
+// Multiple values of a header are joined with commas.
+func RequestHeaderTags(r *http.Request, headerTags map[string]string) ddtrace.StartSpanOption {
+	return func(cfg *ddtrace.StartSpanConfig) {
+		if cfg.Tags == nil {
+			cfg.Tags = make(map[string]interface{})
+		}
+		for header, tag := range headerTags {
+			if v := r.Header.Values(header); len(v) > 0 {
+				cfg.Tags[headerTag("http.request.headers.", header, tag)] = strings.Join(v, ",")
+			}
+		}
+	}
+}
+
+// SetResponseHeaderTags sets the values of the response headers h listed in
+// headerTags, as returned by ParseHeaderTags, as tags of the span s. Multiple
+// values of a header are joined with commas.
+func SetResponseHeaderTags(s ddtrace.Span, h http.Header, headerTags map[string]string) {
+	for header, tag := range headerTags {
+		if v := h.Values(header); len(v) > 0 {
+			s.SetTag(headerTag("http.response.headers.", header, tag), strings.Join(v, ","))
+		}
+	}
+}
+
+// headerTag returns tag, or the default tag name of header when tag is empty.
+func headerTag(prefix, header, tag string) string {
+	if tag != "" {
+		return tag
+	}
+	return prefix + normalizeHeaderTag(header)
+}
+
+// normalizeHeaderTag returns the lowercase header name, with the characters other
+// than letters, digits, hyphens and underscores replaced by underscores.
+func normalizeHeaderTag(header string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		default:
+			return '_'
+		}
+	}, header)
+}
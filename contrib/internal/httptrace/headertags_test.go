@@ -0,0 +1,76 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022 Datadog, Inc.
+
+package httptrace
+
+import (
+	"net/http"
+	"testing"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseHeaderTags(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		headers []string
+		tags    map[string]string
+	}{
+		{
+			name: "empty",
+			tags: nil,
+		},
+		{
+			name:    "header",
+			headers: []string{"X-Amzn-Trace-Id", " content-type "},
+			tags: map[string]string{
+				"X-Amzn-Trace-Id": "",
+				"Content-Type":    "",
+			},
+		},
+		{
+			name:    "tag",
+			headers: []string{"x-request-id:http.request_id", "x-session:"},
+			tags: map[string]string{
+				"X-Request-Id": "http.request_id",
+				"X-Session":    "",
+			},
+		},
+		{
+			name:    "invalid",
+			headers: []string{"", ":tag", " "},
+			tags:    nil,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.tags, ParseHeaderTags(tc.headers))
+		})
+	}
+}
+
+func TestSetResponseHeaderTags(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	h := http.Header{}
+	h.Set("X-Request-Id", "abc")
+	h.Add("Cache-Control", "no-cache")
+	h.Add("Cache-Control", "no-store")
+	h.Set("X_custom.header", "value")
+	s := tracer.StartSpan("test")
+	SetResponseHeaderTags(s, h, ParseHeaderTags([]string{"x-request-id:http.request_id", "cache-control", "x_custom.header", "x-missing"}))
+	s.Finish()
+
+	spans := mt.FinishedSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "abc", spans[0].Tag("http.request_id"))
+	assert.Equal(t, "no-cache,no-store", spans[0].Tag("http.response.headers.cache-control"))
+	assert.Equal(t, "value", spans[0].Tag("http.response.headers.x_custom_header"))
+	assert.Nil(t, spans[0].Tag("http.response.headers.x-missing"))
+}
@@ -11,18 +11,21 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo/instrumentation/httpsec"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/namingschema"
 )
 
 var cfg = newConfig()
 
 // StartRequestSpan starts an HTTP request span with the standard list of HTTP request span tags (http.method, http.url,
-// http.useragent). Any further span start option can be added with opts.
+// http.useragent, http.client_ip). Any further span start option can be added with opts.
 func StartRequestSpan(r *http.Request, opts ...ddtrace.StartSpanOption) (tracer.Span, context.Context) {
 	// Append our span options before the given ones so that the caller can "overwrite" them.
 	// TODO(): rework span start option handling (https://github.com/DataDog/dd-trace-go/issues/1352)
@@ -38,10 +41,48 @@ func StartRequestSpan(r *http.Request, opts ...ddtrace.StartSpanOption) (tracer.
 			tracer.Tag("http.host", r.Host),
 		}, opts...)
 	}
+	if len(cfg.headerTags) > 0 {
+		opts = append([]ddtrace.StartSpanOption{RequestHeaderTags(r, cfg.headerTags)}, opts...)
+	}
 	if spanctx, err := tracer.Extract(tracer.HTTPHeadersCarrier(r.Header)); err == nil {
 		opts = append(opts, tracer.ChildOf(spanctx))
 	}
-	return tracer.StartSpanFromContext(r.Context(), "http.request", opts...)
+	span, ctx := tracer.StartSpanFromContext(r.Context(), namingschema.HTTPServerOp("http.request"), opts...)
+	httpsec.SetIPTags(span, r)
+	return span, ctx
+}
+
+// RequestContentLengthTag returns a span start option setting the size of the body
+// of the given incoming request as the http.request.content_length tag, unless it
+// is unknown.
+func RequestContentLengthTag(r *http.Request) ddtrace.StartSpanOption {
+	return func(cfg *ddtrace.StartSpanConfig) {
+		// the content length of server requests is -1 when unknown
+		if r.ContentLength < 0 {
+			return
+		}
+		if cfg.Tags == nil {
+			cfg.Tags = make(map[string]interface{})
+		}
+		cfg.Tags[ext.HTTPRequestContentLength] = r.ContentLength
+	}
+}
+
+// RouteParamsTags returns a span start option setting the given route parameters
+// matched by the router of the request, e.g. {"id": "123"} for the route
+// /user/:id requested as /user/123, as http.route.params.<name> span tags.
+func RouteParamsTags(params map[string]string) ddtrace.StartSpanOption {
+	return func(cfg *ddtrace.StartSpanConfig) {
+		if len(params) == 0 {
+			return
+		}
+		if cfg.Tags == nil {
+			cfg.Tags = make(map[string]interface{})
+		}
+		for name, value := range params {
+			cfg.Tags[ext.HTTPRouteParams+"."+name] = value
+		}
+	}
 }
 
 // FinishRequestSpan finishes the given HTTP request span and sets the expected response-related tags such as the status
@@ -60,6 +101,28 @@ func FinishRequestSpan(s tracer.Span, status int, opts ...tracer.FinishOption) {
 	s.Finish(opts...)
 }
 
+// SetServerTimingHeader adds to the given response headers a Server-Timing header
+// holding the W3C traceparent of the request span with the given context, e.g.
+//
+//	Server-Timing: traceparent;desc="00-0000000000000000000000000000002a-000000000000002b-01"
+//
+// so that browser clients, such as the RUM SDK, can link their traces to the backend
+// ones. Its sampled flag is only set when the trace is kept by the sampling decision.
+// It must be called before the response status is written.
+func SetServerTimingHeader(h http.Header, ctx ddtrace.SpanContext) {
+	traceID := fmt.Sprintf("%032x", ctx.TraceID())
+	if c, ok := ctx.(interface{ TraceID128() string }); ok {
+		traceID = c.TraceID128()
+	}
+	var sampled int
+	if c, ok := ctx.(interface{ SamplingPriority() (int, bool) }); ok {
+		if p, ok := c.SamplingPriority(); ok && p > 0 {
+			sampled = 1
+		}
+	}
+	h.Add("Server-Timing", fmt.Sprintf(`traceparent;desc="00-%s-%016x-%02x"`, traceID, ctx.SpanID(), sampled))
+}
+
 // urlFromRequest returns the full URL from the HTTP request. If query params are collected, they are obfuscated granted
 // obfuscation is not disabled by the user (through DD_TRACE_OBFUSCATION_QUERY_STRING_REGEXP)
 // See https://docs.datadoghq.com/tracing/configure_data_security#redacting-the-query-in-the-url for more information.
@@ -80,11 +143,7 @@ func urlFromRequest(r *http.Request) string {
 		url = path
 	}
 	// Collect the query string if we are allowed to report it and obfuscate it if possible/allowed
-	if cfg.queryString && r.URL.RawQuery != "" {
-		query := r.URL.RawQuery
-		if cfg.queryStringRegexp != nil {
-			query = cfg.queryStringRegexp.ReplaceAllLiteralString(query, "<redacted>")
-		}
+	if query := ObfuscateQuery(r.URL.RawQuery); query != "" {
 		url = strings.Join([]string{url, query}, "?")
 	}
 	if frag := r.URL.EscapedFragment(); frag != "" {
@@ -92,3 +151,31 @@ func urlFromRequest(r *http.Request) string {
 	}
 	return url
 }
+
+// URLTag returns the given URL of an outgoing request as it should be set in the http.url span tag: without its user
+// information, and with its query string obfuscated or removed as described by ObfuscateQuery.
+func URLTag(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	tagged := *u
+	tagged.User = nil
+	tagged.RawQuery = ObfuscateQuery(u.RawQuery)
+	tagged.ForceQuery = false
+	return tagged.String()
+}
+
+// ObfuscateQuery returns the given raw query string as it should be reported in the http.url span tag. It is empty
+// when query string collection is disabled (through DD_TRACE_HTTP_URL_QUERY_STRING_DISABLED), and the parts of it
+// matching the obfuscation regexp (DD_TRACE_OBFUSCATION_QUERY_STRING_REGEXP) are replaced with "<redacted>" otherwise,
+// unless obfuscation is disabled by the user.
+// See https://docs.datadoghq.com/tracing/configure_data_security#redacting-the-query-in-the-url for more information.
+func ObfuscateQuery(query string) string {
+	if !cfg.queryString || query == "" {
+		return ""
+	}
+	if cfg.queryStringRegexp != nil {
+		query = cfg.queryStringRegexp.ReplaceAllLiteralString(query, "<redacted>")
+	}
+	return query
+}
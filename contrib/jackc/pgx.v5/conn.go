@@ -0,0 +1,83 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022 Datadog, Inc.
+
+package pgx
+
+import (
+	"context"
+	"io"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// pgx does not report the operations below to its tracers, so they are traced by
+// the helpers of this file instead. They use the configuration of the tracer set
+// on the connection by this package, if any.
+
+// WaitForNotification waits for a notification on one of the channels conn
+// listens to, as (*pgx.Conn).WaitForNotification does, and traces the wait
+// so that it does not show up as a gap in the trace. The channel of the received
+// notification is set as the db.notification.channel tag.
+func WaitForNotification(ctx context.Context, conn *pgx.Conn) (*pgconn.Notification, error) {
+	t := connTracer(conn)
+	ctx = t.startSpan(ctx, operationNameWaitForNotification, queryTypeWaitForNotification, "", conn.Config())
+	n, err := conn.WaitForNotification(ctx)
+	if span, ok := ctx.Value(spanKey{}).(ddtrace.Span); ok && n != nil {
+		span.SetTag("db.notification.channel", n.Channel)
+	}
+	t.finishSpan(ctx, err)
+	return n, err
+}
+
+// CopyFromReader executes the given COPY FROM STDIN statement on conn, reading
+// the copied data from r through the COPY protocol, as
+// (*pgconn.PgConn).CopyFrom does. The operation is traced like
+// (*pgx.Conn).CopyFrom, and can be disabled with WithTraceCopyFrom.
+func CopyFromReader(ctx context.Context, conn *pgx.Conn, r io.Reader, sql string) (pgconn.CommandTag, error) {
+	t := connTracer(conn)
+	if !t.cfg.traceCopyFrom {
+		return conn.PgConn().CopyFrom(ctx, r, sql)
+	}
+	ctx = t.startSpan(ctx, operationName, queryTypeCopyFrom, sql, conn.Config())
+	tag, err := conn.PgConn().CopyFrom(ctx, r, sql)
+	setRowsAffected(ctx, tag, err)
+	t.finishSpan(ctx, err)
+	return tag, err
+}
+
+// CopyToWriter executes the given COPY TO STDOUT statement on conn, writing the
+// copied data to w through the COPY protocol, as (*pgconn.PgConn).CopyTo does.
+// The operation is traced with the CopyTo query type.
+func CopyToWriter(ctx context.Context, conn *pgx.Conn, w io.Writer, sql string) (pgconn.CommandTag, error) {
+	t := connTracer(conn)
+	ctx = t.startSpan(ctx, operationName, queryTypeCopyTo, sql, conn.Config())
+	tag, err := conn.PgConn().CopyTo(ctx, w, sql)
+	setRowsAffected(ctx, tag, err)
+	t.finishSpan(ctx, err)
+	return tag, err
+}
+
+// setRowsAffected sets the number of rows copied by a successful COPY on the span held by ctx.
+func setRowsAffected(ctx context.Context, tag pgconn.CommandTag, err error) {
+	if span, ok := ctx.Value(spanKey{}).(ddtrace.Span); ok && err == nil {
+		if s, ok := span.(ddtrace.SpanWithMetrics); ok {
+			s.SetMetric("db.row_count", float64(tag.RowsAffected()))
+		} else {
+			span.SetTag("db.row_count", float64(tag.RowsAffected()))
+		}
+	}
+}
+
+// connTracer returns the tracer set on conn by this package, or a tracer with
+// the default configuration when conn is not traced by this package.
+func connTracer(conn *pgx.Conn) *pgxTracer {
+	if t, ok := conn.Config().Tracer.(*pgxTracer); ok {
+		return t
+	}
+	return NewTracer().(*pgxTracer)
+}
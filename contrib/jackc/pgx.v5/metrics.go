@@ -0,0 +1,93 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022 Datadog, Inc.
+
+package pgx
+
+import (
+	"sync"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// statsdClient is the subset of the statsd client used to report pool metrics.
+type statsdClient interface {
+	Gauge(name string, value float64, tags []string, rate float64) error
+	Close() error
+}
+
+// RecordPoolStats starts a goroutine which reports the statistics of pool to
+// Dogstatsd every interval, as the datadog.tracer.pgx.pool.* metrics. Along with
+// the acquisition spans, they tell pool exhaustion apart from slow queries. The
+// metrics are tagged with the service name of the pool when it was created by
+// NewPool or NewPoolWithConfig, and are sent to the same address as the runtime
+// metrics of the tracer, so RecordPoolStats must be called after tracer.Start.
+// It returns a function stopping the reporting.
+func RecordPoolStats(pool *pgxpool.Pool, interval time.Duration) (stop func()) {
+	addr := globalconfig.DogstatsdAddr()
+	if addr == "" {
+		log.Warn("contrib/jackc/pgx.v5: RecordPoolStats called before tracer.Start; pool metrics disabled")
+		return func() {}
+	}
+	client, err := statsd.New(addr)
+	if err != nil {
+		log.Warn("contrib/jackc/pgx.v5: pool metrics disabled: %v", err)
+		return func() {}
+	}
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer client.Close()
+		reportPoolStats(client, pool, poolStatsTags(pool), interval, done)
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			wg.Wait()
+		})
+	}
+}
+
+// poolStatsTags returns the tags of the metrics of pool.
+func poolStatsTags(pool *pgxpool.Pool) []string {
+	var tags []string
+	if t, ok := pool.Config().ConnConfig.Tracer.(*pgxTracer); ok {
+		tags = append(tags, "service:"+t.cfg.serviceName)
+	}
+	if env := globalconfig.Env(); env != "" {
+		tags = append(tags, "env:"+env)
+	}
+	return tags
+}
+
+// reportPoolStats reports the statistics of pool every interval, until done is closed.
+func reportPoolStats(client statsdClient, pool *pgxpool.Pool, tags []string, interval time.Duration, done <-chan struct{}) {
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-tick.C:
+			stat := pool.Stat()
+			client.Gauge("datadog.tracer.pgx.pool.conns.total", float64(stat.TotalConns()), tags, 1)
+			client.Gauge("datadog.tracer.pgx.pool.conns.idle", float64(stat.IdleConns()), tags, 1)
+			client.Gauge("datadog.tracer.pgx.pool.conns.acquired", float64(stat.AcquiredConns()), tags, 1)
+			client.Gauge("datadog.tracer.pgx.pool.conns.constructing", float64(stat.ConstructingConns()), tags, 1)
+			client.Gauge("datadog.tracer.pgx.pool.conns.max", float64(stat.MaxConns()), tags, 1)
+			client.Gauge("datadog.tracer.pgx.pool.acquire.count", float64(stat.AcquireCount()), tags, 1)
+			client.Gauge("datadog.tracer.pgx.pool.acquire.empty_count", float64(stat.EmptyAcquireCount()), tags, 1)
+			client.Gauge("datadog.tracer.pgx.pool.acquire.canceled_count", float64(stat.CanceledAcquireCount()), tags, 1)
+			client.Gauge("datadog.tracer.pgx.pool.acquire.duration", float64(stat.AcquireDuration().Nanoseconds()), tags, 1)
+		case <-done:
+			return
+		}
+	}
+}
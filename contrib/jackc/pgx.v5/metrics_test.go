@@ -0,0 +1,83 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022 Datadog, Inc.
+
+package pgx
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testStatsdClient struct {
+	mu     sync.Mutex
+	gauges map[string]float64
+	tags   []string
+}
+
+func (c *testStatsdClient) Gauge(name string, value float64, tags []string, _ float64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.gauges == nil {
+		c.gauges = make(map[string]float64)
+	}
+	c.gauges[name] = value
+	c.tags = tags
+	return nil
+}
+
+func (c *testStatsdClient) Close() error { return nil }
+
+func (c *testStatsdClient) gauge(name string) (float64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.gauges[name]
+	return v, ok
+}
+
+func TestRecordPoolStats(t *testing.T) {
+	// The pool does not connect until a connection is acquired.
+	pool, err := NewPool(context.Background(), postgresDSN+"&pool_max_conns=7", WithServiceName("pgx-test"))
+	require.NoError(t, err)
+	defer pool.Close()
+
+	t.Run("report", func(t *testing.T) {
+		globalconfig.SetEnv("test-env")
+		defer globalconfig.SetEnv("")
+
+		var client testStatsdClient
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			reportPoolStats(&client, pool, poolStatsTags(pool), time.Millisecond, done)
+		}()
+		assert.Eventually(t, func() bool {
+			_, ok := client.gauge("datadog.tracer.pgx.pool.acquire.duration")
+			return ok
+		}, time.Second, time.Millisecond)
+		done <- struct{}{}
+
+		max, _ := client.gauge("datadog.tracer.pgx.pool.conns.max")
+		assert.Equal(t, 7.0, max)
+		total, _ := client.gauge("datadog.tracer.pgx.pool.conns.total")
+		assert.Equal(t, 0.0, total)
+		_, ok := client.gauge("datadog.tracer.pgx.pool.acquire.count")
+		assert.True(t, ok)
+		client.mu.Lock()
+		defer client.mu.Unlock()
+		assert.Equal(t, []string{"service:pgx-test", "env:test-env"}, client.tags)
+	})
+
+	t.Run("no-tracer", func(t *testing.T) {
+		stop := RecordPoolStats(pool, time.Millisecond)
+		stop()
+	})
+}
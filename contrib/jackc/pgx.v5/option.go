@@ -0,0 +1,139 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022 Datadog, Inc.
+
+package pgx
+
+import (
+	"math"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
+)
+
+type config struct {
+	serviceName    string
+	analyticsRate  float64
+	childSpansOnly bool
+	errCheck       func(err error) bool
+	traceQuery     bool
+	traceBatch     bool
+	traceCopyFrom  bool
+	tracePrepare   bool
+	traceConnect   bool
+	traceAcquire   bool
+}
+
+// Option represents an option that can be passed to Connect, ConnectConfig,
+// NewPool, NewPoolWithConfig or NewTracer.
+type Option func(*config)
+
+func defaults(cfg *config) {
+	cfg.serviceName = "postgres.db"
+	if svc := globalconfig.ServiceName(); svc != "" {
+		cfg.serviceName = svc
+	}
+	// cfg.analyticsRate = globalconfig.AnalyticsRate()
+	if internal.BoolEnv("DD_TRACE_PGX_ANALYTICS_ENABLED", false) {
+		cfg.analyticsRate = 1.0
+	} else {
+		cfg.analyticsRate = math.NaN()
+	}
+	cfg.traceQuery = true
+	cfg.traceBatch = true
+	cfg.traceCopyFrom = true
+	cfg.tracePrepare = true
+	cfg.traceConnect = true
+	cfg.traceAcquire = true
+}
+
+// WithServiceName sets the given service name for the traced connections.
+func WithServiceName(name string) Option {
+	return func(cfg *config) {
+		cfg.serviceName = name
+	}
+}
+
+// WithAnalytics enables Trace Analytics for all started spans.
+func WithAnalytics(on bool) Option {
+	return func(cfg *config) {
+		if on {
+			cfg.analyticsRate = 1.0
+		} else {
+			cfg.analyticsRate = math.NaN()
+		}
+	}
+}
+
+// WithAnalyticsRate sets the sampling rate for Trace Analytics events
+// correlated to started spans.
+func WithAnalyticsRate(rate float64) Option {
+	return func(cfg *config) {
+		if rate >= 0.0 && rate <= 1.0 {
+			cfg.analyticsRate = rate
+		} else {
+			cfg.analyticsRate = math.NaN()
+		}
+	}
+}
+
+// WithChildSpansOnly causes spans to be created only when there is an
+// existing parent span in the Context.
+func WithChildSpansOnly() Option {
+	return func(cfg *config) {
+		cfg.childSpansOnly = true
+	}
+}
+
+// WithErrorCheck specifies a function fn which determines whether the passed
+// error should be marked as an error. The fn is called whenever a pgx operation
+// finishes with an error.
+func WithErrorCheck(fn func(err error) bool) Option {
+	return func(cfg *config) {
+		cfg.errCheck = fn
+	}
+}
+
+// WithTraceQuery enables or disables tracing of queries and executions. Enabled by default.
+func WithTraceQuery(enabled bool) Option {
+	return func(cfg *config) {
+		cfg.traceQuery = enabled
+	}
+}
+
+// WithTraceBatch enables or disables tracing of batched queries. Enabled by default.
+func WithTraceBatch(enabled bool) Option {
+	return func(cfg *config) {
+		cfg.traceBatch = enabled
+	}
+}
+
+// WithTraceCopyFrom enables or disables tracing of COPY FROM operations. Enabled by default.
+func WithTraceCopyFrom(enabled bool) Option {
+	return func(cfg *config) {
+		cfg.traceCopyFrom = enabled
+	}
+}
+
+// WithTracePrepare enables or disables tracing of prepared statements. Enabled by default.
+func WithTracePrepare(enabled bool) Option {
+	return func(cfg *config) {
+		cfg.tracePrepare = enabled
+	}
+}
+
+// WithTraceConnect enables or disables tracing of connection establishment. Enabled by default.
+func WithTraceConnect(enabled bool) Option {
+	return func(cfg *config) {
+		cfg.traceConnect = enabled
+	}
+}
+
+// WithTraceAcquire enables or disables tracing of connection acquisitions from
+// a pgxpool.Pool. Enabled by default.
+func WithTraceAcquire(enabled bool) Option {
+	return func(cfg *config) {
+		cfg.traceAcquire = enabled
+	}
+}
@@ -0,0 +1,74 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022 Datadog, Inc.
+
+// Package pgx provides functions to trace the jackc/pgx/v5 package (https://github.com/jackc/pgx).
+//
+// As pgx v5 requires Go 1.20, this package is a separate module which must be
+// required on its own: gopkg.in/DataDog/dd-trace-go.v1/contrib/jackc/pgx.v5.
+package pgx // import "gopkg.in/DataDog/dd-trace-go.v1/contrib/jackc/pgx.v5"
+
+import (
+	"context"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Connect establishes a connection with a PostgreSQL server using the given
+// connection string, tracing all the operations performed through it.
+func Connect(ctx context.Context, connString string, opts ...Option) (*pgx.Conn, error) {
+	connConfig, err := pgx.ParseConfig(connString)
+	if err != nil {
+		return nil, err
+	}
+	return ConnectConfig(ctx, connConfig, opts...)
+}
+
+// ConnectConfig establishes a connection with a PostgreSQL server using the
+// given configuration, tracing all the operations performed through it. Any
+// tracer previously set in connConfig is replaced.
+func ConnectConfig(ctx context.Context, connConfig *pgx.ConnConfig, opts ...Option) (*pgx.Conn, error) {
+	// The tracer must be set in a copy, as pgx.ConnectConfig panics with configs
+	// which were not created by pgx.ParseConfig or previously copied.
+	connConfig = connConfig.Copy()
+	connConfig.Tracer = NewTracer(opts...)
+	return pgx.ConnectConfig(ctx, connConfig)
+}
+
+// NewPool creates a connection pool using the given connection string, tracing
+// all the operations performed through it as well as connection acquisitions.
+func NewPool(ctx context.Context, connString string, opts ...Option) (*pgxpool.Pool, error) {
+	config, err := pgxpool.ParseConfig(connString)
+	if err != nil {
+		return nil, err
+	}
+	return NewPoolWithConfig(ctx, config, opts...)
+}
+
+// NewPoolWithConfig creates a connection pool using the given configuration,
+// tracing all the operations performed through it as well as connection
+// acquisitions. Any tracer previously set in config.ConnConfig is replaced.
+func NewPoolWithConfig(ctx context.Context, config *pgxpool.Config, opts ...Option) (*pgxpool.Pool, error) {
+	config = config.Copy()
+	config.ConnConfig.Tracer = NewTracer(opts...)
+	return pgxpool.NewWithConfig(ctx, config)
+}
+
+// NewTracer returns a tracer implementing pgx.QueryTracer, pgx.BatchTracer,
+// pgx.CopyFromTracer, pgx.PrepareTracer, pgx.ConnectTracer and
+// pgxpool.AcquireTracer. It can be set
+// as the Tracer of a pgx.ConnConfig for cases not covered by Connect and
+// ConnectConfig.
+func NewTracer(opts ...Option) pgx.QueryTracer {
+	cfg := new(config)
+	defaults(cfg)
+	for _, fn := range opts {
+		fn(cfg)
+	}
+	log.Debug("contrib/jackc/pgx.v5: Configuring Tracer: %#v", cfg)
+	return &pgxTracer{cfg: cfg}
+}
@@ -0,0 +1,247 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022 Datadog, Inc.
+
+package pgx
+
+import (
+	"context"
+	"math"
+	"strconv"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/namingschema"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const componentName = "pgx"
+
+// The operation names of the spans created by this package. Connection
+// establishment, pool acquisition and notification waits are not queries, so
+// they are reported under their own operation names.
+const (
+	operationName                    = "pgx.query"
+	operationNameConnect             = "pgx.connect"
+	operationNameAcquire             = "pgx.pool.acquire"
+	operationNameWaitForNotification = "pgx.wait_for_notification"
+)
+
+type queryType string
+
+// The query types use the same values as the ones reported by contrib/database/sql,
+// with the addition of the pgx specific operations.
+const (
+	queryTypeConnect             queryType = "Connect"
+	queryTypeQuery               queryType = "Query"
+	queryTypePrepare             queryType = "Prepare"
+	queryTypeBatch               queryType = "Batch"
+	queryTypeBatchQuery          queryType = "BatchQuery"
+	queryTypeCopyFrom            queryType = "CopyFrom"
+	queryTypeCopyTo              queryType = "CopyTo"
+	queryTypeAcquire             queryType = "Acquire"
+	queryTypeWaitForNotification queryType = "WaitForNotification"
+)
+
+var (
+	_ pgx.QueryTracer    = (*pgxTracer)(nil)
+	_ pgx.BatchTracer    = (*pgxTracer)(nil)
+	_ pgx.CopyFromTracer = (*pgxTracer)(nil)
+	_ pgx.PrepareTracer  = (*pgxTracer)(nil)
+	_ pgx.ConnectTracer  = (*pgxTracer)(nil)
+
+	_ pgxpool.AcquireTracer = (*pgxTracer)(nil)
+)
+
+type pgxTracer struct {
+	cfg *config
+}
+
+// TraceQueryStart implements pgx.QueryTracer.
+func (t *pgxTracer) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	if !t.cfg.traceQuery {
+		return ctx
+	}
+	return t.startSpan(ctx, namingschema.DBOp("postgresql", operationName), queryTypeQuery, data.SQL, connConfig(conn))
+}
+
+// TraceQueryEnd implements pgx.QueryTracer.
+func (t *pgxTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	if !t.cfg.traceQuery {
+		return
+	}
+	t.finishSpan(ctx, data.Err)
+}
+
+// TraceBatchStart implements pgx.BatchTracer.
+func (t *pgxTracer) TraceBatchStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceBatchStartData) context.Context {
+	if !t.cfg.traceBatch {
+		return ctx
+	}
+	ctx = t.startSpan(ctx, namingschema.DBOp("postgresql", operationName), queryTypeBatch, "", connConfig(conn))
+	if span, ok := tracer.SpanFromContext(ctx); ok && data.Batch != nil {
+		span.SetTag("db.batch.num_queries", data.Batch.Len())
+	}
+	return ctx
+}
+
+// TraceBatchQuery implements pgx.BatchTracer.
+func (t *pgxTracer) TraceBatchQuery(ctx context.Context, conn *pgx.Conn, data pgx.TraceBatchQueryData) {
+	if !t.cfg.traceBatch {
+		return
+	}
+	// pgx only reports batched queries once they completed, so their spans are
+	// started and finished right away as children of the batch span.
+	t.finishSpan(t.startSpan(ctx, namingschema.DBOp("postgresql", operationName), queryTypeBatchQuery, data.SQL, connConfig(conn)), data.Err)
+}
+
+// TraceBatchEnd implements pgx.BatchTracer.
+func (t *pgxTracer) TraceBatchEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchEndData) {
+	if !t.cfg.traceBatch {
+		return
+	}
+	t.finishSpan(ctx, data.Err)
+}
+
+// TraceCopyFromStart implements pgx.CopyFromTracer.
+func (t *pgxTracer) TraceCopyFromStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceCopyFromStartData) context.Context {
+	if !t.cfg.traceCopyFrom {
+		return ctx
+	}
+	ctx = t.startSpan(ctx, namingschema.DBOp("postgresql", operationName), queryTypeCopyFrom, "", connConfig(conn))
+	if span, ok := tracer.SpanFromContext(ctx); ok {
+		span.SetTag("db.copy_from.table", data.TableName.Sanitize())
+	}
+	return ctx
+}
+
+// TraceCopyFromEnd implements pgx.CopyFromTracer.
+func (t *pgxTracer) TraceCopyFromEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceCopyFromEndData) {
+	if !t.cfg.traceCopyFrom {
+		return
+	}
+	t.finishSpan(ctx, data.Err)
+}
+
+// TracePrepareStart implements pgx.PrepareTracer.
+func (t *pgxTracer) TracePrepareStart(ctx context.Context, conn *pgx.Conn, data pgx.TracePrepareStartData) context.Context {
+	if !t.cfg.tracePrepare {
+		return ctx
+	}
+	return t.startSpan(ctx, namingschema.DBOp("postgresql", operationName), queryTypePrepare, data.SQL, connConfig(conn))
+}
+
+// TracePrepareEnd implements pgx.PrepareTracer.
+func (t *pgxTracer) TracePrepareEnd(ctx context.Context, _ *pgx.Conn, data pgx.TracePrepareEndData) {
+	if !t.cfg.tracePrepare {
+		return
+	}
+	t.finishSpan(ctx, data.Err)
+}
+
+// TraceConnectStart implements pgx.ConnectTracer.
+func (t *pgxTracer) TraceConnectStart(ctx context.Context, data pgx.TraceConnectStartData) context.Context {
+	if !t.cfg.traceConnect {
+		return ctx
+	}
+	return t.startSpan(ctx, operationNameConnect, queryTypeConnect, "", data.ConnConfig)
+}
+
+// TraceConnectEnd implements pgx.ConnectTracer.
+func (t *pgxTracer) TraceConnectEnd(ctx context.Context, data pgx.TraceConnectEndData) {
+	if !t.cfg.traceConnect {
+		return
+	}
+	t.finishSpan(ctx, data.Err)
+}
+
+// TraceAcquireStart implements pgxpool.AcquireTracer.
+func (t *pgxTracer) TraceAcquireStart(ctx context.Context, _ *pgxpool.Pool, _ pgxpool.TraceAcquireStartData) context.Context {
+	if !t.cfg.traceAcquire {
+		return ctx
+	}
+	return t.startSpan(ctx, operationNameAcquire, queryTypeAcquire, "", nil)
+}
+
+// TraceAcquireEnd implements pgxpool.AcquireTracer.
+func (t *pgxTracer) TraceAcquireEnd(ctx context.Context, _ *pgxpool.Pool, data pgxpool.TraceAcquireEndData) {
+	if !t.cfg.traceAcquire {
+		return
+	}
+	// The acquired connection is only known once the acquisition completed.
+	if span, ok := ctx.Value(spanKey{}).(ddtrace.Span); ok && data.Conn != nil {
+		for k, v := range connTags(data.Conn.Config()) {
+			span.SetTag(k, v)
+		}
+	}
+	t.finishSpan(ctx, data.Err)
+}
+
+// startSpan starts a span with the given operation name and query type and returns a context holding it.
+// The span is not started if child spans only are requested and ctx holds no span.
+func (t *pgxTracer) startSpan(ctx context.Context, opName string, qtype queryType, query string, connConfig *pgx.ConnConfig) context.Context {
+	if _, exists := tracer.SpanFromContext(ctx); t.cfg.childSpansOnly && !exists {
+		return ctx
+	}
+	resource := string(qtype)
+	if query != "" {
+		resource = query
+	}
+	opts := []ddtrace.StartSpanOption{
+		tracer.Tag(ext.Component, componentName),
+		tracer.ServiceName(t.cfg.serviceName),
+		tracer.SpanType(ext.SpanTypeSQL),
+		tracer.ResourceName(resource),
+		tracer.Tag("sql.query_type", string(qtype)),
+	}
+	if !math.IsNaN(t.cfg.analyticsRate) {
+		opts = append(opts, tracer.Tag(ext.EventSampleRate, t.cfg.analyticsRate))
+	}
+	if connConfig != nil {
+		for k, v := range connTags(connConfig) {
+			opts = append(opts, tracer.Tag(k, v))
+		}
+	}
+	span, ctx := tracer.StartSpanFromContext(ctx, opName, opts...)
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// connTags returns the tags describing the database reached through connConfig.
+func connTags(connConfig *pgx.ConnConfig) map[string]string {
+	return map[string]string{
+		ext.DBName:     connConfig.Database,
+		ext.DBUser:     connConfig.User,
+		ext.TargetHost: connConfig.Host,
+		ext.TargetPort: strconv.FormatUint(uint64(connConfig.Port), 10),
+	}
+}
+
+// finishSpan finishes the span started by startSpan and held by ctx, if any.
+func (t *pgxTracer) finishSpan(ctx context.Context, err error) {
+	span, ok := ctx.Value(spanKey{}).(ddtrace.Span)
+	if !ok {
+		return
+	}
+	if err != nil && (t.cfg.errCheck == nil || t.cfg.errCheck(err)) {
+		span.Finish(tracer.WithError(err))
+		return
+	}
+	span.Finish()
+}
+
+// spanKey is the context key holding the span started by this package. It avoids
+// finishing a span which was not started by the tracer, such as a parent span
+// when child spans only are requested.
+type spanKey struct{}
+
+// connConfig returns the configuration of conn, or nil if there is no connection.
+func connConfig(conn *pgx.Conn) *pgx.ConnConfig {
+	if conn == nil {
+		return nil
+	}
+	return conn.Config()
+}
@@ -20,6 +20,8 @@ import (
 	"github.com/jinzhu/gorm"
 )
 
+const componentName = "gorm"
+
 const (
 	gormContextKey       = "dd-trace-go:context"
 	gormConfigKey        = "dd-trace-go:config"
@@ -121,6 +123,7 @@ func after(scope *gorm.Scope, operationName string) {
 	}
 
 	opts := []ddtrace.StartSpanOption{
+		tracer.Tag(ext.Component, componentName),
 		tracer.StartTime(t),
 		tracer.ServiceName(cfg.serviceName),
 		tracer.SpanType(ext.SpanTypeSQL),
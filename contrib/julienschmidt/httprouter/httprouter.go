@@ -19,6 +19,8 @@ import (
 	"github.com/julienschmidt/httprouter"
 )
 
+const componentName = "httprouter"
+
 // Router is a traced version of httprouter.Router.
 type Router struct {
 	*httprouter.Router
@@ -49,8 +51,9 @@ func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	}
 	resource := req.Method + " " + route
 	httptrace.TraceAndServe(r.Router, w, req, &httptrace.ServeConfig{
-		Service:  r.config.serviceName,
-		Resource: resource,
-		SpanOpts: r.config.spanOpts,
+		Service:   r.config.serviceName,
+		Resource:  resource,
+		SpanOpts:  r.config.spanOpts,
+		Component: componentName,
 	})
 }
@@ -15,7 +15,11 @@ import (
 	"github.com/labstack/echo/v4"
 )
 
-func useAppSec(c echo.Context, span tracer.Span) func() {
+// useAppSec executes the AppSec logic related to the operation start and
+// returns the function to be executed upon finishing the operation. When AppSec
+// blocks the request, the blocking response is written and blocked is true: the
+// request must not be handled.
+func useAppSec(c echo.Context, span tracer.Span) (afterMiddleware func(), blocked bool) {
 	req := c.Request()
 	instrumentation.SetAppSecEnabledTags(span)
 	params := make(map[string]string)
@@ -25,8 +29,13 @@ func useAppSec(c echo.Context, span tracer.Span) func() {
 	args := httpsec.MakeHandlerOperationArgs(req, params)
 	ctx, op := httpsec.StartOperation(req.Context(), args)
 	c.SetRequest(req.WithContext(ctx))
+	httpsec.MonitorRequestBody(ctx, c.Request())
+	if op.Err() != nil {
+		httpsec.WriteBlockingResponse(c.Response(), c.Request())
+		blocked = true
+	}
 	return func() {
-		events := op.Finish(httpsec.HandlerOperationRes{Status: c.Response().Status})
+		events := op.Finish(httpsec.HandlerOperationRes{Status: c.Response().Status, Headers: httpsec.MakeResponseHeaders(c.Response().Header())})
 		if len(events) > 0 {
 			remoteIP, _, err := net.SplitHostPort(req.RemoteAddr)
 			if err != nil {
@@ -35,5 +44,5 @@ func useAppSec(c echo.Context, span tracer.Span) func() {
 			httpsec.SetSecurityEventTags(span, events, remoteIP, args.Headers, c.Response().Writer.Header())
 		}
 		instrumentation.SetTags(span, op.Tags())
-	}
+	}, blocked
 }
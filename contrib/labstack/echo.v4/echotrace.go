@@ -7,7 +7,10 @@
 package echo
 
 import (
+	"fmt"
 	"math"
+	"net/http"
+	"strconv"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/contrib/internal/httptrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
@@ -19,6 +22,8 @@ import (
 	"github.com/labstack/echo/v4"
 )
 
+const componentName = "echo"
+
 // Middleware returns echo middleware which will trace incoming requests.
 func Middleware(opts ...Option) echo.MiddlewareFunc {
 	appsecEnabled := appsec.Enabled()
@@ -29,6 +34,7 @@ func Middleware(opts ...Option) echo.MiddlewareFunc {
 	}
 	log.Debug("contrib/labstack/echo.v4: Configuring Middleware: %#v", cfg)
 	spanOpts := []ddtrace.StartSpanOption{
+		tracer.Tag(ext.Component, componentName),
 		tracer.ServiceName(cfg.serviceName),
 	}
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
@@ -44,12 +50,14 @@ func Middleware(opts ...Option) echo.MiddlewareFunc {
 
 			request := c.Request()
 			route := c.Path()
-			resource := request.Method + " " + route
-			opts := append(spanOpts, tracer.ResourceName(resource), tracer.Tag(ext.HTTPRoute, route))
+			opts := append(spanOpts, tracer.ResourceName(cfg.resourceNamer(c)), tracer.Tag(ext.HTTPRoute, route))
 
 			if !math.IsNaN(cfg.analyticsRate) {
 				opts = append(opts, tracer.Tag(ext.EventSampleRate, cfg.analyticsRate))
 			}
+			if len(cfg.headerTags) > 0 {
+				opts = append(opts, httptrace.RequestHeaderTags(request, cfg.headerTags))
+			}
 
 			var finishOpts []tracer.FinishOption
 			if cfg.noDebugStack {
@@ -57,20 +65,38 @@ func Middleware(opts ...Option) echo.MiddlewareFunc {
 			}
 
 			span, ctx := httptrace.StartRequestSpan(request, opts...)
+			if cfg.serverTiming {
+				httptrace.SetServerTimingHeader(c.Response().Header(), span.Context())
+			}
 			defer func() {
-				httptrace.FinishRequestSpan(span, c.Response().Status, finishOpts...)
+				// httptrace.FinishRequestSpan is not used as it marks all the
+				// 5xx status codes as errors, regardless of cfg.isStatusError.
+				status := c.Response().Status
+				span.SetTag(ext.HTTPCode, strconv.Itoa(status))
+				httptrace.SetResponseHeaderTags(span, c.Response().Header(), httptrace.EnvHeaderTags())
+				httptrace.SetResponseHeaderTags(span, c.Response().Header(), cfg.headerTags)
+				if cfg.isStatusError(status) {
+					// prepended so that the error returned by the handler, if any, prevails
+					finishOpts = append([]tracer.FinishOption{tracer.WithError(fmt.Errorf("%d: %s", status, http.StatusText(status)))}, finishOpts...)
+				}
+				span.Finish(finishOpts...)
 			}()
 
 			// pass the span through the request context
 			c.SetRequest(request.WithContext(ctx))
 			// serve the request to the next middleware
 			if appsecEnabled {
-				afterMiddleware := useAppSec(c, span)
+				afterMiddleware, blocked := useAppSec(c, span)
 				defer afterMiddleware()
+				if blocked {
+					return nil
+				}
 			}
 			err := next(c)
 			if err != nil {
-				finishOpts = append(finishOpts, tracer.WithError(err))
+				if cfg.errCheck == nil || cfg.errCheck(err) {
+					finishOpts = append(finishOpts, tracer.WithError(err))
+				}
 				// invokes the registered HTTP error handler
 				c.Error(err)
 			}
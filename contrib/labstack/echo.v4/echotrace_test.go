@@ -7,9 +7,11 @@ package echo
 
 import (
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
 	"testing"
 
@@ -93,6 +95,26 @@ func TestTrace200(t *testing.T) {
 	assert.Equal("http://example.com/user/123", span.Tag(ext.HTTPURL))
 }
 
+func TestClientIP(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	router := echo.New()
+	router.Use(Middleware(WithServiceName("foobar")))
+	router.GET("/user/:id", func(c echo.Context) error {
+		return c.NoContent(200)
+	})
+
+	r := httptest.NewRequest("GET", "/user/123", nil)
+	r.Header.Set("X-Forwarded-For", "8.8.8.8")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+
+	spans := mt.FinishedSpans()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "8.8.8.8", spans[0].Tag(ext.HTTPClientIP))
+}
+
 func TestTraceAnalytics(t *testing.T) {
 	assert := assert.New(t)
 	mt := mocktracer.Start()
@@ -303,6 +325,144 @@ func TestIgnoreRequestFunc(t *testing.T) {
 	assert.Len(spans, 0)
 }
 
+func TestResourceNamer(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	router := echo.New()
+	router.Use(Middleware(WithResourceNamer(func(c echo.Context) string {
+		return "user " + c.Param("id")
+	})))
+	router.GET("/user/:id", func(c echo.Context) error {
+		return c.NoContent(200)
+	})
+	r := httptest.NewRequest("GET", "/user/123", nil)
+	router.ServeHTTP(httptest.NewRecorder(), r)
+
+	spans := mt.FinishedSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "user 123", spans[0].Tag(ext.ResourceName))
+	assert.Equal(t, "/user/:id", spans[0].Tag(ext.HTTPRoute))
+}
+
+func TestStatusCheck(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		opts   []Option
+		status int
+		isErr  bool
+	}{
+		{name: "default/4xx", status: 404},
+		{name: "default/5xx", status: 503, isErr: true},
+		{
+			name:   "custom/4xx",
+			opts:   []Option{WithStatusCheck(func(status int) bool { return status >= 400 })},
+			status: 404,
+			isErr:  true,
+		},
+		{
+			name:   "custom/5xx",
+			opts:   []Option{WithStatusCheck(func(status int) bool { return status >= 400 && status < 500 })},
+			status: 503,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mt := mocktracer.Start()
+			defer mt.Stop()
+
+			router := echo.New()
+			router.Use(Middleware(tc.opts...))
+			router.GET("/status", func(c echo.Context) error {
+				return c.NoContent(tc.status)
+			})
+			r := httptest.NewRequest("GET", "/status", nil)
+			router.ServeHTTP(httptest.NewRecorder(), r)
+
+			spans := mt.FinishedSpans()
+			require.Len(t, spans, 1)
+			assert.Equal(t, strconv.Itoa(tc.status), spans[0].Tag(ext.HTTPCode))
+			if tc.isErr {
+				assert.NotNil(t, spans[0].Tag(ext.Error))
+			} else {
+				assert.Nil(t, spans[0].Tag(ext.Error))
+			}
+		})
+	}
+}
+
+func TestErrorCheck(t *testing.T) {
+	errIgnored := errors.New("ignored")
+	errReported := errors.New("reported")
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	router := echo.New()
+	var handled []error
+	router.HTTPErrorHandler = func(err error, c echo.Context) {
+		handled = append(handled, err)
+		c.NoContent(http.StatusBadRequest)
+	}
+	router.Use(Middleware(WithErrorCheck(func(err error) bool {
+		return err != errIgnored
+	})))
+	router.GET("/ignored", func(c echo.Context) error { return errIgnored })
+	router.GET("/reported", func(c echo.Context) error { return errReported })
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/ignored", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/reported", nil))
+
+	// the errors are handled regardless of the error check
+	assert.Contains(t, handled, errIgnored)
+	assert.Contains(t, handled, errReported)
+	spans := mt.FinishedSpans()
+	require.Len(t, spans, 2)
+	assert.Nil(t, spans[0].Tag(ext.Error))
+	assert.Equal(t, errReported, spans[1].Tag(ext.Error))
+}
+
+func TestHeaderTags(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	router := echo.New()
+	router.Use(Middleware(WithHeaderTags([]string{"X-Request-Id", "x-tenant:tenant", "Cache-Control"})))
+	router.GET("/", func(c echo.Context) error {
+		c.Response().Header().Set("Cache-Control", "no-cache")
+		return c.NoContent(200)
+	})
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("X-Request-Id", "abc")
+	r.Header.Set("X-Tenant", "acme")
+	r.Header.Set("Authorization", "secret")
+	router.ServeHTTP(httptest.NewRecorder(), r)
+
+	spans := mt.FinishedSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "abc", spans[0].Tag("http.request.headers.x-request-id"))
+	assert.Equal(t, "acme", spans[0].Tag("tenant"))
+	assert.Equal(t, "no-cache", spans[0].Tag("http.response.headers.cache-control"))
+	assert.Nil(t, spans[0].Tag("http.request.headers.authorization"))
+}
+
+func TestServerTiming(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	router := echo.New()
+	router.Use(Middleware(WithServerTiming()))
+	router.GET("/", func(c echo.Context) error {
+		return c.NoContent(200)
+	})
+	r := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+
+	spans := mt.FinishedSpans()
+	require.Len(t, spans, 1)
+	want := fmt.Sprintf(`traceparent;desc="00-%032x-%016x-00"`, spans[0].TraceID(), spans[0].SpanID())
+	assert.Equal(t, want, w.Header().Get("Server-Timing"))
+}
+
 func TestAppSec(t *testing.T) {
 	appsec.Start()
 	defer appsec.Stop()
@@ -10,6 +10,7 @@ import (
 
 	"github.com/labstack/echo/v4"
 
+	"gopkg.in/DataDog/dd-trace-go.v1/contrib/internal/httptrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
 )
 
@@ -18,6 +19,11 @@ type config struct {
 	analyticsRate     float64
 	noDebugStack      bool
 	ignoreRequestFunc IgnoreRequestFunc
+	resourceNamer     func(c echo.Context) string
+	isStatusError     func(statusCode int) bool
+	errCheck          func(err error) bool
+	headerTags        map[string]string
+	serverTiming      bool
 }
 
 // Option represents an option that can be passed to Middleware.
@@ -32,6 +38,8 @@ func defaults(cfg *config) {
 		cfg.serviceName = svc
 	}
 	cfg.analyticsRate = math.NaN()
+	cfg.resourceNamer = defaultResourceNamer
+	cfg.isStatusError = isServerError
 }
 
 // WithServiceName sets the given service name for the system.
@@ -80,3 +88,58 @@ func WithIgnoreRequest(ignoreRequestFunc IgnoreRequestFunc) Option {
 		cfg.ignoreRequestFunc = ignoreRequestFunc
 	}
 }
+
+// WithResourceNamer sets the function used to name the resource of the span of
+// a request. By default, it is the request method followed by the route of the
+// request, e.g. "GET /users/:id".
+func WithResourceNamer(namer func(c echo.Context) string) Option {
+	return func(cfg *config) {
+		cfg.resourceNamer = namer
+	}
+}
+
+func defaultResourceNamer(c echo.Context) string {
+	return c.Request().Method + " " + c.Path()
+}
+
+// WithStatusCheck specifies a function fn which reports whether the passed
+// statusCode should be considered an error. By default, 5xx status codes are.
+func WithStatusCheck(fn func(statusCode int) bool) Option {
+	return func(cfg *config) {
+		cfg.isStatusError = fn
+	}
+}
+
+func isServerError(statusCode int) bool {
+	return statusCode >= 500 && statusCode < 600
+}
+
+// WithErrorCheck specifies a function fn which determines whether the passed
+// error, returned by the handler, should be marked as an error on the span.
+// The error is passed to the echo error handler regardless of fn.
+func WithErrorCheck(fn func(err error) bool) Option {
+	return func(cfg *config) {
+		cfg.errCheck = fn
+	}
+}
+
+// WithHeaderTags specifies the request and response headers to set as span tags,
+// in addition to the ones set by the DD_TRACE_HEADER_TAGS environment variable.
+// Each entry is either a header name, collected as the http.request.headers.<header>
+// or http.response.headers.<header> tag, or a header:tag pair specifying the tag name.
+// Warning: using this feature can risk exposing sensitive data such as authorisation tokens
+// to Datadog.
+func WithHeaderTags(headers []string) Option {
+	return func(cfg *config) {
+		cfg.headerTags = httptrace.ParseHeaderTags(headers)
+	}
+}
+
+// WithServerTiming adds a Server-Timing response header holding the W3C traceparent
+// of the request span, so that browser clients, such as the RUM SDK, can link their
+// traces to the backend ones.
+func WithServerTiming() Option {
+	return func(cfg *config) {
+		cfg.serverTiming = true
+	}
+}
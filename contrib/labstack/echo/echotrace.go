@@ -18,6 +18,8 @@ import (
 	"github.com/labstack/echo"
 )
 
+const componentName = "echo"
+
 // Middleware returns echo middleware which will trace incoming requests.
 func Middleware(opts ...Option) echo.MiddlewareFunc {
 	cfg := new(config)
@@ -27,6 +29,7 @@ func Middleware(opts ...Option) echo.MiddlewareFunc {
 	}
 	log.Debug("contrib/labstack/echo: Configuring Middleware: %#v", cfg)
 	spanOpts := []ddtrace.StartSpanOption{
+		tracer.Tag(ext.Component, componentName),
 		tracer.ServiceName(cfg.serviceName),
 	}
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
@@ -46,6 +49,7 @@ func Middleware(opts ...Option) echo.MiddlewareFunc {
 
 			span, ctx := httptrace.StartRequestSpan(request, opts...)
 			defer func() {
+				httptrace.SetResponseHeaderTags(span, c.Response().Header(), httptrace.EnvHeaderTags())
 				httptrace.FinishRequestSpan(span, c.Response().Status, finishOpts...)
 			}()
 
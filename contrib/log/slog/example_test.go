@@ -0,0 +1,41 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package slog_test
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"os"
+
+	slogtrace "gopkg.in/DataDog/dd-trace-go.v1/contrib/log/slog"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+)
+
+func ExampleNewJSONHandler() {
+	// Ensure your tracer is started and stopped
+	tracer.Start()
+	defer tracer.Stop()
+
+	// Create the logger, do this once at the beginning of your program
+	logger := slog.New(slogtrace.NewJSONHandler(os.Stdout, nil))
+
+	span, ctx := tracer.StartSpanFromContext(context.Background(), "mySpan")
+	defer span.Finish()
+
+	// Log with the context holding the span to correlate the record with it
+	logger.InfoContext(ctx, "Completed some work!")
+}
+
+func ExampleWithContext() {
+	logger := slog.New(slogtrace.WrapHandler(slog.NewTextHandler(os.Stdout, nil)))
+
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		// All the records of this logger are correlated with the span of the request
+		logger := slogtrace.WithContext(r.Context(), logger)
+		logger.Info("handling request", "path", r.URL.Path)
+	})
+}
@@ -0,0 +1,162 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+// Package slog provides a log/span correlation handler for the log/slog package (https://pkg.go.dev/log/slog).
+package slog // import "gopkg.in/DataDog/dd-trace-go.v1/contrib/log/slog"
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strconv"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
+)
+
+// The keys of the attributes correlating the records with the spans.
+const (
+	keyTraceID = "dd.trace_id"
+	keySpanID  = "dd.span_id"
+	keyService = "dd.service"
+	keyEnv     = "dd.env"
+	keyVersion = "dd.version"
+)
+
+// NewJSONHandler is a convenience function which returns a *slog.JSONHandler
+// wrapped with WrapHandler.
+func NewJSONHandler(w io.Writer, opts *slog.HandlerOptions) slog.Handler {
+	return WrapHandler(slog.NewJSONHandler(w, opts))
+}
+
+// WrapHandler returns a handler which adds to the records logged with a context
+// holding a span the IDs of its trace and of the span, as the dd.trace_id and
+// dd.span_id attributes, along with the service, environment and version of the
+// application when they are known. The attributes are always added at the top
+// level of the records, even when the logger has groups.
+func WrapHandler(h slog.Handler) slog.Handler {
+	return &handler{Handler: h, root: h}
+}
+
+// WithContext returns a logger which adds the attributes of the span held by
+// ctx, as listed by WrapHandler, to all of its records. It allows logging on
+// behalf of a request without passing its context around: the records hold the
+// attributes of this span even when they are logged with a context holding
+// another one. It returns l when ctx holds no span, and uses slog.Default()
+// when l is nil.
+func WithContext(ctx context.Context, l *slog.Logger) *slog.Logger {
+	if l == nil {
+		l = slog.Default()
+	}
+	span, ok := tracer.SpanFromContext(ctx)
+	if !ok {
+		return l
+	}
+	attrs := spanAttrs(span)
+	h, ok := l.Handler().(*handler)
+	if !ok {
+		return slog.New(l.Handler().WithAttrs(attrs))
+	}
+	root := h.root.WithAttrs(attrs)
+	return slog.New(&handler{
+		Handler: replay(root, h.ops),
+		root:    root,
+		ops:     h.ops,
+		grouped: h.grouped,
+		bound:   true,
+	})
+}
+
+// handler is a slog.Handler adding the attributes of the spans to the records.
+type handler struct {
+	slog.Handler
+
+	// root is the wrapped handler, without the attributes and groups which
+	// were added to it since with the ops.
+	root slog.Handler
+	ops  []func(slog.Handler) slog.Handler
+
+	// grouped reports whether any of the ops added a group, in which case the
+	// attributes of the spans can't be added to the records themselves, as
+	// they would end up in the group.
+	grouped bool
+
+	// bound reports whether the handler holds the attributes of a span
+	// already, see WithContext.
+	bound bool
+}
+
+// Handle implements slog.Handler.
+func (h *handler) Handle(ctx context.Context, rec slog.Record) error {
+	if h.bound {
+		return h.Handler.Handle(ctx, rec)
+	}
+	span, ok := tracer.SpanFromContext(ctx)
+	if !ok {
+		return h.Handler.Handle(ctx, rec)
+	}
+	attrs := spanAttrs(span)
+	if h.grouped {
+		return replay(h.root.WithAttrs(attrs), h.ops).Handle(ctx, rec)
+	}
+	rec = rec.Clone()
+	rec.AddAttrs(attrs...)
+	return h.Handler.Handle(ctx, rec)
+}
+
+// WithAttrs implements slog.Handler.
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(sh slog.Handler) slog.Handler {
+		return sh.WithAttrs(attrs)
+	}, false)
+}
+
+// WithGroup implements slog.Handler.
+func (h *handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return h.with(func(sh slog.Handler) slog.Handler {
+		return sh.WithGroup(name)
+	}, true)
+}
+
+// with returns a copy of h with the given op applied.
+func (h *handler) with(op func(slog.Handler) slog.Handler, group bool) *handler {
+	return &handler{
+		Handler: op(h.Handler),
+		root:    h.root,
+		ops:     append(h.ops[:len(h.ops):len(h.ops)], op),
+		grouped: h.grouped || group,
+		bound:   h.bound,
+	}
+}
+
+// replay returns h with the given ops applied in order.
+func replay(h slog.Handler, ops []func(slog.Handler) slog.Handler) slog.Handler {
+	for _, op := range ops {
+		h = op(h)
+	}
+	return h
+}
+
+// spanAttrs returns the attributes correlating the records with span.
+func spanAttrs(span ddtrace.Span) []slog.Attr {
+	attrs := []slog.Attr{
+		slog.String(keyTraceID, strconv.FormatUint(span.Context().TraceID(), 10)),
+		slog.String(keySpanID, strconv.FormatUint(span.Context().SpanID(), 10)),
+	}
+	if svc := globalconfig.ServiceName(); svc != "" {
+		attrs = append(attrs, slog.String(keyService, svc))
+	}
+	if env := globalconfig.Env(); env != "" {
+		attrs = append(attrs, slog.String(keyEnv, env))
+	}
+	if version := globalconfig.ServiceVersion(); version != "" {
+		attrs = append(attrs, slog.String(keyVersion, version))
+	}
+	return attrs
+}
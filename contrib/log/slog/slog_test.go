@@ -0,0 +1,133 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package slog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strconv"
+	"strings"
+	"testing"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// records returns the JSON records written to buf.
+func records(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var recs []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var rec map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &rec))
+		recs = append(recs, rec)
+	}
+	return recs
+}
+
+func assertSpan(t *testing.T, span ddtrace.Span, rec map[string]interface{}) {
+	assert.Equal(t, strconv.FormatUint(span.Context().TraceID(), 10), rec[keyTraceID])
+	assert.Equal(t, strconv.FormatUint(span.Context().SpanID(), 10), rec[keySpanID])
+}
+
+func TestHandler(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	span, ctx := tracer.StartSpanFromContext(context.Background(), "test")
+	defer span.Finish()
+
+	t.Run("span", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := slog.New(NewJSONHandler(&buf, nil))
+		logger.InfoContext(ctx, "with span", "key", "value")
+		logger.Info("without span")
+
+		recs := records(t, &buf)
+		require.Len(t, recs, 2)
+		assertSpan(t, span, recs[0])
+		assert.Equal(t, "value", recs[0]["key"])
+		assert.NotContains(t, recs[1], keyTraceID)
+		assert.NotContains(t, recs[1], keySpanID)
+	})
+
+	t.Run("globalconfig", func(t *testing.T) {
+		defer func(svc, env, version string) {
+			globalconfig.SetServiceName(svc)
+			globalconfig.SetEnv(env)
+			globalconfig.SetServiceVersion(version)
+		}(globalconfig.ServiceName(), globalconfig.Env(), globalconfig.ServiceVersion())
+		globalconfig.SetServiceName("my-service")
+		globalconfig.SetEnv("prod")
+		globalconfig.SetServiceVersion("1.2.3")
+
+		var buf bytes.Buffer
+		slog.New(NewJSONHandler(&buf, nil)).InfoContext(ctx, "msg")
+
+		recs := records(t, &buf)
+		require.Len(t, recs, 1)
+		assert.Equal(t, "my-service", recs[0][keyService])
+		assert.Equal(t, "prod", recs[0][keyEnv])
+		assert.Equal(t, "1.2.3", recs[0][keyVersion])
+	})
+
+	t.Run("groups", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := slog.New(NewJSONHandler(&buf, nil)).With("a", 1).WithGroup("g").With("b", 2)
+		logger.InfoContext(ctx, "msg", "c", 3)
+
+		recs := records(t, &buf)
+		require.Len(t, recs, 1)
+		assertSpan(t, span, recs[0])
+		assert.Equal(t, float64(1), recs[0]["a"])
+		assert.Equal(t, map[string]interface{}{"b": float64(2), "c": float64(3)}, recs[0]["g"])
+	})
+}
+
+func TestWithContext(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	span, ctx := tracer.StartSpanFromContext(context.Background(), "test")
+	defer span.Finish()
+	child, cctx := tracer.StartSpanFromContext(ctx, "child")
+	defer child.Finish()
+
+	t.Run("wrapped", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := WithContext(ctx, slog.New(NewJSONHandler(&buf, nil)).WithGroup("g"))
+		logger.Info("without context")
+		logger.InfoContext(cctx, "with the context of another span", "key", "value")
+
+		recs := records(t, &buf)
+		require.Len(t, recs, 2)
+		for _, rec := range recs {
+			assertSpan(t, span, rec)
+		}
+		assert.Equal(t, map[string]interface{}{"key": "value"}, recs[1]["g"])
+	})
+
+	t.Run("unwrapped", func(t *testing.T) {
+		var buf bytes.Buffer
+		WithContext(ctx, slog.New(slog.NewJSONHandler(&buf, nil))).Info("msg")
+
+		recs := records(t, &buf)
+		require.Len(t, recs, 1)
+		assertSpan(t, span, recs[0])
+	})
+
+	t.Run("no-span", func(t *testing.T) {
+		logger := slog.New(NewJSONHandler(&bytes.Buffer{}, nil))
+		assert.Same(t, logger, WithContext(context.Background(), logger))
+		assert.Same(t, slog.Default(), WithContext(context.Background(), nil))
+	})
+}
@@ -19,6 +19,8 @@ import (
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
 )
 
+const componentName = "dns"
+
 // ListenAndServe calls dns.ListenAndServe with a wrapped Handler.
 func ListenAndServe(addr string, network string, handler dns.Handler) error {
 	return dns.ListenAndServe(addr, network, WrapHandler(handler))
@@ -112,6 +114,7 @@ func (c *Client) ExchangeContext(ctx context.Context, m *dns.Msg, addr string) (
 
 func startSpan(ctx context.Context, opcode int) (ddtrace.Span, context.Context) {
 	return tracer.StartSpanFromContext(ctx, "dns.request",
+		tracer.Tag(ext.Component, componentName),
 		tracer.ServiceName("dns"),
 		tracer.ResourceName(dns.OpcodeToString[opcode]),
 		tracer.SpanType(ext.SpanTypeDNS))
@@ -0,0 +1,68 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package nats_test
+
+import (
+	"context"
+	"log"
+
+	natstrace "gopkg.in/DataDog/dd-trace-go.v1/contrib/nats-io/nats.go"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+
+	"github.com/nats-io/nats.go"
+)
+
+func Example() {
+	nc, err := nats.Connect(nats.DefaultURL)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer nc.Close()
+
+	// Wrap the handler to continue the traces of the received messages
+	_, err = nc.QueueSubscribe("orders", "workers", natstrace.WrapMsgHandler(func(ctx context.Context, msg *nats.Msg) {
+		span, _ := tracer.StartSpanFromContext(ctx, "process.order")
+		defer span.Finish()
+	}))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Wrap the connection to trace the published messages
+	conn := natstrace.WrapConn(nc, natstrace.WithServiceName("orders"))
+	span, ctx := tracer.StartSpanFromContext(context.Background(), "place.order")
+	defer span.Finish()
+	if err := conn.PublishMsgWithContext(ctx, &nats.Msg{Subject: "orders", Data: []byte("order")}); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func ExampleAck() {
+	nc, err := nats.Connect(nats.DefaultURL)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer nc.Close()
+
+	js, err := natstrace.WrapConn(nc).JetStream()
+	if err != nil {
+		log.Fatal(err)
+	}
+	_, err = js.Subscribe("orders", natstrace.WrapMsgHandler(func(ctx context.Context, msg *nats.Msg) {
+		// Measure the latency of the acknowledgement of the message
+		if err := natstrace.Ack(ctx, msg); err != nil {
+			log.Println(err)
+		}
+	}), nats.ManualAck())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// The span of the publish lasts until the message is acknowledged by the server
+	if _, err := js.Publish("orders", []byte("order")); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,47 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package nats
+
+import (
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+
+	"github.com/nats-io/nats.go"
+)
+
+// A MsgCarrier injects and extracts traces from the headers of a nats.Msg.
+type MsgCarrier struct {
+	msg *nats.Msg
+}
+
+var _ interface {
+	tracer.TextMapReader
+	tracer.TextMapWriter
+} = (*MsgCarrier)(nil)
+
+// NewMsgCarrier creates a new MsgCarrier.
+func NewMsgCarrier(msg *nats.Msg) MsgCarrier {
+	return MsgCarrier{msg}
+}
+
+// ForeachKey iterates over every header.
+func (c MsgCarrier) ForeachKey(handler func(key, val string) error) error {
+	for k, vals := range c.msg.Header {
+		for _, v := range vals {
+			if err := handler(k, v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Set sets a header.
+func (c MsgCarrier) Set(key, val string) {
+	if c.msg.Header == nil {
+		c.msg.Header = nats.Header{}
+	}
+	c.msg.Header.Set(key, val)
+}
@@ -0,0 +1,236 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+// Package nats provides functions to trace the nats-io/nats.go package (https://github.com/nats-io/nats.go).
+//
+// The messages published with a connection wrapped with WrapConn, or with its
+// JetStream context, are traced and carry the span in their headers, and the
+// message handlers wrapped with WrapMsgHandler continue the traces of the
+// messages they receive. The span of a message published to JetStream lasts
+// until the publish is acknowledged by the server, and Ack measures the latency
+// of the acknowledgement of the messages received from JetStream.
+package nats // import "gopkg.in/DataDog/dd-trace-go.v1/contrib/nats-io/nats.go"
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/namingschema"
+
+	"github.com/nats-io/nats.go"
+)
+
+const componentName = "nats"
+
+const (
+	tagSubject      = "nats.subject"
+	tagQueue        = "nats.queue"
+	tagStream       = "nats.stream"
+	tagConsumer     = "nats.consumer"
+	tagSequence     = "nats.sequence"
+	tagNumDelivered = "nats.num_delivered"
+	tagDuplicate    = "nats.duplicate"
+	tagAckLatency   = "nats.ack_latency_ms"
+)
+
+// Conn wraps a *nats.Conn, tracing the messages it publishes.
+type Conn struct {
+	*nats.Conn
+	cfg *config
+}
+
+// WrapConn wraps a *nats.Conn so that the messages it publishes are traced.
+func WrapConn(nc *nats.Conn, opts ...Option) *Conn {
+	cfg := newConfig(opts...)
+	log.Debug("contrib/nats-io/nats.go: Wrapping Conn: %#v", cfg)
+	return &Conn{Conn: nc, cfg: cfg}
+}
+
+// Publish publishes data on the given subject, as PublishMsgWithContext does.
+func (c *Conn) Publish(subj string, data []byte) error {
+	return c.PublishMsgWithContext(context.Background(), &nats.Msg{Subject: subj, Data: data})
+}
+
+// PublishMsg publishes msg, as PublishMsgWithContext does.
+func (c *Conn) PublishMsg(msg *nats.Msg) error {
+	return c.PublishMsgWithContext(context.Background(), msg)
+}
+
+// PublishMsgWithContext publishes msg within a span which is a child of the
+// span held by ctx, if any, or else of the span propagated by the headers of
+// msg. The span is propagated to the subscribers in the headers of msg, unless
+// the server does not support headers.
+func (c *Conn) PublishMsgWithContext(ctx context.Context, msg *nats.Msg) error {
+	span := startPublishSpan(ctx, c.cfg, msg, c.HeadersSupported())
+	err := c.Conn.PublishMsg(msg)
+	span.Finish(tracer.WithError(err))
+	return err
+}
+
+// JetStream returns a JetStream context for the connection, tracing the
+// messages it publishes synchronously.
+func (c *Conn) JetStream(opts ...nats.JSOpt) (*JetStream, error) {
+	js, err := c.Conn.JetStream(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &JetStream{JetStreamContext: js, cfg: c.cfg}, nil
+}
+
+// JetStream wraps a nats.JetStreamContext, tracing the messages it publishes
+// synchronously. The messages published asynchronously are not traced.
+type JetStream struct {
+	nats.JetStreamContext
+	cfg *config
+}
+
+// Publish publishes data on the given subject, as PublishMsgWithContext does.
+func (js *JetStream) Publish(subj string, data []byte, opts ...nats.PubOpt) (*nats.PubAck, error) {
+	return js.PublishMsgWithContext(context.Background(), &nats.Msg{Subject: subj, Data: data}, opts...)
+}
+
+// PublishMsg publishes msg, as PublishMsgWithContext does.
+func (js *JetStream) PublishMsg(msg *nats.Msg, opts ...nats.PubOpt) (*nats.PubAck, error) {
+	return js.PublishMsgWithContext(context.Background(), msg, opts...)
+}
+
+// PublishMsgWithContext publishes msg within a span which is a child of the
+// span held by ctx, if any, or else of the span propagated by the headers of
+// msg, and which lasts until the server acknowledges the message. The span is
+// propagated to the subscribers in the headers of msg.
+func (js *JetStream) PublishMsgWithContext(ctx context.Context, msg *nats.Msg, opts ...nats.PubOpt) (*nats.PubAck, error) {
+	span := startPublishSpan(ctx, js.cfg, msg, true)
+	ack, err := js.JetStreamContext.PublishMsg(msg, opts...)
+	if ack != nil {
+		span.SetTag(tagStream, ack.Stream)
+		span.SetTag(tagSequence, ack.Sequence)
+		span.SetTag(tagDuplicate, ack.Duplicate)
+	}
+	span.Finish(tracer.WithError(err))
+	return ack, err
+}
+
+// startPublishSpan starts the span of the publishing of msg and, when inject
+// is true, injects it into the headers of msg.
+func startPublishSpan(ctx context.Context, cfg *config, msg *nats.Msg, inject bool) ddtrace.Span {
+	carrier := NewMsgCarrier(msg)
+	opts := []ddtrace.StartSpanOption{
+		tracer.Tag(ext.Component, componentName),
+		tracer.ServiceName(cfg.publisherServiceName),
+		tracer.ResourceName(msg.Subject),
+		tracer.SpanType(ext.SpanTypeMessageProducer),
+		tracer.Tag(tagSubject, msg.Subject),
+	}
+	if !math.IsNaN(cfg.analyticsRate) {
+		opts = append(opts, tracer.Tag(ext.EventSampleRate, cfg.analyticsRate))
+	}
+	if _, ok := tracer.SpanFromContext(ctx); !ok {
+		if spanctx, err := tracer.Extract(carrier); err == nil {
+			opts = append(opts, tracer.ChildOf(spanctx))
+		}
+	}
+	span, _ := tracer.StartSpanFromContext(ctx, namingschema.MessagingOutboundOp("nats", "nats.publish"), opts...)
+	if inject {
+		if err := tracer.Inject(span.Context(), carrier); err != nil {
+			log.Debug("contrib/nats-io/nats.go: failed injecting tracing headers: %v", err)
+		}
+	}
+	return span
+}
+
+// receivedKey is the key of the context values holding the time at which the
+// messages were handed to the handlers wrapped with WrapMsgHandler.
+type receivedKey struct{}
+
+// received holds the time at which a message was handed to a wrapped handler,
+// and the config of the handler.
+type received struct {
+	at  time.Time
+	cfg *config
+}
+
+// WrapMsgHandler returns a nats.MsgHandler calling h within a span which is a
+// child of the span propagated by the headers of the message, if any. The
+// span is held by the context given to h, which can be used to acknowledge
+// the messages received from JetStream with Ack. The handler can be used with
+// any of the subscription methods of nats.Conn and nats.JetStreamContext.
+func WrapMsgHandler(h func(ctx context.Context, msg *nats.Msg), opts ...Option) nats.MsgHandler {
+	cfg := newConfig(opts...)
+	log.Debug("contrib/nats-io/nats.go: Wrapping MsgHandler: %#v", cfg)
+	return func(msg *nats.Msg) {
+		now := time.Now()
+		opts := []ddtrace.StartSpanOption{
+			tracer.Tag(ext.Component, componentName),
+			tracer.ServiceName(cfg.subscriberServiceName),
+			tracer.ResourceName(msg.Subject),
+			tracer.SpanType(ext.SpanTypeMessageConsumer),
+			tracer.Tag(tagSubject, msg.Subject),
+			tracer.Measured(),
+		}
+		if msg.Sub != nil && msg.Sub.Queue != "" {
+			opts = append(opts, tracer.Tag(tagQueue, msg.Sub.Queue))
+		}
+		if !math.IsNaN(cfg.analyticsRate) {
+			opts = append(opts, tracer.Tag(ext.EventSampleRate, cfg.analyticsRate))
+		}
+		if spanctx, err := tracer.Extract(NewMsgCarrier(msg)); err == nil {
+			opts = append(opts, tracer.ChildOf(spanctx))
+		}
+		span, ctx := tracer.StartSpanFromContext(context.Background(), namingschema.MessagingInboundOp("nats", "nats.receive"), opts...)
+		defer span.Finish()
+		setJetStreamTags(span, msg)
+		h(context.WithValue(ctx, receivedKey{}, received{at: now, cfg: cfg}), msg)
+	}
+}
+
+// Ack acknowledges msg, which was received from JetStream, and waits for the
+// acknowledgement to be confirmed by the server, as msg.AckSync does, within
+// a span which is a child of the span held by ctx. When ctx is the context
+// given to a handler wrapped with WrapMsgHandler, the time elapsed between the
+// handler being called and the acknowledgement being confirmed is set as the
+// nats.ack_latency_ms metric of the span.
+func Ack(ctx context.Context, msg *nats.Msg, opts ...nats.AckOpt) error {
+	r, ok := ctx.Value(receivedKey{}).(received)
+	cfg := r.cfg
+	if !ok {
+		cfg = newConfig()
+	}
+	span, _ := tracer.StartSpanFromContext(ctx, "nats.ack",
+		tracer.Tag(ext.Component, componentName),
+		tracer.ServiceName(cfg.subscriberServiceName),
+		tracer.ResourceName(msg.Subject),
+		tracer.SpanType(ext.SpanTypeMessageConsumer),
+		tracer.Tag(tagSubject, msg.Subject),
+	)
+	setJetStreamTags(span, msg)
+	err := msg.AckSync(opts...)
+	if ok {
+		span.SetTag(tagAckLatency, float64(time.Since(r.at))/float64(time.Millisecond))
+	}
+	span.Finish(tracer.WithError(err))
+	return err
+}
+
+// setJetStreamTags sets the JetStream tags of span when msg was received from
+// JetStream.
+func setJetStreamTags(span ddtrace.Span, msg *nats.Msg) {
+	if msg.Sub == nil || msg.Reply == "" {
+		// not a JetStream message, skip parsing its reply subject
+		return
+	}
+	meta, err := msg.Metadata()
+	if err != nil {
+		return
+	}
+	span.SetTag(tagStream, meta.Stream)
+	span.SetTag(tagConsumer, meta.Consumer)
+	span.SetTag(tagSequence, meta.Sequence.Stream)
+	span.SetTag(tagNumDelivered, meta.NumDelivered)
+}
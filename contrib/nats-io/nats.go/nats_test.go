@@ -0,0 +1,188 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package nats
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/namingschema"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSubject = "gotest"
+
+func TestMsgCarrier(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	span := tracer.StartSpan("test")
+	defer span.Finish()
+
+	msg := &nats.Msg{Subject: testSubject}
+	require.NoError(t, tracer.Inject(span.Context(), NewMsgCarrier(msg)))
+	assert.NotEmpty(t, msg.Header)
+
+	spanctx, err := tracer.Extract(NewMsgCarrier(msg))
+	require.NoError(t, err)
+	assert.Equal(t, span.Context().TraceID(), spanctx.TraceID())
+	assert.Equal(t, span.Context().SpanID(), spanctx.SpanID())
+}
+
+func TestWrapMsgHandler(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	parent := tracer.StartSpan("parent")
+	msg := &nats.Msg{
+		Subject: testSubject,
+		Data:    []byte("hello"),
+		Sub:     &nats.Subscription{Subject: testSubject, Queue: "workers"},
+	}
+	require.NoError(t, tracer.Inject(parent.Context(), NewMsgCarrier(msg)))
+	parent.Finish()
+
+	var called bool
+	WrapMsgHandler(func(ctx context.Context, m *nats.Msg) {
+		called = true
+		assert.Same(t, msg, m)
+		_, ok := tracer.SpanFromContext(ctx)
+		assert.True(t, ok)
+	}, WithServiceName("subscriber"))(msg)
+	assert.True(t, called)
+
+	spans := mt.FinishedSpans()
+	require.Len(t, spans, 2)
+	s := spans[1]
+	assert.Equal(t, "nats.receive", s.OperationName())
+	assert.Equal(t, parent.Context().SpanID(), s.ParentID())
+	assert.Equal(t, parent.Context().TraceID(), s.TraceID())
+	assert.Equal(t, "subscriber", s.Tag(ext.ServiceName))
+	assert.Equal(t, testSubject, s.Tag(ext.ResourceName))
+	assert.Equal(t, ext.SpanTypeMessageConsumer, s.Tag(ext.SpanType))
+	assert.Equal(t, testSubject, s.Tag(tagSubject))
+	assert.Equal(t, "workers", s.Tag(tagQueue))
+	assert.Equal(t, componentName, s.Tag(ext.Component))
+	assert.Nil(t, s.Tag(tagStream))
+
+	t.Run("schema-v1", func(t *testing.T) {
+		namingschema.SetVersion(namingschema.SchemaV1)
+		defer namingschema.SetVersion(namingschema.SchemaV0)
+		mt.Reset()
+
+		WrapMsgHandler(func(context.Context, *nats.Msg) {})(&nats.Msg{Subject: testSubject})
+
+		spans := mt.FinishedSpans()
+		require.Len(t, spans, 1)
+		assert.Equal(t, "nats.process", spans[0].OperationName())
+		assert.Nil(t, spans[0].Tag(tagQueue))
+	})
+}
+
+// spansByName waits for n spans to be finished and returns them by name.
+func spansByName(t *testing.T, mt mocktracer.Tracer, n int) map[string]mocktracer.Span {
+	// the spans of the handlers are finished after they return
+	require.Eventually(t, func() bool { return len(mt.FinishedSpans()) == n }, 5*time.Second, 10*time.Millisecond)
+	spans := make(map[string]mocktracer.Span)
+	for _, s := range mt.FinishedSpans() {
+		spans[s.OperationName()] = s
+	}
+	require.Len(t, spans, n)
+	return spans
+}
+
+func connect(t *testing.T) *nats.Conn {
+	if _, ok := os.LookupEnv("INTEGRATION"); !ok {
+		t.Skip("to enable integration test, set the INTEGRATION environment variable")
+	}
+	nc, err := nats.Connect(nats.DefaultURL)
+	require.NoError(t, err)
+	return nc
+}
+
+func TestPublishSubscribe(t *testing.T) {
+	nc := connect(t)
+	defer nc.Close()
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	received := make(chan struct{})
+	sub, err := nc.QueueSubscribe(testSubject, "workers", WrapMsgHandler(func(context.Context, *nats.Msg) {
+		close(received)
+	}))
+	require.NoError(t, err)
+	defer sub.Unsubscribe()
+
+	parent, ctx := tracer.StartSpanFromContext(context.Background(), "parent")
+	require.NoError(t, WrapConn(nc).PublishMsgWithContext(ctx, &nats.Msg{Subject: testSubject, Data: []byte("hello")}))
+	parent.Finish()
+	select {
+	case <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the message was not received")
+	}
+
+	spans := spansByName(t, mt, 3)
+	pub, recv := spans["nats.publish"], spans["nats.receive"]
+	assert.Equal(t, parent.Context().SpanID(), pub.ParentID())
+	assert.Equal(t, "nats", pub.Tag(ext.ServiceName))
+	assert.Equal(t, testSubject, pub.Tag(tagSubject))
+	assert.Equal(t, ext.SpanTypeMessageProducer, pub.Tag(ext.SpanType))
+
+	assert.Equal(t, pub.SpanID(), recv.ParentID())
+	assert.Equal(t, "workers", recv.Tag(tagQueue))
+}
+
+func TestJetStream(t *testing.T) {
+	nc := connect(t)
+	defer nc.Close()
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	js, err := WrapConn(nc).JetStream()
+	require.NoError(t, err)
+	_, err = js.AddStream(&nats.StreamConfig{Name: "GOTEST", Subjects: []string{testSubject}})
+	if err == nats.ErrJetStreamNotEnabled {
+		t.Skip("JetStream is not enabled on the server")
+	}
+	require.NoError(t, err)
+	defer js.DeleteStream("GOTEST")
+
+	acked := make(chan error, 1)
+	sub, err := js.Subscribe(testSubject, WrapMsgHandler(func(ctx context.Context, msg *nats.Msg) {
+		acked <- Ack(ctx, msg)
+	}), nats.ManualAck())
+	require.NoError(t, err)
+	defer sub.Unsubscribe()
+
+	ack, err := js.Publish(testSubject, []byte("hello"))
+	require.NoError(t, err)
+	select {
+	case err := <-acked:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the message was not received")
+	}
+
+	spans := spansByName(t, mt, 3)
+	pub, recv, ackSpan := spans["nats.publish"], spans["nats.receive"], spans["nats.ack"]
+
+	assert.Equal(t, "GOTEST", pub.Tag(tagStream))
+	assert.Equal(t, ack.Sequence, pub.Tag(tagSequence))
+	assert.Equal(t, pub.SpanID(), recv.ParentID())
+	assert.Equal(t, "GOTEST", recv.Tag(tagStream))
+	assert.Equal(t, uint64(1), recv.Tag(tagNumDelivered))
+	assert.Equal(t, recv.SpanID(), ackSpan.ParentID())
+	assert.NotNil(t, ackSpan.Tag(tagAckLatency))
+}
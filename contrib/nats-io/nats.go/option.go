@@ -0,0 +1,77 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package nats
+
+import (
+	"math"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/namingschema"
+)
+
+type config struct {
+	publisherServiceName  string
+	subscriberServiceName string
+	analyticsRate         float64
+}
+
+func defaults(cfg *config) {
+	cfg.publisherServiceName = namingschema.ServiceName("nats")
+	cfg.subscriberServiceName = "nats"
+	if svc := globalconfig.ServiceName(); svc != "" {
+		cfg.subscriberServiceName = svc
+	}
+	if internal.BoolEnv("DD_TRACE_NATS_ANALYTICS_ENABLED", false) {
+		cfg.analyticsRate = 1.0
+	} else {
+		cfg.analyticsRate = math.NaN()
+	}
+}
+
+func newConfig(opts ...Option) *config {
+	cfg := new(config)
+	defaults(cfg)
+	for _, fn := range opts {
+		fn(cfg)
+	}
+	return cfg
+}
+
+// An Option is used to customize the config for the nats tracer.
+type Option func(cfg *config)
+
+// WithServiceName sets the given service name for the spans of the published
+// and received messages.
+func WithServiceName(name string) Option {
+	return func(cfg *config) {
+		cfg.publisherServiceName = name
+		cfg.subscriberServiceName = name
+	}
+}
+
+// WithAnalytics enables Trace Analytics for all started spans.
+func WithAnalytics(on bool) Option {
+	return func(cfg *config) {
+		if on {
+			cfg.analyticsRate = 1.0
+		} else {
+			cfg.analyticsRate = math.NaN()
+		}
+	}
+}
+
+// WithAnalyticsRate sets the sampling rate for Trace Analytics events
+// correlated to started spans.
+func WithAnalyticsRate(rate float64) Option {
+	return func(cfg *config) {
+		if rate >= 0.0 && rate <= 1.0 {
+			cfg.analyticsRate = rate
+		} else {
+			cfg.analyticsRate = math.NaN()
+		}
+	}
+}
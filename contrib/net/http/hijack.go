@@ -0,0 +1,169 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+)
+
+// defaultWebSocketHeartbeat is the interval at which the metrics of the
+// WebSocket session spans are updated by default.
+const defaultWebSocketHeartbeat = 10 * time.Second
+
+// hijacker wraps the http.Hijacker of a response writer in order to report the
+// hijacking of the connection to the responseWriter.
+type hijacker struct {
+	http.Hijacker
+	rw *responseWriter
+}
+
+// Hijack lets the caller take over the connection, as the wrapped Hijacker
+// does, and reports it to the responseWriter.
+func (h hijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, brw, err := h.Hijacker.Hijack()
+	if err != nil || h.rw.hijacked {
+		return conn, brw, err
+	}
+	h.rw.hijacked = true
+	if h.rw.onHijack == nil {
+		return conn, brw, err
+	}
+	if c := h.rw.onHijack(conn); c != conn {
+		conn = c
+		rewire(brw, conn)
+	}
+	return conn, brw, err
+}
+
+// rewire makes brw read from and write to conn, which wraps the connection brw
+// was reading from and writing to, keeping the data brw already buffered.
+func rewire(brw *bufio.ReadWriter, conn net.Conn) {
+	if n := brw.Reader.Buffered(); n == 0 {
+		brw.Reader.Reset(conn)
+	} else {
+		buffered, _ := brw.Reader.Peek(n)
+		r := io.MultiReader(bytes.NewReader(append([]byte(nil), buffered...)), conn)
+		brw.Reader = bufio.NewReaderSize(r, brw.Reader.Size())
+	}
+	if brw.Writer.Buffered() == 0 {
+		brw.Writer.Reset(conn)
+	}
+}
+
+// upgradeProtocol returns the lowercased protocol the connection of r is
+// upgraded to, or an empty string when r is not an upgrade request.
+func upgradeProtocol(r *http.Request) string {
+	proto := r.Header.Get("Upgrade")
+	if proto == "" {
+		return ""
+	}
+	for _, v := range r.Header["Connection"] {
+		for _, token := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return strings.ToLower(proto)
+			}
+		}
+	}
+	return ""
+}
+
+// webSocketConn wraps the hijacked connection of a WebSocket session in order
+// to trace it with a span covering its lifetime, which is finished when the
+// connection is closed or when the handler returns, whichever comes first.
+type webSocketConn struct {
+	net.Conn
+	span ddtrace.Span
+
+	read, written int64 // accessed atomically; number of bytes read and written
+
+	done chan struct{} // closed when the session is finished
+	once sync.Once
+}
+
+func newWebSocketConn(conn net.Conn, parent ddtrace.Span, cfg *ServeConfig) *webSocketConn {
+	opts := append([]ddtrace.StartSpanOption{
+		tracer.ChildOf(parent.Context()),
+		tracer.Tag(ext.Component, cfg.component()),
+		tracer.SpanType(ext.SpanTypeWeb),
+		tracer.ServiceName(cfg.Service),
+	}, cfg.SpanOpts...)
+	if cfg.Resource != "" {
+		opts = append(opts, tracer.ResourceName(cfg.Resource))
+	}
+	c := &webSocketConn{
+		Conn: conn,
+		span: tracer.StartSpan("websocket.session", opts...),
+		done: make(chan struct{}),
+	}
+	heartbeat := cfg.WebSocketHeartbeat
+	if heartbeat <= 0 {
+		heartbeat = defaultWebSocketHeartbeat
+	}
+	go c.heartbeat(heartbeat)
+	return c
+}
+
+// Read reads data from the connection, counting the bytes read.
+func (c *webSocketConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	atomic.AddInt64(&c.read, int64(n))
+	return n, err
+}
+
+// Write writes data to the connection, counting the bytes written.
+func (c *webSocketConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	atomic.AddInt64(&c.written, int64(n))
+	return n, err
+}
+
+// Close closes the connection and finishes the session span.
+func (c *webSocketConn) Close() error {
+	err := c.Conn.Close()
+	c.finish()
+	return err
+}
+
+// heartbeat updates the metrics of the session span every interval until the
+// session is finished.
+func (c *webSocketConn) heartbeat(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.setMetrics()
+		case <-c.done:
+			return
+		}
+	}
+}
+
+func (c *webSocketConn) setMetrics() {
+	c.span.SetTag("websocket.bytes_read", atomic.LoadInt64(&c.read))
+	c.span.SetTag("websocket.bytes_written", atomic.LoadInt64(&c.written))
+}
+
+// finish finishes the session span, once.
+func (c *webSocketConn) finish() {
+	c.once.Do(func() {
+		close(c.done)
+		c.setMetrics()
+		c.span.Finish()
+	})
+}
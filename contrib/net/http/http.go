@@ -12,6 +12,8 @@ import (
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
 )
 
+const componentName = "http"
+
 // ServeMux is an HTTP request multiplexer that traces all the incoming requests.
 type ServeMux struct {
 	*http.ServeMux
@@ -49,10 +51,14 @@ func (mux *ServeMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		resource = r.Method + " " + route
 	}
 	TraceAndServe(mux.ServeMux, w, r, &ServeConfig{
-		Service:  mux.cfg.serviceName,
-		Resource: resource,
-		SpanOpts: mux.cfg.spanOpts,
-		Route:    route,
+		Service:            mux.cfg.serviceName,
+		Resource:           resource,
+		SpanOpts:           mux.cfg.spanOpts,
+		Route:              route,
+		ContentLengthTags:  mux.cfg.contentLengthTags,
+		ServerTiming:       mux.cfg.serverTiming,
+		WebSocketSpans:     mux.cfg.webSocketSpans,
+		WebSocketHeartbeat: mux.cfg.webSocketHeartbeat,
 	})
 }
 
@@ -74,10 +80,14 @@ func WrapHandler(h http.Handler, service, resource string, opts ...Option) http.
 			resource = r
 		}
 		TraceAndServe(h, w, req, &ServeConfig{
-			Service:    service,
-			Resource:   resource,
-			FinishOpts: cfg.finishOpts,
-			SpanOpts:   cfg.spanOpts,
+			Service:            service,
+			Resource:           resource,
+			FinishOpts:         cfg.finishOpts,
+			SpanOpts:           cfg.spanOpts,
+			ContentLengthTags:  cfg.contentLengthTags,
+			ServerTiming:       cfg.serverTiming,
+			WebSocketSpans:     cfg.webSocketSpans,
+			WebSocketHeartbeat: cfg.webSocketHeartbeat,
 		})
 	})
 }
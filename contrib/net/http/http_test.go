@@ -6,8 +6,10 @@
 package http
 
 import (
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -45,6 +47,20 @@ func TestHttpTracer200(t *testing.T) {
 	assert.Equal("bar", s.Tag("foo"))
 }
 
+func TestClientIP(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	r := httptest.NewRequest("GET", "/200", nil)
+	r.Header.Set("X-Forwarded-For", "8.8.8.8")
+	w := httptest.NewRecorder()
+	router().ServeHTTP(w, r)
+
+	spans := mt.FinishedSpans()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "8.8.8.8", spans[0].Tag(ext.HTTPClientIP))
+}
+
 func TestHttpTracer500(t *testing.T) {
 	mt := mocktracer.Start()
 	defer mt.Stop()
@@ -103,6 +119,58 @@ func TestWrapHandler200(t *testing.T) {
 	assert.Equal("bar", s.Tag("foo"))
 }
 
+func TestWrapHandlerContentLengthTags(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+	assert := assert.New(t)
+
+	handler := WrapHandler(http.HandlerFunc(handler200), "my-service", "my-resource",
+		WithContentLengthTags(),
+	)
+	r := httptest.NewRequest("POST", "/", strings.NewReader("hello"))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	spans := mt.FinishedSpans()
+	assert.Equal(1, len(spans))
+	assert.EqualValues(5, spans[0].Tag(ext.HTTPRequestContentLength))
+	assert.EqualValues(len("OK\n"), spans[0].Tag(ext.HTTPResponseContentLength))
+}
+
+func TestWrapHandlerServerTiming(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+	assert := assert.New(t)
+
+	handler := WrapHandler(http.HandlerFunc(handler200), "my-service", "my-resource",
+		WithServerTiming(),
+		WithSpanOptions(tracer.Tag(ext.SamplingPriority, ext.PriorityUserKeep)),
+	)
+	r := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	spans := mt.FinishedSpans()
+	assert.Equal(1, len(spans))
+	want := fmt.Sprintf(`traceparent;desc="00-%032x-%016x-01"`, spans[0].TraceID(), spans[0].SpanID())
+	assert.Equal(want, w.Header().Get("Server-Timing"))
+}
+
+func TestWrapHandlerDisabled(t *testing.T) {
+	t.Setenv("DD_TRACE_HTTP_ENABLED", "false")
+	mt := mocktracer.Start()
+	defer mt.Stop()
+	assert := assert.New(t)
+
+	handler := WrapHandler(http.HandlerFunc(handler200), "my-service", "my-resource")
+	r := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	assert.Equal(200, w.Code)
+	assert.Len(mt.FinishedSpans(), 0)
+}
+
 func TestNoStack(t *testing.T) {
 	mt := mocktracer.Start()
 	defer mt.Stop()
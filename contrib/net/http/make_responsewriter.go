@@ -58,6 +58,9 @@ func wrapResponseWriter(w http.ResponseWriter) (http.ResponseWriter, *responseWr
 {{- end }}
 
 	mw := newResponseWriter(w)
+	if okHijacker {
+		hHijacker = hijacker{Hijacker: hHijacker, rw: mw}
+	}
 	type monitoredResponseWriter interface {
 		http.ResponseWriter
 		Status() int
@@ -8,6 +8,8 @@ package http
 import (
 	"math"
 	"net/http"
+	"strings"
+	"time"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
@@ -17,12 +19,16 @@ import (
 )
 
 type config struct {
-	serviceName   string
-	analyticsRate float64
-	spanOpts      []ddtrace.StartSpanOption
-	finishOpts    []ddtrace.FinishOption
-	ignoreRequest func(*http.Request) bool
-	resourceNamer func(*http.Request) string
+	serviceName        string
+	analyticsRate      float64
+	spanOpts           []ddtrace.StartSpanOption
+	finishOpts         []ddtrace.FinishOption
+	ignoreRequest      func(*http.Request) bool
+	resourceNamer      func(*http.Request) string
+	contentLengthTags  bool
+	serverTiming       bool
+	webSocketSpans     bool
+	webSocketHeartbeat time.Duration
 }
 
 // MuxOption has been deprecated in favor of Option.
@@ -104,6 +110,36 @@ func WithResourceNamer(namer func(req *http.Request) string) Option {
 	}
 }
 
+// WithContentLengthTags sets the sizes in bytes of the bodies of the requests and
+// of their responses as the http.request.content_length and
+// http.response.content_length tags. The size of the body of a request is only
+// known when its Content-Length header is set.
+func WithContentLengthTags() Option {
+	return func(cfg *config) {
+		cfg.contentLengthTags = true
+	}
+}
+
+// WithServerTiming adds a Server-Timing response header holding the W3C traceparent
+// of the request span, so that browser clients, such as the RUM SDK, can link their
+// traces to the backend ones. See ServeConfig.ServerTiming.
+func WithServerTiming() Option {
+	return func(cfg *config) {
+		cfg.serverTiming = true
+	}
+}
+
+// WithWebSocketSpans traces the WebSocket sessions with a websocket.session span
+// covering their lifetime once their connection is upgraded, and updates its
+// websocket.bytes_read and websocket.bytes_written metrics every heartbeat, or
+// every 10 seconds when heartbeat is not positive. See ServeConfig.WebSocketSpans.
+func WithWebSocketSpans(heartbeat time.Duration) Option {
+	return func(cfg *config) {
+		cfg.webSocketSpans = true
+		cfg.webSocketHeartbeat = heartbeat
+	}
+}
+
 // NoDebugStack prevents stack traces from being attached to spans finishing
 // with an error. This is useful in situations where errors are frequent and
 // performance is critical.
@@ -129,6 +165,7 @@ type roundTripperConfig struct {
 	resourceNamer func(req *http.Request) string
 	ignoreRequest func(*http.Request) bool
 	spanOpts      []ddtrace.StartSpanOption
+	routes        []routeTemplate
 }
 
 func newRoundTripperConfig() *roundTripperConfig {
@@ -167,6 +204,21 @@ func RTWithResourceNamer(namer func(req *http.Request) string) RoundTripperOptio
 	}
 }
 
+// RTWithRouteTemplates sets the route templates, such as "/users/{id}/orders",
+// the paths of the requests are matched against, in the given order. The resource
+// name of a request whose path matches a template is its method followed by the
+// template, and the template is set as its http.route tag, so that the spans of
+// requests to the same endpoint are grouped together. A segment enclosed in braces
+// matches any non-empty path segment. The resource name of the requests matching
+// no template is still given by the resource namer.
+func RTWithRouteTemplates(templates ...string) RoundTripperOption {
+	return func(cfg *roundTripperConfig) {
+		for _, t := range templates {
+			cfg.routes = append(cfg.routes, newRouteTemplate(t))
+		}
+	}
+}
+
 // RTWithSpanOptions defines a set of additional ddtrace.StartSpanOption to be added
 // to spans started by the integration.
 func RTWithSpanOptions(opts ...ddtrace.StartSpanOption) RoundTripperOption {
@@ -216,3 +268,53 @@ func RTWithIgnoreRequest(f func(*http.Request) bool) RoundTripperOption {
 		cfg.ignoreRequest = f
 	}
 }
+
+// routeTemplate is a route template parsed by RTWithRouteTemplates.
+type routeTemplate struct {
+	template string
+	// segments holds the segments of the template, where the empty
+	// string stands for a parameter matching any segment.
+	segments []string
+}
+
+func newRouteTemplate(template string) routeTemplate {
+	segments := splitPath(template)
+	for i, s := range segments {
+		if len(s) > 2 && s[0] == '{' && s[len(s)-1] == '}' {
+			segments[i] = ""
+		}
+	}
+	return routeTemplate{template: template, segments: segments}
+}
+
+// match reports whether the given URL path matches the template.
+func (r routeTemplate) match(path string) bool {
+	segments := splitPath(path)
+	if len(segments) != len(r.segments) {
+		return false
+	}
+	for i, s := range segments {
+		if s == "" || (r.segments[i] != "" && r.segments[i] != s) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchRoute returns the first route template matched by the given URL path.
+func (cfg *roundTripperConfig) matchRoute(path string) (template string, ok bool) {
+	for _, r := range cfg.routes {
+		if r.match(path) {
+			return r.template, true
+		}
+	}
+	return "", false
+}
+
+func splitPath(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
@@ -9,12 +9,16 @@ import (
 	"fmt"
 	"math"
 	"net/http"
-	"os"
 	"strconv"
 
+	"gopkg.in/DataDog/dd-trace-go.v1/contrib/internal/httptrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo/instrumentation/httpsec"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/namingschema"
 )
 
 type roundTripper struct {
@@ -24,14 +28,27 @@ type roundTripper struct {
 
 func (rt *roundTripper) RoundTrip(req *http.Request) (res *http.Response, err error) {
 	if rt.cfg.ignoreRequest(req) {
+		if err := protect(req); err != nil {
+			return nil, err
+		}
 		return rt.base.RoundTrip(req)
 	}
-	resourceName := rt.cfg.resourceNamer(req)
 	opts := []ddtrace.StartSpanOption{
+		tracer.Tag(ext.Component, componentName),
 		tracer.SpanType(ext.SpanTypeHTTP),
-		tracer.ResourceName(resourceName),
 		tracer.Tag(ext.HTTPMethod, req.Method),
-		tracer.Tag(ext.HTTPURL, req.URL.String()),
+		tracer.Tag(ext.HTTPURL, httptrace.URLTag(req.URL)),
+	}
+	if route, ok := rt.cfg.matchRoute(req.URL.Path); ok {
+		opts = append(opts,
+			tracer.ResourceName(req.Method+" "+route),
+			tracer.Tag(ext.HTTPRoute, route),
+		)
+	} else {
+		opts = append(opts, tracer.ResourceName(rt.cfg.resourceNamer(req)))
+	}
+	if n, ok := requestContentLength(req); ok {
+		opts = append(opts, tracer.Tag(ext.HTTPRequestContentLength, n))
 	}
 	if !math.IsNaN(rt.cfg.analyticsRate) {
 		opts = append(opts, tracer.Tag(ext.EventSampleRate, rt.cfg.analyticsRate))
@@ -39,10 +56,13 @@ func (rt *roundTripper) RoundTrip(req *http.Request) (res *http.Response, err er
 	if rt.cfg.serviceName != "" {
 		opts = append(opts, tracer.ServiceName(rt.cfg.serviceName))
 	}
+	if headerTags := httptrace.EnvHeaderTags(); len(headerTags) > 0 {
+		opts = append(opts, httptrace.RequestHeaderTags(req, headerTags))
+	}
 	if len(rt.cfg.spanOpts) > 0 {
 		opts = append(opts, rt.cfg.spanOpts...)
 	}
-	span, ctx := tracer.StartSpanFromContext(req.Context(), "http.request", opts...)
+	span, ctx := tracer.StartSpanFromContext(req.Context(), namingschema.HTTPClientOp("http.request"), opts...)
 	defer func() {
 		if rt.cfg.after != nil {
 			rt.cfg.after(res, span)
@@ -52,12 +72,15 @@ func (rt *roundTripper) RoundTrip(req *http.Request) (res *http.Response, err er
 	if rt.cfg.before != nil {
 		rt.cfg.before(req, span)
 	}
+	if err = protect(req); err != nil {
+		span.SetTag("http.errors", err.Error())
+		return nil, err
+	}
 	r2 := req.Clone(ctx)
 	// inject the span context into the http request copy
-	err = tracer.Inject(span.Context(), tracer.HTTPHeadersCarrier(r2.Header))
-	if err != nil {
+	if err := tracer.Inject(span.Context(), tracer.HTTPHeadersCarrier(r2.Header)); err != nil {
 		// this should never happen
-		fmt.Fprintf(os.Stderr, "contrib/net/http.Roundtrip: failed to inject http headers: %v\n", err)
+		log.Warn("contrib/net/http.Roundtrip: failed to inject http headers: %v", err)
 	}
 	res, err = rt.base.RoundTrip(r2)
 	if err != nil {
@@ -65,6 +88,10 @@ func (rt *roundTripper) RoundTrip(req *http.Request) (res *http.Response, err er
 		span.SetTag(ext.Error, err)
 	} else {
 		span.SetTag(ext.HTTPCode, strconv.Itoa(res.StatusCode))
+		if res.ContentLength >= 0 {
+			span.SetTag(ext.HTTPResponseContentLength, res.ContentLength)
+		}
+		httptrace.SetResponseHeaderTags(span, res.Header, httptrace.EnvHeaderTags())
 		// treat 5XX as errors
 		if res.StatusCode/100 == 5 {
 			span.SetTag("http.errors", res.Status)
@@ -74,6 +101,30 @@ func (rt *roundTripper) RoundTrip(req *http.Request) (res *http.Response, err er
 	return res, err
 }
 
+// protect monitors the URL of req with AppSec, when enabled, in order to detect
+// and block the server-side request forgeries before req gets sent, at its call
+// site (RASP). It returns a non-nil error when the incoming request sending req
+// was blocked, in which case req must not be sent.
+func protect(req *http.Request) error {
+	if !appsec.Enabled() {
+		return nil
+	}
+	return httpsec.ProtectRoundTripOperation(req.Context(), req.URL.String())
+}
+
+// requestContentLength returns the size of the body of the given outgoing request,
+// unless it is unknown.
+func requestContentLength(req *http.Request) (int64, bool) {
+	if req.ContentLength > 0 {
+		return req.ContentLength, true
+	}
+	if req.ContentLength == 0 && (req.Body == nil || req.Body == http.NoBody) {
+		return 0, true
+	}
+	// a zero ContentLength with a non-nil Body is unknown for client requests
+	return 0, false
+}
+
 // Unwrap returns the original http.RoundTripper.
 func (rt *roundTripper) Unwrap() http.RoundTripper {
 	return rt.base
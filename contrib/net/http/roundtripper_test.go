@@ -7,18 +7,23 @@ package http
 
 import (
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/namingschema"
 )
 
 func TestRoundTripper(t *testing.T) {
@@ -161,6 +166,47 @@ func TestRoundTripperNetworkError(t *testing.T) {
 	assert.Equal(t, true, s0.Tag("CalledAfter"))
 }
 
+func TestRoundTripperSchemaV1(t *testing.T) {
+	namingschema.SetVersion(namingschema.SchemaV1)
+	defer namingschema.SetVersion(namingschema.SchemaV0)
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Hello World"))
+	}))
+	defer s.Close()
+
+	client := &http.Client{
+		Transport: WrapRoundTripper(http.DefaultTransport),
+	}
+	client.Get(s.URL + "/hello/world")
+
+	spans := mt.FinishedSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "http.client.request", spans[0].OperationName())
+}
+
+func TestRoundTripperURLObfuscation(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Hello World"))
+	}))
+	defer s.Close()
+
+	client := &http.Client{
+		Transport: WrapRoundTripper(http.DefaultTransport),
+	}
+	u := strings.Replace(s.URL, "http://", "http://user:password@", 1)
+	client.Get(u + "/hello/world?page=2&access_token=secret")
+
+	spans := mt.FinishedSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, s.URL+"/hello/world?page=2&access_<redacted>", spans[0].Tag(ext.HTTPURL))
+}
+
 func TestWrapClient(t *testing.T) {
 	c := WrapClient(http.DefaultClient)
 	assert.Equal(t, c, http.DefaultClient)
@@ -284,6 +330,70 @@ func TestRoundTripperIgnoreRequest(t *testing.T) {
 	assert.Len(t, spans, 1)
 }
 
+func TestRoundTripperAppSec(t *testing.T) {
+	t.Setenv("DD_APPSEC_RULES", "../../../internal/appsec/testdata/blocking.json")
+	appsec.Start()
+	defer appsec.Stop()
+
+	if !appsec.Enabled() {
+		t.Skip("appsec disabled")
+	}
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Hello World"))
+	}))
+	defer backend.Close()
+
+	client := WrapClient(&http.Client{Timeout: time.Second})
+	mux := NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		req, err := http.NewRequestWithContext(r.Context(), "GET", r.URL.Query().Get("url"), nil)
+		require.NoError(t, err)
+		res, err := client.Do(req)
+		if err != nil {
+			// the request was blocked, the blocking response is written by the middleware
+			return
+		}
+		res.Body.Close()
+		w.Write([]byte("OK"))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	for _, tc := range []struct {
+		url     string
+		blocked bool
+	}{
+		{url: backend.URL + "/hello"},
+		{url: "http://169.254.169.254/latest/meta-data/", blocked: true},
+	} {
+		t.Run(tc.url, func(t *testing.T) {
+			mt := mocktracer.Start()
+			defer mt.Stop()
+
+			res, err := srv.Client().Get(srv.URL + "/?url=" + tc.url)
+			require.NoError(t, err)
+			defer res.Body.Close()
+
+			spans := mt.FinishedSpans()
+			require.Len(t, spans, 2)
+			client, web := spans[0], spans[1]
+			assert.Equal(t, ext.SpanTypeHTTP, client.Tag(ext.SpanType))
+			assert.Equal(t, float64(1), web.Tag("_dd.appsec.rasp.rule.eval"))
+			if !tc.blocked {
+				assert.Equal(t, http.StatusOK, res.StatusCode)
+				assert.Nil(t, client.Tag(ext.Error))
+				return
+			}
+			// the outgoing request is not sent
+			assert.Equal(t, http.StatusForbidden, res.StatusCode)
+			assert.NotNil(t, client.Tag(ext.Error))
+			assert.Equal(t, true, web.Tag("appsec.blocked"))
+			assert.Contains(t, web.Tag("_dd.stack"), "net/http.(*Client).Do")
+		})
+	}
+}
+
 func TestServiceName(t *testing.T) {
 	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte("Hello World"))
@@ -358,6 +468,66 @@ func TestResourceNamer(t *testing.T) {
 		assert.Len(t, spans, 1)
 		assert.Equal(t, "GET /hello/world", spans[0].Tag(ext.ResourceName))
 	})
+
+	t.Run("route-templates", func(t *testing.T) {
+		mt := mocktracer.Start()
+		defer mt.Stop()
+		customNamer := func(req *http.Request) string {
+			return "custom"
+		}
+		rt := WrapRoundTripper(http.DefaultTransport,
+			RTWithResourceNamer(customNamer),
+			RTWithRouteTemplates("/users/{id}", "/hello/{name}/", "/hello/world"),
+		)
+		client := &http.Client{
+			Transport: rt,
+		}
+		client.Get(s.URL + "/hello/world")
+		client.Get(s.URL + "/users/42/")
+		client.Get(s.URL + "/users/42/orders")
+		client.Get(s.URL + "/users//")
+		spans := mt.FinishedSpans()
+		assert.Len(t, spans, 4)
+		assert.Equal(t, "GET /hello/{name}/", spans[0].Tag(ext.ResourceName))
+		assert.Equal(t, "/hello/{name}/", spans[0].Tag(ext.HTTPRoute))
+		assert.Equal(t, "GET /users/{id}", spans[1].Tag(ext.ResourceName))
+		assert.Equal(t, "/users/{id}", spans[1].Tag(ext.HTTPRoute))
+		for _, span := range spans[2:] {
+			assert.Equal(t, "custom", span.Tag(ext.ResourceName))
+			assert.Nil(t, span.Tag(ext.HTTPRoute))
+		}
+	})
+}
+
+func TestRoundTripperContentLength(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		if r.URL.Path == "/stream" {
+			w.(http.Flusher).Flush()
+		}
+		w.Write([]byte("Hello World"))
+	}))
+	defer s.Close()
+
+	mt := mocktracer.Start()
+	defer mt.Stop()
+	client := WrapClient(&http.Client{})
+
+	client.Post(s.URL, "text/plain", strings.NewReader("hello"))
+	client.Get(s.URL)
+	req, err := http.NewRequest("POST", s.URL+"/stream", io.MultiReader(strings.NewReader("hello")))
+	assert.NoError(t, err)
+	client.Do(req)
+
+	spans := mt.FinishedSpans()
+	assert.Len(t, spans, 3)
+	assert.Equal(t, int64(5), spans[0].Tag(ext.HTTPRequestContentLength))
+	assert.Equal(t, int64(11), spans[0].Tag(ext.HTTPResponseContentLength))
+	assert.Equal(t, int64(0), spans[1].Tag(ext.HTTPRequestContentLength))
+	assert.Equal(t, int64(11), spans[1].Tag(ext.HTTPResponseContentLength))
+	// the sizes of streamed bodies are unknown
+	assert.Nil(t, spans[2].Tag(ext.HTTPRequestContentLength))
+	assert.Nil(t, spans[2].Tag(ext.HTTPResponseContentLength))
 }
 
 func TestSpanOptions(t *testing.T) {
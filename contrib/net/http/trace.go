@@ -8,7 +8,9 @@ package http // import "gopkg.in/DataDog/dd-trace-go.v1/contrib/net/http"
 //go:generate sh -c "go run make_responsewriter.go | gofmt > trace_gen.go"
 
 import (
+	"net"
 	"net/http"
+	"time"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/contrib/internal/httptrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
@@ -31,8 +33,27 @@ type ServeConfig struct {
 	Route string
 	// RouteParams specifies framework-specific route parameters (e.g. for route /user/:id coming
 	// in as /user/123 we'll have {"id": "123"}). This field is optional and is used for monitoring
-	// by AppSec. It is only taken into account when AppSec is enabled.
+	// by AppSec, when enabled, and set as span tags when RouteParamsTags is true.
 	RouteParams map[string]string
+	// RouteParamsTags should be true in order to set the RouteParams as http.route.params.<name> tags.
+	RouteParamsTags bool
+	// ContentLengthTags should be true in order to set the sizes of the bodies of the request and of
+	// the response as the http.request.content_length and http.response.content_length tags.
+	ContentLengthTags bool
+	// ServerTiming should be true in order to add a Server-Timing response header holding the W3C
+	// traceparent of the request span, so that browser clients, such as the RUM SDK, can link their
+	// traces to it. Its sampled flag follows the sampling decision of the trace.
+	ServerTiming bool
+	// WebSocketSpans should be true in order to trace the WebSocket sessions, once their connection
+	// is upgraded, with a websocket.session span covering their lifetime. Its websocket.bytes_read
+	// and websocket.bytes_written metrics are updated every WebSocketHeartbeat, or every 10 seconds
+	// when it is not positive, so that they are reported by the snapshots of the session span when
+	// the tracer is started with tracer.WithLongRunningSpans.
+	WebSocketSpans     bool
+	WebSocketHeartbeat time.Duration
+	// Component specifies the name of the integration tracing the request, set as the component
+	// tag of the request span, see ext.Component. It is "http" when left blank.
+	Component string
 	// FinishOpts specifies any options to be used when finishing the request span.
 	FinishOpts []ddtrace.FinishOption
 	// SpanOpts specifies any options to be applied to the request starting span.
@@ -40,17 +61,59 @@ type ServeConfig struct {
 }
 
 // TraceAndServe serves the handler h using the given ResponseWriter and Request, applying tracing
-// according to the specified config.
+// according to the specified config. When the connection of the request is hijacked by h, such as
+// when it is upgraded to the WebSocket protocol, the request span is finished right away rather
+// than when h returns, since the connection is no longer handled by the HTTP server.
 func TraceAndServe(h http.Handler, w http.ResponseWriter, r *http.Request, cfg *ServeConfig) {
 	if cfg == nil {
 		cfg = new(ServeConfig)
 	}
-	opts := append(cfg.SpanOpts, tracer.ServiceName(cfg.Service), tracer.ResourceName(cfg.Resource))
+	opts := append(cfg.SpanOpts, tracer.Tag(ext.Component, cfg.component()), tracer.ServiceName(cfg.Service), tracer.ResourceName(cfg.Resource))
 	opts = append(opts, tracer.Tag(ext.HTTPRoute, cfg.Route))
+	if cfg.ContentLengthTags {
+		opts = append(opts, httptrace.RequestContentLengthTag(r))
+	}
+	if cfg.RouteParamsTags {
+		opts = append(opts, httptrace.RouteParamsTags(cfg.RouteParams))
+	}
 	span, ctx := httptrace.StartRequestSpan(r, opts...)
+	if cfg.ServerTiming {
+		httptrace.SetServerTimingHeader(w.Header(), span.Context())
+	}
 	rw, ddrw := wrapResponseWriter(w)
+	finish := func(status int) {
+		if cfg.ContentLengthTags {
+			span.SetTag(ext.HTTPResponseContentLength, ddrw.written)
+		}
+		httptrace.SetResponseHeaderTags(span, w.Header(), httptrace.EnvHeaderTags())
+		httptrace.FinishRequestSpan(span, status, cfg.FinishOpts...)
+	}
+	var wsConn *webSocketConn
+	ddrw.onHijack = func(conn net.Conn) net.Conn {
+		status := ddrw.status
+		proto := upgradeProtocol(r)
+		if proto != "" {
+			span.SetTag(ext.HTTPUpgraded, proto)
+			if status == 0 {
+				// the response is written to the hijacked connection
+				status = http.StatusSwitchingProtocols
+			}
+		}
+		finish(status)
+		if cfg.WebSocketSpans && proto == "websocket" {
+			wsConn = newWebSocketConn(conn, span, cfg)
+			return wsConn
+		}
+		return conn
+	}
 	defer func() {
-		httptrace.FinishRequestSpan(span, ddrw.status, cfg.FinishOpts...)
+		if ddrw.hijacked {
+			if wsConn != nil {
+				wsConn.finish()
+			}
+			return
+		}
+		finish(ddrw.status)
 	}()
 
 	if appsec.Enabled() {
@@ -59,15 +122,29 @@ func TraceAndServe(h http.Handler, w http.ResponseWriter, r *http.Request, cfg *
 	h.ServeHTTP(rw, r.WithContext(ctx))
 }
 
+// component returns the name of the integration tracing the requests served with cfg.
+func (cfg *ServeConfig) component() string {
+	if cfg.Component != "" {
+		return cfg.Component
+	}
+	return componentName
+}
+
 // responseWriter is a small wrapper around an http response writer that will
 // intercept and store the status of a request.
 type responseWriter struct {
 	http.ResponseWriter
-	status int
+	status  int
+	written int64 // the number of bytes of the body written so far
+
+	// hijacked is set once the connection was hijacked, in which case the
+	// connection returned by onHijack, if set, is returned to the handler.
+	hijacked bool
+	onHijack func(net.Conn) net.Conn
 }
 
 func newResponseWriter(w http.ResponseWriter) *responseWriter {
-	return &responseWriter{w, 0}
+	return &responseWriter{ResponseWriter: w}
 }
 
 // Status returns the status code that was monitored.
@@ -82,7 +159,9 @@ func (w *responseWriter) Write(b []byte) (int, error) {
 	if w.status == 0 {
 		w.WriteHeader(http.StatusOK)
 	}
-	return w.ResponseWriter.Write(b)
+	n, err := w.ResponseWriter.Write(b)
+	w.written += int64(n)
+	return n, err
 }
 
 // WriteHeader sends an HTTP response header with status code.
@@ -24,6 +24,9 @@ func wrapResponseWriter(w http.ResponseWriter) (http.ResponseWriter, *responseWr
 	hHijacker, okHijacker := w.(http.Hijacker)
 
 	mw := newResponseWriter(w)
+	if okHijacker {
+		hHijacker = hijacker{Hijacker: hHijacker, rw: mw}
+	}
 	type monitoredResponseWriter interface {
 		http.ResponseWriter
 		Status() int
@@ -6,10 +6,13 @@
 package http
 
 import (
+	"bufio"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -328,6 +331,71 @@ func TestTraceAndServeHost(t *testing.T) {
 	})
 }
 
+func TestTraceAndServeContentLengthAndRouteParams(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello "))
+		w.Write([]byte("world"))
+	})
+	newRequest := func() *http.Request {
+		r := httptest.NewRequest("POST", "/user/123", strings.NewReader("body"))
+		return r
+	}
+	params := map[string]string{"id": "123"}
+
+	t.Run("on", func(t *testing.T) {
+		mt := mocktracer.Start()
+		assert := assert.New(t)
+		defer mt.Stop()
+
+		TraceAndServe(handler, httptest.NewRecorder(), newRequest(), &ServeConfig{
+			Service:           "service",
+			Route:             "/user/:id",
+			RouteParams:       params,
+			RouteParamsTags:   true,
+			ContentLengthTags: true,
+		})
+		span := mt.FinishedSpans()[0]
+
+		assert.EqualValues(4, span.Tag(ext.HTTPRequestContentLength))
+		assert.EqualValues(11, span.Tag(ext.HTTPResponseContentLength))
+		assert.Equal("123", span.Tag(ext.HTTPRouteParams+".id"))
+	})
+
+	t.Run("off", func(t *testing.T) {
+		mt := mocktracer.Start()
+		assert := assert.New(t)
+		defer mt.Stop()
+
+		TraceAndServe(handler, httptest.NewRecorder(), newRequest(), &ServeConfig{
+			Service:     "service",
+			Route:       "/user/:id",
+			RouteParams: params,
+		})
+		span := mt.FinishedSpans()[0]
+
+		assert.Nil(span.Tag(ext.HTTPRequestContentLength))
+		assert.Nil(span.Tag(ext.HTTPResponseContentLength))
+		assert.Nil(span.Tag(ext.HTTPRouteParams + ".id"))
+	})
+
+	t.Run("unknown-request-length", func(t *testing.T) {
+		mt := mocktracer.Start()
+		assert := assert.New(t)
+		defer mt.Stop()
+
+		r := newRequest()
+		r.ContentLength = -1
+		TraceAndServe(handler, httptest.NewRecorder(), r, &ServeConfig{
+			Service:           "service",
+			ContentLengthTags: true,
+		})
+		span := mt.FinishedSpans()[0]
+
+		assert.Nil(span.Tag(ext.HTTPRequestContentLength))
+		assert.EqualValues(11, span.Tag(ext.HTTPResponseContentLength))
+	})
+}
+
 type noopHandler struct{}
 
 func (noopHandler) ServeHTTP(_ http.ResponseWriter, _ *http.Request) {}
@@ -355,3 +423,123 @@ func BenchmarkTraceAndServe(b *testing.B) {
 		TraceAndServe(handler, noopWriter{}, req, &cfg)
 	}
 }
+
+func TestTraceAndServeHijack(t *testing.T) {
+	// echo hijacks the connection of the request, writing the switching protocols
+	// response itself for upgrade requests, and echoes what it reads until the
+	// client closes the connection.
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, brw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		if r.Header.Get("Upgrade") != "" {
+			brw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+		} else {
+			brw.WriteString("HTTP/1.1 200 OK\r\n\r\n")
+		}
+		brw.Flush()
+		b := make([]byte, 4)
+		for {
+			n, err := brw.Read(b)
+			if err != nil {
+				return
+			}
+			conn.Write(b[:n])
+		}
+	})
+
+	dial := func(t *testing.T, srv *httptest.Server, upgrade bool) {
+		conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		req := "GET /ws HTTP/1.1\r\nHost: localhost\r\n"
+		if upgrade {
+			req += "Connection: keep-alive, Upgrade\r\nUpgrade: WebSocket\r\n"
+		}
+		_, err = conn.Write([]byte(req + "\r\n"))
+		assert.NoError(t, err)
+		br := bufio.NewReader(conn)
+		res, err := http.ReadResponse(br, nil)
+		assert.NoError(t, err)
+		_, err = conn.Write([]byte("ping"))
+		assert.NoError(t, err)
+		b := make([]byte, 4)
+		_, err = io.ReadFull(br, b)
+		assert.NoError(t, err)
+		assert.Equal(t, "ping", string(b))
+		if upgrade {
+			assert.Equal(t, http.StatusSwitchingProtocols, res.StatusCode)
+		}
+	}
+
+	t.Run("upgrade", func(t *testing.T) {
+		mt := mocktracer.Start()
+		defer mt.Stop()
+		done := make(chan struct{})
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer close(done)
+			TraceAndServe(echo, w, r, &ServeConfig{Service: "service", Resource: "ws", WebSocketSpans: true})
+		}))
+		defer srv.Close()
+
+		dial(t, srv, true)
+		<-done
+
+		spans := mt.FinishedSpans()
+		assert.Len(t, spans, 2)
+		req, sess := spans[0], spans[1]
+		assert.Equal(t, "http.request", req.OperationName())
+		assert.Equal(t, "101", req.Tag(ext.HTTPCode))
+		assert.Equal(t, "websocket", req.Tag(ext.HTTPUpgraded))
+		// the request span is finished when the connection is upgraded
+		assert.True(t, req.FinishTime().Before(sess.FinishTime()))
+
+		assert.Equal(t, "websocket.session", sess.OperationName())
+		assert.Equal(t, req.SpanID(), sess.ParentID())
+		assert.Equal(t, "service", sess.Tag(ext.ServiceName))
+		assert.Equal(t, "ws", sess.Tag(ext.ResourceName))
+		// the switching protocols response is written through the session connection
+		assert.Greater(t, sess.Tag("websocket.bytes_written"), int64(4))
+		assert.Equal(t, int64(4), sess.Tag("websocket.bytes_read"))
+	})
+
+	t.Run("hijack", func(t *testing.T) {
+		mt := mocktracer.Start()
+		defer mt.Stop()
+		done := make(chan struct{})
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer close(done)
+			TraceAndServe(echo, w, r, &ServeConfig{WebSocketSpans: true})
+		}))
+		defer srv.Close()
+
+		dial(t, srv, false)
+		<-done
+
+		spans := mt.FinishedSpans()
+		assert.Len(t, spans, 1)
+		assert.Equal(t, "200", spans[0].Tag(ext.HTTPCode))
+		assert.Nil(t, spans[0].Tag(ext.HTTPUpgraded))
+	})
+}
+
+func TestUpgradeProtocol(t *testing.T) {
+	for _, tc := range []struct {
+		connection, upgrade, want string
+	}{
+		{connection: "Upgrade", upgrade: "websocket", want: "websocket"},
+		{connection: "keep-alive, upgrade", upgrade: "h2c", want: "h2c"},
+		{connection: "keep-alive", upgrade: "websocket"},
+		{connection: "Upgrade"},
+	} {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Connection", tc.connection)
+		r.Header.Set("Upgrade", tc.upgrade)
+		assert.Equal(t, tc.want, upgradeProtocol(r), tc)
+	}
+}
@@ -24,6 +24,8 @@ import (
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
 )
 
+const componentName = "elastic"
+
 // NewHTTPClient returns a new http.Client which traces requests under the given service name.
 func NewHTTPClient(opts ...ClientOption) *http.Client {
 	cfg := new(clientConfig)
@@ -49,6 +51,7 @@ func (t *httpTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 	method := req.Method
 	resource := t.config.resourceNamer(url, method)
 	opts := []ddtrace.StartSpanOption{
+		tracer.Tag(ext.Component, componentName),
 		tracer.ServiceName(t.config.serviceName),
 		tracer.SpanType(ext.SpanTypeElasticSearch),
 		tracer.ResourceName(resource),
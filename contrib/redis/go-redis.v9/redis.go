@@ -0,0 +1,181 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+// Package redis provides tracing functions for tracing the redis/go-redis package (https://github.com/redis/go-redis).
+// This package supports go-redis v9.
+package redis
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const componentName = "redis"
+
+type datadogHook struct {
+	*params
+}
+
+// params holds the tracer and a set of parameters which are recorded with every trace.
+type params struct {
+	config         *clientConfig
+	additionalTags []ddtrace.StartSpanOption
+}
+
+// NewClient returns a new Client that is traced with the default tracer under
+// the service name "redis".
+func NewClient(opt *redis.Options, opts ...ClientOption) redis.UniversalClient {
+	client := redis.NewClient(opt)
+	WrapClient(client, opts...)
+	return client
+}
+
+// WrapClient adds a hook to the given client that traces with the default tracer under
+// the service name "redis".
+func WrapClient(client redis.UniversalClient, opts ...ClientOption) {
+	cfg := new(clientConfig)
+	defaults(cfg)
+	for _, fn := range opts {
+		fn(cfg)
+	}
+
+	hookParams := &params{
+		additionalTags: additionalTagOptions(client),
+		config:         cfg,
+	}
+
+	client.AddHook(&datadogHook{params: hookParams})
+}
+
+type clientOptions interface {
+	Options() *redis.Options
+}
+
+type clusterOptions interface {
+	Options() *redis.ClusterOptions
+}
+
+func additionalTagOptions(client redis.UniversalClient) []ddtrace.StartSpanOption {
+	additionalTags := []ddtrace.StartSpanOption{}
+	if clientOptions, ok := client.(clientOptions); ok {
+		opt := clientOptions.Options()
+		if opt.Addr == "FailoverClient" {
+			additionalTags = []ddtrace.StartSpanOption{
+				tracer.Tag("out.db", strconv.Itoa(opt.DB)),
+			}
+		} else {
+			host, port, err := net.SplitHostPort(opt.Addr)
+			if err != nil {
+				host = opt.Addr
+				port = "6379"
+			}
+			additionalTags = []ddtrace.StartSpanOption{
+				tracer.Tag(ext.TargetHost, host),
+				tracer.Tag(ext.TargetPort, port),
+				tracer.Tag("out.db", strconv.Itoa(opt.DB)),
+			}
+		}
+	} else if clientOptions, ok := client.(clusterOptions); ok {
+		additionalTags = []ddtrace.StartSpanOption{
+			tracer.Tag("addrs", strings.Join(clientOptions.Options().Addrs, ", ")),
+		}
+	}
+	return additionalTags
+}
+
+// DialHook implements redis.Hook. Connections are not traced.
+func (ddh *datadogHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+// ProcessHook implements redis.Hook, tracing every command with a "redis.command" span.
+func (ddh *datadogHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		p := ddh.params
+		opts := make([]ddtrace.StartSpanOption, 0, 5+1+len(ddh.additionalTags)+1) // 5 options below + redis.raw_command + ddh.additionalTags + analyticsRate
+		opts = append(opts,
+			tracer.Tag(ext.Component, componentName),
+			tracer.SpanType(ext.SpanTypeRedis),
+			tracer.ServiceName(p.config.serviceName),
+			tracer.ResourceName(cmd.Name()),
+			tracer.Tag("redis.args_length", strconv.Itoa(len(cmd.Args()))),
+		)
+		if !p.config.skipRaw {
+			opts = append(opts, tracer.Tag("redis.raw_command", cmd.String()))
+		}
+		opts = append(opts, ddh.additionalTags...)
+		if !math.IsNaN(p.config.analyticsRate) {
+			opts = append(opts, tracer.Tag(ext.EventSampleRate, p.config.analyticsRate))
+		}
+		span, ctx := tracer.StartSpanFromContext(ctx, "redis.command", opts...)
+		err := next(ctx, cmd)
+		var finishOpts []ddtrace.FinishOption
+		if err != nil && err != redis.Nil {
+			finishOpts = append(finishOpts, tracer.WithError(err))
+		}
+		span.Finish(finishOpts...)
+		return err
+	}
+}
+
+// ProcessPipelineHook implements redis.Hook, tracing every pipeline with a single
+// "redis.command" span named after its first command.
+func (ddh *datadogHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		p := ddh.params
+		var resource string
+		var length int
+		for _, cmd := range cmds {
+			if resource == "" {
+				resource = cmd.Name()
+			}
+			length += len(cmd.Args())
+		}
+		opts := make([]ddtrace.StartSpanOption, 0, 6+1+len(ddh.additionalTags)+1) // 6 options below + redis.raw_command + ddh.additionalTags + analyticsRate
+		opts = append(opts,
+			tracer.Tag(ext.Component, componentName),
+			tracer.SpanType(ext.SpanTypeRedis),
+			tracer.ServiceName(p.config.serviceName),
+			tracer.ResourceName(resource),
+			tracer.Tag("redis.args_length", strconv.Itoa(length)),
+			tracer.Tag("redis.pipeline_length", strconv.Itoa(len(cmds))),
+		)
+		if !p.config.skipRaw {
+			opts = append(opts, tracer.Tag("redis.raw_command", commandsToString(cmds)))
+		}
+		opts = append(opts, ddh.additionalTags...)
+		if !math.IsNaN(p.config.analyticsRate) {
+			opts = append(opts, tracer.Tag(ext.EventSampleRate, p.config.analyticsRate))
+		}
+		span, ctx := tracer.StartSpanFromContext(ctx, "redis.command", opts...)
+		err := next(ctx, cmds)
+		var finishOpts []ddtrace.FinishOption
+		if err != nil && err != redis.Nil {
+			finishOpts = append(finishOpts, tracer.WithError(err))
+		}
+		span.Finish(finishOpts...)
+		return err
+	}
+}
+
+// commandsToString returns a string representation of a slice of redis Commands, separated by newlines.
+func commandsToString(cmds []redis.Cmder) string {
+	var b bytes.Buffer
+	for _, cmd := range cmds {
+		b.WriteString(cmd.String())
+		b.WriteString("\n")
+	}
+	return b.String()
+}
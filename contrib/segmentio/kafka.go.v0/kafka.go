@@ -8,15 +8,20 @@ package kafka
 import (
 	"context"
 	"math"
+	"time"
 
 	"github.com/segmentio/kafka-go"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/datastreams"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/namingschema"
 )
 
+const componentName = "kafka"
+
 // NewReader calls kafka.NewReader and wraps the resulting Consumer.
 func NewReader(conf kafka.ReaderConfig, opts ...Option) *Reader {
 	return WrapReader(kafka.NewReader(conf), opts...)
@@ -33,6 +38,9 @@ func WrapReader(c *kafka.Reader, opts ...Option) *Reader {
 		Reader: c,
 		cfg:    newConfig(opts...),
 	}
+	if wrapped.cfg.groupID == "" {
+		wrapped.cfg.groupID = c.Config().GroupID
+	}
 	log.Debug("contrib/segmentio/kafka-go.v0/kafka: Wrapping Reader: %#v", wrapped.cfg)
 	return wrapped
 }
@@ -46,6 +54,7 @@ type Reader struct {
 
 func (r *Reader) startSpan(ctx context.Context, msg *kafka.Message) ddtrace.Span {
 	opts := []tracer.StartSpanOption{
+		tracer.Tag(ext.Component, componentName),
 		tracer.ServiceName(r.cfg.consumerServiceName),
 		tracer.ResourceName("Consume Topic " + msg.Topic),
 		tracer.SpanType(ext.SpanTypeMessageConsumer),
@@ -56,19 +65,42 @@ func (r *Reader) startSpan(ctx context.Context, msg *kafka.Message) ddtrace.Span
 	if !math.IsNaN(r.cfg.analyticsRate) {
 		opts = append(opts, tracer.Tag(ext.EventSampleRate, r.cfg.analyticsRate))
 	}
+	// the span starts when the message was produced, so that its duration
+	// includes the time the message spent in the queue
+	if !msg.Time.IsZero() && msg.Time.Before(time.Now()) {
+		opts = append(opts, tracer.StartTime(msg.Time))
+	}
 	// kafka supports headers, so try to extract a span context
 	carrier := messageCarrier{msg}
 	if spanctx, err := tracer.Extract(carrier); err == nil {
 		opts = append(opts, tracer.ChildOf(spanctx))
 	}
-	span, _ := tracer.StartSpanFromContext(ctx, "kafka.consume", opts...)
+	span, _ := tracer.StartSpanFromContext(ctx, namingschema.MessagingInboundOp("kafka", "kafka.consume"), opts...)
 	// reinject the span context so consumers can pick it up
 	if err := tracer.Inject(span.Context(), carrier); err != nil {
 		log.Debug("contrib/segmentio/kafka.go.v0: Failed to inject span context into carrier, %v", err)
 	}
+	if r.cfg.dataStreamsEnabled {
+		setConsumeCheckpoint(r.cfg.groupID, msg)
+	}
 	return span
 }
 
+// setConsumeCheckpoint sets a Data Streams Monitoring checkpoint on the pathway
+// propagated by msg, and reinjects the updated pathway so consumers can pick it up.
+func setConsumeCheckpoint(groupID string, msg *kafka.Message) {
+	edges := []string{"direction:in", "topic:" + msg.Topic, "type:kafka"}
+	if groupID != "" {
+		edges = append(edges, "group:"+groupID)
+	}
+	carrier := messageCarrier{msg}
+	ctx, ok := tracer.SetDataStreamsCheckpoint(datastreams.ExtractFromCarrier(context.Background(), carrier), edges...)
+	if !ok {
+		return
+	}
+	datastreams.InjectToCarrier(ctx, carrier)
+}
+
 // Close calls the underlying Reader.Close and if polling is enabled, finishes
 // any remaining span.
 func (r *Reader) Close() error {
@@ -125,25 +157,43 @@ type Writer struct {
 }
 
 func (w *Writer) startSpan(ctx context.Context, msg *kafka.Message) ddtrace.Span {
+	topic := w.Writer.Topic
+	if topic == "" {
+		topic = msg.Topic
+	}
 	opts := []tracer.StartSpanOption{
+		tracer.Tag(ext.Component, componentName),
 		tracer.ServiceName(w.cfg.producerServiceName),
+		tracer.ResourceName("Produce Topic " + topic),
 		tracer.SpanType(ext.SpanTypeMessageProducer),
 	}
-	if w.Writer.Topic != "" {
-		opts = append(opts, tracer.ResourceName("Produce Topic "+w.Writer.Topic))
-	} else {
-		opts = append(opts, tracer.ResourceName("Produce Topic "+msg.Topic))
-	}
 	if !math.IsNaN(w.cfg.analyticsRate) {
 		opts = append(opts, tracer.Tag(ext.EventSampleRate, w.cfg.analyticsRate))
 	}
 	carrier := messageCarrier{msg}
-	span, _ := tracer.StartSpanFromContext(ctx, "kafka.produce", opts...)
-	err := tracer.Inject(span.Context(), carrier)
-	log.Debug("contrib/segmentio/kafka.go.v0: Failed to inject span context into carrier, %v", err)
+	span, _ := tracer.StartSpanFromContext(ctx, namingschema.MessagingOutboundOp("kafka", "kafka.produce"), opts...)
+	if err := tracer.Inject(span.Context(), carrier); err != nil {
+		log.Debug("contrib/segmentio/kafka.go.v0: Failed to inject span context into carrier, %v", err)
+	}
+	if w.cfg.dataStreamsEnabled {
+		setProduceCheckpoint(topic, msg)
+	}
 	return span
 }
 
+// setProduceCheckpoint sets a Data Streams Monitoring checkpoint on the pathway
+// propagated by msg, produced to topic, if any, or on a new one, and injects the
+// updated pathway so consumers can pick it up.
+func setProduceCheckpoint(topic string, msg *kafka.Message) {
+	edges := []string{"direction:out", "topic:" + topic, "type:kafka"}
+	carrier := messageCarrier{msg}
+	ctx, ok := tracer.SetDataStreamsCheckpoint(datastreams.ExtractFromCarrier(context.Background(), carrier), edges...)
+	if !ok {
+		return
+	}
+	datastreams.InjectToCarrier(ctx, carrier)
+}
+
 func finishSpan(span ddtrace.Span, partition int, offset int64, err error) {
 	span.SetTag("partition", partition)
 	span.SetTag("offset", offset)
@@ -7,12 +7,17 @@ package kafka
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/datastreams"
 
 	kafka "github.com/segmentio/kafka-go"
 	"github.com/stretchr/testify/assert"
@@ -153,3 +158,48 @@ func TestFetchMessageFunctional(t *testing.T) {
 	assert.Equal(t, "queue", s1.Tag(ext.SpanType))
 	assert.Equal(t, 0, s1.Tag("partition"))
 }
+
+func TestConsumeQueueTime(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	r := &Reader{cfg: newConfig()}
+	produced := time.Now().Add(-time.Second)
+	msg := kafka.Message{Topic: testTopic, Time: produced}
+	r.startSpan(context.Background(), &msg).Finish()
+
+	// messages with no timestamp, or one in the future, start the span upon consumption
+	before := time.Now()
+	msg = kafka.Message{Topic: testTopic, Time: time.Now().Add(time.Hour)}
+	r.startSpan(context.Background(), &msg).Finish()
+
+	spans := mt.FinishedSpans()
+	assert.Len(t, spans, 2)
+	assert.True(t, spans[0].StartTime().Equal(produced))
+	assert.False(t, spans[1].StartTime().Before(before))
+}
+
+func TestDataStreamsCheckpoints(t *testing.T) {
+	os.Setenv("DD_DATA_STREAMS_ENABLED", "true")
+	defer os.Unsetenv("DD_DATA_STREAMS_ENABLED")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/info" {
+			w.Write([]byte(`{"endpoints":["/v0.1/pipeline_stats"]}`))
+		}
+	}))
+	defer srv.Close()
+	tracer.Start(tracer.WithAgentAddr(strings.TrimPrefix(srv.URL, "http://")), tracer.WithLogStartup(false))
+	defer tracer.Stop()
+
+	msg := &kafka.Message{Topic: testTopic}
+	setProduceCheckpoint(testTopic, msg)
+	produced, ok := datastreams.PathwayFromContext(datastreams.ExtractFromCarrier(context.Background(), messageCarrier{msg}))
+	assert.True(t, ok)
+
+	setConsumeCheckpoint(testGroupID, msg)
+	consumed, ok := datastreams.PathwayFromContext(datastreams.ExtractFromCarrier(context.Background(), messageCarrier{msg}))
+	assert.True(t, ok)
+	assert.NotEqual(t, produced.GetHash(), consumed.GetHash())
+	assert.Equal(t, produced.PathwayStart(), consumed.PathwayStart())
+	assert.Len(t, msg.Headers, 1)
+}
@@ -10,12 +10,15 @@ import (
 
 	"gopkg.in/DataDog/dd-trace-go.v1/internal"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/namingschema"
 )
 
 type config struct {
 	consumerServiceName string
 	producerServiceName string
 	analyticsRate       float64
+	dataStreamsEnabled  bool
+	groupID             string
 }
 
 // An Option customizes the config.
@@ -24,13 +27,14 @@ type Option func(cfg *config)
 func newConfig(opts ...Option) *config {
 	cfg := &config{
 		consumerServiceName: "kafka",
-		producerServiceName: "kafka",
+		producerServiceName: namingschema.ServiceName("kafka"),
 		// analyticsRate: globalconfig.AnalyticsRate(),
 		analyticsRate: math.NaN(),
 	}
 	if internal.BoolEnv("DD_TRACE_KAFKA_ANALYTICS_ENABLED", false) {
 		cfg.analyticsRate = 1.0
 	}
+	cfg.dataStreamsEnabled = internal.BoolEnv("DD_DATA_STREAMS_ENABLED", false)
 	if svc := globalconfig.ServiceName(); svc != "" {
 		cfg.consumerServiceName = svc
 	}
@@ -70,3 +74,13 @@ func WithAnalyticsRate(rate float64) Option {
 		}
 	}
 }
+
+// WithDataStreams enables the Data Streams Monitoring product features: a
+// checkpoint is set on the pathway of every produced and consumed message. It
+// is enabled by default when DD_DATA_STREAMS_ENABLED is set to true. The
+// consumed messages are attributed to the group ID of the Reader.
+func WithDataStreams() Option {
+	return func(cfg *config) {
+		cfg.dataStreamsEnabled = true
+	}
+}
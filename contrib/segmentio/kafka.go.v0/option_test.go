@@ -44,3 +44,21 @@ func TestAnalyticsSettings(t *testing.T) {
 		assert.Equal(t, 0.2, cfg.analyticsRate)
 	})
 }
+
+func TestDataStreamsSettings(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		cfg := newConfig()
+		assert.False(t, cfg.dataStreamsEnabled)
+	})
+
+	t.Run("option", func(t *testing.T) {
+		cfg := newConfig(WithDataStreams())
+		assert.True(t, cfg.dataStreamsEnabled)
+	})
+
+	t.Run("env", func(t *testing.T) {
+		t.Setenv("DD_DATA_STREAMS_ENABLED", "true")
+		cfg := newConfig()
+		assert.True(t, cfg.dataStreamsEnabled)
+	})
+}
@@ -8,6 +8,7 @@ package logrus
 
 import (
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
 
 	"github.com/sirupsen/logrus"
 )
@@ -20,7 +21,8 @@ func (d *DDContextLogHook) Levels() []logrus.Level {
 	return []logrus.Level{logrus.PanicLevel, logrus.FatalLevel, logrus.ErrorLevel, logrus.WarnLevel, logrus.InfoLevel, logrus.DebugLevel, logrus.TraceLevel}
 }
 
-// Fire implements logrus.Hook interface, attaches trace and span details found in entry context
+// Fire implements logrus.Hook interface, attaches trace and span details found in entry context,
+// along with the service, environment and version of the application when they are known.
 func (d *DDContextLogHook) Fire(e *logrus.Entry) error {
 	span, found := tracer.SpanFromContext(e.Context)
 	if !found {
@@ -28,5 +30,14 @@ func (d *DDContextLogHook) Fire(e *logrus.Entry) error {
 	}
 	e.Data["dd.trace_id"] = span.Context().TraceID()
 	e.Data["dd.span_id"] = span.Context().SpanID()
+	if svc := globalconfig.ServiceName(); svc != "" {
+		e.Data["dd.service"] = svc
+	}
+	if env := globalconfig.Env(); env != "" {
+		e.Data["dd.env"] = env
+	}
+	if version := globalconfig.ServiceVersion(); version != "" {
+		e.Data["dd.version"] = version
+	}
 	return nil
 }
@@ -10,6 +10,7 @@ import (
 	"testing"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
 
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
@@ -29,3 +30,32 @@ func TestFire(t *testing.T) {
 	assert.Equal(t, uint64(1234), e.Data["dd.trace_id"])
 	assert.Equal(t, uint64(1234), e.Data["dd.span_id"])
 }
+
+func TestFireGlobalConfig(t *testing.T) {
+	defer globalconfig.SetServiceName("")
+	defer globalconfig.SetEnv("")
+	defer globalconfig.SetServiceVersion("")
+	tracer.Start(tracer.WithService("test-service"), tracer.WithEnv("test-env"), tracer.WithServiceVersion("1.2.3"))
+	defer tracer.Stop()
+	_, sctx := tracer.StartSpanFromContext(context.Background(), "testSpan", tracer.WithSpanID(1234))
+
+	hook := &DDContextLogHook{}
+	e := logrus.NewEntry(logrus.New())
+	e.Context = sctx
+	err := hook.Fire(e)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "test-service", e.Data["dd.service"])
+	assert.Equal(t, "test-env", e.Data["dd.env"])
+	assert.Equal(t, "1.2.3", e.Data["dd.version"])
+}
+
+func TestFireNoSpan(t *testing.T) {
+	hook := &DDContextLogHook{}
+	e := logrus.NewEntry(logrus.New())
+	e.Context = context.Background()
+	err := hook.Fire(e)
+
+	assert.NoError(t, err)
+	assert.Empty(t, e.Data)
+}
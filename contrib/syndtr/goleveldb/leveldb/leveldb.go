@@ -22,6 +22,8 @@ import (
 	"github.com/syndtr/goleveldb/leveldb/util"
 )
 
+const componentName = "leveldb"
+
 // A DB wraps a leveldb.DB and traces all queries.
 type DB struct {
 	*leveldb.DB
@@ -268,6 +270,7 @@ func (it *Iterator) Release() {
 
 func startSpan(cfg *config, name string) ddtrace.Span {
 	opts := []ddtrace.StartSpanOption{
+		tracer.Tag(ext.Component, componentName),
 		tracer.SpanType(ext.SpanTypeLevelDB),
 		tracer.ServiceName(cfg.serviceName),
 		tracer.ResourceName(name),
@@ -0,0 +1,223 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package testing
+
+import (
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+)
+
+var (
+	ciTagsOnce sync.Once
+	ciTagsMap  map[string]string
+)
+
+// ciTags returns the tags describing the CI pipeline running the tests and the git commit under
+// test, detected once from the environment.
+func ciTags() map[string]string {
+	ciTagsOnce.Do(func() {
+		ciTagsMap = detectCITags(os.Getenv, localGitTags)
+	})
+	return ciTagsMap
+}
+
+// detectCITags returns the tags of the CI provider detected using getenv, amended with the git
+// metadata set by the DD_GIT_* environment variables. The git metadata which remains unknown is
+// completed using gitTags.
+func detectCITags(getenv func(string) string, gitTags func() map[string]string) map[string]string {
+	tags := make(map[string]string)
+	switch {
+	case getenv("GITHUB_ACTIONS") != "":
+		githubTags(getenv, tags)
+	case getenv("GITLAB_CI") != "":
+		gitlabTags(getenv, tags)
+	case getenv("JENKINS_URL") != "":
+		jenkinsTags(getenv, tags)
+	}
+	for tag, env := range map[string]string{
+		ext.GitRepositoryURL: "DD_GIT_REPOSITORY_URL",
+		ext.GitCommitSHA:     "DD_GIT_COMMIT_SHA",
+		ext.GitCommitMessage: "DD_GIT_COMMIT_MESSAGE",
+		ext.GitBranch:        "DD_GIT_BRANCH",
+		ext.GitTag:           "DD_GIT_TAG",
+	} {
+		if v := getenv(env); v != "" {
+			tags[tag] = v
+		}
+	}
+	if tags[ext.GitRepositoryURL] == "" || tags[ext.GitCommitSHA] == "" {
+		for tag, v := range gitTags() {
+			if _, ok := tags[tag]; !ok && v != "" {
+				tags[tag] = v
+			}
+		}
+	}
+	if v, ok := tags[ext.GitBranch]; ok {
+		// the branch may hold a tag reference, e.g. GITHUB_REF
+		branch, tag := normalizeRef(v)
+		delete(tags, ext.GitBranch)
+		if branch != "" {
+			tags[ext.GitBranch] = branch
+		}
+		if tag != "" && tags[ext.GitTag] == "" {
+			tags[ext.GitTag] = tag
+		}
+	}
+	if _, tag := normalizeRef(tags[ext.GitTag]); tag != "" {
+		tags[ext.GitTag] = tag
+	}
+	if v, ok := tags[ext.GitRepositoryURL]; ok {
+		tags[ext.GitRepositoryURL] = filterCredentials(v)
+	}
+	return tags
+}
+
+// githubTags sets the tags of the GitHub Actions workflows into tags.
+// See https://docs.github.com/en/actions/learn-github-actions/environment-variables
+func githubTags(getenv func(string) string, tags map[string]string) {
+	server := getenv("GITHUB_SERVER_URL")
+	if server == "" {
+		server = "https://github.com"
+	}
+	server = strings.TrimSuffix(server, "/")
+	repo := server + "/" + getenv("GITHUB_REPOSITORY")
+	pipelineURL := repo + "/actions/runs/" + getenv("GITHUB_RUN_ID")
+	if v := getenv("GITHUB_RUN_ATTEMPT"); v != "" {
+		pipelineURL += "/attempts/" + v
+	}
+	ref := getenv("GITHUB_HEAD_REF")
+	if ref == "" {
+		ref = getenv("GITHUB_REF")
+	}
+	setTags(tags, map[string]string{
+		ext.CIProviderName:   "github",
+		ext.CIPipelineID:     getenv("GITHUB_RUN_ID"),
+		ext.CIPipelineName:   getenv("GITHUB_WORKFLOW"),
+		ext.CIPipelineNumber: getenv("GITHUB_RUN_NUMBER"),
+		ext.CIPipelineURL:    pipelineURL,
+		ext.CIJobName:        getenv("GITHUB_JOB"),
+		ext.CIJobURL:         repo + "/commit/" + getenv("GITHUB_SHA") + "/checks",
+		ext.CIWorkspacePath:  getenv("GITHUB_WORKSPACE"),
+		ext.GitRepositoryURL: repo + ".git",
+		ext.GitCommitSHA:     getenv("GITHUB_SHA"),
+		ext.GitBranch:        ref,
+	})
+}
+
+// gitlabTags sets the tags of the GitLab CI pipelines into tags.
+// See https://docs.gitlab.com/ee/ci/variables/predefined_variables.html
+func gitlabTags(getenv func(string) string, tags map[string]string) {
+	branch := getenv("CI_COMMIT_BRANCH")
+	if branch == "" {
+		branch = getenv("CI_COMMIT_REF_NAME")
+	}
+	setTags(tags, map[string]string{
+		ext.CIProviderName:   "gitlab",
+		ext.CIPipelineID:     getenv("CI_PIPELINE_ID"),
+		ext.CIPipelineName:   getenv("CI_PROJECT_PATH"),
+		ext.CIPipelineNumber: getenv("CI_PIPELINE_IID"),
+		ext.CIPipelineURL:    getenv("CI_PIPELINE_URL"),
+		ext.CIJobName:        getenv("CI_JOB_NAME"),
+		ext.CIJobURL:         getenv("CI_JOB_URL"),
+		ext.CIStageName:      getenv("CI_JOB_STAGE"),
+		ext.CIWorkspacePath:  getenv("CI_PROJECT_DIR"),
+		ext.GitRepositoryURL: getenv("CI_REPOSITORY_URL"),
+		ext.GitCommitSHA:     getenv("CI_COMMIT_SHA"),
+		ext.GitCommitMessage: getenv("CI_COMMIT_MESSAGE"),
+		ext.GitBranch:        branch,
+		ext.GitTag:           getenv("CI_COMMIT_TAG"),
+	})
+}
+
+// jenkinsTags sets the tags of the Jenkins pipelines into tags.
+// See https://www.jenkins.io/doc/book/pipeline/jenkinsfile/#using-environment-variables
+func jenkinsTags(getenv func(string) string, tags map[string]string) {
+	repo := getenv("GIT_URL")
+	if repo == "" {
+		repo = getenv("GIT_URL_1")
+	}
+	setTags(tags, map[string]string{
+		ext.CIProviderName:   "jenkins",
+		ext.CIPipelineID:     getenv("BUILD_TAG"),
+		ext.CIPipelineName:   getenv("JOB_NAME"),
+		ext.CIPipelineNumber: getenv("BUILD_NUMBER"),
+		ext.CIPipelineURL:    getenv("BUILD_URL"),
+		ext.CIWorkspacePath:  getenv("WORKSPACE"),
+		ext.GitRepositoryURL: repo,
+		ext.GitCommitSHA:     getenv("GIT_COMMIT"),
+		ext.GitBranch:        getenv("GIT_BRANCH"),
+	})
+}
+
+// setTags sets the non-empty values of src into tags.
+func setTags(tags, src map[string]string) {
+	for k, v := range src {
+		if v != "" {
+			tags[k] = v
+		}
+	}
+}
+
+// localGitTags returns the git metadata of the repository of the working directory, if any.
+func localGitTags() map[string]string {
+	git := func(args ...string) string {
+		out, err := exec.Command("git", args...).Output()
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSpace(string(out))
+	}
+	tags := map[string]string{
+		ext.GitRepositoryURL: git("config", "--get", "remote.origin.url"),
+		ext.GitCommitSHA:     git("rev-parse", "HEAD"),
+		ext.GitCommitMessage: git("log", "-1", "--format=%B"),
+	}
+	if branch := git("rev-parse", "--abbrev-ref", "HEAD"); branch != "HEAD" {
+		// HEAD is detached otherwise
+		tags[ext.GitBranch] = branch
+	}
+	return tags
+}
+
+// normalizeRef returns the branch or the tag referred to by the given git reference, e.g.
+// "refs/heads/main" or "origin/main" for the branch "main" and "refs/tags/v1.0.0" for the
+// tag "v1.0.0".
+func normalizeRef(ref string) (branch, tag string) {
+	for _, prefix := range []string{"refs/tags/", "origin/tags/", "tags/"} {
+		if strings.HasPrefix(ref, prefix) {
+			return "", strings.TrimPrefix(ref, prefix)
+		}
+	}
+	for _, prefix := range []string{"refs/heads/", "refs/", "origin/"} {
+		ref = strings.TrimPrefix(ref, prefix)
+	}
+	return ref, ""
+}
+
+// filterCredentials returns the given repository URL without the credentials it may hold.
+func filterCredentials(repo string) string {
+	u, err := url.Parse(repo)
+	if err != nil || u.User == nil {
+		// e.g. git@github.com:DataDog/dd-trace-go.git
+		return repo
+	}
+	u.User = nil
+	return u.String()
+}
+
+// repositoryName returns the name of the repository at the given URL, e.g. "dd-trace-go" for
+// "https://github.com/DataDog/dd-trace-go.git".
+func repositoryName(repo string) string {
+	if i := strings.LastIndexAny(repo, "/:"); i >= 0 {
+		repo = repo[i+1:]
+	}
+	return strings.TrimSuffix(repo, ".git")
+}
@@ -0,0 +1,33 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package testing_test
+
+import (
+	"os"
+	"testing"
+
+	testtrace "gopkg.in/DataDog/dd-trace-go.v1/contrib/testing"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+)
+
+func ExampleRun() {
+	// The testing.M given to the TestMain function of the package of the tests.
+	var m *testing.M
+
+	// Run the tests with the tracer started in CI Visibility mode, and exit
+	// with their exit code once the test spans are sent.
+	os.Exit(testtrace.Run(m, tracer.WithEnv("ci")))
+}
+
+func ExampleStartTest() {
+	// The testing.T given to the test function.
+	var t *testing.T
+
+	// Trace the test. The spans started from the returned context are part of its trace.
+	ctx := testtrace.StartTest(t)
+	span, _ := tracer.StartSpanFromContext(ctx, "test.setup")
+	span.Finish()
+}
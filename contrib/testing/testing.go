@@ -0,0 +1,123 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+// Package testing provides functions to trace the tests run by the go test command, and report
+// them to Datadog CI Visibility (https://docs.datadoghq.com/continuous_integration/).
+package testing // import "gopkg.in/DataDog/dd-trace-go.v1/contrib/testing"
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+)
+
+const componentName = "testing"
+
+const (
+	// testFramework is the value of the test.framework tag of the test spans.
+	testFramework = "golang.org/pkg/testing"
+
+	// spanName is the operation name of the test spans.
+	spanName = "testing.test"
+)
+
+// Run starts the tracer in CI Visibility mode, runs the tests of m and stops the tracer, sending
+// the test spans, before returning the exit code of the tests. It is meant to be called by the
+// TestMain function of the package of the tests, along with StartTest in every test, e.g.:
+//
+//	func TestMain(m *testing.M) {
+//		os.Exit(testtrace.Run(m))
+//	}
+//
+// The given options are passed to tracer.Start. Unless set by DD_SERVICE, the service name
+// defaults to the name of the git repository of the tests.
+func Run(m *testing.M, opts ...tracer.StartOption) int {
+	startOpts := []tracer.StartOption{tracer.WithCIVisibility(true)}
+	if _, ok := os.LookupEnv("DD_SERVICE"); !ok {
+		if name := repositoryName(ciTags()[ext.GitRepositoryURL]); name != "" {
+			startOpts = append(startOpts, tracer.WithService(name))
+		}
+	}
+	tracer.Start(append(startOpts, opts...)...)
+	defer tracer.Stop()
+	return m.Run()
+}
+
+// StartTest starts the span of the given test, or benchmark, which is finished along with it,
+// and returns a context holding it, to be used as the parent of the spans of the test. The span
+// is tagged with the outcome of the test and the metadata of the CI pipeline running it. StartTest
+// is meant to be called at the beginning of the test, e.g.:
+//
+//	func TestFoo(t *testing.T) {
+//		ctx := testtrace.StartTest(t)
+//		// ...
+//	}
+func StartTest(tb testing.TB) context.Context {
+	suite := callerPackage(2)
+	opts := []tracer.StartSpanOption{
+		tracer.Tag(ext.Component, componentName),
+		tracer.SpanType(ext.SpanTypeTest),
+		tracer.ResourceName(suite + "." + tb.Name()),
+		tracer.Tag(ext.SpanKind, ext.SpanKindTest),
+		tracer.Tag(ext.TestName, tb.Name()),
+		tracer.Tag(ext.TestSuite, suite),
+		tracer.Tag(ext.TestFramework, testFramework),
+		tracer.Tag(ext.TestFrameworkVersion, runtime.Version()),
+		tracer.Tag(ext.OSPlatform, runtime.GOOS),
+		tracer.Tag(ext.OSArchitecture, runtime.GOARCH),
+		tracer.Tag(ext.RuntimeName, runtime.Compiler),
+		tracer.Tag(ext.RuntimeVersion, runtime.Version()),
+		// the tests must not be sampled out
+		tracer.Tag(ext.ManualKeep, true),
+	}
+	if _, ok := tb.(*testing.B); ok {
+		opts = append(opts, tracer.Tag(ext.TestType, ext.TestTypeBenchmark))
+	} else {
+		opts = append(opts, tracer.Tag(ext.TestType, ext.TestTypeTest))
+	}
+	for k, v := range ciTags() {
+		opts = append(opts, tracer.Tag(k, v))
+	}
+	span, ctx := tracer.StartSpanFromContext(context.Background(), spanName, opts...)
+	tb.Cleanup(func() {
+		switch {
+		case tb.Skipped():
+			span.SetTag(ext.TestStatus, ext.TestStatusSkip)
+		case tb.Failed():
+			span.SetTag(ext.TestStatus, ext.TestStatusFail)
+			span.SetTag(ext.Error, true)
+		default:
+			span.SetTag(ext.TestStatus, ext.TestStatusPass)
+		}
+		span.Finish()
+	})
+	return ctx
+}
+
+// callerPackage returns the import path of the package of the function skip frames above the
+// caller of callerPackage, e.g. "github.com/DataDog/dd-trace-go/ddtrace" for a function of the
+// ddtrace package.
+func callerPackage(skip int) string {
+	pc, _, _, ok := runtime.Caller(skip)
+	if !ok {
+		return ""
+	}
+	fn := runtime.FuncForPC(pc)
+	if fn == nil {
+		return ""
+	}
+	// e.g. github.com/DataDog/dd-trace-go/ddtrace.TestFoo.func1
+	name := fn.Name()
+	i := strings.LastIndex(name, "/")
+	if j := strings.Index(name[i+1:], "."); j >= 0 {
+		return name[:i+1+j]
+	}
+	return name
+}
@@ -0,0 +1,101 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package testing
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+)
+
+const testPackage = "gopkg.in/DataDog/dd-trace-go.v1/contrib/testing"
+
+func TestStartTest(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	t.Run("pass", func(t *testing.T) {
+		ctx := StartTest(t)
+		child, _ := tracer.StartSpanFromContext(ctx, "child")
+		child.Finish()
+	})
+	t.Run("skip", func(t *testing.T) {
+		StartTest(t)
+		t.Skip("skipped")
+	})
+	t.Run("fail", func(t *testing.T) {
+		tb := &failingTB{TB: t}
+		StartTest(tb)
+		tb.cleanup()
+	})
+
+	spans := mt.FinishedSpans()
+	require.Len(t, spans, 4)
+	child, pass, skip, fail := spans[0], spans[1], spans[2], spans[3]
+	assert.Equal(t, pass.SpanID(), child.ParentID())
+
+	assert.Equal(t, "testing.test", pass.OperationName())
+	assert.Equal(t, testPackage+".TestStartTest/pass", pass.Tag(ext.ResourceName))
+	assert.Equal(t, ext.SpanTypeTest, pass.Tag(ext.SpanType))
+	assert.Equal(t, ext.SpanKindTest, pass.Tag(ext.SpanKind))
+	assert.Equal(t, "TestStartTest/pass", pass.Tag(ext.TestName))
+	assert.Equal(t, testPackage, pass.Tag(ext.TestSuite))
+	assert.Equal(t, "golang.org/pkg/testing", pass.Tag(ext.TestFramework))
+	assert.Equal(t, ext.TestTypeTest, pass.Tag(ext.TestType))
+	assert.Equal(t, runtime.GOOS, pass.Tag(ext.OSPlatform))
+	assert.Equal(t, true, pass.Tag(ext.ManualKeep))
+	assert.Equal(t, ext.TestStatusPass, pass.Tag(ext.TestStatus))
+	assert.Nil(t, pass.Tag(ext.Error))
+	for k, v := range ciTags() {
+		assert.Equal(t, v, pass.Tag(k))
+	}
+
+	assert.Equal(t, ext.TestStatusSkip, skip.Tag(ext.TestStatus))
+	assert.Equal(t, ext.TestStatusFail, fail.Tag(ext.TestStatus))
+	assert.Equal(t, true, fail.Tag(ext.Error))
+}
+
+func BenchmarkStartTest(b *testing.B) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	b.Run("benchmark", func(b *testing.B) {
+		StartTest(b)
+	})
+
+	spans := mt.FinishedSpans()
+	require.Len(b, spans, 1)
+	assert.Equal(b, ext.TestTypeBenchmark, spans[0].Tag(ext.TestType))
+}
+
+// failingTB is a failed test, whose cleanup functions are run by cleanup.
+type failingTB struct {
+	testing.TB
+	cleanups []func()
+}
+
+func (tb *failingTB) Failed() bool { return true }
+
+func (tb *failingTB) Cleanup(f func()) { tb.cleanups = append(tb.cleanups, f) }
+
+func (tb *failingTB) cleanup() {
+	for i := len(tb.cleanups) - 1; i >= 0; i-- {
+		tb.cleanups[i]()
+	}
+}
+
+func TestCallerPackage(t *testing.T) {
+	assert.Equal(t, testPackage, callerPackage(1))
+	func() {
+		assert.Equal(t, testPackage, callerPackage(1))
+	}()
+}
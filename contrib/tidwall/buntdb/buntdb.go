@@ -18,6 +18,8 @@ import (
 	"github.com/tidwall/buntdb"
 )
 
+const componentName = "buntdb"
+
 // A DB wraps a buntdb.DB, automatically tracing any transactions.
 type DB struct {
 	*buntdb.DB
@@ -95,6 +97,7 @@ func WrapTx(tx *buntdb.Tx, opts ...Option) *Tx {
 
 func (tx *Tx) startSpan(name string) ddtrace.Span {
 	opts := []ddtrace.StartSpanOption{
+		tracer.Tag(ext.Component, componentName),
 		tracer.SpanType(ext.AppTypeDB),
 		tracer.ServiceName(tx.cfg.serviceName),
 		tracer.ResourceName(name),
@@ -13,6 +13,7 @@ import (
 	twirptrace "gopkg.in/DataDog/dd-trace-go.v1/contrib/twitchtv/twirp"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
 
+	"github.com/twitchtv/twirp"
 	"github.com/twitchtv/twirp/example"
 )
 
@@ -49,3 +50,27 @@ func ExampleWrapServer() {
 	traced := twirptrace.WrapServer(server)
 	http.ListenAndServe(":8080", traced)
 }
+
+func ExampleNewServerInterceptor() {
+	tracer.Start()
+	defer tracer.Stop()
+
+	server := example.NewHaberdasherServer(hatmaker{},
+		twirp.WithServerInterceptors(twirptrace.NewServerInterceptor()))
+	traced := twirptrace.WrapServer(server)
+	http.ListenAndServe(":8080", traced)
+}
+
+func ExampleNewClientHooks() {
+	tracer.Start()
+	defer tracer.Stop()
+
+	client := example.NewHaberdasherProtobufClient("http://localhost:8080", &http.Client{},
+		twirp.WithClientHooks(twirptrace.NewClientHooks()))
+	hat, err := client.MakeHat(context.Background(), &example.Size{Inches: 6})
+	if err != nil {
+		fmt.Println("error making hat:", err)
+		return
+	}
+	fmt.Println("made hat:", hat)
+}
@@ -21,9 +21,12 @@ import (
 	"github.com/twitchtv/twirp"
 )
 
+const componentName = "twirp"
+
 type (
-	twirpErrorKey struct{}
-	twirpSpanKey  struct{}
+	twirpErrorKey      struct{}
+	twirpSpanKey       struct{}
+	twirpClientSpanKey struct{}
 )
 
 // HTTPClient is duplicated from twirp's generated service code.
@@ -51,6 +54,7 @@ func WrapClient(c HTTPClient, opts ...Option) HTTPClient {
 
 func (wc *wrappedClient) Do(req *http.Request) (*http.Response, error) {
 	opts := []tracer.StartSpanOption{
+		tracer.Tag(ext.Component, componentName),
 		tracer.SpanType(ext.SpanTypeHTTP),
 		tracer.ServiceName(wc.cfg.clientServiceName()),
 		tracer.Tag(ext.HTTPMethod, req.Method),
@@ -106,6 +110,7 @@ func WrapServer(h http.Handler, opts ...Option) http.Handler {
 	log.Debug("contrib/twitchtv/twirp: Wrapping Server: %#v", cfg)
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		opts := []tracer.StartSpanOption{
+			tracer.Tag(ext.Component, componentName),
 			tracer.SpanType(ext.SpanTypeWeb),
 			tracer.ServiceName(cfg.serverServiceName()),
 			tracer.Tag(ext.HTTPMethod, r.Method),
@@ -153,24 +158,45 @@ func spanNameFromContext(ctx context.Context) string {
 	return fmt.Sprintf("twirp.%s", svc)
 }
 
+// startServerSpan starts the span of the request served by a twirp server,
+// taking the names of the package and service called from ctx.
+func startServerSpan(ctx context.Context, cfg *config, opts ...tracer.StartSpanOption) (tracer.Span, context.Context) {
+	opts = append(opts,
+		tracer.Tag(ext.Component, componentName),
+		tracer.SpanType(ext.SpanTypeWeb),
+		tracer.ServiceName(cfg.serverServiceName()),
+		tracer.Measured(),
+	)
+	if pkg, ok := twirp.PackageName(ctx); ok {
+		opts = append(opts, tracer.Tag("twirp.package", pkg))
+	}
+	if svc, ok := twirp.ServiceName(ctx); ok {
+		opts = append(opts, tracer.Tag("twirp.service", svc))
+	}
+	if !math.IsNaN(cfg.analyticsRate) {
+		opts = append(opts, tracer.Tag(ext.EventSampleRate, cfg.analyticsRate))
+	}
+	return tracer.StartSpanFromContext(ctx, spanNameFromContext(ctx), opts...)
+}
+
+// finishWithError finishes span with the given error. The code of a twirp
+// error is its error type, e.g. "not_found".
+func finishWithError(span tracer.Span, err error) {
+	if err == nil {
+		span.Finish()
+		return
+	}
+	span.SetTag(ext.Error, err)
+	if twerr, ok := err.(twirp.Error); ok {
+		span.SetTag(ext.ErrorType, string(twerr.Code()))
+		span.SetTag(ext.ErrorMsg, twerr.Msg())
+	}
+	span.Finish()
+}
+
 func requestReceivedHook(cfg *config) func(context.Context) (context.Context, error) {
 	return func(ctx context.Context) (context.Context, error) {
-		opts := []tracer.StartSpanOption{
-			tracer.SpanType(ext.SpanTypeWeb),
-			tracer.ServiceName(cfg.serverServiceName()),
-			tracer.Measured(),
-		}
-		if pkg, ok := twirp.PackageName(ctx); ok {
-			opts = append(opts, tracer.Tag("twirp.package", pkg))
-		}
-		if svc, ok := twirp.ServiceName(ctx); ok {
-			opts = append(opts, tracer.Tag("twirp.service", svc))
-		}
-		if !math.IsNaN(cfg.analyticsRate) {
-			opts = append(opts, tracer.Tag(ext.EventSampleRate, cfg.analyticsRate))
-		}
-		span, ctx := tracer.StartSpanFromContext(ctx, spanNameFromContext(ctx), opts...)
-
+		span, ctx := startServerSpan(ctx, cfg)
 		ctx = context.WithValue(ctx, twirpSpanKey{}, span)
 		return ctx, nil
 	}
@@ -215,8 +241,11 @@ func responseSentHook(cfg *config) func(context.Context) {
 		if sc, ok := twirp.StatusCode(ctx); ok {
 			span.SetTag(ext.HTTPCode, sc)
 		}
-		err, _ := ctx.Value(twirpErrorKey{}).(twirp.Error)
-		span.Finish(tracer.WithError(err))
+		if err, ok := ctx.Value(twirpErrorKey{}).(twirp.Error); ok {
+			finishWithError(span, err)
+			return
+		}
+		span.Finish()
 	}
 }
 
@@ -225,3 +254,115 @@ func errorHook(cfg *config) func(context.Context, twirp.Error) context.Context {
 		return context.WithValue(ctx, twirpErrorKey{}, err)
 	}
 }
+
+// NewServerInterceptor creates an interceptor for a twirp server to perform
+// tracing, given to it with twirp.WithServerInterceptors. The interceptor can be
+// used along with NewServerHooks, in which case the calls are traced by the span
+// of the hooks.
+func NewServerInterceptor(opts ...Option) twirp.Interceptor {
+	cfg := new(config)
+	defaults(cfg)
+	for _, fn := range opts {
+		fn(cfg)
+	}
+	log.Debug("contrib/twitchtv/twirp: Creating Server Interceptor: %#v", cfg)
+	return func(next twirp.Method) twirp.Method {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			if _, ok := ctx.Value(twirpSpanKey{}).(tracer.Span); ok {
+				// the request is traced by the server hooks
+				return next(ctx, req)
+			}
+			var opts []tracer.StartSpanOption
+			if method, ok := twirp.MethodName(ctx); ok {
+				opts = append(opts,
+					tracer.ResourceName(method),
+					tracer.Tag("twirp.method", method),
+				)
+			}
+			span, ctx := startServerSpan(ctx, cfg, opts...)
+			res, err := next(ctx, req)
+			code := http.StatusOK
+			if twerr, ok := err.(twirp.Error); ok {
+				code = twirp.ServerHTTPStatusFromErrorCode(twerr.Code())
+			} else if err != nil {
+				code = http.StatusInternalServerError
+			}
+			span.SetTag(ext.HTTPCode, strconv.Itoa(code))
+			finishWithError(span, err)
+			return res, err
+		}
+	}
+}
+
+// NewClientHooks creates the callback hooks for a twirp client to perform
+// tracing, given to it with twirp.WithClientHooks. The context of the calls
+// is propagated to the server in the headers of their requests.
+func NewClientHooks(opts ...Option) *twirp.ClientHooks {
+	cfg := new(config)
+	defaults(cfg)
+	for _, fn := range opts {
+		fn(cfg)
+	}
+	log.Debug("contrib/twitchtv/twirp: Creating Client Hooks: %#v", cfg)
+	return &twirp.ClientHooks{
+		RequestPrepared:  clientRequestPreparedHook(cfg),
+		ResponseReceived: clientResponseReceivedHook(cfg),
+		Error:            clientErrorHook(cfg),
+	}
+}
+
+func clientRequestPreparedHook(cfg *config) func(context.Context, *http.Request) (context.Context, error) {
+	return func(ctx context.Context, req *http.Request) (context.Context, error) {
+		opts := []tracer.StartSpanOption{
+			tracer.Tag(ext.Component, componentName),
+			tracer.Tag(ext.SpanKind, ext.SpanKindClient),
+			tracer.SpanType(ext.SpanTypeHTTP),
+			tracer.ServiceName(cfg.clientServiceName()),
+			tracer.Tag(ext.HTTPMethod, req.Method),
+			tracer.Tag(ext.HTTPURL, req.URL.Path),
+		}
+		if pkg, ok := twirp.PackageName(ctx); ok {
+			opts = append(opts, tracer.Tag("twirp.package", pkg))
+		}
+		if svc, ok := twirp.ServiceName(ctx); ok {
+			opts = append(opts, tracer.Tag("twirp.service", svc))
+		}
+		if method, ok := twirp.MethodName(ctx); ok {
+			opts = append(opts,
+				tracer.ResourceName(method),
+				tracer.Tag("twirp.method", method),
+			)
+		}
+		if !math.IsNaN(cfg.analyticsRate) {
+			opts = append(opts, tracer.Tag(ext.EventSampleRate, cfg.analyticsRate))
+		}
+		span, ctx := tracer.StartSpanFromContext(ctx, "twirp.request", opts...)
+		if err := tracer.Inject(span.Context(), tracer.HTTPHeadersCarrier(req.Header)); err != nil {
+			log.Warn("contrib/twitchtv/twirp.clientRequestPreparedHook: failed to inject http headers: %v\n", err)
+		}
+		ctx = context.WithValue(ctx, twirpClientSpanKey{}, span)
+		return ctx, nil
+	}
+}
+
+func clientResponseReceivedHook(cfg *config) func(context.Context) {
+	return func(ctx context.Context) {
+		span, ok := ctx.Value(twirpClientSpanKey{}).(tracer.Span)
+		if !ok {
+			return
+		}
+		span.SetTag(ext.HTTPCode, strconv.Itoa(http.StatusOK))
+		span.Finish()
+	}
+}
+
+func clientErrorHook(cfg *config) func(context.Context, twirp.Error) {
+	return func(ctx context.Context, err twirp.Error) {
+		span, ok := ctx.Value(twirpClientSpanKey{}).(tracer.Span)
+		if !ok {
+			// the request failed before it was prepared
+			return
+		}
+		finishWithError(span, err)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
@@ -198,6 +199,8 @@ func TestServerHooks(t *testing.T) {
 		assert.Equal("Method", span.Tag("twirp.method"))
 		assert.Equal("500", span.Tag(ext.HTTPCode))
 		assert.Equal("twirp error internal: something bad or unexpected happened", span.Tag(ext.Error).(error).Error())
+		assert.Equal("internal", span.Tag(ext.ErrorType))
+		assert.Equal("something bad or unexpected happened", span.Tag(ext.ErrorMsg))
 	})
 
 	t.Run("chained", func(t *testing.T) {
@@ -401,3 +404,100 @@ func TestHaberdash(t *testing.T) {
 	assert.Equal(ext.SpanTypeWeb, spans[1].Tag(ext.SpanType))
 	assert.Equal(ext.SpanTypeHTTP, spans[2].Tag(ext.SpanType))
 }
+
+func TestServerInterceptor(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	t.Run("alone", func(t *testing.T) {
+		defer mt.Reset()
+		assert := assert.New(t)
+
+		srv := httptest.NewServer(example.NewHaberdasherServer(haberdasher(6),
+			twirp.WithServerInterceptors(NewServerInterceptor(WithServiceName("twirp-test")))))
+		defer srv.Close()
+
+		client := example.NewHaberdasherProtobufClient(srv.URL, &http.Client{})
+		_, err := client.MakeHat(context.Background(), &example.Size{Inches: 6})
+		assert.NoError(err)
+		_, err = client.MakeHat(context.Background(), &example.Size{Inches: 7})
+		assert.Error(err)
+
+		spans := mt.FinishedSpans()
+		assert.Len(spans, 2)
+		span := spans[0]
+		assert.Equal("twirp.Haberdasher", span.OperationName())
+		assert.Equal("twirp-test", span.Tag(ext.ServiceName))
+		assert.Equal("MakeHat", span.Tag(ext.ResourceName))
+		assert.Equal("Haberdasher", span.Tag("twirp.service"))
+		assert.Equal("200", span.Tag(ext.HTTPCode))
+		assert.Nil(span.Tag(ext.Error))
+
+		span = spans[1]
+		assert.Equal("400", span.Tag(ext.HTTPCode))
+		assert.Equal("invalid_argument", span.Tag(ext.ErrorType))
+		assert.NotNil(span.Tag(ext.Error))
+	})
+
+	t.Run("hooks", func(t *testing.T) {
+		defer mt.Reset()
+		assert := assert.New(t)
+
+		srv := httptest.NewServer(example.NewHaberdasherServer(haberdasher(6),
+			twirp.WithServerHooks(NewServerHooks()),
+			twirp.WithServerInterceptors(NewServerInterceptor())))
+		defer srv.Close()
+
+		client := example.NewHaberdasherProtobufClient(srv.URL, &http.Client{})
+		_, err := client.MakeHat(context.Background(), &example.Size{Inches: 6})
+		assert.NoError(err)
+
+		spans := mt.FinishedSpans()
+		assert.Len(spans, 1)
+		assert.Equal("MakeHat", spans[0].Tag(ext.ResourceName))
+	})
+}
+
+func TestClientHooks(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+	assert := assert.New(t)
+
+	srv := httptest.NewServer(WrapServer(example.NewHaberdasherServer(haberdasher(6), NewServerHooks())))
+	defer srv.Close()
+
+	client := example.NewHaberdasherJSONClient(srv.URL, &http.Client{},
+		twirp.WithClientHooks(NewClientHooks(WithServiceName("twirp-client"))))
+	_, err := client.MakeHat(context.Background(), &example.Size{Inches: 6})
+	assert.NoError(err)
+	_, err = client.MakeHat(context.Background(), &example.Size{Inches: 7})
+	assert.Error(err)
+
+	spans := mt.FinishedSpans()
+	assert.Len(spans, 6)
+	var clientSpans []mocktracer.Span
+	for _, s := range spans {
+		if s.OperationName() == "twirp.request" {
+			clientSpans = append(clientSpans, s)
+		}
+	}
+	assert.Len(clientSpans, 2)
+	span := clientSpans[0]
+	assert.Equal("twirp-client", span.Tag(ext.ServiceName))
+	assert.Equal(ext.SpanKindClient, span.Tag(ext.SpanKind))
+	assert.Equal("MakeHat", span.Tag(ext.ResourceName))
+	assert.Equal("twitch.twirp.example", span.Tag("twirp.package"))
+	assert.Equal("200", span.Tag(ext.HTTPCode))
+	assert.Nil(span.Tag(ext.Error))
+
+	span = clientSpans[1]
+	assert.Equal("invalid_argument", span.Tag(ext.ErrorType))
+	assert.NotNil(span.Tag(ext.Error))
+
+	// the server spans are children of the client spans
+	for _, s := range spans {
+		if s.OperationName() == "twirp.handler" {
+			assert.Contains([]uint64{clientSpans[0].SpanID(), clientSpans[1].SpanID()}, s.ParentID())
+		}
+	}
+}
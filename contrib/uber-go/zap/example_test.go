@@ -0,0 +1,32 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022 Datadog, Inc.
+
+package zap_test
+
+import (
+	"context"
+
+	zaptrace "gopkg.in/DataDog/dd-trace-go.v1/contrib/uber-go/zap"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+
+	"go.uber.org/zap"
+)
+
+func Example() {
+	// Ensure your tracer is started and stopped
+	// Setup zap, wrapping its core, do this once at the beginning of your program
+	logger, _ := zap.NewProduction(zap.WrapCore(zaptrace.WrapCore))
+	defer logger.Sync()
+
+	span, ctx := tracer.StartSpanFromContext(context.Background(), "mySpan")
+	defer span.Finish()
+
+	// Pass the current span context to the logger to correlate its entries
+	logger.Info("Completed some work!", zaptrace.Context(ctx))
+
+	// or create a logger correlating all of its entries
+	cLog := logger.With(zaptrace.Context(ctx))
+	cLog.Info("Completed some more work!")
+}
@@ -0,0 +1,98 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022 Datadog, Inc.
+
+// Package zap provides a log/span correlation core for the uber-go/zap package (https://github.com/uber-go/zap).
+package zap // import "gopkg.in/DataDog/dd-trace-go.v1/contrib/uber-go/zap"
+
+import (
+	"context"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// contextKey is the key of the fields returned by Context.
+const contextKey = "dd.context"
+
+// Context returns a field holding ctx. Entries logged with this field through a
+// core wrapped by WrapCore are correlated to the span found in ctx, if any. The
+// field itself is never encoded.
+func Context(ctx context.Context) zap.Field {
+	return zap.Field{Key: contextKey, Type: zapcore.SkipType, Interface: ctx}
+}
+
+// WrapCore wraps core so that the entries logged with a Context field, or by a
+// logger created with one, are correlated to the span found in its context: the
+// dd.trace_id and dd.span_id fields are added to them, along with dd.service,
+// dd.env and dd.version when they are known. It can be passed to zap.WrapCore.
+func WrapCore(core zapcore.Core) zapcore.Core {
+	return &ddCore{core}
+}
+
+type ddCore struct {
+	zapcore.Core
+}
+
+// With implements zapcore.Core.
+func (c *ddCore) With(fields []zapcore.Field) zapcore.Core {
+	return &ddCore{c.Core.With(correlate(fields))}
+}
+
+// Check implements zapcore.Core.
+func (c *ddCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core.
+func (c *ddCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(ent, correlate(fields))
+}
+
+// correlate returns the given fields, where the Context fields are replaced with
+// the correlation fields of the span found in their context.
+func correlate(fields []zapcore.Field) []zapcore.Field {
+	for i, f := range fields {
+		if f.Key != contextKey || f.Type != zapcore.SkipType {
+			continue
+		}
+		ctx, _ := f.Interface.(context.Context)
+		out := make([]zapcore.Field, 0, len(fields)+4)
+		out = append(out, fields[:i]...)
+		out = append(out, spanFields(ctx)...)
+		return append(out, correlate(fields[i+1:])...)
+	}
+	return fields
+}
+
+// spanFields returns the correlation fields of the span found in ctx, if any.
+func spanFields(ctx context.Context) []zapcore.Field {
+	if ctx == nil {
+		return nil
+	}
+	span, found := tracer.SpanFromContext(ctx)
+	if !found {
+		return nil
+	}
+	fields := []zapcore.Field{
+		zap.Uint64("dd.trace_id", span.Context().TraceID()),
+		zap.Uint64("dd.span_id", span.Context().SpanID()),
+	}
+	if svc := globalconfig.ServiceName(); svc != "" {
+		fields = append(fields, zap.String("dd.service", svc))
+	}
+	if env := globalconfig.Env(); env != "" {
+		fields = append(fields, zap.String("dd.env", env))
+	}
+	if version := globalconfig.ServiceVersion(); version != "" {
+		fields = append(fields, zap.String("dd.version", version))
+	}
+	return fields
+}
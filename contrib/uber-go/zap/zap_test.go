@@ -0,0 +1,78 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022 Datadog, Inc.
+
+package zap
+
+import (
+	"context"
+	"testing"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func newTestLogger() (*zap.Logger, *observer.ObservedLogs) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	return zap.New(WrapCore(core)), logs
+}
+
+func TestWrapCore(t *testing.T) {
+	defer globalconfig.SetServiceName("")
+	defer globalconfig.SetEnv("")
+	defer globalconfig.SetServiceVersion("")
+	tracer.Start(tracer.WithService("test-service"), tracer.WithEnv("test-env"), tracer.WithServiceVersion("1.2.3"))
+	defer tracer.Stop()
+	span, ctx := tracer.StartSpanFromContext(context.Background(), "testSpan", tracer.WithSpanID(1234))
+	defer span.Finish()
+
+	want := map[string]interface{}{
+		"dd.trace_id": uint64(1234),
+		"dd.span_id":  uint64(1234),
+		"dd.service":  "test-service",
+		"dd.env":      "test-env",
+		"dd.version":  "1.2.3",
+		"key":         "value",
+	}
+
+	t.Run("field", func(t *testing.T) {
+		logger, logs := newTestLogger()
+		logger.Info("msg", Context(ctx), zap.String("key", "value"))
+		assert.Equal(t, 1, logs.Len())
+		assert.Equal(t, want, logs.All()[0].ContextMap())
+	})
+
+	t.Run("with", func(t *testing.T) {
+		logger, logs := newTestLogger()
+		logger.With(Context(ctx)).Info("msg", zap.String("key", "value"))
+		assert.Equal(t, 1, logs.Len())
+		assert.Equal(t, want, logs.All()[0].ContextMap())
+	})
+
+	t.Run("no-span", func(t *testing.T) {
+		logger, logs := newTestLogger()
+		logger.Info("msg", Context(context.Background()), zap.String("key", "value"))
+		assert.Equal(t, 1, logs.Len())
+		assert.Equal(t, map[string]interface{}{"key": "value"}, logs.All()[0].ContextMap())
+	})
+
+	t.Run("no-context", func(t *testing.T) {
+		logger, logs := newTestLogger()
+		logger.Info("msg", zap.String("key", "value"))
+		assert.Equal(t, 1, logs.Len())
+		assert.Equal(t, map[string]interface{}{"key": "value"}, logs.All()[0].ContextMap())
+	})
+
+	t.Run("disabled-level", func(t *testing.T) {
+		core, logs := observer.New(zapcore.InfoLevel)
+		logger := zap.New(WrapCore(core))
+		logger.Debug("msg", Context(ctx))
+		assert.Equal(t, 0, logs.Len())
+	})
+}
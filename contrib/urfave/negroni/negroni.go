@@ -19,13 +19,15 @@ import (
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
 )
 
+const componentName = "negroni"
+
 // DatadogMiddleware returns middleware that will trace incoming requests.
 type DatadogMiddleware struct {
 	cfg *config
 }
 
 func (m *DatadogMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
-	opts := append(m.cfg.spanOpts, tracer.ServiceName(m.cfg.serviceName), tracer.ResourceName(m.cfg.resourceNamer(r)))
+	opts := append(m.cfg.spanOpts, tracer.Tag(ext.Component, componentName), tracer.ServiceName(m.cfg.serviceName), tracer.ResourceName(m.cfg.resourceNamer(r)))
 	if !math.IsNaN(m.cfg.analyticsRate) {
 		opts = append(opts, tracer.Tag(ext.EventSampleRate, m.cfg.analyticsRate))
 	}
@@ -43,6 +45,7 @@ func (m *DatadogMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request, ne
 				opts = []tracer.FinishOption{tracer.WithError(fmt.Errorf("%d: %s", status, http.StatusText(status)))}
 			}
 		}
+		httptrace.SetResponseHeaderTags(span, w.Header(), httptrace.EnvHeaderTags())
 		httptrace.FinishRequestSpan(span, status, opts...)
 	}()
 
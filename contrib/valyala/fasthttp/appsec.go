@@ -0,0 +1,70 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package fasthttp
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo/instrumentation"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo/instrumentation/httpsec"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttpadaptor"
+)
+
+// useAppSec executes the AppSec logic related to the operation start and
+// returns the context of the operation along with the function to be executed
+// upon finishing the operation with the response status. The fasthttp request
+// is converted into a net/http one for AppSec to monitor it. When AppSec blocks
+// the request, the blocking response is written and blocked is true: the
+// request must not be handled.
+func useAppSec(ctx context.Context, fctx *fasthttp.RequestCtx, span tracer.Span) (_ context.Context, afterHandler func(status int), blocked bool) {
+	var req http.Request
+	if err := fasthttpadaptor.ConvertRequest(fctx, &req, true); err != nil {
+		log.Debug("contrib/valyala/fasthttp: appsec: could not convert the request: %v", err)
+		return ctx, nil, false
+	}
+	instrumentation.SetAppSecEnabledTags(span)
+	httpsec.SetIPTags(span, &req)
+	args := httpsec.MakeHandlerOperationArgs(&req, nil)
+	ctx, op := httpsec.StartOperation(ctx, args)
+	httpsec.MonitorRequestBody(ctx, &req)
+	if op.Err() != nil {
+		fasthttpadaptor.NewFastHTTPHandlerFunc(httpsec.WriteBlockingResponse)(fctx)
+		blocked = true
+	}
+	return ctx, func(status int) {
+		headers := make(http.Header)
+		fctx.Response.Header.VisitAll(func(k, v []byte) {
+			headers.Add(string(k), string(v))
+		})
+		events := op.Finish(httpsec.HandlerOperationRes{Status: status, Headers: httpsec.MakeResponseHeaders(headers)})
+		if len(events) > 0 {
+			remoteIP, _, err := net.SplitHostPort(req.RemoteAddr)
+			if err != nil {
+				remoteIP = req.RemoteAddr
+			}
+			httpsec.SetSecurityEventTags(span, events, remoteIP, args.Headers, headers)
+		}
+		instrumentation.SetTags(span, op.Tags())
+	}, blocked
+}
+
+// protect monitors the URL of req with AppSec, when enabled, in order to detect
+// and block the server-side request forgeries before req gets sent, at its call
+// site (RASP). It returns a non-nil error when the incoming request sending req
+// was blocked, in which case req must not be sent.
+func protect(ctx context.Context, req *fasthttp.Request) error {
+	if !appsec.Enabled() {
+		return nil
+	}
+	return httpsec.ProtectRoundTripOperation(ctx, req.URI().String())
+}
@@ -0,0 +1,94 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package fasthttp
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/namingschema"
+
+	"github.com/valyala/fasthttp"
+)
+
+// A Doer sends HTTP requests, as the clients of fasthttp do, such as
+// fasthttp.Client, fasthttp.HostClient, fasthttp.LBClient and
+// fasthttp.PipelineClient.
+type Doer interface {
+	Do(req *fasthttp.Request, resp *fasthttp.Response) error
+}
+
+// WrapClient wraps a fasthttp client so that the requests it sends with Do and
+// DoContext are traced.
+func WrapClient(c Doer, opts ...ClientOption) *Client {
+	cfg := newClientConfig(opts...)
+	log.Debug("contrib/valyala/fasthttp: Wrapping Client: %#v", cfg)
+	return &Client{Doer: c, cfg: cfg}
+}
+
+// A Client wraps a fasthttp client.
+type Client struct {
+	Doer
+	cfg *clientConfig
+}
+
+// Do sends req and waits for its response, as the wrapped client does, and
+// traces it. The span of the request starts a new trace: use DoContext to
+// connect it to the trace of a context.
+func (c *Client) Do(req *fasthttp.Request, resp *fasthttp.Response) error {
+	return c.DoContext(context.Background(), req, resp)
+}
+
+// DoContext sends req and waits for its response, as the wrapped client does,
+// and traces it within a child span of the span of ctx, if any, e.g. the one
+// returned by ContextFromRequestCtx. The trace is propagated through the
+// headers of req.
+func (c *Client) DoContext(ctx context.Context, req *fasthttp.Request, resp *fasthttp.Response) (err error) {
+	startOpts := []ddtrace.StartSpanOption{
+		tracer.Tag(ext.Component, componentName),
+		tracer.SpanType(ext.SpanTypeHTTP),
+		tracer.ResourceName(c.cfg.resourceNamer(req)),
+		tracer.Tag(ext.SpanKind, ext.SpanKindClient),
+		tracer.Tag(ext.HTTPMethod, string(req.Header.Method())),
+		tracer.Tag(ext.HTTPURL, requestURL(req)),
+	}
+	if c.cfg.serviceName != "" {
+		startOpts = append(startOpts, tracer.ServiceName(c.cfg.serviceName))
+	}
+	if !math.IsNaN(c.cfg.analyticsRate) {
+		startOpts = append(startOpts, tracer.Tag(ext.EventSampleRate, c.cfg.analyticsRate))
+	}
+	startOpts = append(startOpts, c.cfg.spanOpts...)
+	span, ctx := tracer.StartSpanFromContext(ctx, namingschema.HTTPClientOp("http.request"), startOpts...)
+	defer func() { span.Finish(tracer.WithError(err)) }()
+	if err = protect(ctx, req); err != nil {
+		span.SetTag("http.errors", err.Error())
+		return err
+	}
+	if err := tracer.Inject(span.Context(), NewRequestHeaderCarrier(&req.Header)); err != nil {
+		// this should never happen
+		log.Warn("contrib/valyala/fasthttp: failed to inject http headers: %v", err)
+	}
+	if err = c.Doer.Do(req, resp); err != nil {
+		span.SetTag("http.errors", err.Error())
+		return err
+	}
+	status := resp.StatusCode()
+	span.SetTag(ext.HTTPCode, strconv.Itoa(status))
+	// treat 5XX as errors
+	if status/100 == 5 {
+		span.SetTag("http.errors", strconv.Itoa(status)+" "+http.StatusText(status))
+		span.SetTag(ext.Error, fmt.Errorf("%d: %s", status, http.StatusText(status)))
+	}
+	return nil
+}
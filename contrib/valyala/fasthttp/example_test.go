@@ -0,0 +1,37 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package fasthttp_test
+
+import (
+	"log"
+
+	fasthttptrace "gopkg.in/DataDog/dd-trace-go.v1/contrib/valyala/fasthttp"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+
+	"github.com/valyala/fasthttp"
+)
+
+func Example() {
+	tracer.Start()
+	defer tracer.Stop()
+
+	client := fasthttptrace.WrapClient(&fasthttp.Client{}, fasthttptrace.ClientWithServiceName("backend-client"))
+	handler := func(fctx *fasthttp.RequestCtx) {
+		// The request sent to the backend is traced within the trace of the
+		// request handled.
+		req := fasthttp.AcquireRequest()
+		defer fasthttp.ReleaseRequest(req)
+		resp := fasthttp.AcquireResponse()
+		defer fasthttp.ReleaseResponse(resp)
+		req.SetRequestURI("http://backend.local/data")
+		if err := client.DoContext(fasthttptrace.ContextFromRequestCtx(fctx), req, resp); err != nil {
+			fctx.Error(err.Error(), fasthttp.StatusBadGateway)
+			return
+		}
+		fctx.SetBody(resp.Body())
+	}
+	log.Fatal(fasthttp.ListenAndServe(":8080", fasthttptrace.WrapHandler(handler, fasthttptrace.WithServiceName("my-proxy"))))
+}
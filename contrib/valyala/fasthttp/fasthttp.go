@@ -0,0 +1,116 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+// Package fasthttp provides functions to trace the valyala/fasthttp package (https://github.com/valyala/fasthttp).
+//
+// The request handlers wrapped with WrapHandler start a span for each request,
+// which is a child of the trace propagated through its headers, if any. The
+// handlers can get the context holding it with ContextFromRequestCtx, e.g. to
+// pass it to the Client returned by WrapClient, which traces the outgoing
+// requests and propagates their trace through their headers.
+package fasthttp // import "gopkg.in/DataDog/dd-trace-go.v1/contrib/valyala/fasthttp"
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/namingschema"
+
+	"github.com/valyala/fasthttp"
+)
+
+const componentName = "fasthttp"
+
+// contextKey is the key of the user value of the requests holding their
+// context, see ContextFromRequestCtx. The user values of fasthttp are keyed by
+// strings.
+const contextKey = "__dd_trace_context"
+
+// WrapHandler returns a request handler tracing the requests handled by h.
+func WrapHandler(h fasthttp.RequestHandler, opts ...Option) fasthttp.RequestHandler {
+	cfg := newConfig(opts...)
+	log.Debug("contrib/valyala/fasthttp: Wrapping Handler: %#v", cfg)
+	appsecEnabled := appsec.Enabled()
+	return func(fctx *fasthttp.RequestCtx) {
+		if cfg.ignoreRequest != nil && cfg.ignoreRequest(fctx) {
+			h(fctx)
+			return
+		}
+		startOpts := []ddtrace.StartSpanOption{
+			tracer.Tag(ext.Component, componentName),
+			tracer.SpanType(ext.SpanTypeWeb),
+			tracer.ServiceName(cfg.serviceName),
+			tracer.ResourceName(cfg.resourceNamer(fctx)),
+			tracer.Tag(ext.SpanKind, ext.SpanKindServer),
+			tracer.Tag(ext.HTTPMethod, string(fctx.Method())),
+			tracer.Tag(ext.HTTPURL, requestURL(&fctx.Request)),
+			tracer.Tag(ext.HTTPUserAgent, string(fctx.UserAgent())),
+			tracer.Measured(),
+		}
+		if host := fctx.Host(); len(host) > 0 {
+			startOpts = append(startOpts, tracer.Tag("http.host", string(host)))
+		}
+		if !math.IsNaN(cfg.analyticsRate) {
+			startOpts = append(startOpts, tracer.Tag(ext.EventSampleRate, cfg.analyticsRate))
+		}
+		if spanctx, err := tracer.Extract(NewRequestHeaderCarrier(&fctx.Request.Header)); err == nil {
+			startOpts = append(startOpts, tracer.ChildOf(spanctx))
+		}
+		startOpts = append(startOpts, cfg.spanOpts...)
+		span, ctx := tracer.StartSpanFromContext(context.Background(), namingschema.HTTPServerOp("http.request"), startOpts...)
+		defer span.Finish()
+
+		var (
+			afterAppSec func(status int)
+			blocked     bool
+		)
+		if appsecEnabled {
+			ctx, afterAppSec, blocked = useAppSec(ctx, fctx, span)
+		}
+		fctx.SetUserValue(contextKey, ctx)
+		if !blocked {
+			h(fctx)
+		}
+
+		status := fctx.Response.StatusCode()
+		span.SetTag(ext.HTTPCode, strconv.Itoa(status))
+		if afterAppSec != nil {
+			afterAppSec(status)
+		}
+		if cfg.isStatusError(status) {
+			span.SetTag(ext.Error, fmt.Errorf("%d: %s", status, http.StatusText(status)))
+		}
+	}
+}
+
+// ContextFromRequestCtx returns the context holding the span of the request of
+// fctx, started by a handler wrapped with WrapHandler, in order to start child
+// spans or to pass it to the traced clients. It returns context.Background()
+// when the request is not traced. The returned context remains valid once the
+// request is handled, unlike fctx which is reused by fasthttp.
+func ContextFromRequestCtx(fctx *fasthttp.RequestCtx) context.Context {
+	if ctx, ok := fctx.UserValue(contextKey).(context.Context); ok {
+		return ctx
+	}
+	return context.Background()
+}
+
+// requestURL returns the URL of req without its query string, which may hold
+// sensitive data.
+func requestURL(req *fasthttp.Request) string {
+	uri := req.URI()
+	if host := uri.Host(); len(host) > 0 {
+		return string(uri.Scheme()) + "://" + string(host) + string(uri.PathOriginal())
+	}
+	return string(uri.PathOriginal())
+}
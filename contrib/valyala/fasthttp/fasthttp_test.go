@@ -0,0 +1,169 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package fasthttp
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttputil"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+)
+
+// startServer serves h on an in-memory listener and returns a client sending
+// requests to it.
+func startServer(t *testing.T, h fasthttp.RequestHandler) *fasthttp.Client {
+	ln := fasthttputil.NewInmemoryListener()
+	srv := &fasthttp.Server{Handler: h}
+	go srv.Serve(ln)
+	t.Cleanup(func() { ln.Close() })
+	return &fasthttp.Client{
+		Dial: func(_ string) (net.Conn, error) { return ln.Dial() },
+	}
+}
+
+func get(t *testing.T, c Doer, uri string) int {
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(resp)
+	req.SetRequestURI(uri)
+	require.NoError(t, c.Do(req, resp))
+	return resp.StatusCode()
+}
+
+func TestWrapHandler(t *testing.T) {
+	assert := assert.New(t)
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	c := startServer(t, WrapHandler(func(fctx *fasthttp.RequestCtx) {
+		span, ok := tracer.SpanFromContext(ContextFromRequestCtx(fctx))
+		assert.True(ok)
+		span.SetTag("handled", true)
+		fctx.SetStatusCode(fasthttp.StatusCreated)
+	}, WithServiceName("my-service")))
+
+	assert.Equal(fasthttp.StatusCreated, get(t, c, "http://example.com/users/123?secret=1"))
+
+	spans := mt.FinishedSpans()
+	require.Len(t, spans, 1)
+	s := spans[0]
+	assert.Equal("http.request", s.OperationName())
+	assert.Equal("my-service", s.Tag(ext.ServiceName))
+	assert.Equal("GET /users/123", s.Tag(ext.ResourceName))
+	assert.Equal(ext.SpanTypeWeb, s.Tag(ext.SpanType))
+	assert.Equal(ext.SpanKindServer, s.Tag(ext.SpanKind))
+	assert.Equal("GET", s.Tag(ext.HTTPMethod))
+	assert.Equal("http://example.com/users/123", s.Tag(ext.HTTPURL))
+	assert.Equal("example.com", s.Tag("http.host"))
+	assert.Equal("201", s.Tag(ext.HTTPCode))
+	assert.Equal(true, s.Tag("handled"))
+	assert.Nil(s.Tag(ext.Error))
+}
+
+func TestWrapHandlerError(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	c := startServer(t, WrapHandler(func(fctx *fasthttp.RequestCtx) {
+		fctx.SetStatusCode(fasthttp.StatusServiceUnavailable)
+	}))
+	assert.Equal(t, fasthttp.StatusServiceUnavailable, get(t, c, "http://example.com/"))
+
+	spans := mt.FinishedSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "fasthttp", spans[0].Tag(ext.ServiceName))
+	assert.Equal(t, "503", spans[0].Tag(ext.HTTPCode))
+	assert.NotNil(t, spans[0].Tag(ext.Error))
+}
+
+func TestWrapHandlerIgnoreRequest(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	c := startServer(t, WrapHandler(func(fctx *fasthttp.RequestCtx) {
+		assert.Equal(t, "/health", string(fctx.Path()))
+	}, WithIgnoreRequest(func(fctx *fasthttp.RequestCtx) bool {
+		return string(fctx.Path()) == "/health"
+	})))
+	assert.Equal(t, fasthttp.StatusOK, get(t, c, "http://example.com/health"))
+	assert.Empty(t, mt.FinishedSpans())
+}
+
+func TestClient(t *testing.T) {
+	assert := assert.New(t)
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	c := WrapClient(startServer(t, WrapHandler(func(fctx *fasthttp.RequestCtx) {
+		if string(fctx.Path()) == "/fail" {
+			fctx.SetStatusCode(fasthttp.StatusInternalServerError)
+		}
+	}, WithServiceName("server"))), ClientWithServiceName("client"))
+
+	assert.Equal(fasthttp.StatusOK, get(t, c, "http://example.com/ok"))
+	spans := mt.FinishedSpans()
+	require.Len(t, spans, 2)
+	server, client := spans[0], spans[1]
+	assert.Equal("server", server.Tag(ext.ServiceName))
+	assert.Equal("client", client.Tag(ext.ServiceName))
+	assert.Equal("http.request", client.Tag(ext.ResourceName))
+	assert.Equal(ext.SpanTypeHTTP, client.Tag(ext.SpanType))
+	assert.Equal(ext.SpanKindClient, client.Tag(ext.SpanKind))
+	assert.Equal("http://example.com/ok", client.Tag(ext.HTTPURL))
+	assert.Equal("200", client.Tag(ext.HTTPCode))
+	assert.Equal(client.TraceID(), server.TraceID())
+	assert.Equal(client.SpanID(), server.ParentID())
+
+	mt.Reset()
+	assert.Equal(fasthttp.StatusInternalServerError, get(t, c, "http://example.com/fail"))
+	spans = mt.FinishedSpans()
+	require.Len(t, spans, 2)
+	assert.Equal("500", spans[1].Tag(ext.HTTPCode))
+	assert.NotNil(spans[1].Tag(ext.Error))
+}
+
+func TestClientDoContext(t *testing.T) {
+	assert := assert.New(t)
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	c := WrapClient(startServer(t, func(fctx *fasthttp.RequestCtx) {}))
+	parent, ctx := tracer.StartSpanFromContext(context.Background(), "parent")
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(resp)
+	req.SetRequestURI("http://example.com/")
+	require.NoError(t, c.DoContext(ctx, req, resp))
+	parent.Finish()
+
+	spans := mt.FinishedSpans()
+	require.Len(t, spans, 2)
+	assert.Equal(spans[1].SpanID(), spans[0].ParentID())
+}
+
+func TestRequestHeaderCarrier(t *testing.T) {
+	var h fasthttp.RequestHeader
+	c := NewRequestHeaderCarrier(&h)
+	c.Set("x-datadog-trace-id", "1")
+	c.Set("x-datadog-parent-id", "2")
+	got := make(map[string]string)
+	require.NoError(t, c.ForeachKey(func(k, v string) error {
+		got[k] = v
+		return nil
+	}))
+	assert.Equal(t, "1", got["X-Datadog-Trace-Id"])
+	assert.Equal(t, "2", got["X-Datadog-Parent-Id"])
+}
@@ -0,0 +1,43 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package fasthttp
+
+import (
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+
+	"github.com/valyala/fasthttp"
+)
+
+// A RequestHeaderCarrier injects and extracts traces from a fasthttp.RequestHeader.
+type RequestHeaderCarrier struct {
+	header *fasthttp.RequestHeader
+}
+
+var _ interface {
+	tracer.TextMapReader
+	tracer.TextMapWriter
+} = (*RequestHeaderCarrier)(nil)
+
+// NewRequestHeaderCarrier creates a new RequestHeaderCarrier.
+func NewRequestHeaderCarrier(header *fasthttp.RequestHeader) RequestHeaderCarrier {
+	return RequestHeaderCarrier{header}
+}
+
+// ForeachKey iterates over every header.
+func (c RequestHeaderCarrier) ForeachKey(handler func(key, val string) error) error {
+	var err error
+	c.header.VisitAll(func(k, v []byte) {
+		if err == nil {
+			err = handler(string(k), string(v))
+		}
+	})
+	return err
+}
+
+// Set sets a header.
+func (c RequestHeaderCarrier) Set(key, val string) {
+	c.header.Set(key, val)
+}
@@ -0,0 +1,191 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package fasthttp
+
+import (
+	"math"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
+
+	"github.com/valyala/fasthttp"
+)
+
+type config struct {
+	serviceName   string
+	analyticsRate float64
+	isStatusError func(statusCode int) bool
+	resourceNamer func(*fasthttp.RequestCtx) string
+	ignoreRequest func(*fasthttp.RequestCtx) bool
+	spanOpts      []ddtrace.StartSpanOption
+}
+
+// Option represents an option that can be passed to WrapHandler.
+type Option func(*config)
+
+func newConfig(opts ...Option) *config {
+	cfg := &config{
+		serviceName:   "fasthttp",
+		isStatusError: isServerError,
+		resourceNamer: defaultResourceNamer,
+	}
+	if svc := globalconfig.ServiceName(); svc != "" {
+		cfg.serviceName = svc
+	}
+	if internal.BoolEnv("DD_TRACE_FASTHTTP_ANALYTICS_ENABLED", false) {
+		cfg.analyticsRate = 1.0
+	} else {
+		cfg.analyticsRate = globalconfig.AnalyticsRate()
+	}
+	for _, fn := range opts {
+		fn(cfg)
+	}
+	return cfg
+}
+
+// WithServiceName sets the given service name for the handler.
+func WithServiceName(name string) Option {
+	return func(cfg *config) {
+		cfg.serviceName = name
+	}
+}
+
+// WithAnalytics enables Trace Analytics for all started spans.
+func WithAnalytics(on bool) Option {
+	return func(cfg *config) {
+		if on {
+			cfg.analyticsRate = 1.0
+		} else {
+			cfg.analyticsRate = math.NaN()
+		}
+	}
+}
+
+// WithAnalyticsRate sets the sampling rate for Trace Analytics events
+// correlated to started spans.
+func WithAnalyticsRate(rate float64) Option {
+	return func(cfg *config) {
+		if rate >= 0.0 && rate <= 1.0 {
+			cfg.analyticsRate = rate
+		} else {
+			cfg.analyticsRate = math.NaN()
+		}
+	}
+}
+
+// WithStatusCheck sets the function telling whether a response status code is
+// an error. By default, the 5XX status codes are.
+func WithStatusCheck(fn func(statusCode int) bool) Option {
+	return func(cfg *config) {
+		cfg.isStatusError = fn
+	}
+}
+
+// WithResourceNamer sets the function naming the resource of the requests. By
+// default, it is made of their method and path, e.g. "GET /users/123": routers
+// should name it after the route of the requests instead, to limit the number
+// of resources.
+func WithResourceNamer(fn func(*fasthttp.RequestCtx) string) Option {
+	return func(cfg *config) {
+		cfg.resourceNamer = fn
+	}
+}
+
+// WithIgnoreRequest sets a function which determines if tracing will be
+// skipped for a given request, e.g. health checks.
+func WithIgnoreRequest(fn func(*fasthttp.RequestCtx) bool) Option {
+	return func(cfg *config) {
+		cfg.ignoreRequest = fn
+	}
+}
+
+// WithSpanOptions applies the given set of options to the spans started by
+// the handler.
+func WithSpanOptions(opts ...ddtrace.StartSpanOption) Option {
+	return func(cfg *config) {
+		cfg.spanOpts = opts
+	}
+}
+
+func defaultResourceNamer(fctx *fasthttp.RequestCtx) string {
+	return string(fctx.Method()) + " " + string(fctx.Path())
+}
+
+func isServerError(statusCode int) bool {
+	return statusCode >= 500 && statusCode < 600
+}
+
+type clientConfig struct {
+	serviceName   string
+	analyticsRate float64
+	resourceNamer func(*fasthttp.Request) string
+	spanOpts      []ddtrace.StartSpanOption
+}
+
+// ClientOption represents an option that can be passed to WrapClient.
+type ClientOption func(*clientConfig)
+
+func newClientConfig(opts ...ClientOption) *clientConfig {
+	cfg := &clientConfig{
+		resourceNamer: func(_ *fasthttp.Request) string { return "http.request" },
+	}
+	if internal.BoolEnv("DD_TRACE_FASTHTTP_ANALYTICS_ENABLED", false) {
+		cfg.analyticsRate = 1.0
+	} else {
+		cfg.analyticsRate = globalconfig.AnalyticsRate()
+	}
+	for _, fn := range opts {
+		fn(cfg)
+	}
+	return cfg
+}
+
+// ClientWithServiceName sets the given service name for the client.
+func ClientWithServiceName(name string) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.serviceName = name
+	}
+}
+
+// ClientWithAnalytics enables Trace Analytics for all started spans.
+func ClientWithAnalytics(on bool) ClientOption {
+	return func(cfg *clientConfig) {
+		if on {
+			cfg.analyticsRate = 1.0
+		} else {
+			cfg.analyticsRate = math.NaN()
+		}
+	}
+}
+
+// ClientWithAnalyticsRate sets the sampling rate for Trace Analytics events
+// correlated to started spans.
+func ClientWithAnalyticsRate(rate float64) ClientOption {
+	return func(cfg *clientConfig) {
+		if rate >= 0.0 && rate <= 1.0 {
+			cfg.analyticsRate = rate
+		} else {
+			cfg.analyticsRate = math.NaN()
+		}
+	}
+}
+
+// ClientWithResourceNamer sets the function naming the resource of the
+// requests. It is "http.request" by default.
+func ClientWithResourceNamer(fn func(*fasthttp.Request) string) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.resourceNamer = fn
+	}
+}
+
+// ClientWithSpanOptions applies the given set of options to the spans started
+// by the client.
+func ClientWithSpanOptions(opts ...ddtrace.StartSpanOption) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.spanOpts = opts
+	}
+}
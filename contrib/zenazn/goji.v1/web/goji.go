@@ -20,6 +20,8 @@ import (
 	"github.com/zenazn/goji/web"
 )
 
+const componentName = "goji"
+
 // Middleware returns a goji middleware function that will trace incoming requests.
 // If goji's Router middleware is also installed, the tracer will be able to determine
 // the original route name (e.g. "/user/:id"), and include it as part of the traces' resource
@@ -53,6 +55,7 @@ func Middleware(opts ...Option) func(*web.C, http.Handler) http.Handler {
 				Resource:   resource,
 				FinishOpts: cfg.finishOpts,
 				SpanOpts:   cfg.spanOpts,
+				Component:  componentName,
 			})
 		})
 	}
@@ -64,6 +64,28 @@ type Span interface {
 	Context() SpanContext
 }
 
+// SpanWithMetrics is implemented by the spans which can hold numeric metadata,
+// such as the spans of the tracer and of the mock tracer. It is kept apart from
+// Span so that the existing implementations of the latter remain valid.
+type SpanWithMetrics interface {
+	Span
+
+	// SetMetric sets a numeric measurement, such as a payload size or a retry
+	// count, as metadata on the span, which can then be queried numerically.
+	SetMetric(key string, value float64)
+}
+
+// SpanWithLinks is implemented by the spans which can be linked to other spans
+// after being started. Like SpanWithMetrics, it extends Span without changing it.
+type SpanWithLinks interface {
+	Span
+
+	// AddLink links the span to the span identified by ctx, to represent a causal
+	// relationship which isn't a parent-child one. The attributes are optional and
+	// describe the link. Links added after the span is finished are ignored.
+	AddLink(ctx SpanContext, attributes map[string]string)
+}
+
 // SpanContext represents a span state that can propagate to descendant spans
 // and across process boundaries. It contains all the information needed to
 // spawn a direct descendant of the span that it belongs to. It can be used
@@ -130,6 +152,27 @@ type StartSpanConfig struct {
 
 	// Context is the parent context where the span should be stored.
 	Context context.Context
+
+	// Links holds the links to other spans the new span is causally related to,
+	// without being their child.
+	Links []SpanLink
+}
+
+// SpanLink represents a causal relationship between two spans which isn't a
+// parent-child one, such as a batch consumer span aggregating messages coming
+// from many upstream traces.
+type SpanLink struct {
+	// TraceID is the trace ID of the linked span.
+	TraceID uint64 `json:"trace_id"`
+	// SpanID is the span ID of the linked span.
+	SpanID uint64 `json:"span_id"`
+	// Attributes holds optional key/value pairs describing the link.
+	Attributes map[string]string `json:"attributes,omitempty"`
+	// Tracestate holds the W3C tracestate of the linked span, if any.
+	Tracestate string `json:"tracestate,omitempty"`
+	// Flags holds the W3C trace flags of the linked span. The most significant
+	// bit is set when the flags are known.
+	Flags uint32 `json:"flags,omitempty"`
 }
 
 // Logger implementations are able to log given messages that the tracer or profiler might output.
@@ -78,4 +78,10 @@ const (
 
 	// SpanTypeGraphql marks a span as a graphql operation.
 	SpanTypeGraphQL = "graphql"
+
+	// SpanTypeServerless marks a span as a serverless function invocation.
+	SpanTypeServerless = "serverless"
+
+	// SpanTypeTest marks a span as a test run, reported to CI Visibility.
+	SpanTypeTest = "test"
 )
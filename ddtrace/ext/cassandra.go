@@ -26,4 +26,12 @@ const (
 
 	// CassandraPaginated specifies the tag name for paginated queries.
 	CassandraPaginated = "cassandra.paginated"
+
+	// CassandraContactPoints specifies the tag name for the comma-separated list of
+	// the contact points of the cluster.
+	CassandraContactPoints = "cassandra.contact_points"
+
+	// CassandraAttempt specifies the tag name for the index of the attempt at
+	// executing a query, zero for the first attempt and non-zero for retries.
+	CassandraAttempt = "cassandra.attempt"
 )
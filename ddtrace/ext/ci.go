@@ -0,0 +1,54 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package ext
+
+// CI Visibility tags describing the CI pipeline running the tests.
+const (
+	// CIProviderName is the name of the CI provider, e.g. "github".
+	CIProviderName = "ci.provider.name"
+
+	// CIPipelineID is the ID of the CI pipeline.
+	CIPipelineID = "ci.pipeline.id"
+
+	// CIPipelineName is the name of the CI pipeline.
+	CIPipelineName = "ci.pipeline.name"
+
+	// CIPipelineNumber is the number of the run of the CI pipeline.
+	CIPipelineNumber = "ci.pipeline.number"
+
+	// CIPipelineURL is the URL of the run of the CI pipeline.
+	CIPipelineURL = "ci.pipeline.url"
+
+	// CIJobName is the name of the CI job.
+	CIJobName = "ci.job.name"
+
+	// CIJobURL is the URL of the CI job.
+	CIJobURL = "ci.job.url"
+
+	// CIStageName is the name of the stage of the CI pipeline.
+	CIStageName = "ci.stage.name"
+
+	// CIWorkspacePath is the path of the workspace of the CI job.
+	CIWorkspacePath = "ci.workspace_path"
+)
+
+// CI Visibility tags describing the git commit under test.
+const (
+	// GitRepositoryURL is the URL of the git repository.
+	GitRepositoryURL = "git.repository_url"
+
+	// GitCommitSHA is the SHA of the git commit.
+	GitCommitSHA = "git.commit.sha"
+
+	// GitCommitMessage is the message of the git commit.
+	GitCommitMessage = "git.commit.message"
+
+	// GitBranch is the git branch.
+	GitBranch = "git.branch"
+
+	// GitTag is the git tag.
+	GitTag = "git.tag"
+)
@@ -18,4 +18,8 @@ const (
 	DBUser = "db.user"
 	// DBStatement records a database statement for the given database type.
 	DBStatement = "db.statement"
+	// DBOperation indicates the name of the operation being executed, e.g. "find" or "insert".
+	DBOperation = "db.operation"
+	// MongoDBCollection indicates the name of the MongoDB collection an operation is executed on.
+	MongoDBCollection = "db.mongodb.collection"
 )
@@ -0,0 +1,29 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+package ext
+
+// SpanKind is the tag describing the relationship between a span, its parent
+// and its children, as defined by OpenTelemetry.
+const SpanKind = "span.kind"
+
+// Values of the SpanKind tag.
+const (
+	// SpanKindServer marks a span handling a synchronous request from a remote client.
+	SpanKindServer = "server"
+
+	// SpanKindClient marks a span making a synchronous request to a remote service.
+	SpanKindClient = "client"
+
+	// SpanKindProducer marks a span sending a message to a broker.
+	SpanKindProducer = "producer"
+
+	// SpanKindConsumer marks a span processing a message received from a broker.
+	SpanKindConsumer = "consumer"
+
+	// SpanKindInternal marks a span for an operation which doesn't cross the
+	// boundaries of the process.
+	SpanKindInternal = "internal"
+)
@@ -47,6 +47,20 @@ const (
 	// See https://docs.datadoghq.com/tracing/trace_collection/tracing_naming_convention/#http-requests
 	HTTPRequestHeaders = "http.request.headers"
 
+	// HTTPRequestContentLength sets the size in bytes of the body of the HTTP request.
+	HTTPRequestContentLength = "http.request.content_length"
+
+	// HTTPResponseContentLength sets the size in bytes of the body of the HTTP response.
+	HTTPResponseContentLength = "http.response.content_length"
+
+	// HTTPRouteParams sets the HTTP route parameters partial tag
+	// This tag is meant to be composed, i.e http.route.params.id, http.route.params.name, etc...
+	HTTPRouteParams = "http.route.params"
+
+	// HTTPUpgraded sets the protocol the connection of the HTTP request was upgraded to,
+	// e.g. "websocket".
+	HTTPUpgraded = "http.upgraded"
+
 	// SpanName is a pseudo-key for setting a span's operation name by means of
 	// a tag. It is mostly here to facilitate vendor-agnostic frameworks like Opentracing
 	// and OpenCensus.
@@ -79,6 +93,10 @@ const (
 	// ErrorDetails holds details about an error which implements a formatter.
 	ErrorDetails = "error.details"
 
+	// ErrorFingerprint holds a fingerprint of an error, which is stable across
+	// its occurrences, see tracer.WithErrorTracking.
+	ErrorFingerprint = "error.fingerprint"
+
 	// Environment specifies the environment to use with a trace.
 	Environment = "env"
 
@@ -100,4 +118,10 @@ const (
 
 	// RuntimeID is a tag that contains a unique id for this process.
 	RuntimeID = "runtime-id"
+
+	// Component holds the name of the integration which created the span, e.g.
+	// "gin" or "redis". The spans of an integration are not created when it is
+	// disabled with DD_TRACE_<COMPONENT>_ENABLED=false or when it is listed in
+	// DD_TRACE_DISABLED_INTEGRATIONS.
+	Component = "component"
 )
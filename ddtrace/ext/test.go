@@ -0,0 +1,69 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package ext
+
+// CI Visibility tags of the test spans.
+const (
+	// TestName is the name of the test.
+	TestName = "test.name"
+
+	// TestSuite is the name of the suite of the test, e.g. its package.
+	TestSuite = "test.suite"
+
+	// TestFramework is the name of the framework running the test.
+	TestFramework = "test.framework"
+
+	// TestFrameworkVersion is the version of the framework running the test.
+	TestFrameworkVersion = "test.framework_version"
+
+	// TestType is the type of the test, e.g. TestTypeTest.
+	TestType = "test.type"
+
+	// TestStatus is the outcome of the test: TestStatusPass, TestStatusFail or TestStatusSkip.
+	TestStatus = "test.status"
+
+	// TestSkipReason is the reason why the test was skipped.
+	TestSkipReason = "test.skip_reason"
+)
+
+// Values of the TestType tag.
+const (
+	// TestTypeTest marks a span as a test, as opposed to a benchmark.
+	TestTypeTest = "test"
+
+	// TestTypeBenchmark marks a span as a benchmark.
+	TestTypeBenchmark = "benchmark"
+)
+
+// Values of the TestStatus tag.
+const (
+	// TestStatusPass marks a test as passed.
+	TestStatusPass = "pass"
+
+	// TestStatusFail marks a test as failed.
+	TestStatusFail = "fail"
+
+	// TestStatusSkip marks a test as skipped.
+	TestStatusSkip = "skip"
+)
+
+// SpanKindTest marks a span as a test run, as the value of the SpanKind tag.
+const SpanKindTest = "test"
+
+// Runtime and OS tags of the test spans.
+const (
+	// OSPlatform is the operating system running the tests.
+	OSPlatform = "os.platform"
+
+	// OSArchitecture is the architecture of the system running the tests.
+	OSArchitecture = "os.architecture"
+
+	// RuntimeName is the name of the runtime running the tests.
+	RuntimeName = "runtime.name"
+
+	// RuntimeVersion is the version of the runtime running the tests.
+	RuntimeVersion = "runtime.version"
+)
@@ -63,7 +63,11 @@ func (NoopTracer) Inject(context ddtrace.SpanContext, carrier interface{}) error
 // Stop implements ddtrace.Tracer.
 func (NoopTracer) Stop() {}
 
-var _ ddtrace.Span = (*NoopSpan)(nil)
+var (
+	_ ddtrace.Span            = (*NoopSpan)(nil)
+	_ ddtrace.SpanWithMetrics = (*NoopSpan)(nil)
+	_ ddtrace.SpanWithLinks   = (*NoopSpan)(nil)
+)
 
 // NoopSpan is an implementation of ddtrace.Span that is a no-op.
 type NoopSpan struct{}
@@ -71,6 +75,9 @@ type NoopSpan struct{}
 // SetTag implements ddtrace.Span.
 func (NoopSpan) SetTag(key string, value interface{}) {}
 
+// SetMetric implements ddtrace.SpanWithMetrics.
+func (NoopSpan) SetMetric(key string, value float64) {}
+
 // SetOperationName implements ddtrace.Span.
 func (NoopSpan) SetOperationName(operationName string) {}
 
@@ -89,6 +96,9 @@ func (NoopSpan) Tracer() ddtrace.Tracer { return NoopTracer{} }
 // Context implements ddtrace.Span.
 func (NoopSpan) Context() ddtrace.SpanContext { return NoopSpanContext{} }
 
+// AddLink implements ddtrace.SpanWithLinks.
+func (NoopSpan) AddLink(ctx ddtrace.SpanContext, attributes map[string]string) {}
+
 var _ ddtrace.SpanContext = (*NoopSpanContext)(nil)
 
 // NoopSpanContext is an implementation of ddtrace.SpanContext that is a no-op.
@@ -102,3 +112,29 @@ func (NoopSpanContext) TraceID() uint64 { return 0 }
 
 // ForeachBaggageItem implements ddtrace.SpanContext.
 func (NoopSpanContext) ForeachBaggageItem(handler func(k, v string) bool) {}
+
+var _ ddtrace.Span = (*DisabledSpan)(nil)
+
+// DisabledSpan is started by the tracers in place of the spans of the disabled
+// integrations, see ext.Component. It discards everything but carries the context
+// of its parent, if any, so that the spans started from it are attached to the
+// latter, as is the trace context it propagates.
+type DisabledSpan struct {
+	NoopSpan
+	parent ddtrace.SpanContext
+}
+
+// NewDisabledSpan returns a DisabledSpan child of the given span context, which is
+// nil for a root span.
+func NewDisabledSpan(parent ddtrace.SpanContext) *DisabledSpan {
+	if parent == nil {
+		parent = NoopSpanContext{}
+	}
+	return &DisabledSpan{parent: parent}
+}
+
+// Context implements ddtrace.Span. It returns the span context of the parent of the span.
+func (s *DisabledSpan) Context() ddtrace.SpanContext { return s.parent }
+
+// Tracer implements ddtrace.Span.
+func (s *DisabledSpan) Tracer() ddtrace.Tracer { return GetGlobalTracer() }
@@ -16,6 +16,8 @@ import (
 )
 
 var _ ddtrace.Span = (*mockspan)(nil)
+var _ ddtrace.SpanWithMetrics = (*mockspan)(nil)
+var _ ddtrace.SpanWithLinks = (*mockspan)(nil)
 var _ Span = (*mockspan)(nil)
 
 // Span is an interface that allows querying a span returned by the mock tracer.
@@ -47,6 +49,9 @@ type Span interface {
 	// Context returns the span's SpanContext.
 	Context() ddtrace.SpanContext
 
+	// Links returns a copy of the links to other spans held by this span.
+	Links() []ddtrace.SpanLink
+
 	// Stringer allows pretty-printing the span's fields for debugging.
 	fmt.Stringer
 }
@@ -89,10 +94,20 @@ func newSpan(t *mocktracer, operationName string, cfg *ddtrace.StartSpanConfig)
 			s.context.baggage[k] = v
 			return true
 		})
+	} else if cfg.Parent != nil {
+		// the parent was extracted by a custom propagator (see WithPropagator)
+		s.parentID = cfg.Parent.SpanID()
+		s.context.traceID = cfg.Parent.TraceID()
+		s.context.baggage = make(map[string]string)
+		cfg.Parent.ForeachBaggageItem(func(k, v string) bool {
+			s.context.baggage[k] = v
+			return true
+		})
 	}
 	for k, v := range cfg.Tags {
 		s.SetTag(k, v)
 	}
+	s.links = append(s.links, cfg.Links...)
 	return s
 }
 
@@ -107,6 +122,7 @@ type mockspan struct {
 	parentID  uint64
 	context   *spanContext
 	tracer    *mocktracer
+	links     []ddtrace.SpanLink
 }
 
 // SetTag sets a given tag on the span.
@@ -130,6 +146,12 @@ func (s *mockspan) SetTag(key string, value interface{}) {
 	s.tags[key] = value
 }
 
+// SetMetric sets a given metric on the span, which is then returned by Tag as
+// a float64.
+func (s *mockspan) SetMetric(key string, value float64) {
+	s.SetTag(key, value)
+}
+
 func (s *mockspan) FinishTime() time.Time {
 	s.RLock()
 	defer s.RUnlock()
@@ -215,6 +237,30 @@ func (s *mockspan) Finish(opts ...ddtrace.FinishOption) {
 	s.tracer.addFinishedSpan(s)
 }
 
+// AddLink implements ddtrace.SpanWithLinks.
+func (s *mockspan) AddLink(ctx ddtrace.SpanContext, attributes map[string]string) {
+	if ctx == nil {
+		return
+	}
+	s.Lock()
+	defer s.Unlock()
+	if s.finished {
+		return
+	}
+	s.links = append(s.links, ddtrace.SpanLink{
+		TraceID:    ctx.TraceID(),
+		SpanID:     ctx.SpanID(),
+		Attributes: attributes,
+	})
+}
+
+// Links returns a copy of the links to other spans held by this span.
+func (s *mockspan) Links() []ddtrace.SpanLink {
+	s.RLock()
+	defer s.RUnlock()
+	return append([]ddtrace.SpanLink(nil), s.links...)
+}
+
 // String implements fmt.Stringer.
 func (s *mockspan) String() string {
 	sc := s.context
@@ -233,27 +279,33 @@ baggage: %#v
 // Context returns the SpanContext of this Span.
 func (s *mockspan) Context() ddtrace.SpanContext { return s.context }
 
-// SetUser associates user information to the current trace which the
-// provided span belongs to. The options can be used to tune which user
-// bit of information gets monitored. This mockup only sets the user
-// information as span tags of the root span of the current trace.
-func (s *mockspan) SetUser(id string, opts ...tracer.UserMonitoringOption) {
+// Root returns the root span of the trace the span belongs to, as long as the
+// spans between them are not finished.
+func (s *mockspan) Root() tracer.Span {
+	s.tracer.RLock()
+	defer s.tracer.RUnlock()
 	// Walk the span up to the root parent span
-	openSpans := s.tracer.openSpans
-	var current Span = s
+	current := s
 	for {
 		pid := current.ParentID()
 		if pid == 0 {
 			break
 		}
-		parent, ok := openSpans[pid]
+		parent, ok := s.tracer.openSpans[pid].(*mockspan)
 		if !ok {
 			break
 		}
 		current = parent
 	}
+	return current
+}
 
-	root, ok := current.(*mockspan)
+// SetUser associates user information to the current trace which the
+// provided span belongs to. The options can be used to tune which user
+// bit of information gets monitored. This mockup only sets the user
+// information as span tags of the root span of the current trace.
+func (s *mockspan) SetUser(id string, opts ...tracer.UserMonitoringOption) {
+	root, ok := s.Root().(*mockspan)
 	if !ok {
 		return
 	}
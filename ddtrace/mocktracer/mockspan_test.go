@@ -79,6 +79,16 @@ func TestSpanSetTag(t *testing.T) {
 	assert.Equal("d", s.Tag("c"))
 }
 
+func TestSpanSetMetric(t *testing.T) {
+	s := basicSpan("http.request")
+	s.SetMetric("size", 1024)
+	s.SetMetric(ext.SamplingPriority, 2)
+
+	assert := assert.New(t)
+	assert.Equal(1024., s.Tag("size"))
+	assert.Equal(2, s.context.samplingPriority())
+}
+
 func TestSpanSetTagPriority(t *testing.T) {
 	assert := assert.New(t)
 	s := basicSpan("http.request")
@@ -167,6 +177,20 @@ func TestSpanContext(t *testing.T) {
 	})
 }
 
+func TestSpanLinks(t *testing.T) {
+	assert := assert.New(t)
+	upstream := basicSpan("kafka.produce")
+	link := ddtrace.SpanLink{TraceID: 1, SpanID: 2}
+	s := newSpan(&mocktracer{}, "batch.consume", &ddtrace.StartSpanConfig{
+		Links: []ddtrace.SpanLink{link},
+	})
+	s.AddLink(upstream.Context(), map[string]string{"a": "b"})
+	assert.Equal([]ddtrace.SpanLink{
+		link,
+		{TraceID: upstream.TraceID(), SpanID: upstream.SpanID(), Attributes: map[string]string{"a": "b"}},
+	}, s.Links())
+}
+
 func TestSpanFinish(t *testing.T) {
 	s := basicSpan("http.request")
 	want := errors.New("some error")
@@ -67,6 +67,14 @@ func (sc *spanContext) hasSamplingPriority() bool {
 	return sc.hasPriority
 }
 
+// SamplingPriority returns the sampling priority of the span and whether it was
+// set, as the span contexts of the tracer do.
+func (sc *spanContext) SamplingPriority() (int, bool) {
+	sc.RLock()
+	defer sc.RUnlock()
+	return sc.priority, sc.hasPriority
+}
+
 func (sc *spanContext) samplingPriority() int {
 	sc.RLock()
 	defer sc.RUnlock()
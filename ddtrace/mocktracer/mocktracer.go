@@ -18,8 +18,10 @@ import (
 	"sync"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/internal"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	maininternal "gopkg.in/DataDog/dd-trace-go.v1/internal"
 )
 
 var _ ddtrace.Tracer = (*mocktracer)(nil)
@@ -33,6 +35,15 @@ type Tracer interface {
 	// FinishedSpans returns the set of finished spans.
 	FinishedSpans() []Span
 
+	// Propagated returns the headers written by each successful call to Inject
+	// on a text map carrier, in call order.
+	Propagated() []map[string]string
+
+	// InjectedComments returns the SQL comments injected by each call to Inject
+	// on a tracer.SQLCommentCarrier while the mock tracer is started, in call
+	// order.
+	InjectedComments() []string
+
 	// Reset resets the spans and services recorded in the tracer. This is
 	// especially useful when running tests in a loop, where a clean start
 	// is desired for FinishedSpans calls.
@@ -43,26 +54,48 @@ type Tracer interface {
 	Stop()
 }
 
+// A StartOption customizes the mock tracer started by Start.
+type StartOption func(*mocktracer)
+
+// WithPropagator sets p as the propagator used by the mock tracer to inject and
+// extract span contexts, instead of the default one, which reads and writes the
+// tracer.DefaultTraceIDHeader, tracer.DefaultParentIDHeader,
+// tracer.DefaultPriorityHeader and tracer.DefaultBaggageHeaderPrefix headers.
+// Headers written by p are still recorded and returned by Propagated.
+func WithPropagator(p tracer.Propagator) StartOption {
+	return func(t *mocktracer) {
+		t.propagator = p
+	}
+}
+
 // Start sets the internal tracer to a mock and returns an interface
 // which allows querying it. Call Start at the beginning of your tests
 // to activate the mock tracer. When your test runs, use the returned
 // interface to query the tracer's state.
-func Start() Tracer {
+func Start(opts ...StartOption) Tracer {
 	t := newMockTracer()
+	for _, fn := range opts {
+		fn(t)
+	}
 	internal.SetGlobalTracer(t)
 	internal.Testing = true
 	return t
 }
 
 type mocktracer struct {
-	sync.RWMutex  // guards below spans
-	finishedSpans []Span
-	openSpans     map[uint64]Span
+	sync.RWMutex     // guards below spans, headers and comments
+	finishedSpans    []Span
+	openSpans        map[uint64]Span
+	propagated       []map[string]string
+	injectedComments []string
+	propagator       tracer.Propagator // replaces the default propagation, if set
+	integrations     *maininternal.Integrations
 }
 
 func newMockTracer() *mocktracer {
 	var t mocktracer
 	t.openSpans = make(map[uint64]Span)
+	t.integrations = maininternal.NewIntegrations()
 	return &t
 }
 
@@ -77,6 +110,9 @@ func (t *mocktracer) StartSpan(operationName string, opts ...ddtrace.StartSpanOp
 	for _, fn := range opts {
 		fn(&cfg)
 	}
+	if c, ok := cfg.Tags[ext.Component].(string); ok && !t.integrations.Enabled(c) {
+		return internal.NewDisabledSpan(cfg.Parent)
+	}
 	span := newSpan(t, operationName, &cfg)
 
 	t.Lock()
@@ -102,6 +138,18 @@ func (t *mocktracer) FinishedSpans() []Span {
 	return t.finishedSpans
 }
 
+func (t *mocktracer) Propagated() []map[string]string {
+	t.RLock()
+	defer t.RUnlock()
+	return t.propagated
+}
+
+func (t *mocktracer) InjectedComments() []string {
+	t.RLock()
+	defer t.RUnlock()
+	return t.injectedComments
+}
+
 func (t *mocktracer) Reset() {
 	t.Lock()
 	defer t.Unlock()
@@ -109,6 +157,8 @@ func (t *mocktracer) Reset() {
 		delete(t.openSpans, k)
 	}
 	t.finishedSpans = nil
+	t.propagated = nil
+	t.injectedComments = nil
 }
 
 func (t *mocktracer) addFinishedSpan(s Span) {
@@ -129,6 +179,9 @@ const (
 )
 
 func (t *mocktracer) Extract(carrier interface{}) (ddtrace.SpanContext, error) {
+	if t.propagator != nil {
+		return t.propagator.Extract(carrier)
+	}
 	reader, ok := carrier.(tracer.TextMapReader)
 	if !ok {
 		return nil, tracer.ErrInvalidCarrier
@@ -177,6 +230,22 @@ func (t *mocktracer) Inject(context ddtrace.SpanContext, carrier interface{}) er
 	if !ok {
 		return tracer.ErrInvalidCarrier
 	}
+	rec := &recordingWriter{TextMapWriter: writer, headers: make(map[string]string)}
+	if err := t.inject(context, rec); err != nil {
+		return err
+	}
+	t.Lock()
+	t.propagated = append(t.propagated, rec.headers)
+	t.Unlock()
+	return nil
+}
+
+// inject injects the span context into writer, using the configured propagator
+// if any.
+func (t *mocktracer) inject(context ddtrace.SpanContext, writer tracer.TextMapWriter) error {
+	if t.propagator != nil {
+		return t.propagator.Inject(context, writer)
+	}
 	ctx, ok := context.(*spanContext)
 	if !ok || ctx.traceID == 0 || ctx.spanID == 0 {
 		return tracer.ErrInvalidSpanContext
@@ -192,3 +261,24 @@ func (t *mocktracer) Inject(context ddtrace.SpanContext, carrier interface{}) er
 	})
 	return nil
 }
+
+// RecordSQLComment records the comment injected by a tracer.SQLCommentCarrier
+// while the mock tracer is the global tracer.
+func (t *mocktracer) RecordSQLComment(comment string) {
+	t.Lock()
+	t.injectedComments = append(t.injectedComments, comment)
+	t.Unlock()
+}
+
+// recordingWriter is a tracer.TextMapWriter recording the headers written to
+// the wrapped writer.
+type recordingWriter struct {
+	tracer.TextMapWriter
+	headers map[string]string
+}
+
+// Set implements tracer.TextMapWriter.
+func (w *recordingWriter) Set(key, val string) {
+	w.headers[key] = val
+	w.TextMapWriter.Set(key, val)
+}
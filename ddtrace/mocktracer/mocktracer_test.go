@@ -6,6 +6,7 @@
 package mocktracer
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
@@ -72,6 +73,22 @@ func TestTracerStartSpan(t *testing.T) {
 	})
 }
 
+func TestTracerStartSpanDisabledIntegration(t *testing.T) {
+	t.Setenv("DD_TRACE_GIN_ENABLED", "false")
+	mt := newMockTracer()
+	root := mt.StartSpan("http.request", tracer.Tag(ext.Component, "http"))
+	disabled := mt.StartSpan("gin.request", tracer.Tag(ext.Component, "gin"), tracer.ChildOf(root.Context()))
+	child := mt.StartSpan("child", tracer.ChildOf(disabled.Context()))
+	child.Finish()
+	disabled.Finish()
+	root.Finish()
+
+	spans := mt.FinishedSpans()
+	assert.Len(t, spans, 2)
+	assert.Equal(t, "child", spans[0].OperationName())
+	assert.Equal(t, root.Context().SpanID(), spans[0].ParentID())
+}
+
 func TestTracerFinishedSpans(t *testing.T) {
 	mt := newMockTracer()
 	assert.Empty(t, mt.FinishedSpans())
@@ -267,3 +284,105 @@ func TestTracerExtract(t *testing.T) {
 		assert.Equal("B", got.baggageItem("a"))
 	})
 }
+
+func TestTracerPropagated(t *testing.T) {
+	assert := assert.New(t)
+	mt := newMockTracer()
+	sctx := &spanContext{traceID: 1, spanID: 2, baggage: map[string]string{"A": "B"}}
+	carrier := make(map[string]string)
+	carrier["other"] = "header"
+	assert.Nil(mt.Inject(sctx, tracer.TextMapCarrier(carrier)))
+	assert.NotNil(mt.Inject(&spanContext{}, tracer.TextMapCarrier(carrier)))
+
+	assert.Equal([]map[string]string{{
+		traceHeader:         "1",
+		spanHeader:          "2",
+		baggagePrefix + "A": "B",
+	}}, mt.Propagated())
+
+	mt.Reset()
+	assert.Empty(mt.Propagated())
+}
+
+func TestTracerInjectedComments(t *testing.T) {
+	assert := assert.New(t)
+	mt := Start()
+	defer mt.Stop()
+	sctx := &spanContext{traceID: 1, spanID: 2}
+
+	carrier := &tracer.SQLCommentCarrier{Query: "SELECT 1", Mode: tracer.SQLInjectionModeService, DBServiceName: "db"}
+	assert.Nil(carrier.Inject(sctx))
+	assert.Equal("/*dddbs='db'*/ SELECT 1", carrier.Query)
+
+	carrier = &tracer.SQLCommentCarrier{Query: "SELECT 1", Mode: tracer.SQLInjectionDisabled, DBServiceName: "db"}
+	assert.Nil(carrier.Inject(sctx))
+	assert.Equal("SELECT 1", carrier.Query)
+
+	carrier = &tracer.SQLCommentCarrier{Query: "SELECT 1", Mode: tracer.SQLInjectionModeService, DBServiceName: "db"}
+	assert.Equal(tracer.ErrInvalidCarrier, mt.(*mocktracer).Inject(sctx, carrier))
+
+	assert.Equal([]string{"/*dddbs='db'*/"}, mt.InjectedComments())
+	assert.Empty(mt.Propagated())
+
+	mt.Reset()
+	assert.Empty(mt.InjectedComments())
+}
+
+// testPropagator propagates span contexts through a single header.
+type testPropagator struct{}
+
+func (testPropagator) Inject(ctx ddtrace.SpanContext, carrier interface{}) error {
+	writer, ok := carrier.(tracer.TextMapWriter)
+	if !ok {
+		return tracer.ErrInvalidCarrier
+	}
+	writer.Set("x-test-ids", fmt.Sprintf("%d-%d", ctx.TraceID(), ctx.SpanID()))
+	return nil
+}
+
+func (testPropagator) Extract(carrier interface{}) (ddtrace.SpanContext, error) {
+	reader, ok := carrier.(tracer.TextMapReader)
+	if !ok {
+		return nil, tracer.ErrInvalidCarrier
+	}
+	var sc testSpanContext
+	err := reader.ForeachKey(func(k, v string) error {
+		if k == "x-test-ids" {
+			_, err := fmt.Sscanf(v, "%d-%d", &sc.traceID, &sc.spanID)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if sc.traceID == 0 || sc.spanID == 0 {
+		return nil, tracer.ErrSpanContextNotFound
+	}
+	return sc, nil
+}
+
+type testSpanContext struct{ traceID, spanID uint64 }
+
+func (sc testSpanContext) TraceID() uint64                           { return sc.traceID }
+func (sc testSpanContext) SpanID() uint64                            { return sc.spanID }
+func (sc testSpanContext) ForeachBaggageItem(func(k, v string) bool) {}
+
+func TestTracerWithPropagator(t *testing.T) {
+	assert := assert.New(t)
+	mt := Start(WithPropagator(testPropagator{}))
+	defer mt.Stop()
+
+	carrier := tracer.TextMapCarrier(make(map[string]string))
+	assert.Nil(tracer.Inject(&spanContext{traceID: 1, spanID: 2}, carrier))
+	assert.Equal([]map[string]string{{"x-test-ids": "1-2"}}, mt.Propagated())
+
+	sctx, err := tracer.Extract(carrier)
+	assert.Nil(err)
+	span := tracer.StartSpan("child", tracer.ChildOf(sctx))
+	span.Finish()
+	spans := mt.FinishedSpans()
+	assert.Len(spans, 1)
+	assert.Equal(uint64(1), spans[0].TraceID())
+	assert.Equal(uint64(2), spans[0].ParentID())
+}
@@ -0,0 +1,35 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+package opentelemetry_test
+
+import (
+	"context"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/opentelemetry"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+func Example() {
+	// Start the Datadog tracer and use it as the OpenTelemetry TracerProvider.
+	provider := opentelemetry.NewTracerProvider(tracer.WithService("my-service"))
+	defer provider.Shutdown()
+	otel.SetTracerProvider(provider)
+
+	// Spans started with the OpenTelemetry API are Datadog spans.
+	ctx, span := otel.Tracer("my-library").Start(context.Background(), "web.request",
+		oteltrace.WithSpanKind(oteltrace.SpanKindServer),
+		oteltrace.WithAttributes(attribute.String("resource.name", "/home")),
+	)
+	defer span.End()
+
+	// They can be the parents of spans started with the Datadog API.
+	child, _ := tracer.StartSpanFromContext(ctx, "db.query")
+	child.Finish()
+}
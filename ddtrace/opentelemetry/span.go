@@ -0,0 +1,157 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+package opentelemetry
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+var _ oteltrace.Span = (*span)(nil)
+
+// span implements oteltrace.Span on top of ddtrace.Span.
+type span struct {
+	ddtrace.Span
+	provider *TracerProvider
+
+	mu          sync.Mutex
+	finished    bool
+	statusCode  codes.Code
+	statusDescr string
+}
+
+// TracerProvider returns the provider which created the span.
+func (s *span) TracerProvider() oteltrace.TracerProvider { return s.provider }
+
+// SetName sets the operation name of the span.
+func (s *span) SetName(name string) { s.SetOperationName(name) }
+
+// End finishes the span. The span is marked as errored when its status was set
+// to codes.Error. Only the first call has an effect.
+func (s *span) End(options ...oteltrace.SpanEndOption) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.finished {
+		return
+	}
+	s.finished = true
+	cfg := oteltrace.NewSpanEndConfig(options...)
+	var opts []ddtrace.FinishOption
+	if ts := cfg.Timestamp(); !ts.IsZero() {
+		opts = append(opts, tracer.FinishTime(ts))
+	}
+	if s.statusCode == codes.Error {
+		opts = append(opts, tracer.WithError(errors.New(s.statusDescr)), tracer.NoDebugStack())
+	}
+	s.Finish(opts...)
+}
+
+// AddEvent does nothing: span events are not supported by Datadog spans.
+func (s *span) AddEvent(_ string, _ ...oteltrace.EventOption) {}
+
+// IsRecording reports whether the span was not ended yet.
+func (s *span) IsRecording() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !s.finished
+}
+
+// RecordError sets the message and the type of err as tags on the span. As in
+// OpenTelemetry, it does not change the status of the span: SetStatus must be
+// called to mark the span as errored.
+func (s *span) RecordError(err error, _ ...oteltrace.EventOption) {
+	if err == nil || !s.IsRecording() {
+		return
+	}
+	s.SetTag(ext.ErrorMsg, err.Error())
+	s.SetTag(ext.ErrorType, fmt.Sprintf("%T", err))
+}
+
+// samplingPriorityContext is implemented by the span contexts of the Datadog tracer.
+type samplingPriorityContext interface {
+	SamplingPriority() (p int, ok bool)
+}
+
+// SpanContext returns the OpenTelemetry span context matching the context of
+// the Datadog span. The upper 64 bits of its trace ID are zero. It is sampled
+// unless the trace was dropped.
+func (s *span) SpanContext() oteltrace.SpanContext {
+	ctx := s.Context()
+	var tid oteltrace.TraceID
+	var sid oteltrace.SpanID
+	binary.BigEndian.PutUint64(tid[8:], ctx.TraceID())
+	binary.BigEndian.PutUint64(sid[:], ctx.SpanID())
+	flags := oteltrace.FlagsSampled
+	if sc, ok := ctx.(samplingPriorityContext); ok {
+		if p, ok := sc.SamplingPriority(); ok && p <= 0 {
+			flags = 0
+		}
+	}
+	return oteltrace.NewSpanContext(oteltrace.SpanContextConfig{
+		TraceID:    tid,
+		SpanID:     sid,
+		TraceFlags: flags,
+	})
+}
+
+// SetStatus sets the status of the span, following the OpenTelemetry rules: the
+// codes.Ok status is final, and codes.Unset is ignored. The description is only
+// kept for the codes.Error status, and is used as the error message of the span.
+func (s *span) SetStatus(code codes.Code, description string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.finished || code == codes.Unset || s.statusCode == codes.Ok {
+		return
+	}
+	s.statusCode = code
+	if code == codes.Error {
+		s.statusDescr = description
+	} else {
+		s.statusDescr = ""
+	}
+}
+
+// SetAttributes sets the given attributes as tags on the span. The
+// operation.name attribute sets the operation name of the span instead.
+func (s *span) SetAttributes(kv ...attribute.KeyValue) {
+	for _, kv := range kv {
+		if kv.Key == operationNameKey {
+			s.SetOperationName(kv.Value.Emit())
+			continue
+		}
+		s.SetTag(toTag(kv))
+	}
+}
+
+// operationNameKey is the attribute setting the operation name of the span.
+const operationNameKey = attribute.Key("operation.name")
+
+// toTag returns the Datadog tag matching the attribute kv. Arrays are set as strings.
+func toTag(kv attribute.KeyValue) (string, interface{}) {
+	key := string(kv.Key)
+	switch kv.Value.Type() {
+	case attribute.BOOL:
+		return key, kv.Value.AsBool()
+	case attribute.INT64:
+		return key, kv.Value.AsInt64()
+	case attribute.FLOAT64:
+		return key, kv.Value.AsFloat64()
+	case attribute.STRING:
+		return key, kv.Value.AsString()
+	default:
+		return key, kv.Value.Emit()
+	}
+}
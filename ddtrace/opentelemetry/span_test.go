@@ -0,0 +1,125 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+package opentelemetry
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+func TestSpanEnd(t *testing.T) {
+	assert := assert.New(t)
+	mt := mocktracer.Start()
+	defer mt.Stop()
+	tr := newTestProvider().Tracer("test")
+
+	_, sp := tr.Start(context.Background(), "otel.op")
+	assert.True(sp.IsRecording())
+	sp.SetName("renamed")
+	sp.SetAttributes(attribute.String("operation.name", "renamed.again"))
+	end := time.Now().Add(time.Minute)
+	sp.End(oteltrace.WithTimestamp(end))
+	sp.End()
+	assert.False(sp.IsRecording())
+
+	spans := mt.FinishedSpans()
+	assert.Len(spans, 1)
+	assert.Equal("renamed.again", spans[0].OperationName())
+	assert.Nil(spans[0].Tag("operation.name"))
+	assert.Equal(end.UnixNano(), spans[0].FinishTime().UnixNano())
+}
+
+func TestSpanStatus(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+	tr := newTestProvider().Tracer("test")
+
+	for name, tt := range map[string]struct {
+		statuses []codes.Code
+		errored  bool
+	}{
+		"error":        {statuses: []codes.Code{codes.Error}, errored: true},
+		"unset":        {statuses: []codes.Code{codes.Error, codes.Unset}, errored: true},
+		"ok-is-final":  {statuses: []codes.Code{codes.Ok, codes.Error}},
+		"ok-overrides": {statuses: []codes.Code{codes.Error, codes.Ok}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			mt.Reset()
+			_, sp := tr.Start(context.Background(), "otel.op")
+			for _, code := range tt.statuses {
+				sp.SetStatus(code, "boom")
+			}
+			sp.End()
+			err, _ := mt.FinishedSpans()[0].Tag(ext.Error).(error)
+			if tt.errored {
+				assert.EqualError(t, err, "boom")
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
+
+func TestSpanRecordError(t *testing.T) {
+	assert := assert.New(t)
+	mt := mocktracer.Start()
+	defer mt.Stop()
+	tr := newTestProvider().Tracer("test")
+
+	_, sp := tr.Start(context.Background(), "otel.op")
+	sp.RecordError(nil)
+	sp.RecordError(errors.New("boom"))
+	sp.End()
+	sp.RecordError(errors.New("after end"))
+
+	s := mt.FinishedSpans()[0]
+	assert.Equal("boom", s.Tag(ext.ErrorMsg))
+	assert.Equal("*errors.errorString", s.Tag(ext.ErrorType))
+	assert.Nil(s.Tag(ext.Error))
+}
+
+func TestSpanContext(t *testing.T) {
+	assert := assert.New(t)
+	mt := mocktracer.Start()
+	defer mt.Stop()
+	p := newTestProvider()
+
+	_, sp := p.Tracer("test").Start(context.Background(), "otel.op")
+	defer sp.End()
+	sc := sp.SpanContext()
+	ddctx := sp.(*span).Context()
+	tid, sid := sc.TraceID(), sc.SpanID()
+	assert.True(sc.IsValid())
+	assert.True(sc.IsSampled())
+	assert.Equal(ddctx.TraceID(), binary.BigEndian.Uint64(tid[8:]))
+	assert.Equal(ddctx.SpanID(), binary.BigEndian.Uint64(sid[:]))
+	assert.Equal(p, sp.TracerProvider())
+}
+
+func TestSpanContextDropped(t *testing.T) {
+	p := NewTracerProvider(
+		tracer.WithLogStartup(false),
+		tracer.WithSamplingRules([]tracer.SamplingRule{tracer.RateRule(0)}),
+	)
+	defer p.Shutdown()
+
+	_, sp := p.Tracer("test").Start(context.Background(), "otel.op")
+	defer sp.End()
+	assert.True(t, sp.SpanContext().IsValid())
+	assert.False(t, sp.SpanContext().IsSampled())
+}
@@ -0,0 +1,101 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+package opentelemetry
+
+import (
+	"context"
+	"encoding/binary"
+	"strconv"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+var _ oteltrace.Tracer = (*oteltracer)(nil)
+
+// oteltracer implements oteltrace.Tracer on top of the Datadog tracer.
+type oteltracer struct {
+	provider *TracerProvider
+}
+
+// Start starts a Datadog span. Its parent is the span held by ctx, which can be
+// either a Datadog or an OpenTelemetry span, unless the trace.WithNewRoot option
+// is given. The returned context holds the started span for both the
+// OpenTelemetry API and tracer.SpanFromContext.
+func (t *oteltracer) Start(ctx context.Context, spanName string, opts ...oteltrace.SpanStartOption) (context.Context, oteltrace.Span) {
+	cfg := oteltrace.NewSpanStartConfig(opts...)
+	ddopts := make([]ddtrace.StartSpanOption, 0, 3+len(cfg.Attributes()))
+	if !cfg.NewRoot() {
+		if parent, ok := parentContext(ctx); ok {
+			ddopts = append(ddopts, tracer.ChildOf(parent))
+		}
+	}
+	if ts := cfg.Timestamp(); !ts.IsZero() {
+		ddopts = append(ddopts, tracer.StartTime(ts))
+	}
+	ddopts = append(ddopts, tracer.Tag(ext.SpanKind, spanKind(cfg.SpanKind())))
+	for _, kv := range cfg.Attributes() {
+		if kv.Key == operationNameKey {
+			spanName = kv.Value.Emit()
+			continue
+		}
+		k, v := toTag(kv)
+		ddopts = append(ddopts, tracer.Tag(k, v))
+	}
+	s := &span{
+		Span:     tracer.StartSpan(spanName, ddopts...),
+		provider: t.provider,
+	}
+	ctx = oteltrace.ContextWithSpan(ctx, s)
+	return tracer.ContextWithSpan(ctx, s.Span), s
+}
+
+// parentContext returns the context of the span held by ctx, if any. Datadog
+// spans, including the ones started by this package, take precedence over the
+// remote OpenTelemetry span contexts, which are converted to Datadog ones through
+// the Datadog propagation headers.
+func parentContext(ctx context.Context) (ddtrace.SpanContext, bool) {
+	if s, ok := tracer.SpanFromContext(ctx); ok {
+		return s.Context(), true
+	}
+	sc := oteltrace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return nil, false
+	}
+	tid, sid := sc.TraceID(), sc.SpanID()
+	priority := ext.PriorityAutoReject
+	if sc.IsSampled() {
+		priority = ext.PriorityAutoKeep
+	}
+	parent, err := tracer.Extract(tracer.TextMapCarrier{
+		tracer.DefaultTraceIDHeader:  strconv.FormatUint(binary.BigEndian.Uint64(tid[8:]), 10),
+		tracer.DefaultParentIDHeader: strconv.FormatUint(binary.BigEndian.Uint64(sid[:]), 10),
+		tracer.DefaultPriorityHeader: strconv.Itoa(priority),
+	})
+	if err != nil {
+		return nil, false
+	}
+	return parent, true
+}
+
+// spanKind returns the value of the span.kind tag matching kind.
+func spanKind(kind oteltrace.SpanKind) string {
+	switch oteltrace.ValidateSpanKind(kind) {
+	case oteltrace.SpanKindServer:
+		return ext.SpanKindServer
+	case oteltrace.SpanKindClient:
+		return ext.SpanKindClient
+	case oteltrace.SpanKindProducer:
+		return ext.SpanKindProducer
+	case oteltrace.SpanKindConsumer:
+		return ext.SpanKindConsumer
+	default:
+		return ext.SpanKindInternal
+	}
+}
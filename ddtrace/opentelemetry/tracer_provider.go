@@ -0,0 +1,80 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+// Package opentelemetry provides a wrapper on top of the Datadog tracer that can be used with OpenTelemetry.
+// It implements the go.opentelemetry.io/otel/trace.TracerProvider interface, so that code instrumented
+// with the OpenTelemetry API emits Datadog spans.
+//
+// Spans started through the OpenTelemetry API and through the Datadog API are
+// interoperable: the context returned by (trace.Tracer).Start holds the started
+// span for tracer.SpanFromContext too, and spans started from a context holding a
+// Datadog span are its children.
+//
+// The OpenTelemetry span kind is set as the span.kind tag, and attributes are set
+// as tags. The attributes service.name, resource.name, span.type and
+// operation.name set the service, resource, type and operation name of the span.
+// Span events are not supported and are dropped.
+package opentelemetry // import "gopkg.in/DataDog/dd-trace-go.v1/ddtrace/opentelemetry"
+
+import (
+	"errors"
+	"sync/atomic"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+var _ oteltrace.TracerProvider = (*TracerProvider)(nil)
+
+// TracerProvider provides implementation of OpenTelemetry TracerProvider interface.
+// TracerProvider provides Tracers that are used by OpenTelemetry instrumentation
+// to start Datadog spans.
+type TracerProvider struct {
+	tracer *oteltracer
+	// stopped is 1 once the provider is shut down.
+	stopped uint32
+}
+
+// NewTracerProvider starts the Datadog tracer with the given options and returns
+// a TracerProvider creating Datadog spans. It should be set as the global
+// OpenTelemetry TracerProvider with otel.SetTracerProvider, and shut down with
+// Shutdown once the program ends.
+func NewTracerProvider(opts ...tracer.StartOption) *TracerProvider {
+	tracer.Start(opts...)
+	p := &TracerProvider{}
+	p.tracer = &oteltracer{provider: p}
+	return p
+}
+
+// Tracer returns a Tracer starting spans with the Datadog tracer. The name and
+// options of the instrumentation library are ignored. Once the provider is shut
+// down, it returns a no-op Tracer.
+func (p *TracerProvider) Tracer(_ string, _ ...oteltrace.TracerOption) oteltrace.Tracer {
+	if atomic.LoadUint32(&p.stopped) != 0 {
+		return oteltrace.NewNoopTracerProvider().Tracer("")
+	}
+	return p.tracer
+}
+
+// errProviderShutdown is returned by Shutdown when the provider was already shut down.
+var errProviderShutdown = errors.New("opentelemetry: tracer provider already shut down")
+
+// Shutdown stops the Datadog tracer, flushing the finished spans. It returns an
+// error when called more than once.
+func (p *TracerProvider) Shutdown() error {
+	if !atomic.CompareAndSwapUint32(&p.stopped, 0, 1) {
+		return errProviderShutdown
+	}
+	tracer.Stop()
+	return nil
+}
+
+// ForceFlush flushes the finished spans to the agent.
+func (p *TracerProvider) ForceFlush() {
+	if atomic.LoadUint32(&p.stopped) == 0 {
+		tracer.Flush()
+	}
+}
@@ -0,0 +1,148 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+package opentelemetry
+
+import (
+	"context"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// newTestProvider returns a TracerProvider which doesn't start the Datadog tracer,
+// so that it can be used along with the mock tracer.
+func newTestProvider() *TracerProvider {
+	p := &TracerProvider{}
+	p.tracer = &oteltracer{provider: p}
+	return p
+}
+
+func TestStart(t *testing.T) {
+	assert := assert.New(t)
+	mt := mocktracer.Start()
+	defer mt.Stop()
+	tr := newTestProvider().Tracer("test")
+
+	start := time.Now().Add(-time.Minute)
+	_, sp := tr.Start(context.Background(), "otel.op",
+		oteltrace.WithSpanKind(oteltrace.SpanKindClient),
+		oteltrace.WithTimestamp(start),
+		oteltrace.WithAttributes(
+			attribute.String("operation.name", "http.request"),
+			attribute.String("service.name", "otel-svc"),
+			attribute.String("resource.name", "GET /"),
+			attribute.Int("http.status_code", 200),
+			attribute.Bool("cached", true),
+			attribute.StringSlice("tags", []string{"a", "b"}),
+		),
+	)
+	sp.SetAttributes(attribute.Float64("ratio", 0.5))
+	sp.End()
+
+	spans := mt.FinishedSpans()
+	assert.Len(spans, 1)
+	s := spans[0]
+	assert.Equal("http.request", s.OperationName())
+	assert.Equal("otel-svc", s.Tag(ext.ServiceName))
+	assert.Equal("GET /", s.Tag(ext.ResourceName))
+	assert.Equal(ext.SpanKindClient, s.Tag(ext.SpanKind))
+	assert.Equal(int64(200), s.Tag("http.status_code"))
+	assert.Equal(true, s.Tag("cached"))
+	assert.Equal("[a b]", s.Tag("tags"))
+	assert.Equal(0.5, s.Tag("ratio"))
+	assert.Equal(start.UnixNano(), s.StartTime().UnixNano())
+}
+
+func TestSpanKind(t *testing.T) {
+	for kind, want := range map[oteltrace.SpanKind]string{
+		oteltrace.SpanKindUnspecified: ext.SpanKindInternal,
+		oteltrace.SpanKindInternal:    ext.SpanKindInternal,
+		oteltrace.SpanKindServer:      ext.SpanKindServer,
+		oteltrace.SpanKindClient:      ext.SpanKindClient,
+		oteltrace.SpanKindProducer:    ext.SpanKindProducer,
+		oteltrace.SpanKindConsumer:    ext.SpanKindConsumer,
+	} {
+		assert.Equal(t, want, spanKind(kind))
+	}
+}
+
+func TestContextInterop(t *testing.T) {
+	assert := assert.New(t)
+	mt := mocktracer.Start()
+	defer mt.Stop()
+	tr := newTestProvider().Tracer("test")
+
+	ctx, root := tr.Start(context.Background(), "otel.root")
+	ddspan, ok := tracer.SpanFromContext(ctx)
+	assert.True(ok)
+	assert.Equal(root.(*span).Span, ddspan)
+
+	child, ctx := tracer.StartSpanFromContext(ctx, "dd.child")
+	_, grandchild := tr.Start(ctx, "otel.grandchild")
+	grandchild.End()
+	child.Finish()
+	root.End()
+
+	spans := mt.FinishedSpans()
+	assert.Len(spans, 3)
+	assert.Equal("otel.grandchild", spans[0].OperationName())
+	assert.Equal(spans[1].SpanID(), spans[0].ParentID())
+	assert.Equal(spans[2].SpanID(), spans[1].ParentID())
+	assert.Equal(spans[2].TraceID(), spans[0].TraceID())
+
+	t.Run("new-root", func(t *testing.T) {
+		mt.Reset()
+		_, sp := tr.Start(ctx, "otel.root", oteltrace.WithNewRoot())
+		sp.End()
+		assert.Zero(mt.FinishedSpans()[0].ParentID())
+	})
+}
+
+func TestRemoteParent(t *testing.T) {
+	assert := assert.New(t)
+	mt := mocktracer.Start()
+	defer mt.Stop()
+	tr := newTestProvider().Tracer("test")
+
+	var tid oteltrace.TraceID
+	var sid oteltrace.SpanID
+	binary.BigEndian.PutUint64(tid[8:], 42)
+	binary.BigEndian.PutUint64(sid[:], 24)
+	remote := oteltrace.NewSpanContext(oteltrace.SpanContextConfig{
+		TraceID:    tid,
+		SpanID:     sid,
+		TraceFlags: oteltrace.FlagsSampled,
+		Remote:     true,
+	})
+	ctx := oteltrace.ContextWithRemoteSpanContext(context.Background(), remote)
+	_, sp := tr.Start(ctx, "otel.op")
+	sp.End()
+
+	s := mt.FinishedSpans()[0]
+	assert.Equal(uint64(42), s.TraceID())
+	assert.Equal(uint64(24), s.ParentID())
+	assert.Equal(ext.PriorityAutoKeep, s.Tag(ext.SamplingPriority))
+}
+
+func TestShutdown(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+	p := newTestProvider()
+	assert.Equal(t, p.tracer, p.Tracer("test"))
+
+	assert.NoError(t, p.Shutdown())
+	assert.Error(t, p.Shutdown())
+	_, sp := p.Tracer("test").Start(context.Background(), "otel.op")
+	assert.False(t, sp.IsRecording())
+}
@@ -0,0 +1,31 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+// Package sampler provides the deterministic sampling used by the tracer, so
+// that libraries and applications can make head sampling decisions consistent
+// with the ones of the tracer, for example to skip computing expensive tags on
+// the traces the tracer is going to drop.
+package sampler // import "gopkg.in/DataDog/dd-trace-go.v1/ddtrace/sampler"
+
+import "math"
+
+// knuthFactor is the factor of the Knuth multiplicative hashing of the trace
+// IDs, which is the same as the one of the agent.
+const knuthFactor = uint64(1111111111111111111)
+
+// SampleByRate reports whether the trace of the given ID is kept when sampling
+// at the given rate, between 0 and 1. The decision only depends on the trace ID
+// and the rate, and is the one made by the tracer and the agent for the same
+// rate, as set for example with tracer.WithSamplingRules or the
+// DD_TRACE_SAMPLE_RATE environment variable. A trace kept at a given rate is
+// kept at any higher rate.
+//
+// The trace ID of a span can be obtained with span.Context().TraceID().
+func SampleByRate(traceID uint64, rate float64) bool {
+	if rate < 1 {
+		return traceID*knuthFactor < uint64(rate*math.MaxUint64)
+	}
+	return true
+}
@@ -0,0 +1,53 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package sampler
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSampleByRate(t *testing.T) {
+	t.Run("bounds", func(t *testing.T) {
+		for _, id := range []uint64{0, 1, 42, 1 << 63} {
+			assert.True(t, SampleByRate(id, 1))
+			assert.True(t, SampleByRate(id, 1.5))
+		}
+		for _, id := range []uint64{1, 42, 1 << 62} {
+			assert.False(t, SampleByRate(id, 0))
+		}
+	})
+
+	t.Run("deterministic", func(t *testing.T) {
+		id := rand.Uint64()
+		want := SampleByRate(id, 0.5)
+		for i := 0; i < 10; i++ {
+			assert.Equal(t, want, SampleByRate(id, 0.5))
+		}
+	})
+
+	t.Run("monotonic", func(t *testing.T) {
+		for i := 0; i < 1000; i++ {
+			id := rand.Uint64()
+			if SampleByRate(id, 0.2) {
+				assert.True(t, SampleByRate(id, 0.5), "trace %d kept at 0.2 but not at 0.5", id)
+			}
+		}
+	})
+
+	t.Run("rate", func(t *testing.T) {
+		const n = 100000
+		var kept int
+		for i := 0; i < n; i++ {
+			if SampleByRate(rand.Uint64(), 0.3) {
+				kept++
+			}
+		}
+		assert.InDelta(t, 0.3, float64(kept)/n, 0.02)
+	})
+}
@@ -0,0 +1,172 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package tracer
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/version"
+
+	"github.com/tinylib/msgp/msgp"
+)
+
+const (
+	// ciVisibilityIntakeSubdomain is the subdomain of the CI Visibility intake of the test spans.
+	ciVisibilityIntakeSubdomain = "citestcycle-intake"
+
+	// ciVisibilityIntakePath is the path of the CI Visibility intake of the test spans.
+	ciVisibilityIntakePath = "/api/v2/citestcycle"
+
+	// ciVisibilityEVPProxyPath is the path of the agent's proxy to the intakes of the Datadog
+	// backend, used to reach the CI Visibility intake through the agent.
+	ciVisibilityEVPProxyPath = "/evp_proxy/v2"
+
+	// ciVisibilityPayloadVersion is the version of the format of the CI Visibility payloads.
+	ciVisibilityPayloadVersion = 1
+)
+
+// ciVisibilityTransport is the transport of the tracer in CI Visibility mode. It sends the
+// traces to the CI Visibility intake, either through the agent or directly when agentless.
+type ciVisibilityTransport struct {
+	traceURL string            // the delivery URL for the test spans
+	client   *http.Client      // the HTTP client used in the POST
+	headers  map[string]string // the Transport headers
+	env      string            // the environment of the tests
+}
+
+var _ transport = (*ciVisibilityTransport)(nil)
+
+// newCIVisibilityTransport returns the transport sending the traces of the tracer with the given
+// configuration to the CI Visibility intake. When agentless, the intake of the DD_SITE Datadog site
+// is used, or the one at DD_CIVISIBILITY_AGENTLESS_URL, authenticated using DD_API_KEY.
+func newCIVisibilityTransport(c *config) *ciVisibilityTransport {
+	headers := map[string]string{
+		"Datadog-Meta-Lang":             "go",
+		"Datadog-Meta-Lang-Version":     strings.TrimPrefix(runtime.Version(), "go"),
+		"Datadog-Meta-Lang-Interpreter": runtime.Compiler + "-" + runtime.GOARCH + "-" + runtime.GOOS,
+		"Datadog-Meta-Tracer-Version":   version.Tag,
+		"Content-Type":                  "application/msgpack",
+	}
+	var traceURL string
+	if c.ciVisibilityAgentless {
+		if v := os.Getenv("DD_CIVISIBILITY_AGENTLESS_URL"); v != "" {
+			traceURL = strings.TrimSuffix(v, "/") + ciVisibilityIntakePath
+		} else {
+			site := "datadoghq.com"
+			if v := os.Getenv("DD_SITE"); v != "" {
+				site = v
+			}
+			traceURL = fmt.Sprintf("https://%s.%s%s", ciVisibilityIntakeSubdomain, site, ciVisibilityIntakePath)
+		}
+		headers["dd-api-key"] = os.Getenv("DD_API_KEY")
+	} else {
+		traceURL = fmt.Sprintf("http://%s%s%s", c.agentAddr, ciVisibilityEVPProxyPath, ciVisibilityIntakePath)
+		headers["X-Datadog-EVP-Subdomain"] = ciVisibilityIntakeSubdomain
+	}
+	return &ciVisibilityTransport{
+		traceURL: traceURL,
+		client:   c.httpClient,
+		headers:  headers,
+		env:      c.env,
+	}
+}
+
+// send converts the traces of the payload p into a CI Visibility payload and sends it.
+func (t *ciVisibilityTransport) send(p *payload) (body io.ReadCloser, err error) {
+	var traces spanLists
+	if err := msgp.Decode(p, &traces); err != nil {
+		return nil, fmt.Errorf("cannot decode traces: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := t.encode(&buf, traces); err != nil {
+		return nil, fmt.Errorf("cannot encode CI Visibility payload: %v", err)
+	}
+	req, err := http.NewRequest("POST", t.traceURL, &buf)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create http request: %v", err)
+	}
+	for header, value := range t.headers {
+		req.Header.Set(header, value)
+	}
+	response, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if code := response.StatusCode; code >= 400 {
+		msg := make([]byte, 1000)
+		n, _ := response.Body.Read(msg)
+		txt := http.StatusText(code)
+		if n > 0 {
+			return nil, &apiError{statusCode: code, msg: fmt.Sprintf("%s (Status: %s)", msg[:n], txt)}
+		}
+		return nil, &apiError{statusCode: code, msg: txt}
+	}
+	// the intake does not return sampling rates
+	return io.NopCloser(strings.NewReader("{}")), nil
+}
+
+// encode writes the CI Visibility payload of the given traces to w. It holds a test event for
+// each test span and a span event for every other span, along with the metadata of the tests.
+func (t *ciVisibilityTransport) encode(w io.Writer, traces spanLists) error {
+	mw := msgp.NewWriter(w)
+	mw.WriteMapHeader(3)
+	mw.WriteString("version")
+	mw.WriteInt(ciVisibilityPayloadVersion)
+	mw.WriteString("metadata")
+	mw.WriteMapHeader(1)
+	mw.WriteString("*")
+	meta := map[string]string{
+		"language":        "go",
+		"library_version": version.Tag,
+		"runtime-id":      globalconfig.RuntimeID(),
+	}
+	if t.env != "" {
+		meta["env"] = t.env
+	}
+	mw.WriteMapStrStr(meta)
+	mw.WriteString("events")
+	var n int
+	for _, trace := range traces {
+		n += len(trace)
+	}
+	mw.WriteArrayHeader(uint32(n))
+	for _, trace := range traces {
+		for _, s := range trace {
+			typ := "span"
+			if s.Type == ext.SpanTypeTest {
+				typ = "test"
+			}
+			mw.WriteMapHeader(3)
+			mw.WriteString("type")
+			mw.WriteString(typ)
+			mw.WriteString("version")
+			mw.WriteInt(ciVisibilityPayloadVersion)
+			mw.WriteString("content")
+			if err := s.EncodeMsg(mw); err != nil {
+				return err
+			}
+		}
+	}
+	return mw.Flush()
+}
+
+// sendStats is a no-op: the CI Visibility intake does not receive stats.
+func (t *ciVisibilityTransport) sendStats(_ *statsPayload) error {
+	return nil
+}
+
+func (t *ciVisibilityTransport) endpoint() string {
+	return t.traceURL
+}
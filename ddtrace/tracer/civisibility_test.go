@@ -0,0 +1,107 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package tracer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tinylib/msgp/msgp"
+)
+
+func TestCIVisibilityTransport(t *testing.T) {
+	var (
+		path, subdomain, apiKey string
+		payload                 map[string]interface{}
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/info" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		path = r.URL.Path
+		subdomain = r.Header.Get("X-Datadog-EVP-Subdomain")
+		apiKey = r.Header.Get("dd-api-key")
+		v, err := msgp.NewReader(r.Body).ReadIntf()
+		assert.NoError(t, err)
+		payload, _ = v.(map[string]interface{})
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	send := func(t *testing.T, c *config) {
+		test := newBasicSpan("testing.test")
+		test.Type = ext.SpanTypeTest
+		child := newBasicSpan("child")
+		p := newPayload()
+		require.NoError(t, p.push(spanList{test, child}))
+		rc, err := c.transport.send(p)
+		require.NoError(t, err)
+		rc.Close()
+	}
+
+	t.Run("agent", func(t *testing.T) {
+		c := newConfig(WithCIVisibility(true), WithAgentAddr(strings.TrimPrefix(srv.URL, "http://")), WithEnv("ci"))
+		assert.IsType(t, &ciVisibilityTransport{}, c.transport)
+		send(t, c)
+
+		assert.Equal(t, "/evp_proxy/v2/api/v2/citestcycle", path)
+		assert.Equal(t, "citestcycle-intake", subdomain)
+		assert.Empty(t, apiKey)
+		assert.EqualValues(t, 1, payload["version"])
+		meta := payload["metadata"].(map[string]interface{})["*"].(map[string]interface{})
+		assert.Equal(t, "go", meta["language"])
+		assert.Equal(t, "ci", meta["env"])
+		events := payload["events"].([]interface{})
+		require.Len(t, events, 2)
+		test, child := events[0].(map[string]interface{}), events[1].(map[string]interface{})
+		assert.Equal(t, "test", test["type"])
+		assert.Equal(t, "testing.test", test["content"].(map[string]interface{})["name"])
+		assert.Equal(t, "span", child["type"])
+		assert.Equal(t, "child", child["content"].(map[string]interface{})["name"])
+	})
+
+	t.Run("agentless", func(t *testing.T) {
+		t.Setenv("DD_CIVISIBILITY_ENABLED", "true")
+		t.Setenv("DD_CIVISIBILITY_AGENTLESS_ENABLED", "true")
+		t.Setenv("DD_CIVISIBILITY_AGENTLESS_URL", srv.URL)
+		t.Setenv("DD_API_KEY", "key")
+		c := newConfig(WithAgentAddr("127.9.9.9:8181"))
+		// there is no agent
+		assert.Zero(t, c.agent)
+		assert.False(t, c.canComputeStats())
+		send(t, c)
+
+		assert.Equal(t, "/api/v2/citestcycle", path)
+		assert.Empty(t, subdomain)
+		assert.Equal(t, "key", apiKey)
+	})
+
+	t.Run("site", func(t *testing.T) {
+		t.Setenv("DD_CIVISIBILITY_AGENTLESS_ENABLED", "true")
+		t.Setenv("DD_SITE", "datadoghq.eu")
+		c := newConfig(WithCIVisibility(true))
+		assert.Equal(t, "https://citestcycle-intake.datadoghq.eu/api/v2/citestcycle", c.transport.endpoint())
+	})
+
+	t.Run("error", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			http.Error(w, "invalid payload", http.StatusBadRequest)
+		}))
+		defer srv.Close()
+		c := newConfig(WithCIVisibility(true), WithAgentAddr(strings.TrimPrefix(srv.URL, "http://")))
+		p := newPayload()
+		require.NoError(t, p.push(spanList{newBasicSpan("testing.test")}))
+		_, err := c.transport.send(p)
+		assert.EqualError(t, err, "invalid payload\n (Status: Bad Request)")
+	})
+}
@@ -12,9 +12,15 @@ import (
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/internal"
 )
 
-type contextKey struct{}
+type (
+	contextKey         struct{}
+	spanTagsContextKey struct{}
+)
 
-var activeSpanKey = contextKey{}
+var (
+	activeSpanKey = contextKey{}
+	spanTagsKey   = spanTagsContextKey{}
+)
 
 // ContextWithSpan returns a copy of the given context which includes the span s.
 func ContextWithSpan(ctx context.Context, s Span) context.Context {
@@ -35,14 +41,56 @@ func SpanFromContext(ctx context.Context) (Span, bool) {
 	return &internal.NoopSpan{}, false
 }
 
+// ContextWithSpanTags returns a copy of the given context holding the given tags, along with
+// the ones held by ctx, which they override. The tags are set on all the spans started with
+// StartSpanFromContext from the returned context, or from the contexts derived from it, such as
+// the ones of the integrations, e.g. to tag all the spans of a request with its tenant:
+//
+//	ctx = tracer.ContextWithSpanTags(ctx, map[string]interface{}{"tenant": tenant})
+//
+// The options given to StartSpanFromContext take precedence over them.
+func ContextWithSpanTags(ctx context.Context, tags map[string]interface{}) context.Context {
+	parent := spanTagsFromContext(ctx)
+	merged := make(map[string]interface{}, len(parent)+len(tags))
+	for k, v := range parent {
+		merged[k] = v
+	}
+	for k, v := range tags {
+		merged[k] = v
+	}
+	return context.WithValue(ctx, spanTagsKey, merged)
+}
+
+// spanTagsFromContext returns the tags held by ctx, see ContextWithSpanTags. The returned
+// map must not be modified.
+func spanTagsFromContext(ctx context.Context) map[string]interface{} {
+	if ctx == nil {
+		return nil
+	}
+	tags, _ := ctx.Value(spanTagsKey).(map[string]interface{})
+	return tags
+}
+
 // StartSpanFromContext returns a new span with the given operation name and options. If a span
 // is found in the context, it will be used as the parent of the resulting span. If the ChildOf
 // option is passed, it will only be used as the parent if there is no span found in `ctx`.
+// The tags held by the context, see ContextWithSpanTags, are set on the span.
 func StartSpanFromContext(ctx context.Context, operationName string, opts ...StartSpanOption) (Span, context.Context) {
 	// copy opts in case the caller reuses the slice in parallel
-	// we will add at least 1, at most 2 items
-	optsLocal := make([]StartSpanOption, len(opts), len(opts)+2)
-	copy(optsLocal, opts)
+	// we will add at least 1, at most 3 items
+	optsLocal := make([]StartSpanOption, 0, len(opts)+3)
+	if tags := spanTagsFromContext(ctx); len(tags) > 0 {
+		// first, so that the given options take precedence
+		optsLocal = append(optsLocal, func(cfg *ddtrace.StartSpanConfig) {
+			if cfg.Tags == nil {
+				cfg.Tags = make(map[string]interface{}, len(tags))
+			}
+			for k, v := range tags {
+				cfg.Tags[k] = v
+			}
+		})
+	}
+	optsLocal = append(optsLocal, opts...)
 
 	if ctx == nil {
 		// default to context.Background() to avoid panics on Go >= 1.15
@@ -60,3 +108,27 @@ func StartSpanFromContext(ctx context.Context, operationName string, opts ...Sta
 	}
 	return s, ContextWithSpan(ctx, s)
 }
+
+// StartOperation starts a span as StartSpanFromContext does, and returns the
+// context holding it along with the function finishing it with the given error,
+// if any. The error message, type and stack trace are reported on the span, the
+// stack trace starting at the caller of the finish function. It is meant to be
+// deferred with the named error result of the calling function, so that the
+// span is finished with the error actually returned:
+//
+//	func fetch(ctx context.Context) (err error) {
+//		ctx, finish := tracer.StartOperation(ctx, "fetch")
+//		defer func() { finish(err) }()
+//		...
+//	}
+func StartOperation(ctx context.Context, operationName string, opts ...StartSpanOption) (context.Context, func(err error)) {
+	s, ctx := StartSpanFromContext(ctx, operationName, opts...)
+	return ctx, func(err error) {
+		if err == nil {
+			s.Finish()
+			return
+		}
+		// skip setTagError, span.Finish and this function
+		s.Finish(WithError(err), StackFrames(defaultStackLength, 3))
+	}
+}
@@ -7,10 +7,13 @@ package tracer
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/internal"
 )
 
@@ -76,6 +79,42 @@ func TestStartSpanFromContext(t *testing.T) {
 	assert.Equal("/", got.Resource)
 }
 
+func TestStartOperation(t *testing.T) {
+	_, _, _, stop := startTestTracer(t)
+	defer stop()
+
+	t.Run("no-error", func(t *testing.T) {
+		assert := assert.New(t)
+		ctx, finish := StartOperation(context.Background(), "op", ResourceName("res"))
+		s, ok := SpanFromContext(ctx)
+		assert.True(ok)
+		finish(nil)
+		got := s.(*span)
+		assert.Equal("op", got.Name)
+		assert.Equal("res", got.Resource)
+		assert.True(got.finished)
+		assert.Equal(int32(0), got.Error)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		assert := assert.New(t)
+		var s Span
+		func() (err error) {
+			ctx, finish := StartOperation(context.Background(), "op")
+			defer func() { finish(err) }()
+			s, _ = SpanFromContext(ctx)
+			return errors.New("failed")
+		}()
+		got := s.(*span)
+		assert.True(got.finished)
+		assert.Equal(int32(1), got.Error)
+		assert.Equal("failed", got.Meta[ext.ErrorMsg])
+		stack := got.Meta[ext.ErrorStack]
+		assert.True(strings.HasPrefix(stack, "gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer.TestStartOperation.func2"), stack)
+		assert.NotContains(stack, "setTagError")
+	})
+}
+
 func TestStartSpanFromContextRace(t *testing.T) {
 	_, _, _, stop := startTestTracer(t)
 	defer stop()
@@ -125,3 +164,28 @@ func TestStartSpanFromNilContext(t *testing.T) {
 	assert.True(ok)
 	assert.Equal(child, ctxSpan)
 }
+
+func TestContextWithSpanTags(t *testing.T) {
+	_, _, _, stop := startTestTracer(t)
+	defer stop()
+	assert := assert.New(t)
+
+	ctx := ContextWithSpanTags(context.Background(), map[string]interface{}{"tenant": "acme", "tier": "free"})
+	ctx = ContextWithSpanTags(ctx, map[string]interface{}{"tier": "enterprise", "shard": 3})
+	root, ctx := StartSpanFromContext(ctx, "root", Tag("shard", 4))
+	child, _ := StartSpanFromContext(ctx, "child")
+
+	for _, s := range []*span{root.(*span), child.(*span)} {
+		assert.Equal("acme", s.Meta["tenant"])
+		assert.Equal("enterprise", s.Meta["tier"])
+	}
+	// the options take precedence over the tags of the context
+	assert.Equal(4., root.(*span).Metrics["shard"])
+	assert.Equal(3., child.(*span).Metrics["shard"])
+
+	// the parent contexts are left untouched
+	parent := ContextWithSpanTags(context.Background(), map[string]interface{}{"tenant": "acme"})
+	ContextWithSpanTags(parent, map[string]interface{}{"tenant": "other"})
+	s, _ := StartSpanFromContext(parent, "op")
+	assert.Equal("acme", s.(*span).Meta["tenant"])
+}
@@ -0,0 +1,29 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022 Datadog, Inc.
+
+package tracer
+
+import (
+	"context"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/internal"
+)
+
+// SetDataStreamsCheckpoint sets a Data Streams Monitoring checkpoint, identified
+// by the given edge tags (e.g. "direction:out", "topic:orders", "type:kafka"),
+// on the pathway carried by ctx, or on a new pathway if ctx carries none. It
+// returns a copy of ctx carrying the updated pathway, along with true, if Data
+// Streams Monitoring is enabled (see DD_DATA_STREAMS_ENABLED) and the tracer is
+// started. Otherwise, ctx is returned unchanged along with false.
+func SetDataStreamsCheckpoint(ctx context.Context, edgeTags ...string) (outCtx context.Context, ok bool) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if t, ok := internal.GetGlobalTracer().(*tracer); ok && t.dataStreams != nil {
+		_, ctx = t.dataStreams.SetCheckpoint(ctx, edgeTags...)
+		return ctx, true
+	}
+	return ctx, false
+}
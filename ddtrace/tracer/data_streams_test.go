@@ -0,0 +1,49 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022 Datadog, Inc.
+
+package tracer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/datastreams"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetDataStreamsCheckpoint(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		Start(withTransport(newDefaultTransport()))
+		defer Stop()
+
+		ctx, ok := SetDataStreamsCheckpoint(context.Background(), "type:kafka", "direction:out", "topic:topic1")
+		assert.False(t, ok)
+		_, ok = datastreams.PathwayFromContext(ctx)
+		assert.False(t, ok)
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		os.Setenv("DD_DATA_STREAMS_ENABLED", "true")
+		defer os.Unsetenv("DD_DATA_STREAMS_ENABLED")
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/info" {
+				w.Write([]byte(`{"endpoints":["/v0.1/pipeline_stats"]}`))
+			}
+		}))
+		defer srv.Close()
+		Start(WithAgentAddr(strings.TrimPrefix(srv.URL, "http://")))
+		defer Stop()
+
+		ctx, ok := SetDataStreamsCheckpoint(context.Background(), "type:kafka", "direction:out", "topic:topic1")
+		assert.True(t, ok)
+		_, ok = datastreams.PathwayFromContext(ctx)
+		assert.True(t, ok)
+	})
+}
@@ -0,0 +1,108 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package tracer
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/internal"
+)
+
+// debugInfo is the response of the handler returned by DebugHandler.
+type debugInfo struct {
+	startupInfo
+	QueuedTraces int `json:"queued_traces"` // Number of traces waiting to be written
+	QueueSize    int `json:"queue_size"`    // Capacity of the payload queue
+
+	Sampling SamplingState `json:"sampling"` // State of the samplers
+}
+
+// SamplingState describes the state of the samplers of the running tracer, to
+// help understanding why a trace was kept or dropped. The rules are applied
+// first, in order, and the traces matching none of them are sampled with the
+// global rate when set, or with the rates provided by the agent otherwise.
+type SamplingState struct {
+	// AgentRates holds the sampling rates provided by the agent, keyed by
+	// "service:<service>,env:<env>".
+	AgentRates map[string]float64 `json:"agent_rates"`
+	// AgentDefaultRate is the agent rate of the services not in AgentRates.
+	AgentDefaultRate float64 `json:"agent_default_rate"`
+
+	// GlobalRate is the rate applied to the traces matching no trace rule,
+	// as set with DD_TRACE_SAMPLE_RATE or remote configuration. It is nil
+	// when unset, in which case the agent rates apply.
+	GlobalRate *float64 `json:"global_rate"`
+	// TraceRules are the trace sampling rules, and SpanRules the single span
+	// sampling rules.
+	TraceRules []SamplingRule `json:"trace_rules"`
+	SpanRules  []SamplingRule `json:"span_rules"`
+
+	// RateLimit is the number of traces per second the rate limiter allows to
+	// be kept by the rules and the global rate, and RateLimiterEffectiveRate
+	// the ratio of those it allowed over the last second. They are nil when
+	// neither trace rules nor a global rate are configured.
+	RateLimit                *float64 `json:"rate_limit"`
+	RateLimiterEffectiveRate *float64 `json:"rate_limiter_effective_rate"`
+}
+
+// SamplingDecisions returns the current state of the samplers of the running
+// tracer, and false when the tracer is not started.
+func SamplingDecisions() (SamplingState, bool) {
+	t, ok := internal.GetGlobalTracer().(*tracer)
+	if !ok {
+		return SamplingState{}, false
+	}
+	return newSamplingState(t), true
+}
+
+func newSamplingState(t *tracer) SamplingState {
+	var s SamplingState
+	s.AgentRates, s.AgentDefaultRate = t.prioritySampling.snapshot()
+	traces := t.rulesSampling.traces
+	if rate := traces.rate(); !math.IsNaN(rate) {
+		s.GlobalRate = &rate
+	}
+	s.TraceRules = append([]SamplingRule(nil), traces.rules...)
+	s.SpanRules = append([]SamplingRule(nil), t.rulesSampling.spans.rules...)
+	if limit, ok := traces.limit(); ok {
+		rate := traces.limiter.effectiveRate()
+		s.RateLimit, s.RateLimiterEffectiveRate = &limit, &rate
+	}
+	return s
+}
+
+// DebugHandler returns an HTTP handler dumping the configuration of the running
+// tracer as JSON, in the format of the startup logs, along with the state of its
+// samplers as returned by SamplingDecisions, to help debugging incidents.
+// Unlike the startup logs, it reflects the settings updated at runtime, such as
+// through remote configuration, and does not check whether the agent can be reached.
+// It responds with 503 Service Unavailable when the tracer is not started.
+//
+// The configuration may hold sensitive information, so the handler should
+// only be exposed on an internal address, e.g.:
+//
+//	http.Handle("/debug/ddtrace", tracer.DebugHandler())
+func DebugHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t, ok := internal.GetGlobalTracer().(*tracer)
+		if !ok {
+			http.Error(w, "tracer not started", http.StatusServiceUnavailable)
+			return
+		}
+		info := debugInfo{
+			startupInfo:  newStartupInfo(t),
+			QueuedTraces: len(t.out),
+			QueueSize:    cap(t.out),
+			Sampling:     newSamplingState(t),
+		}
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(info)
+	})
+}
@@ -0,0 +1,99 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package tracer
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDebugHandler(t *testing.T) {
+	t.Run("not-started", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		DebugHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/debug/ddtrace", nil))
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	})
+
+	t.Run("started", func(t *testing.T) {
+		trc, _, _, stop := startTestTracer(t, WithEnv("prod"), WithServiceVersion("1.0"))
+		defer stop()
+		defer globalconfig.SetEnv("")
+		defer globalconfig.SetServiceVersion("")
+		trc.updateGlobalConfig("staging", "1.1", map[string]string{"redis": "cache"})
+
+		rec := httptest.NewRecorder()
+		DebugHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/debug/ddtrace", nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+		var info debugInfo
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &info))
+		assert.Equal(t, "staging", info.Env)
+		assert.Equal(t, "1.1", info.ApplicationVersion)
+		assert.Equal(t, map[string]string{"redis": "cache"}, info.ServiceMappings)
+		assert.Equal(t, "", info.AgentError)
+		assert.Equal(t, payloadQueueSize, info.QueueSize)
+		assert.Equal(t, 1., info.Sampling.AgentDefaultRate)
+		assert.Nil(t, info.Sampling.GlobalRate)
+	})
+}
+
+func TestSamplingDecisions(t *testing.T) {
+	t.Run("not-started", func(t *testing.T) {
+		_, ok := SamplingDecisions()
+		assert.False(t, ok)
+	})
+
+	t.Run("agent-rates", func(t *testing.T) {
+		trc, _, _, stop := startTestTracer(t)
+		defer stop()
+		assert.NoError(t, trc.prioritySampling.readRatesJSON(io.NopCloser(strings.NewReader(
+			`{"rate_by_service":{"service:web,env:prod":0.5,"service:,env:":0.2}}`,
+		))))
+
+		s, ok := SamplingDecisions()
+		assert.True(t, ok)
+		assert.Equal(t, map[string]float64{"service:web,env:prod": 0.5}, s.AgentRates)
+		assert.Equal(t, 0.2, s.AgentDefaultRate)
+		assert.Nil(t, s.GlobalRate)
+		assert.Empty(t, s.TraceRules)
+		assert.Nil(t, s.RateLimit)
+		assert.Nil(t, s.RateLimiterEffectiveRate)
+	})
+
+	t.Run("rules", func(t *testing.T) {
+		t.Setenv("DD_TRACE_SAMPLE_RATE", "0.3")
+		t.Setenv("DD_TRACE_RATE_LIMIT", "1")
+		trc, _, _, stop := startTestTracer(t, WithSamplingRules([]SamplingRule{
+			ServiceRule("web", 1),
+			SpanNameServiceRule("http.request", "web", 1),
+		}))
+		defer stop()
+		now := time.Now()
+		trc.rulesSampling.traces.limiter.allowOne(now)
+		trc.rulesSampling.traces.limiter.allowOne(now)
+
+		s, ok := SamplingDecisions()
+		assert.True(t, ok)
+		assert.Equal(t, 0.3, *s.GlobalRate)
+		assert.Len(t, s.TraceRules, 1)
+		assert.Len(t, s.SpanRules, 1)
+		assert.Equal(t, 1., *s.RateLimit)
+		assert.Equal(t, 0.5, *s.RateLimiterEffectiveRate)
+
+		_, err := json.Marshal(s)
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,136 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package tracer
+
+import (
+	"hash/fnv"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/internal"
+)
+
+const (
+	// keyRootCause prefixes the tags of the root causes of the errors.
+	keyRootCause = "error.root_cause"
+
+	// maxRootCauses is the maximum number of root causes tagged for an error.
+	maxRootCauses = 8
+	// maxUnwrapDepth is the maximum depth of the unwrapped error chains, which
+	// protects against errors unwrapping to themselves.
+	maxUnwrapDepth = 32
+)
+
+// errorTrackingEnabled reports whether the global tracer has error tracking
+// enabled, see WithErrorTracking.
+func errorTrackingEnabled() bool {
+	t, ok := internal.GetGlobalTracer().(*tracer)
+	return ok && t.config.errorTracking
+}
+
+// setErrorTrackingTags sets the root causes and the fingerprint of err, whose
+// stack, if taken, is the given one. It must be called with s locked.
+func (s *span) setErrorTrackingTags(err error, stack string) {
+	causes := rootCauses(err)
+	switch len(causes) {
+	case 0:
+		causes = []error{err}
+	case 1:
+		s.setMeta(keyRootCause+".type", reflect.TypeOf(causes[0]).String())
+		s.setMeta(keyRootCause+".msg", causes[0].Error())
+	default:
+		for i, c := range causes {
+			prefix := keyRootCause + "." + strconv.Itoa(i)
+			s.setMeta(prefix+".type", reflect.TypeOf(c).String())
+			s.setMeta(prefix+".msg", c.Error())
+		}
+	}
+	s.setMeta(ext.ErrorFingerprint, fingerprint(causes, stack))
+}
+
+// rootCauses returns the errors at the end of the chains of errors wrapped by
+// err, through the Unwrap() error and Unwrap() []error methods, or nil when err
+// wraps no error.
+func rootCauses(err error) []error {
+	var causes []error
+	var walk func(err error, depth int)
+	walk = func(err error, depth int) {
+		if len(causes) == maxRootCauses {
+			return
+		}
+		if depth < maxUnwrapDepth {
+			switch e := err.(type) {
+			case interface{ Unwrap() []error }:
+				var wrapped bool
+				for _, u := range e.Unwrap() {
+					if u != nil {
+						wrapped = true
+						walk(u, depth+1)
+					}
+				}
+				if wrapped {
+					return
+				}
+			case interface{ Unwrap() error }:
+				if u := e.Unwrap(); u != nil {
+					walk(u, depth+1)
+					return
+				}
+			}
+		}
+		if depth > 0 {
+			causes = append(causes, err)
+		}
+	}
+	walk(err, 0)
+	return causes
+}
+
+// fingerprint returns a fingerprint of the errors with the given root causes
+// and stack, made of the types of the causes and of the functions of the stack,
+// which leaves out the error messages and the line numbers, as they often vary
+// across the occurrences of the same errors.
+func fingerprint(causes []error, stack string) string {
+	h := fnv.New64a()
+	for _, c := range causes {
+		h.Write([]byte(reflect.TypeOf(c).String()))
+		h.Write([]byte{0})
+	}
+	for _, line := range strings.Split(stack, "\n") {
+		if line != "" && line[0] != '\t' {
+			h.Write([]byte(line))
+			h.Write([]byte{0})
+		}
+	}
+	return strconv.FormatUint(h.Sum64(), 16)
+}
+
+// sanitizeStack returns stack, as returned by takeStacktrace, with its file
+// paths made relative to the module cache or to the Go root, when they are in
+// any of them, so that they don't depend on the build machine.
+func sanitizeStack(stack string) string {
+	var goroot string
+	if root := runtime.GOROOT(); root != "" {
+		goroot = root + "/src/"
+	}
+	lines := strings.Split(stack, "\n")
+	for i, line := range lines {
+		if !strings.HasPrefix(line, "\t") {
+			continue
+		}
+		file := line[1:]
+		if j := strings.LastIndex(file, "/pkg/mod/"); j >= 0 {
+			file = file[j+len("/pkg/mod/"):]
+		} else if goroot != "" && strings.HasPrefix(file, goroot) {
+			file = file[len(goroot):]
+		}
+		lines[i] = "\t" + file
+	}
+	return strings.Join(lines, "\n")
+}
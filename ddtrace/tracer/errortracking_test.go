@@ -0,0 +1,117 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package tracer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+)
+
+// multiError wraps several errors, as the errors returned by errors.Join do.
+type multiError []error
+
+func (m multiError) Error() string   { return fmt.Sprint([]error(m)) }
+func (m multiError) Unwrap() []error { return m }
+
+type codeError struct{ code int }
+
+func (e *codeError) Error() string { return fmt.Sprintf("code %d", e.code) }
+
+func TestRootCauses(t *testing.T) {
+	assert := assert.New(t)
+	assert.Empty(rootCauses(io.EOF))
+	assert.Equal([]error{io.EOF}, rootCauses(fmt.Errorf("read: %w", fmt.Errorf("body: %w", io.EOF))))
+
+	code := &codeError{code: 42}
+	err := fmt.Errorf("batch: %w", multiError{fmt.Errorf("a: %w", io.EOF), nil, code})
+	assert.Equal([]error{io.EOF, code}, rootCauses(err))
+
+	// errors wrapping no error are their own root cause
+	assert.Empty(rootCauses(multiError{nil}))
+}
+
+func TestErrorTracking(t *testing.T) {
+	tracer, _, _, stop := startTestTracer(t, WithErrorTracking(true))
+	defer stop()
+
+	finish := func(err error) *span {
+		s := tracer.StartSpan("op").(*span)
+		s.Finish(WithError(err))
+		return s
+	}
+
+	t.Run("wrapped", func(t *testing.T) {
+		assert := assert.New(t)
+		var spans []*span
+		for i := 0; i < 2; i++ {
+			spans = append(spans, finish(fmt.Errorf("request %d: %w", i, &codeError{code: i})))
+		}
+		s := spans[0]
+		assert.Equal("request 0: code 0", s.Meta[ext.ErrorMsg])
+		assert.Equal("*tracer.codeError", s.Meta[keyRootCause+".type"])
+		assert.Equal("code 0", s.Meta[keyRootCause+".msg"])
+		require.NotEmpty(t, s.Meta[ext.ErrorFingerprint])
+		// the fingerprint doesn't depend on the messages
+		assert.Equal(s.Meta[ext.ErrorFingerprint], spans[1].Meta[ext.ErrorFingerprint])
+		assert.NotEqual(s.Meta[ext.ErrorFingerprint], finish(io.EOF).Meta[ext.ErrorFingerprint])
+	})
+
+	t.Run("joined", func(t *testing.T) {
+		assert := assert.New(t)
+		s := finish(multiError{io.EOF, &codeError{code: 1}})
+		assert.NotContains(s.Meta, keyRootCause+".type")
+		assert.Equal("*errors.errorString", s.Meta[keyRootCause+".0.type"])
+		assert.Equal("EOF", s.Meta[keyRootCause+".0.msg"])
+		assert.Equal("*tracer.codeError", s.Meta[keyRootCause+".1.type"])
+		assert.Equal("code 1", s.Meta[keyRootCause+".1.msg"])
+	})
+
+	t.Run("tag", func(t *testing.T) {
+		s := tracer.StartSpan("op").(*span)
+		s.SetTag(ext.Error, errors.New("failed"))
+		s.Finish()
+		assert.NotContains(t, s.Meta, keyRootCause+".type")
+		assert.NotEmpty(t, s.Meta[ext.ErrorFingerprint])
+	})
+}
+
+func TestErrorTrackingDisabled(t *testing.T) {
+	tracer, _, _, stop := startTestTracer(t)
+	defer stop()
+
+	s := tracer.StartSpan("op").(*span)
+	s.Finish(WithError(fmt.Errorf("wrap: %w", io.EOF)))
+	assert.NotContains(t, s.Meta, ext.ErrorFingerprint)
+	assert.NotContains(t, s.Meta, keyRootCause+".type")
+}
+
+func TestSanitizeStack(t *testing.T) {
+	stack := strings.Join([]string{
+		"main.handle",
+		"\t/home/ci/src/app/main.go:12",
+		"github.com/org/lib.Do",
+		"\t/home/ci/go/pkg/mod/github.com/org/lib@v1.0.0/lib.go:34",
+		"net/http.HandlerFunc.ServeHTTP",
+		"\t" + runtime.GOROOT() + "/src/net/http/server.go:2109",
+	}, "\n")
+	assert.Equal(t, strings.Join([]string{
+		"main.handle",
+		"\t/home/ci/src/app/main.go:12",
+		"github.com/org/lib.Do",
+		"\tgithub.com/org/lib@v1.0.0/lib.go:34",
+		"net/http.HandlerFunc.ServeHTTP",
+		"\tnet/http/server.go:2109",
+	}, "\n"), sanitizeStack(stack))
+}
@@ -0,0 +1,194 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package tracer
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+)
+
+const (
+	// additionalAgentQueueSize is the number of payloads waiting to be sent to
+	// an additional agent above which the new ones are dropped.
+	additionalAgentQueueSize = 16
+
+	// additionalAgentStopTimeout is the maximum duration the tracer waits for the
+	// queued payloads to be sent to the additional agents when it stops.
+	additionalAgentStopTimeout = 5 * time.Second
+)
+
+// fanOutTransport is the transport of the tracer when additional agents are
+// configured with WithAdditionalAgent. It sends the payloads to the main
+// transport, and dual-ships the payloads given to ship to the additional agents
+// in the background, so that their failures or slowness never affect the main
+// agent. The failures of each additional agent are accounted for separately,
+// under the datadog.tracer.additional_agent.* metrics tagged with its endpoint.
+type fanOutTransport struct {
+	transport // the main transport
+
+	cfg        *config
+	additional []*additionalAgent
+	wg         sync.WaitGroup
+
+	mu      sync.RWMutex // guards stopped and the queues from being closed
+	stopped bool
+}
+
+var _ transport = (*fanOutTransport)(nil)
+
+// additionalAgent sends the payloads queued for an additional agent.
+type additionalAgent struct {
+	transport transport
+	tag       string // the agent tag of its metrics
+	queue     chan fanOutJob
+}
+
+// fanOutJob holds either the traces or the stats to send to an additional agent.
+type fanOutJob struct {
+	traces *payload
+	stats  *statsPayload
+}
+
+// newFanOutTransport returns a transport sending the payloads to main, and
+// dual-shipping them to the given additional transports.
+func newFanOutTransport(c *config, main transport, additional ...transport) *fanOutTransport {
+	t := &fanOutTransport{transport: main, cfg: c}
+	for _, at := range additional {
+		a := &additionalAgent{
+			transport: at,
+			tag:       "agent:" + at.endpoint(),
+			queue:     make(chan fanOutJob, additionalAgentQueueSize),
+		}
+		t.additional = append(t.additional, a)
+		t.wg.Add(1)
+		go func() {
+			defer t.wg.Done()
+			t.run(a)
+		}()
+	}
+	return t
+}
+
+// newAdditionalAgentTransport returns the transport sending traces to the agent
+// at the given URL, as given to WithAdditionalAgent.
+func newAdditionalAgentTransport(agentURL string) (transport, error) {
+	u, err := url.Parse(agentURL)
+	if err != nil {
+		return nil, err
+	}
+	var t *httpTransport
+	switch u.Scheme {
+	case "unix":
+		t = newHTTPTransport(defaultAddress, udsClient(u.Path))
+	case "http":
+		port := u.Port()
+		if port == "" {
+			port = defaultPort
+		}
+		t = newHTTPTransport(net.JoinHostPort(u.Hostname(), port), defaultClient)
+	default:
+		return nil, fmt.Errorf("unsupported scheme %q, only http and unix are supported", u.Scheme)
+	}
+	t.additional = true
+	return t, nil
+}
+
+// ship queues a clone of p, a payload about to be sent to the main transport,
+// to be sent to each additional agent. It is called once per payload, so that
+// the retries and the splits of the payloads sent to the main agent aren't
+// dual-shipped. The payloads are dropped when the queue of an agent is full.
+func (t *fanOutTransport) ship(p *payload) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.stopped {
+		return
+	}
+	for _, a := range t.additional {
+		c := p.clone()
+		select {
+		case a.queue <- fanOutJob{traces: c}:
+		default:
+			c.Close()
+			t.cfg.statsd.Count("datadog.tracer.additional_agent.traces_dropped", int64(p.itemCount()),
+				[]string{a.tag, "reason:queue_full"}, 1)
+		}
+	}
+}
+
+// sendStats sends p to the main transport, and queues it to be sent to each
+// additional agent, which would otherwise lack the stats computed by the tracer.
+func (t *fanOutTransport) sendStats(p *statsPayload) error {
+	t.mu.RLock()
+	if !t.stopped {
+		for _, a := range t.additional {
+			select {
+			case a.queue <- fanOutJob{stats: p}:
+			default:
+				t.cfg.statsd.Incr("datadog.tracer.additional_agent.stats_dropped", []string{a.tag, "reason:queue_full"}, 1)
+			}
+		}
+	}
+	t.mu.RUnlock()
+	return t.transport.sendStats(p)
+}
+
+// run sends the payloads queued for a until its queue is closed.
+func (t *fanOutTransport) run(a *additionalAgent) {
+	for job := range a.queue {
+		if job.stats != nil {
+			if err := a.transport.sendStats(job.stats); err != nil {
+				t.cfg.statsd.Incr("datadog.tracer.additional_agent.stats_dropped", []string{a.tag, "reason:send_failed"}, 1)
+				log.Debug("Error sending stats to additional agent %s: %v", a.transport.endpoint(), err)
+			}
+			continue
+		}
+		count := job.traces.itemCount()
+		rc, err := a.transport.send(job.traces)
+		job.traces.Close()
+		if err != nil {
+			t.cfg.statsd.Count("datadog.tracer.additional_agent.traces_dropped", int64(count),
+				[]string{a.tag, "reason:send_failed"}, 1)
+			log.Debug("Error sending %d traces to additional agent %s: %v", count, a.transport.endpoint(), err)
+			continue
+		}
+		t.cfg.statsd.Count("datadog.tracer.additional_agent.flush_traces", int64(count), []string{a.tag}, 1)
+		// the sampling rates are only taken from the main agent
+		io.Copy(io.Discard, rc)
+		rc.Close()
+	}
+}
+
+// stop sends the payloads left in the queues of the additional agents, giving
+// up after additionalAgentStopTimeout. The payloads are no longer dual-shipped
+// afterwards.
+func (t *fanOutTransport) stop() {
+	t.mu.Lock()
+	if t.stopped {
+		t.mu.Unlock()
+		return
+	}
+	t.stopped = true
+	for _, a := range t.additional {
+		close(a.queue)
+	}
+	t.mu.Unlock()
+	done := make(chan struct{})
+	go func() {
+		t.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(additionalAgentStopTimeout):
+		log.Warn("Timed out sending the remaining payloads to the additional agents.")
+	}
+}
@@ -0,0 +1,129 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package tracer
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// additionalTransport is a statusTransport with its own endpoint.
+type additionalTransport struct {
+	*statusTransport
+	url string
+}
+
+func (t additionalTransport) endpoint() string { return t.url }
+
+func TestFanOutTransport(t *testing.T) {
+	accept := func(_, _ int) int { return 0 }
+
+	t.Run("dual-ship", func(t *testing.T) {
+		var tg testStatsdClient
+		main := &statusTransport{reject: accept}
+		gateway := &statusTransport{reject: accept}
+		down := &statusTransport{reject: func(_, _ int) int { return http.StatusServiceUnavailable }}
+		c := newConfig(withTransport(main))
+		c.statsd = &tg
+		c.transport = newFanOutTransport(c, main,
+			additionalTransport{gateway, "http://gateway:8126/v0.4/traces"},
+			additionalTransport{down, "http://down:8126/v0.4/traces"})
+		w := newAgentTraceWriter(c, newPrioritySampler(), nil)
+		for i := 0; i < 3; i++ {
+			w.add([]*span{makeSpan(0)})
+		}
+		w.flush()
+		w.stop()
+
+		assert.Equal(t, []int{3}, main.accepted)
+		assert.Equal(t, []int{3}, gateway.accepted)
+		assert.Equal(t, 1, down.attempts)
+		counts := tg.Counts()
+		assert.Equal(t, int64(3), counts["datadog.tracer.flush_traces"])
+		assert.Zero(t, counts["datadog.tracer.traces_dropped"])
+		assert.Equal(t, int64(3), counts["datadog.tracer.additional_agent.flush_traces"])
+		assert.Equal(t, int64(3), counts["datadog.tracer.additional_agent.traces_dropped"])
+		for _, call := range tg.CountCalls() {
+			switch call.name {
+			case "datadog.tracer.additional_agent.flush_traces":
+				assert.Equal(t, []string{"agent:http://gateway:8126/v0.4/traces"}, call.tags)
+			case "datadog.tracer.additional_agent.traces_dropped":
+				assert.Equal(t, []string{"agent:http://down:8126/v0.4/traces", "reason:send_failed"}, call.tags)
+			}
+		}
+	})
+
+	t.Run("retries", func(t *testing.T) {
+		defer func(old time.Duration) { sendRetryInterval = old }(sendRetryInterval)
+		sendRetryInterval = time.Millisecond
+
+		main := &statusTransport{reject: func(attempt, _ int) int {
+			if attempt == 1 {
+				return http.StatusTooManyRequests
+			}
+			return 0
+		}}
+		gateway := &statusTransport{reject: accept}
+		c := newConfig(withTransport(main), withNoopStats())
+		c.transport = newFanOutTransport(c, main, gateway)
+		w := newAgentTraceWriter(c, newPrioritySampler(), nil)
+		w.add([]*span{makeSpan(0)})
+		w.flush()
+		w.stop()
+
+		assert.Equal(t, 2, main.attempts)
+		assert.Equal(t, 1, gateway.attempts)
+		assert.Equal(t, []int{1}, gateway.accepted)
+	})
+
+	t.Run("stopped", func(t *testing.T) {
+		gateway := &statusTransport{reject: accept}
+		c := newConfig(withTransport(discardTransport{}), withNoopStats())
+		ft := newFanOutTransport(c, discardTransport{}, gateway)
+		ft.stop()
+		ft.stop()
+		p := newPayload()
+		require.NoError(t, p.push([]*span{makeSpan(0)}))
+		ft.ship(p)
+		assert.NoError(t, ft.sendStats(&statsPayload{}))
+		assert.Zero(t, gateway.attempts)
+	})
+}
+
+func TestWithAdditionalAgent(t *testing.T) {
+	t.Run("default", func(t *testing.T) {
+		c := newConfig()
+		_, ok := c.transport.(*fanOutTransport)
+		assert.False(t, ok)
+	})
+
+	t.Run("urls", func(t *testing.T) {
+		c := newConfig(
+			WithAdditionalAgent("http://apm-gateway"),
+			WithAdditionalAgent("unix:///var/run/datadog/apm.socket"),
+			WithAdditionalAgent("ftp://apm-gateway:8126"),
+		)
+		ft, ok := c.transport.(*fanOutTransport)
+		require.True(t, ok)
+		defer ft.stop()
+		require.Len(t, ft.additional, 2)
+		assert.Equal(t, "http://apm-gateway:8126/v0.4/traces", ft.additional[0].transport.endpoint())
+		assert.True(t, ft.additional[0].transport.(*httpTransport).additional)
+		assert.Equal(t, "http://"+defaultAddress+"/v0.4/traces", ft.additional[1].transport.endpoint())
+		_, ok = ft.transport.(*httpTransport)
+		assert.True(t, ok)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		c := newConfig(WithAdditionalAgent("ftp://apm-gateway:8126"))
+		_, ok := c.transport.(*fanOutTransport)
+		assert.False(t, ok)
+	})
+}
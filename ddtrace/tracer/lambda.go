@@ -0,0 +1,151 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package tracer
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"reflect"
+	"sync/atomic"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/internal"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+)
+
+var (
+	// lambdaExtensionPath is the path of the Datadog Lambda extension, whose
+	// presence reports that it is running; replaced in tests.
+	lambdaExtensionPath = "/opt/extensions/datadog-agent"
+
+	// lambdaExtensionFlushURL is the endpoint of the Datadog Lambda extension
+	// notifying it of the end of an invocation; replaced in tests.
+	lambdaExtensionFlushURL = "http://localhost:8124/lambda/flush"
+)
+
+const (
+	// lambdaSpanName is the operation name of the spans of the Lambda invocations.
+	lambdaSpanName = "aws.lambda"
+
+	tagColdStart       = "cold_start"
+	tagFunctionName    = "function_name"
+	tagFunctionVersion = "function_version"
+)
+
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// coldStart is 1 until the first Lambda invocation is traced.
+var coldStart uint32 = 1
+
+// WrapLambdaHandler wraps the given AWS Lambda handler, of any of the signatures
+// accepted by lambda.Start of github.com/aws/aws-lambda-go, so that every invocation
+// is traced by an aws.lambda root span, which is a child of the span held by the
+// context of the invocation, if any. The span context is passed to the handler when
+// it accepts a context.Context as its first argument. The traces are flushed at the
+// end of every invocation, within its deadline, and the Datadog Lambda extension is
+// notified of it when it is running. The tracer must be started before, e.g.:
+//
+//	func main() {
+//		tracer.Start()
+//		defer tracer.Stop()
+//		lambda.Start(tracer.WrapLambdaHandler(handler))
+//	}
+//
+// The handler is returned as is when it is not a function.
+func WrapLambdaHandler(handler interface{}) interface{} {
+	fn := reflect.ValueOf(handler)
+	if fn.Kind() != reflect.Func {
+		log.Warn("WrapLambdaHandler: handler of kind %s is not a function, not tracing it", fn.Kind())
+		return handler
+	}
+	typ := fn.Type()
+	withContext := typ.NumIn() > 0 && typ.In(0) == contextType
+	errIndex := -1
+	if n := typ.NumOut(); n > 0 && typ.Out(n-1).Implements(errorType) {
+		errIndex = n - 1
+	}
+	return reflect.MakeFunc(typ, func(args []reflect.Value) (results []reflect.Value) {
+		ctx := context.Background()
+		if withContext {
+			if c, ok := args[0].Interface().(context.Context); ok && c != nil {
+				ctx = c
+			}
+		}
+		span, ctx := startLambdaSpan(ctx)
+		if withContext {
+			args[0] = reflect.ValueOf(ctx)
+		}
+		defer func() {
+			if r := recover(); r != nil {
+				span.Finish(WithError(fmt.Errorf("%v", r)))
+				flushLambdaInvocation(ctx)
+				panic(r)
+			}
+		}()
+		results = fn.Call(args)
+		var err error
+		if errIndex >= 0 {
+			err, _ = results[errIndex].Interface().(error)
+		}
+		span.Finish(WithError(err))
+		flushLambdaInvocation(ctx)
+		return results
+	}).Interface()
+}
+
+// startLambdaSpan starts the aws.lambda span of an invocation with the given context.
+func startLambdaSpan(ctx context.Context) (Span, context.Context) {
+	name := os.Getenv("AWS_LAMBDA_FUNCTION_NAME")
+	opts := []StartSpanOption{
+		SpanType(ext.SpanTypeServerless),
+		ResourceName(name),
+		Tag(tagFunctionName, name),
+		Tag(tagColdStart, atomic.CompareAndSwapUint32(&coldStart, 1, 0)),
+	}
+	if v := os.Getenv("AWS_LAMBDA_FUNCTION_VERSION"); v != "" {
+		opts = append(opts, Tag(tagFunctionVersion, v))
+	}
+	return StartSpanFromContext(ctx, lambdaSpanName, opts...)
+}
+
+// flushLambdaInvocation flushes the traces of the invocation with the given
+// context and notifies the Datadog Lambda extension of its end, if running.
+func flushLambdaInvocation(ctx context.Context) {
+	t, ok := internal.GetGlobalTracer().(*tracer)
+	if !ok {
+		return
+	}
+	if err := t.flushSync(ctx); err != nil {
+		log.Error("Lambda: failed to flush the traces of the invocation: %v", err)
+	}
+	if t.config.lambdaExtension {
+		if err := flushLambdaExtension(ctx, t.config.httpClient); err != nil {
+			log.Error("Lambda: failed to notify the Datadog extension of the end of the invocation: %v", err)
+		}
+	}
+}
+
+// flushLambdaExtension notifies the Datadog Lambda extension of the end of an invocation.
+func flushLambdaExtension(ctx context.Context, client *http.Client) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, lambdaExtensionFlushURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package tracer
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// withLambdaExtension sets up the environment of a Lambda function running the
+// Datadog extension, whose flush endpoint counts its calls into flushes.
+func withLambdaExtension(t *testing.T, flushes *int32) {
+	t.Setenv("AWS_LAMBDA_FUNCTION_NAME", "my-function")
+	t.Setenv("AWS_LAMBDA_FUNCTION_VERSION", "$LATEST")
+	path := filepath.Join(t.TempDir(), "datadog-agent")
+	assert.NoError(t, os.WriteFile(path, nil, 0755))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		atomic.AddInt32(flushes, 1)
+	}))
+	t.Cleanup(srv.Close)
+	oldPath, oldURL := lambdaExtensionPath, lambdaExtensionFlushURL
+	lambdaExtensionPath, lambdaExtensionFlushURL = path, srv.URL
+	t.Cleanup(func() {
+		lambdaExtensionPath, lambdaExtensionFlushURL = oldPath, oldURL
+	})
+}
+
+func TestLambdaExtensionDetection(t *testing.T) {
+	t.Run("extension", func(t *testing.T) {
+		var flushes int32
+		withLambdaExtension(t, &flushes)
+		c := newConfig()
+		assert.True(t, c.lambdaExtension)
+		assert.False(t, c.logToStdout)
+		assert.Zero(t, c.agent)
+	})
+
+	t.Run("no-extension", func(t *testing.T) {
+		t.Setenv("AWS_LAMBDA_FUNCTION_NAME", "my-function")
+		old := lambdaExtensionPath
+		lambdaExtensionPath = filepath.Join(t.TempDir(), "datadog-agent")
+		defer func() { lambdaExtensionPath = old }()
+		c := newConfig()
+		assert.False(t, c.lambdaExtension)
+		assert.True(t, c.logToStdout)
+	})
+
+	t.Run("lambda-mode", func(t *testing.T) {
+		var flushes int32
+		withLambdaExtension(t, &flushes)
+		c := newConfig(WithLambdaMode(true))
+		assert.False(t, c.lambdaExtension)
+		assert.True(t, c.logToStdout)
+	})
+}
+
+func TestWrapLambdaHandler(t *testing.T) {
+	var flushes int32
+	withLambdaExtension(t, &flushes)
+	atomic.StoreUint32(&coldStart, 1)
+	_, transport, _, stop := startTestTracer(t)
+	defer stop()
+
+	handler := WrapLambdaHandler(func(ctx context.Context, name string) (string, error) {
+		s, ok := SpanFromContext(ctx)
+		assert.True(t, ok)
+		assert.Equal(t, lambdaSpanName, s.(*span).Name)
+		if name == "" {
+			return "", errors.New("missing name")
+		}
+		return "hello " + name, nil
+	}).(func(context.Context, string) (string, error))
+
+	out, err := handler(context.Background(), "gopher")
+	assert.NoError(t, err)
+	assert.Equal(t, "hello gopher", out)
+	// the traces are sent and the extension notified by the end of the invocation
+	assert.Equal(t, 1, transport.Len())
+	assert.EqualValues(t, 1, atomic.LoadInt32(&flushes))
+
+	_, err = handler(context.Background(), "")
+	assert.EqualError(t, err, "missing name")
+	assert.Equal(t, 2, transport.Len())
+	assert.EqualValues(t, 2, atomic.LoadInt32(&flushes))
+
+	traces := transport.Traces()
+	first, second := traces[0][0], traces[1][0]
+	assert.Equal(t, lambdaSpanName, first.Name)
+	assert.Equal(t, "my-function", first.Resource)
+	assert.Equal(t, ext.SpanTypeServerless, first.Type)
+	assert.Equal(t, "my-function", first.Meta[tagFunctionName])
+	assert.Equal(t, "$LATEST", first.Meta[tagFunctionVersion])
+	assert.Equal(t, "true", first.Meta[tagColdStart])
+	assert.Equal(t, int32(0), first.Error)
+	assert.Equal(t, "false", second.Meta[tagColdStart])
+	assert.Equal(t, int32(1), second.Error)
+	assert.Equal(t, "missing name", second.Meta[ext.ErrorMsg])
+
+	t.Run("no-context", func(t *testing.T) {
+		transport.Reset()
+		var called bool
+		WrapLambdaHandler(func() { called = true }).(func())()
+		assert.True(t, called)
+		assert.Equal(t, 1, transport.Len())
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		transport.Reset()
+		handler := WrapLambdaHandler(func() error { panic("boom") }).(func() error)
+		assert.PanicsWithValue(t, "boom", func() { handler() })
+		assert.Equal(t, 1, transport.Len())
+		assert.Equal(t, "boom", transport.Traces()[0][0].Meta[ext.ErrorMsg])
+	})
+
+	t.Run("not-a-function", func(t *testing.T) {
+		assert.Equal(t, "handler", WrapLambdaHandler("handler"))
+	})
+}
@@ -17,6 +17,7 @@ import (
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/namingschema"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/osinfo"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/version"
 )
@@ -51,6 +52,10 @@ type startupInfo struct {
 	LambdaMode                  string            `json:"lambda_mode"`                    // Whether or not the client has enabled lambda mode
 	AppSec                      bool              `json:"appsec"`                         // AppSec status: true when started, false otherwise.
 	AgentFeatures               agentFeatures     `json:"agent_features"`                 // Lists the capabilities of the agent.
+	PartialFlushEnabled         bool              `json:"partial_flush_enabled"`          // Whether Partial Flushing is enabled
+	PartialFlushMinSpans        int               `json:"partial_flush_min_spans"`        // The min number of spans to trigger a partial flush
+	DataStreamsEnabled          bool              `json:"data_streams_enabled"`           // Whether Data Streams Monitoring is enabled
+	SpanAttributeSchema         string            `json:"span_attribute_schema"`          // The naming schema of the integrations, e.g. v0
 }
 
 // checkEndpoint tries to connect to the URL specified by endpoint.
@@ -73,6 +78,25 @@ func checkEndpoint(endpoint string) error {
 // logStartup generates a startupInfo for a tracer and writes it to the log in
 // JSON format.
 func logStartup(t *tracer) {
+	info := newStartupInfo(t)
+	if !t.config.logToStdout {
+		if err := checkEndpoint(t.config.transport.endpoint()); err != nil {
+			info.AgentError = fmt.Sprintf("%s", err)
+			log.Warn("DIAGNOSTICS Unable to reach agent intake: %s", err)
+		}
+	}
+	bs, err := json.Marshal(info)
+	if err != nil {
+		log.Warn("DIAGNOSTICS Failed to serialize json for startup log (%v) %#v\n", err, info)
+		return
+	}
+	log.Info("DATADOG TRACER CONFIGURATION %s\n", string(bs))
+}
+
+// newStartupInfo returns the startupInfo of the configuration currently in
+// effect in t, without checking whether the agent can be reached.
+func newStartupInfo(t *tracer) startupInfo {
+	gc := t.loadGlobalConfig()
 	tags := make(map[string]string)
 	for k, v := range t.config.globalTags {
 		tags[k] = fmt.Sprintf("%v", v)
@@ -85,26 +109,30 @@ func logStartup(t *tracer) {
 		Version:                     version.Tag,
 		Lang:                        "Go",
 		LangVersion:                 runtime.Version(),
-		Env:                         t.config.env,
+		Env:                         gc.env,
 		Service:                     t.config.serviceName,
 		AgentURL:                    t.config.transport.endpoint(),
 		Debug:                       t.config.debug,
 		AnalyticsEnabled:            !math.IsNaN(globalconfig.AnalyticsRate()),
-		SampleRate:                  fmt.Sprintf("%f", t.rulesSampling.traces.globalRate),
+		SampleRate:                  fmt.Sprintf("%f", t.rulesSampling.GlobalRate()),
 		SampleRateLimit:             "disabled",
-		SamplingRules:               append(t.config.traceRules, t.config.spanRules...),
-		ServiceMappings:             t.config.serviceMappings,
+		SamplingRules:               append(append([]SamplingRule(nil), t.config.traceRules...), t.config.spanRules...),
+		ServiceMappings:             gc.serviceMappings,
 		Tags:                        tags,
 		RuntimeMetricsEnabled:       t.config.runtimeMetrics,
 		HealthMetricsEnabled:        t.config.runtimeMetrics,
-		ApplicationVersion:          t.config.version,
+		ApplicationVersion:          gc.version,
 		ProfilerCodeHotspotsEnabled: t.config.profilerHotspots,
 		ProfilerEndpointsEnabled:    t.config.profilerEndpoints,
 		Architecture:                runtime.GOARCH,
 		GlobalService:               globalconfig.ServiceName(),
 		LambdaMode:                  fmt.Sprintf("%t", t.config.logToStdout),
-		AgentFeatures:               t.config.agent,
+		AgentFeatures:               t.config.agentFeatures(),
 		AppSec:                      appsec.Enabled(),
+		PartialFlushEnabled:         t.config.partialFlushEnabled,
+		PartialFlushMinSpans:        t.config.partialFlushMinSpans,
+		DataStreamsEnabled:          t.config.dataStreamsMonitoringEnabled,
+		SpanAttributeSchema:         namingschema.GetVersion().String(),
 	}
 	if _, _, err := samplingRulesFromEnv(); err != nil {
 		info.SamplingRulesError = fmt.Sprintf("%s", err)
@@ -112,16 +140,5 @@ func logStartup(t *tracer) {
 	if limit, ok := t.rulesSampling.TraceRateLimit(); ok {
 		info.SampleRateLimit = fmt.Sprintf("%v", limit)
 	}
-	if !t.config.logToStdout {
-		if err := checkEndpoint(t.config.transport.endpoint()); err != nil {
-			info.AgentError = fmt.Sprintf("%s", err)
-			log.Warn("DIAGNOSTICS Unable to reach agent intake: %s", err)
-		}
-	}
-	bs, err := json.Marshal(info)
-	if err != nil {
-		log.Warn("DIAGNOSTICS Failed to serialize json for startup log (%v) %#v\n", err, info)
-		return
-	}
-	log.Info("DATADOG TRACER CONFIGURATION %s\n", string(bs))
+	return info
 }
@@ -0,0 +1,72 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package tracer
+
+import (
+	"fmt"
+	"strconv"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
+)
+
+// IDFormat is a format of the trace IDs returned by TraceIDFormat.
+type IDFormat int
+
+const (
+	// IDFormatDecimal formats the lower 64 bits of the trace ID as a decimal number,
+	// which is what the Datadog logs pipelines expect in the dd.trace_id attribute.
+	IDFormatDecimal IDFormat = iota
+	// IDFormatHex16 formats the lower 64 bits of the trace ID as 16 lowercase
+	// hexadecimal characters.
+	IDFormatHex16
+	// IDFormatHex32 formats the full 128-bit trace ID as 32 lowercase hexadecimal
+	// characters, as in W3C traceparent headers and OpenTelemetry. The upper 64 bits
+	// are zero for the traces having a 64-bit trace ID, see WithTraceID128Bit.
+	IDFormatHex32
+)
+
+// TraceIDFormat returns the trace ID of the span s in the given format, in order
+// to correlate logs with traces, or an empty string when s has no trace ID, such
+// as the spans of a tracer which was not started, or when the format is unknown.
+func TraceIDFormat(s ddtrace.Span, format IDFormat) string {
+	if s == nil {
+		return ""
+	}
+	sctx := s.Context()
+	id := sctx.TraceID()
+	if id == 0 {
+		return ""
+	}
+	switch format {
+	case IDFormatDecimal:
+		return strconv.FormatUint(id, 10)
+	case IDFormatHex16:
+		return fmt.Sprintf("%016x", id)
+	case IDFormatHex32:
+		upper := "0000000000000000"
+		if ctx, ok := sctx.(*spanContext); ok {
+			upper = ctx.traceIDUpper()
+		}
+		return fmt.Sprintf("%s%016x", upper, id)
+	}
+	return ""
+}
+
+// SamplingPriority returns the sampling priority of the trace of the span s, see
+// ext.PriorityAutoKeep and the other priorities, and whether it was decided. The
+// priority is decided when the root span of the trace is started, or received
+// from upstream services, and may change when the sampling decision is overridden,
+// e.g. with ext.ManualKeep. It is false for the spans of a tracer which was not
+// started.
+func SamplingPriority(s ddtrace.Span) (p int, ok bool) {
+	if s == nil {
+		return 0, false
+	}
+	if ctx, ok := s.Context().(*spanContext); ok {
+		return ctx.samplingPriority()
+	}
+	return 0, false
+}
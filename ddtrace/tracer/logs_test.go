@@ -0,0 +1,65 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package tracer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/internal"
+)
+
+func TestTraceIDFormat(t *testing.T) {
+	t.Run("64-bit", func(t *testing.T) {
+		assert := assert.New(t)
+		tracer, _, _, stop := startTestTracer(t, WithTraceID128Bit(false))
+		defer stop()
+
+		s := tracer.StartSpan("op", WithSpanID(0x1f))
+		assert.Equal("31", TraceIDFormat(s, IDFormatDecimal))
+		assert.Equal("000000000000001f", TraceIDFormat(s, IDFormatHex16))
+		assert.Equal("0000000000000000000000000000001f", TraceIDFormat(s, IDFormatHex32))
+		assert.Equal("", TraceIDFormat(s, IDFormat(-1)))
+	})
+
+	t.Run("128-bit", func(t *testing.T) {
+		assert := assert.New(t)
+		tracer, _, _, stop := startTestTracer(t, WithTraceID128Bit(true))
+		defer stop()
+
+		s := tracer.StartSpan("op", WithSpanID(0x1f)).(*span)
+		upper := s.context.trace.propagatingTags[keyTraceID128]
+		assert.Len(upper, 16)
+		assert.Equal("000000000000001f", TraceIDFormat(s, IDFormatHex16))
+		assert.Equal(upper+"000000000000001f", TraceIDFormat(s, IDFormatHex32))
+	})
+
+	t.Run("noop", func(t *testing.T) {
+		assert.Equal(t, "", TraceIDFormat(internal.NoopSpan{}, IDFormatDecimal))
+		assert.Equal(t, "", TraceIDFormat(nil, IDFormatHex32))
+	})
+}
+
+func TestSamplingPriority(t *testing.T) {
+	assert := assert.New(t)
+	tracer, _, _, stop := startTestTracer(t)
+	defer stop()
+
+	s := tracer.StartSpan("op")
+	p, ok := SamplingPriority(s)
+	assert.True(ok)
+	assert.Equal(ext.PriorityAutoKeep, p)
+
+	s.SetTag(ext.ManualDrop, true)
+	p, ok = SamplingPriority(s)
+	assert.True(ok)
+	assert.Equal(ext.PriorityUserReject, p)
+
+	_, ok = SamplingPriority(internal.NoopSpan{})
+	assert.False(ok)
+}
@@ -0,0 +1,156 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package tracer
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// keyPartialVersion is the metric holding the version of the snapshots of
+	// the long-running spans, which starts at 1 and is incremented by each of
+	// them, so that the latest one can be told apart.
+	keyPartialVersion = "_dd.partial_version"
+	// keyWasLongRunning is the metric set on the finished spans of which
+	// snapshots were reported.
+	keyWasLongRunning = "_dd.was_long_running"
+
+	// maxLongRunningAge is the duration after which unfinished spans are no
+	// longer tracked, so that the spans which are never finished don't leak.
+	maxLongRunningAge = 12 * time.Hour
+)
+
+// longRunningCheckInterval is the interval at which the unfinished spans are
+// checked for being long-running; replaced in tests.
+var longRunningCheckInterval = time.Second
+
+// longRunningSpans tracks the unfinished spans of the tracer, in order to
+// report snapshots of the ones running for longer than the threshold, see
+// WithLongRunningSpans.
+type longRunningSpans struct {
+	threshold time.Duration
+
+	mu    sync.Mutex
+	spans map[*span]*longRunningSpan // unfinished spans
+}
+
+// longRunningSpan holds the reporting state of an unfinished span.
+type longRunningSpan struct {
+	version    int   // number of snapshots reported
+	lastReport int64 // Unix nanoseconds of the last snapshot, or of the span start
+}
+
+func newLongRunningSpans(threshold time.Duration) *longRunningSpans {
+	return &longRunningSpans{
+		threshold: threshold,
+		spans:     make(map[*span]*longRunningSpan),
+	}
+}
+
+// add starts tracking the unfinished span s, which was started at start.
+func (l *longRunningSpans) add(s *span, start int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.spans[s] = &longRunningSpan{lastReport: start}
+}
+
+// remove stops tracking s, which is being finished, and returns true when
+// snapshots of it were reported. It must be called without holding the lock
+// of s, which snapshots acquires while holding l.mu.
+func (l *longRunningSpans) remove(s *span) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	lr, ok := l.spans[s]
+	if !ok {
+		return false
+	}
+	delete(l.spans, s)
+	return lr.version > 0
+}
+
+// snapshots returns the snapshots of the kept spans which were not reported
+// during the last threshold, as of now.
+func (l *longRunningSpans) snapshots(now int64) []*span {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var snapshots []*span
+	for s, lr := range l.spans {
+		if now-lr.lastReport < int64(l.threshold) {
+			continue
+		}
+		snap, start := snapshot(s, now)
+		if snap == nil || now-start > int64(maxLongRunningAge) {
+			// finished concurrently, or abandoned
+			delete(l.spans, s)
+			continue
+		}
+		lr.lastReport = now
+		if p, ok := s.context.samplingPriority(); !ok || p <= 0 {
+			// the trace won't be kept
+			continue
+		}
+		lr.version++
+		snap.Metrics[keyPartialVersion] = float64(lr.version)
+		snapshots = append(snapshots, snap)
+	}
+	return snapshots
+}
+
+// snapshot returns a copy of the unfinished span s with its duration as of now,
+// along with its start, or nil if it finished.
+func snapshot(s *span, now int64) (*span, int64) {
+	s.RLock()
+	defer s.RUnlock()
+	if s.finished {
+		return nil, 0
+	}
+	snap := &span{
+		Name:     s.Name,
+		Service:  s.Service,
+		Resource: s.Resource,
+		Type:     s.Type,
+		Start:    s.Start,
+		Duration: now - s.Start,
+		Meta:     make(map[string]string, len(s.Meta)),
+		Metrics:  make(map[string]float64, len(s.Metrics)+1),
+		SpanID:   s.SpanID,
+		TraceID:  s.TraceID,
+		ParentID: s.ParentID,
+		Error:    s.Error,
+		context:  s.context,
+		finished: true,
+	}
+	for k, v := range s.Meta {
+		snap.Meta[k] = v
+	}
+	for k, v := range s.Metrics {
+		snap.Metrics[k] = v
+	}
+	return snap, s.Start
+}
+
+// reportLongRunningSpans periodically sends the snapshots of the long-running
+// spans until the tracer is stopped.
+func (t *tracer) reportLongRunningSpans(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			for _, s := range t.longRunning.snapshots(now()) {
+				// the snapshots are copies of unfinished spans, which are only
+				// scrubbed once finished
+				if len(t.config.tagScrubbers) > 0 {
+					s.scrub(t.config.tagScrubbers)
+				}
+				t.pushTrace(&finishedTrace{spans: []*span{s}, willSend: true})
+			}
+		case <-t.stop:
+			return
+		}
+	}
+}
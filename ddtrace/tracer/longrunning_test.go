@@ -0,0 +1,124 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package tracer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+)
+
+func TestLongRunningSpans(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		tracer, _, _, stop := startTestTracer(t)
+		defer stop()
+		assert.Nil(t, tracer.longRunning)
+	})
+
+	t.Run("threshold", func(t *testing.T) {
+		c := newConfig(WithLongRunningSpans(time.Millisecond))
+		assert.Equal(t, time.Second, c.longRunningThreshold)
+		c = newConfig(WithLongRunningSpans(-1))
+		assert.Equal(t, time.Duration(-1), c.longRunningThreshold)
+	})
+
+	t.Run("snapshots", func(t *testing.T) {
+		tracer, _, _, stop := startTestTracer(t, WithLongRunningSpans(time.Minute))
+		defer stop()
+		l := tracer.longRunning
+
+		start := time.Now()
+		root := tracer.StartSpan("root", StartTime(start), Tag("k", "v")).(*span)
+		child := tracer.StartSpan("child", StartTime(start), ChildOf(root.Context()))
+		dropped := tracer.StartSpan("dropped", StartTime(start), Tag(ext.ManualDrop, true))
+
+		assert.Empty(t, l.snapshots(start.Add(time.Second).UnixNano()))
+
+		snaps := l.snapshots(start.Add(time.Minute).UnixNano())
+		require.Len(t, snaps, 2)
+		for _, s := range snaps {
+			assert.Equal(t, time.Minute.Nanoseconds(), s.Duration)
+			assert.Equal(t, 1., s.Metrics[keyPartialVersion])
+		}
+		child.Finish()
+
+		// not reported again before another threshold
+		assert.Empty(t, l.snapshots(start.Add(90*time.Second).UnixNano()))
+		snaps = l.snapshots(start.Add(2 * time.Minute).UnixNano())
+		require.Len(t, snaps, 1)
+		snap := snaps[0]
+		assert.Equal(t, "root", snap.Name)
+		assert.Equal(t, root.SpanID, snap.SpanID)
+		assert.Equal(t, "v", snap.Meta["k"])
+		assert.Equal(t, 2., snap.Metrics[keyPartialVersion])
+
+		// the snapshots are copies
+		root.SetTag("k", "w")
+		assert.Equal(t, "v", snap.Meta["k"])
+		root.Finish()
+		assert.Equal(t, 1., root.Metrics[keyWasLongRunning])
+		assert.NotContains(t, root.Metrics, keyPartialVersion)
+		dropped.Finish()
+		assert.Empty(t, l.spans)
+	})
+
+	t.Run("abandoned", func(t *testing.T) {
+		tracer, _, _, stop := startTestTracer(t, WithLongRunningSpans(time.Minute))
+		defer stop()
+		l := tracer.longRunning
+
+		start := time.Now()
+		tracer.StartSpan("abandoned", StartTime(start))
+		assert.Empty(t, l.snapshots(start.Add(maxLongRunningAge+time.Minute).UnixNano()))
+		assert.Empty(t, l.spans)
+	})
+
+	t.Run("reported", func(t *testing.T) {
+		defer func(old time.Duration) { longRunningCheckInterval = old }(longRunningCheckInterval)
+		longRunningCheckInterval = 10 * time.Millisecond
+		tracer, transport, flush, stop := startTestTracer(t, WithLongRunningSpans(time.Second))
+		defer stop()
+
+		s := tracer.StartSpan("hung", StartTime(time.Now().Add(-2*time.Second)))
+		flush(1)
+		snap := transport.Traces()[0]
+		require.Len(t, snap, 1)
+		assert.Equal(t, "hung", snap[0].Name)
+		assert.Equal(t, 1., snap[0].Metrics[keyPartialVersion])
+		assert.GreaterOrEqual(t, snap[0].Duration, (2 * time.Second).Nanoseconds())
+
+		s.Finish()
+		flush(1)
+		final := transport.Traces()[0]
+		require.Len(t, final, 1)
+		assert.Equal(t, 1., final[0].Metrics[keyWasLongRunning])
+		assert.NotContains(t, final[0].Metrics, keyPartialVersion)
+	})
+
+	t.Run("scrubbed", func(t *testing.T) {
+		defer func(old time.Duration) { longRunningCheckInterval = old }(longRunningCheckInterval)
+		longRunningCheckInterval = 10 * time.Millisecond
+		t.Setenv("DD_TRACE_SCRUB_TAGS", `[{"key": "user.email"}, {"pattern": "\\d{4}-\\d{4}"}]`)
+		tracer, transport, flush, stop := startTestTracer(t, WithLongRunningSpans(time.Second))
+		defer stop()
+
+		s := tracer.StartSpan("hung",
+			StartTime(time.Now().Add(-2*time.Second)),
+			ResourceName("GET /cards/4111-1111"),
+			Tag("user.email", "jane@example.com"),
+		)
+		flush(1)
+		snap := transport.Traces()[0]
+		require.Len(t, snap, 1)
+		assert.Equal(t, "GET /cards/"+redacted, snap[0].Resource)
+		assert.Equal(t, redacted, snap[0].Meta["user.email"])
+		s.Finish()
+	})
+}
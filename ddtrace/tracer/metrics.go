@@ -6,8 +6,11 @@
 package tracer
 
 import (
+	"errors"
 	"runtime"
 	"runtime/debug"
+	"strconv"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -37,12 +40,21 @@ func (t *tracer) reportRuntimeMetrics(interval time.Duration) {
 		PauseQuantiles: make([]time.Duration, 5),
 	}
 
+	rm := newRuntimeMetricsReader()
+	// The service and env tags are global tags of the statsd client, while its
+	// version tag holds the version of the tracer.
+	var tags []string
+	if t.config.version != "" {
+		tags = []string{"service_version:" + t.config.version}
+	}
+
 	tick := time.NewTicker(interval)
 	defer tick.Stop()
 	for {
 		select {
 		case <-tick.C:
 			log.Debug("Reporting runtime metrics...")
+			rm.report(t.config.statsd, tags)
 			runtime.ReadMemStats(&ms)
 			debug.ReadGCStats(&gc)
 
@@ -93,6 +105,9 @@ func (t *tracer) reportRuntimeMetrics(interval time.Duration) {
 	}
 }
 
+// reportHealthMetrics periodically reports the health metrics of the tracer at
+// the given interval: the number of spans started, finished and dropped, the
+// failed flushes and the fill level of the payload queue.
 func (t *tracer) reportHealthMetrics(interval time.Duration) {
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
@@ -102,8 +117,56 @@ func (t *tracer) reportHealthMetrics(interval time.Duration) {
 			t.config.statsd.Count("datadog.tracer.spans_started", int64(atomic.SwapUint32(&t.spansStarted, 0)), nil, 1)
 			t.config.statsd.Count("datadog.tracer.spans_finished", int64(atomic.SwapUint32(&t.spansFinished, 0)), nil, 1)
 			t.config.statsd.Count("datadog.tracer.traces_dropped", int64(atomic.SwapUint32(&t.tracesDropped, 0)), []string{"reason:trace_too_large"}, 1)
+			t.config.statsd.Count("datadog.tracer.spans_dropped", int64(atomic.SwapUint32(&t.spansDropped, 0)), []string{"reason:queue_full"}, 1)
+			t.config.statsd.Count("datadog.tracer.spans_dropped", int64(atomic.SwapUint32(&t.spansPostProcessorDropped, 0)), []string{"reason:post_processor"}, 1)
+			t.config.statsd.Gauge("datadog.tracer.queue.enqueued_traces", float64(len(t.out)), nil, 1)
+			t.config.statsd.Gauge("datadog.tracer.queue.fill_ratio", float64(len(t.out))/float64(cap(t.out)), nil, 1)
+			t.health.report(t.config.statsd)
 		case <-t.stop:
 			return
 		}
 	}
 }
+
+// healthMetrics holds the health metrics recorded by the trace writer until
+// they are reported by reportHealthMetrics.
+type healthMetrics struct {
+	mu          sync.Mutex
+	flushErrors int64
+	// apiErrors holds the number of payloads rejected by the agent, by status code.
+	apiErrors map[int]int64
+}
+
+func newHealthMetrics() *healthMetrics {
+	return &healthMetrics{apiErrors: make(map[int]int64)}
+}
+
+// recordFlushError records a flush which failed with err. It is a no-op on a nil *healthMetrics.
+func (h *healthMetrics) recordFlushError(err error) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.flushErrors++
+	var apiErr *apiError
+	if errors.As(err, &apiErr) {
+		h.apiErrors[apiErr.statusCode]++
+	}
+}
+
+// report reports the recorded health metrics to statsd and resets them.
+func (h *healthMetrics) report(statsd statsdClient) {
+	var apiErrors map[int]int64
+	h.mu.Lock()
+	flushErrors := h.flushErrors
+	h.flushErrors = 0
+	if len(h.apiErrors) > 0 {
+		apiErrors, h.apiErrors = h.apiErrors, make(map[int]int64)
+	}
+	h.mu.Unlock()
+	statsd.Count("datadog.tracer.flush_errors", flushErrors, nil, 1)
+	for code, n := range apiErrors {
+		statsd.Count("datadog.tracer.api.errors", n, []string{"status_code:" + strconv.Itoa(code)}, 1)
+	}
+}
@@ -308,3 +308,40 @@ func TestTracerMetrics(t *testing.T) {
 	assert.Equal(1, calls["datadog.tracer.stopped"])
 	assert.True(tg.closed)
 }
+
+func TestReportHealthMetricsDropped(t *testing.T) {
+	assert := assert.New(t)
+	var tg testStatsdClient
+	trc := newUnstartedTracer(withStatsdClient(&tg))
+	trc.out = make(chan *finishedTrace, 1)
+	trc.pushTrace(&finishedTrace{spans: []*span{newBasicSpan("a")}})
+	trc.pushTrace(&finishedTrace{spans: []*span{newBasicSpan("b"), newBasicSpan("c")}})
+	trc.health.recordFlushError(&apiError{statusCode: 413, msg: "Request Entity Too Large"})
+	trc.health.recordFlushError(errors.New("connection refused"))
+
+	trc.wg.Add(1)
+	go func() {
+		defer trc.wg.Done()
+		trc.reportHealthMetrics(time.Millisecond)
+	}()
+	err := tg.Wait(8, time.Second)
+	close(trc.stop)
+	trc.wg.Wait()
+	assert.NoError(err)
+
+	counts := tg.Counts()
+	assert.Equal(int64(2), counts["datadog.tracer.spans_dropped"])
+	assert.Equal(int64(2), counts["datadog.tracer.flush_errors"])
+	assert.Equal(int64(1), counts["datadog.tracer.api.errors"])
+	for _, c := range tg.CountCalls() {
+		if c.name == "datadog.tracer.api.errors" {
+			assert.Equal([]string{"status_code:413"}, c.tags)
+		}
+	}
+	gauges := make(map[string]float64)
+	for _, c := range tg.GaugeCalls() {
+		gauges[c.name] = c.floatVal
+	}
+	assert.Equal(1.0, gauges["datadog.tracer.queue.enqueued_traces"])
+	assert.Equal(1.0, gauges["datadog.tracer.queue.fill_ratio"])
+}
@@ -12,11 +12,14 @@ import (
 	"math"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
@@ -24,6 +27,7 @@ import (
 	"gopkg.in/DataDog/dd-trace-go.v1/internal"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/namingschema"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/traceprof"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/version"
 
@@ -49,16 +53,45 @@ type config struct {
 	debug bool
 
 	// agent holds the capabilities of the agent and determines some
-	// of the behaviour of the tracer.
+	// of the behaviour of the tracer. It is reloaded when the state of the
+	// agent changes, see refreshAgentFeatures.
 	agent agentFeatures
 
+	// agentMu guards agent and agentRefreshing.
+	agentMu sync.RWMutex
+
+	// agentRefreshing reports whether the capabilities of the agent are being
+	// reloaded.
+	agentRefreshing bool
+
 	// featureFlags specifies any enabled feature flags.
 	featureFlags map[string]struct{}
 
+	// integrations holds the integrations disabled by the environment, whose
+	// spans are not started.
+	integrations *internal.Integrations
+
+	// globalConfig holds the *globalConfig snapshot of the settings which can be
+	// updated at runtime, such as through remote configuration. It is unset until
+	// they are, see loadGlobalConfig.
+	globalConfig atomic.Value
+
 	// logToStdout reports whether we should log all traces to the standard
 	// output instead of using the agent. This is used in Lambda environments.
 	logToStdout bool
 
+	// lambdaExtension reports whether traces are sent to the Datadog Lambda
+	// extension, which acts as the agent in Lambda environments.
+	lambdaExtension bool
+
+	// ciVisibility reports whether the tracer runs in CI Visibility mode, where
+	// the traces of the tests are sent to the CI Visibility intake.
+	ciVisibility bool
+
+	// ciVisibilityAgentless reports whether, in CI Visibility mode, the traces
+	// are sent directly to the intake instead of through the agent.
+	ciVisibilityAgentless bool
+
 	// logStartup, when true, causes various startup info to be written
 	// when the tracer starts.
 	logStartup bool
@@ -93,6 +126,10 @@ type config struct {
 	// transport specifies the Transport interface which will be used to send data to the agent.
 	transport transport
 
+	// additionalAgents holds the URLs of the agents the traces are dual-shipped
+	// to, see WithAdditionalAgent.
+	additionalAgents []string
+
 	// propagator propagates span context cross-process
 	propagator Propagator
 
@@ -142,8 +179,78 @@ type config struct {
 
 	// enabled reports whether tracing is enabled.
 	enabled bool
+
+	// streaming reports whether payloads are streamed to the agent, see
+	// WithStreamingTransport.
+	streaming bool
+
+	// errorTracking reports whether the spans with errors are given the error
+	// tracking tags, see WithErrorTracking.
+	errorTracking bool
+
+	// flushBufferSize specifies the initial capacity of the buffers in which the
+	// payloads are encoded.
+	flushBufferSize int
+
+	// partialFlushEnabled reports whether finished spans of long-running traces are
+	// flushed before the whole trace finishes.
+	partialFlushEnabled bool
+
+	// partialFlushMinSpans is the number of finished spans in a trace which triggers
+	// a partial flush, when partial flushing is enabled.
+	partialFlushMinSpans int
+
+	// spanPooling reports whether spans are reused once they were sent.
+	spanPooling bool
+
+	// postProcessors are run in order on the finished spans before they are sent,
+	// see WithPostProcessor.
+	postProcessors []func(ReadOnlySpan) bool
+
+	// tagScrubbers are run in order on the resource names and string tags of
+	// the spans when they finish, see WithTagScrubber.
+	tagScrubbers []func(key, value string) string
+
+	// baggageTagKeys holds the keys of the baggage items which are copied into the tags of the
+	// spans carrying them, prefixed with baggageTagPrefix.
+	baggageTagKeys []string
+
+	// propagatedTraceTagKeys holds the keys of the trace tags set with SetTraceTag
+	// which are also propagated downstream, as _dd.p.<key> tags.
+	propagatedTraceTagKeys []string
+
+	// dataStreamsMonitoringEnabled specifies whether Data Streams Monitoring is enabled.
+	dataStreamsMonitoringEnabled bool
+
+	// traceID128BitEnabled specifies whether new traces are given 128-bit trace IDs.
+	traceID128BitEnabled bool
+
+	// statsComputationEnabled reports whether the tracer computes the APM stats
+	// of the spans itself, instead of leaving it to the agent. Unless it was set,
+	// as reported by statsComputationSet, it is decided by the agent.
+	statsComputationEnabled bool
+	statsComputationSet     bool
+
+	// peerServiceDefaults reports whether the client and producer spans are given
+	// a default peer.service tag. Unless it was set, as reported by
+	// peerServiceDefaultsSet, it is decided by the agent.
+	peerServiceDefaults    bool
+	peerServiceDefaultsSet bool
+
+	// traceProtocol is the version of the agent API the traces are sent with,
+	// either traceProtocolV04 or traceProtocolV07. When empty, v0.7 is used if
+	// the agent supports it.
+	traceProtocol string
+
+	// longRunningThreshold is the duration after which the unfinished spans are
+	// reported, see WithLongRunningSpans. 0 disables it.
+	longRunningThreshold time.Duration
 }
 
+// defaultPartialFlushMinSpans is the default number of finished spans in a trace
+// which triggers a partial flush.
+const defaultPartialFlushMinSpans = 1000
+
 // HasFeature reports whether feature f is enabled.
 func (c *config) HasFeature(f string) bool {
 	_, ok := c.featureFlags[strings.TrimSpace(f)]
@@ -190,7 +297,14 @@ func newConfig(opts ...StartOption) *config {
 	c.sampler = NewAllSampler()
 	c.agentAddr = resolveAgentAddr()
 	c.httpClient = defaultHTTPClient()
+	if v := os.Getenv("DD_TRACE_AGENT_URL"); v != "" {
+		withAgentURL(v)(c)
+	}
+	if v := os.Getenv("DD_TRACE_PIPE_NAME"); v != "" {
+		WithNamedPipe(v)(c)
+	}
 
+	c.integrations = internal.NewIntegrations()
 	if internal.BoolEnv("DD_TRACE_ANALYTICS_ENABLED", false) {
 		globalconfig.SetAnalyticsRate(1.0)
 	}
@@ -216,6 +330,18 @@ func newConfig(opts ...StartOption) *config {
 		c.serviceName = v
 		globalconfig.SetServiceName(v)
 	}
+	if v := os.Getenv("DD_TRACE_CLIENT_IP_HEADER"); v != "" {
+		globalconfig.SetClientIPHeader(v)
+	}
+	if v := os.Getenv("DD_TRACE_CLIENT_IP_HEADERS"); v != "" {
+		globalconfig.SetClientIPHeaders(globalconfig.ParseClientIPHeaders(v))
+	}
+	if v := os.Getenv("DD_TRACE_CLIENT_IP_HEADER_DISABLED"); v != "" {
+		globalconfig.SetClientIPHeaderDisabled(internal.BoolEnv("DD_TRACE_CLIENT_IP_HEADER_DISABLED", false))
+	}
+	if v := os.Getenv("DD_TRACE_CLIENT_IP_ALLOW_PRIVATE"); v != "" {
+		globalconfig.SetClientIPAllowPrivate(internal.BoolEnv("DD_TRACE_CLIENT_IP_ALLOW_PRIVATE", false))
+	}
 	if ver := os.Getenv("DD_VERSION"); ver != "" {
 		c.version = ver
 	}
@@ -228,14 +354,65 @@ func newConfig(opts ...StartOption) *config {
 	if _, ok := os.LookupEnv("AWS_LAMBDA_FUNCTION_NAME"); ok {
 		// AWS_LAMBDA_FUNCTION_NAME being set indicates that we're running in an AWS Lambda environment.
 		// See: https://docs.aws.amazon.com/lambda/latest/dg/configuration-envvars.html
-		c.logToStdout = true
+		if _, err := os.Stat(lambdaExtensionPath); err == nil {
+			// the Datadog Lambda extension receives the traces like the agent
+			c.lambdaExtension = true
+		} else {
+			c.logToStdout = true
+		}
 	}
+	c.ciVisibility = internal.BoolEnv("DD_CIVISIBILITY_ENABLED", false)
+	c.ciVisibilityAgentless = internal.BoolEnv("DD_CIVISIBILITY_AGENTLESS_ENABLED", false)
 	c.logStartup = internal.BoolEnv("DD_TRACE_STARTUP_LOGS", true)
 	c.runtimeMetrics = internal.BoolEnv("DD_RUNTIME_METRICS_ENABLED", false)
 	c.debug = internal.BoolEnv("DD_TRACE_DEBUG", false)
 	c.enabled = internal.BoolEnv("DD_TRACE_ENABLED", true)
 	c.profilerEndpoints = internal.BoolEnv(traceprof.EndpointEnvVar, true)
 	c.profilerHotspots = internal.BoolEnv(traceprof.CodeHotspotsEnvVar, true)
+	c.streaming = internal.BoolEnv("DD_TRACE_STREAMING_ENABLED", false)
+	c.errorTracking = internal.BoolEnv("DD_TRACE_ERROR_TRACKING_ENABLED", false)
+	c.partialFlushEnabled = internal.BoolEnv("DD_TRACE_PARTIAL_FLUSH_ENABLED", false)
+	c.partialFlushMinSpans = internal.IntEnv("DD_TRACE_PARTIAL_FLUSH_MIN_SPANS", defaultPartialFlushMinSpans)
+	c.spanPooling = internal.BoolEnv("DD_TRACE_SPAN_POOLING_ENABLED", false)
+	if v := os.Getenv("DD_TRACE_SCRUB_TAGS"); v != "" {
+		rules, err := unmarshalScrubRules([]byte(v))
+		if err != nil {
+			log.Warn("DIAGNOSTICS Error(s) parsing DD_TRACE_SCRUB_TAGS: %v", err)
+		}
+		for _, r := range rules {
+			WithTagScrubber(r.scrub)(c)
+		}
+	}
+	if v := os.Getenv("DD_TRACE_BAGGAGE_TAG_KEYS"); v != "" {
+		WithBaggageTagKeys(strings.Split(v, ",")...)(c)
+	}
+	if v := os.Getenv("DD_TRACE_PROPAGATED_TRACE_TAG_KEYS"); v != "" {
+		WithPropagatedTraceTags(strings.Split(v, ",")...)(c)
+	}
+	c.dataStreamsMonitoringEnabled = internal.BoolEnv("DD_DATA_STREAMS_ENABLED", false)
+	c.traceID128BitEnabled = internal.BoolEnv("DD_TRACE_128_BIT_TRACEID_GENERATION_ENABLED", false)
+	if _, ok := os.LookupEnv("DD_TRACE_STATS_COMPUTATION_ENABLED"); ok {
+		c.statsComputationEnabled = internal.BoolEnv("DD_TRACE_STATS_COMPUTATION_ENABLED", false)
+		c.statsComputationSet = true
+	}
+	if _, ok := os.LookupEnv("DD_TRACE_PEER_SERVICE_DEFAULTS_ENABLED"); ok {
+		c.peerServiceDefaults = internal.BoolEnv("DD_TRACE_PEER_SERVICE_DEFAULTS_ENABLED", false)
+		c.peerServiceDefaultsSet = true
+	}
+	switch v := os.Getenv("DD_TRACE_AGENT_PROTOCOL_VERSION"); v {
+	case "":
+	case "0.4", "v0.4":
+		c.traceProtocol = traceProtocolV04
+	case "0.7", "v0.7":
+		c.traceProtocol = traceProtocolV07
+	default:
+		log.Warn("Ignoring DD_TRACE_AGENT_PROTOCOL_VERSION=%q: only 0.4 and 0.7 are supported.", v)
+	}
+	if c.partialFlushEnabled && c.partialFlushMinSpans < 1 {
+		// same as WithPartialFlushing: values below 1 disable partial flushing
+		log.Warn("DD_TRACE_PARTIAL_FLUSH_MIN_SPANS=%d is below 1, partial flushing is disabled.", c.partialFlushMinSpans)
+		c.partialFlushEnabled = false
+	}
 
 	for _, fn := range opts {
 		fn(c)
@@ -255,6 +432,8 @@ func newConfig(opts ...StartOption) *config {
 			}
 		}
 	}
+	globalconfig.SetEnv(c.env)
+	globalconfig.SetServiceVersion(c.version)
 	if c.serviceName == "" {
 		if v, ok := c.globalTags["service"]; ok {
 			if s, ok := v.(string); ok {
@@ -266,7 +445,29 @@ func newConfig(opts ...StartOption) *config {
 		}
 	}
 	if c.transport == nil {
-		c.transport = newHTTPTransport(c.agentAddr, c.httpClient)
+		if c.ciVisibility {
+			c.transport = newCIVisibilityTransport(c)
+		} else {
+			if c.streaming {
+				c.transport = newStreamingTransport(c.agentAddr, c.httpClient)
+			} else {
+				c.transport = newHTTPTransport(c.agentAddr, c.httpClient)
+			}
+		}
+	}
+	if len(c.additionalAgents) > 0 && !c.ciVisibility {
+		var additional []transport
+		for _, u := range c.additionalAgents {
+			t, err := newAdditionalAgentTransport(u)
+			if err != nil {
+				log.Warn("Ignoring additional agent %q: %v", u, err)
+				continue
+			}
+			additional = append(additional, t)
+		}
+		if len(additional) > 0 {
+			c.transport = newFanOutTransport(c, c.transport, additional...)
+		}
 	}
 	if c.propagator == nil {
 		envKey := "DD_TRACE_X_DATADOG_TAGS_MAX_LENGTH"
@@ -289,7 +490,10 @@ func newConfig(opts ...StartOption) *config {
 	if c.debug {
 		log.SetLevel(log.LevelDebug)
 	}
-	c.loadAgentFeatures()
+	if c.loadAgentFeatures() && c.ciVisibility && !c.ciVisibilityAgentless && !c.agent.EVPProxy {
+		log.Warn("CI Visibility is enabled, but the agent does not support sending the test spans through %s: "+
+			"upgrade the agent, or enable the agentless mode with DD_CIVISIBILITY_AGENTLESS_ENABLED.", ciVisibilityEVPProxyPath)
+	}
 	if c.statsd == nil {
 		// configure statsd client
 		addr := c.dogstatsdAddr
@@ -311,6 +515,7 @@ func newConfig(opts ...StartOption) *config {
 			// not a valid TCP address, leave it as it is (could be a socket connection)
 		}
 		c.dogstatsdAddr = addr
+		globalconfig.SetDogstatsdAddr(addr)
 		client, err := statsd.New(addr, statsd.WithMaxMessagesPerPayload(40), statsd.WithTags(statsTags(c)))
 		if err != nil {
 			log.Warn("Runtime and health metrics disabled: %v", err)
@@ -351,6 +556,48 @@ func udsClient(socketPath string) *http.Client {
 	}
 }
 
+// pipeClient returns a new http.Client which connects using the given Windows named pipe.
+func pipeClient(pipePath string) *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			DialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+				return dialPipe(ctx, pipePath)
+			},
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: 1 * time.Second,
+		},
+		Timeout: defaultHTTPTimeout,
+	}
+}
+
+// withAgentURL configures the tracer to reach the agent at the given URL, as
+// set by DD_TRACE_AGENT_URL. Only the http and unix schemes are supported, e.g.
+// "http://trace-agent:8126" or "unix:///var/run/datadog/apm.socket".
+func withAgentURL(agentURL string) StartOption {
+	return func(c *config) {
+		u, err := url.Parse(agentURL)
+		if err != nil {
+			log.Warn("Failed to parse DD_TRACE_AGENT_URL: %v", err)
+			return
+		}
+		switch u.Scheme {
+		case "unix":
+			WithUDS(u.Path)(c)
+		case "http":
+			port := u.Port()
+			if port == "" {
+				port = defaultPort
+			}
+			c.agentAddr = net.JoinHostPort(u.Hostname(), port)
+		default:
+			log.Warn("Unsupported scheme %q in DD_TRACE_AGENT_URL, only http and unix are supported.", u.Scheme)
+		}
+	}
+}
+
 // defaultDogstatsdAddr returns the default connection address for Dogstatsd.
 func defaultDogstatsdAddr() string {
 	envHost, envPort := os.Getenv("DD_AGENT_HOST"), os.Getenv("DD_DOGSTATSD_PORT")
@@ -380,12 +627,32 @@ type agentFeatures struct {
 	// the /v0.6/stats endpoint.
 	Stats bool
 
+	// DataStreams reports whether the agent can receive data streams stats on
+	// the /v0.1/pipeline_stats endpoint.
+	DataStreams bool
+
+	// V07 reports whether the agent can receive traces on the /v0.7/traces
+	// endpoint.
+	V07 bool
+
+	// EVPProxy reports whether the agent proxies the requests to the Datadog
+	// intakes on the /evp_proxy/v2 endpoint, as used by CI Visibility.
+	EVPProxy bool
+
+	// PeerTags lists the tags the agent computes the stats of the client and
+	// producer spans by, in addition to their service and resource.
+	PeerTags []string
+
 	// StatsdPort specifies the Dogstatsd port as provided by the agent.
 	// If it's the default, it will be 0, which means 8125.
 	StatsdPort int
 
 	// featureFlags specifies all the feature flags reported by the trace-agent.
 	featureFlags map[string]struct{}
+
+	// state is the Datadog-Agent-State header of the response of the agent
+	// these features were loaded from, which changes along with them.
+	state string
 }
 
 // HasFlag reports whether the agent has set the feat feature flag.
@@ -394,55 +661,187 @@ func (a *agentFeatures) HasFlag(feat string) bool {
 	return ok
 }
 
+// agentFeatures returns the capabilities of the agent, as last loaded.
+func (c *config) agentFeatures() agentFeatures {
+	c.agentMu.RLock()
+	defer c.agentMu.RUnlock()
+	return c.agent
+}
+
+// headerAgentState is the header of the responses of the agent holding a hash
+// of its configuration and version, which changes along with its capabilities.
+const headerAgentState = "Datadog-Agent-State"
+
 // loadAgentFeatures queries the trace-agent for its capabilities and updates
-// the tracer's behaviour.
-func (c *config) loadAgentFeatures() {
-	c.agent = agentFeatures{}
-	if c.logToStdout {
+// the tracer's behaviour. It returns false when they could not be fetched.
+func (c *config) loadAgentFeatures() bool {
+	features, ok := c.fetchAgentFeatures()
+	c.agentMu.Lock()
+	c.agent = features
+	c.agentMu.Unlock()
+	return ok
+}
+
+// fetchAgentFeatures queries the trace-agent for its capabilities. It returns
+// false when they could not be fetched, in which case they are all off.
+func (c *config) fetchAgentFeatures() (features agentFeatures, ok bool) {
+	if c.logToStdout || c.lambdaExtension || (c.ciVisibility && c.ciVisibilityAgentless) {
 		// there is no agent; all features off
-		return
+		return features, true
 	}
 	resp, err := c.httpClient.Get(fmt.Sprintf("http://%s/info", c.agentAddr))
 	if err != nil {
 		log.Error("Loading features: %v", err)
-		return
+		return features, false
 	}
+	defer resp.Body.Close()
 	if resp.StatusCode == http.StatusNotFound {
 		// agent is older than 7.28.0, features not discoverable
-		return
+		return features, true
 	}
-	defer resp.Body.Close()
 	type infoResponse struct {
 		Endpoints     []string `json:"endpoints"`
 		ClientDropP0s bool     `json:"client_drop_p0s"`
 		StatsdPort    int      `json:"statsd_port"`
 		FeatureFlags  []string `json:"feature_flags"`
+		PeerTags      []string `json:"peer_tags"`
 	}
 	var info infoResponse
 	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
 		log.Error("Decoding features: %v", err)
-		return
+		return features, false
 	}
-	c.agent.DropP0s = info.ClientDropP0s
-	c.agent.StatsdPort = info.StatsdPort
+	features.DropP0s = info.ClientDropP0s
+	features.StatsdPort = info.StatsdPort
+	features.PeerTags = info.PeerTags
+	features.state = resp.Header.Get(headerAgentState)
 	for _, endpoint := range info.Endpoints {
-		switch endpoint {
+		switch strings.TrimSuffix(endpoint, "/") {
 		case "/v0.6/stats":
-			c.agent.Stats = true
+			features.Stats = true
+		case "/v0.1/pipeline_stats":
+			features.DataStreams = true
+		case "/v0.7/traces":
+			features.V07 = true
+		case "/evp_proxy/v2":
+			features.EVPProxy = true
 		}
 	}
-	c.agent.featureFlags = make(map[string]struct{}, len(info.FeatureFlags))
+	features.featureFlags = make(map[string]struct{}, len(info.FeatureFlags))
 	for _, flag := range info.FeatureFlags {
-		c.agent.featureFlags[flag] = struct{}{}
+		features.featureFlags[flag] = struct{}{}
+	}
+	return features, true
+}
+
+// refreshAgentFeatures reloads the capabilities of the agent in the background
+// when state, the Datadog-Agent-State header of one of its responses, differs
+// from the state they were loaded with, as happens when the agent restarts with
+// another version or configuration, or when it was unreachable when the tracer
+// started. The current capabilities are kept when they can't be reloaded, which
+// is then attempted again on the next response.
+func (c *config) refreshAgentFeatures(state string) {
+	c.agentMu.Lock()
+	if state == "" || state == c.agent.state || c.agentRefreshing {
+		c.agentMu.Unlock()
+		return
 	}
+	c.agentRefreshing = true
+	c.agentMu.Unlock()
+	go func() {
+		features, ok := c.fetchAgentFeatures()
+		c.agentMu.Lock()
+		defer c.agentMu.Unlock()
+		c.agentRefreshing = false
+		if !ok {
+			return
+		}
+		if features.state == "" {
+			// don't reload them again on every response
+			features.state = state
+		}
+		c.agent = features
+		log.Debug("Reloaded the features of the agent, as its state changed to %q.", state)
+	}()
 }
 
+// canComputeStats reports whether the tracer computes the APM stats of the spans
+// and sends them to the agent: client stats computation must be enabled, either
+// by WithClientStatsComputation, through the legacy "discovery" feature flag or,
+// when it was not configured, by the agent reporting it can drop the P0 traces,
+// and the agent must accept them.
 func (c *config) canComputeStats() bool {
-	return c.agent.Stats && c.HasFeature("discovery")
+	c.agentMu.RLock()
+	defer c.agentMu.RUnlock()
+	return c.canComputeStatsLocked()
+}
+
+// canComputeStatsLocked is canComputeStats for callers holding c.agentMu.
+func (c *config) canComputeStatsLocked() bool {
+	// the CI Visibility intake does not receive stats
+	if c.ciVisibility || !c.agent.Stats {
+		return false
+	}
+	if c.HasFeature("discovery") {
+		return true
+	}
+	if c.statsComputationSet {
+		return c.statsComputationEnabled
+	}
+	return c.agent.DropP0s
 }
 
 func (c *config) canDropP0s() bool {
-	return c.canComputeStats() && c.agent.DropP0s
+	c.agentMu.RLock()
+	defer c.agentMu.RUnlock()
+	return c.canComputeStatsLocked() && c.agent.DropP0s
+}
+
+// canSetPeerService reports whether the client and producer spans are given a
+// default peer.service tag: either when enabled by WithPeerServiceDefaults or,
+// when it was not configured, when the v1 naming schema is in use or the agent
+// computes the stats of these spans by peer tags.
+func (c *config) canSetPeerService() bool {
+	if c.peerServiceDefaultsSet {
+		return c.peerServiceDefaults
+	}
+	if namingschema.GetVersion() == namingschema.SchemaV1 {
+		return true
+	}
+	c.agentMu.RLock()
+	defer c.agentMu.RUnlock()
+	return len(c.agent.PeerTags) > 0
+}
+
+// The versions of the agent API the traces can be sent with.
+const (
+	// traceProtocolV04 sends the traces as an array of arrays of spans.
+	traceProtocolV04 = "v0.4"
+	// traceProtocolV07 sends the traces as the chunks of a tracer payload,
+	// along with the metadata of the tracer.
+	traceProtocolV07 = "v0.7"
+)
+
+// currentTraceProtocol returns the version of the agent API the next payloads of
+// traces are sent with: the one set with DD_TRACE_AGENT_PROTOCOL_VERSION, if any,
+// or else v0.7 when the agent supports it. The traces sent to the additional
+// agents, to CI Visibility, or through a custom transport, are always sent with
+// v0.4.
+func (c *config) currentTraceProtocol() string {
+	switch c.transport.(type) {
+	case *httpTransport, *streamingTransport:
+	default:
+		return traceProtocolV04
+	}
+	if c.traceProtocol != "" {
+		return c.traceProtocol
+	}
+	c.agentMu.RLock()
+	defer c.agentMu.RUnlock()
+	if c.agent.V07 {
+		return traceProtocolV07
+	}
+	return traceProtocolV04
 }
 
 func statsTags(c *config) []string {
@@ -525,9 +924,24 @@ func WithDebugMode(enabled bool) StartOption {
 }
 
 // WithLambdaMode enables lambda mode on the tracer, for use with AWS Lambda.
+// In lambda mode, traces are written to the standard output for the Datadog
+// Forwarder, even when the Datadog Lambda extension is detected.
 func WithLambdaMode(enabled bool) StartOption {
 	return func(c *config) {
 		c.logToStdout = enabled
+		if enabled {
+			c.lambdaExtension = false
+		}
+	}
+}
+
+// WithCIVisibility enables the CI Visibility mode of the tracer, for tracing tests, e.g. using
+// contrib/testing. In CI Visibility mode, the traces are sent to the CI Visibility intake through
+// the agent or, when the DD_CIVISIBILITY_AGENTLESS_ENABLED environment variable is true, directly,
+// using the API key set by DD_API_KEY. It can also be enabled using DD_CIVISIBILITY_ENABLED.
+func WithCIVisibility(enabled bool) StartOption {
+	return func(c *config) {
+		c.ciVisibility = enabled
 	}
 }
 
@@ -569,6 +983,20 @@ func WithAgentAddr(addr string) StartOption {
 	}
 }
 
+// WithAdditionalAgent dual-ships the traces and the stats sent to the agent to
+// the additional agent at the given URL, e.g. "http://apm-gateway:8126" or
+// "unix:///var/run/datadog/apm.socket", which helps migrating from an agent to
+// another. The payloads are sent to the additional agents in the background, so
+// that their failures don't affect the main agent, which alone provides the
+// sampling rates. The failures of each additional agent are reported by the
+// datadog.tracer.additional_agent.* health metrics, tagged with its endpoint.
+// This option may be used multiple times, it has no effect in CI Visibility mode.
+func WithAdditionalAgent(agentURL string) StartOption {
+	return func(c *config) {
+		c.additionalAgents = append(c.additionalAgents, agentURL)
+	}
+}
+
 // WithEnv sets the environment to which all traces started by the tracer will be submitted.
 // The default value is the environment variable DD_ENV, if it is set.
 func WithEnv(env string) StartOption {
@@ -623,11 +1051,227 @@ func WithHTTPClient(client *http.Client) StartOption {
 	}
 }
 
+// WithStreamingTransport enables the streaming of traces to the agent. When enabled, the finished
+// traces are held until they are flushed, and encoded while they are sent: the encoder writes them
+// to the request through a pipe, which is streamed to the agent with chunked transfer encoding over
+// persistent connections. It saves the memory of the encoded payloads of high-volume services, and
+// the latency of encoding them before sending them. The streamed spans are not reused by span
+// pooling, see WithSpanPooling. It can also be enabled using the DD_TRACE_STREAMING_ENABLED
+// environment variable.
+func WithStreamingTransport(enabled bool) StartOption {
+	return func(c *config) {
+		c.streaming = enabled
+	}
+}
+
+// WithErrorTracking specifies whether the spans finished, or tagged, with errors are given
+// tags helping to track the errors: their error.stack is sanitized from the paths of the build
+// machine, the type and message of the root causes of the errors wrapping other errors, found
+// through their Unwrap methods, are set as error.root_cause.type and error.root_cause.msg, or
+// error.root_cause.<i>.type and error.root_cause.<i>.msg when they have several, e.g. when they
+// were made using errors.Join, and error.fingerprint holds a fingerprint of the root cause types
+// and of the stack functions, which is stable across the occurrences of the errors. It can also
+// be enabled using the DD_TRACE_ERROR_TRACKING_ENABLED environment variable.
+func WithErrorTracking(enabled bool) StartOption {
+	return func(c *config) {
+		c.errorTracking = enabled
+	}
+}
+
+// WithFlushBufferSize sets the initial capacity, in bytes, of the buffers in which the
+// traces are encoded before being flushed to the agent. The buffers are reused across
+// flushes, so that they only need to grow the first time they are filled. Setting it
+// to the size of the usual payloads of the service avoids growing them altogether.
+// Negative values are ignored. The default is 0: the buffers start empty.
+func WithFlushBufferSize(size int) StartOption {
+	return func(c *config) {
+		if size >= 0 {
+			c.flushBufferSize = size
+		}
+	}
+}
+
+// WithPartialFlushing enables flushing the finished spans of a trace as soon as
+// their number reaches numSpans, rather than waiting for the whole trace to finish.
+// It prevents long-running traces from holding all their spans in memory. Partial
+// flushing can also be enabled using the DD_TRACE_PARTIAL_FLUSH_ENABLED and
+// DD_TRACE_PARTIAL_FLUSH_MIN_SPANS environment variables, which this option
+// overrides. Values of numSpans below 1 disable partial flushing, and so do values
+// of DD_TRACE_PARTIAL_FLUSH_MIN_SPANS below 1.
+func WithPartialFlushing(numSpans int) StartOption {
+	return func(c *config) {
+		c.partialFlushEnabled = numSpans >= 1
+		c.partialFlushMinSpans = numSpans
+	}
+}
+
+// WithLongRunningSpans enables reporting snapshots of the spans still running
+// after threshold, such as hung HTTP handlers or stuck database transactions, so
+// that they appear in Datadog before they finish, if ever. A snapshot of such a
+// span is sent every threshold until it finishes, with its duration as of the
+// snapshot and a _dd.partial_version metric incremented by each snapshot. Only
+// the spans of the traces kept by the sampling are reported, and the spans are
+// no longer reported after running for 12 hours. Values of threshold below one
+// second are rounded up to one second, and values of 0 or below disable it,
+// which is the default.
+func WithLongRunningSpans(threshold time.Duration) StartOption {
+	return func(c *config) {
+		if threshold > 0 && threshold < time.Second {
+			threshold = time.Second
+		}
+		c.longRunningThreshold = threshold
+	}
+}
+
+// WithSpanPooling specifies whether spans are reused once they were encoded and
+// handed to the transport, which reduces allocations in high-throughput services.
+// When enabled, a span, its context and the spans started from it must not be
+// used once it and its children finished and the trace was flushed: their memory
+// may already hold another span. The spans sent by partial flushes, see
+// WithPartialFlushing, are not reused. It can also be enabled using the
+// DD_TRACE_SPAN_POOLING_ENABLED environment variable, which this option overrides.
+func WithSpanPooling(enabled bool) StartOption {
+	return func(c *config) {
+		c.spanPooling = enabled
+	}
+}
+
+// WithPostProcessor registers fn to be run on every finished span kept by the
+// sampling, before it is encoded and sent. fn may read the span and change its
+// tags, e.g. to scrub sensitive values or to add computed ones, and returns false
+// to drop the span. The post processors are run in the order they are registered,
+// the ones following a post processor dropping a span not being run on it.
+//
+// The post processors are run sequentially on a single goroutine, once all the
+// spans of a trace, or of a partially flushed chunk of a trace, are finished,
+// in the order the spans were started. They are never run concurrently, so they
+// need no synchronization, but they must not block as no trace is sent while
+// they run. The spans must not be retained by fn. The children of a dropped span
+// are kept unless they are dropped too. When the tracer computes the APM stats of
+// the spans itself, rather than the agent, the stats include the dropped spans.
+func WithPostProcessor(fn func(ReadOnlySpan) bool) StartOption {
+	return func(c *config) {
+		c.postProcessors = append(c.postProcessors, fn)
+	}
+}
+
+// WithTagScrubber registers fn to scrub the sensitive values, e.g. credit card
+// numbers or email addresses, which the application may have put in the
+// resource names or in the tags of its spans. fn is called with the key and the
+// value of every string tag of the spans when they finish, and with
+// ext.ResourceName and their resource name, and returns the value to set
+// instead, or the given value to leave it unchanged. The tags internal to the
+// tracer are not scrubbed, and neither are the numeric ones.
+//
+// The tag scrubbers are run in the order they are registered, after the ones
+// set with the DD_TRACE_SCRUB_TAGS environment variable, before the spans are
+// encoded or used to compute APM stats, and before the post processors set
+// with WithPostProcessor. They are run by the goroutine finishing the spans,
+// so fn must be safe for concurrent use and should be fast.
+func WithTagScrubber(fn func(key, value string) string) StartOption {
+	return func(c *config) {
+		c.tagScrubbers = append(c.tagScrubbers, fn)
+	}
+}
+
+// WithBaggageTagKeys specifies the keys of the baggage items which are copied into
+// the tags of the spans inheriting them, i.e. the spans started from the span they
+// were set on, locally or downstream. The tags are named after the keys, prefixed
+// with "baggage.". It can also be set using the comma-separated list of the
+// DD_TRACE_BAGGAGE_TAG_KEYS environment variable, which this option overrides.
+func WithBaggageTagKeys(keys ...string) StartOption {
+	return func(c *config) {
+		c.baggageTagKeys = nil
+		for _, k := range keys {
+			if k = strings.TrimSpace(k); k != "" {
+				c.baggageTagKeys = append(c.baggageTagKeys, k)
+			}
+		}
+	}
+}
+
+// WithPropagatedTraceTags specifies the keys of the trace tags set with SetTraceTag
+// which are also propagated downstream, as _dd.p.<key> trace tags, so that all the
+// services of a distributed trace can tag it. Their values must then be made of
+// printable ASCII characters other than commas. It can also be set using the
+// comma-separated list of the DD_TRACE_PROPAGATED_TRACE_TAG_KEYS environment
+// variable, which this option overrides.
+func WithPropagatedTraceTags(keys ...string) StartOption {
+	return func(c *config) {
+		c.propagatedTraceTagKeys = nil
+		for _, k := range keys {
+			if k = strings.TrimSpace(k); k != "" {
+				c.propagatedTraceTagKeys = append(c.propagatedTraceTagKeys, k)
+			}
+		}
+	}
+}
+
+// WithTraceID128Bit specifies whether new traces are given 128-bit trace IDs. The
+// lower 64 bits are used as the trace ID of the spans, while the upper 64 bits,
+// made of the start time of the trace in seconds followed by 32 zero bits, are
+// propagated along with it through the Datadog and W3C Trace Context headers. It
+// can also be enabled using the DD_TRACE_128_BIT_TRACEID_GENERATION_ENABLED
+// environment variable, which this option overrides.
+func WithTraceID128Bit(enabled bool) StartOption {
+	return func(c *config) {
+		c.traceID128BitEnabled = enabled
+	}
+}
+
+// WithClientStatsComputation specifies whether the tracer computes the APM stats
+// (hits, errors and duration distributions per service and resource) of the spans
+// itself and sends them to the agent's /v0.6/stats endpoint. The stats then
+// account for all the spans, including the ones of the traces dropped by the
+// tracer, and the agent no longer needs to compute them. It is only effective with
+// agents supporting client-side stats. Unless configured, it is enabled when the
+// agent reports it lets the tracer drop the P0 traces. It can also be set using the
+// DD_TRACE_STATS_COMPUTATION_ENABLED environment variable, which this option overrides.
+func WithClientStatsComputation(enabled bool) StartOption {
+	return func(c *config) {
+		c.statsComputationEnabled = enabled
+		c.statsComputationSet = true
+	}
+}
+
+// WithPeerServiceDefaults specifies whether the client and producer spans without
+// a peer.service tag are given one, taken from the first of their db.instance,
+// db.name, out.host and peer.hostname tags which is set. The tag it was taken
+// from is recorded in the _dd.peer.service.source tag. Unless configured, the
+// default peer.service tags are only set when the v1 naming schema is selected
+// with DD_TRACE_SPAN_ATTRIBUTE_SCHEMA, or when the agent computes the stats of
+// these spans by peer tags. It can also be enabled using the
+// DD_TRACE_PEER_SERVICE_DEFAULTS_ENABLED environment variable, which this option
+// overrides.
+func WithPeerServiceDefaults(enabled bool) StartOption {
+	return func(c *config) {
+		c.peerServiceDefaults = enabled
+		c.peerServiceDefaultsSet = true
+	}
+}
+
 // WithUDS configures the HTTP client to dial the Datadog Agent via the specified Unix Domain Socket path.
+// The socket can also be set using the DD_TRACE_AGENT_URL environment variable,
+// e.g. DD_TRACE_AGENT_URL=unix:///var/run/datadog/apm.socket.
 func WithUDS(socketPath string) StartOption {
 	return WithHTTPClient(udsClient(socketPath))
 }
 
+// WithNamedPipe configures the HTTP client to dial the Datadog Agent via the specified
+// Windows named pipe, either given by its name (e.g. "datadog-apm") or by its full path
+// (e.g. `\\.\pipe\datadog-apm`). It can also be set using the DD_TRACE_PIPE_NAME
+// environment variable. Named pipes are only supported on Windows.
+func WithNamedPipe(name string) StartOption {
+	const pipePrefix = `\\.\pipe\`
+	if !strings.HasPrefix(name, pipePrefix) {
+		name = pipePrefix + name
+	}
+	if runtime.GOOS != "windows" {
+		log.Warn("Named pipe %s configured, but named pipes are only supported on Windows.", name)
+	}
+	return WithHTTPClient(pipeClient(name))
+}
+
 // WithAnalytics allows specifying whether Trace Search & Analytics should be enabled
 // for integrations.
 func WithAnalytics(on bool) StartOption {
@@ -651,7 +1295,56 @@ func WithAnalyticsRate(rate float64) StartOption {
 	}
 }
 
+// WithClientIPHeader sets the name of the HTTP header from which the client IP
+// of incoming requests is resolved and tagged as http.client_ip by the HTTP
+// integrations. By default, a list of well-known IP headers such as
+// X-Forwarded-For is used. It takes precedence over the DD_TRACE_CLIENT_IP_HEADER
+// environment variable.
+func WithClientIPHeader(name string) StartOption {
+	return func(_ *config) {
+		globalconfig.SetClientIPHeader(name)
+	}
+}
+
+// WithClientIPHeaders sets the names of the HTTP headers from which the client
+// IP of incoming requests is resolved, in order of priority: the first header
+// holding a valid IP is used, and the remote address of the requests when none
+// of them is found. It is ignored when WithClientIPHeader is set, and takes
+// precedence over the comma-separated list of the DD_TRACE_CLIENT_IP_HEADERS
+// environment variable.
+func WithClientIPHeaders(names ...string) StartOption {
+	return func(_ *config) {
+		globalconfig.SetClientIPHeaders(names)
+	}
+}
+
+// WithClientIPHeaderDisabled sets whether the HTTP headers of incoming requests
+// are left out of the resolution of their client IP, which is then their remote
+// address. It takes precedence over the DD_TRACE_CLIENT_IP_HEADER_DISABLED
+// environment variable.
+func WithClientIPHeaderDisabled(disabled bool) StartOption {
+	return func(_ *config) {
+		globalconfig.SetClientIPHeaderDisabled(disabled)
+	}
+}
+
+// WithPrivateClientIPs sets whether private IPs, such as the RFC 1918 ones,
+// can be tagged as the client IP of incoming requests when no public IP is
+// found, which is useful when the clients reach the services through a private
+// network. Loopback and link-local IPs are never tagged. It takes precedence
+// over the DD_TRACE_CLIENT_IP_ALLOW_PRIVATE environment variable.
+func WithPrivateClientIPs(allow bool) StartOption {
+	return func(_ *config) {
+		globalconfig.SetClientIPAllowPrivate(allow)
+	}
+}
+
 // WithRuntimeMetrics enables automatic collection of runtime metrics every 10 seconds.
+// Along with the runtime.go.mem_stats.* metrics, the metrics of the runtime/metrics
+// package, such as the GC pauses, the heap, the goroutines and the scheduler
+// latencies, are reported as runtime.go.metrics.*, with the quantiles of the
+// observations of each interval for the histograms. It can also be enabled using
+// the DD_RUNTIME_METRICS_ENABLED environment variable.
 func WithRuntimeMetrics() StartOption {
 	return func(cfg *config) {
 		cfg.runtimeMetrics = true
@@ -673,7 +1366,11 @@ func WithDogstatsdAddress(addr string) StartOption {
 }
 
 // WithSamplingRules specifies the sampling rates to apply to spans based on the
-// provided rules.
+// provided rules. Trace sampling rules (e.g. ServiceRule, NameServiceRule) decide
+// whether a whole trace is kept, while single span sampling rules (created with
+// SpanNameServiceRule or SpanNameServiceMPSRule) keep individual matching spans
+// even when their trace is dropped. Rules set via the DD_TRACE_SAMPLING_RULES and
+// DD_SPAN_SAMPLING_RULES environment variables take precedence.
 func WithSamplingRules(rules []SamplingRule) StartOption {
 	return func(cfg *config) {
 		for _, rule := range rules {
@@ -816,6 +1513,15 @@ func withContext(ctx context.Context) StartSpanOption {
 	}
 }
 
+// WithSpanLinks links the started span to the given spans, to represent causal
+// relationships which aren't parent-child ones. Links can also be added after
+// the span is started with the AddLink method of ddtrace.SpanWithLinks.
+func WithSpanLinks(links []ddtrace.SpanLink) StartSpanOption {
+	return func(cfg *ddtrace.StartSpanConfig) {
+		cfg.Links = append(cfg.Links, links...)
+	}
+}
+
 // StartTime sets a custom time as the start time for the created span. By
 // default a span is started using the creation time.
 func StartTime(t time.Time) StartSpanOption {
@@ -6,6 +6,7 @@
 package tracer
 
 import (
+	"fmt"
 	"io"
 	"io/ioutil"
 	"math"
@@ -16,10 +17,12 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/namingschema"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/traceprof"
 
 	"github.com/stretchr/testify/assert"
@@ -192,7 +195,8 @@ func TestLoadAgentFeatures(t *testing.T) {
 
 	t.Run("OK", func(t *testing.T) {
 		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
-			w.Write([]byte(`{"endpoints":["/v0.6/stats"],"feature_flags":["a","b"],"client_drop_p0s":true,"statsd_port":8999}`))
+			w.Header().Set("Datadog-Agent-State", "state")
+			w.Write([]byte(`{"endpoints":["/v0.6/stats","/v0.1/pipeline_stats","/v0.7/traces","/evp_proxy/v2/"],"feature_flags":["a","b"],"client_drop_p0s":true,"statsd_port":8999,"peer_tags":["db.instance"]}`))
 		}))
 		defer srv.Close()
 		cfg := newConfig(WithAgentAddr(strings.TrimPrefix(srv.URL, "http://")))
@@ -203,6 +207,11 @@ func TestLoadAgentFeatures(t *testing.T) {
 			"b": struct{}{},
 		})
 		assert.True(t, cfg.agent.Stats)
+		assert.True(t, cfg.agent.DataStreams)
+		assert.True(t, cfg.agent.V07)
+		assert.True(t, cfg.agent.EVPProxy)
+		assert.Equal(t, []string{"db.instance"}, cfg.agent.PeerTags)
+		assert.Equal(t, "state", cfg.agent.state)
 		assert.True(t, cfg.agent.HasFlag("a"))
 		assert.True(t, cfg.agent.HasFlag("b"))
 	})
@@ -219,6 +228,133 @@ func TestLoadAgentFeatures(t *testing.T) {
 		assert.True(t, cfg.agent.Stats)
 		assert.Equal(t, cfg.agent.StatsdPort, 8999)
 	})
+
+	t.Run("client-stats", func(t *testing.T) {
+		var endpoints string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.Write([]byte(`{"endpoints":[` + endpoints + `],"client_drop_p0s":true}`))
+		}))
+		defer srv.Close()
+		addr := strings.TrimPrefix(srv.URL, "http://")
+
+		// enabled by the agent, as it lets the tracer drop the P0 traces
+		endpoints = `"/v0.6/stats"`
+		cfg := newConfig(WithAgentAddr(addr))
+		assert.True(t, cfg.canComputeStats())
+		assert.True(t, cfg.canDropP0s())
+
+		cfg = newConfig(WithAgentAddr(addr), WithClientStatsComputation(false))
+		assert.False(t, cfg.canComputeStats())
+		assert.False(t, cfg.canDropP0s())
+
+		cfg = newConfig(WithAgentAddr(addr), WithClientStatsComputation(true))
+		assert.True(t, cfg.canComputeStats())
+		assert.True(t, cfg.canDropP0s())
+
+		cfg.agent.DropP0s = false
+		assert.True(t, cfg.canComputeStats())
+		assert.False(t, cfg.canDropP0s())
+		cfg.statsComputationSet = false
+		assert.False(t, cfg.canComputeStats())
+
+		t.Setenv("DD_TRACE_STATS_COMPUTATION_ENABLED", "true")
+		cfg = newConfig(WithAgentAddr(addr))
+		assert.True(t, cfg.canComputeStats())
+		cfg = newConfig(WithAgentAddr(addr), WithClientStatsComputation(false))
+		assert.False(t, cfg.canComputeStats())
+
+		// the agent does not accept client stats
+		endpoints = `"/v0.4/traces"`
+		cfg = newConfig(WithAgentAddr(addr))
+		assert.False(t, cfg.canComputeStats())
+	})
+
+	t.Run("peer-service", func(t *testing.T) {
+		var peerTags string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.Write([]byte(`{"peer_tags":[` + peerTags + `]}`))
+		}))
+		defer srv.Close()
+		addr := strings.TrimPrefix(srv.URL, "http://")
+
+		cfg := newConfig(WithAgentAddr(addr))
+		assert.False(t, cfg.canSetPeerService())
+		cfg = newConfig(WithAgentAddr(addr), WithPeerServiceDefaults(true))
+		assert.True(t, cfg.canSetPeerService())
+
+		// enabled by the agent, as it computes the stats by peer tags
+		peerTags = `"peer.service"`
+		cfg = newConfig(WithAgentAddr(addr))
+		assert.True(t, cfg.canSetPeerService())
+		cfg = newConfig(WithAgentAddr(addr), WithPeerServiceDefaults(false))
+		assert.False(t, cfg.canSetPeerService())
+
+		t.Setenv("DD_TRACE_PEER_SERVICE_DEFAULTS_ENABLED", "false")
+		cfg = newConfig(WithAgentAddr(addr))
+		assert.False(t, cfg.canSetPeerService())
+	})
+
+	t.Run("peer-service-schema-v1", func(t *testing.T) {
+		namingschema.SetVersion(namingschema.SchemaV1)
+		defer namingschema.SetVersion(namingschema.SchemaV0)
+
+		cfg := newConfig(WithAgentAddr("localhost:9"))
+		assert.True(t, cfg.canSetPeerService())
+		cfg = newConfig(WithAgentAddr("localhost:9"), WithPeerServiceDefaults(false))
+		assert.False(t, cfg.canSetPeerService())
+	})
+
+	t.Run("protocol", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.Write([]byte(`{"endpoints":["/v0.4/traces","/v0.7/traces"]}`))
+		}))
+		defer srv.Close()
+		addr := strings.TrimPrefix(srv.URL, "http://")
+
+		cfg := newConfig(WithAgentAddr(addr))
+		assert.Equal(t, traceProtocolV07, cfg.currentTraceProtocol())
+		cfg = newConfig(WithAgentAddr(addr), WithAdditionalAgent("http://localhost:8127"))
+		assert.Equal(t, traceProtocolV04, cfg.currentTraceProtocol())
+		cfg = newConfig(WithAgentAddr(addr), withTransport(newDummyTransport()))
+		assert.Equal(t, traceProtocolV04, cfg.currentTraceProtocol())
+
+		t.Setenv("DD_TRACE_AGENT_PROTOCOL_VERSION", "0.4")
+		cfg = newConfig(WithAgentAddr(addr))
+		assert.Equal(t, traceProtocolV04, cfg.currentTraceProtocol())
+	})
+
+	t.Run("refresh", func(t *testing.T) {
+		var (
+			mu    sync.Mutex
+			state = "1"
+			info  = `{"endpoints":["/v0.4/traces"]}`
+			calls int
+		)
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+			calls++
+			w.Header().Set("Datadog-Agent-State", state)
+			w.Write([]byte(info))
+		}))
+		defer srv.Close()
+		cfg := newConfig(WithAgentAddr(strings.TrimPrefix(srv.URL, "http://")))
+		assert.False(t, cfg.agentFeatures().V07)
+
+		// same state: not reloaded
+		cfg.refreshAgentFeatures("1")
+		cfg.refreshAgentFeatures("")
+		mu.Lock()
+		assert.Equal(t, 1, calls)
+		state, info = "2", `{"endpoints":["/v0.4/traces","/v0.7/traces"]}`
+		mu.Unlock()
+
+		// the agent restarted with another state
+		cfg.refreshAgentFeatures("2")
+		assert.Eventually(t, func() bool { return cfg.agentFeatures().V07 }, time.Second, time.Millisecond)
+		assert.Equal(t, traceProtocolV07, cfg.currentTraceProtocol())
+		assert.Equal(t, "2", cfg.agentFeatures().state)
+	})
 }
 
 func TestTracerOptionsDefaults(t *testing.T) {
@@ -327,6 +463,43 @@ func TestTracerOptionsDefaults(t *testing.T) {
 		assert.Equal(t, "trace-agent:8126", c.agentAddr)
 	})
 
+	t.Run("env-agent-url", func(t *testing.T) {
+		t.Run("http", func(t *testing.T) {
+			t.Setenv("DD_AGENT_HOST", "trace-agent")
+			t.Setenv("DD_TRACE_AGENT_URL", "http://custom-agent:8127")
+			c := newConfig()
+			assert.Equal(t, "custom-agent:8127", c.agentAddr)
+		})
+
+		t.Run("http-no-port", func(t *testing.T) {
+			t.Setenv("DD_TRACE_AGENT_URL", "http://custom-agent")
+			c := newConfig()
+			assert.Equal(t, "custom-agent:8126", c.agentAddr)
+		})
+
+		t.Run("unix", func(t *testing.T) {
+			udsPath := filepath.Join(t.TempDir(), "apm.socket")
+			ln, err := net.Listen("unix", udsPath)
+			require.NoError(t, err)
+			srv := http.Server{Handler: http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {})}
+			go srv.Serve(ln)
+			defer srv.Close()
+
+			t.Setenv("DD_TRACE_AGENT_URL", "unix://"+udsPath)
+			c := newConfig()
+			resp, err := c.httpClient.Get(fmt.Sprintf("http://%s/info", c.agentAddr))
+			require.NoError(t, err)
+			resp.Body.Close()
+		})
+
+		t.Run("unsupported", func(t *testing.T) {
+			t.Setenv("DD_TRACE_AGENT_URL", "ftp://custom-agent:8127")
+			c := newConfig()
+			assert.Equal(t, "localhost:8126", c.agentAddr)
+			assert.Equal(t, defaultClient, c.httpClient)
+		})
+	})
+
 	t.Run("override", func(t *testing.T) {
 		os.Setenv("DD_ENV", "dev")
 		defer os.Unsetenv("DD_ENV")
@@ -771,6 +944,12 @@ func TestVersionConfig(t *testing.T) {
 		c = newConfig(WithGlobalTag("version", "1.1.2"), WithServiceVersion("1.1.4"))
 		assert.Equal("1.1.4", c.version)
 	})
+
+	t.Run("globalconfig", func(t *testing.T) {
+		defer globalconfig.SetServiceVersion("")
+		newConfig(WithServiceVersion("1.2.3"))
+		assert.Equal(t, "1.2.3", globalconfig.ServiceVersion())
+	})
 }
 
 func TestEnvConfig(t *testing.T) {
@@ -827,6 +1006,12 @@ func TestEnvConfig(t *testing.T) {
 		c = newConfig(WithGlobalTag("env", "testing2"), WithEnv("testing4"))
 		assert.Equal("testing4", c.env)
 	})
+
+	t.Run("globalconfig", func(t *testing.T) {
+		defer globalconfig.SetEnv("")
+		newConfig(WithEnv("testing"))
+		assert.Equal(t, "testing", globalconfig.Env())
+	})
 }
 
 func TestStatsTags(t *testing.T) {
@@ -903,3 +1088,142 @@ func TestWithLogStartup(t *testing.T) {
 	WithLogStartup(true)(c)
 	assert.True(t, c.logStartup)
 }
+
+func TestWithSpanPooling(t *testing.T) {
+	c := newConfig()
+	assert.False(t, c.spanPooling)
+	c = newConfig(WithSpanPooling(true))
+	assert.True(t, c.spanPooling)
+
+	t.Setenv("DD_TRACE_SPAN_POOLING_ENABLED", "true")
+	c = newConfig()
+	assert.True(t, c.spanPooling)
+	c = newConfig(WithSpanPooling(false))
+	assert.False(t, c.spanPooling)
+}
+
+func TestWithBaggageTagKeys(t *testing.T) {
+	c := newConfig()
+	assert.Nil(t, c.baggageTagKeys)
+	c = newConfig(WithBaggageTagKeys("user.id", " ", " region "))
+	assert.Equal(t, []string{"user.id", "region"}, c.baggageTagKeys)
+
+	t.Setenv("DD_TRACE_BAGGAGE_TAG_KEYS", "user.id, session.id,")
+	c = newConfig()
+	assert.Equal(t, []string{"user.id", "session.id"}, c.baggageTagKeys)
+	c = newConfig(WithBaggageTagKeys("region"))
+	assert.Equal(t, []string{"region"}, c.baggageTagKeys)
+}
+
+func TestWithPartialFlushing(t *testing.T) {
+	t.Run("default", func(t *testing.T) {
+		c := newConfig()
+		assert.False(t, c.partialFlushEnabled)
+		assert.Equal(t, defaultPartialFlushMinSpans, c.partialFlushMinSpans)
+	})
+
+	t.Run("option", func(t *testing.T) {
+		c := newConfig(WithPartialFlushing(20))
+		assert.True(t, c.partialFlushEnabled)
+		assert.Equal(t, 20, c.partialFlushMinSpans)
+
+		c = newConfig(WithPartialFlushing(0))
+		assert.False(t, c.partialFlushEnabled)
+	})
+
+	t.Run("env", func(t *testing.T) {
+		t.Setenv("DD_TRACE_PARTIAL_FLUSH_ENABLED", "true")
+		t.Setenv("DD_TRACE_PARTIAL_FLUSH_MIN_SPANS", "50")
+		c := newConfig()
+		assert.True(t, c.partialFlushEnabled)
+		assert.Equal(t, 50, c.partialFlushMinSpans)
+	})
+
+	t.Run("env-disabled", func(t *testing.T) {
+		t.Setenv("DD_TRACE_PARTIAL_FLUSH_ENABLED", "true")
+		t.Setenv("DD_TRACE_PARTIAL_FLUSH_MIN_SPANS", "-1")
+		c := newConfig()
+		assert.False(t, c.partialFlushEnabled)
+	})
+
+	t.Run("override-env", func(t *testing.T) {
+		t.Setenv("DD_TRACE_PARTIAL_FLUSH_ENABLED", "true")
+		t.Setenv("DD_TRACE_PARTIAL_FLUSH_MIN_SPANS", "50")
+		c := newConfig(WithPartialFlushing(0))
+		assert.False(t, c.partialFlushEnabled)
+
+		t.Setenv("DD_TRACE_PARTIAL_FLUSH_MIN_SPANS", "0")
+		c = newConfig(WithPartialFlushing(20))
+		assert.True(t, c.partialFlushEnabled)
+		assert.Equal(t, 20, c.partialFlushMinSpans)
+	})
+}
+
+func TestWithClientIPHeader(t *testing.T) {
+	defer globalconfig.SetClientIPHeader(globalconfig.ClientIPHeader())
+
+	t.Run("env", func(t *testing.T) {
+		t.Setenv("DD_TRACE_CLIENT_IP_HEADER", "x-env-ip")
+		newConfig()
+		assert.Equal(t, "x-env-ip", globalconfig.ClientIPHeader())
+	})
+
+	t.Run("option", func(t *testing.T) {
+		newConfig(WithClientIPHeader("x-custom-ip"))
+		assert.Equal(t, "x-custom-ip", globalconfig.ClientIPHeader())
+	})
+
+	t.Run("override-env", func(t *testing.T) {
+		t.Setenv("DD_TRACE_CLIENT_IP_HEADER", "x-env-ip")
+		newConfig(WithClientIPHeader("x-custom-ip"))
+		assert.Equal(t, "x-custom-ip", globalconfig.ClientIPHeader())
+	})
+}
+
+func TestWithClientIPHeaders(t *testing.T) {
+	defer globalconfig.SetClientIPHeaders(globalconfig.ClientIPHeaders())
+
+	t.Run("env", func(t *testing.T) {
+		t.Setenv("DD_TRACE_CLIENT_IP_HEADERS", "x-env-ip, true-client-ip,,")
+		newConfig()
+		assert.Equal(t, []string{"x-env-ip", "true-client-ip"}, globalconfig.ClientIPHeaders())
+	})
+
+	t.Run("override-env", func(t *testing.T) {
+		t.Setenv("DD_TRACE_CLIENT_IP_HEADERS", "x-env-ip")
+		newConfig(WithClientIPHeaders("x-custom-ip", "x-forwarded-for"))
+		assert.Equal(t, []string{"x-custom-ip", "x-forwarded-for"}, globalconfig.ClientIPHeaders())
+	})
+}
+
+func TestWithClientIPHeaderDisabled(t *testing.T) {
+	defer globalconfig.SetClientIPHeaderDisabled(globalconfig.ClientIPHeaderDisabled())
+
+	t.Run("env", func(t *testing.T) {
+		t.Setenv("DD_TRACE_CLIENT_IP_HEADER_DISABLED", "true")
+		newConfig()
+		assert.True(t, globalconfig.ClientIPHeaderDisabled())
+	})
+
+	t.Run("override-env", func(t *testing.T) {
+		t.Setenv("DD_TRACE_CLIENT_IP_HEADER_DISABLED", "true")
+		newConfig(WithClientIPHeaderDisabled(false))
+		assert.False(t, globalconfig.ClientIPHeaderDisabled())
+	})
+}
+
+func TestWithPrivateClientIPs(t *testing.T) {
+	defer globalconfig.SetClientIPAllowPrivate(globalconfig.ClientIPAllowPrivate())
+
+	t.Run("env", func(t *testing.T) {
+		t.Setenv("DD_TRACE_CLIENT_IP_ALLOW_PRIVATE", "true")
+		newConfig()
+		assert.True(t, globalconfig.ClientIPAllowPrivate())
+	})
+
+	t.Run("override-env", func(t *testing.T) {
+		t.Setenv("DD_TRACE_CLIENT_IP_ALLOW_PRIVATE", "true")
+		newConfig(WithPrivateClientIPs(false))
+		assert.False(t, globalconfig.ClientIPAllowPrivate())
+	})
+}
@@ -9,8 +9,15 @@ import (
 	"bytes"
 	"encoding/binary"
 	"io"
+	"math"
+	"runtime"
+	"strings"
 	"sync/atomic"
 
+	"gopkg.in/DataDog/dd-trace-go.v1/internal"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/version"
+
 	"github.com/tinylib/msgp/msgp"
 )
 
@@ -24,12 +31,29 @@ import (
 // payload implements io.Reader and can be used with the decoder directly. To create
 // a new payload use the newPayload method.
 //
+// The payloads of the v0.7 protocol hold the traces as the chunks of a tracer
+// payload: the array of the traces is preceded by the metadata of the tracer,
+// and each trace is preceded by the keys of its chunk, see push.
+//
+// The streamed payloads, see newStreamingPayload, hold the traces pushed to them
+// rather than their encoding: they are encoded as the payload is read, through a
+// pipe, so that they are streamed to the agent while being encoded.
+//
 // payload is not safe for concurrent use, is meant to be used only once and eventually
-// dismissed.
+// dismissed. Its buffer is given back to its bufferPool once it is closed.
 type payload struct {
+	// protocol is the version of the agent API the payload is encoded for,
+	// either traceProtocolV04 or traceProtocolV07.
+	protocol string
+
+	// prefix holds the msgpack-encoded metadata of the tracer which precedes
+	// the array of the traces in the v0.7 payloads, see tracerPayloadPrefix.
+	prefix []byte
+
 	// header specifies the first few bytes in the msgpack stream
 	// indicating the type of array (fixarray, array16 or array32)
-	// and the number of items contained in the stream.
+	// and the number of items contained in the stream, preceded
+	// by the prefix.
 	header []byte
 
 	// off specifies the current read position on the header.
@@ -38,24 +62,164 @@ type payload struct {
 	// count specifies the number of items in the stream.
 	count uint32
 
-	// buf holds the sequence of msgpack-encoded items.
-	buf bytes.Buffer
+	// buf holds the sequence of msgpack-encoded items. It is nil once the
+	// payload is closed.
+	buf *payloadBuffer
+
+	// pool is the pool buf is taken from and given back to, it may be nil.
+	pool *bufferPool
+
+	// refs counts the references to buf: one for the payload until it is
+	// closed, plus one for each of its clones which are not closed yet.
+	refs int32
+
+	// rd reads the items of the payload this payload is a clone of, in which
+	// case it is set along with parent.
+	rd     *bytes.Reader
+	parent *payload
+
+	// streaming reports whether the payload is streamed, in which case items
+	// holds its items, whose encoded size is estimated by estimate, and stream
+	// reads their encoding once the payload is read, until the encoder writing
+	// them is done.
+	streaming bool
+	items     spanLists
+	estimate  int
+	stream    *io.PipeReader
+	encoded   chan struct{}
 }
 
 var _ io.Reader = (*payload)(nil)
 
 // newPayload returns a ready to use payload.
 func newPayload() *payload {
+	return newPooledPayload(nil)
+}
+
+// newPooledPayload returns a ready to use payload whose buffer is taken from
+// the given pool, which may be nil.
+func newPooledPayload(pool *bufferPool) *payload {
+	return newProtocolPayload(pool, traceProtocolV04, nil)
+}
+
+// newProtocolPayload returns a ready to use payload of the given protocol, whose
+// buffer is taken from the given pool, which may be nil. The v0.7 payloads begin
+// with prefix, the metadata of the tracer encoded by tracerPayloadPrefix.
+func newProtocolPayload(pool *bufferPool, protocol string, prefix []byte) *payload {
+	if protocol != traceProtocolV07 {
+		prefix = nil
+	}
 	p := &payload{
-		header: make([]byte, 8),
-		off:    8,
+		protocol: protocol,
+		prefix:   prefix,
+		header:   make([]byte, len(prefix)+8),
+		off:      len(prefix) + 8,
+		buf:      pool.get(),
+		pool:     pool,
+		refs:     1,
 	}
 	return p
 }
 
+// newStreamingPayload returns a ready to use streamed payload of the given protocol,
+// which is encoded as it is read. The v0.7 payloads begin with prefix, the metadata
+// of the tracer encoded by tracerPayloadPrefix.
+func newStreamingPayload(protocol string, prefix []byte) *payload {
+	p := newProtocolPayload(nil, protocol, prefix)
+	p.buf = nil
+	p.streaming = true
+	return p
+}
+
+// tracerPayloadPrefix returns the beginning of the v0.7 tracer payloads sent by
+// the tracer with the configuration c and the settings gc currently in effect:
+// the map of its metadata, whose last key is the one of the array of the trace
+// chunks, which follows.
+func tracerPayloadPrefix(c *config, gc *globalConfig) []byte {
+	fields := [][2]string{
+		{"containerID", internal.ContainerID()},
+		{"languageName", "go"},
+		{"languageVersion", strings.TrimPrefix(runtime.Version(), "go")},
+		{"tracerVersion", version.Tag},
+		{"runtimeID", globalconfig.RuntimeID()},
+		{"env", gc.env},
+		{"hostname", c.hostname},
+		{"appVersion", gc.version},
+	}
+	n := uint32(1) // the chunks
+	for _, f := range fields {
+		if f[1] != "" {
+			n++
+		}
+	}
+	b := msgp.AppendMapHeader(nil, n)
+	for _, f := range fields {
+		if f[1] != "" {
+			b = msgp.AppendString(b, f[0])
+			b = msgp.AppendString(b, f[1])
+		}
+	}
+	return msgp.AppendString(b, "chunks")
+}
+
+// clone returns a payload reading the items of p from the start, which can be
+// sent in place of p, e.g. to retry sending it after a failure. p must not be
+// read, nor pushed to, while it has clones. The buffer of p is given back to its
+// pool once p and all of its clones are closed.
+func (p *payload) clone() *payload {
+	atomic.AddInt32(&p.refs, 1)
+	if p.streaming {
+		c := &payload{
+			protocol:  p.protocol,
+			prefix:    p.prefix,
+			header:    make([]byte, len(p.header)),
+			count:     atomic.LoadUint32(&p.count),
+			parent:    p,
+			streaming: true,
+			items:     p.items,
+			estimate:  p.estimate,
+		}
+		c.updateHeader()
+		return c
+	}
+	c := &payload{
+		protocol: p.protocol,
+		prefix:   p.prefix,
+		header:   make([]byte, len(p.header)),
+		count:    atomic.LoadUint32(&p.count),
+		buf:      p.buf,
+		rd:       bytes.NewReader(p.buf.Bytes()),
+		parent:   p,
+	}
+	c.updateHeader()
+	return c
+}
+
 // push pushes a new item into the stream.
 func (p *payload) push(t spanList) error {
-	if err := msgp.Encode(&p.buf, t); err != nil {
+	if p.streaming {
+		p.items = append(p.items, t)
+		p.estimate += t.Msgsize()
+		atomic.AddUint32(&p.count, 1)
+		p.updateHeader()
+		return nil
+	}
+	// encode directly with the writer of the buffer rather than msgp.Encode,
+	// which would allocate t as an interface on every call
+	wr := p.buf.wr
+	var err error
+	if p.protocol == traceProtocolV07 {
+		err = writeChunkHeader(wr, t)
+	}
+	if err == nil {
+		err = t.EncodeMsg(wr)
+	}
+	if err == nil {
+		err = wr.Flush()
+	}
+	if err != nil {
+		// discard what was partially encoded
+		wr.Reset(&p.buf.Buffer)
 		return err
 	}
 	atomic.AddUint32(&p.count, 1)
@@ -63,6 +227,99 @@ func (p *payload) push(t spanList) error {
 	return nil
 }
 
+// priorityNone is the sampling priority of the v0.7 trace chunks whose traces
+// have none, as used by the agent.
+const priorityNone = math.MinInt8
+
+// writeChunkHeader writes the beginning of the v0.7 trace chunk holding t: its
+// sampling priority and origin, carried by its first span, and the key of its
+// spans, which follow.
+func writeChunkHeader(wr *msgp.Writer, t spanList) error {
+	priority, origin := int32(priorityNone), ""
+	if len(t) > 0 {
+		if p, ok := t[0].Metrics[keySamplingPriority]; ok {
+			priority = int32(p)
+		}
+		origin = t[0].Meta[keyOrigin]
+	}
+	if err := wr.WriteMapHeader(3); err != nil {
+		return err
+	}
+	if err := wr.WriteString("priority"); err != nil {
+		return err
+	}
+	if err := wr.WriteInt32(priority); err != nil {
+		return err
+	}
+	if err := wr.WriteString("origin"); err != nil {
+		return err
+	}
+	if err := wr.WriteString(origin); err != nil {
+		return err
+	}
+	return wr.WriteString("spans")
+}
+
+// traces decodes the traces of p, which must not be read, nor pushed to,
+// meanwhile.
+func (p *payload) traces() (spanLists, error) {
+	if p.streaming {
+		return append(spanLists(nil), p.items...), nil
+	}
+	c := p.clone()
+	defer c.Close()
+	var traces spanLists
+	if p.protocol != traceProtocolV07 {
+		err := msgp.Decode(c, &traces)
+		return traces, err
+	}
+	r := msgp.NewReader(c)
+	n, err := r.ReadMapHeader()
+	if err != nil {
+		return nil, err
+	}
+	for ; n > 0; n-- {
+		key, err := r.ReadString()
+		if err != nil {
+			return nil, err
+		}
+		if key != "chunks" {
+			if err := r.Skip(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		chunks, err := r.ReadArrayHeader()
+		if err != nil {
+			return nil, err
+		}
+		for ; chunks > 0; chunks-- {
+			fields, err := r.ReadMapHeader()
+			if err != nil {
+				return nil, err
+			}
+			for ; fields > 0; fields-- {
+				key, err := r.ReadString()
+				if err != nil {
+					return nil, err
+				}
+				if key != "spans" {
+					if err := r.Skip(); err != nil {
+						return nil, err
+					}
+					continue
+				}
+				var t spanList
+				if err := t.DecodeMsg(r); err != nil {
+					return nil, err
+				}
+				traces = append(traces, t)
+			}
+		}
+	}
+	return traces, nil
+}
+
 // itemCount returns the number of items available in the srteam.
 func (p *payload) itemCount() int {
 	return int(atomic.LoadUint32(&p.count))
@@ -71,6 +328,15 @@ func (p *payload) itemCount() int {
 // size returns the payload size in bytes. After the first read the value becomes
 // inaccurate by up to 8 bytes.
 func (p *payload) size() int {
+	if p.streaming {
+		return p.estimate + len(p.header) - p.off
+	}
+	if p.buf == nil {
+		return 0
+	}
+	if p.rd != nil {
+		return p.rd.Len() + len(p.header) - p.off
+	}
 	return p.buf.Len() + len(p.header) - p.off
 }
 
@@ -103,30 +369,59 @@ const (
 // present in the stream.
 func (p *payload) updateHeader() {
 	n := uint64(atomic.LoadUint32(&p.count))
+	// the array header ends the header, right after the prefix, so that the
+	// offset of the array header in h is the offset of the prefix in p.header
+	h := p.header[len(p.prefix):]
 	switch {
 	case n <= 15:
-		p.header[7] = msgpackArrayFix + byte(n)
+		h[7] = msgpackArrayFix + byte(n)
 		p.off = 7
 	case n <= 1<<16-1:
-		binary.BigEndian.PutUint64(p.header, n) // writes 2 bytes
-		p.header[5] = msgpackArray16
+		binary.BigEndian.PutUint64(h, n) // writes 2 bytes
+		h[5] = msgpackArray16
 		p.off = 5
 	default: // n <= 1<<32-1
-		binary.BigEndian.PutUint64(p.header, n) // writes 4 bytes
-		p.header[3] = msgpackArray32
+		binary.BigEndian.PutUint64(h, n) // writes 4 bytes
+		h[3] = msgpackArray32
 		p.off = 3
 	}
+	copy(p.header[p.off:], p.prefix)
 }
 
 // Close implements io.Closer
 func (p *payload) Close() error {
-	// Once the payload has been read, clear the buffer for garbage collection to avoid
-	// a memory leak when references to this object may still be kept by faulty transport
-	// implementations or the standard library. See dd-trace-go#976
-	p.buf = bytes.Buffer{}
+	if p.streaming {
+		if p.stream != nil {
+			// stop the encoder, and wait for it to be done reading the traces
+			p.stream.Close()
+			<-p.encoded
+			p.stream = nil
+		}
+		return nil
+	}
+	// Once the payload has been read, release the buffer to avoid a memory leak when
+	// references to this object may still be kept by faulty transport implementations
+	// or the standard library. See dd-trace-go#976. The HTTP client closes the request
+	// body once it is done reading it, so the buffer can then be reused.
+	if p.buf != nil {
+		if p.parent != nil {
+			p.parent.release(p.buf)
+		} else {
+			p.release(p.buf)
+		}
+		p.buf = nil
+	}
 	return nil
 }
 
+// release releases a reference to b, the buffer of p, giving it back to the
+// pool of p once there are none left.
+func (p *payload) release(b *payloadBuffer) {
+	if atomic.AddInt32(&p.refs, -1) == 0 {
+		p.pool.put(b)
+	}
+}
+
 // Read implements io.Reader. It reads from the msgpack-encoded stream.
 func (p *payload) Read(b []byte) (n int, err error) {
 	if p.off < len(p.header) {
@@ -135,5 +430,110 @@ func (p *payload) Read(b []byte) (n int, err error) {
 		p.off += n
 		return n, nil
 	}
+	if p.streaming {
+		if p.stream == nil {
+			if p.encoded != nil {
+				// closed
+				return 0, io.EOF
+			}
+			var pw *io.PipeWriter
+			p.stream, pw = io.Pipe()
+			p.encoded = make(chan struct{})
+			go p.encode(pw)
+		}
+		return p.stream.Read(b)
+	}
+	if p.buf == nil {
+		return 0, io.EOF
+	}
+	if p.rd != nil {
+		return p.rd.Read(b)
+	}
 	return p.buf.Read(b)
 }
+
+// streamingChunkSize is the size of the buffer the items of the streamed payloads
+// are encoded into before being written to the stream.
+const streamingChunkSize = 32 * 1024
+
+// encode encodes the items of the streamed payload p into w, which is closed once
+// they are encoded, or with the error that stopped their encoding.
+func (p *payload) encode(w *io.PipeWriter) {
+	defer close(p.encoded)
+	wr := msgp.NewWriterSize(w, streamingChunkSize)
+	var err error
+	for _, t := range p.items {
+		if p.protocol == traceProtocolV07 {
+			err = writeChunkHeader(wr, t)
+		}
+		if err == nil {
+			err = t.EncodeMsg(wr)
+		}
+		if err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = wr.Flush()
+	}
+	w.CloseWithError(err)
+}
+
+// payloadBuffer holds the msgpack-encoded items of a payload, along with the
+// msgpack writer encoding them into it.
+type payloadBuffer struct {
+	bytes.Buffer
+	wr *msgp.Writer
+}
+
+// maxPooledBuffers is the number of payload buffers kept by a bufferPool: one
+// for the payload being filled, and one for the payload being sent.
+const maxPooledBuffers = 2
+
+// bufferPool keeps the buffers of the payloads once they are sent, so that their
+// memory is reused by the following payloads rather than grown again from scratch
+// on every flush. A nil *bufferPool allocates new buffers and never reuses them.
+type bufferPool struct {
+	// size is the initial capacity of the buffers.
+	size    int
+	buffers chan *payloadBuffer
+}
+
+// newBufferPool returns a bufferPool of buffers with the given initial capacity.
+func newBufferPool(size int) *bufferPool {
+	return &bufferPool{
+		size:    size,
+		buffers: make(chan *payloadBuffer, maxPooledBuffers),
+	}
+}
+
+// get returns a pooled buffer, or a new one if none is available.
+func (bp *bufferPool) get() *payloadBuffer {
+	if bp != nil {
+		select {
+		case b := <-bp.buffers:
+			return b
+		default:
+		}
+	}
+	b := new(payloadBuffer)
+	if bp != nil && bp.size > 0 {
+		b.Grow(bp.size)
+	}
+	b.wr = msgp.NewWriter(&b.Buffer)
+	return b
+}
+
+// put gives b back to the pool, unless the pool is full or b grew larger
+// than the maximum payload size.
+func (bp *bufferPool) put(b *payloadBuffer) {
+	if bp == nil || b.Cap() > payloadMaxLimit {
+		return
+	}
+	b.Reset()
+	b.wr.Reset(&b.Buffer)
+	select {
+	case bp.buffers <- b:
+	default:
+	}
+}
@@ -7,12 +7,17 @@ package tracer
 
 import (
 	"bytes"
+	"encoding/json"
 	"io"
 	"strconv"
 	"strings"
 	"sync/atomic"
 	"testing"
 
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/version"
+
 	"github.com/stretchr/testify/assert"
 	"github.com/tinylib/msgp/msgp"
 )
@@ -74,6 +79,190 @@ func TestPayloadDecode(t *testing.T) {
 	}
 }
 
+// TestPayloadPool ensures that the buffers of the payloads are reused once they
+// are closed, and that a closed payload cannot be read anymore.
+func TestPayloadPool(t *testing.T) {
+	pool := newBufferPool(1024)
+	p := newPooledPayload(pool)
+	assert.Equal(t, 1024, p.buf.Cap())
+	buf := p.buf
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, p.push(newSpanList(i%5+1)))
+	}
+	want := new(bytes.Buffer)
+	_, err := io.Copy(want, p)
+	assert.NoError(t, err)
+	assert.NoError(t, p.Close())
+	assert.NoError(t, p.Close())
+	assert.Equal(t, 0, p.size())
+	n, err := p.Read(make([]byte, 10))
+	assert.Equal(t, 0, n)
+	assert.Equal(t, io.EOF, err)
+
+	// the next payload reuses the buffer, and encodes the same
+	p2 := newPooledPayload(pool)
+	assert.Same(t, buf, p2.buf)
+	assert.Equal(t, 0, p2.buf.Len())
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, p2.push(newSpanList(i%5+1)))
+	}
+	got, err := io.ReadAll(p2)
+	assert.NoError(t, err)
+	assert.Equal(t, want.Bytes(), got)
+
+	// only a few buffers are kept
+	bufs := make([]*payloadBuffer, maxPooledBuffers+1)
+	for i := range bufs {
+		bufs[i] = pool.get()
+	}
+	for _, b := range bufs {
+		pool.put(b)
+	}
+	assert.Len(t, pool.buffers, maxPooledBuffers)
+
+	// a nil pool allocates new buffers
+	var nilPool *bufferPool
+	p3 := newPooledPayload(nilPool)
+	assert.NotNil(t, p3.buf)
+	assert.NoError(t, p3.Close())
+}
+
+// TestPayloadClone ensures that the clones of a payload read its items from the
+// start, and that its buffer is reused only once it and its clones are closed.
+func TestPayloadClone(t *testing.T) {
+	pool := newBufferPool(1024)
+	p := newPooledPayload(pool)
+	buf := p.buf
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, p.push(newSpanList(i%5+1)))
+	}
+	size := p.size()
+	c1, c2 := p.clone(), p.clone()
+	assert.Equal(t, size, c1.size())
+	assert.Equal(t, p.itemCount(), c1.itemCount())
+	want, err := io.ReadAll(c1)
+	assert.NoError(t, err)
+	assert.Len(t, want, size)
+	assert.NoError(t, c1.Close())
+	got, err := io.ReadAll(c2)
+	assert.NoError(t, err)
+	assert.Equal(t, want, got)
+
+	// the original is not consumed by its clones
+	assert.Equal(t, size, p.size())
+	assert.NoError(t, p.Close())
+	assert.Empty(t, pool.buffers)
+	assert.NoError(t, c2.Close())
+	assert.NoError(t, c2.Close())
+	assert.Len(t, pool.buffers, 1)
+	assert.Same(t, buf, pool.get())
+}
+
+// TestPayloadV07 ensures that the v0.7 payloads hold the traces as the chunks of
+// a tracer payload, and that their traces can be decoded back.
+func TestPayloadV07(t *testing.T) {
+	c := newConfig(WithEnv("prod"), WithServiceVersion("1.2.3"), withNoopStats())
+	p := newProtocolPayload(nil, traceProtocolV07, tracerPayloadPrefix(c, c.loadGlobalConfig()))
+	defer p.Close()
+	sampled := newSpanList(2)
+	sampled[0].Metrics[keySamplingPriority] = ext.PriorityUserKeep
+	sampled[0].Meta[keyOrigin] = "synthetics"
+	for _, trace := range []spanList{sampled, newSpanList(1)} {
+		assert.NoError(t, p.push(trace))
+	}
+	for i := 0; i < 20; i++ {
+		// past the fixarray header
+		assert.NoError(t, p.push(newSpanList(1)))
+	}
+
+	traces, err := p.traces()
+	assert.NoError(t, err)
+	assert.Len(t, traces, 22)
+	assert.Len(t, traces[0], 2)
+	assert.Equal(t, sampled[0].Name, traces[0][0].Name)
+
+	var js bytes.Buffer
+	_, err = msgp.CopyToJSON(&js, p.clone())
+	assert.NoError(t, err)
+	var tp struct {
+		LanguageName  string `json:"languageName"`
+		TracerVersion string `json:"tracerVersion"`
+		RuntimeID     string `json:"runtimeID"`
+		Env           string `json:"env"`
+		AppVersion    string `json:"appVersion"`
+		Chunks        []struct {
+			Priority int32   `json:"priority"`
+			Origin   string  `json:"origin"`
+			Spans    []*span `json:"spans"`
+		} `json:"chunks"`
+	}
+	assert.NoError(t, json.Unmarshal(js.Bytes(), &tp))
+	assert.Equal(t, "go", tp.LanguageName)
+	assert.Equal(t, version.Tag, tp.TracerVersion)
+	assert.Equal(t, globalconfig.RuntimeID(), tp.RuntimeID)
+	assert.Equal(t, "prod", tp.Env)
+	assert.Equal(t, "1.2.3", tp.AppVersion)
+	assert.Len(t, tp.Chunks, 22)
+	assert.Equal(t, int32(ext.PriorityUserKeep), tp.Chunks[0].Priority)
+	assert.Equal(t, "synthetics", tp.Chunks[0].Origin)
+	assert.Len(t, tp.Chunks[0].Spans, 2)
+	assert.Equal(t, int32(priorityNone), tp.Chunks[1].Priority)
+	assert.Equal(t, "", tp.Chunks[1].Origin)
+}
+
+// TestStreamingPayload ensures that the streamed payloads are encoded as they are
+// read, the same way as the payloads encoded when their traces are pushed.
+func TestStreamingPayload(t *testing.T) {
+	for _, protocol := range []string{traceProtocolV04, traceProtocolV07} {
+		t.Run(protocol, func(t *testing.T) {
+			prefix := tracerPayloadPrefix(newConfig(withNoopStats()), &globalConfig{env: "prod"})
+			buffered := newProtocolPayload(nil, protocol, prefix)
+			streamed := newStreamingPayload(protocol, prefix)
+			for i := 0; i < 20; i++ {
+				trace := newSpanList(i%3 + 1)
+				assert.NoError(t, buffered.push(trace))
+				assert.NoError(t, streamed.push(trace))
+			}
+			assert.Equal(t, 20, streamed.itemCount())
+			assert.GreaterOrEqual(t, streamed.size(), buffered.size())
+
+			c := streamed.clone()
+			want, err := io.ReadAll(buffered)
+			assert.NoError(t, err)
+			got, err := io.ReadAll(streamed)
+			assert.NoError(t, err)
+			assert.Equal(t, want, got)
+			assert.NoError(t, streamed.Close())
+
+			// the clones are encoded again
+			got, err = io.ReadAll(c)
+			assert.NoError(t, err)
+			assert.Equal(t, want, got)
+			assert.NoError(t, c.Close())
+
+			traces, err := streamed.traces()
+			assert.NoError(t, err)
+			assert.Len(t, traces, 20)
+		})
+	}
+
+	t.Run("close", func(t *testing.T) {
+		p := newStreamingPayload(traceProtocolV04, nil)
+		for i := 0; i < 100; i++ {
+			assert.NoError(t, p.push(newSpanList(10)))
+		}
+		// the encoder stops once the payload is closed mid-read
+		_, err := p.Read(make([]byte, 64))
+		assert.NoError(t, err)
+		_, err = p.Read(make([]byte, 64))
+		assert.NoError(t, err)
+		assert.NoError(t, p.Close())
+		n, err := p.Read(make([]byte, 64))
+		assert.Zero(t, n)
+		assert.Equal(t, io.EOF, err)
+	})
+}
+
 func BenchmarkPayloadThroughput(b *testing.B) {
 	b.Run("10K", benchmarkPayloadThroughput(1))
 	b.Run("100K", benchmarkPayloadThroughput(10))
@@ -0,0 +1,39 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package tracer
+
+import "gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+
+// keyPeerServiceSource holds the tag the default peer.service tag of a span was
+// taken from, see WithPeerServiceDefaults.
+const keyPeerServiceSource = "_dd.peer.service.source"
+
+// peerServiceSources lists the tags the default peer.service tag is taken from,
+// in order of precedence.
+var peerServiceSources = []string{
+	ext.DBInstance,
+	ext.DBName,
+	ext.TargetHost,
+	ext.PeerHostname,
+}
+
+// setPeerService gives s, when it is a client or producer span without a
+// peer.service tag, the default one. It must be called with s locked.
+func (s *span) setPeerService() {
+	if _, ok := s.Meta[ext.PeerService]; ok {
+		return
+	}
+	if kind := s.Meta[ext.SpanKind]; kind != ext.SpanKindClient && kind != ext.SpanKindProducer {
+		return
+	}
+	for _, source := range peerServiceSources {
+		if v := s.Meta[source]; v != "" {
+			s.setMeta(ext.PeerService, v)
+			s.setMeta(keyPeerServiceSource, source)
+			return
+		}
+	}
+}
@@ -0,0 +1,63 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package tracer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+)
+
+func TestPeerServiceDefaults(t *testing.T) {
+	for name, tt := range map[string]struct {
+		tags   map[string]interface{}
+		peer   string
+		source string
+	}{
+		"db": {
+			tags:   map[string]interface{}{ext.SpanKind: ext.SpanKindClient, ext.DBInstance: "orders", ext.TargetHost: "db.local"},
+			peer:   "orders",
+			source: ext.DBInstance,
+		},
+		"host": {
+			tags:   map[string]interface{}{ext.SpanKind: ext.SpanKindProducer, ext.TargetHost: "kafka.local"},
+			peer:   "kafka.local",
+			source: ext.TargetHost,
+		},
+		"set": {
+			tags: map[string]interface{}{ext.SpanKind: ext.SpanKindClient, ext.PeerService: "billing", ext.TargetHost: "db.local"},
+			peer: "billing",
+		},
+		"server": {
+			tags: map[string]interface{}{ext.SpanKind: ext.SpanKindServer, ext.TargetHost: "localhost"},
+		},
+		"no-source": {
+			tags: map[string]interface{}{ext.SpanKind: ext.SpanKindClient},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			tracer, _, _, stop := startTestTracer(t, WithPeerServiceDefaults(true))
+			defer stop()
+			s := tracer.StartSpan("op").(*span)
+			for k, v := range tt.tags {
+				s.SetTag(k, v)
+			}
+			s.Finish()
+			assert.Equal(t, tt.peer, s.Meta[ext.PeerService])
+			assert.Equal(t, tt.source, s.Meta[keyPeerServiceSource])
+		})
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		tracer, _, _, stop := startTestTracer(t)
+		defer stop()
+		s := tracer.StartSpan("op", Tag(ext.SpanKind, ext.SpanKindClient), Tag(ext.TargetHost, "db.local")).(*span)
+		s.Finish()
+		assert.NotContains(t, s.Meta, ext.PeerService)
+	})
+}
@@ -0,0 +1,23 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+//go:build !windows
+// +build !windows
+
+package tracer
+
+import (
+	"context"
+	"errors"
+	"net"
+)
+
+// errNamedPipeUnsupported is returned when dialing a named pipe outside of Windows.
+var errNamedPipeUnsupported = errors.New("named pipes are only supported on Windows")
+
+// dialPipe connects to the named pipe at path. Named pipes only exist on Windows.
+func dialPipe(_ context.Context, _ string) (net.Conn, error) {
+	return nil, errNamedPipeUnsupported
+}
@@ -0,0 +1,24 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+//go:build !windows
+// +build !windows
+
+package tracer
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithNamedPipe(t *testing.T) {
+	t.Setenv("DD_TRACE_PIPE_NAME", "datadog-apm")
+	c := newConfig()
+	assert.NotEqual(t, defaultClient, c.httpClient)
+	_, err := c.httpClient.Get(fmt.Sprintf("http://%s/info", c.agentAddr))
+	assert.ErrorIs(t, err, errNamedPipeUnsupported)
+}
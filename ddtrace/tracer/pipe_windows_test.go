@@ -0,0 +1,36 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package tracer
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/Microsoft/go-winio"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithNamedPipe(t *testing.T) {
+	t.Setenv("DD_TRACE_STARTUP_LOGS", "0")
+	ln, err := winio.ListenPipe(`\\.\pipe\dd-trace-go-test`, nil)
+	require.NoError(t, err)
+	var hits int
+	srv := http.Server{Handler: http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		hits++
+	})}
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	trc := newTracer(WithNamedPipe("dd-trace-go-test"))
+	defer trc.Stop()
+
+	p, err := encode(getTestTrace(1, 1))
+	require.NoError(t, err)
+	_, err = trc.config.transport.send(p)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, hits)
+}
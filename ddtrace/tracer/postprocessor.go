@@ -0,0 +1,217 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022 Datadog, Inc.
+
+package tracer
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// ReadOnlySpan is a finished span, as seen by the post processors registered with
+// WithPostProcessor. Its name, identifiers and timing can't be changed, but its
+// tags can, to scrub sensitive values or to add computed ones before the span is
+// encoded.
+type ReadOnlySpan interface {
+	// OperationName returns the operation name of the span.
+	OperationName() string
+
+	// ServiceName returns the service name of the span.
+	ServiceName() string
+
+	// ResourceName returns the resource name of the span.
+	ResourceName() string
+
+	// SpanType returns the type of the span, e.g. "web" or "db".
+	SpanType() string
+
+	// TraceID returns the identifier of the trace of the span.
+	TraceID() uint64
+
+	// SpanID returns the identifier of the span.
+	SpanID() uint64
+
+	// ParentID returns the identifier of the parent of the span, or 0 for a root span.
+	ParentID() uint64
+
+	// StartTime returns the time the span started.
+	StartTime() time.Time
+
+	// Duration returns the duration of the span.
+	Duration() time.Duration
+
+	// IsError reports whether the span finished with an error.
+	IsError() bool
+
+	// Tag returns the value of the tag with the given key, a string or a float64,
+	// or nil when the span has no such tag.
+	Tag(key string) interface{}
+
+	// Tags returns a copy of all the tags of the span.
+	Tags() map[string]interface{}
+
+	// SetTag sets the tag with the given key to value. Numeric values are set as
+	// metrics, and any other value is set as its string representation.
+	SetTag(key string, value interface{})
+
+	// RemoveTag removes the tag with the given key, if any.
+	RemoveTag(key string)
+}
+
+// readOnlySpan implements ReadOnlySpan on a finished span. The span is locked by
+// every method, as the user may still hold it.
+type readOnlySpan struct {
+	s *span
+}
+
+var _ ReadOnlySpan = readOnlySpan{}
+
+func (r readOnlySpan) OperationName() string {
+	r.s.RLock()
+	defer r.s.RUnlock()
+	return r.s.Name
+}
+
+func (r readOnlySpan) ServiceName() string {
+	r.s.RLock()
+	defer r.s.RUnlock()
+	return r.s.Service
+}
+
+func (r readOnlySpan) ResourceName() string {
+	r.s.RLock()
+	defer r.s.RUnlock()
+	return r.s.Resource
+}
+
+func (r readOnlySpan) SpanType() string {
+	r.s.RLock()
+	defer r.s.RUnlock()
+	return r.s.Type
+}
+
+func (r readOnlySpan) TraceID() uint64 { return r.s.TraceID }
+
+func (r readOnlySpan) SpanID() uint64 { return r.s.SpanID }
+
+func (r readOnlySpan) ParentID() uint64 { return r.s.ParentID }
+
+func (r readOnlySpan) StartTime() time.Time {
+	r.s.RLock()
+	defer r.s.RUnlock()
+	return time.Unix(0, r.s.Start)
+}
+
+func (r readOnlySpan) Duration() time.Duration {
+	r.s.RLock()
+	defer r.s.RUnlock()
+	return time.Duration(r.s.Duration)
+}
+
+func (r readOnlySpan) IsError() bool {
+	r.s.RLock()
+	defer r.s.RUnlock()
+	return r.s.Error != 0
+}
+
+func (r readOnlySpan) Tag(key string) interface{} {
+	r.s.RLock()
+	defer r.s.RUnlock()
+	if v, ok := r.s.Meta[key]; ok {
+		return v
+	}
+	if v, ok := r.s.Metrics[key]; ok {
+		return v
+	}
+	return nil
+}
+
+func (r readOnlySpan) Tags() map[string]interface{} {
+	r.s.RLock()
+	defer r.s.RUnlock()
+	tags := make(map[string]interface{}, len(r.s.Meta)+len(r.s.Metrics))
+	for k, v := range r.s.Meta {
+		tags[k] = v
+	}
+	for k, v := range r.s.Metrics {
+		tags[k] = v
+	}
+	return tags
+}
+
+func (r readOnlySpan) SetTag(key string, value interface{}) {
+	r.s.Lock()
+	defer r.s.Unlock()
+	// the tags are written directly rather than through setMeta and setMetric,
+	// which would rename the span when setting ext.ServiceName for instance.
+	if v, ok := toFloat64(value); ok {
+		delete(r.s.Meta, key)
+		if r.s.Metrics == nil {
+			r.s.Metrics = make(map[string]float64, 1)
+		}
+		r.s.Metrics[key] = v
+		return
+	}
+	delete(r.s.Metrics, key)
+	if r.s.Meta == nil {
+		r.s.Meta = make(map[string]string, 1)
+	}
+	r.s.Meta[key] = fmt.Sprint(value)
+}
+
+func (r readOnlySpan) RemoveTag(key string) {
+	r.s.Lock()
+	defer r.s.Unlock()
+	delete(r.s.Meta, key)
+	delete(r.s.Metrics, key)
+}
+
+// postProcess runs the post processors of the tracer on the given finished spans
+// of a trace chunk, and returns the spans they kept. A span is dropped as soon as
+// a post processor returns false, the remaining ones not being run on it. When
+// the first span of the chunk is dropped, the sampling priority and the trace
+// level tags it carries are moved to the first kept span.
+func (t *tracer) postProcess(spans []*span) []*span {
+	kept := spans[:0:0]
+	for _, s := range spans {
+		keep := true
+		for _, fn := range t.config.postProcessors {
+			if !fn(readOnlySpan{s}) {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			kept = append(kept, s)
+		}
+	}
+	if len(kept) == len(spans) {
+		return spans
+	}
+	atomic.AddUint32(&t.spansPostProcessorDropped, uint32(len(spans)-len(kept)))
+	if len(kept) > 0 && kept[0] != spans[0] {
+		moveChunkTags(spans[0], kept[0])
+	}
+	return kept
+}
+
+// moveChunkTags copies the sampling priority and the trace level tags carried by
+// the first span of a chunk, from to its new first span.
+func moveChunkTags(from, to *span) {
+	from.RLock()
+	defer from.RUnlock()
+	to.Lock()
+	defer to.Unlock()
+	if p, ok := from.Metrics[keySamplingPriority]; ok {
+		to.setMetric(keySamplingPriority, p)
+	}
+	for k, v := range from.Meta {
+		if strings.HasPrefix(k, "_dd.p.") {
+			to.setMeta(k, v)
+		}
+	}
+}
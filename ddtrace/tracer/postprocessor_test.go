@@ -0,0 +1,134 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022 Datadog, Inc.
+
+package tracer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+)
+
+func TestPostProcessor(t *testing.T) {
+	t.Run("tags", func(t *testing.T) {
+		var calls []string
+		tracer, transport, flush, stop := startTestTracer(t,
+			WithPostProcessor(func(s ReadOnlySpan) bool {
+				calls = append(calls, "scrub:"+s.OperationName())
+				if s.Tag("password") != nil {
+					s.SetTag("password", "?")
+				}
+				s.RemoveTag("secret")
+				return true
+			}),
+			WithPostProcessor(func(s ReadOnlySpan) bool {
+				calls = append(calls, "compute:"+s.OperationName())
+				s.SetTag("duration_ms", s.Duration().Milliseconds())
+				s.SetTag(ext.ServiceName, "other")
+				return true
+			}),
+		)
+		defer stop()
+
+		root := tracer.StartSpan("root", ServiceName("service"))
+		child := tracer.StartSpan("child", ChildOf(root.Context()), Tag("password", "hunter2"), Tag("secret", 42))
+		child.Finish()
+		root.Finish()
+		flush(1)
+
+		assert.Equal(t, []string{"scrub:root", "compute:root", "scrub:child", "compute:child"}, calls)
+		spans := transport.Traces()[0]
+		require.Len(t, spans, 2)
+		c := spans[1]
+		assert.Equal(t, "child", c.Name)
+		assert.Equal(t, "service", c.Service)
+		assert.Equal(t, "other", c.Meta[ext.ServiceName])
+		assert.Equal(t, "?", c.Meta["password"])
+		assert.NotContains(t, c.Metrics, "secret")
+		assert.Contains(t, c.Metrics, "duration_ms")
+	})
+
+	t.Run("drop", func(t *testing.T) {
+		var calls int
+		tracer, transport, flush, stop := startTestTracer(t,
+			WithPostProcessor(func(s ReadOnlySpan) bool {
+				return s.ParentID() != 0
+			}),
+			WithPostProcessor(func(s ReadOnlySpan) bool {
+				calls++
+				return s.ResourceName() != "health"
+			}),
+		)
+		defer stop()
+
+		root := tracer.StartSpan("root")
+		tracer.StartSpan("child", ChildOf(root.Context()), ResourceName("health")).Finish()
+		tracer.StartSpan("child", ChildOf(root.Context()), ResourceName("users")).Finish()
+		root.Finish()
+		flush(1)
+
+		// the second post processor is not run on the dropped root span
+		assert.Equal(t, 2, calls)
+		spans := transport.Traces()[0]
+		require.Len(t, spans, 1)
+		s := spans[0]
+		assert.Equal(t, "users", s.Resource)
+		// the sampling priority and the trace level tags are moved from the root
+		assert.Contains(t, s.Metrics, keySamplingPriority)
+		assert.Contains(t, s.Meta, keyDecisionMaker)
+		assert.Equal(t, uint32(2), tracer.spansPostProcessorDropped)
+	})
+
+	t.Run("drop-all", func(t *testing.T) {
+		tracer, transport, _, stop := startTestTracer(t,
+			WithPostProcessor(func(s ReadOnlySpan) bool { return false }),
+		)
+		defer stop()
+
+		tracer.StartSpan("root").Finish()
+		tracer.StartSpan("root").Finish()
+		tracer.flushSync(context.Background())
+
+		assert.Equal(t, 0, transport.Len())
+	})
+}
+
+func TestReadOnlySpan(t *testing.T) {
+	s := newBasicSpan("op")
+	s.SetTag(ext.ResourceName, "resource")
+	s.SetTag(ext.SpanType, "web")
+	s.SetTag("str", "value")
+	s.SetTag("num", 1)
+	s.SetTag(ext.Error, true)
+	s.Finish()
+	r := readOnlySpan{s}
+
+	assert.Equal(t, "op", r.OperationName())
+	assert.Equal(t, "resource", r.ResourceName())
+	assert.Equal(t, "web", r.SpanType())
+	assert.Equal(t, s.TraceID, r.TraceID())
+	assert.Equal(t, s.SpanID, r.SpanID())
+	assert.Equal(t, uint64(0), r.ParentID())
+	assert.Equal(t, s.Start, r.StartTime().UnixNano())
+	assert.Equal(t, s.Duration, r.Duration().Nanoseconds())
+	assert.True(t, r.IsError())
+	assert.Equal(t, "value", r.Tag("str"))
+	assert.Equal(t, 1.0, r.Tag("num"))
+	assert.Nil(t, r.Tag("missing"))
+
+	r.SetTag("str", 2)
+	r.SetTag("num", "one")
+	assert.Equal(t, 2.0, r.Tag("str"))
+	assert.Equal(t, "one", r.Tag("num"))
+	tags := r.Tags()
+	assert.Equal(t, 2.0, tags["str"])
+	assert.Equal(t, "one", tags["num"])
+	r.RemoveTag("str")
+	assert.Nil(t, r.Tag("str"))
+}
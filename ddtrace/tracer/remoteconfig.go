@@ -0,0 +1,221 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022 Datadog, Inc.
+
+package tracer
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sync"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/remoteconfig"
+
+	rc "github.com/DataDog/datadog-agent/pkg/remoteconfig/state"
+)
+
+// RemoteConfigUpdate describes the outcome of applying an APM_TRACING remote
+// configuration payload.
+type RemoteConfigUpdate struct {
+	// Path is the remote configuration file path the update originates from.
+	Path string
+	// SamplingRate is the global trace sampling rate in effect after the update.
+	// It is NaN when no global sampling rate is set.
+	SamplingRate float64
+	// Env is the environment of the spans in effect after the update.
+	Env string
+	// Version is the version of the service in effect after the update.
+	Version string
+	// Error is non-nil when the payload could not be applied.
+	Error error
+}
+
+var (
+	// rcUpdateMu guards rcUpdateCallbacks.
+	rcUpdateMu sync.RWMutex
+	// rcUpdateCallbacks holds the functions registered with OnRemoteConfigUpdate.
+	rcUpdateCallbacks []func(RemoteConfigUpdate)
+)
+
+// OnRemoteConfigUpdate registers fn to be called every time the tracer processes
+// an APM_TRACING remote configuration update, whether it was applied successfully
+// or not. It is meant for observability purposes and fn must not block.
+func OnRemoteConfigUpdate(fn func(RemoteConfigUpdate)) {
+	if fn == nil {
+		return
+	}
+	rcUpdateMu.Lock()
+	defer rcUpdateMu.Unlock()
+	rcUpdateCallbacks = append(rcUpdateCallbacks, fn)
+}
+
+// notifyRemoteConfigUpdate calls all the functions registered with OnRemoteConfigUpdate.
+func notifyRemoteConfigUpdate(u RemoteConfigUpdate) {
+	// callbacks are called without holding the lock so that they can register other callbacks
+	rcUpdateMu.RLock()
+	callbacks := make([]func(RemoteConfigUpdate), len(rcUpdateCallbacks))
+	copy(callbacks, rcUpdateCallbacks)
+	rcUpdateMu.RUnlock()
+	for _, fn := range callbacks {
+		fn(u)
+	}
+}
+
+// apmTracingConfig is the payload of an APM_TRACING remote configuration file.
+type apmTracingConfig struct {
+	LibConfig struct {
+		// SamplingRate is the global trace sampling rate. A nil value reverts to
+		// the locally configured rate.
+		SamplingRate *float64 `json:"tracing_sampling_rate"`
+		// Env, Version and ServiceMapping replace the environment, the version and
+		// the service mappings of the tracer. A nil value reverts to the locally
+		// configured value.
+		Env            *string           `json:"env"`
+		Version        *string           `json:"version"`
+		ServiceMapping map[string]string `json:"tracing_service_mapping"`
+	} `json:"lib_config"`
+}
+
+// globalConfig is a snapshot of the tracer settings which can be updated at
+// runtime. Snapshots are swapped atomically as a whole and must not be modified
+// once stored.
+type globalConfig struct {
+	// env is the environment set on all spans.
+	env string
+	// version is the version of the service, set on its spans.
+	version string
+	// serviceMappings renames services of the spans.
+	serviceMappings map[string]string
+}
+
+// localGlobalConfig returns the snapshot of the settings configured locally in c.
+func localGlobalConfig(c *config) *globalConfig {
+	return &globalConfig{
+		env:             c.env,
+		version:         c.version,
+		serviceMappings: c.serviceMappings,
+	}
+}
+
+// loadGlobalConfig returns the snapshot of the settings currently in effect,
+// which are the ones configured locally in c until updated.
+func (c *config) loadGlobalConfig() *globalConfig {
+	if gc, ok := c.globalConfig.Load().(*globalConfig); ok {
+		return gc
+	}
+	return localGlobalConfig(c)
+}
+
+// loadGlobalConfig returns the snapshot of the settings currently in effect.
+func (t *tracer) loadGlobalConfig() *globalConfig {
+	return t.config.loadGlobalConfig()
+}
+
+// updateGlobalConfig atomically replaces the environment, the version and the
+// service mappings applied to new spans, as well as to the client stats and the
+// metadata of the payloads, without restarting the tracer. Spans started before
+// the update are not affected. It is used to apply remote configuration updates.
+func (t *tracer) updateGlobalConfig(env, version string, serviceMappings map[string]string) {
+	gc := &globalConfig{env: env, version: version}
+	if len(serviceMappings) > 0 {
+		gc.serviceMappings = make(map[string]string, len(serviceMappings))
+		for from, to := range serviceMappings {
+			gc.serviceMappings[from] = to
+		}
+	}
+	t.config.globalConfig.Store(gc)
+	globalconfig.SetEnv(env)
+	globalconfig.SetServiceVersion(version)
+}
+
+// startRemoteConfig starts the remote configuration client shared by the
+// tracer and its products, such as AppSec, and subscribes the tracer to the
+// APM_TRACING product.
+func (t *tracer) startRemoteConfig(cfg remoteconfig.ClientConfig) error {
+	client, err := remoteconfig.Start(cfg)
+	if err != nil {
+		return err
+	}
+	client.Subscribe(remoteconfig.ProductAPMTracing, t.onRemoteConfigUpdate, remoteconfig.APMTracingSampleRate, remoteconfig.APMTracingGlobalConfig)
+	t.rcClient = client
+	return nil
+}
+
+// stopRemoteConfig stops the remote configuration client, if any.
+func (t *tracer) stopRemoteConfig() {
+	if t.rcClient != nil {
+		remoteconfig.Stop()
+	}
+}
+
+// onRemoteConfigUpdate applies the APM_TRACING payloads received through remote
+// configuration. It is used as a callback for the APM_TRACING product.
+func (t *tracer) onRemoteConfigUpdate(u remoteconfig.ProductUpdate) map[string]rc.ApplyStatus {
+	statuses := make(map[string]rc.ApplyStatus, len(u))
+	for path, raw := range u {
+		log.Debug("Remote config: processing %s", path)
+		status := rc.ApplyStatus{State: rc.ApplyStateAcknowledged}
+		err := t.applyAPMTracingConfig(raw)
+		if err != nil {
+			log.Error("Remote config: error while processing %s. Configuration won't be applied: %v", path, err)
+			status.State = rc.ApplyStateError
+			status.Error = err.Error()
+		}
+		statuses[path] = status
+		gc := t.loadGlobalConfig()
+		notifyRemoteConfigUpdate(RemoteConfigUpdate{
+			Path:         path,
+			SamplingRate: t.rulesSampling.GlobalRate(),
+			Env:          gc.env,
+			Version:      gc.version,
+			Error:        err,
+		})
+	}
+	return statuses
+}
+
+// applyAPMTracingConfig updates the tracer configuration according to raw. A nil
+// payload means the configuration was removed, in which case the local
+// configuration is restored.
+func (t *tracer) applyAPMTracingConfig(raw []byte) error {
+	if raw == nil {
+		t.rulesSampling.SetGlobalRate(globalSampleRate())
+		t.updateGlobalConfig(t.config.env, t.config.version, t.config.serviceMappings)
+		return nil
+	}
+	var c apmTracingConfig
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return err
+	}
+	rate := globalSampleRate()
+	if r := c.LibConfig.SamplingRate; r != nil {
+		if *r < 0.0 || *r > 1.0 {
+			return fmt.Errorf("tracing_sampling_rate out of range: %f", *r)
+		}
+		rate = *r
+	}
+	if math.IsNaN(rate) {
+		log.Debug("Remote config: global sampling rate unset")
+	} else {
+		log.Debug("Remote config: global sampling rate set to %f", rate)
+	}
+	t.rulesSampling.SetGlobalRate(rate)
+
+	env, version, mappings := t.config.env, t.config.version, t.config.serviceMappings
+	if c.LibConfig.Env != nil {
+		env = *c.LibConfig.Env
+	}
+	if c.LibConfig.Version != nil {
+		version = *c.LibConfig.Version
+	}
+	if c.LibConfig.ServiceMapping != nil {
+		mappings = c.LibConfig.ServiceMapping
+	}
+	log.Debug("Remote config: env set to %q, version set to %q, service mappings set to %v", env, version, mappings)
+	t.updateGlobalConfig(env, version, mappings)
+	return nil
+}
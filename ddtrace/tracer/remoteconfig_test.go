@@ -0,0 +1,158 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022 Datadog, Inc.
+
+package tracer
+
+import (
+	"math"
+	"testing"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/remoteconfig"
+
+	rc "github.com/DataDog/datadog-agent/pkg/remoteconfig/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOnRemoteConfigUpdate(t *testing.T) {
+	const path = "datadog/2/APM_TRACING/config/lib_config"
+
+	t.Run("sampling-rate", func(t *testing.T) {
+		assert := assert.New(t)
+		tracer := newTracer(withTransport(newDummyTransport()))
+		defer tracer.Stop()
+		assert.True(math.IsNaN(tracer.rulesSampling.GlobalRate()))
+
+		statuses := tracer.onRemoteConfigUpdate(remoteconfig.ProductUpdate{
+			path: []byte(`{"lib_config":{"tracing_sampling_rate":0.5}}`),
+		})
+		assert.Equal(rc.ApplyStateAcknowledged, statuses[path].State)
+		assert.Equal(0.5, tracer.rulesSampling.GlobalRate())
+
+		span := tracer.newRootSpan("op", "svc", "res")
+		tracer.sample(span)
+		assert.Equal(0.5, span.Metrics[keyRulesSamplerAppliedRate])
+
+		statuses = tracer.onRemoteConfigUpdate(remoteconfig.ProductUpdate{path: nil})
+		assert.Equal(rc.ApplyStateAcknowledged, statuses[path].State)
+		assert.True(math.IsNaN(tracer.rulesSampling.GlobalRate()))
+	})
+
+	t.Run("env-fallback", func(t *testing.T) {
+		assert := assert.New(t)
+		t.Setenv("DD_TRACE_SAMPLE_RATE", "0.2")
+		tracer := newTracer(withTransport(newDummyTransport()))
+		defer tracer.Stop()
+
+		tracer.onRemoteConfigUpdate(remoteconfig.ProductUpdate{
+			path: []byte(`{"lib_config":{"tracing_sampling_rate":1}}`),
+		})
+		assert.Equal(1.0, tracer.rulesSampling.GlobalRate())
+
+		tracer.onRemoteConfigUpdate(remoteconfig.ProductUpdate{path: []byte(`{"lib_config":{}}`)})
+		assert.Equal(0.2, tracer.rulesSampling.GlobalRate())
+	})
+
+	t.Run("global-config", func(t *testing.T) {
+		assert := assert.New(t)
+		tracer := newTracer(
+			withTransport(newDummyTransport()),
+			WithEnv("local-env"),
+			WithUniversalVersion("1.0"),
+			WithServiceMapping("db", "local-db"),
+		)
+		defer tracer.Stop()
+		defer globalconfig.SetEnv("")
+		defer globalconfig.SetServiceVersion("")
+
+		statuses := tracer.onRemoteConfigUpdate(remoteconfig.ProductUpdate{
+			path: []byte(`{"lib_config":{"env":"rc-env","version":"2.0","tracing_service_mapping":{"db":"rc-db"}}}`),
+		})
+		assert.Equal(rc.ApplyStateAcknowledged, statuses[path].State)
+		assert.Equal("rc-env", globalconfig.Env())
+		sp := tracer.StartSpan("op", ServiceName("db")).(*span)
+		assert.Equal("rc-db", sp.Service)
+		assert.Equal("rc-env", sp.Meta[ext.Environment])
+		assert.Equal("2.0", sp.Meta[ext.Version])
+
+		tracer.onRemoteConfigUpdate(remoteconfig.ProductUpdate{path: []byte(`{"lib_config":{"version":"3.0"}}`)})
+		sp = tracer.StartSpan("op", ServiceName("db")).(*span)
+		assert.Equal("local-db", sp.Service)
+		assert.Equal("local-env", sp.Meta[ext.Environment])
+		assert.Equal("3.0", sp.Meta[ext.Version])
+
+		tracer.onRemoteConfigUpdate(remoteconfig.ProductUpdate{path: nil})
+		sp = tracer.StartSpan("op", ServiceName("db")).(*span)
+		assert.Equal("local-db", sp.Service)
+		assert.Equal("1.0", sp.Meta[ext.Version])
+		assert.Equal("local-env", globalconfig.Env())
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		assert := assert.New(t)
+		tracer := newTracer(withTransport(newDummyTransport()))
+		defer tracer.Stop()
+
+		for _, payload := range []string{`{"lib_config":{"tracing_sampling_rate":2}}`, `{`} {
+			statuses := tracer.onRemoteConfigUpdate(remoteconfig.ProductUpdate{path: []byte(payload)})
+			assert.Equal(rc.ApplyStateError, statuses[path].State)
+			assert.NotEmpty(statuses[path].Error)
+			assert.True(math.IsNaN(tracer.rulesSampling.GlobalRate()))
+		}
+	})
+
+	t.Run("callback", func(t *testing.T) {
+		assert := assert.New(t)
+		tracer := newTracer(withTransport(newDummyTransport()))
+		defer tracer.Stop()
+		defer func(old []func(RemoteConfigUpdate)) { rcUpdateCallbacks = old }(rcUpdateCallbacks)
+
+		var updates []RemoteConfigUpdate
+		OnRemoteConfigUpdate(func(u RemoteConfigUpdate) { updates = append(updates, u) })
+		tracer.onRemoteConfigUpdate(remoteconfig.ProductUpdate{
+			path: []byte(`{"lib_config":{"tracing_sampling_rate":0.1}}`),
+		})
+		tracer.onRemoteConfigUpdate(remoteconfig.ProductUpdate{path: []byte(`{`)})
+
+		assert.Len(updates, 2)
+		assert.Equal(path, updates[0].Path)
+		assert.Equal(0.1, updates[0].SamplingRate)
+		assert.NoError(updates[0].Error)
+		assert.Equal(0.1, updates[1].SamplingRate)
+		assert.Error(updates[1].Error)
+	})
+	t.Run("callback-registers-callback", func(t *testing.T) {
+		tracer := newTracer(withTransport(newDummyTransport()))
+		defer tracer.Stop()
+		defer func(old []func(RemoteConfigUpdate)) { rcUpdateCallbacks = old }(rcUpdateCallbacks)
+
+		var calls int
+		OnRemoteConfigUpdate(func(u RemoteConfigUpdate) {
+			OnRemoteConfigUpdate(func(RemoteConfigUpdate) { calls++ })
+		})
+		tracer.onRemoteConfigUpdate(remoteconfig.ProductUpdate{path: nil})
+		tracer.onRemoteConfigUpdate(remoteconfig.ProductUpdate{path: nil})
+		assert.Equal(t, 1, calls)
+	})
+}
+
+func TestStartRemoteConfig(t *testing.T) {
+	tracer := newTracer(withTransport(newDummyTransport()))
+	defer tracer.Stop()
+
+	err := tracer.startRemoteConfig(remoteconfig.DefaultClientConfig())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{remoteconfig.ProductAPMTracing}, tracer.rcClient.Products)
+	assert.Equal(t, []remoteconfig.Capability{remoteconfig.APMTracingSampleRate, remoteconfig.APMTracingGlobalConfig}, tracer.rcClient.Capabilities)
+
+	// other products share the client started by the tracer
+	err = remoteconfig.Subscribe("ASM_FEATURES", func(remoteconfig.ProductUpdate) map[string]rc.ApplyStatus { return nil }, remoteconfig.ASMActivation)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{remoteconfig.ProductAPMTracing, "ASM_FEATURES"}, tracer.rcClient.Products)
+
+	tracer.stopRemoteConfig()
+	assert.Error(t, remoteconfig.Subscribe("ASM_FEATURES", nil))
+}
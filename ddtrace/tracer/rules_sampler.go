@@ -14,10 +14,9 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"golang.org/x/time/rate"
-
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/samplernames"
@@ -52,6 +51,10 @@ func (r *rulesSampler) HasSpanRules() bool { return r.spans.enabled() }
 
 func (r *rulesSampler) TraceRateLimit() (float64, bool) { return r.traces.limit() }
 
+func (r *rulesSampler) GlobalRate() float64 { return r.traces.rate() }
+
+func (r *rulesSampler) SetGlobalRate(rate float64) { r.traces.setGlobalRate(rate) }
+
 // SamplingRule is used for applying sampling rates to spans that match
 // the service name, operation name or both.
 // For basic usage, consider using the helper functions ServiceRule, NameRule, etc.
@@ -73,7 +76,12 @@ type SamplingRule struct {
 	ruleType     SamplingRuleType
 	exactService string
 	exactName    string
-	limiter      *rateLimiter
+	// globService and globName hold the glob patterns the Service and Name
+	// regular expressions of single span sampling rules were compiled from,
+	// so that rules are displayed the way they were configured.
+	globService string
+	globName    string
+	limiter     *rateLimiter
 }
 
 // match returns true when the span's details match all the expected values in the rule.
@@ -159,12 +167,13 @@ func RateRule(rate float64) SamplingRule {
 // Operation and service fields must be valid glob patterns.
 func SpanNameServiceRule(name, service string, rate float64) SamplingRule {
 	return SamplingRule{
-		Service:   globMatch(service),
-		Name:      globMatch(name),
-		Rate:      rate,
-		ruleType:  SamplingRuleSpan,
-		exactName: name,
-		limiter:   newSingleSpanRateLimiter(0),
+		Service:     globMatch(service),
+		Name:        globMatch(name),
+		Rate:        rate,
+		ruleType:    SamplingRuleSpan,
+		globService: service,
+		globName:    name,
+		limiter:     newSingleSpanRateLimiter(0),
 	}
 }
 
@@ -179,7 +188,8 @@ func SpanNameServiceMPSRule(name, service string, rate, limit float64) SamplingR
 		MaxPerSecond: limit,
 		Rate:         rate,
 		ruleType:     SamplingRuleSpan,
-		exactName:    name,
+		globService:  service,
+		globName:     name,
 		limiter:      newSingleSpanRateLimiter(limit),
 	}
 }
@@ -199,9 +209,11 @@ func SpanNameServiceMPSRule(name, service string, rate, limit float64) SamplingR
 // Its value is the number of spans to sample per second.
 // Spans that matched the rules but exceeded the rate limit are not sampled.
 type traceRulesSampler struct {
-	rules      []SamplingRule // the rules to match spans with
-	globalRate float64        // a rate to apply when no rules match a span
-	limiter    *rateLimiter   // used to limit the volume of spans sampled
+	rules   []SamplingRule // the rules to match spans with
+	limiter *rateLimiter   // used to limit the volume of spans sampled
+
+	mu         sync.RWMutex // guards globalRate
+	globalRate float64      // a rate to apply when no rules match a span
 }
 
 // newTraceRulesSampler configures a *traceRulesSampler instance using the given set of rules.
@@ -234,8 +246,23 @@ func globalSampleRate() float64 {
 	return defaultRate
 }
 
+// rate returns the global sampling rate currently in effect.
+func (rs *traceRulesSampler) rate() float64 {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+	return rs.globalRate
+}
+
+// setGlobalRate replaces the global sampling rate at runtime. A NaN rate
+// disables it, falling back to the configured rules and priority sampling.
+func (rs *traceRulesSampler) setGlobalRate(rate float64) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.globalRate = rate
+}
+
 func (rs *traceRulesSampler) enabled() bool {
-	return len(rs.rules) > 0 || !math.IsNaN(rs.globalRate)
+	return len(rs.rules) > 0 || !math.IsNaN(rs.rate())
 }
 
 // apply uses the sampling rules to determine the sampling rate for the
@@ -243,13 +270,13 @@ func (rs *traceRulesSampler) enabled() bool {
 // set using DD_TRACE_SAMPLE_RATE, then it returns false and the span is not
 // modified.
 func (rs *traceRulesSampler) apply(span *span) bool {
-	if !rs.enabled() {
+	rate := rs.rate()
+	if len(rs.rules) == 0 && math.IsNaN(rate) {
 		// short path when disabled
 		return false
 	}
 
 	var matched bool
-	rate := rs.globalRate
 	for _, rule := range rs.rules {
 		if rule.match(span) {
 			matched = true
@@ -287,7 +314,7 @@ func (rs *traceRulesSampler) applyRule(span *span, rate float64, now time.Time)
 // true if rules sampling is enabled. If not present it returns math.NaN() and false.
 func (rs *traceRulesSampler) limit() (float64, bool) {
 	if rs.enabled() {
-		return float64(rs.limiter.limiter.Limit()), true
+		return rs.limiter.limit, true
 	}
 	return math.NaN(), false
 }
@@ -311,10 +338,7 @@ func newRateLimiter() *rateLimiter {
 			limit = l
 		}
 	}
-	return &rateLimiter{
-		limiter:  rate.NewLimiter(rate.Limit(limit), int(math.Ceil(limit))),
-		prevTime: time.Now(),
-	}
+	return newTokenBucket(limit, int(math.Ceil(limit)))
 }
 
 // singleSpanRulesSampler allows a user-defined list of rules to apply to spans
@@ -375,62 +399,136 @@ func (rs *singleSpanRulesSampler) apply(span *span) bool {
 	return false
 }
 
-// rateLimiter is a wrapper on top of golang.org/x/time/rate which implements a rate limiter but also
-// returns the effective rate of allowance.
+// rateLimiter is a lock-free token bucket rate limiter which also returns the
+// effective rate of allowance. It is used on the hot path of the samplers and
+// is therefore implemented with atomic operations only, so that concurrent
+// span starts never wait on each other.
+//
+// The bucket is implemented as a generic cell rate algorithm: instead of a
+// number of tokens, it keeps the theoretical arrival time (tat) at which the
+// bucket will be full again. Allowing a span moves it one interval forward,
+// which is only possible as long as it doesn't go beyond the burst window.
 type rateLimiter struct {
-	limiter *rate.Limiter
+	limit    float64 // spans per second
+	interval int64   // nanoseconds between two tokens, 0 when unlimited
+	window   int64   // nanoseconds of tokens the bucket holds, i.e. interval*burst
+	tat      int64   // accessed atomically; theoretical arrival time, in Unix nanoseconds
+
+	// The following fields are accessed atomically. The counters of a period
+	// are reset by the first call made after it ended, so that the counts of
+	// the calls concurrent to the reset can be accounted to either period.
+	prevTime    int64  // Unix nanoseconds at which the current period started
+	allowed     uint64 // number of spans allowed in the current period
+	seen        uint64 // number of spans seen in the current period
+	prevAllowed uint64 // number of spans allowed in the previous period
+	prevSeen    uint64 // number of spans seen in the previous period
+}
 
-	mu          sync.Mutex // guards below fields
-	prevTime    time.Time  // time at which prevAllowed and prevSeen were set
-	allowed     float64    // number of spans allowed in the current period
-	seen        float64    // number of spans seen in the current period
-	prevAllowed float64    // number of spans allowed in the previous period
-	prevSeen    float64    // number of spans seen in the previous period
+// newTokenBucket returns a rateLimiter allowing limit spans per second, with
+// a burst of the given number of spans. An infinite limit allows all spans.
+func newTokenBucket(limit float64, burst int) *rateLimiter {
+	r := &rateLimiter{
+		limit:    limit,
+		prevTime: time.Now().UnixNano(),
+	}
+	switch {
+	case limit >= float64(time.Second):
+		// more than one span per nanosecond, including an infinite limit: unlimited
+	case limit <= 0 || burst <= 0:
+		// nothing can be allowed
+		r.interval, r.window = math.MaxInt64, 0
+	default:
+		r.interval = int64(float64(time.Second) / limit)
+		r.window = r.interval * int64(burst)
+		if r.window/int64(burst) != r.interval {
+			// overflow: the bucket cannot be emptied anyway
+			r.window = math.MaxInt64
+		}
+	}
+	return r
 }
 
 // allowOne returns the rate limiter's decision to allow the span to be sampled, and the
 // effective rate at the time it is called. The effective rate is computed by averaging the rate
 // for the previous second with the current rate
 func (r *rateLimiter) allowOne(now time.Time) (bool, float64) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	if d := now.Sub(r.prevTime); d >= time.Second {
-		// enough time has passed to reset the counters
-		if d.Truncate(time.Second) == time.Second && r.seen > 0 {
-			// exactly one second, so update prev
-			r.prevAllowed = r.allowed
-			r.prevSeen = r.seen
-		} else {
-			// more than one second, so reset previous rate
-			r.prevAllowed = 0
-			r.prevSeen = 0
+	if r.interval == 0 {
+		// unlimited: everything is allowed, without contending on the counters
+		return true, 1
+	}
+	nowNs := now.UnixNano()
+	if prev := atomic.LoadInt64(&r.prevTime); nowNs-prev >= int64(time.Second) {
+		// enough time has passed to reset the counters, which is done by the
+		// first caller to update prevTime
+		if atomic.CompareAndSwapInt64(&r.prevTime, prev, nowNs) {
+			allowed := atomic.SwapUint64(&r.allowed, 0)
+			seen := atomic.SwapUint64(&r.seen, 0)
+			if time.Duration(nowNs-prev).Truncate(time.Second) == time.Second && seen > 0 {
+				// exactly one second, so update prev
+				atomic.StoreUint64(&r.prevAllowed, allowed)
+				atomic.StoreUint64(&r.prevSeen, seen)
+			} else {
+				// more than one second, so reset previous rate
+				atomic.StoreUint64(&r.prevAllowed, 0)
+				atomic.StoreUint64(&r.prevSeen, 0)
+			}
 		}
-		r.prevTime = now
-		r.allowed = 0
-		r.seen = 0
 	}
 
-	r.seen++
-	var sampled bool
-	if r.limiter.AllowN(now, 1) {
-		r.allowed++
-		sampled = true
+	seen := atomic.AddUint64(&r.seen, 1)
+	allowed := atomic.LoadUint64(&r.allowed)
+	sampled := r.take(nowNs)
+	if sampled {
+		allowed = atomic.AddUint64(&r.allowed, 1)
+	}
+	total := atomic.LoadUint64(&r.prevSeen) + seen
+	if total == 0 {
+		return sampled, 1
 	}
-	er := (r.prevAllowed + r.allowed) / (r.prevSeen + r.seen)
+	er := float64(atomic.LoadUint64(&r.prevAllowed)+allowed) / float64(total)
 	return sampled, er
 }
 
+// effectiveRate returns the rate of allowance over the previous and current
+// periods, as returned by allowOne, without counting a new span.
+func (r *rateLimiter) effectiveRate() float64 {
+	if r.interval == 0 {
+		return 1
+	}
+	total := atomic.LoadUint64(&r.prevSeen) + atomic.LoadUint64(&r.seen)
+	if total == 0 {
+		return 1
+	}
+	return float64(atomic.LoadUint64(&r.prevAllowed)+atomic.LoadUint64(&r.allowed)) / float64(total)
+}
+
+// take takes a token from the bucket at the given time, in Unix nanoseconds.
+// It returns false when the bucket is empty.
+func (r *rateLimiter) take(now int64) bool {
+	for {
+		tat := atomic.LoadInt64(&r.tat)
+		next := tat
+		if next < now {
+			// the bucket is full
+			next = now
+		}
+		if next-now > r.window-r.interval {
+			// the token would be beyond the burst window
+			return false
+		}
+		if atomic.CompareAndSwapInt64(&r.tat, tat, next+r.interval) {
+			return true
+		}
+	}
+}
+
 // newSingleSpanRateLimiter returns a rate limiter which restricts the number of single spans sampled per second.
 // This defaults to infinite, allow all behaviour. The MaxPerSecond value of the rule may override the default.
 func newSingleSpanRateLimiter(mps float64) *rateLimiter {
-	limit := math.MaxFloat64
-	if mps > 0 {
-		limit = mps
-	}
-	return &rateLimiter{
-		limiter:  rate.NewLimiter(rate.Limit(limit), int(math.Ceil(limit))),
-		prevTime: time.Now(),
+	if mps <= 0 {
+		return newTokenBucket(math.Inf(1), 0)
 	}
+	return newTokenBucket(mps, int(math.Ceil(mps)))
 }
 
 // globMatch compiles pattern string into glob format, i.e. regular expressions with only '?'
@@ -533,6 +631,8 @@ func unmarshalSamplingRules(b []byte, spanType SamplingRuleType) ([]SamplingRule
 				MaxPerSecond: v.MaxPerSecond,
 				limiter:      newSingleSpanRateLimiter(v.MaxPerSecond),
 				ruleType:     SamplingRuleSpan,
+				globService:  v.Service,
+				globName:     v.Name,
 			})
 		case SamplingRuleTrace:
 			if v.Rate == "" {
@@ -576,11 +676,15 @@ func (sr *SamplingRule) MarshalJSON() ([]byte, error) {
 	}{}
 	if sr.exactService != "" {
 		s.Service = sr.exactService
+	} else if sr.globService != "" {
+		s.Service = sr.globService
 	} else if sr.Service != nil {
 		s.Service = fmt.Sprintf("%s", sr.Service)
 	}
 	if sr.exactName != "" {
 		s.Name = sr.exactName
+	} else if sr.globName != "" {
+		s.Name = sr.globName
 	} else if sr.Name != nil {
 		s.Name = fmt.Sprintf("%s", sr.Name)
 	}
@@ -0,0 +1,143 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package tracer
+
+import (
+	"math"
+	"runtime/metrics"
+	"strings"
+)
+
+// runtimeMetricsPrefix prefixes the names of the metrics read from runtime/metrics.
+const runtimeMetricsPrefix = "runtime.go.metrics."
+
+// runtimeMetricNames lists the runtime/metrics reported along with the runtime
+// metrics; the ones which are not supported by the Go version are skipped.
+var runtimeMetricNames = []string{
+	"/gc/cycles/total:gc-cycles",
+	"/gc/heap/allocs:bytes",
+	"/gc/heap/goal:bytes",
+	"/gc/heap/objects:objects",
+	"/gc/pauses:seconds",
+	"/memory/classes/heap/objects:bytes",
+	"/memory/classes/total:bytes",
+	"/sched/goroutines:goroutines",
+	"/sched/latencies:seconds",
+}
+
+// histogramQuantiles holds the quantiles reported for the histogram metrics,
+// keyed by their metric name suffix.
+var histogramQuantiles = []struct {
+	suffix string
+	q      float64
+}{
+	{".p50", 0.5},
+	{".p95", 0.95},
+	{".p99", 0.99},
+	{".max", 1},
+}
+
+// runtimeMetricsReader reads the runtime/metrics and reports them as gauges.
+// The histograms, such as the GC pauses and the scheduler latencies, are
+// reported as quantiles of their observations since the previous report.
+type runtimeMetricsReader struct {
+	samples []metrics.Sample
+	names   []string            // statsd names of the samples
+	counts  map[string][]uint64 // histogram counts of the previous report, by sample name
+}
+
+func newRuntimeMetricsReader() *runtimeMetricsReader {
+	supported := make(map[string]bool)
+	for _, d := range metrics.All() {
+		supported[d.Name] = true
+	}
+	r := &runtimeMetricsReader{counts: make(map[string][]uint64)}
+	for _, name := range runtimeMetricNames {
+		if !supported[name] {
+			continue
+		}
+		r.samples = append(r.samples, metrics.Sample{Name: name})
+		r.names = append(r.names, runtimeMetricName(name))
+	}
+	return r
+}
+
+// runtimeMetricName returns the statsd name of the runtime/metrics name, e.g.
+// runtime.go.metrics.gc_heap_goal.bytes for /gc/heap/goal:bytes.
+func runtimeMetricName(name string) string {
+	name = strings.TrimPrefix(name, "/")
+	r := strings.NewReplacer("/", "_", "-", "_")
+	if i := strings.IndexByte(name, ':'); i >= 0 {
+		return runtimeMetricsPrefix + r.Replace(name[:i]) + "." + r.Replace(name[i+1:])
+	}
+	return runtimeMetricsPrefix + r.Replace(name)
+}
+
+// report reads the runtime metrics and sends them to statsd with the given tags.
+func (r *runtimeMetricsReader) report(statsd statsdClient, tags []string) {
+	metrics.Read(r.samples)
+	for i, s := range r.samples {
+		switch s.Value.Kind() {
+		case metrics.KindUint64:
+			statsd.Gauge(r.names[i], float64(s.Value.Uint64()), tags, 1)
+		case metrics.KindFloat64:
+			statsd.Gauge(r.names[i], s.Value.Float64(), tags, 1)
+		case metrics.KindFloat64Histogram:
+			h := s.Value.Float64Histogram()
+			counts := r.delta(s.Name, h.Counts)
+			for _, hq := range histogramQuantiles {
+				if v, ok := quantile(counts, h.Buckets, hq.q); ok {
+					statsd.Gauge(r.names[i]+hq.suffix, v, tags, 1)
+				}
+			}
+		}
+	}
+}
+
+// delta returns the counts of the histogram named name observed since the
+// previous report, and keeps the given cumulative counts for the next one.
+func (r *runtimeMetricsReader) delta(name string, counts []uint64) []uint64 {
+	prev := r.counts[name]
+	r.counts[name] = append(prev[:0:0], counts...)
+	if len(prev) != len(counts) {
+		return counts
+	}
+	delta := make([]uint64, len(counts))
+	for i, c := range counts {
+		delta[i] = c - prev[i]
+	}
+	return delta
+}
+
+// quantile returns the upper bound of the bucket holding the q-quantile of the
+// histogram made of the given counts and buckets, as returned by runtime/metrics.
+// It returns false when the histogram is empty.
+func quantile(counts []uint64, buckets []float64, q float64) (float64, bool) {
+	var total uint64
+	for _, c := range counts {
+		total += c
+	}
+	if total == 0 {
+		return 0, false
+	}
+	rank := uint64(math.Ceil(q * float64(total)))
+	if rank == 0 {
+		rank = 1
+	}
+	var seen uint64
+	for i, c := range counts {
+		seen += c
+		if seen < rank {
+			continue
+		}
+		if v := buckets[i+1]; !math.IsInf(v, 1) {
+			return v, true
+		}
+		// the last bucket is unbounded
+		return buckets[i], true
+	}
+	return 0, false
+}
@@ -0,0 +1,50 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package tracer
+
+import (
+	"math"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRuntimeMetricName(t *testing.T) {
+	assert.Equal(t, "runtime.go.metrics.gc_heap_goal.bytes", runtimeMetricName("/gc/heap/goal:bytes"))
+	assert.Equal(t, "runtime.go.metrics.gc_cycles_total.gc_cycles", runtimeMetricName("/gc/cycles/total:gc-cycles"))
+}
+
+func TestQuantile(t *testing.T) {
+	buckets := []float64{math.Inf(-1), 1, 2, 3, math.Inf(1)}
+	counts := []uint64{0, 5, 4, 1}
+	for q, want := range map[float64]float64{0: 2, 0.5: 2, 0.9: 3, 0.95: 3, 1: 3} {
+		v, ok := quantile(counts, buckets, q)
+		assert.True(t, ok)
+		assert.Equal(t, want, v, q)
+	}
+	_, ok := quantile([]uint64{0, 0, 0, 0}, buckets, 0.5)
+	assert.False(t, ok)
+}
+
+func TestRuntimeMetricsReader(t *testing.T) {
+	assert := assert.New(t)
+	var tg testStatsdClient
+	r := newRuntimeMetricsReader()
+	runtime.GC()
+	r.report(&tg, []string{"service_version:1.2.3"})
+
+	calls := tg.CallNames()
+	assert.Contains(calls, "runtime.go.metrics.sched_goroutines.goroutines")
+	assert.Contains(calls, "runtime.go.metrics.gc_heap_goal.bytes")
+	assert.Contains(calls, "runtime.go.metrics.gc_pauses.seconds.p99")
+	assert.Contains(tg.gaugeCalls[0].tags, "service_version:1.2.3")
+
+	// the histograms are only reported when observed since the previous report
+	tg.Reset()
+	r.report(&tg, nil)
+	assert.NotContains(tg.CallNames(), "runtime.go.metrics.gc_pauses.seconds.p99")
+}
@@ -8,11 +8,11 @@ package tracer
 import (
 	"encoding/json"
 	"io"
-	"math"
 	"sync"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/sampler"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/samplernames"
 )
 
@@ -63,9 +63,6 @@ func (r *rateSampler) SetRate(rate float64) {
 	r.Unlock()
 }
 
-// constants used for the Knuth hashing, same as agent.
-const knuthFactor = uint64(1111111111111111111)
-
 // Sample returns true if the given span should be sampled.
 func (r *rateSampler) Sample(spn ddtrace.Span) bool {
 	if r.rate == 1 {
@@ -82,12 +79,9 @@ func (r *rateSampler) Sample(spn ddtrace.Span) bool {
 }
 
 // sampledByRate verifies if the number n should be sampled at the specified
-// rate.
+// rate, see sampler.SampleByRate.
 func sampledByRate(n uint64, rate float64) bool {
-	if rate < 1 {
-		return n*knuthFactor < uint64(rate*math.MaxUint64)
-	}
-	return true
+	return sampler.SampleByRate(n, rate)
 }
 
 // prioritySampler holds a set of per-service sampling rates and applies
@@ -125,6 +119,18 @@ func (ps *prioritySampler) readRatesJSON(rc io.ReadCloser) error {
 	return nil
 }
 
+// snapshot returns a copy of the per-service rates, and the default rate
+// applied to the services without one.
+func (ps *prioritySampler) snapshot() (rates map[string]float64, defaultRate float64) {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+	rates = make(map[string]float64, len(ps.rates))
+	for k, v := range ps.rates {
+		rates[k] = v
+	}
+	return rates, ps.defaultRate
+}
+
 // getRate returns the sampling rate to be used for the given span. Callers must
 // guard the span.
 func (ps *prioritySampler) getRate(spn *span) float64 {
@@ -13,6 +13,7 @@ import (
 	"regexp"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -20,7 +21,6 @@ import (
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/internal"
 
 	"github.com/stretchr/testify/assert"
-	"golang.org/x/time/rate"
 )
 
 func TestPrioritySampler(t *testing.T) {
@@ -214,20 +214,26 @@ func TestRuleEnvVars(t *testing.T) {
 		assert := assert.New(t)
 		defer os.Unsetenv("DD_TRACE_RATE_LIMIT")
 		for _, tt := range []struct {
-			in  string
-			out *rate.Limiter
+			in    string
+			limit float64
+			burst int64
 		}{
-			{in: "", out: rate.NewLimiter(100.0, 100)},
-			{in: "0.0", out: rate.NewLimiter(0.0, 0)},
-			{in: "0.5", out: rate.NewLimiter(0.5, 1)},
-			{in: "1.0", out: rate.NewLimiter(1.0, 1)},
-			{in: "42.0", out: rate.NewLimiter(42.0, 42)},
-			{in: "-1.0", out: rate.NewLimiter(100.0, 100)},    // default if out of range
-			{in: "1point0", out: rate.NewLimiter(100.0, 100)}, // default if invalid value
+			{in: "", limit: 100.0, burst: 100},
+			{in: "0.0", limit: 0.0, burst: 0},
+			{in: "0.5", limit: 0.5, burst: 1},
+			{in: "1.0", limit: 1.0, burst: 1},
+			{in: "42.0", limit: 42.0, burst: 42},
+			{in: "-1.0", limit: 100.0, burst: 100},    // default if out of range
+			{in: "1point0", limit: 100.0, burst: 100}, // default if invalid value
 		} {
 			os.Setenv("DD_TRACE_RATE_LIMIT", tt.in)
 			res := newRateLimiter()
-			assert.Equal(tt.out, res.limiter)
+			assert.Equal(tt.limit, res.limit)
+			if tt.burst == 0 {
+				assert.Zero(res.window)
+			} else {
+				assert.Equal(tt.burst, res.window/res.interval)
+			}
 		}
 	})
 
@@ -656,6 +662,23 @@ func TestRulesSamplerConcurrency(t *testing.T) {
 	wg.Wait()
 }
 
+func TestSingleSpanSamplingDroppedTrace(t *testing.T) {
+	assert := assert.New(t)
+	rules := []SamplingRule{SpanNameServiceRule("db.*", "postgres.db", 1.0)}
+	tracer := newTracer(WithSamplingRules(rules), withTransport(newDummyTransport()))
+	defer tracer.Stop()
+
+	root := tracer.StartSpan("web.request", ServiceName("web"), Tag(ext.ManualDrop, true)).(*span)
+	child := tracer.StartSpan("db.query", ServiceName("postgres.db"), ChildOf(root.Context())).(*span)
+	info := &finishedTrace{spans: []*span{root, child}}
+	tracer.sampleFinishedTrace(info)
+
+	assert.Equal([]*span{child}, info.spans)
+	assert.Equal(float64(samplingMechanismSingleSpan), child.Metrics[keySpanSamplingMechanism])
+	assert.Equal(1.0, child.Metrics[keySingleSpanSamplingRuleRate])
+	assert.NotContains(root.Metrics, keySpanSamplingMechanism)
+}
+
 func TestRulesSamplerInternals(t *testing.T) {
 	makeSpanAt := func(op string, svc string, ts time.Time) *span {
 		s := newSpan(op, svc, "", 0, 0, 0)
@@ -679,7 +702,7 @@ func TestRulesSamplerInternals(t *testing.T) {
 		now := time.Now()
 		rs := newRulesSampler(nil, nil)
 		// set samplingLimiter to specific state
-		rs.traces.limiter.prevTime = now.Add(-1 * time.Second)
+		rs.traces.limiter.prevTime = now.Add(-1 * time.Second).UnixNano()
 		rs.traces.limiter.allowed = 1
 		rs.traces.limiter.seen = 1
 
@@ -694,8 +717,8 @@ func TestRulesSamplerInternals(t *testing.T) {
 		now := time.Now()
 		rs := newRulesSampler(nil, nil)
 		// force sampling limiter to 1.0 spans/sec
-		rs.traces.limiter.limiter = rate.NewLimiter(rate.Limit(1.0), 1)
-		rs.traces.limiter.prevTime = now.Add(-1 * time.Second)
+		rs.traces.limiter = newTokenBucket(1.0, 1)
+		rs.traces.limiter.prevTime = now.Add(-1 * time.Second).UnixNano()
 		rs.traces.limiter.allowed = 2
 		rs.traces.limiter.seen = 2
 		// first span kept, second dropped
@@ -725,11 +748,11 @@ func TestSamplingLimiter(t *testing.T) {
 
 		sampled, _ := sl.allowOne(now)
 		assert.True(sampled)
-		assert.Equal(42.0, sl.prevAllowed)
-		assert.Equal(100.0, sl.prevSeen)
-		assert.Equal(now, sl.prevTime)
-		assert.Equal(1.0, sl.seen)
-		assert.Equal(1.0, sl.allowed)
+		assert.EqualValues(42, sl.prevAllowed)
+		assert.EqualValues(100, sl.prevSeen)
+		assert.Equal(now.UnixNano(), sl.prevTime)
+		assert.EqualValues(1, sl.seen)
+		assert.EqualValues(1, sl.allowed)
 	})
 
 	t.Run("averages-rates", func(t *testing.T) {
@@ -740,14 +763,14 @@ func TestSamplingLimiter(t *testing.T) {
 		sl.allowed = 41
 		sl.seen = 99
 		// this event occurs within the current period
-		now := sl.prevTime
+		now := time.Unix(0, sl.prevTime)
 
 		sampled, rate := sl.allowOne(now)
 		assert.True(sampled)
 		assert.Equal(0.42, rate)
-		assert.Equal(now, sl.prevTime)
-		assert.Equal(100.0, sl.seen)
-		assert.Equal(42.0, sl.allowed)
+		assert.Equal(now.UnixNano(), sl.prevTime)
+		assert.EqualValues(100, sl.seen)
+		assert.EqualValues(42, sl.allowed)
 
 	})
 
@@ -763,12 +786,116 @@ func TestSamplingLimiter(t *testing.T) {
 
 		sampled, _ := sl.allowOne(now)
 		assert.True(sampled)
-		assert.Equal(0.0, sl.prevSeen)
-		assert.Equal(0.0, sl.prevAllowed)
-		assert.Equal(now, sl.prevTime)
-		assert.Equal(1.0, sl.seen)
-		assert.Equal(1.0, sl.allowed)
+		assert.EqualValues(0, sl.prevSeen)
+		assert.EqualValues(0, sl.prevAllowed)
+		assert.Equal(now.UnixNano(), sl.prevTime)
+		assert.EqualValues(1, sl.seen)
+		assert.EqualValues(1, sl.allowed)
+	})
+
+	t.Run("token-bucket", func(t *testing.T) {
+		assert := assert.New(t)
+		sl := newTokenBucket(2.0, 2)
+		now := time.Now()
+		// the bucket starts full
+		assert.True(sl.take(now.UnixNano()))
+		assert.True(sl.take(now.UnixNano()))
+		assert.False(sl.take(now.UnixNano()))
+		// one token is added every 500ms
+		assert.False(sl.take(now.Add(499 * time.Millisecond).UnixNano()))
+		assert.True(sl.take(now.Add(500 * time.Millisecond).UnixNano()))
+		assert.False(sl.take(now.Add(500 * time.Millisecond).UnixNano()))
+		// the bucket holds at most 2 tokens
+		later := now.Add(time.Hour).UnixNano()
+		assert.True(sl.take(later))
+		assert.True(sl.take(later))
+		assert.False(sl.take(later))
+	})
+
+	t.Run("zero", func(t *testing.T) {
+		sl := newTokenBucket(0, 0)
+		sampled, rate := sl.allowOne(time.Now())
+		assert.False(t, sampled)
+		assert.Equal(t, 0.0, rate)
+	})
+
+	t.Run("unlimited", func(t *testing.T) {
+		sl := newSingleSpanRateLimiter(0)
+		for i := 0; i < 1000; i++ {
+			sampled, rate := sl.allowOne(time.Now())
+			assert.True(t, sampled)
+			assert.Equal(t, 1.0, rate)
+		}
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		sl := newTokenBucket(100.0, 100)
+		now := time.Now()
+		var (
+			wg      sync.WaitGroup
+			allowed uint64
+		)
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 1000; j++ {
+					if sampled, _ := sl.allowOne(now); sampled {
+						atomic.AddUint64(&allowed, 1)
+					}
+				}
+			}()
+		}
+		wg.Wait()
+		// only the burst is allowed at a given time
+		assert.EqualValues(t, 100, allowed)
+		assert.EqualValues(t, 100, sl.allowed)
+		assert.EqualValues(t, 8000, sl.seen)
+	})
+}
+
+// BenchmarkRateLimiter measures the throughput of the rate limiter when
+// called concurrently, as done by the samplers on every span start. Run it
+// with -cpu to check how it scales with the number of cores, e.g.
+// go test -run XXX -bench BenchmarkRateLimiter -cpu 1,8,64 ./ddtrace/tracer
+func BenchmarkRateLimiter(b *testing.B) {
+	for _, bc := range []struct {
+		name  string
+		limit float64
+	}{
+		{name: "limited", limit: defaultRateLimit},
+		{name: "1M", limit: 1e6},
+		{name: "unlimited", limit: math.Inf(1)},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			sl := newTokenBucket(bc.limit, int(math.Min(bc.limit, math.MaxInt32)))
+			b.ReportAllocs()
+			b.ResetTimer()
+			start := time.Now()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					sl.allowOne(nowTime())
+				}
+			})
+			b.ReportMetric(float64(b.N)/time.Since(start).Seconds(), "spans/s")
+		})
+	}
+}
+
+// BenchmarkRulesSamplerParallel measures the throughput of the trace rules
+// sampler applied concurrently to the spans matching a rule.
+func BenchmarkRulesSamplerParallel(b *testing.B) {
+	rs := newTraceRulesSampler([]SamplingRule{NameRule("web.request", 1.0)})
+	b.ReportAllocs()
+	b.ResetTimer()
+	start := time.Now()
+	b.RunParallel(func(pb *testing.PB) {
+		span := newBasicSpan("web.request")
+		for pb.Next() {
+			rs.apply(span)
+		}
 	})
+	b.ReportMetric(float64(b.N)/time.Since(start).Seconds(), "spans/s")
 }
 
 func BenchmarkRulesSampler(b *testing.B) {
@@ -933,18 +1060,22 @@ func TestSamplingRuleMarshall(t *testing.T) {
 		in  SamplingRule
 		out string
 	}{
-		{SamplingRule{nil, nil, 0, 0, 0, "srv", "ops", nil},
+		{SamplingRule{nil, nil, 0, 0, 0, "srv", "ops", "", "", nil},
 			`{"service":"srv","name":"ops","sample_rate":0,"type":"trace(0)"}`},
-		{SamplingRule{regexp.MustCompile("srv.[0-9]+]"), nil, 0, 0, 0, "srv", "ops", nil},
+		{SamplingRule{regexp.MustCompile("srv.[0-9]+]"), nil, 0, 0, 0, "srv", "ops", "", "", nil},
 			`{"service":"srv","name":"ops","sample_rate":0,"type":"trace(0)"}`},
-		{SamplingRule{regexp.MustCompile("srv.*"), regexp.MustCompile("ops.[0-9]+]"), 0, 0, 0, "", "", nil},
+		{SamplingRule{regexp.MustCompile("srv.*"), regexp.MustCompile("ops.[0-9]+]"), 0, 0, 0, "", "", "", "", nil},
 			`{"service":"srv.*","name":"ops.[0-9]+]","sample_rate":0,"type":"trace(0)"}`},
-		{SamplingRule{regexp.MustCompile("srv.[0-9]+]"), regexp.MustCompile("ops.[0-9]+]"), 0.55, 0, 0, "", "", nil},
+		{SamplingRule{regexp.MustCompile("srv.[0-9]+]"), regexp.MustCompile("ops.[0-9]+]"), 0.55, 0, 0, "", "", "", "", nil},
 			`{"service":"srv.[0-9]+]","name":"ops.[0-9]+]","sample_rate":0.55,"type":"trace(0)"}`},
-		{SamplingRule{regexp.MustCompile("srv.[0-9]+]"), regexp.MustCompile("ops.[0-9]+]"), 0.55, 0, 1, "", "", nil},
+		{SamplingRule{regexp.MustCompile("srv.[0-9]+]"), regexp.MustCompile("ops.[0-9]+]"), 0.55, 0, 1, "", "", "", "", nil},
 			`{"service":"srv.[0-9]+]","name":"ops.[0-9]+]","sample_rate":0.55,"type":"span(1)"}`},
-		{SamplingRule{regexp.MustCompile("srv.[0-9]+]"), regexp.MustCompile("ops.[0-9]+]"), 0.55, 1000, 1, "", "", nil},
+		{SamplingRule{regexp.MustCompile("srv.[0-9]+]"), regexp.MustCompile("ops.[0-9]+]"), 0.55, 1000, 1, "", "", "", "", nil},
 			`{"service":"srv.[0-9]+]","name":"ops.[0-9]+]","sample_rate":0.55,"type":"span(1)","max_per_second":1000}`},
+		{SpanNameServiceRule("db.*", "postgres.db", 1.0),
+			`{"service":"postgres.db","name":"db.*","sample_rate":1,"type":"span(1)"}`},
+		{SpanNameServiceMPSRule("db.?", "", 1.0, 10),
+			`{"service":"^.*$","name":"db.?","sample_rate":1,"type":"span(1)","max_per_second":10}`},
 	} {
 		m, err := tt.in.MarshalJSON()
 		assert.Nil(t, err)
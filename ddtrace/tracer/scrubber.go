@@ -0,0 +1,99 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package tracer
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+)
+
+// redacted replaces the values, or the parts of them, scrubbed by the rules of
+// DD_TRACE_SCRUB_TAGS.
+const redacted = "<redacted>"
+
+// scrubRule is a tag scrubbing rule set with DD_TRACE_SCRUB_TAGS.
+type scrubRule struct {
+	// key is the key of the tags the rule applies to, or "" when it applies
+	// to all of them. The resource name has the ext.ResourceName key.
+	key string
+	// pattern matches the parts of the values which are redacted, or is nil
+	// when the values are redacted entirely.
+	pattern *regexp.Regexp
+}
+
+// scrub implements the tag scrubber function of the rule, see WithTagScrubber.
+func (r scrubRule) scrub(key, value string) string {
+	if r.key != "" && r.key != key {
+		return value
+	}
+	if r.pattern == nil {
+		return redacted
+	}
+	return r.pattern.ReplaceAllLiteralString(value, redacted)
+}
+
+// unmarshalScrubRules parses the tag scrubbing rules set with DD_TRACE_SCRUB_TAGS:
+// a JSON array of objects with a "key" and/or a "pattern" field, e.g.
+//
+//	[{"key": "user.email"}, {"pattern": "\\b\\d{13,16}\\b"}, {"key": "http.url", "pattern": "token=[^&]*"}]
+//
+// The values of the tags with the given key are redacted entirely, unless a
+// pattern is given, in which case the parts of the values matching it are
+// redacted, in the tags with the given key or in all of them. The rules which
+// are invalid are skipped, and reported by the returned error.
+func unmarshalScrubRules(b []byte) ([]scrubRule, error) {
+	var jsonRules []struct {
+		Key     string `json:"key"`
+		Pattern string `json:"pattern"`
+	}
+	if err := json.Unmarshal(b, &jsonRules); err != nil {
+		return nil, fmt.Errorf("error unmarshalling JSON: %v", err)
+	}
+	rules := make([]scrubRule, 0, len(jsonRules))
+	var errs []string
+	for i, v := range jsonRules {
+		if v.Key == "" && v.Pattern == "" {
+			errs = append(errs, fmt.Sprintf("at index %d: rule has neither a key nor a pattern", i))
+			continue
+		}
+		rule := scrubRule{key: v.Key}
+		if v.Pattern != "" {
+			re, err := regexp.Compile(v.Pattern)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("at index %d: %v", i, err))
+				continue
+			}
+			rule.pattern = re
+		}
+		rules = append(rules, rule)
+	}
+	if len(errs) != 0 {
+		return rules, fmt.Errorf("found errors:\n\t%s", strings.Join(errs, "\n\t"))
+	}
+	return rules, nil
+}
+
+// scrub replaces the resource name and the string tags of s with the values
+// returned by the given tag scrubbers, run in order. The tags internal to the
+// tracer, prefixed with "_dd.", are left untouched. It must be called with s
+// locked.
+func (s *span) scrub(scrubbers []func(key, value string) string) {
+	for _, fn := range scrubbers {
+		s.Resource = fn(ext.ResourceName, s.Resource)
+		for k, v := range s.Meta {
+			if strings.HasPrefix(k, "_dd.") {
+				continue
+			}
+			if sv := fn(k, v); sv != v {
+				s.Meta[k] = sv
+			}
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package tracer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+)
+
+func TestUnmarshalScrubRules(t *testing.T) {
+	rules, err := unmarshalScrubRules([]byte(`[
+		{"key": "user.email"},
+		{"pattern": "\\d{4}-\\d{4}"},
+		{"key": "http.url", "pattern": "token=[^&]*"},
+		{},
+		{"pattern": "("}
+	]`))
+	assert.ErrorContains(t, err, "at index 3")
+	assert.ErrorContains(t, err, "at index 4")
+	require.Len(t, rules, 3)
+
+	for _, tt := range []struct {
+		key, value, want string
+	}{
+		{"user.email", "jane@example.com", redacted},
+		{"user.id", "42", "42"},
+		{ext.ResourceName, "GET /cards/4111-1111", "GET /cards/" + redacted},
+		{"http.url", "/users?token=abc&page=2", "/users?" + redacted + "&page=2"},
+	} {
+		v := tt.value
+		for _, r := range rules {
+			v = r.scrub(tt.key, v)
+		}
+		assert.Equal(t, tt.want, v, tt.key)
+	}
+
+	_, err = unmarshalScrubRules([]byte(`{"key": "user.email"}`))
+	assert.ErrorContains(t, err, "error unmarshalling JSON")
+}
+
+func TestTagScrubber(t *testing.T) {
+	t.Setenv("DD_TRACE_SCRUB_TAGS", `[{"key": "user.email"}, {"pattern": "\\d{4}-\\d{4}"}]`)
+	var keys []string
+	tracer, transport, flush, stop := startTestTracer(t,
+		WithTagScrubber(func(key, value string) string {
+			keys = append(keys, key)
+			return strings.ReplaceAll(value, "secret", "?")
+		}),
+	)
+	defer stop()
+
+	s := tracer.StartSpan("web.request",
+		ResourceName("GET /cards/4111-1111"),
+		Tag("user.email", "jane@example.com"),
+		Tag("note", "a secret"),
+		Tag("count", 4111),
+	)
+	s.SetTag("_dd.custom", "4111-1111")
+	s.Finish()
+	flush(1)
+
+	spans := transport.Traces()[0]
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "GET /cards/"+redacted, span.Resource)
+	assert.Equal(t, redacted, span.Meta["user.email"])
+	assert.Equal(t, "a ?", span.Meta["note"])
+	assert.Equal(t, "4111-1111", span.Meta["_dd.custom"])
+	assert.Equal(t, 4111.0, span.Metrics["count"])
+	assert.Contains(t, keys, ext.ResourceName)
+	assert.NotContains(t, keys, "_dd.custom")
+}
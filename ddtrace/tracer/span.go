@@ -10,6 +10,7 @@ package tracer
 import (
 	"context"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"math"
 	"os"
@@ -45,16 +46,76 @@ type (
 )
 
 var (
-	_ ddtrace.Span   = (*span)(nil)
-	_ msgp.Encodable = (*spanList)(nil)
-	_ msgp.Decodable = (*spanLists)(nil)
+	_ ddtrace.Span            = (*span)(nil)
+	_ ddtrace.SpanWithMetrics = (*span)(nil)
+	_ ddtrace.SpanWithLinks   = (*span)(nil)
+	_ msgp.Encodable          = (*spanList)(nil)
+	_ msgp.Decodable          = (*spanLists)(nil)
 )
 
+const (
+	// initialMetaSize and initialMetricsSize are the initial capacities of the tag
+	// maps of new spans. They fit the tags set by the tracer itself on most spans.
+	initialMetaSize    = 8
+	initialMetricsSize = 4
+
+	// maxPooledMapSize is the number of tags above which the tag maps of a released
+	// span are dropped rather than reused, so that pooled spans don't hold on to
+	// the memory of the largest spans ever created.
+	maxPooledMapSize = 64
+)
+
+// spanPool holds the spans released by the tracer once they were encoded, when
+// span pooling is enabled.
+var spanPool = sync.Pool{
+	New: func() interface{} {
+		return &span{
+			Meta:    make(map[string]string, initialMetaSize),
+			Metrics: make(map[string]float64, initialMetricsSize),
+		}
+	},
+}
+
+// releaseSpan resets s and puts it back into spanPool. s must not be used afterwards.
+func releaseSpan(s *span) {
+	// the trace of s may be pushed before Finish returns, so wait for it to
+	// release the lock
+	s.Lock()
+	defer s.Unlock()
+	meta, metrics := s.Meta, s.Metrics
+	if len(meta) > maxPooledMapSize {
+		meta = make(map[string]string, initialMetaSize)
+	} else {
+		for k := range meta {
+			delete(meta, k)
+		}
+	}
+	if len(metrics) > maxPooledMapSize {
+		metrics = make(map[string]float64, initialMetricsSize)
+	} else {
+		for k := range metrics {
+			delete(metrics, k)
+		}
+	}
+	s.Name, s.Service, s.Resource, s.Type = "", "", "", ""
+	s.Start, s.Duration = 0, 0
+	s.Meta, s.Metrics = meta, metrics
+	s.SpanID, s.TraceID, s.ParentID = 0, 0, 0
+	s.Error = 0
+	s.noDebugStack, s.finished = false, false
+	s.context = nil
+	s.pprofCtxActive, s.pprofCtxRestore = nil, nil
+	s.taskEnd = nil
+	s.links = nil
+	spanPool.Put(s)
+}
+
 // errorConfig holds customization options for setting error tags.
 type errorConfig struct {
 	noDebugStack bool
 	stackFrames  uint
 	stackSkip    uint
+	tracking     bool // see WithErrorTracking
 }
 
 // span represents a computation. Callers must call Finish when a span is
@@ -83,6 +144,8 @@ type span struct {
 	pprofCtxRestore context.Context `msg:"-"` // contains pprof.WithLabel labels of the parent span (if any) that need to be restored when this span finishes
 
 	taskEnd func() // ends execution tracer (runtime/trace) task, if started
+
+	links []ddtrace.SpanLink `msg:"-"` // links to causally related spans, serialized into Meta on finish
 }
 
 // Context yields the SpanContext for this Span. Note that the return
@@ -117,14 +180,13 @@ func (s *span) SetTag(key string, value interface{}) {
 	case ext.Error:
 		s.setTagError(value, errorConfig{
 			noDebugStack: s.noDebugStack,
+			tracking:     errorTrackingEnabled(),
 		})
 		return
 	}
-	if v, ok := value.(bool); ok {
-		s.setTagBool(key, v)
-		return
-	}
-	if v, ok := value.(string); ok {
+	// fast paths for the most common types, which don't need any conversion
+	switch v := value.(type) {
+	case string:
 		if key == ext.ResourceName && s.pprofCtxActive != nil && spanResourcePIISafe(s) {
 			// If the user overrides the resource name for the span,
 			// update the endpoint label for the runtime profilers.
@@ -133,10 +195,20 @@ func (s *span) SetTag(key string, value interface{}) {
 			// stay as the original parent span context regardless
 			// of what we change at a lower level.
 			s.pprofCtxActive = pprof.WithLabels(s.pprofCtxActive, pprof.Labels(traceprof.TraceEndpoint, v))
+			s.context.setPprofContext(s.pprofCtxActive)
 			pprof.SetGoroutineLabels(s.pprofCtxActive)
 		}
 		s.setMeta(key, v)
 		return
+	case bool:
+		s.setTagBool(key, v)
+		return
+	case float64:
+		s.setMetric(key, v)
+		return
+	case int:
+		s.setMetric(key, float64(v))
+		return
 	}
 	if v, ok := toFloat64(value); ok {
 		s.setMetric(key, v)
@@ -170,6 +242,15 @@ func (s *span) setSamplingPriority(priority int, sampler samplernames.SamplerNam
 	s.setSamplingPriorityLocked(priority, sampler)
 }
 
+// Root returns the local root span of the trace the span belongs to, which is the
+// service entry span of the trace. It returns nil when the span isn't part of a trace.
+func (s *span) Root() Span {
+	if s == nil || s.context == nil || s.context.trace == nil || s.context.trace.root == nil {
+		return nil
+	}
+	return s.context.trace.root
+}
+
 // SetUser associates user information to the current trace which the
 // provided span belongs to. The options can be used to tune which user
 // bit of information gets monitored. In case of distributed traces,
@@ -209,6 +290,40 @@ func (s *span) SetUser(id string, opts ...UserMonitoringOption) {
 	}
 }
 
+// AddLink links the span to the span identified by ctx, to represent a causal
+// relationship which isn't a parent-child one. The attributes are optional and
+// describe the link. Links added after the span is finished are ignored.
+func (s *span) AddLink(ctx ddtrace.SpanContext, attributes map[string]string) {
+	if ctx == nil {
+		return
+	}
+	s.Lock()
+	defer s.Unlock()
+	if s.finished {
+		return
+	}
+	s.links = append(s.links, newSpanLink(ctx, attributes))
+}
+
+// newSpanLink returns a link to the span identified by ctx.
+func newSpanLink(ctx ddtrace.SpanContext, attributes map[string]string) ddtrace.SpanLink {
+	link := ddtrace.SpanLink{
+		TraceID:    ctx.TraceID(),
+		SpanID:     ctx.SpanID(),
+		Attributes: attributes,
+	}
+	if sc, ok := ctx.(*spanContext); ok && sc.trace != nil {
+		link.Tracestate = sc.trace.tracestate
+		if p, ok := sc.samplingPriority(); ok {
+			link.Flags = 1 << 31
+			if p > 0 {
+				link.Flags |= 1
+			}
+		}
+	}
+	return link
+}
+
 // setSamplingPriorityLocked updates the sampling priority.
 // It also updates the trace's sampling priority.
 func (s *span) setSamplingPriorityLocked(priority int, sampler samplernames.SamplerName) {
@@ -253,8 +368,16 @@ func (s *span) setTagError(value interface{}, cfg errorConfig) {
 		setError(true)
 		s.setMeta(ext.ErrorMsg, v.Error())
 		s.setMeta(ext.ErrorType, reflect.TypeOf(v).String())
+		var stack string
 		if !cfg.noDebugStack {
-			s.setMeta(ext.ErrorStack, takeStacktrace(cfg.stackFrames, cfg.stackSkip))
+			stack = takeStacktrace(cfg.stackFrames, cfg.stackSkip)
+			if cfg.tracking {
+				stack = sanitizeStack(stack)
+			}
+			s.setMeta(ext.ErrorStack, stack)
+		}
+		if cfg.tracking {
+			s.setErrorTrackingTags(v, stack)
 		}
 		switch v.(type) {
 		case xerrors.Formatter:
@@ -320,6 +443,9 @@ func (s *span) setMeta(key, v string) {
 		s.Name = v
 	case ext.ServiceName:
 		s.Service = v
+		if s.context != nil {
+			s.context.setServiceName(v)
+		}
 	case ext.ResourceName:
 		s.Resource = v
 	case ext.SpanType:
@@ -355,6 +481,18 @@ func (s *span) setTagBool(key string, v bool) {
 	}
 }
 
+// SetMetric sets a numeric tag, in our case called a metric, on the span. Unlike
+// SetTag, it never converts the value, which is always stored as a metric.
+func (s *span) SetMetric(key string, value float64) {
+	s.Lock()
+	defer s.Unlock()
+	// see SetTag for why finished spans are left untouched
+	if s.finished {
+		return
+	}
+	s.setMetric(key, value)
+}
+
 // setMetric sets a numeric tag, in our case called a metric. This method
 // is not safe for concurrent use.
 func (s *span) setMetric(key string, v float64) {
@@ -396,6 +534,7 @@ func (s *span) Finish(opts ...ddtrace.FinishOption) {
 				noDebugStack: cfg.NoDebugStack,
 				stackFrames:  cfg.StackFrames,
 				stackSkip:    cfg.SkipStackFrames,
+				tracking:     errorTrackingEnabled(),
 			})
 			s.Unlock()
 		}
@@ -403,12 +542,18 @@ func (s *span) Finish(opts ...ddtrace.FinishOption) {
 	if s.taskEnd != nil {
 		s.taskEnd()
 	}
+	if t, ok := internal.GetGlobalTracer().(*tracer); ok && t.longRunning != nil && t.longRunning.remove(s) {
+		// snapshots of s were reported while it was running
+		s.SetTag(keyWasLongRunning, 1)
+	}
+	// s may be released to spanPool as soon as it is finished
+	pprofCtxRestore := s.pprofCtxRestore
 	s.finish(t)
 
-	if s.pprofCtxRestore != nil {
+	if pprofCtxRestore != nil {
 		// Restore the labels of the parent span so any CPU samples after this
 		// point are attributed correctly.
-		pprof.SetGoroutineLabels(s.pprofCtxRestore)
+		pprof.SetGoroutineLabels(pprofCtxRestore)
 	}
 }
 
@@ -443,10 +588,23 @@ func (s *span) finish(finishTime int64) {
 		s.Duration = 0
 	}
 	s.finished = true
+	if len(s.links) > 0 {
+		if links, err := json.Marshal(s.links); err == nil {
+			s.setMeta(keySpanLinks, string(links))
+		} else {
+			log.Error("Failed to serialize span links: %v", err)
+		}
+	}
 
 	keep := true
 	if t, ok := internal.GetGlobalTracer().(*tracer); ok {
 		// we have an active tracer
+		if len(t.config.tagScrubbers) > 0 {
+			s.scrub(t.config.tagScrubbers)
+		}
+		if t.config.canSetPeerService() {
+			s.setPeerService()
+		}
 		if t.config.canComputeStats() && shouldComputeStats(s) {
 			// the agent supports computed stats
 			select {
@@ -585,11 +743,12 @@ func (s *span) Format(f fmt.State, c rune) {
 			fmt.Fprintf(f, "dd.service=%s ", svc)
 		}
 		if tr, ok := internal.GetGlobalTracer().(*tracer); ok {
-			if tr.config.env != "" {
-				fmt.Fprintf(f, "dd.env=%s ", tr.config.env)
+			gc := tr.loadGlobalConfig()
+			if gc.env != "" {
+				fmt.Fprintf(f, "dd.env=%s ", gc.env)
 			}
-			if tr.config.version != "" {
-				fmt.Fprintf(f, "dd.version=%s ", tr.config.version)
+			if gc.version != "" {
+				fmt.Fprintf(f, "dd.version=%s ", gc.version)
 			}
 		} else {
 			if env := os.Getenv("DD_ENV"); env != "" {
@@ -629,6 +788,14 @@ const (
 	keySingleSpanSamplingMPS = "_dd.span_sampling.max_per_second"
 	// keyPropagatedUserID holds the propagated user identifier, if user id propagation is enabled.
 	keyPropagatedUserID = "_dd.p.usr.id"
+	// keySpanLinks holds the JSON encoded links of the span to other spans, if any.
+	keySpanLinks = "_dd.span_links"
+	// keyTraceID128 holds the upper 64 bits of a 128-bit trace ID, either generated
+	// (see WithTraceID128Bit) or received through propagation, as 16 lowercase
+	// hexadecimal characters.
+	keyTraceID128 = "_dd.p.tid"
+	// baggageTagPrefix prefixes the tags holding the baggage items selected with WithBaggageTagKeys.
+	baggageTagPrefix = "baggage."
 )
 
 // The following set of tags is used for user monitoring and set through calls to span.SetUser().
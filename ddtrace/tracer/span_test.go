@@ -6,6 +6,7 @@
 package tracer
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
@@ -15,6 +16,7 @@ import (
 	"testing"
 	"time"
 
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/samplernames"
 
@@ -60,6 +62,16 @@ func TestSpanContext(t *testing.T) {
 	assert.NotNil(span.Context())
 }
 
+func TestSpanRoot(t *testing.T) {
+	assert := assert.New(t)
+	tracer := newTracer(withTransport(newDefaultTransport()))
+	defer tracer.Stop()
+	root := tracer.StartSpan("web.request").(*span)
+	child := tracer.StartSpan("db.query", ChildOf(root.Context())).(*span)
+	assert.Equal(root, root.Root())
+	assert.Equal(root, child.Root())
+}
+
 func TestSpanOperationName(t *testing.T) {
 	assert := assert.New(t)
 
@@ -412,6 +424,49 @@ func TestSpanString(t *testing.T) {
 	assert.NotEqual("", span.String())
 }
 
+func TestSpanLinks(t *testing.T) {
+	tracer := newTracer(withTransport(newDefaultTransport()))
+	defer tracer.Stop()
+	upstream := tracer.newRootSpan("kafka.produce", "producer", "topic")
+	upstream.context.setSamplingPriority(ext.PriorityAutoReject, samplernames.AgentRate)
+	other := tracer.newRootSpan("kafka.produce", "producer", "topic")
+
+	t.Run("start-option", func(t *testing.T) {
+		assert := assert.New(t)
+		link := ddtrace.SpanLink{TraceID: 1, SpanID: 2, Attributes: map[string]string{"a": "b"}}
+		span := tracer.StartSpan("batch.consume", WithSpanLinks([]ddtrace.SpanLink{link})).(*span)
+		span.Finish()
+		assert.Equal(`[{"trace_id":1,"span_id":2,"attributes":{"a":"b"}}]`, span.Meta[keySpanLinks])
+	})
+
+	t.Run("add-link", func(t *testing.T) {
+		assert := assert.New(t)
+		s := tracer.StartSpan("batch.consume").(ddtrace.SpanWithLinks)
+		s.AddLink(upstream.Context(), map[string]string{"messaging.operation": "receive"})
+		s.AddLink(other.Context(), nil)
+		s.Finish()
+		s.AddLink(other.Context(), nil)
+
+		var links []ddtrace.SpanLink
+		assert.NoError(json.Unmarshal([]byte(s.(*span).Meta[keySpanLinks]), &links))
+		assert.Equal([]ddtrace.SpanLink{
+			{
+				TraceID:    upstream.TraceID,
+				SpanID:     upstream.SpanID,
+				Attributes: map[string]string{"messaging.operation": "receive"},
+				Flags:      1 << 31,
+			},
+			{TraceID: other.TraceID, SpanID: other.SpanID, Flags: 1<<31 | 1},
+		}, links)
+	})
+
+	t.Run("none", func(t *testing.T) {
+		span := tracer.StartSpan("batch.consume").(*span)
+		span.Finish()
+		assert.NotContains(t, span.Meta, keySpanLinks)
+	})
+}
+
 const (
 	intUpperLimit = int64(1) << 53
 	intLowerLimit = -intUpperLimit
@@ -459,6 +514,23 @@ func TestSpanSetMetric(t *testing.T) {
 			_, ok := span.Metrics["finished.test"]
 			assert.False(ok)
 		},
+		"metric": func(assert *assert.Assertions, span *span) {
+			span.SetTag("size", "big")
+			span.SetMetric("size", 1024)
+			assert.Equal(1024.0, span.Metrics["size"])
+			assert.NotContains(span.Meta, "size")
+		},
+		"metric-priority": func(assert *assert.Assertions, span *span) {
+			span.SetMetric(ext.SamplingPriority, 2)
+			assert.Equal(2.0, span.Metrics[keySamplingPriority])
+		},
+		"metric-finished": func(assert *assert.Assertions, span *span) {
+			span.Finish()
+			span.SetMetric("finished.test", 1337)
+			assert.Equal(3, len(span.Metrics))
+			_, ok := span.Metrics["finished.test"]
+			assert.False(ok)
+		},
 	} {
 		t.Run(name, func(t *testing.T) {
 			assert := assert.New(t)
@@ -698,6 +770,31 @@ func TestSpanLog(t *testing.T) {
 	})
 }
 
+func TestReleaseSpan(t *testing.T) {
+	assert := assert.New(t)
+	s := newBasicSpan("op")
+	s.SetTag("key", "value")
+	s.SetTag("num", 1)
+	meta := s.Meta
+	releaseSpan(s)
+	assert.Empty(s.Name)
+	assert.Zero(s.SpanID)
+	assert.Nil(s.context)
+	assert.Empty(s.Meta)
+	assert.Empty(s.Metrics)
+	meta["reused"] = "true"
+	assert.Equal("true", s.Meta["reused"])
+
+	s = newBasicSpan("big")
+	for i := 0; i <= maxPooledMapSize; i++ {
+		s.SetTag(fmt.Sprint(i), "value")
+	}
+	meta = s.Meta
+	releaseSpan(s)
+	assert.Empty(s.Meta)
+	assert.Len(meta, maxPooledMapSize+1)
+}
+
 func BenchmarkSetTagMetric(b *testing.B) {
 	span := newBasicSpan("bench.span")
 	keys := "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
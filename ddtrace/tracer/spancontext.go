@@ -6,11 +6,14 @@
 package tracer
 
 import (
+	gocontext "context"
+	"fmt"
 	"strconv"
 	"sync"
 	"sync/atomic"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/internal"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/samplernames"
@@ -38,6 +41,12 @@ type spanContext struct {
 	baggage    map[string]string
 	hasBaggage uint32 // atomic int for quick checking presence of baggage. 0 indicates no baggage, otherwise baggage exists.
 	origin     string // e.g. "synthetics"
+
+	// service and pprofCtx hold the service name and the pprof labels context of
+	// span, inherited by its local children, so that they can be started without
+	// reading span, which may already be released to spanPool.
+	service  string
+	pprofCtx gocontext.Context
 }
 
 // newSpanContext creates a new SpanContext to serve as context for the given
@@ -50,6 +59,7 @@ func newSpanContext(span *span, parent *spanContext) *spanContext {
 		traceID: span.TraceID,
 		spanID:  span.SpanID,
 		span:    span,
+		service: span.Service,
 	}
 	if parent != nil {
 		context.trace = parent.trace
@@ -75,6 +85,36 @@ func newSpanContext(span *span, parent *spanContext) *spanContext {
 // SpanID implements ddtrace.SpanContext.
 func (c *spanContext) SpanID() uint64 { return c.spanID }
 
+// serviceName returns the service name of the span of c, inherited by its local children.
+func (c *spanContext) serviceName() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.service
+}
+
+// setServiceName sets the service name of the span of c, inherited by its local children.
+func (c *spanContext) setServiceName(service string) {
+	c.mu.Lock()
+	c.service = service
+	c.mu.Unlock()
+}
+
+// pprofContext returns the pprof labels context of the span of c, inherited by its
+// local children.
+func (c *spanContext) pprofContext() gocontext.Context {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.pprofCtx
+}
+
+// setPprofContext sets the pprof labels context of the span of c, inherited by its
+// local children.
+func (c *spanContext) setPprofContext(ctx gocontext.Context) {
+	c.mu.Lock()
+	c.pprofCtx = ctx
+	c.mu.Unlock()
+}
+
 // TraceID implements ddtrace.SpanContext.
 func (c *spanContext) TraceID() uint64 { return c.traceID }
 
@@ -99,6 +139,33 @@ func (c *spanContext) setSamplingPriority(p int, sampler samplernames.SamplerNam
 	c.trace.setSamplingPriority(p, sampler)
 }
 
+// SamplingPriority returns the sampling priority of the trace and whether it was
+// set. It allows other packages of this module, such as ddtrace/opentelemetry,
+// to read it through an interface.
+func (c *spanContext) SamplingPriority() (p int, ok bool) {
+	return c.samplingPriority()
+}
+
+// TraceID128 returns the whole trace ID, including the upper 64 bits of a 128-bit
+// one, as 32 lowercase hexadecimal characters. It allows other packages of this
+// module, such as contrib/internal/httptrace, to read it through an interface.
+func (c *spanContext) TraceID128() string {
+	return c.traceIDUpper() + fmt.Sprintf("%016x", c.traceID)
+}
+
+// traceIDUpper returns the upper 64 bits of the trace ID as 16 lowercase hexadecimal
+// characters, which are zero when the trace ID is a 64-bit one.
+func (c *spanContext) traceIDUpper() string {
+	if c.trace != nil {
+		c.trace.mu.RLock()
+		defer c.trace.mu.RUnlock()
+		if v, ok := c.trace.propagatingTags[keyTraceID128]; ok && len(v) == 16 && isLowerHex(v) {
+			return v
+		}
+	}
+	return "0000000000000000"
+}
+
 func (c *spanContext) samplingPriority() (p int, ok bool) {
 	if c.trace == nil {
 		return 0, false
@@ -161,6 +228,8 @@ type trace struct {
 	priority         *float64          // sampling priority
 	locked           bool              // specifies if the sampling priority can be altered
 	samplingDecision samplingDecision  // samplingDecision indicates whether to send the trace to the agent.
+	tracestate       string            // list members of the W3C tracestate header owned by other vendors
+	done             []*span           // finished spans awaiting a partial flush, when partial flushing is enabled
 
 	// root specifies the root of the trace, if known; it is nil when a span
 	// context is extracted from a carrier, at which point there are no spans in
@@ -201,6 +270,21 @@ func (t *trace) samplingPriority() (p int, ok bool) {
 	return t.samplingPriorityLocked()
 }
 
+// finalSamplingPriority returns the sampling priority of the trace, if any, and
+// whether it is final: either it was decided by the user, see ext.ManualKeep and
+// ext.ManualDrop, or it can no longer change as the root span finished. Other
+// priorities, such as the ones applying the rates of the agent, may still be
+// overridden by the user.
+func (t *trace) finalSamplingPriority() (p int, final bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	p, ok := t.samplingPriorityLocked()
+	if !ok {
+		return 0, false
+	}
+	return p, t.locked || p >= ext.PriorityUserKeep || p <= ext.PriorityUserReject
+}
+
 func (t *trace) setSamplingPriority(p int, sampler samplernames.SamplerName) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
@@ -326,17 +410,29 @@ func (t *trace) finishedOne(s *span) {
 			s.setMeta(k, v)
 		}
 	}
+	tr, ok := internal.GetGlobalTracer().(*tracer)
 	if len(t.spans) != t.finished {
+		if ok && tr.config.partialFlushEnabled {
+			t.done = append(t.done, s)
+			if len(t.done) >= tr.config.partialFlushMinSpans {
+				t.partialFlush(tr)
+			}
+		}
 		return
 	}
 	defer func() {
 		t.spans = nil
+		t.done = nil
 		t.finished = 0 // important, because a buffer can be used for several flushes
 	}()
-	tr, ok := internal.GetGlobalTracer().(*tracer)
 	if !ok {
 		return
 	}
+	if t.priority != nil && t.spans[0] != t.root {
+		// the root span was part of an earlier partial flush, so this last chunk
+		// needs to carry the sampling priority too.
+		t.spans[0].setMetric(keySamplingPriority, *t.priority)
+	}
 	// we have a tracer that can receive completed traces.
 	atomic.AddUint32(&tr.spansFinished, uint32(len(t.spans)))
 	tr.pushTrace(&finishedTrace{
@@ -344,3 +440,43 @@ func (t *trace) finishedOne(s *span) {
 		willSend: decisionKeep == samplingDecision(atomic.LoadUint32((*uint32)(&t.samplingDecision))),
 	})
 }
+
+// partialFlush sends the finished spans of the trace to tr as a chunk, keeping
+// only the unfinished ones in the buffer. It must be called with t.mu held.
+func (t *trace) partialFlush(tr *tracer) {
+	finished := t.done
+	done := make(map[*span]struct{}, len(finished))
+	for _, s := range finished {
+		done[s] = struct{}{}
+	}
+	unfinished := make([]*span, 0, len(t.spans)-len(finished))
+	for _, s := range t.spans {
+		if _, ok := done[s]; !ok {
+			unfinished = append(unfinished, s)
+		}
+	}
+	// The first span of a chunk carries the trace level tags and the sampling
+	// priority. The spans are finished, so they can't be modified concurrently.
+	first := finished[0]
+	if first != t.spans[0] {
+		for k, v := range t.tags {
+			first.setMeta(k, v)
+		}
+		for k, v := range t.propagatingTags {
+			first.setMeta(k, v)
+		}
+	}
+	if t.priority != nil {
+		first.setMetric(keySamplingPriority, *t.priority)
+	}
+	log.Debug("Partially flushing %d spans of trace %d, %d spans remaining.", len(finished), first.TraceID, len(unfinished))
+	t.spans = unfinished
+	t.done = nil
+	t.finished = 0
+	atomic.AddUint32(&tr.spansFinished, uint32(len(finished)))
+	tr.pushTrace(&finishedTrace{
+		spans:    finished,
+		willSend: decisionKeep == samplingDecision(atomic.LoadUint32((*uint32)(&t.samplingDecision))),
+		partial:  true,
+	})
+}
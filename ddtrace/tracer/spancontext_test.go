@@ -7,6 +7,7 @@ package tracer
 
 import (
 	"context"
+	"fmt"
 	"strings"
 	"sync"
 	"testing"
@@ -206,6 +207,61 @@ func TestSpanTracePushSeveral(t *testing.T) {
 	}
 }
 
+func TestSpanTracePartialFlush(t *testing.T) {
+	assert := assert.New(t)
+	tracer, transport, flush, stop := startTestTracer(t, WithPartialFlushing(2))
+	defer stop()
+
+	root := tracer.StartSpan("root", Tag(ext.SamplingPriority, ext.PriorityUserKeep)).(*span)
+	root.context.trace.setTag("trace.tag", "value")
+	children := make([]*span, 5)
+	for i := range children {
+		children[i] = tracer.StartSpan(fmt.Sprintf("child%d", i), ChildOf(root.Context())).(*span)
+	}
+	for _, child := range children[:4] {
+		child.Finish()
+	}
+	flush(2)
+	traces := transport.Traces()
+	assert.Len(traces, 2)
+	for i, chunk := range traces {
+		assert.Len(chunk, 2)
+		assert.Equal(children[i*2].Name, chunk[0].Name)
+		assert.Equal(float64(ext.PriorityUserKeep), chunk[0].Metrics[keySamplingPriority])
+		assert.Equal("value", chunk[0].Meta["trace.tag"])
+	}
+
+	children[4].Finish()
+	root.Finish()
+	flush(1)
+	traces = transport.Traces()
+	assert.Len(traces, 1)
+	assert.Len(traces[0], 2)
+	assert.Equal("root", traces[0][0].Name)
+	assert.Equal(float64(ext.PriorityUserKeep), traces[0][0].Metrics[keySamplingPriority])
+}
+
+func TestSpanTracePartialFlushRootFirst(t *testing.T) {
+	assert := assert.New(t)
+	tracer, transport, flush, stop := startTestTracer(t, WithPartialFlushing(1))
+	defer stop()
+
+	root := tracer.StartSpan("root", Tag(ext.SamplingPriority, ext.PriorityUserKeep)).(*span)
+	child := tracer.StartSpan("child", ChildOf(root.Context())).(*span)
+	root.Finish()
+	flush(1)
+	traces := transport.Traces()
+	assert.Len(traces, 1)
+	assert.Equal("root", traces[0][0].Name)
+
+	child.Finish()
+	flush(1)
+	traces = transport.Traces()
+	assert.Len(traces, 1)
+	assert.Equal("child", traces[0][0].Name)
+	assert.Equal(float64(ext.PriorityUserKeep), traces[0][0].Metrics[keySamplingPriority])
+}
+
 // TestSpanFinishPriority asserts that the root span will have the sampling
 // priority metric set by inheriting it from a child.
 func TestSpanFinishPriority(t *testing.T) {
@@ -382,6 +438,15 @@ func TestSpanContextBaggage(t *testing.T) {
 	assert.Equal("value", ctx.baggage["key"])
 }
 
+func TestSpanContextTraceID128(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx := spanContext{traceID: 42, trace: newTrace()}
+	assert.Equal("0000000000000000000000000000002a", ctx.TraceID128())
+	ctx.trace.setPropagatingTag(keyTraceID128, "640cfd8d00000000")
+	assert.Equal("640cfd8d00000000000000000000002a", ctx.TraceID128())
+}
+
 func TestSpanContextIterator(t *testing.T) {
 	assert := assert.New(t)
 
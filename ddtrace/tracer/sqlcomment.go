@@ -11,6 +11,7 @@ import (
 
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/internal"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
 )
@@ -29,6 +30,37 @@ const (
 	SQLInjectionModeFull SQLCommentInjectionMode = "full"
 )
 
+// DBMPropagationMode represents the mode of the propagation of the trace context to the databases
+// monitored by Database Monitoring (DBM), through SQL comments. It is the same as
+// SQLCommentInjectionMode.
+type DBMPropagationMode = SQLCommentInjectionMode
+
+const (
+	// DBMPropagationModeUndefined represents the DBM propagation mode is not set. This is the same as DBMPropagationModeDisabled.
+	DBMPropagationModeUndefined = SQLInjectionUndefined
+	// DBMPropagationModeDisabled represents the DBM propagation mode where the propagation is disabled.
+	DBMPropagationModeDisabled = SQLInjectionDisabled
+	// DBMPropagationModeService represents the DBM propagation mode where only the service tags are propagated.
+	DBMPropagationModeService = SQLInjectionModeService
+	// DBMPropagationModeFull represents the DBM propagation mode where both the service tags and the trace context are propagated.
+	DBMPropagationModeFull = SQLInjectionModeFull
+)
+
+// SQLCommentSamplingMode represents how the sampling decision of the traces is injected into
+// the SQL comments of SQLInjectionModeFull.
+type SQLCommentSamplingMode int
+
+const (
+	// SQLCommentSamplingImmediate sets the sampled flag of the traceparent from the sampling
+	// priority of the trace when the comment is injected, even though the priority may still
+	// change. This is the default.
+	SQLCommentSamplingImmediate SQLCommentSamplingMode = iota
+	// SQLCommentSamplingDeferred only sets the sampled flag of the traceparent once the sampling
+	// decision of the trace is final, so that the queries of the traces which may still be
+	// dropped aren't marked as sampled: their sampling is then resolved from the trace itself.
+	SQLCommentSamplingDeferred
+)
+
 // Key names for SQL comment tags.
 const (
 	sqlCommentTraceParent   = "traceparent"
@@ -36,6 +68,11 @@ const (
 	sqlCommentDBService     = "dddbs"
 	sqlCommentParentVersion = "ddpv"
 	sqlCommentEnv           = "dde"
+	sqlCommentDBHostname    = "ddh"
+	sqlCommentDBName        = "dddb"
+	// sqlCommentSamplingPriority holds the sampling priority of the trace, which is only
+	// injected once it is final.
+	sqlCommentSamplingPriority = "ddsp"
 )
 
 // Current trace context version (see https://www.w3.org/TR/trace-context/#version)
@@ -49,6 +86,20 @@ type SQLCommentCarrier struct {
 	Mode          SQLCommentInjectionMode
 	DBServiceName string
 	SpanID        uint64
+	// PeerDBHostname and PeerDBName identify the database the query is sent to, if known, and are
+	// injected along with the service tags.
+	PeerDBHostname string
+	PeerDBName     string
+	// SamplingMode sets how the sampling decision of the trace is injected, see
+	// SQLCommentSamplingDeferred.
+	SamplingMode SQLCommentSamplingMode
+}
+
+// sqlCommentRecorder is implemented by the global tracers recording the SQL
+// comments injected by the carriers, such as the mock tracer.
+type sqlCommentRecorder interface {
+	// RecordSQLComment records the comment injected into a query.
+	RecordSQLComment(comment string)
 }
 
 // Inject injects a span context in the carrier's Query field as a comment.
@@ -63,22 +114,28 @@ func (c *SQLCommentCarrier) Inject(spanCtx ddtrace.SpanContext) error {
 	case SQLInjectionModeFull:
 		var (
 			samplingPriority int
+			final            bool
 			traceID          uint64
+			traceIDUpper     = "0000000000000000"
 		)
 		if ctx, ok := spanCtx.(*spanContext); ok {
-			if sp, ok := ctx.samplingPriority(); ok {
-				samplingPriority = sp
+			if ctx.trace != nil {
+				samplingPriority, final = ctx.trace.finalSamplingPriority()
 			}
 			traceID = ctx.TraceID()
+			traceIDUpper = ctx.traceIDUpper()
 		}
 		if traceID == 0 {
 			traceID = c.SpanID
 		}
 		sampled := int64(0)
-		if samplingPriority > 0 {
+		if samplingPriority > 0 && (final || c.SamplingMode != SQLCommentSamplingDeferred) {
 			sampled = 1
 		}
-		tags[sqlCommentTraceParent] = encodeTraceParent(traceID, c.SpanID, sampled)
+		tags[sqlCommentTraceParent] = encodeTraceParent(traceIDUpper, traceID, c.SpanID, sampled)
+		if final {
+			tags[sqlCommentSamplingPriority] = strconv.Itoa(samplingPriority)
+		}
 		fallthrough
 	case SQLInjectionModeService:
 		var env, version string
@@ -100,21 +157,34 @@ func (c *SQLCommentCarrier) Inject(spanCtx ddtrace.SpanContext) error {
 			tags[sqlCommentParentVersion] = version
 		}
 		tags[sqlCommentDBService] = c.DBServiceName
+		if c.PeerDBHostname != "" {
+			tags[sqlCommentDBHostname] = c.PeerDBHostname
+		}
+		if c.PeerDBName != "" {
+			tags[sqlCommentDBName] = c.PeerDBName
+		}
 	}
+	query := c.Query
 	c.Query = commentQuery(c.Query, tags)
+	if r, ok := internal.GetGlobalTracer().(sqlCommentRecorder); ok && c.Query != query {
+		r.RecordSQLComment(strings.TrimSuffix(strings.TrimSuffix(c.Query, query), " "))
+	}
 	return nil
 }
 
 // encodeTraceParent encodes trace parent as per the w3c trace context spec (https://www.w3.org/TR/trace-context/#version).
-func encodeTraceParent(traceID uint64, spanID uint64, sampled int64) string {
+// The trace id is made of traceIDUpper, the upper 64 bits of a 128-bit trace ID as
+// 16 lowercase hexadecimal characters, followed by the lower 64 bits in traceID.
+func encodeTraceParent(traceIDUpper string, traceID uint64, spanID uint64, sampled int64) string {
 	var b strings.Builder
 	// traceparent has a fixed length of 55:
 	// 2 bytes for the version, 32 for the trace id, 16 for the span id, 2 for the sampled flag and 3 for separators
 	b.Grow(55)
 	b.WriteString(w3cContextVersion)
 	b.WriteRune('-')
+	b.WriteString(traceIDUpper)
 	tid := strconv.FormatUint(traceID, 16)
-	for i := 0; i < 32-len(tid); i++ {
+	for i := 0; i < 16-len(tid); i++ {
 		b.WriteRune('0')
 	}
 	b.WriteString(tid)
@@ -145,7 +215,7 @@ func commentQuery(query string, tags map[string]string) string {
 	var b strings.Builder
 	// the sqlcommenter specification dictates that tags should be sorted. Since we know all injected keys,
 	// we skip a sorting operation by specifying the order of keys statically
-	orderedKeys := []string{sqlCommentDBService, sqlCommentEnv, sqlCommentParentService, sqlCommentParentVersion, sqlCommentTraceParent}
+	orderedKeys := []string{sqlCommentDBName, sqlCommentDBService, sqlCommentEnv, sqlCommentDBHostname, sqlCommentParentService, sqlCommentParentVersion, sqlCommentSamplingPriority, sqlCommentTraceParent}
 	first := true
 	for _, k := range orderedKeys {
 		if v, ok := tags[k]; ok {
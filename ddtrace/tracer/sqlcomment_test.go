@@ -27,6 +27,10 @@ func TestSQLCommentCarrier(t *testing.T) {
 		samplingPriority  int
 		expectedQuery     string
 		expectedSpanIDGen bool
+		peerDBHostname    string
+		peerDBName        string
+		samplingMode      SQLCommentSamplingMode
+		finishRoot        bool
 	}{
 		{
 			name:              "default",
@@ -68,6 +72,65 @@ func TestSQLCommentCarrier(t *testing.T) {
 			expectedQuery:     "/*dddbs='whiskey-db',dde='test-env',ddps='whiskey-service%20%21%23%24%25%26%27%28%29%2A%2B%2C%2F%3A%3B%3D%3F%40%5B%5D',ddpv='1.0.0',traceparent='00-0000000000000000000000000000000a-<span_id>-01'*/ SELECT * from FOO -- test query",
 			expectedSpanIDGen: true,
 		},
+		{
+			name:              "peer-db",
+			query:             "SELECT * from FOO",
+			mode:              SQLInjectionModeService,
+			injectSpan:        true,
+			peerDBHostname:    "db.internal",
+			peerDBName:        "orders",
+			expectedQuery:     "/*dddb='orders',dddbs='whiskey-db',dde='test-env',ddh='db.internal',ddps='whiskey-service%20%21%23%24%25%26%27%28%29%2A%2B%2C%2F%3A%3B%3D%3F%40%5B%5D',ddpv='1.0.0'*/ SELECT * from FOO",
+			expectedSpanIDGen: false,
+		},
+		{
+			name:              "user-keep",
+			query:             "SELECT * from FOO",
+			mode:              SQLInjectionModeFull,
+			injectSpan:        true,
+			samplingPriority:  2,
+			expectedQuery:     "/*dddbs='whiskey-db',dde='test-env',ddps='whiskey-service%20%21%23%24%25%26%27%28%29%2A%2B%2C%2F%3A%3B%3D%3F%40%5B%5D',ddpv='1.0.0',ddsp='2',traceparent='00-0000000000000000000000000000000a-<span_id>-01'*/ SELECT * from FOO",
+			expectedSpanIDGen: true,
+		},
+		{
+			name:              "user-reject",
+			query:             "SELECT * from FOO",
+			mode:              SQLInjectionModeFull,
+			injectSpan:        true,
+			samplingPriority:  -1,
+			expectedQuery:     "/*dddbs='whiskey-db',dde='test-env',ddps='whiskey-service%20%21%23%24%25%26%27%28%29%2A%2B%2C%2F%3A%3B%3D%3F%40%5B%5D',ddpv='1.0.0',ddsp='-1',traceparent='00-0000000000000000000000000000000a-<span_id>-00'*/ SELECT * from FOO",
+			expectedSpanIDGen: true,
+		},
+		{
+			name:              "deferred-auto-keep",
+			query:             "SELECT * from FOO",
+			mode:              SQLInjectionModeFull,
+			injectSpan:        true,
+			samplingPriority:  1,
+			samplingMode:      SQLCommentSamplingDeferred,
+			expectedQuery:     "/*dddbs='whiskey-db',dde='test-env',ddps='whiskey-service%20%21%23%24%25%26%27%28%29%2A%2B%2C%2F%3A%3B%3D%3F%40%5B%5D',ddpv='1.0.0',traceparent='00-0000000000000000000000000000000a-<span_id>-00'*/ SELECT * from FOO",
+			expectedSpanIDGen: true,
+		},
+		{
+			name:              "deferred-user-keep",
+			query:             "SELECT * from FOO",
+			mode:              SQLInjectionModeFull,
+			injectSpan:        true,
+			samplingPriority:  2,
+			samplingMode:      SQLCommentSamplingDeferred,
+			expectedQuery:     "/*dddbs='whiskey-db',dde='test-env',ddps='whiskey-service%20%21%23%24%25%26%27%28%29%2A%2B%2C%2F%3A%3B%3D%3F%40%5B%5D',ddpv='1.0.0',ddsp='2',traceparent='00-0000000000000000000000000000000a-<span_id>-01'*/ SELECT * from FOO",
+			expectedSpanIDGen: true,
+		},
+		{
+			name:              "deferred-finished-root",
+			query:             "SELECT * from FOO",
+			mode:              SQLInjectionModeFull,
+			injectSpan:        true,
+			samplingPriority:  1,
+			samplingMode:      SQLCommentSamplingDeferred,
+			finishRoot:        true,
+			expectedQuery:     "/*dddbs='whiskey-db',dde='test-env',ddps='whiskey-service%20%21%23%24%25%26%27%28%29%2A%2B%2C%2F%3A%3B%3D%3F%40%5B%5D',ddpv='1.0.0',ddsp='1',traceparent='00-0000000000000000000000000000000a-<span_id>-01'*/ SELECT * from FOO",
+			expectedSpanIDGen: true,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -81,10 +144,13 @@ func TestSQLCommentCarrier(t *testing.T) {
 			if tc.injectSpan {
 				root := tracer.StartSpan("service.calling.db", WithSpanID(10)).(*span)
 				root.SetTag(ext.SamplingPriority, tc.samplingPriority)
+				if tc.finishRoot {
+					root.Finish()
+				}
 				spanCtx = root.Context()
 			}
 
-			carrier := SQLCommentCarrier{Query: tc.query, Mode: tc.mode, DBServiceName: "whiskey-db"}
+			carrier := SQLCommentCarrier{Query: tc.query, Mode: tc.mode, DBServiceName: "whiskey-db", PeerDBHostname: tc.peerDBHostname, PeerDBName: tc.peerDBName, SamplingMode: tc.samplingMode}
 			err := carrier.Inject(spanCtx)
 			require.NoError(t, err)
 			expected := strings.ReplaceAll(tc.expectedQuery, "<span_id>", fmt.Sprintf("%016s", strconv.FormatUint(carrier.SpanID, 16)))
@@ -93,6 +159,22 @@ func TestSQLCommentCarrier(t *testing.T) {
 	}
 }
 
+func TestSQLCommentCarrierTraceID128(t *testing.T) {
+	tracer := newTracer(WithService("whiskey-service"), WithTraceID128Bit(true))
+	defer tracer.Stop()
+	root := tracer.StartSpan("service.calling.db", WithSpanID(10)).(*span)
+	root.SetTag(ext.SamplingPriority, 2)
+	spanCtx := root.Context().(*spanContext)
+	upper := spanCtx.traceIDUpper()
+	require.NotEqual(t, "0000000000000000", upper)
+
+	carrier := SQLCommentCarrier{Query: "SELECT * from FOO", Mode: SQLInjectionModeFull, DBServiceName: "whiskey-db"}
+	err := carrier.Inject(spanCtx)
+	require.NoError(t, err)
+	expected := fmt.Sprintf("/*dddbs='whiskey-db',ddps='whiskey-service',ddsp='2',traceparent='00-%s000000000000000a-%016x-01'*/ SELECT * from FOO", upper, carrier.SpanID)
+	assert.Equal(t, expected, carrier.Query)
+}
+
 func BenchmarkSQLCommentInjection(b *testing.B) {
 	tracer := newTracer(WithService("whiskey-service !#$%&'()*+,/:;=?@[]"), WithEnv("test-env"), WithServiceVersion("1.0.0"))
 	defer tracer.Stop()
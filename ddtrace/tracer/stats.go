@@ -179,10 +179,11 @@ func (c *concentrator) flushAndSend(timenow time.Time, includeCurrent bool) {
 		c.mu.Lock()
 		defer c.mu.Unlock()
 		now := timenow.UnixNano()
+		gc := c.cfg.loadGlobalConfig()
 		sp := statsPayload{
 			Hostname: c.cfg.hostname,
-			Env:      c.cfg.env,
-			Version:  c.cfg.version,
+			Env:      gc.env,
+			Version:  gc.version,
 			Stats:    make([]statsBucket, 0, len(c.buckets)),
 		}
 		for ts, srb := range c.buckets {
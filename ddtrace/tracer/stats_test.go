@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // waitForBuckets reports whether concentrator c contains n buckets within a 5ms
@@ -175,5 +176,24 @@ func TestConcentrator(t *testing.T) {
 			c.Stop()
 			assert.NotEmpty(t, transport.Stats())
 		})
+
+		// stats should hold the env and version currently in effect
+		t.Run("global-config", func(t *testing.T) {
+			transport := newDummyTransport()
+			cfg := &config{transport: transport, env: "prod", version: "1.0"}
+			cfg.globalConfig.Store(&globalConfig{env: "staging", version: "1.1"})
+			c := newConcentrator(cfg, 500000)
+			c.Start()
+			c.In <- &aggregableSpan{
+				key:      key1,
+				Start:    time.Now().UnixNano(),
+				Duration: 1,
+			}
+			c.Stop()
+			stats := transport.Stats()
+			require.NotEmpty(t, stats)
+			assert.Equal(t, "staging", stats[0].Env)
+			assert.Equal(t, "1.1", stats[0].Version)
+		})
 	})
 }
@@ -8,7 +8,9 @@ package tracer
 import (
 	"fmt"
 	"net/http"
+	"net/url"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -122,6 +124,17 @@ type PropagatorConfig struct {
 	// B3 specifies if B3 headers should be added for trace propagation.
 	// See https://github.com/openzipkin/b3-propagation
 	B3 bool
+
+	// TraceContext specifies if W3C Trace Context headers (traceparent and
+	// tracestate) should be added for trace propagation.
+	// See https://www.w3.org/TR/trace-context/
+	TraceContext bool
+
+	// Baggage specifies if the W3C baggage header should be used to propagate
+	// baggage items, in addition to the BaggagePrefix headers. When extracting,
+	// its items are added to the span context extracted by the other propagators.
+	// See https://www.w3.org/TR/baggage/
+	Baggage bool
 }
 
 // NewPropagator returns a new propagator which uses TextMap to inject
@@ -174,6 +187,12 @@ func getPropagators(cfg *PropagatorConfig, env string) []Propagator {
 	if cfg.B3 {
 		defaultPs = append(defaultPs, &propagatorB3{})
 	}
+	if cfg.TraceContext {
+		defaultPs = append(defaultPs, &propagatorW3c{})
+	}
+	if cfg.Baggage {
+		defaultPs = append(defaultPs, &propagatorBaggage{})
+	}
 	if ps == "" {
 		return defaultPs
 	}
@@ -181,8 +200,14 @@ func getPropagators(cfg *PropagatorConfig, env string) []Propagator {
 	if cfg.B3 {
 		list = append(list, &propagatorB3{})
 	}
+	if cfg.TraceContext {
+		list = append(list, &propagatorW3c{})
+	}
+	if cfg.Baggage {
+		list = append(list, &propagatorBaggage{})
+	}
 	for _, v := range strings.Split(ps, ",") {
-		switch strings.ToLower(v) {
+		switch strings.ToLower(strings.TrimSpace(v)) {
 		case "datadog":
 			list = append(list, dd)
 		case "b3":
@@ -190,6 +215,16 @@ func getPropagators(cfg *PropagatorConfig, env string) []Propagator {
 				// propagatorB3 hasn't already been added, add a new one.
 				list = append(list, &propagatorB3{})
 			}
+		case "tracecontext":
+			if !cfg.TraceContext {
+				// propagatorW3c hasn't already been added, add a new one.
+				list = append(list, &propagatorW3c{})
+			}
+		case "baggage":
+			if !cfg.Baggage {
+				// propagatorBaggage hasn't already been added, add a new one.
+				list = append(list, &propagatorBaggage{})
+			}
 		default:
 			log.Warn("unrecognized propagator: %s\n", v)
 		}
@@ -216,19 +251,42 @@ func (p *chainedPropagator) Inject(spanCtx ddtrace.SpanContext, carrier interfac
 
 // Extract implements Propagator.
 func (p *chainedPropagator) Extract(carrier interface{}) (ddtrace.SpanContext, error) {
+	var ctx ddtrace.SpanContext
 	for _, v := range p.extractors {
-		ctx, err := v.Extract(carrier)
-		if ctx != nil {
+		if _, ok := v.(*propagatorBaggage); ok {
+			// baggage alone does not make a span context, see below
+			continue
+		}
+		c, err := v.Extract(carrier)
+		if c != nil {
 			// first extractor returns
-			log.Debug("Extracted span context: %#v", ctx)
-			return ctx, nil
+			ctx = c
+			break
 		}
 		if err == ErrSpanContextNotFound {
 			continue
 		}
 		return nil, err
 	}
-	return nil, ErrSpanContextNotFound
+	if ctx == nil {
+		return nil, ErrSpanContextNotFound
+	}
+	if sc, ok := ctx.(*spanContext); ok {
+		// add the baggage items to the extracted span context
+		for _, v := range p.extractors {
+			if _, ok := v.(*propagatorBaggage); !ok {
+				continue
+			}
+			if b, err := v.Extract(carrier); err == nil {
+				b.ForeachBaggageItem(func(k, v string) bool {
+					sc.setBaggageItem(k, v)
+					return true
+				})
+			}
+		}
+	}
+	log.Debug("Extracted span context: %#v", ctx)
+	return ctx, nil
 }
 
 // propagator implements Propagator and injects/extracts span contexts
@@ -372,6 +430,11 @@ func unmarshalPropagatingTags(ctx *spanContext, v string) {
 		log.Warn("Did not extract %s: %v. Incoming tags will not be propagated further.", traceTagsHeader, err.Error())
 		ctx.trace.setTag(keyPropagationError, "decoding_error")
 	}
+	if tid, ok := ctx.trace.propagatingTags[keyTraceID128]; ok && (len(tid) != 16 || !isLowerHex(tid)) {
+		log.Warn("Did not extract %s: malformed value %q.", keyTraceID128, tid)
+		delete(ctx.trace.propagatingTags, keyTraceID128)
+		ctx.trace.setTag(keyPropagationError, "malformed_tid "+tid)
+	}
 }
 
 const (
@@ -456,3 +519,376 @@ func (*propagatorB3) extractTextMap(reader TextMapReader) (ddtrace.SpanContext,
 	}
 	return &ctx, nil
 }
+
+const (
+	traceparentHeader = "traceparent"
+	tracestateHeader  = "tracestate"
+)
+
+// maxTracestateListMembers is the maximum number of list members allowed
+// in the tracestate header by the W3C Trace Context specification.
+const maxTracestateListMembers = 32
+
+// propagatorW3c implements Propagator and injects/extracts span contexts
+// using W3C Trace Context headers. Only TextMap carriers are supported.
+// See https://www.w3.org/TR/trace-context/
+type propagatorW3c struct{}
+
+func (p *propagatorW3c) Inject(spanCtx ddtrace.SpanContext, carrier interface{}) error {
+	switch c := carrier.(type) {
+	case TextMapWriter:
+		return p.injectTextMap(spanCtx, c)
+	default:
+		return ErrInvalidCarrier
+	}
+}
+
+func (*propagatorW3c) injectTextMap(spanCtx ddtrace.SpanContext, writer TextMapWriter) error {
+	ctx, ok := spanCtx.(*spanContext)
+	if !ok || ctx.traceID == 0 || ctx.spanID == 0 {
+		return ErrInvalidSpanContext
+	}
+	flags := "00"
+	if p, ok := ctx.samplingPriority(); ok && p >= ext.PriorityAutoKeep {
+		flags = "01"
+	}
+	writer.Set(traceparentHeader, fmt.Sprintf("00-%s%016x-%016x-%s", ctx.traceIDUpper(), ctx.traceID, ctx.spanID, flags))
+	if ts := composeTracestate(ctx); ts != "" {
+		writer.Set(tracestateHeader, ts)
+	}
+	return nil
+}
+
+// composeTracestate returns the tracestate header value for ctx. The Datadog
+// list member is always placed first, followed by the list members of other
+// vendors which were received upstream. The Datadog list member is omitted
+// when it has no fields, as the specification does not allow empty values.
+func composeTracestate(ctx *spanContext) string {
+	var fields []string
+	if p, ok := ctx.samplingPriority(); ok {
+		fields = append(fields, "s:"+strconv.Itoa(p))
+	}
+	if ctx.origin != "" {
+		fields = append(fields, "o:"+sanitizeTracestateValue(ctx.origin))
+	}
+	var tracestate string
+	if ctx.trace != nil {
+		ctx.trace.mu.RLock()
+		for k, v := range ctx.trace.propagatingTags {
+			if !strings.HasPrefix(k, "_dd.p.") || k == keyTraceID128 {
+				// the upper 64 bits of the trace ID are already part of traceparent
+				continue
+			}
+			fields = append(fields, "t."+sanitizeTracestateKey(strings.TrimPrefix(k, "_dd.p."))+":"+sanitizeTracestateValue(v))
+		}
+		tracestate = ctx.trace.tracestate
+		ctx.trace.mu.RUnlock()
+	}
+	var members []string
+	if len(fields) > 0 {
+		members = append(members, "dd="+strings.Join(fields, ";"))
+	}
+	if tracestate != "" {
+		for _, m := range strings.Split(tracestate, ",") {
+			if len(members) >= maxTracestateListMembers {
+				break
+			}
+			members = append(members, m)
+		}
+	}
+	return strings.Join(members, ",")
+}
+
+// sanitizeTracestateKey replaces the characters which are not allowed in the
+// keys of the Datadog tracestate list member with underscores.
+func sanitizeTracestateKey(k string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e || r == ' ' || r == ',' || r == '=' || r == ':' || r == ';' {
+			return '_'
+		}
+		return r
+	}, k)
+}
+
+// sanitizeTracestateValue replaces the characters which are not allowed in the
+// values of the Datadog tracestate list member with underscores. The '=' character
+// is encoded as '~'.
+func sanitizeTracestateValue(v string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '=' {
+			return '~'
+		}
+		if r < 0x20 || r > 0x7e || r == ',' || r == ';' || r == '~' {
+			return '_'
+		}
+		return r
+	}, v)
+}
+
+func (p *propagatorW3c) Extract(carrier interface{}) (ddtrace.SpanContext, error) {
+	switch c := carrier.(type) {
+	case TextMapReader:
+		return p.extractTextMap(c)
+	default:
+		return nil, ErrInvalidCarrier
+	}
+}
+
+func (*propagatorW3c) extractTextMap(reader TextMapReader) (ddtrace.SpanContext, error) {
+	var parentHeader, stateHeader string
+	err := reader.ForeachKey(func(k, v string) error {
+		switch strings.ToLower(k) {
+		case traceparentHeader:
+			if parentHeader != "" {
+				// the specification requires a single traceparent header
+				return ErrSpanContextCorrupted
+			}
+			parentHeader = v
+		case tracestateHeader:
+			if stateHeader != "" {
+				stateHeader += ","
+			}
+			stateHeader += v
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if parentHeader == "" {
+		return nil, ErrSpanContextNotFound
+	}
+	var ctx spanContext
+	if err := parseTraceparent(&ctx, parentHeader); err != nil {
+		return nil, err
+	}
+	parseTracestate(&ctx, stateHeader)
+	return &ctx, nil
+}
+
+// parseTraceparent attempts to parse the traceparent header value v into ctx.
+// The lower 64 bits of the trace ID are used as the Datadog trace ID, while
+// non-zero upper 64 bits are kept as a propagating tag so that the full trace
+// ID can be re-emitted on injection.
+func parseTraceparent(ctx *spanContext, v string) error {
+	v = strings.Trim(v, "\t ")
+	if len(v) < 55 {
+		return ErrSpanContextCorrupted
+	}
+	version := v[0:2]
+	if version == "ff" || !isLowerHex(version) {
+		return ErrSpanContextCorrupted
+	}
+	if version == "00" && len(v) != 55 {
+		// future versions may append more fields to the header
+		return ErrSpanContextCorrupted
+	}
+	if v[2] != '-' || v[35] != '-' || v[52] != '-' || (len(v) > 55 && v[55] != '-') {
+		return ErrSpanContextCorrupted
+	}
+	fullTraceID, spanID, flags := v[3:35], v[36:52], v[53:55]
+	if !isLowerHex(fullTraceID) || !isLowerHex(spanID) || !isLowerHex(flags) {
+		return ErrSpanContextCorrupted
+	}
+	if strings.Trim(fullTraceID, "0") == "" || strings.Trim(spanID, "0") == "" {
+		return ErrSpanContextCorrupted
+	}
+	var err error
+	if ctx.traceID, err = strconv.ParseUint(fullTraceID[16:], 16, 64); err != nil {
+		return ErrSpanContextCorrupted
+	}
+	if ctx.spanID, err = strconv.ParseUint(spanID, 16, 64); err != nil {
+		return ErrSpanContextCorrupted
+	}
+	f, err := strconv.ParseUint(flags, 16, 8)
+	if err != nil {
+		return ErrSpanContextCorrupted
+	}
+	ctx.setSamplingPriority(int(f&0x1), samplernames.Unknown)
+	if traceIDHigh := fullTraceID[:16]; traceIDHigh != "0000000000000000" {
+		ctx.trace.setPropagatingTag(keyTraceID128, traceIDHigh)
+	}
+	return nil
+}
+
+// parseTracestate attempts to parse the tracestate header value v into ctx.
+// The Datadog list member provides the sampling priority, origin and propagating
+// tags, while the list members of other vendors are retained so that they can
+// be propagated further. Invalid entries are ignored.
+func parseTracestate(ctx *spanContext, v string) {
+	if v == "" {
+		return
+	}
+	var others []string
+	for _, member := range strings.Split(v, ",") {
+		member = strings.TrimSpace(member)
+		if member == "" {
+			continue
+		}
+		if !strings.HasPrefix(member, "dd=") {
+			others = append(others, member)
+			continue
+		}
+		for _, field := range strings.Split(strings.TrimPrefix(member, "dd="), ";") {
+			i := strings.IndexByte(field, ':')
+			if i <= 0 {
+				continue
+			}
+			key, val := field[:i], field[i+1:]
+			switch {
+			case key == "s":
+				p, err := strconv.Atoi(val)
+				if err != nil {
+					continue
+				}
+				// the sampling flag of traceparent takes precedence when the two disagree
+				if sampled, _ := ctx.samplingPriority(); (sampled == 1) == (p > 0) {
+					ctx.setSamplingPriority(p, samplernames.Unknown)
+				}
+			case key == "o":
+				ctx.origin = strings.ReplaceAll(val, "~", "=")
+			case key == "t.tid":
+				// the trace ID of traceparent is authoritative
+			case strings.HasPrefix(key, "t."):
+				ctx.trace.setPropagatingTag("_dd.p."+key[len("t."):], strings.ReplaceAll(val, "~", "="))
+			}
+		}
+	}
+	if len(others) > 0 {
+		ctx.trace.mu.Lock()
+		ctx.trace.tracestate = strings.Join(others, ",")
+		ctx.trace.mu.Unlock()
+	}
+}
+
+// isLowerHex reports whether s only contains lowercase hexadecimal characters.
+func isLowerHex(s string) bool {
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// baggageHeader is the header of the W3C Baggage specification.
+const baggageHeader = "baggage"
+
+// W3C Baggage limits: baggage items beyond them are not propagated.
+const (
+	maxBaggageMembers = 64
+	maxBaggageBytes   = 8192
+)
+
+// propagatorBaggage implements Propagator and injects/extracts the baggage
+// items of span contexts using the W3C baggage header. It does not propagate
+// trace and span IDs: the span contexts it extracts only hold baggage items,
+// which chainedPropagator adds to the span context extracted by the other
+// propagators. Only TextMap carriers are supported.
+// See https://www.w3.org/TR/baggage/
+type propagatorBaggage struct{}
+
+func (p *propagatorBaggage) Inject(spanCtx ddtrace.SpanContext, carrier interface{}) error {
+	switch c := carrier.(type) {
+	case TextMapWriter:
+		return p.injectTextMap(spanCtx, c)
+	default:
+		return ErrInvalidCarrier
+	}
+}
+
+func (*propagatorBaggage) injectTextMap(spanCtx ddtrace.SpanContext, writer TextMapWriter) error {
+	ctx, ok := spanCtx.(*spanContext)
+	if !ok {
+		return ErrInvalidSpanContext
+	}
+	var keys []string
+	ctx.ForeachBaggageItem(func(k, _ string) bool {
+		if isBaggageKey(k) {
+			keys = append(keys, k)
+		}
+		return true
+	})
+	if len(keys) == 0 {
+		return nil
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for i, k := range keys {
+		if i == maxBaggageMembers {
+			break
+		}
+		member := k + "=" + url.PathEscape(ctx.baggageItem(k))
+		if b.Len()+len(member)+1 > maxBaggageBytes {
+			break
+		}
+		if b.Len() > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(member)
+	}
+	if b.Len() > 0 {
+		writer.Set(baggageHeader, b.String())
+	}
+	return nil
+}
+
+func (p *propagatorBaggage) Extract(carrier interface{}) (ddtrace.SpanContext, error) {
+	switch c := carrier.(type) {
+	case TextMapReader:
+		return p.extractTextMap(c)
+	default:
+		return nil, ErrInvalidCarrier
+	}
+}
+
+func (*propagatorBaggage) extractTextMap(reader TextMapReader) (ddtrace.SpanContext, error) {
+	var header string
+	err := reader.ForeachKey(func(k, v string) error {
+		if strings.ToLower(k) == baggageHeader {
+			if header != "" {
+				header += ","
+			}
+			header += v
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if header == "" {
+		return nil, ErrSpanContextNotFound
+	}
+	var ctx spanContext
+	for i, member := range strings.Split(header, ",") {
+		if i == maxBaggageMembers {
+			break
+		}
+		// the metadata properties following the value are ignored
+		member = strings.SplitN(member, ";", 2)[0]
+		kv := strings.SplitN(member, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		k := strings.TrimSpace(kv[0])
+		v, err := url.PathUnescape(strings.TrimSpace(kv[1]))
+		if err != nil || !isBaggageKey(k) {
+			continue
+		}
+		ctx.setBaggageItem(k, v)
+	}
+	return &ctx, nil
+}
+
+// isBaggageKey returns true when k is a valid W3C baggage key, i.e. an HTTP token.
+func isBaggageKey(k string) bool {
+	if k == "" {
+		return false
+	}
+	for _, r := range k {
+		if r <= 0x20 || r >= 0x7f || strings.ContainsRune(`"(),/:;<=>?@[\]{}`, r) {
+			return false
+		}
+	}
+	return true
+}
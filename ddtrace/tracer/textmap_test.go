@@ -13,6 +13,7 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/internal"
@@ -549,6 +550,387 @@ func TestB3(t *testing.T) {
 	})
 }
 
+func TestW3C(t *testing.T) {
+	t.Run("inject", func(t *testing.T) {
+		os.Setenv("DD_PROPAGATION_STYLE_INJECT", "tracecontext")
+		defer os.Unsetenv("DD_PROPAGATION_STYLE_INJECT")
+
+		var tests = []struct {
+			in       []uint64 // contains [<trace_id>, <span_id>]
+			priority int
+			origin   string
+			out      map[string]string
+		}{
+			{
+				[]uint64{1412508178991881, 1842642739201064},
+				1,
+				"",
+				map[string]string{
+					traceparentHeader: "00-0000000000000000000504ab30404b09-00068bdfb1eb0428-01",
+					tracestateHeader:  "dd=s:1;t.dm:-1",
+				},
+			},
+			{
+				[]uint64{9530669991610245, 9455715668862222},
+				-1,
+				"synthetics",
+				map[string]string{
+					traceparentHeader: "00-00000000000000000021dc1807524785-002197ec5d8a250e-00",
+					tracestateHeader:  "dd=s:-1;o:synthetics",
+				},
+			},
+			{
+				[]uint64{1, 1},
+				2,
+				"synthetics=web;app",
+				map[string]string{
+					traceparentHeader: "00-00000000000000000000000000000001-0000000000000001-01",
+					tracestateHeader:  "dd=s:2;o:synthetics~web_app;t.dm:-1",
+				},
+			},
+		}
+
+		for _, test := range tests {
+			t.Run("", func(t *testing.T) {
+				tracer := newTracer()
+				root := tracer.StartSpan("web.request").(*span)
+				root.SetTag(ext.SamplingPriority, test.priority)
+				ctx, ok := root.Context().(*spanContext)
+				ctx.traceID = test.in[0]
+				ctx.spanID = test.in[1]
+				ctx.origin = test.origin
+				headers := TextMapCarrier(map[string]string{})
+				err := tracer.Inject(ctx, headers)
+
+				assert := assert.New(t)
+				assert.True(ok)
+				assert.Nil(err)
+				assert.Equal(test.out[traceparentHeader], headers[traceparentHeader])
+				assert.Equal(test.out[tracestateHeader], headers[tracestateHeader])
+				assert.NotContains(headers, DefaultTraceIDHeader)
+			})
+		}
+	})
+
+	t.Run("extract", func(t *testing.T) {
+		os.Setenv("DD_PROPAGATION_STYLE_EXTRACT", "tracecontext")
+		defer os.Unsetenv("DD_PROPAGATION_STYLE_EXTRACT")
+
+		var tests = []struct {
+			in       TextMapCarrier
+			out      []uint64 // contains [<trace_id>, <span_id>]
+			priority int
+			origin   string
+		}{
+			{
+				TextMapCarrier{
+					traceparentHeader: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+				},
+				[]uint64{11803532876627986230, 67667974448284343},
+				1,
+				"",
+			},
+			{
+				TextMapCarrier{
+					traceparentHeader: "00-00000000000000000000000000000001-0000000000000002-00",
+					tracestateHeader:  "dd=s:-1;o:synthetics~web;t.dm:-4,othervendor=t61rcWkgMzE",
+				},
+				[]uint64{1, 2},
+				-1,
+				"synthetics=web",
+			},
+			{
+				TextMapCarrier{
+					traceparentHeader: "00-00000000000000000000000000000001-0000000000000002-01",
+					tracestateHeader:  "dd=s:-1",
+				},
+				[]uint64{1, 2},
+				1,
+				"",
+			},
+			{
+				TextMapCarrier{
+					traceparentHeader: "01-00000000000000000000000000000003-0000000000000004-01-future",
+					tracestateHeader:  "dd=s:2",
+				},
+				[]uint64{3, 4},
+				2,
+				"",
+			},
+			{
+				TextMapCarrier{
+					traceparentHeader: " \t00-00000000000000000000000000000005-0000000000000006-01\t ",
+				},
+				[]uint64{5, 6},
+				1,
+				"",
+			},
+		}
+
+		for _, test := range tests {
+			t.Run("", func(t *testing.T) {
+				tracer := newTracer()
+				assert := assert.New(t)
+				ctx, err := tracer.Extract(test.in)
+				assert.Nil(err)
+				sctx, ok := ctx.(*spanContext)
+				assert.True(ok)
+
+				assert.Equal(test.out[0], sctx.traceID)
+				assert.Equal(test.out[1], sctx.spanID)
+				assert.Equal(test.origin, sctx.origin)
+				p, ok := sctx.samplingPriority()
+				assert.True(ok)
+				assert.Equal(test.priority, p)
+			})
+		}
+	})
+
+	t.Run("extract/invalid", func(t *testing.T) {
+		os.Setenv("DD_PROPAGATION_STYLE_EXTRACT", "tracecontext")
+		defer os.Unsetenv("DD_PROPAGATION_STYLE_EXTRACT")
+
+		for _, tp := range []string{
+			"00-00000000000000000000000000000000-0000000000000001-01",
+			"00-00000000000000000000000000000001-0000000000000000-01",
+			"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+			"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+			"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+			"-00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-",
+			"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-",
+		} {
+			t.Run(tp, func(t *testing.T) {
+				tracer := newTracer()
+				_, err := tracer.Extract(TextMapCarrier{traceparentHeader: tp})
+				assert.Equal(t, ErrSpanContextCorrupted, err)
+			})
+		}
+		tracer := newTracer()
+		_, err := tracer.Extract(TextMapCarrier{})
+		assert.Equal(t, ErrSpanContextNotFound, err)
+	})
+
+	t.Run("propagate", func(t *testing.T) {
+		os.Setenv("DD_PROPAGATION_STYLE_INJECT", "tracecontext")
+		defer os.Unsetenv("DD_PROPAGATION_STYLE_INJECT")
+		os.Setenv("DD_PROPAGATION_STYLE_EXTRACT", "tracecontext")
+		defer os.Unsetenv("DD_PROPAGATION_STYLE_EXTRACT")
+
+		tracer := newTracer()
+		assert := assert.New(t)
+		ctx, err := tracer.Extract(TextMapCarrier{
+			traceparentHeader: "00-00000000000000000000000000000001-0000000000000002-01",
+			tracestateHeader:  "dd=s:2;t.usr.id:baz64~~,othervendor=t61rcWkgMzE",
+		})
+		assert.Nil(err)
+		sp := tracer.StartSpan("web.request", ChildOf(ctx)).(*span)
+		headers := TextMapCarrier(map[string]string{})
+		assert.Nil(tracer.Inject(sp.Context(), headers))
+
+		assert.Equal(fmt.Sprintf("00-00000000000000000000000000000001-%016x-01", sp.SpanID), headers[traceparentHeader])
+		assert.Equal("dd=s:2;t.usr.id:baz64~~,othervendor=t61rcWkgMzE", headers[tracestateHeader])
+	})
+
+	t.Run("propagate/128-bit", func(t *testing.T) {
+		os.Setenv("DD_PROPAGATION_STYLE_INJECT", "tracecontext")
+		defer os.Unsetenv("DD_PROPAGATION_STYLE_INJECT")
+		os.Setenv("DD_PROPAGATION_STYLE_EXTRACT", "tracecontext")
+		defer os.Unsetenv("DD_PROPAGATION_STYLE_EXTRACT")
+
+		tracer := newTracer()
+		assert := assert.New(t)
+		ctx, err := tracer.Extract(TextMapCarrier{
+			traceparentHeader: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			tracestateHeader:  "dd=s:1;t.tid:0000000000000001",
+		})
+		assert.Nil(err)
+		assert.Equal(uint64(0xa3ce929d0e0e4736), ctx.(*spanContext).traceID)
+		sp := tracer.StartSpan("web.request", ChildOf(ctx)).(*span)
+		headers := TextMapCarrier(map[string]string{})
+		assert.Nil(tracer.Inject(sp.Context(), headers))
+
+		assert.Equal(fmt.Sprintf("00-4bf92f3577b34da6a3ce929d0e0e4736-%016x-01", sp.SpanID), headers[traceparentHeader])
+		assert.NotContains(headers[tracestateHeader], "t.tid")
+	})
+
+	t.Run("inject/no-datadog-member", func(t *testing.T) {
+		ctx := &spanContext{traceID: 1, spanID: 2}
+		headers := TextMapCarrier(map[string]string{})
+		assert.Nil(t, (&propagatorW3c{}).Inject(ctx, headers))
+
+		assert.Equal(t, "00-00000000000000000000000000000001-0000000000000002-00", headers[traceparentHeader])
+		assert.NotContains(t, headers, tracestateHeader)
+	})
+
+	t.Run("config", func(t *testing.T) {
+		tracer := newTracer(WithPropagator(NewPropagator(&PropagatorConfig{TraceContext: true})))
+		root := tracer.StartSpan("web.request").(*span)
+		root.SetTag(ext.SamplingPriority, 1)
+		headers := TextMapCarrier(map[string]string{})
+		err := tracer.Inject(root.Context(), headers)
+
+		assert := assert.New(t)
+		assert.Nil(err)
+		assert.Equal(fmt.Sprintf("00-%032x-%016x-01", root.TraceID, root.SpanID), headers[traceparentHeader])
+		assert.Equal(strconv.FormatUint(root.TraceID, 10), headers[DefaultTraceIDHeader])
+	})
+}
+
 func assertTraceTags(t *testing.T, expected, actual string) {
 	assert.ElementsMatch(t, strings.Split(expected, ","), strings.Split(actual, ","))
 }
+
+func TestTraceID128Bit(t *testing.T) {
+	t.Run("generate", func(t *testing.T) {
+		tracer := newTracer(WithTraceID128Bit(true))
+		defer tracer.Stop()
+		start := time.Unix(1700000000, 0)
+		root := tracer.StartSpan("web.request", StartTime(start)).(*span)
+		child := tracer.StartSpan("db.query", ChildOf(root.Context())).(*span)
+
+		root.context.trace.mu.RLock()
+		tid := root.context.trace.propagatingTags[keyTraceID128]
+		root.context.trace.mu.RUnlock()
+		assert.Equal(t, "6553f10000000000", tid)
+		assert.Equal(t, root.context.trace, child.context.trace)
+	})
+
+	t.Run("env", func(t *testing.T) {
+		os.Setenv("DD_TRACE_128_BIT_TRACEID_GENERATION_ENABLED", "true")
+		defer os.Unsetenv("DD_TRACE_128_BIT_TRACEID_GENERATION_ENABLED")
+		assert.True(t, newConfig().traceID128BitEnabled)
+		assert.False(t, newConfig(WithTraceID128Bit(false)).traceID128BitEnabled)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		tracer := newTracer()
+		defer tracer.Stop()
+		root := tracer.StartSpan("web.request").(*span)
+
+		root.context.trace.mu.RLock()
+		_, ok := root.context.trace.propagatingTags[keyTraceID128]
+		root.context.trace.mu.RUnlock()
+		assert.False(t, ok)
+	})
+
+	for _, style := range []string{"datadog", "tracecontext"} {
+		t.Run("propagate/"+style, func(t *testing.T) {
+			os.Setenv("DD_PROPAGATION_STYLE_INJECT", style)
+			defer os.Unsetenv("DD_PROPAGATION_STYLE_INJECT")
+			os.Setenv("DD_PROPAGATION_STYLE_EXTRACT", style)
+			defer os.Unsetenv("DD_PROPAGATION_STYLE_EXTRACT")
+
+			tracer := newTracer(WithTraceID128Bit(true))
+			defer tracer.Stop()
+			root := tracer.StartSpan("web.request", StartTime(time.Unix(1700000000, 0))).(*span)
+			root.SetTag(ext.SamplingPriority, 1)
+			headers := TextMapCarrier(map[string]string{})
+			assert.Nil(t, tracer.Inject(root.Context(), headers))
+
+			ctx, err := tracer.Extract(headers)
+			assert.Nil(t, err)
+			sctx := ctx.(*spanContext)
+			assert.Equal(t, root.TraceID, sctx.traceID)
+			assert.Equal(t, "6553f10000000000", sctx.trace.propagatingTags[keyTraceID128])
+		})
+	}
+
+	t.Run("extract/malformed", func(t *testing.T) {
+		tracer := newTracer()
+		defer tracer.Stop()
+		ctx, err := tracer.Extract(TextMapCarrier{
+			DefaultTraceIDHeader:  "1",
+			DefaultParentIDHeader: "2",
+			traceTagsHeader:       "_dd.p.tid=XYZ",
+		})
+		assert.Nil(t, err)
+		sctx := ctx.(*spanContext)
+		assert.NotContains(t, sctx.trace.propagatingTags, keyTraceID128)
+		assert.Equal(t, "malformed_tid XYZ", sctx.trace.tags[keyPropagationError])
+	})
+}
+
+func TestBaggagePropagator(t *testing.T) {
+	t.Run("inject", func(t *testing.T) {
+		os.Setenv("DD_PROPAGATION_STYLE_INJECT", "datadog,baggage")
+		defer os.Unsetenv("DD_PROPAGATION_STYLE_INJECT")
+
+		tracer := newTracer()
+		defer tracer.Stop()
+		root := tracer.StartSpan("web.request").(*span)
+		root.SetBaggageItem("user.id", "42")
+		root.SetBaggageItem("region", "us east,1")
+		root.SetBaggageItem("invalid key", "x")
+		headers := TextMapCarrier(map[string]string{})
+		assert.Nil(t, tracer.Inject(root.Context(), headers))
+
+		assert.Equal(t, "region=us%20east%2C1,user.id=42", headers[baggageHeader])
+		assert.Equal(t, strconv.FormatUint(root.TraceID, 10), headers[DefaultTraceIDHeader])
+		assert.Equal(t, "42", headers[DefaultBaggageHeaderPrefix+"user.id"])
+	})
+
+	t.Run("inject/limits", func(t *testing.T) {
+		tracer := newTracer(WithPropagator(NewPropagator(&PropagatorConfig{Baggage: true})))
+		defer tracer.Stop()
+		root := tracer.StartSpan("web.request").(*span)
+		for i := 0; i < maxBaggageMembers+1; i++ {
+			root.SetBaggageItem(fmt.Sprintf("k%03d", i), "v")
+		}
+		headers := TextMapCarrier(map[string]string{})
+		assert.Nil(t, tracer.Inject(root.Context(), headers))
+		assert.Len(t, strings.Split(headers[baggageHeader], ","), maxBaggageMembers)
+
+		root.SetBaggageItem("k000", strings.Repeat("v", maxBaggageBytes))
+		headers = TextMapCarrier(map[string]string{})
+		assert.Nil(t, tracer.Inject(root.Context(), headers))
+		assert.LessOrEqual(t, len(headers[baggageHeader]), maxBaggageBytes)
+		assert.NotContains(t, headers[baggageHeader], "k000=")
+	})
+
+	t.Run("extract", func(t *testing.T) {
+		os.Setenv("DD_PROPAGATION_STYLE_EXTRACT", "datadog,baggage")
+		defer os.Unsetenv("DD_PROPAGATION_STYLE_EXTRACT")
+
+		tracer := newTracer()
+		defer tracer.Stop()
+		ctx, err := tracer.Extract(TextMapCarrier{
+			DefaultTraceIDHeader:                "1",
+			DefaultParentIDHeader:               "2",
+			DefaultBaggageHeaderPrefix + "item": "x",
+			"Baggage":                           "user.id=42;prop=1, region = us%20east%2C1,malformed,in valid=x",
+		})
+		assert.Nil(t, err)
+		sctx := ctx.(*spanContext)
+		assert.Equal(t, uint64(1), sctx.traceID)
+		assert.Equal(t, uint64(2), sctx.spanID)
+		assert.Equal(t, map[string]string{
+			"item":    "x",
+			"user.id": "42",
+			"region":  "us east,1",
+		}, sctx.baggage)
+	})
+
+	t.Run("extract/no-context", func(t *testing.T) {
+		tracer := newTracer(WithPropagator(NewPropagator(&PropagatorConfig{Baggage: true})))
+		defer tracer.Stop()
+		_, err := tracer.Extract(TextMapCarrier{baggageHeader: "user.id=42"})
+		assert.Equal(t, ErrSpanContextNotFound, err)
+	})
+
+	t.Run("propagate", func(t *testing.T) {
+		tracer := newTracer(WithPropagator(NewPropagator(&PropagatorConfig{TraceContext: true, Baggage: true})))
+		defer tracer.Stop()
+		root := tracer.StartSpan("web.request").(*span)
+		root.SetBaggageItem("user.id", "a=b;c")
+		headers := TextMapCarrier(map[string]string{})
+		assert.Nil(t, tracer.Inject(root.Context(), headers))
+		// only keep the W3C headers
+		delete(headers, DefaultBaggageHeaderPrefix+"user.id")
+
+		ctx, err := tracer.Extract(headers)
+		assert.Nil(t, err)
+		assert.Equal(t, root.TraceID, ctx.(*spanContext).traceID)
+		assert.Equal(t, "a=b;c", ctx.(*spanContext).baggageItem("user.id"))
+	})
+}
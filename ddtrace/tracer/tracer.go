@@ -7,6 +7,7 @@ package tracer
 
 import (
 	gocontext "context"
+	"fmt"
 	"os"
 	"runtime/pprof"
 	rt "runtime/trace"
@@ -19,6 +20,8 @@ import (
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/internal"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/datastreams"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/debugger"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/remoteconfig"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/traceprof"
@@ -43,6 +46,10 @@ type tracer struct {
 	// stats are enabled.
 	stats *concentrator
 
+	// dataStreams specifies the processor used to aggregate Data Streams Monitoring
+	// checkpoints, when it is enabled.
+	dataStreams *datastreams.Processor
+
 	// traceWriter is responsible for sending finished traces to their
 	// destination, such as the Trace Agent or Datadog Forwarder.
 	traceWriter traceWriter
@@ -50,9 +57,9 @@ type tracer struct {
 	// out receives finishedTrace with spans  to be added to the payload.
 	out chan *finishedTrace
 
-	// flush receives a channel onto which it will confirm after a flush has been
-	// triggered and completed.
-	flush chan chan<- struct{}
+	// flush receives a channel onto which it will send the error of the
+	// triggered flush, or nil, once the flushed traces are sent.
+	flush chan chan<- error
 
 	// stop causes the tracer to shut down when closed.
 	stop chan struct{}
@@ -73,6 +80,17 @@ type tracer struct {
 	// finished, and dropped
 	spansStarted, spansFinished, tracesDropped uint32
 
+	// spansDropped tracks the number of spans of the traces dropped because the
+	// payload queue was full.
+	spansDropped uint32
+
+	// spansPostProcessorDropped tracks the number of spans dropped by the post
+	// processors.
+	spansPostProcessorDropped uint32
+
+	// health holds the health metrics recorded by the trace writer.
+	health *healthMetrics
+
 	// Records the number of dropped P0 traces and spans.
 	droppedP0Traces, droppedP0Spans uint32
 
@@ -88,6 +106,14 @@ type tracer struct {
 	// obfuscator holds the obfuscator used to obfuscate resources in aggregated stats.
 	// obfuscator may be nil if disabled.
 	obfuscator *obfuscate.Obfuscator
+
+	// rcClient holds the remote configuration client shared by the tracer and AppSec.
+	// It is nil when the client could not be created.
+	rcClient *remoteconfig.Client
+
+	// longRunning tracks the unfinished spans to report the long-running ones.
+	// It is nil unless enabled with WithLongRunningSpans.
+	longRunning *longRunningSpans
 }
 
 const (
@@ -123,18 +149,30 @@ func Start(opts ...StartOption) {
 	if !t.config.enabled {
 		return
 	}
-	internal.SetGlobalTracer(t)
-	if t.config.logStartup {
-		logStartup(t)
-	}
-	// Start AppSec with remote configuration
+	// The remote configuration client is set before the tracer is published, as
+	// it is read when stopping the tracer.
 	cfg := remoteconfig.DefaultClientConfig()
 	cfg.AgentAddr = t.config.agentAddr
 	cfg.AppVersion = t.config.version
 	cfg.Env = t.config.env
 	cfg.HTTP = t.config.httpClient
 	cfg.ServiceName = t.config.serviceName
-	appsec.Start(appsec.WithRCConfig(cfg))
+	if err := t.startRemoteConfig(cfg); err != nil {
+		log.Warn("Remote config: disabled due to a client creation error: %v", err)
+	}
+	internal.SetGlobalTracer(t)
+	if t.config.logStartup {
+		logStartup(t)
+	}
+	// Start AppSec and Dynamic Instrumentation with the remote configuration client
+	// shared with the tracer
+	appsec.Start(appsec.WithRCClient(t.rcClient))
+	debugger.Start(
+		debugger.WithRCClient(t.rcClient),
+		debugger.WithAgentAddr(t.config.agentAddr),
+		debugger.WithHTTPClient(t.config.httpClient),
+		debugger.WithService(t.config.serviceName, t.config.env, t.config.version),
+	)
 }
 
 // Stop stops the started tracer. Subsequent calls are valid but become no-op.
@@ -143,6 +181,24 @@ func Stop() {
 	log.Flush()
 }
 
+// StopContext stops the started tracer like Stop, but gives up waiting for the
+// buffered traces to be sent once ctx is done, in which case it returns the error
+// of ctx and the shutdown completes in the background. It is of use in processes
+// which must exit within a deadline, e.g. on SIGTERM.
+func StopContext(ctx gocontext.Context) error {
+	done := make(chan struct{})
+	go func() {
+		Stop()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // Span is an alias for ddtrace.Span. It is here to allow godoc to group methods returning
 // ddtrace.Span. It is recommended and is considered more correct to refer to this type as
 // ddtrace.Span instead.
@@ -168,6 +224,39 @@ func Inject(ctx ddtrace.SpanContext, carrier interface{}) error {
 	return internal.GetGlobalTracer().Inject(ctx, carrier)
 }
 
+// SetTraceTag sets the tag key to value on the local root span of the trace of
+// the span held by ctx, so that code deep down the call stack can tag the whole
+// trace, e.g. with customer.tier=enterprise, without access to its root span.
+// When key was selected with WithPropagatedTraceTags, the tag is also propagated
+// downstream as the _dd.p.<key> trace tag. It is a no-op when ctx holds no span,
+// and the tag is ignored when the local root span is already finished.
+func SetTraceTag(ctx gocontext.Context, key string, value interface{}) {
+	s, ok := SpanFromContext(ctx)
+	if !ok {
+		return
+	}
+	sp, ok := s.(*span)
+	if !ok {
+		// not a span of this tracer, e.g. a mocktracer span: no local root is known
+		s.SetTag(key, value)
+		return
+	}
+	trace := sp.context.trace
+	root := trace.root
+	if root == nil {
+		root = sp
+	}
+	root.SetTag(key, value)
+	if t, ok := internal.GetGlobalTracer().(*tracer); ok {
+		for _, k := range t.config.propagatedTraceTagKeys {
+			if k == key {
+				trace.setPropagatingTag("_dd.p."+key, fmt.Sprint(value))
+				break
+			}
+		}
+	}
+}
+
 // SetUser associates user information to the current trace which the
 // provided span belongs to. The options can be used to tune which user
 // bit of information gets monitored. In case of distributed traces,
@@ -191,12 +280,14 @@ const payloadQueueSize = 1000
 
 func newUnstartedTracer(opts ...StartOption) *tracer {
 	c := newConfig(opts...)
+	c.globalConfig.Store(localGlobalConfig(c))
 	sampler := newPrioritySampler()
+	health := newHealthMetrics()
 	var writer traceWriter
 	if c.logToStdout {
 		writer = newLogTraceWriter(c)
 	} else {
-		writer = newAgentTraceWriter(c, sampler)
+		writer = newAgentTraceWriter(c, sampler, health)
 	}
 	traces, spans, err := samplingRulesFromEnv()
 	if err != nil {
@@ -213,9 +304,10 @@ func newUnstartedTracer(opts ...StartOption) *tracer {
 		traceWriter:      writer,
 		out:              make(chan *finishedTrace, payloadQueueSize),
 		stop:             make(chan struct{}),
-		flush:            make(chan chan<- struct{}),
+		flush:            make(chan chan<- error),
 		rulesSampling:    newRulesSampler(c.traceRules, c.spanRules),
 		prioritySampling: sampler,
+		health:           health,
 		pid:              strconv.Itoa(os.Getpid()),
 		stats:            newConcentrator(c, defaultStatsBucketSize),
 		obfuscator: obfuscate.NewObfuscator(obfuscate.Config{
@@ -228,6 +320,16 @@ func newUnstartedTracer(opts ...StartOption) *tracer {
 			},
 		}),
 	}
+	if c.longRunningThreshold > 0 {
+		t.longRunning = newLongRunningSpans(c.longRunningThreshold)
+	}
+	if c.dataStreamsMonitoringEnabled {
+		if c.agent.DataStreams {
+			t.dataStreams = datastreams.NewProcessor(c.statsd, c.env, c.serviceName, c.agentAddr, c.httpClient)
+		} else {
+			log.Warn("Data Streams Monitoring is enabled but not supported by the agent. Please upgrade the agent.")
+		}
+	}
 	return t
 }
 
@@ -259,7 +361,17 @@ func newTracer(opts ...StartOption) *tracer {
 		defer t.wg.Done()
 		t.reportHealthMetrics(statsInterval)
 	}()
+	if t.longRunning != nil {
+		t.wg.Add(1)
+		go func() {
+			defer t.wg.Done()
+			t.reportLongRunningSpans(longRunningCheckInterval)
+		}()
+	}
 	t.stats.Start()
+	if t.dataStreams != nil {
+		t.dataStreams.Start()
+	}
 	return t
 }
 
@@ -274,16 +386,42 @@ func newTracer(opts ...StartOption) *tracer {
 // whereas the invokation can make use of Flush to ensure any created spans
 // reach the agent.
 func Flush() {
+	FlushContext(gocontext.Background())
+}
+
+// FlushContext flushes any buffered traces like Flush and waits for them to
+// be sent, or for ctx to be done, whichever happens first. It returns the error
+// of sending them, or the error of ctx when it is done first. It is of use in
+// serverless functions and cron jobs which exit right after their work is done.
+// It is a no-op returning nil if the tracer is not started.
+func FlushContext(ctx gocontext.Context) error {
 	if t, ok := internal.GetGlobalTracer().(*tracer); ok {
-		t.flushSync()
+		return t.flushSync(ctx)
 	}
+	return nil
 }
 
-// flushSync triggers a flush and waits for it to complete.
-func (t *tracer) flushSync() {
-	done := make(chan struct{})
-	t.flush <- done
-	<-done
+// flushSync triggers a flush and waits for it to complete or for ctx to be done.
+func (t *tracer) flushSync(ctx gocontext.Context) error {
+	// done is buffered so that the worker never blocks on a caller which gave up
+	done := make(chan error, 1)
+	select {
+	case t.flush <- done:
+	case <-t.stop:
+		// the worker flushed the buffered traces on its way out
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case err := <-done:
+		if t.dataStreams != nil {
+			t.dataStreams.Flush()
+		}
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 // worker receives finished traces to be added into the payload, as well
@@ -292,37 +430,34 @@ func (t *tracer) worker(tick <-chan time.Time) {
 	for {
 		select {
 		case trace := <-t.out:
-			t.sampleFinishedTrace(trace)
-			if len(trace.spans) != 0 {
-				t.traceWriter.add(trace.spans)
-			}
+			t.writeFinishedTrace(trace)
 		case <-tick:
 			t.config.statsd.Incr("datadog.tracer.flush_triggered", []string{"reason:scheduled"}, 1)
 			t.traceWriter.flush()
 
 		case done := <-t.flush:
 			t.config.statsd.Incr("datadog.tracer.flush_triggered", []string{"reason:invoked"}, 1)
-			t.traceWriter.flush()
-			// TODO(x): In reality, the traceWriter.flush() call is not synchronous
-			// when using the agent traceWriter. However, this functionnality is used
-			// in Lambda so for that purpose this mechanism should suffice.
-			done <- struct{}{}
+			// include the traces finished before the flush was requested
+			t.drainFinishedTraces()
+			sent := t.traceWriter.flush()
+			go func() { done <- <-sent }()
 
 		case <-t.stop:
-		loop:
-			// the loop ensures that the payload channel is fully drained
-			// before the final flush to ensure no traces are lost (see #526)
-			for {
-				select {
-				case trace := <-t.out:
-					t.sampleFinishedTrace(trace)
-					if len(trace.spans) != 0 {
-						t.traceWriter.add(trace.spans)
-					}
-				default:
-					break loop
-				}
-			}
+			// drain the payload channel before the final flush to ensure
+			// no traces are lost (see #526)
+			t.drainFinishedTraces()
+			return
+		}
+	}
+}
+
+// drainFinishedTraces adds the finished traces queued for the worker to the payload.
+func (t *tracer) drainFinishedTraces() {
+	for {
+		select {
+		case trace := <-t.out:
+			t.writeFinishedTrace(trace)
+		default:
 			return
 		}
 	}
@@ -332,6 +467,7 @@ func (t *tracer) worker(tick <-chan time.Time) {
 type finishedTrace struct {
 	spans    []*span
 	willSend bool // willSend indicates whether the trace will be sent to the agent.
+	partial  bool // partial indicates whether the spans are a chunk of a partially flushed trace.
 }
 
 // sampleFinishedTrace applies single-span sampling to the provided trace, which is considered to be finished.
@@ -364,6 +500,40 @@ func (t *tracer) sampleFinishedTrace(info *finishedTrace) {
 	}
 }
 
+// writeFinishedTrace samples trace, runs the post processors on its kept spans
+// and adds the spans they keep to the trace writer, which encodes them right
+// away. All the spans of trace are then released to spanPool when span pooling
+// is enabled, unless they were partially flushed: the unfinished spans of their
+// trace, and the trace itself, may still reference them; or unless they are
+// streamed: the payload holds them until it is sent.
+func (t *tracer) writeFinishedTrace(trace *finishedTrace) {
+	spans := trace.spans
+	t.sampleFinishedTrace(trace)
+	if len(trace.spans) != 0 && len(t.config.postProcessors) != 0 {
+		trace.spans = t.postProcess(trace.spans)
+	}
+	if len(trace.spans) != 0 {
+		t.traceWriter.add(trace.spans)
+	}
+	if t.config.spanPooling && !trace.partial && !t.config.streaming {
+		for _, s := range spans {
+			releaseSpan(s)
+		}
+	}
+}
+
+// newSpan returns an empty span with pre-sized tag maps, taken from spanPool
+// when span pooling is enabled.
+func (t *tracer) newSpan() *span {
+	if t.config.spanPooling {
+		return spanPool.Get().(*span)
+	}
+	return &span{
+		Meta:    make(map[string]string, initialMetaSize),
+		Metrics: make(map[string]float64, initialMetricsSize),
+	}
+}
+
 func (t *tracer) pushTrace(trace *finishedTrace) {
 	select {
 	case <-t.stop:
@@ -373,6 +543,7 @@ func (t *tracer) pushTrace(trace *finishedTrace) {
 	select {
 	case t.out <- trace:
 	default:
+		atomic.AddUint32(&t.spansDropped, uint32(len(trace.spans)))
 		log.Error("payload queue full, dropping %d traces", len(trace.spans))
 	}
 }
@@ -383,6 +554,9 @@ func (t *tracer) StartSpan(operationName string, options ...ddtrace.StartSpanOpt
 	for _, fn := range options {
 		fn(&opts)
 	}
+	if c, ok := opts.Tags[ext.Component].(string); ok && !t.config.integrations.Enabled(c) {
+		return internal.NewDisabledSpan(opts.Parent)
+	}
 	var startTime int64
 	if opts.StartTime.IsZero() {
 		startTime = now()
@@ -400,7 +574,7 @@ func (t *tracer) StartSpan(operationName string, options ...ddtrace.StartSpanOpt
 				// Inherit the context.Context from parent span if it was propagated
 				// using ChildOf() rather than StartSpanFromContext(), see
 				// applyPPROFLabels() below.
-				pprofContext = ctx.span.pprofCtxActive
+				pprofContext = ctx.pprofContext()
 			}
 		}
 	}
@@ -419,19 +593,19 @@ func (t *tracer) StartSpan(operationName string, options ...ddtrace.StartSpanOpt
 		id = generateSpanID(startTime)
 	}
 	// span defaults
-	span := &span{
-		Name:         operationName,
-		Service:      t.config.serviceName,
-		Resource:     operationName,
-		SpanID:       id,
-		TraceID:      id,
-		Start:        startTime,
-		taskEnd:      startExecutionTracerTask(operationName),
-		noDebugStack: t.config.noDebugStack,
-	}
+	span := t.newSpan()
+	span.Name = operationName
+	span.Service = t.config.serviceName
+	span.Resource = operationName
+	span.SpanID = id
+	span.TraceID = id
+	span.Start = startTime
+	span.taskEnd = startExecutionTracerTask(operationName)
+	span.noDebugStack = t.config.noDebugStack
 	if t.config.hostname != "" {
 		span.setMeta(keyHostname, t.config.hostname)
 	}
+	var parentService string
 	if context != nil {
 		// this is a child span
 		span.TraceID = context.traceID
@@ -441,9 +615,8 @@ func (t *tracer) StartSpan(operationName string, options ...ddtrace.StartSpanOpt
 		}
 		if context.span != nil {
 			// local parent, inherit service
-			context.span.RLock()
-			span.Service = context.span.Service
-			context.span.RUnlock()
+			parentService = context.serviceName()
+			span.Service = parentService
 		} else {
 			// remote parent
 			if context.origin != "" {
@@ -453,6 +626,10 @@ func (t *tracer) StartSpan(operationName string, options ...ddtrace.StartSpanOpt
 		}
 	}
 	span.context = newSpanContext(span, context)
+	if context == nil && t.config.traceID128BitEnabled {
+		// this is a new trace, generate the upper 64 bits of its trace ID
+		span.context.trace.setPropagatingTag(keyTraceID128, generateTraceIDHigh(startTime))
+	}
 	if context == nil || context.span == nil {
 		// this is either a root span or it has a remote parent, we should add the PID.
 		span.setMeta(ext.Pid, t.pid)
@@ -462,31 +639,41 @@ func (t *tracer) StartSpan(operationName string, options ...ddtrace.StartSpanOpt
 			span.setMeta("language", "go")
 		}
 	}
+	// copy the selected baggage items inherited from the parent
+	for _, k := range t.config.baggageTagKeys {
+		if v := span.context.baggageItem(k); v != "" {
+			span.setMeta(baggageTagPrefix+k, v)
+		}
+	}
 	// add tags from options
 	for k, v := range opts.Tags {
 		span.SetTag(k, v)
 	}
+	if len(opts.Links) > 0 {
+		span.links = append([]ddtrace.SpanLink(nil), opts.Links...)
+	}
 	// add global tags
 	for k, v := range t.config.globalTags {
 		span.SetTag(k, v)
 	}
-	if t.config.serviceMappings != nil {
-		if newSvc, ok := t.config.serviceMappings[span.Service]; ok {
+	gc := t.loadGlobalConfig()
+	if gc.serviceMappings != nil {
+		if newSvc, ok := gc.serviceMappings[span.Service]; ok {
 			span.Service = newSvc
 		}
 	}
-	if context == nil || context.span == nil || context.span.Service != span.Service {
+	if context == nil || context.span == nil || parentService != span.Service {
 		span.setMetric(keyTopLevel, 1)
 		// all top level spans are measured. So the measured tag is redundant.
 		delete(span.Metrics, keyMeasured)
 	}
-	if t.config.version != "" {
+	if gc.version != "" {
 		if t.config.universalVersion || (!t.config.universalVersion && span.Service == t.config.serviceName) {
-			span.setMeta(ext.Version, t.config.version)
+			span.setMeta(ext.Version, gc.version)
 		}
 	}
-	if t.config.env != "" {
-		span.setMeta(ext.Environment, t.config.env)
+	if gc.env != "" {
+		span.setMeta(ext.Environment, gc.env)
 	}
 	if _, ok := span.context.samplingPriority(); !ok {
 		// if not already sampled or a brand new trace, sample it
@@ -495,11 +682,15 @@ func (t *tracer) StartSpan(operationName string, options ...ddtrace.StartSpanOpt
 	if t.config.profilerHotspots || t.config.profilerEndpoints {
 		t.applyPPROFLabels(pprofContext, span)
 	}
-	if t.config.serviceMappings != nil {
-		if newSvc, ok := t.config.serviceMappings[span.Service]; ok {
+	if gc.serviceMappings != nil {
+		if newSvc, ok := gc.serviceMappings[span.Service]; ok {
 			span.Service = newSvc
 		}
 	}
+	span.context.setServiceName(span.Service)
+	if t.longRunning != nil {
+		t.longRunning.add(span, startTime)
+	}
 	if log.DebugEnabled() {
 		// avoid allocating the ...interface{} argument if debug logging is disabled
 		log.Debug("Started Span: %v, Operation: %s, Resource: %s, Tags: %v, %v",
@@ -515,6 +706,12 @@ func generateSpanID(startTime int64) uint64 {
 	return random.Uint64() ^ uint64(startTime)
 }
 
+// generateTraceIDHigh returns the upper 64 bits of a 128-bit trace ID starting at
+// startTime, in hexadecimal: its start time in seconds, followed by 32 zero bits.
+func generateTraceIDHigh(startTime int64) string {
+	return fmt.Sprintf("%08x00000000", uint32(startTime/int64(time.Second)))
+}
+
 // applyPPROFLabels applies pprof labels for the profiler's code hotspots and
 // endpoint filtering feature to span. When span finishes, any pprof labels
 // found in ctx are restored.
@@ -538,6 +735,7 @@ func (t *tracer) applyPPROFLabels(ctx gocontext.Context, span *span) {
 	if len(labels) > 0 {
 		span.pprofCtxRestore = ctx
 		span.pprofCtxActive = pprof.WithLabels(ctx, pprof.Labels(labels...))
+		span.context.setPprofContext(span.pprofCtxActive)
 		pprof.SetGoroutineLabels(span.pprofCtxActive)
 	}
 }
@@ -553,13 +751,18 @@ func spanResourcePIISafe(s *span) bool {
 func (t *tracer) Stop() {
 	t.stopOnce.Do(func() {
 		close(t.stop)
+		t.stopRemoteConfig()
 		t.config.statsd.Incr("datadog.tracer.stopped", nil, 1)
 	})
 	t.stats.Stop()
+	if t.dataStreams != nil {
+		t.dataStreams.Stop()
+	}
 	t.wg.Wait()
 	t.traceWriter.stop()
 	t.config.statsd.Close()
 	appsec.Stop()
+	debugger.Stop()
 }
 
 // Inject uses the configured or default TextMap Propagator.
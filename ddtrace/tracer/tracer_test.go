@@ -679,6 +679,27 @@ func TestTracerStartSpanOptions(t *testing.T) {
 	assert.Equal(1.0, span.Metrics[keyTopLevel])
 }
 
+func TestTracerStartSpanDisabledIntegration(t *testing.T) {
+	t.Setenv("DD_TRACE_DISABLED_INTEGRATIONS", "redis")
+	tracer := newTracer()
+	defer tracer.Stop()
+	assert := assert.New(t)
+
+	root := tracer.StartSpan("web.request", Tag(ext.Component, "http")).(*span)
+	disabled := tracer.StartSpan("redis.command", Tag(ext.Component, "redis"), ChildOf(root.Context()))
+	assert.IsType(&internal.DisabledSpan{}, disabled)
+	assert.Equal(root.Context(), disabled.Context())
+	disabled.SetTag("key", "value")
+	disabled.Finish()
+
+	child := tracer.StartSpan("child", ChildOf(disabled.Context())).(*span)
+	assert.Equal(root.SpanID, child.ParentID)
+	assert.Equal(root.TraceID, child.TraceID)
+
+	orphan := tracer.StartSpan("redis.command", Tag(ext.Component, "redis"))
+	assert.Equal(uint64(0), orphan.Context().TraceID())
+}
+
 func TestTracerStartChildSpan(t *testing.T) {
 	t.Run("own-service", func(t *testing.T) {
 		assert := assert.New(t)
@@ -729,6 +750,21 @@ func TestTracerBaggagePropagation(t *testing.T) {
 	assert.Equal("value", context.baggage["key"])
 }
 
+func TestTracerBaggageTags(t *testing.T) {
+	assert := assert.New(t)
+	tracer := newTracer(WithBaggageTagKeys("user.id", "missing"))
+	defer tracer.Stop()
+	root := tracer.StartSpan("web.request").(*span)
+	root.SetBaggageItem("user.id", "42")
+	root.SetBaggageItem("region", "us-east-1")
+	child := tracer.StartSpan("db.query", ChildOf(root.Context())).(*span)
+
+	assert.Equal("42", child.Meta[baggageTagPrefix+"user.id"])
+	assert.NotContains(child.Meta, baggageTagPrefix+"region")
+	assert.NotContains(child.Meta, baggageTagPrefix+"missing")
+	assert.NotContains(root.Meta, baggageTagPrefix+"user.id")
+}
+
 func TestStartSpanOrigin(t *testing.T) {
 	assert := assert.New(t)
 
@@ -1435,11 +1471,11 @@ func TestPushPayload(t *testing.T) {
 	s.Meta["key"] = strings.Repeat("X", payloadSizeLimit/2+10)
 
 	// half payload size reached
-	tracer.pushTrace(&finishedTrace{[]*span{s}, true})
+	tracer.pushTrace(&finishedTrace{spans: []*span{s}, willSend: true})
 	tracer.awaitPayload(t, 1)
 
 	// payload size exceeded
-	tracer.pushTrace(&finishedTrace{[]*span{s}, true})
+	tracer.pushTrace(&finishedTrace{spans: []*span{s}, willSend: true})
 	flush(2)
 }
 
@@ -1727,6 +1763,79 @@ func BenchmarkTracerAddSpans(b *testing.B) {
 	}
 }
 
+// BenchmarkTracerAddSpansPooled is BenchmarkTracerAddSpans with span pooling enabled.
+func BenchmarkTracerAddSpansPooled(b *testing.B) {
+	tracer, _, _, stop := startTestTracer(b, WithSampler(NewRateSampler(0)), WithSpanPooling(true))
+	defer stop()
+
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		span := tracer.StartSpan("pylons.request", ServiceName("pylons"), ResourceName("/"))
+		span.Finish()
+	}
+}
+
+func TestSpanPooling(t *testing.T) {
+	assert := assert.New(t)
+	tracer, transport, flush, stop := startTestTracer(t, WithSpanPooling(true))
+	defer stop()
+
+	root := tracer.StartSpan("root").(*span)
+	child := tracer.StartSpan("child", ChildOf(root.Context())).(*span)
+	assert.NotNil(root.Meta)
+	assert.NotNil(root.Metrics)
+	child.Finish()
+	root.Finish()
+	flush(1)
+
+	// the spans were encoded before being released
+	traces := transport.Traces()
+	assert.Len(traces, 1)
+	assert.Len(traces[0], 2)
+	assert.ElementsMatch([]string{"root", "child"}, []string{traces[0][0].Name, traces[0][1].Name})
+	root.RLock()
+	defer root.RUnlock()
+	assert.Empty(root.Name)
+	assert.Empty(root.Meta)
+	assert.NotNil(root.Meta)
+	assert.Nil(root.context)
+}
+
+func TestSpanPoolingPartialFlush(t *testing.T) {
+	assert := assert.New(t)
+	tracer, transport, flush, stop := startTestTracer(t, WithSpanPooling(true), WithPartialFlushing(1))
+	defer stop()
+
+	root := tracer.StartSpan("root", ServiceName("web")).(*span)
+	parent := tracer.StartSpan("parent", ChildOf(root.Context()), ServiceName("cache")).(*span)
+	child := tracer.StartSpan("child", ChildOf(parent.Context())).(*span)
+	parent.Finish()
+	flush(1)
+	traces := transport.Traces()
+	assert.Len(traces, 1)
+	assert.Equal("parent", traces[0][0].Name)
+
+	// the partially flushed parent is not released
+	parent.RLock()
+	assert.Equal("parent", parent.Name)
+	assert.Equal("cache", parent.Service)
+	parent.RUnlock()
+
+	// the spans started after the flush still inherit the service of the parent
+	late := tracer.StartSpan("late", ChildOf(parent.Context())).(*span)
+	assert.Equal("cache", late.Service)
+	assert.NotContains(late.Metrics, keyTopLevel)
+	grandchild := tracer.StartSpan("grandchild", ChildOf(child.Context())).(*span)
+	assert.Equal("cache", grandchild.Service)
+
+	grandchild.Finish()
+	late.Finish()
+	child.Finish()
+	root.Finish()
+	flush(4)
+	assert.Len(transport.Traces(), 4)
+}
+
 func BenchmarkStartSpan(b *testing.B) {
 	tracer, _, _, stop := startTestTracer(b, WithSampler(NewRateSampler(0)))
 	defer stop()
@@ -1910,11 +2019,14 @@ func (w *testTraceWriter) add(spans []*span) {
 	w.mu.Unlock()
 }
 
-func (w *testTraceWriter) flush() {
+func (w *testTraceWriter) flush() <-chan error {
 	w.mu.Lock()
 	w.flushed = append(w.flushed, w.buf...)
 	w.buf = w.buf[:0]
 	w.mu.Unlock()
+	sent := make(chan error, 1)
+	sent <- nil
+	return sent
 }
 
 func (w *testTraceWriter) stop() {}
@@ -1961,10 +2073,99 @@ loop:
 		}
 	}
 	assert.Len(t, tw.Flushed(), 0)
-	tr.flushSync()
+	assert.NoError(t, tr.flushSync(context.Background()))
 	assert.Len(t, tw.Flushed(), 1)
 }
 
+// blockingTransport is a dummyTransport whose sends block until unblock is
+// closed, and fail with err when it is not nil.
+type blockingTransport struct {
+	*dummyTransport
+	unblock chan struct{}
+	err     error
+}
+
+func (t *blockingTransport) send(p *payload) (io.ReadCloser, error) {
+	<-t.unblock
+	if t.err != nil {
+		return nil, t.err
+	}
+	return t.dummyTransport.send(p)
+}
+
+func TestFlushContext(t *testing.T) {
+	t.Run("sent", func(t *testing.T) {
+		tr, transport, _, stop := startTestTracer(t)
+		defer stop()
+		tr.StartSpan("op").Finish()
+		// the trace is sent by the time FlushContext returns
+		assert.NoError(t, FlushContext(context.Background()))
+		assert.Equal(t, 1, transport.Len())
+	})
+
+	t.Run("transport-error", func(t *testing.T) {
+		transport := &blockingTransport{
+			dummyTransport: newDummyTransport(),
+			unblock:        make(chan struct{}),
+			err:            errors.New("agent unreachable"),
+		}
+		close(transport.unblock)
+		tr, _, _, stop := startTestTracer(t, withTransport(transport))
+		defer stop()
+		tr.StartSpan("op").Finish()
+		assert.EqualError(t, FlushContext(context.Background()), "agent unreachable")
+	})
+
+	t.Run("deadline", func(t *testing.T) {
+		transport := &blockingTransport{
+			dummyTransport: newDummyTransport(),
+			unblock:        make(chan struct{}),
+		}
+		tr, _, _, stop := startTestTracer(t, withTransport(transport))
+		defer stop()
+		defer close(transport.unblock)
+		tr.StartSpan("op").Finish()
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		assert.Equal(t, context.DeadlineExceeded, FlushContext(ctx))
+	})
+
+	t.Run("stopped", func(t *testing.T) {
+		tr, _, _, stop := startTestTracer(t)
+		stop()
+		assert.NoError(t, tr.flushSync(context.Background()))
+	})
+
+	t.Run("not-started", func(t *testing.T) {
+		assert.NoError(t, FlushContext(context.Background()))
+	})
+}
+
+func TestStopContext(t *testing.T) {
+	t.Run("stopped", func(t *testing.T) {
+		tr, transport, _, _ := startTestTracer(t)
+		tr.StartSpan("op").Finish()
+		assert.NoError(t, StopContext(context.Background()))
+		assert.Equal(t, 1, transport.Len())
+	})
+
+	t.Run("deadline", func(t *testing.T) {
+		transport := &blockingTransport{
+			dummyTransport: newDummyTransport(),
+			unblock:        make(chan struct{}),
+		}
+		tr, _, _, _ := startTestTracer(t, withTransport(transport))
+		tr.StartSpan("op").Finish()
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		assert.Equal(t, context.DeadlineExceeded, StopContext(ctx))
+
+		// the shutdown completes in the background
+		close(transport.unblock)
+		assert.Eventually(t, func() bool { return transport.Len() == 1 }, time.Second, time.Millisecond)
+	})
+}
+
 func TestTakeStackTrace(t *testing.T) {
 	t.Run("n=12", func(t *testing.T) {
 		val := takeStacktrace(12, 0)
@@ -2077,6 +2278,43 @@ func TestUserMonitoring(t *testing.T) {
 	})
 }
 
+func TestSetTraceTag(t *testing.T) {
+	tr, _, _, stop := startTestTracer(t, WithPropagatedTraceTags("customer.tier"))
+	defer stop()
+
+	t.Run("root", func(t *testing.T) {
+		assert := assert.New(t)
+		root, ctx := StartSpanFromContext(context.Background(), "root")
+		child, ctx := StartSpanFromContext(ctx, "child")
+		SetTraceTag(ctx, "region", "eu")
+		SetTraceTag(ctx, "retries", 3)
+		child.Finish()
+		root.Finish()
+		assert.Equal("eu", root.(*span).Meta["region"])
+		assert.Equal(3., root.(*span).Metrics["retries"])
+		assert.NotContains(child.(*span).Meta, "region")
+		assert.NotContains(root.(*span).context.trace.propagatingTags, "_dd.p.region")
+	})
+
+	t.Run("propagated", func(t *testing.T) {
+		assert := assert.New(t)
+		root, ctx := StartSpanFromContext(context.Background(), "root")
+		child, ctx := StartSpanFromContext(ctx, "child")
+		SetTraceTag(ctx, "customer.tier", "enterprise")
+		assert.Equal("enterprise", root.(*span).Meta["customer.tier"])
+
+		carrier := TextMapCarrier{}
+		assert.NoError(tr.Inject(child.Context(), carrier))
+		assert.Contains(carrier[traceTagsHeader], "_dd.p.customer.tier=enterprise")
+		child.Finish()
+		root.Finish()
+	})
+
+	t.Run("no-span", func(t *testing.T) {
+		SetTraceTag(context.Background(), "region", "eu")
+	})
+}
+
 // BenchmarkTracerStackFrames tests the performance of taking stack trace.
 func BenchmarkTracerStackFrames(b *testing.B) {
 	tracer, _, _, stop := startTestTracer(b, WithSampler(NewRateSampler(0)))
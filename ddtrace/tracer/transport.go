@@ -72,10 +72,15 @@ type transport interface {
 }
 
 type httpTransport struct {
-	traceURL string            // the delivery URL for traces
-	statsURL string            // the delivery URL for stats
-	client   *http.Client      // the HTTP client used in the POST
-	headers  map[string]string // the Transport headers
+	traceURL    string            // the delivery URL for traces
+	traceURLV07 string            // the delivery URL for traces sent with the v0.7 protocol
+	statsURL    string            // the delivery URL for stats
+	client      *http.Client      // the HTTP client used in the POST
+	headers     map[string]string // the Transport headers
+
+	// additional reports whether the transport sends to an additional agent,
+	// see WithAdditionalAgent, which leaves the dropped traces to the main one.
+	additional bool
 }
 
 // newTransport returns a new Transport implementation that sends traces to a
@@ -101,10 +106,11 @@ func newHTTPTransport(addr string, client *http.Client) *httpTransport {
 		defaultHeaders["Datadog-Container-ID"] = cid
 	}
 	return &httpTransport{
-		traceURL: fmt.Sprintf("http://%s/v0.4/traces", addr),
-		statsURL: fmt.Sprintf("http://%s/v0.6/stats", addr),
-		client:   client,
-		headers:  defaultHeaders,
+		traceURL:    fmt.Sprintf("http://%s/v0.4/traces", addr),
+		traceURLV07: fmt.Sprintf("http://%s/v0.7/traces", addr),
+		statsURL:    fmt.Sprintf("http://%s/v0.6/stats", addr),
+		client:      client,
+		headers:     defaultHeaders,
 	}
 }
 
@@ -137,35 +143,58 @@ func (t *httpTransport) sendStats(p *statsPayload) error {
 }
 
 func (t *httpTransport) send(p *payload) (body io.ReadCloser, err error) {
-	req, err := http.NewRequest("POST", t.traceURL, p)
+	url := t.traceURL
+	if p.protocol == traceProtocolV07 {
+		url = t.traceURLV07
+	}
+	req, err := http.NewRequest("POST", url, p)
 	if err != nil {
 		return nil, fmt.Errorf("cannot create http request: %v", err)
 	}
+	if p.streaming {
+		// the size of the streamed payloads is only known once they are encoded
+		req.ContentLength = -1
+		req.TransferEncoding = []string{"chunked"}
+	} else {
+		req.Header.Set("Content-Length", strconv.Itoa(p.size()))
+	}
+	return t.do(req, p.itemCount())
+}
+
+// do sets the tracer headers on the given request, sending count traces, sends it to
+// the agent and returns the response body.
+func (t *httpTransport) do(req *http.Request, count int) (body io.ReadCloser, err error) {
 	for header, value := range t.headers {
 		req.Header.Set(header, value)
 	}
-	req.Header.Set(traceCountHeader, strconv.Itoa(p.itemCount()))
-	req.Header.Set("Content-Length", strconv.Itoa(p.size()))
+	req.Header.Set(traceCountHeader, strconv.Itoa(count))
 	req.Header.Set(headerComputedTopLevel, "yes")
-	if t, ok := traceinternal.GetGlobalTracer().(*tracer); ok {
-		if t.config.canComputeStats() {
+	tr, _ := traceinternal.GetGlobalTracer().(*tracer)
+	if tr != nil {
+		if tr.config.canComputeStats() {
 			req.Header.Set("Datadog-Client-Computed-Stats", "yes")
 		}
-		droppedTraces := int(atomic.SwapUint32(&t.droppedP0Traces, 0))
-		partialTraces := int(atomic.SwapUint32(&t.partialTraces, 0))
-		droppedSpans := int(atomic.SwapUint32(&t.droppedP0Spans, 0))
-		if stats := t.config.statsd; stats != nil {
-			stats.Count("datadog.tracer.dropped_p0_traces", int64(droppedTraces),
-				[]string{fmt.Sprintf("partial:%s", strconv.FormatBool(partialTraces > 0))}, 1)
-			stats.Count("datadog.tracer.dropped_p0_spans", int64(droppedSpans), nil, 1)
+		if !t.additional {
+			droppedTraces := int(atomic.SwapUint32(&tr.droppedP0Traces, 0))
+			partialTraces := int(atomic.SwapUint32(&tr.partialTraces, 0))
+			droppedSpans := int(atomic.SwapUint32(&tr.droppedP0Spans, 0))
+			if stats := tr.config.statsd; stats != nil {
+				stats.Count("datadog.tracer.dropped_p0_traces", int64(droppedTraces),
+					[]string{fmt.Sprintf("partial:%s", strconv.FormatBool(partialTraces > 0))}, 1)
+				stats.Count("datadog.tracer.dropped_p0_spans", int64(droppedSpans), nil, 1)
+			}
+			req.Header.Set("Datadog-Client-Dropped-P0-Traces", strconv.Itoa(droppedTraces))
+			req.Header.Set("Datadog-Client-Dropped-P0-Spans", strconv.Itoa(droppedSpans))
 		}
-		req.Header.Set("Datadog-Client-Dropped-P0-Traces", strconv.Itoa(droppedTraces))
-		req.Header.Set("Datadog-Client-Dropped-P0-Spans", strconv.Itoa(droppedSpans))
 	}
 	response, err := t.client.Do(req)
 	if err != nil {
 		return nil, err
 	}
+	if tr != nil && !t.additional {
+		// the capabilities of the agent may have changed, e.g. after a restart
+		tr.config.refreshAgentFeatures(response.Header.Get(headerAgentState))
+	}
 	if code := response.StatusCode; code >= 400 {
 		// error, check the body for context information and
 		// return a nice error.
@@ -174,17 +203,68 @@ func (t *httpTransport) send(p *payload) (body io.ReadCloser, err error) {
 		response.Body.Close()
 		txt := http.StatusText(code)
 		if n > 0 {
-			return nil, fmt.Errorf("%s (Status: %s)", msg[:n], txt)
+			return nil, &apiError{statusCode: code, msg: fmt.Sprintf("%s (Status: %s)", msg[:n], txt)}
 		}
-		return nil, fmt.Errorf("%s", txt)
+		return nil, &apiError{statusCode: code, msg: txt}
 	}
 	return response.Body, nil
 }
 
+// apiError is returned when the agent responds to a payload with an error status code.
+type apiError struct {
+	statusCode int
+	msg        string
+}
+
+func (e *apiError) Error() string { return e.msg }
+
 func (t *httpTransport) endpoint() string {
 	return t.traceURL
 }
 
+// streamingTransport is a transport streaming the payloads to the agent with chunked
+// transfer encoding: the HTTP client writes each payload in chunks as it reads them
+// from the payload, which encodes its traces through a pipe as it is read when it is
+// streamed, see newStreamingPayload. The response
+// bodies are fully read and closed before being returned, so that the keep-alive
+// connections go back to the idle pool of the client as soon as a payload is sent,
+// to be reused by the following flushes. See WithStreamingTransport.
+type streamingTransport struct {
+	*httpTransport
+}
+
+var _ transport = (*streamingTransport)(nil)
+
+// newStreamingTransport returns a new streamingTransport which sends traces to the
+// trace agent at the given address, using the given *http.Client.
+func newStreamingTransport(addr string, client *http.Client) *streamingTransport {
+	return &streamingTransport{httpTransport: newHTTPTransport(addr, client)}
+}
+
+func (t *streamingTransport) send(p *payload) (body io.ReadCloser, err error) {
+	url := t.traceURL
+	if p.protocol == traceProtocolV07 {
+		url = t.traceURLV07
+	}
+	req, err := http.NewRequest("POST", url, p)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create http request: %v", err)
+	}
+	req.ContentLength = -1
+	req.TransferEncoding = []string{"chunked"}
+	rc, err := t.do(req, p.itemCount())
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	// read the whole (small) response so that the connection can be reused
+	resp, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(resp)), nil
+}
+
 // resolveAgentAddr resolves the given agent address and fills in any missing host
 // and port using the defaults. Some environment variable settings will
 // take precedence over configuration.
@@ -16,9 +16,11 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/tinylib/msgp/msgp"
 )
 
 // getTestSpan returns a Span with different fields set
@@ -140,6 +142,9 @@ func TestTransportResponse(t *testing.T) {
 			rc, err := transport.send(newPayload())
 			if tt.err != "" {
 				assert.Equal(tt.err, err.Error())
+				var apiErr *apiError
+				assert.ErrorAs(err, &apiErr)
+				assert.Equal(tt.status, apiErr.statusCode)
 				return
 			}
 			assert.NoError(err)
@@ -151,6 +156,113 @@ func TestTransportResponse(t *testing.T) {
 	}
 }
 
+func TestTransportProtocol(t *testing.T) {
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+	}))
+	defer srv.Close()
+	transport := newHTTPTransport(strings.TrimPrefix(srv.URL, "http://"), defaultClient)
+	for _, protocol := range []string{traceProtocolV04, traceProtocolV07} {
+		p := newProtocolPayload(nil, protocol, nil)
+		rc, err := transport.send(p)
+		assert.NoError(t, err)
+		rc.Close()
+	}
+	assert.Equal(t, []string{"/v0.4/traces", "/v0.7/traces"}, paths)
+}
+
+func TestStreamingTransport(t *testing.T) {
+	assert := assert.New(t)
+	var (
+		mu          sync.Mutex
+		conns       = make(map[string]bool)
+		decoded     int
+		counts      []string
+		transferEnc []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var traces spanLists
+		err := msgp.Decode(r.Body, &traces)
+		assert.NoError(err)
+		mu.Lock()
+		conns[r.RemoteAddr] = true
+		decoded += len(traces)
+		counts = append(counts, r.Header.Get(traceCountHeader))
+		transferEnc = r.TransferEncoding
+		mu.Unlock()
+		w.Write([]byte(`{"rate_by_service":{"service:,env:":0.5}}`))
+	}))
+	defer srv.Close()
+
+	transport := newStreamingTransport(strings.TrimPrefix(srv.URL, "http://"), defaultClient)
+	for i := 0; i < 3; i++ {
+		p := newStreamingPayload(traceProtocolV04, nil)
+		for _, trace := range getTestTrace(100, 10) {
+			assert.NoError(p.push(trace))
+		}
+		rc, err := transport.send(p)
+		p.Close()
+		assert.NoError(err)
+		slurp, err := io.ReadAll(rc)
+		assert.NoError(err)
+		assert.Equal(`{"rate_by_service":{"service:,env:":0.5}}`, string(slurp))
+	}
+	assert.Equal(300, decoded)
+	assert.Equal([]string{"100", "100", "100"}, counts)
+	assert.Equal([]string{"chunked"}, transferEnc)
+	assert.Len(conns, 1, "the connection should be reused across flushes")
+}
+
+func TestStreamingTransportError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		w.Write([]byte("too large"))
+	}))
+	defer srv.Close()
+
+	transport := newStreamingTransport(strings.TrimPrefix(srv.URL, "http://"), defaultClient)
+	p, err := encode(getTestTrace(1, 1))
+	assert.NoError(t, err)
+	_, err = transport.send(p)
+	var apiErr *apiError
+	assert.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, apiErr.statusCode)
+	assert.EqualError(t, err, "too large (Status: Request Entity Too Large)")
+}
+
+func TestWithStreamingTransport(t *testing.T) {
+	t.Run("default", func(t *testing.T) {
+		c := newConfig()
+		assert.False(t, c.streaming)
+		assert.IsType(t, &httpTransport{}, c.transport)
+		w := newAgentTraceWriter(c, nil, nil)
+		assert.False(t, w.payload.streaming)
+	})
+
+	t.Run("option", func(t *testing.T) {
+		c := newConfig(WithStreamingTransport(true))
+		assert.True(t, c.streaming)
+		assert.IsType(t, &streamingTransport{}, c.transport)
+		w := newAgentTraceWriter(c, nil, nil)
+		assert.True(t, w.payload.streaming)
+	})
+
+	t.Run("v0.7", func(t *testing.T) {
+		c := newConfig(WithStreamingTransport(true))
+		c.agent.V07 = true
+		assert.Equal(t, traceProtocolV07, c.currentTraceProtocol())
+	})
+
+	t.Run("env", func(t *testing.T) {
+		t.Setenv("DD_TRACE_STREAMING_ENABLED", "true")
+		c := newConfig()
+		assert.True(t, c.streaming)
+		assert.IsType(t, &streamingTransport{}, c.transport)
+	})
+}
+
 func TestTraceCountHeader(t *testing.T) {
 	assert := assert.New(t)
 
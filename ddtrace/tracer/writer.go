@@ -11,6 +11,7 @@ import (
 	"errors"
 	"io"
 	"math"
+	"net/http"
 	"os"
 	"strconv"
 	"sync"
@@ -23,8 +24,9 @@ type traceWriter interface {
 	// add adds traces to be sent by the writer.
 	add([]*span)
 
-	// flush causes the writer to send any buffered traces.
-	flush()
+	// flush causes the writer to send any buffered traces. The returned channel
+	// receives the error of sending them, or nil, once they are sent.
+	flush() <-chan error
 
 	// stop gracefully shuts down the writer.
 	stop()
@@ -37,6 +39,14 @@ type agentTraceWriter struct {
 	// payload encodes and buffers traces in msgpack format
 	payload *payload
 
+	// buffers pools the buffers of the payloads across flushes
+	buffers *bufferPool
+
+	// prefix holds the metadata of the tracer beginning the v0.7 payloads,
+	// see tracerPayloadPrefix, as of the settings prefixConfig.
+	prefix       []byte
+	prefixConfig *globalConfig
+
 	// climit limits the number of concurrent outgoing connections
 	climit chan struct{}
 
@@ -46,15 +56,34 @@ type agentTraceWriter struct {
 	// prioritySampling is the prioritySampler into which agentTraceWriter will
 	// read sampling rates sent by the agent
 	prioritySampling *prioritySampler
+
+	// health records the failed flushes, it may be nil.
+	health *healthMetrics
 }
 
-func newAgentTraceWriter(c *config, s *prioritySampler) *agentTraceWriter {
-	return &agentTraceWriter{
+func newAgentTraceWriter(c *config, s *prioritySampler, hm *healthMetrics) *agentTraceWriter {
+	h := &agentTraceWriter{
 		config:           c,
-		payload:          newPayload(),
+		buffers:          newBufferPool(c.flushBufferSize),
 		climit:           make(chan struct{}, concurrentConnectionLimit),
 		prioritySampling: s,
+		health:           hm,
 	}
+	h.payload = h.newPayload()
+	return h
+}
+
+// newPayload returns a new payload to encode the traces into, whose metadata are
+// the ones of the settings currently in effect.
+func (h *agentTraceWriter) newPayload() *payload {
+	if gc := h.config.loadGlobalConfig(); gc != h.prefixConfig {
+		h.prefix = tracerPayloadPrefix(h.config, gc)
+		h.prefixConfig = gc
+	}
+	if h.config.streaming {
+		return newStreamingPayload(h.config.currentTraceProtocol(), h.prefix)
+	}
+	return newProtocolPayload(h.buffers, h.config.currentTraceProtocol(), h.prefix)
 }
 
 func (h *agentTraceWriter) add(trace []*span) {
@@ -72,17 +101,22 @@ func (h *agentTraceWriter) stop() {
 	h.config.statsd.Incr("datadog.tracer.flush_triggered", []string{"reason:shutdown"}, 1)
 	h.flush()
 	h.wg.Wait()
+	if t, ok := h.config.transport.(*fanOutTransport); ok {
+		t.stop()
+	}
 }
 
 // flush will push any currently buffered traces to the server.
-func (h *agentTraceWriter) flush() {
+func (h *agentTraceWriter) flush() <-chan error {
+	sent := make(chan error, 1)
 	if h.payload.itemCount() == 0 {
-		return
+		sent <- nil
+		return sent
 	}
 	h.wg.Add(1)
 	h.climit <- struct{}{}
 	oldp := h.payload
-	h.payload = newPayload()
+	h.payload = h.newPayload()
 	go func(p *payload) {
 		defer func(start time.Time) {
 			<-h.climit
@@ -91,18 +125,103 @@ func (h *agentTraceWriter) flush() {
 		}(time.Now())
 		size, count := p.size(), p.itemCount()
 		log.Debug("Sending payload: size: %d traces: %d\n", size, count)
-		rc, err := h.config.transport.send(p)
-		if err != nil {
-			h.config.statsd.Count("datadog.tracer.traces_dropped", int64(count), []string{"reason:send_failed"}, 1)
-			log.Error("lost %d traces: %v", count, err)
-		} else {
+		if t, ok := h.config.transport.(*fanOutTransport); ok {
+			t.ship(p)
+		}
+		rc, dropped, err := h.send(p)
+		p.Close()
+		if dropped > 0 {
+			h.health.recordFlushError(err)
+			log.Error("lost %d traces: %v", dropped, err)
+		}
+		if rc != nil {
 			h.config.statsd.Count("datadog.tracer.flush_bytes", int64(size), nil, 1)
-			h.config.statsd.Count("datadog.tracer.flush_traces", int64(count), nil, 1)
+			h.config.statsd.Count("datadog.tracer.flush_traces", int64(count-dropped), nil, 1)
 			if err := h.prioritySampling.readRatesJSON(rc); err != nil {
 				h.config.statsd.Incr("datadog.tracer.decode_error", nil, 1)
 			}
 		}
+		sent <- err
 	}(oldp)
+	return sent
+}
+
+// maxSendAttempts is the maximum number of attempts to send a payload rejected
+// by the agent with a retryable status code.
+const maxSendAttempts = 3
+
+// sendRetryInterval is the interval before the first retry of a payload, doubled
+// before every following one; replaced in tests.
+var sendRetryInterval = 100 * time.Millisecond
+
+// send sends the traces of p to the agent. The payloads rejected by the agent with
+// the 429 or 5xx status codes are sent again after an exponential backoff, up to
+// maxSendAttempts times, and the ones rejected with the 413 status code are split
+// in two halves, sent separately. It returns the response of the agent to the last
+// payload it accepted, if any, along with the number of traces which could not be
+// sent and the last error. The caller remains responsible for closing p.
+func (h *agentTraceWriter) send(p *payload) (rc io.ReadCloser, dropped int, err error) {
+	count := p.itemCount()
+	for attempt := 1; ; attempt++ {
+		rc, err = h.config.transport.send(p.clone())
+		if err == nil {
+			return rc, 0, nil
+		}
+		var apiErr *apiError
+		if !errors.As(err, &apiErr) {
+			break
+		}
+		if apiErr.statusCode == http.StatusRequestEntityTooLarge {
+			return h.split(p, err)
+		}
+		if attempt == maxSendAttempts || (apiErr.statusCode != http.StatusTooManyRequests && apiErr.statusCode < 500) {
+			break
+		}
+		h.config.statsd.Count("datadog.tracer.traces_retried", int64(count), []string{"status_code:" + strconv.Itoa(apiErr.statusCode)}, 1)
+		log.Debug("Retrying to send payload of %d traces: %v", count, err)
+		time.Sleep(sendRetryInterval << (attempt - 1))
+	}
+	h.config.statsd.Count("datadog.tracer.traces_dropped", int64(count), []string{"reason:send_failed"}, 1)
+	return nil, count, err
+}
+
+// split sends the traces of p, rejected by the agent as too large with cause, in
+// two payloads. The traces rejected on their own are dropped.
+func (h *agentTraceWriter) split(p *payload, cause error) (rc io.ReadCloser, dropped int, err error) {
+	count := p.itemCount()
+	if count < 2 {
+		h.config.statsd.Count("datadog.tracer.traces_dropped", int64(count), []string{"reason:payload_too_large"}, 1)
+		return nil, count, cause
+	}
+	traces, decodeErr := p.traces()
+	if decodeErr != nil {
+		h.config.statsd.Count("datadog.tracer.traces_dropped", int64(count), []string{"reason:encoding_error"}, 1)
+		return nil, count, decodeErr
+	}
+	h.config.statsd.Incr("datadog.tracer.payload_split", nil, 1)
+	log.Debug("Splitting payload of %d traces: %v", count, cause)
+	for _, half := range []spanLists{traces[:count/2], traces[count/2:]} {
+		hp := newProtocolPayload(nil, p.protocol, p.prefix)
+		for _, t := range half {
+			if err := hp.push(t); err != nil {
+				// the traces were just decoded from the same encoding
+				log.Error("Error encoding msgpack: %v", err)
+			}
+		}
+		hrc, hdropped, herr := h.send(hp)
+		hp.Close()
+		dropped += hdropped
+		if herr != nil {
+			err = herr
+		}
+		if hrc != nil {
+			if rc != nil {
+				rc.Close()
+			}
+			rc = hrc
+		}
+	}
+	return rc, dropped, err
 }
 
 // logWriter specifies the output target of the logTraceWriter; replaced in tests.
@@ -308,11 +427,15 @@ func (h *logTraceWriter) stop() {
 }
 
 // flush will write any buffered traces to standard output.
-func (h *logTraceWriter) flush() {
+func (h *logTraceWriter) flush() <-chan error {
+	sent := make(chan error, 1)
 	if !h.hasTraces {
-		return
+		sent <- nil
+		return sent
 	}
 	h.buf.WriteString(logBufferSuffix)
-	h.w.Write(h.buf.Bytes())
+	_, err := h.w.Write(h.buf.Bytes())
 	h.resetBuffer()
+	sent <- err
+	return sent
 }
@@ -11,8 +11,13 @@ import (
 	"fmt"
 	"io"
 	"math"
+	"net/http"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/DataDog/datadog-go/v5/statsd"
 	"github.com/stretchr/testify/assert"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
@@ -23,6 +28,34 @@ func TestImplementsTraceWriter(t *testing.T) {
 	assert.Implements(t, (*traceWriter)(nil), &logTraceWriter{})
 }
 
+func TestWithFlushBufferSize(t *testing.T) {
+	c := newConfig()
+	assert.Zero(t, c.flushBufferSize)
+	c = newConfig(WithFlushBufferSize(-1))
+	assert.Zero(t, c.flushBufferSize)
+	c = newConfig(WithFlushBufferSize(1 << 20))
+	assert.Equal(t, 1<<20, c.flushBufferSize)
+	w := newAgentTraceWriter(c, nil, nil)
+	assert.Equal(t, 1<<20, w.payload.buf.Cap())
+}
+
+func TestAgentTraceWriterPrefix(t *testing.T) {
+	c := newConfig(WithEnv("prod"), WithServiceVersion("1.0"), withNoopStats())
+	c.globalConfig.Store(localGlobalConfig(c))
+	w := newAgentTraceWriter(c, nil, nil)
+	assert.Equal(t, tracerPayloadPrefix(c, localGlobalConfig(c)), w.prefix)
+	prefix := w.prefix
+
+	// the prefix is reused while the settings are unchanged
+	w.payload = w.newPayload()
+	assert.Same(t, &prefix[0], &w.prefix[0])
+
+	c.globalConfig.Store(&globalConfig{env: "staging", version: "1.1"})
+	w.payload = w.newPayload()
+	assert.NotEqual(t, prefix, w.prefix)
+	assert.Equal(t, tracerPayloadPrefix(c, &globalConfig{env: "staging", version: "1.1"}), w.prefix)
+}
+
 // makeSpan returns a span, adding n entries to meta and metrics each.
 func makeSpan(n int) *span {
 	s := newSpan("encodeName", "encodeService", "encodeResource", random.Uint64(), random.Uint64(), random.Uint64())
@@ -313,3 +346,157 @@ func BenchmarkJsonEncodeFloat(b *testing.B) {
 		encodeFloat(bs, float64(1e-9))
 	}
 }
+
+// discardTransport reads and closes the payloads it sends, as the HTTP
+// transport does, and discards them.
+type discardTransport struct{}
+
+func (discardTransport) send(p *payload) (io.ReadCloser, error) {
+	defer p.Close()
+	if _, err := io.Copy(io.Discard, p); err != nil {
+		return nil, err
+	}
+	return io.NopCloser(strings.NewReader("OK")), nil
+}
+
+func (discardTransport) sendStats(*statsPayload) error { return nil }
+
+func (discardTransport) endpoint() string { return "http://localhost:8126/v0.4/traces" }
+
+// statusTransport rejects the payloads it sends with the status code returned
+// by reject for the number of traces they hold, if not 0, and records the number
+// of traces of the payloads it accepts.
+type statusTransport struct {
+	reject func(attempt, count int) int
+
+	mu       sync.Mutex
+	attempts int
+	accepted []int
+}
+
+func (t *statusTransport) send(p *payload) (io.ReadCloser, error) {
+	defer p.Close()
+	traces, err := decode(p)
+	if err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.attempts++
+	if code := t.reject(t.attempts, len(traces)); code != 0 {
+		return nil, &apiError{statusCode: code, msg: http.StatusText(code)}
+	}
+	t.accepted = append(t.accepted, len(traces))
+	return io.NopCloser(strings.NewReader("{}")), nil
+}
+
+func (t *statusTransport) sendStats(*statsPayload) error { return nil }
+
+func (t *statusTransport) endpoint() string { return "http://localhost:8126/v0.4/traces" }
+
+func TestAgentTraceWriterRetry(t *testing.T) {
+	defer func(old time.Duration) { sendRetryInterval = old }(sendRetryInterval)
+	sendRetryInterval = time.Millisecond
+
+	flush := func(tp *statusTransport, n int) *testStatsdClient {
+		var tg testStatsdClient
+		c := newConfig(withTransport(tp))
+		c.statsd = &tg
+		w := newAgentTraceWriter(c, newPrioritySampler(), nil)
+		for i := 0; i < n; i++ {
+			w.add([]*span{makeSpan(0)})
+		}
+		w.flush()
+		w.wg.Wait()
+		return &tg
+	}
+
+	for _, code := range []int{http.StatusTooManyRequests, http.StatusServiceUnavailable} {
+		t.Run(fmt.Sprintf("%d", code), func(t *testing.T) {
+			tp := &statusTransport{reject: func(attempt, _ int) int {
+				if attempt < maxSendAttempts {
+					return code
+				}
+				return 0
+			}}
+			tg := flush(tp, 5)
+			assert.Equal(t, maxSendAttempts, tp.attempts)
+			assert.Equal(t, []int{5}, tp.accepted)
+			counts := tg.Counts()
+			assert.Equal(t, int64(5*(maxSendAttempts-1)), counts["datadog.tracer.traces_retried"])
+			assert.Equal(t, int64(5), counts["datadog.tracer.flush_traces"])
+			assert.Zero(t, counts["datadog.tracer.traces_dropped"])
+		})
+	}
+
+	t.Run("exhausted", func(t *testing.T) {
+		tp := &statusTransport{reject: func(_, _ int) int { return http.StatusInternalServerError }}
+		tg := flush(tp, 5)
+		assert.Equal(t, maxSendAttempts, tp.attempts)
+		assert.Empty(t, tp.accepted)
+		counts := tg.Counts()
+		assert.Equal(t, int64(5*(maxSendAttempts-1)), counts["datadog.tracer.traces_retried"])
+		assert.Equal(t, int64(5), counts["datadog.tracer.traces_dropped"])
+		assert.Zero(t, counts["datadog.tracer.flush_traces"])
+	})
+
+	t.Run("not-retryable", func(t *testing.T) {
+		tp := &statusTransport{reject: func(_, _ int) int { return http.StatusBadRequest }}
+		tg := flush(tp, 5)
+		assert.Equal(t, 1, tp.attempts)
+		counts := tg.Counts()
+		assert.Zero(t, counts["datadog.tracer.traces_retried"])
+		assert.Equal(t, int64(5), counts["datadog.tracer.traces_dropped"])
+	})
+
+	t.Run("split", func(t *testing.T) {
+		// the agent accepts payloads of at most 2 traces
+		tp := &statusTransport{reject: func(_, count int) int {
+			if count > 2 {
+				return http.StatusRequestEntityTooLarge
+			}
+			return 0
+		}}
+		tg := flush(tp, 5)
+		assert.Equal(t, []int{2, 1, 2}, tp.accepted)
+		counts := tg.Counts()
+		assert.Equal(t, int64(2), counts["datadog.tracer.payload_split"])
+		assert.Equal(t, int64(5), counts["datadog.tracer.flush_traces"])
+		assert.Zero(t, counts["datadog.tracer.traces_dropped"])
+	})
+
+	t.Run("too-large", func(t *testing.T) {
+		tp := &statusTransport{reject: func(_, _ int) int { return http.StatusRequestEntityTooLarge }}
+		tg := flush(tp, 2)
+		assert.Empty(t, tp.accepted)
+		counts := tg.Counts()
+		assert.Equal(t, int64(1), counts["datadog.tracer.payload_split"])
+		assert.Equal(t, int64(2), counts["datadog.tracer.traces_dropped"])
+		for _, c := range tg.CountCalls() {
+			if c.name == "datadog.tracer.traces_dropped" {
+				assert.Equal(t, []string{"reason:payload_too_large"}, c.tags)
+			}
+		}
+	})
+}
+
+// BenchmarkAgentTraceWriterFlush benchmarks the encoding of traces by the agent
+// trace writer across flushes of payloads of approximately 1MB.
+func BenchmarkAgentTraceWriterFlush(b *testing.B) {
+	c := newConfig(withTransport(discardTransport{}))
+	c.statsd = &statsd.NoOpClient{}
+	w := newAgentTraceWriter(c, newPrioritySampler(), nil)
+	trace := make([]*span, 10)
+	for i := range trace {
+		trace[i] = makeSpan(10)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for w.payload.size() < 1<<20 {
+			w.add(trace)
+		}
+		w.flush()
+		w.wg.Wait()
+	}
+}
@@ -0,0 +1,89 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022 Datadog, Inc.
+
+// Package debugger provides the hooks of Dynamic Instrumentation, which
+// captures snapshots of the calls of the functions on which probes are
+// installed from the Datadog UI, without redeploying the application.
+//
+// Rather than patching the machine code of the functions, which would require
+// their offsets in the binary, the functions are instrumented at compile time,
+// either manually or by code generation tools, with a call to Enter at their
+// entry, and to Call.Exit upon return:
+//
+//	func handle(ctx context.Context, id string) (err error) {
+//		call := debugger.Enter(ctx, "main.handle", debugger.V("id", id))
+//		defer func() { call.Exit(debugger.V("err", err)) }()
+//		// ...
+//	}
+//
+// The hooks are cheap when no probe is installed on the function. Dynamic
+// Instrumentation is started by tracer.Start when the environment variable
+// DD_DYNAMIC_INSTRUMENTATION_ENABLED is set to true, the probes being received
+// through remote configuration.
+package debugger
+
+import (
+	"context"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/debugger"
+)
+
+// A Value is a named value captured by the probes, such as an argument or a
+// return value of a function.
+type Value struct {
+	name  string
+	value interface{}
+}
+
+// V returns the value v named name.
+func V(name string, v interface{}) Value {
+	return Value{name: name, value: v}
+}
+
+// A Call is a call of a function on which probes are installed, returned by
+// Enter. A nil Call is valid, and captures nothing.
+type Call struct {
+	call *debugger.Call
+}
+
+// Enter is the hook to call upon entering the function with the given name,
+// formatted as reported by runtime.FuncForPC, e.g. "main.handle" or
+// "github.com/user/repo/pkg.(*Server).Serve", with its arguments. The span
+// held by ctx, if any, is reported along with the snapshots of the call.
+func Enter(ctx context.Context, function string, args ...Value) *Call {
+	if !debugger.Enabled() {
+		return nil
+	}
+	var traceID, spanID uint64
+	if span, ok := tracer.SpanFromContext(ctx); ok {
+		traceID, spanID = span.Context().TraceID(), span.Context().SpanID()
+	}
+	call := debugger.Enter(function, traceID, spanID, captures(args))
+	if call == nil {
+		return nil
+	}
+	return &Call{call: call}
+}
+
+// Exit is the hook to call upon returning from the function of c, with its
+// return values.
+func (c *Call) Exit(returns ...Value) {
+	if c == nil {
+		return
+	}
+	c.call.Exit(captures(returns))
+}
+
+func captures(values []Value) []debugger.Capture {
+	if len(values) == 0 {
+		return nil
+	}
+	c := make([]debugger.Capture, len(values))
+	for i, v := range values {
+		c[i] = debugger.Capture{Name: v.name, Value: v.value}
+	}
+	return c
+}
@@ -0,0 +1,29 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022 Datadog, Inc.
+
+package debugger
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDisabled(t *testing.T) {
+	call := Enter(context.Background(), "main.handle", V("id", 42))
+	assert.Nil(t, call)
+	// a nil call is valid
+	call.Exit(V("err", nil))
+}
+
+func TestCaptures(t *testing.T) {
+	assert.Nil(t, captures(nil))
+	c := captures([]Value{V("id", 42), V("name", "x")})
+	assert.Len(t, c, 2)
+	assert.Equal(t, "id", c[0].Name)
+	assert.Equal(t, 42, c[0].Value)
+	assert.Equal(t, "name", c[1].Name)
+}
@@ -0,0 +1,27 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022 Datadog, Inc.
+
+package debugger_test
+
+import (
+	"context"
+	"errors"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/debugger"
+)
+
+func handle(ctx context.Context, id string) (err error) {
+	call := debugger.Enter(ctx, "gopkg.in/DataDog/dd-trace-go.v1/debugger_test.handle", debugger.V("id", id))
+	defer func() { call.Exit(debugger.V("err", err)) }()
+	if id == "" {
+		return errors.New("missing id")
+	}
+	return nil
+}
+
+func Example() {
+	// the snapshots of handle are captured when a probe is installed on it
+	handle(context.Background(), "42")
+}
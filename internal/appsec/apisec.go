@@ -0,0 +1,228 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+//go:build appsec
+// +build appsec
+
+package appsec
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo/instrumentation/httpsec"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+)
+
+// API Security schema span tags. The response body is not observed by the HTTP
+// instrumentation and its schema can therefore not be extracted.
+const (
+	schemaReqHeadersTag = "_dd.appsec.s.req.headers"
+	schemaReqCookiesTag = "_dd.appsec.s.req.cookies"
+	schemaReqQueryTag   = "_dd.appsec.s.req.query"
+	schemaReqParamsTag  = "_dd.appsec.s.req.params"
+	schemaReqBodyTag    = "_dd.appsec.s.req.body"
+	schemaResHeadersTag = "_dd.appsec.s.res.headers"
+)
+
+// Scalar schema types, as expected by the backend.
+const (
+	schemaUnknown = 0
+	schemaNull    = 1
+	schemaBool    = 2
+	schemaInt     = 4
+	schemaString  = 8
+	schemaFloat   = 16
+)
+
+// Schema extraction limits, bounding the size of the extracted schemas and the
+// cost of their extraction.
+const (
+	maxSchemaDepth      = 18
+	maxSchemaArrayItems = 10
+	maxSchemaRecordKeys = 256
+)
+
+// sample returns true when the schemas of the current request must be extracted.
+func (c APISecConfig) sample() bool {
+	return c.Enabled && rand.Float64() < c.SampleRate
+}
+
+// schemaArrayInfo is the metadata of an array schema.
+type schemaArrayInfo struct {
+	Len       int  `json:"len"`
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// extractSchema returns the schema of the given value. Scalars are
+// represented by a one-element array holding their type, records by a map of
+// their keys to their schemas, and arrays by a two-element array holding the
+// distinct schemas of their first items and their metadata.
+func extractSchema(v interface{}) interface{} {
+	return extractValueSchema(reflect.ValueOf(v), 0)
+}
+
+func extractValueSchema(v reflect.Value, depth int) interface{} {
+	if !v.IsValid() {
+		return []int{schemaNull}
+	}
+	switch v.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		if v.IsNil() {
+			return []int{schemaNull}
+		}
+		return extractValueSchema(v.Elem(), depth)
+	case reflect.Bool:
+		return []int{schemaBool}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return []int{schemaInt}
+	case reflect.Float32, reflect.Float64:
+		return []int{schemaFloat}
+	case reflect.String:
+		return []int{schemaString}
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return []int{schemaNull}
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			// Byte slices are raw strings
+			return []int{schemaString}
+		}
+		if depth >= maxSchemaDepth {
+			return []int{schemaUnknown}
+		}
+		return extractArraySchema(v, depth)
+	case reflect.Map:
+		if v.IsNil() {
+			return []int{schemaNull}
+		}
+		if v.Type().Key().Kind() != reflect.String {
+			return []int{schemaUnknown}
+		}
+		if depth >= maxSchemaDepth {
+			return []int{schemaUnknown}
+		}
+		return extractMapSchema(v, depth)
+	case reflect.Struct:
+		if depth >= maxSchemaDepth {
+			return []int{schemaUnknown}
+		}
+		return extractStructSchema(v, depth)
+	default:
+		return []int{schemaUnknown}
+	}
+}
+
+func extractArraySchema(v reflect.Value, depth int) interface{} {
+	info := schemaArrayInfo{Len: v.Len()}
+	n := info.Len
+	if n > maxSchemaArrayItems {
+		n = maxSchemaArrayItems
+		info.Truncated = true
+	}
+	items := make([]interface{}, 0, n)
+	seen := make(map[string]struct{}, n)
+	for i := 0; i < n; i++ {
+		item := extractValueSchema(v.Index(i), depth+1)
+		// Only keep the distinct item schemas
+		key, err := json.Marshal(item)
+		if err != nil {
+			continue
+		}
+		if _, ok := seen[string(key)]; ok {
+			continue
+		}
+		seen[string(key)] = struct{}{}
+		items = append(items, item)
+	}
+	return []interface{}{items, info}
+}
+
+func extractMapSchema(v reflect.Value, depth int) interface{} {
+	keys := make([]string, 0, v.Len())
+	for _, k := range v.MapKeys() {
+		keys = append(keys, k.String())
+	}
+	sort.Strings(keys)
+	if len(keys) > maxSchemaRecordKeys {
+		keys = keys[:maxSchemaRecordKeys]
+	}
+	record := make(map[string]interface{}, len(keys))
+	for _, k := range keys {
+		record[k] = extractValueSchema(v.MapIndex(reflect.ValueOf(k).Convert(v.Type().Key())), depth+1)
+	}
+	return record
+}
+
+func extractStructSchema(v reflect.Value, depth int) interface{} {
+	t := v.Type()
+	record := make(map[string]interface{}, t.NumField())
+	for i := 0; i < t.NumField() && len(record) < maxSchemaRecordKeys; i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			// Unexported field
+			continue
+		}
+		name := f.Name
+		if tag, ok := f.Tag.Lookup("json"); ok {
+			tagName := strings.Split(tag, ",")[0]
+			if tagName == "-" {
+				continue
+			}
+			if tagName != "" {
+				name = tagName
+			}
+		}
+		record[name] = extractValueSchema(v.Field(i), depth+1)
+	}
+	return record
+}
+
+// encodeSchema returns the span tag value of the given schema: its JSON
+// representation, gzip-compressed and base64-encoded.
+func encodeSchema(schema interface{}) (string, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(w).Encode([]interface{}{schema}); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// addSchemaTag extracts the schema of the given value and adds it to th under the given tag.
+func addSchemaTag(th tagsHolder, tag string, v interface{}) {
+	value, err := encodeSchema(extractSchema(v))
+	if err != nil {
+		log.Debug("appsec: could not encode the %s schema: %v", tag, err)
+		return
+	}
+	th.AddTag(tag, value)
+}
+
+// addRequestSchemaTags adds the schemas of the non-empty request addresses of args to th.
+func addRequestSchemaTags(th tagsHolder, args httpsec.HandlerOperationArgs) {
+	if len(args.Headers) > 0 {
+		addSchemaTag(th, schemaReqHeadersTag, args.Headers)
+	}
+	if len(args.Cookies) > 0 {
+		addSchemaTag(th, schemaReqCookiesTag, args.Cookies)
+	}
+	if len(args.Query) > 0 {
+		addSchemaTag(th, schemaReqQueryTag, args.Query)
+	}
+	if len(args.PathParams) > 0 {
+		addSchemaTag(th, schemaReqParamsTag, args.PathParams)
+	}
+}
@@ -0,0 +1,109 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+//go:build appsec
+// +build appsec
+
+package appsec
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractSchema(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		value    interface{}
+		expected string
+	}{
+		{name: "nil", value: nil, expected: `[1]`},
+		{name: "bool", value: true, expected: `[2]`},
+		{name: "int", value: 42, expected: `[4]`},
+		{name: "uint", value: uint8(42), expected: `[4]`},
+		{name: "float", value: 4.2, expected: `[16]`},
+		{name: "string", value: "hello", expected: `[8]`},
+		{name: "bytes", value: []byte("hello"), expected: `[8]`},
+		{name: "unknown", value: func() {}, expected: `[0]`},
+		{
+			name:     "headers",
+			value:    map[string][]string{"content-type": {"application/json"}, "x-ids": {"1", "2"}},
+			expected: `{"content-type":[[[8]],{"len":1}],"x-ids":[[[8]],{"len":2}]}`,
+		},
+		{
+			name: "json-body",
+			value: map[string]interface{}{
+				"name":  "dd",
+				"age":   float64(13),
+				"admin": false,
+				"tags":  []interface{}{"a", 1.0, "b", nil},
+				"owner": map[string]interface{}{"id": "42"},
+			},
+			expected: `{"admin":[2],"age":[16],"name":[8],"owner":{"id":[8]},"tags":[[[8],[16],[1]],{"len":4}]}`,
+		},
+		{
+			name: "struct",
+			value: &struct {
+				Name     string `json:"name"`
+				Age      int    `json:"age,omitempty"`
+				Password string `json:"-"`
+				Admin    bool
+				private  string
+			}{},
+			expected: `{"Admin":[2],"age":[4],"name":[8]}`,
+		},
+		{
+			name:     "truncated-array",
+			value:    make([]int, maxSchemaArrayItems+1),
+			expected: `[[[4]],{"len":11,"truncated":true}]`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			schema, err := json.Marshal(extractSchema(tc.value))
+			require.NoError(t, err)
+			require.JSONEq(t, tc.expected, string(schema))
+		})
+	}
+
+	t.Run("max-depth", func(t *testing.T) {
+		var v interface{} = "leaf"
+		for i := 0; i < maxSchemaDepth+1; i++ {
+			v = []interface{}{v}
+		}
+		schema, err := json.Marshal(extractSchema(v))
+		require.NoError(t, err)
+		require.Contains(t, string(schema), `[0]`)
+		require.NotContains(t, string(schema), `[8]`)
+	})
+}
+
+func TestEncodeSchema(t *testing.T) {
+	value, err := encodeSchema(extractSchema(map[string][]string{"q": {"v"}}))
+	require.NoError(t, err)
+	require.JSONEq(t, `[{"q":[[[8]],{"len":1}]}]`, decodeSchema(t, value))
+}
+
+func TestAPISecSample(t *testing.T) {
+	require.False(t, APISecConfig{Enabled: false, SampleRate: 1}.sample())
+	require.False(t, APISecConfig{Enabled: true, SampleRate: 0}.sample())
+	require.True(t, APISecConfig{Enabled: true, SampleRate: 1}.sample())
+}
+
+// decodeSchema decodes the given schema span tag value into its JSON representation.
+func decodeSchema(t *testing.T, value string) string {
+	compressed, err := base64.StdEncoding.DecodeString(value)
+	require.NoError(t, err)
+	r, err := gzip.NewReader(bytes.NewReader(compressed))
+	require.NoError(t, err)
+	schema, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return string(schema)
+}
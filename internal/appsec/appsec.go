@@ -63,6 +63,9 @@ func Start(opts ...StartOption) {
 		appsec.stopRC()
 		return
 	}
+	if err := appsec.enableRulesUpdates(); err != nil {
+		log.Debug("appsec: Remote config: the security rules won't be updated: %v", err)
+	}
 	setActiveAppSec(appsec)
 }
 
@@ -96,10 +99,22 @@ type appsec struct {
 	unregisterWAF dyngo.UnregisterFunc
 	limiter       *TokenTicker
 	rc            *remoteconfig.Client
-	started       bool
+	// rcShared is true when rc is owned by the caller of Start, in which case AppSec doesn't start nor stop it.
+	rcShared bool
+	started  bool
+	// rules are the security rules of the WAF, which can be updated through remote config.
+	rules *rulesManager
 }
 
 func newAppSec(cfg *Config) *appsec {
+	if cfg.rcClient != nil {
+		return &appsec{
+			cfg:      cfg,
+			rc:       cfg.rcClient,
+			rcShared: true,
+			rules:    newRulesManager(cfg.rules),
+		}
+	}
 	var rc *remoteconfig.Client
 	var err error
 	if cfg.rc != nil {
@@ -109,8 +124,9 @@ func newAppSec(cfg *Config) *appsec {
 		log.Error("appsec: Remote config: disabled due to a client creation error: %v", err)
 	}
 	return &appsec{
-		cfg: cfg,
-		rc:  rc,
+		cfg:   cfg,
+		rc:    rc,
+		rules: newRulesManager(cfg.rules),
 	}
 }
 
@@ -119,7 +135,7 @@ func (a *appsec) start() error {
 	a.limiter = NewTokenTicker(int64(a.cfg.traceRateLimit), int64(a.cfg.traceRateLimit))
 	a.limiter.Start()
 	// Register the WAF operation event listener
-	unregisterWAF, err := registerWAF(a.cfg.rules, a.cfg.wafTimeout, a.limiter, &a.cfg.obfuscator)
+	unregisterWAF, err := registerWAF(a.rules, a.cfg.wafTimeout, a.limiter, &a.cfg.obfuscator, a.cfg.apiSec)
 	if err != nil {
 		return err
 	}
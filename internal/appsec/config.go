@@ -19,17 +19,20 @@ import (
 )
 
 const (
-	enabledEnvVar         = "DD_APPSEC_ENABLED"
-	rulesEnvVar           = "DD_APPSEC_RULES"
-	wafTimeoutEnvVar      = "DD_APPSEC_WAF_TIMEOUT"
-	traceRateLimitEnvVar  = "DD_APPSEC_TRACE_RATE_LIMIT"
-	obfuscatorKeyEnvVar   = "DD_APPSEC_OBFUSCATION_PARAMETER_KEY_REGEXP"
-	obfuscatorValueEnvVar = "DD_APPSEC_OBFUSCATION_PARAMETER_VALUE_REGEXP"
+	enabledEnvVar          = "DD_APPSEC_ENABLED"
+	rulesEnvVar            = "DD_APPSEC_RULES"
+	wafTimeoutEnvVar       = "DD_APPSEC_WAF_TIMEOUT"
+	traceRateLimitEnvVar   = "DD_APPSEC_TRACE_RATE_LIMIT"
+	obfuscatorKeyEnvVar    = "DD_APPSEC_OBFUSCATION_PARAMETER_KEY_REGEXP"
+	obfuscatorValueEnvVar  = "DD_APPSEC_OBFUSCATION_PARAMETER_VALUE_REGEXP"
+	apiSecEnabledEnvVar    = "DD_API_SECURITY_ENABLED"
+	apiSecSampleRateEnvVar = "DD_API_SECURITY_REQUEST_SAMPLE_RATE"
 )
 
 const (
 	defaultWAFTimeout           = 4 * time.Millisecond
 	defaultTraceRate            = 100 // up to 100 appsec traces/s
+	defaultAPISecSampleRate     = 0.1 // 10% of the requests
 	defaultObfuscatorKeyRegex   = `(?i)(?:p(?:ass)?w(?:or)?d|pass(?:_?phrase)?|secret|(?:api_?|private_?|public_?)key)|token|consumer_?(?:id|key|secret)|sign(?:ed|ature)|bearer|authorization`
 	defaultObfuscatorValueRegex = `(?i)(?:p(?:ass)?w(?:or)?d|pass(?:_?phrase)?|secret|(?:api_?|private_?|public_?|access_?|secret_?)key(?:_?id)?|token|consumer_?(?:id|key|secret)|sign(?:ed|ature)?|auth(?:entication|orization)?)(?:\s*=[^;]|"\s*:\s*"[^"]+")|bearer\s+[a-z0-9\._\-]+|token:[a-z0-9]{13}|gh[opsu]_[0-9a-zA-Z]{36}|ey[I-L][\w=-]+\.ey[I-L][\w=-]+(?:\.[\w.+\/=-]+)?|[\-]{5}BEGIN[a-z\s]+PRIVATE\sKEY[\-]{5}[^\-]+[\-]{5}END[a-z\s]+PRIVATE\sKEY|ssh-rsa\s*[a-z0-9\/\.+]{100,}`
 )
@@ -47,8 +50,13 @@ type Config struct {
 	traceRateLimit uint
 	// Obfuscator configuration parameters
 	obfuscator ObfuscatorConfig
+	// API Security schema collection configuration
+	apiSec APISecConfig
 	// rc is the remote configuration client used to receive product configuration updates. Nil if rc is disabled (default)
 	rc *remoteconfig.ClientConfig
+	// rcClient is a remote configuration client shared with the caller. When set, it takes precedence over rc and
+	// AppSec only registers its products, capabilities and callbacks on it: starting and stopping it is left to the caller.
+	rcClient *remoteconfig.Client
 }
 
 // WithRCConfig sets the AppSec remote config client configuration to the specified cfg
@@ -58,12 +66,28 @@ func WithRCConfig(cfg remoteconfig.ClientConfig) StartOption {
 	}
 }
 
+// WithRCClient makes AppSec use the given remote config client, which is shared with the caller, instead of creating
+// its own. The caller remains responsible for starting the client once AppSec is started, and for stopping it.
+func WithRCClient(client *remoteconfig.Client) StartOption {
+	return func(c *Config) {
+		c.rcClient = client
+	}
+}
+
 // ObfuscatorConfig wraps the key and value regexp to be passed to the WAF to perform obfuscation.
 type ObfuscatorConfig struct {
 	KeyRegex   string
 	ValueRegex string
 }
 
+// APISecConfig holds the API Security configuration. When enabled, the schemas of the requests and responses of a
+// sample of the HTTP requests are extracted and added to their service entry span as _dd.appsec.s.* tags.
+type APISecConfig struct {
+	Enabled bool
+	// SampleRate is the ratio of the requests whose schemas are extracted, between 0 and 1.
+	SampleRate float64
+}
+
 // isEnabled returns true when appsec is enabled when the environment variable
 // It also returns whether the env var is actually set in the env or not
 // DD_APPSEC_ENABLED is set to true.
@@ -88,6 +112,7 @@ func newConfig() (*Config, error) {
 		wafTimeout:     readWAFTimeoutConfig(),
 		traceRateLimit: readRateLimitConfig(),
 		obfuscator:     readObfuscatorConfig(),
+		apiSec:         readAPISecConfig(),
 	}, nil
 }
 
@@ -135,6 +160,33 @@ func readRateLimitConfig() (rate uint) {
 	return uint(parsed)
 }
 
+func readAPISecConfig() APISecConfig {
+	cfg := APISecConfig{SampleRate: defaultAPISecSampleRate}
+	if value := os.Getenv(apiSecEnabledEnvVar); value != "" {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			logEnvVarParsingError(apiSecEnabledEnvVar, value, err, cfg.Enabled)
+		} else {
+			cfg.Enabled = enabled
+		}
+	}
+	value := os.Getenv(apiSecSampleRateEnvVar)
+	if value == "" {
+		return cfg
+	}
+	rate, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		logEnvVarParsingError(apiSecSampleRateEnvVar, value, err, cfg.SampleRate)
+		return cfg
+	}
+	if rate < 0 || rate > 1 {
+		logUnexpectedEnvVarValue(apiSecSampleRateEnvVar, rate, "expecting a value between 0 and 1", cfg.SampleRate)
+		return cfg
+	}
+	cfg.SampleRate = rate
+	return cfg
+}
+
 func readObfuscatorConfig() ObfuscatorConfig {
 	keyRE := readObfuscatorConfigRegexp(obfuscatorKeyEnvVar, defaultObfuscatorKeyRegex)
 	valueRE := readObfuscatorConfigRegexp(obfuscatorValueEnvVar, defaultObfuscatorValueRegex)
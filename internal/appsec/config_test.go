@@ -25,6 +25,7 @@ func TestConfig(t *testing.T) {
 			KeyRegex:   defaultObfuscatorKeyRegex,
 			ValueRegex: defaultObfuscatorValueRegex,
 		},
+		apiSec: APISecConfig{SampleRate: defaultAPISecSampleRate},
 	}
 
 	t.Run("default", func(t *testing.T) {
@@ -247,15 +248,68 @@ func TestConfig(t *testing.T) {
 			})
 		})
 	})
+
+	t.Run("api-security", func(t *testing.T) {
+		t.Run("enabled", func(t *testing.T) {
+			expCfg := *expectedDefaultConfig
+			expCfg.apiSec.Enabled = true
+			restoreEnv := cleanEnv()
+			defer restoreEnv()
+			require.NoError(t, os.Setenv(apiSecEnabledEnvVar, "true"))
+			cfg, err := newConfig()
+			require.NoError(t, err)
+			require.Equal(t, &expCfg, cfg)
+		})
+
+		t.Run("enabled-not-parsable", func(t *testing.T) {
+			restoreEnv := cleanEnv()
+			defer restoreEnv()
+			require.NoError(t, os.Setenv(apiSecEnabledEnvVar, "not a bool"))
+			cfg, err := newConfig()
+			require.NoError(t, err)
+			require.Equal(t, expectedDefaultConfig, cfg)
+		})
+
+		t.Run("sample-rate", func(t *testing.T) {
+			expCfg := *expectedDefaultConfig
+			expCfg.apiSec.SampleRate = 0.5
+			restoreEnv := cleanEnv()
+			defer restoreEnv()
+			require.NoError(t, os.Setenv(apiSecSampleRateEnvVar, "0.5"))
+			cfg, err := newConfig()
+			require.NoError(t, err)
+			require.Equal(t, &expCfg, cfg)
+		})
+
+		t.Run("sample-rate-out-of-range", func(t *testing.T) {
+			restoreEnv := cleanEnv()
+			defer restoreEnv()
+			require.NoError(t, os.Setenv(apiSecSampleRateEnvVar, "1.5"))
+			cfg, err := newConfig()
+			require.NoError(t, err)
+			require.Equal(t, expectedDefaultConfig, cfg)
+		})
+
+		t.Run("sample-rate-not-parsable", func(t *testing.T) {
+			restoreEnv := cleanEnv()
+			defer restoreEnv()
+			require.NoError(t, os.Setenv(apiSecSampleRateEnvVar, "not a float"))
+			cfg, err := newConfig()
+			require.NoError(t, err)
+			require.Equal(t, expectedDefaultConfig, cfg)
+		})
+	})
 }
 
 func cleanEnv() func() {
 	env := map[string]string{
-		wafTimeoutEnvVar:      os.Getenv(wafTimeoutEnvVar),
-		rulesEnvVar:           os.Getenv(rulesEnvVar),
-		traceRateLimitEnvVar:  os.Getenv(traceRateLimitEnvVar),
-		obfuscatorKeyEnvVar:   os.Getenv(obfuscatorKeyEnvVar),
-		obfuscatorValueEnvVar: os.Getenv(obfuscatorValueEnvVar),
+		wafTimeoutEnvVar:       os.Getenv(wafTimeoutEnvVar),
+		rulesEnvVar:            os.Getenv(rulesEnvVar),
+		traceRateLimitEnvVar:   os.Getenv(traceRateLimitEnvVar),
+		obfuscatorKeyEnvVar:    os.Getenv(obfuscatorKeyEnvVar),
+		obfuscatorValueEnvVar:  os.Getenv(obfuscatorValueEnvVar),
+		apiSecEnabledEnvVar:    os.Getenv(apiSecEnabledEnvVar),
+		apiSecSampleRateEnvVar: os.Getenv(apiSecSampleRateEnvVar),
 	}
 	for k, _ := range env {
 		if err := os.Unsetenv(k); err != nil {
@@ -0,0 +1,14 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022 Datadog, Inc.
+
+package dyngo
+
+import "errors"
+
+// ErrBlocked is the error reported by an operation that one of its event
+// listeners decided to block, such as the AppSec WAF when it matches a security
+// rule whose action is to block the request. The instrumented function must
+// then be aborted.
+var ErrBlocked = errors.New("operation blocked")
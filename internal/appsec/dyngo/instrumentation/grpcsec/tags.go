@@ -8,8 +8,11 @@ package grpcsec
 import (
 	"encoding/json"
 	"net"
+	"net/http"
+	"strings"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo/instrumentation"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo/instrumentation/httpsec"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
@@ -42,3 +45,30 @@ func setSecurityEventTags(span ddtrace.Span, events []json.RawMessage, addr net.
 	}
 	return nil
 }
+
+// SetIPTags sets the IP related span tags of the gRPC request out of the
+// request metadata and peer address. The client IP is resolved the same way as
+// for HTTP requests, while conflicting IP metadata are reported under the
+// grpc.metadata tags.
+func SetIPTags(span instrumentation.TagSetter, md map[string][]string, addr net.Addr) {
+	hdrs := make(http.Header, len(md))
+	for k, v := range md {
+		for _, v := range v {
+			hdrs.Add(k, v)
+		}
+	}
+	var remoteAddr string
+	if addr != nil {
+		remoteAddr = addr.String()
+	}
+	clientIP, ipHeaders := httpsec.ClientIP(hdrs, remoteAddr)
+	if clientIP != "" {
+		span.SetTag(ext.HTTPClientIP, clientIP)
+	}
+	if len(ipHeaders) > 1 {
+		for _, hdr := range ipHeaders {
+			span.SetTag("grpc.metadata."+hdr, strings.Join(hdrs.Values(hdr), ","))
+		}
+		span.SetTag(httpsec.MultipleIPHeaders, strings.Join(ipHeaders, ","))
+	}
+}
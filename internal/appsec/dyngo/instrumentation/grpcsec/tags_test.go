@@ -151,6 +151,56 @@ func TestSetSecurityEventTags(t *testing.T) {
 	}
 }
 
+func TestSetIPTags(t *testing.T) {
+	for _, tc := range []struct {
+		name         string
+		md           map[string][]string
+		addr         net.Addr
+		expectedTags map[string]interface{}
+	}{
+		{
+			name:         "global-peer",
+			addr:         &net.TCPAddr{IP: net.ParseIP("1.2.3.4"), Port: 6789},
+			expectedTags: map[string]interface{}{ext.HTTPClientIP: "1.2.3.4"},
+		},
+		{
+			name: "private-peer",
+			addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 6789},
+		},
+		{
+			name:         "xff-metadata",
+			md:           map[string][]string{"x-forwarded-for": {"10.0.0.1, 8.8.8.8"}},
+			addr:         &net.TCPAddr{IP: net.ParseIP("1.2.3.4"), Port: 6789},
+			expectedTags: map[string]interface{}{ext.HTTPClientIP: "8.8.8.8"},
+		},
+		{
+			name: "multiple-ip-metadata",
+			md: map[string][]string{
+				"x-forwarded-for": {"8.8.8.8"},
+				"x-real-ip":       {"9.9.9.9"},
+			},
+			expectedTags: map[string]interface{}{
+				"grpc.metadata.x-forwarded-for": "8.8.8.8",
+				"grpc.metadata.x-real-ip":       "9.9.9.9",
+				"_dd.multiple-ip-headers":       "x-forwarded-for,x-real-ip",
+			},
+		},
+		{
+			name: "no-peer",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var span MockSpan
+			SetIPTags(&span, tc.md, tc.addr)
+			if tc.expectedTags == nil {
+				require.Empty(t, span.tags)
+				return
+			}
+			require.Equal(t, tc.expectedTags, span.tags)
+		})
+	}
+}
+
 type MockSpan struct {
 	tags     map[string]interface{}
 	finished bool
@@ -0,0 +1,92 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022 Datadog, Inc.
+
+package httpsec
+
+import (
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+)
+
+const (
+	// blockedTag is the span tag set on the requests blocked by AppSec.
+	blockedTag = "appsec.blocked"
+
+	// envBlockedTemplateJSON is the path to the file holding the JSON body of
+	// the blocking responses.
+	envBlockedTemplateJSON = "DD_APPSEC_HTTP_BLOCKED_TEMPLATE_JSON"
+	// envBlockedTemplateHTML is the path to the file holding the HTML body of
+	// the blocking responses.
+	envBlockedTemplateHTML = "DD_APPSEC_HTTP_BLOCKED_TEMPLATE_HTML"
+)
+
+var (
+	defaultBlockedTemplateJSON = []byte(`{"errors":[{"title":"You've been blocked","detail":"Sorry, you cannot access this page. Please contact the customer service team. Security provided by Datadog."}]}`)
+	defaultBlockedTemplateHTML = []byte(`<!DOCTYPE html><html lang="en"><head><meta charset="UTF-8"><title>You've been blocked</title></head>` +
+		`<body><h1>Sorry, you cannot access this page. Please contact the customer service team.</h1><p>Security provided by Datadog.</p></body></html>`)
+)
+
+var (
+	blockedTemplatesOnce sync.Once
+	blockedTemplateJSON  []byte
+	blockedTemplateHTML  []byte
+)
+
+// Block marks the operation as blocked, so that the request is aborted and
+// answered with a blocking response instead of being handled. It is meant to
+// be called by the operation start event listeners.
+func (op *Operation) Block() {
+	if atomic.SwapUint32(&op.blocked, 1) == 0 {
+		op.AddTag(blockedTag, true)
+	}
+}
+
+// Err returns dyngo.ErrBlocked when the operation was blocked, nil otherwise.
+func (op *Operation) Err() error {
+	if atomic.LoadUint32(&op.blocked) == 1 {
+		return dyngo.ErrBlocked
+	}
+	return nil
+}
+
+// WriteBlockingResponse writes the response of a blocked request: a 403 status
+// code along with a JSON or HTML body, depending on the content types accepted
+// by the request. The default bodies can be replaced by the content of the
+// files pointed by the DD_APPSEC_HTTP_BLOCKED_TEMPLATE_JSON and
+// DD_APPSEC_HTTP_BLOCKED_TEMPLATE_HTML environment variables.
+func WriteBlockingResponse(w http.ResponseWriter, r *http.Request) {
+	blockedTemplatesOnce.Do(func() {
+		blockedTemplateJSON = readBlockedTemplate(envBlockedTemplateJSON, defaultBlockedTemplateJSON)
+		blockedTemplateHTML = readBlockedTemplate(envBlockedTemplateHTML, defaultBlockedTemplateHTML)
+	})
+	contentType, body := "application/json", blockedTemplateJSON
+	if accept := r.Header.Get("Accept"); strings.Contains(accept, "text/html") && !strings.Contains(accept, "application/json") {
+		contentType, body = "text/html", blockedTemplateHTML
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusForbidden)
+	w.Write(body)
+}
+
+// readBlockedTemplate returns the content of the file pointed by the given
+// environment variable, or def when it is unset or cannot be read.
+func readBlockedTemplate(env string, def []byte) []byte {
+	path := os.Getenv(env)
+	if path == "" {
+		return def
+	}
+	tmpl, err := os.ReadFile(path)
+	if err != nil {
+		log.Error("appsec: could not read the blocking response template %s=%s: %v", env, path, err)
+		return def
+	}
+	return tmpl
+}
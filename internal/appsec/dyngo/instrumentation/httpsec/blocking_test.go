@@ -0,0 +1,340 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022 Datadog, Inc.
+
+package httpsec
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteBlockingResponse(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		accept      string
+		contentType string
+		body        []byte
+	}{
+		{
+			name:        "default",
+			contentType: "application/json",
+			body:        defaultBlockedTemplateJSON,
+		},
+		{
+			name:        "html",
+			accept:      "text/html,application/xhtml+xml",
+			contentType: "text/html",
+			body:        defaultBlockedTemplateHTML,
+		},
+		{
+			name:        "html-and-json",
+			accept:      "text/html,application/json",
+			contentType: "application/json",
+			body:        defaultBlockedTemplateJSON,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			blockedTemplatesOnce = sync.Once{}
+			req := httptest.NewRequest("GET", "/", nil)
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+			rec := httptest.NewRecorder()
+			WriteBlockingResponse(rec, req)
+			require.Equal(t, http.StatusForbidden, rec.Code)
+			require.Equal(t, tc.contentType, rec.Header().Get("Content-Type"))
+			require.Equal(t, tc.body, rec.Body.Bytes())
+		})
+	}
+
+	t.Run("templates", func(t *testing.T) {
+		dir := t.TempDir()
+		jsonTmpl := filepath.Join(dir, "blocked.json")
+		require.NoError(t, os.WriteFile(jsonTmpl, []byte(`{"blocked":true}`), 0644))
+		t.Setenv(envBlockedTemplateJSON, jsonTmpl)
+		t.Setenv(envBlockedTemplateHTML, filepath.Join(dir, "does-not-exist.html"))
+		blockedTemplatesOnce = sync.Once{}
+		defer func() { blockedTemplatesOnce = sync.Once{} }()
+
+		rec := httptest.NewRecorder()
+		WriteBlockingResponse(rec, httptest.NewRequest("GET", "/", nil))
+		require.Equal(t, `{"blocked":true}`, rec.Body.String())
+
+		// unreadable templates fall back to the default ones
+		rec = httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Accept", "text/html")
+		WriteBlockingResponse(rec, req)
+		require.Equal(t, defaultBlockedTemplateHTML, rec.Body.Bytes())
+	})
+}
+
+func TestWrapHandlerBlocking(t *testing.T) {
+	unregister := dyngo.Register(OnHandlerOperationStart(func(op *Operation, args HandlerOperationArgs) {
+		if args.Headers["user-agent"][0] == "blocked" {
+			op.Block()
+		}
+	}))
+	defer unregister()
+
+	var called bool
+	span := &testSpan{tags: map[string]interface{}{}}
+	h := WrapHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}), span, nil)
+
+	for _, tc := range []struct {
+		userAgent string
+		blocked   bool
+	}{
+		{userAgent: "allowed"},
+		{userAgent: "blocked", blocked: true},
+	} {
+		t.Run(tc.userAgent, func(t *testing.T) {
+			called = false
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("User-Agent", tc.userAgent)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			require.Equal(t, !tc.blocked, called)
+			if tc.blocked {
+				require.Equal(t, http.StatusForbidden, rec.Code)
+				require.Equal(t, true, span.tags[blockedTag])
+			} else {
+				require.NotContains(t, span.tags, blockedTag)
+			}
+		})
+	}
+}
+
+func TestMonitorUserBlocking(t *testing.T) {
+	unregister := dyngo.Register(OnHandlerOperationStart(func(op *Operation, _ HandlerOperationArgs) {
+		op.On(OnUserIDOperationStart(func(_ *UserIDOperation, args UserIDOperationArgs) {
+			if args.UserID == "blocked" {
+				op.Block()
+			}
+		}))
+	}))
+	defer unregister()
+
+	span := &testSpan{tags: map[string]interface{}{}}
+	h := WrapHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := MonitorUser(r.Context(), r.URL.Query().Get("user")); err != nil {
+			require.Equal(t, dyngo.ErrBlocked, err)
+			return
+		}
+		w.Write([]byte("hello"))
+	}), span, nil)
+
+	for _, tc := range []struct {
+		user    string
+		blocked bool
+	}{
+		{user: "allowed"},
+		{user: "blocked", blocked: true},
+	} {
+		t.Run(tc.user, func(t *testing.T) {
+			rec := &statusRecorder{ResponseRecorder: httptest.NewRecorder()}
+			h.ServeHTTP(rec, httptest.NewRequest("GET", "/?user="+tc.user, nil))
+			if tc.blocked {
+				require.Equal(t, http.StatusForbidden, rec.Code)
+				require.Equal(t, true, span.tags[blockedTag])
+			} else {
+				require.Equal(t, "hello", rec.Body.String())
+				require.NotContains(t, span.tags, blockedTag)
+			}
+		})
+	}
+
+	t.Run("no-operation", func(t *testing.T) {
+		require.NoError(t, MonitorUser(context.Background(), "blocked"))
+	})
+}
+
+func TestProtectSQLOperationBlocking(t *testing.T) {
+	var got []SQLOperationArgs
+	unregister := dyngo.Register(OnHandlerOperationStart(func(op *Operation, _ HandlerOperationArgs) {
+		op.On(OnSQLOperationStart(func(_ *SQLOperation, args SQLOperationArgs) {
+			got = append(got, args)
+			if strings.Contains(args.Query, "OR 1=1") {
+				op.Block()
+			}
+		}))
+	}))
+	defer unregister()
+
+	span := &testSpan{tags: map[string]interface{}{}}
+	h := WrapHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := "SELECT * FROM users WHERE id = " + r.URL.Query().Get("id")
+		if err := ProtectSQLOperation(r.Context(), query, "postgresql"); err != nil {
+			require.Equal(t, dyngo.ErrBlocked, err)
+			return
+		}
+		w.Write([]byte("hello"))
+	}), span, nil)
+
+	for _, tc := range []struct {
+		id      string
+		blocked bool
+	}{
+		{id: "1"},
+		{id: "1%20OR%201=1", blocked: true},
+	} {
+		t.Run(tc.id, func(t *testing.T) {
+			got = nil
+			rec := &statusRecorder{ResponseRecorder: httptest.NewRecorder()}
+			h.ServeHTTP(rec, httptest.NewRequest("GET", "/?id="+tc.id, nil))
+			require.Len(t, got, 1)
+			require.Equal(t, "postgresql", got[0].DBSystem)
+			if tc.blocked {
+				require.Equal(t, http.StatusForbidden, rec.Code)
+				require.Equal(t, true, span.tags[blockedTag])
+			} else {
+				require.Equal(t, "hello", rec.Body.String())
+				require.NotContains(t, span.tags, blockedTag)
+			}
+		})
+	}
+
+	t.Run("no-operation", func(t *testing.T) {
+		got = nil
+		require.NoError(t, ProtectSQLOperation(context.Background(), "SELECT 1 OR 1=1", "postgresql"))
+		require.Empty(t, got)
+	})
+}
+
+func TestProtectRoundTripOperationBlocking(t *testing.T) {
+	unregister := dyngo.Register(OnHandlerOperationStart(func(op *Operation, _ HandlerOperationArgs) {
+		op.On(OnRoundTripOperationStart(func(_ *RoundTripOperation, args RoundTripOperationArgs) {
+			if strings.HasPrefix(args.URL, "http://169.254.169.254/") {
+				op.Block()
+			}
+		}))
+	}))
+	defer unregister()
+
+	span := &testSpan{tags: map[string]interface{}{}}
+	h := WrapHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := ProtectRoundTripOperation(r.Context(), r.URL.Query().Get("url")); err != nil {
+			require.Equal(t, dyngo.ErrBlocked, err)
+			return
+		}
+		w.Write([]byte("hello"))
+	}), span, nil)
+
+	for _, tc := range []struct {
+		url     string
+		blocked bool
+	}{
+		{url: "http://example.com/"},
+		{url: "http://169.254.169.254/latest/meta-data/", blocked: true},
+	} {
+		t.Run(tc.url, func(t *testing.T) {
+			rec := &statusRecorder{ResponseRecorder: httptest.NewRecorder()}
+			h.ServeHTTP(rec, httptest.NewRequest("GET", "/?url="+tc.url, nil))
+			if tc.blocked {
+				require.Equal(t, http.StatusForbidden, rec.Code)
+				require.Equal(t, true, span.tags[blockedTag])
+			} else {
+				require.Equal(t, "hello", rec.Body.String())
+				require.NotContains(t, span.tags, blockedTag)
+			}
+		})
+	}
+
+	t.Run("no-operation", func(t *testing.T) {
+		require.NoError(t, ProtectRoundTripOperation(context.Background(), "http://169.254.169.254/"))
+	})
+}
+
+func TestProtectOpenOperationBlocking(t *testing.T) {
+	unregister := dyngo.Register(OnHandlerOperationStart(func(op *Operation, _ HandlerOperationArgs) {
+		op.On(OnOpenOperationStart(func(_ *OpenOperation, args OpenOperationArgs) {
+			if strings.Contains(args.Path, "../") {
+				op.Block()
+			}
+		}))
+	}))
+	defer unregister()
+
+	span := &testSpan{tags: map[string]interface{}{}}
+	h := WrapHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := ProtectOpenOperation(r.Context(), "/tmp/"+r.URL.Query().Get("file")); err != nil {
+			require.Equal(t, dyngo.ErrBlocked, err)
+			return
+		}
+		w.Write([]byte("hello"))
+	}), span, nil)
+
+	for _, tc := range []struct {
+		file    string
+		blocked bool
+	}{
+		{file: "file.txt"},
+		{file: "../etc/passwd", blocked: true},
+	} {
+		t.Run(tc.file, func(t *testing.T) {
+			rec := &statusRecorder{ResponseRecorder: httptest.NewRecorder()}
+			h.ServeHTTP(rec, httptest.NewRequest("GET", "/?file="+tc.file, nil))
+			if tc.blocked {
+				require.Equal(t, http.StatusForbidden, rec.Code)
+				require.Equal(t, true, span.tags[blockedTag])
+			} else {
+				require.Equal(t, "hello", rec.Body.String())
+				require.NotContains(t, span.tags, blockedTag)
+			}
+		})
+	}
+
+	t.Run("no-operation", func(t *testing.T) {
+		require.NoError(t, ProtectOpenOperation(context.Background(), "../etc/passwd"))
+	})
+}
+
+// statusRecorder is an httptest.ResponseRecorder reporting its status code
+// once written, as the response writers of the HTTP integrations do.
+type statusRecorder struct {
+	*httptest.ResponseRecorder
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseRecorder.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.WriteHeader(http.StatusOK)
+	}
+	return r.ResponseRecorder.Write(b)
+}
+
+func (r *statusRecorder) Status() int {
+	return r.status
+}
+
+// testSpan is a ddtrace.Span recording its tags.
+type testSpan struct {
+	ddtrace.Span
+	tags map[string]interface{}
+}
+
+func (s *testSpan) SetTag(key string, value interface{}) {
+	s.tags[key] = value
+}
@@ -0,0 +1,115 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022 Datadog, Inc.
+
+package httpsec
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+)
+
+// maxBodySize is the maximum number of request body bytes read and parsed
+// in order to monitor it. Larger bodies are left untouched.
+const maxBodySize = 64 * 1024
+
+// MonitorRequestBody parses the body of the given request with ParseBody and
+// monitors it when it could be parsed. The request body is then considered
+// monitored for the rest of the request: later calls to MonitorParsedBody, such
+// as the ones made through appsec.MonitorParsedHTTPBody by the request handler,
+// are ignored in order to avoid evaluating the same body twice.
+func MonitorRequestBody(ctx context.Context, r *http.Request) {
+	op := fromContext(ctx)
+	if op == nil {
+		return
+	}
+	if body, ok := ParseBody(r); ok {
+		MonitorParsedBody(ctx, body)
+		op.bodyMonitored = true
+	}
+}
+
+// ParseBody reads and decodes the body of the given request when its content
+// type is JSON or URL-encoded form values, so that it can be monitored under
+// the `server.request.body` address. The request body is restored so that it
+// can still be entirely read by the request handler. It returns false when the
+// body cannot or should not be parsed, e.g. when the content type is not
+// supported or when its length is unknown or larger than maxBodySize. Bodies
+// of unknown length, such as chunked ones, are never read as doing so could
+// block until the client is done sending them.
+func ParseBody(r *http.Request) (interface{}, bool) {
+	if r.Body == nil || r.Body == http.NoBody || r.ContentLength <= 0 || r.ContentLength > maxBodySize {
+		return nil, false
+	}
+	kind := bodyKind(r.Header.Get("Content-Type"))
+	if kind == bodyKindUnsupported {
+		return nil, false
+	}
+	buf, err := io.ReadAll(io.LimitReader(r.Body, r.ContentLength))
+	r.Body = &restoredBody{Reader: io.MultiReader(bytes.NewReader(buf), r.Body), Closer: r.Body}
+	if err != nil {
+		log.Debug("appsec: could not read the request body: %v", err)
+		return nil, false
+	}
+	switch kind {
+	case bodyKindJSON:
+		var body interface{}
+		if err := json.Unmarshal(buf, &body); err != nil {
+			log.Debug("appsec: could not parse the json request body: %v", err)
+			return nil, false
+		}
+		return body, true
+	case bodyKindForm:
+		values, err := url.ParseQuery(string(buf))
+		if err != nil {
+			log.Debug("appsec: could not parse the form request body: %v", err)
+			return nil, false
+		}
+		return map[string][]string(values), true
+	}
+	return nil, false
+}
+
+type bodyKindType int
+
+const (
+	bodyKindUnsupported bodyKindType = iota
+	bodyKindJSON
+	bodyKindForm
+)
+
+// bodyKind returns the kind of body described by the given Content-Type
+// header value.
+func bodyKind(contentType string) bodyKindType {
+	if contentType == "" {
+		return bodyKindUnsupported
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return bodyKindUnsupported
+	}
+	switch {
+	case mediaType == "application/json", strings.HasSuffix(mediaType, "+json"):
+		return bodyKindJSON
+	case mediaType == "application/x-www-form-urlencoded":
+		return bodyKindForm
+	}
+	return bodyKindUnsupported
+}
+
+// restoredBody is a request body whose already-read bytes are served again
+// before the remaining bytes of the original body, which is still the one
+// being closed.
+type restoredBody struct {
+	io.Reader
+	io.Closer
+}
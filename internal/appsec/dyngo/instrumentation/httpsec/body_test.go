@@ -0,0 +1,142 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022 Datadog, Inc.
+
+package httpsec
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBody(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		contentType string
+		body        string
+		expected    interface{}
+		ok          bool
+	}{
+		{
+			name:        "json",
+			contentType: "application/json",
+			body:        `{"a":["b",1]}`,
+			expected:    map[string]interface{}{"a": []interface{}{"b", 1.0}},
+			ok:          true,
+		},
+		{
+			name:        "json-suffix",
+			contentType: "application/vnd.api+json; charset=utf-8",
+			body:        `["a"]`,
+			expected:    []interface{}{"a"},
+			ok:          true,
+		},
+		{
+			name:        "form",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "a=b&a=c&d=e",
+			expected:    map[string][]string{"a": {"b", "c"}, "d": {"e"}},
+			ok:          true,
+		},
+		{
+			name:        "invalid-json",
+			contentType: "application/json",
+			body:        `{"a":`,
+		},
+		{
+			name:        "unsupported",
+			contentType: "text/plain",
+			body:        "hello",
+		},
+		{
+			name: "no-content-type",
+			body: `{"a":"b"}`,
+		},
+		{
+			name:        "too-large",
+			contentType: "application/json",
+			body:        `"` + strings.Repeat("a", maxBodySize) + `"`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/", strings.NewReader(tc.body))
+			if tc.contentType != "" {
+				req.Header.Set("Content-Type", tc.contentType)
+			}
+			body, ok := ParseBody(req)
+			require.Equal(t, tc.ok, ok)
+			require.Equal(t, tc.expected, body)
+
+			// The body must still be entirely readable
+			b, err := io.ReadAll(req.Body)
+			require.NoError(t, err)
+			require.Equal(t, tc.body, string(b))
+			require.NoError(t, req.Body.Close())
+		})
+	}
+
+	t.Run("unknown-length", func(t *testing.T) {
+		// The body is never written to, so reading it would block forever
+		pr, pw := io.Pipe()
+		defer pw.Close()
+		req := httptest.NewRequest("POST", "/", pr)
+		req.Header.Set("Content-Type", "application/json")
+		req.ContentLength = -1
+		body, ok := ParseBody(req)
+		require.False(t, ok)
+		require.Nil(t, body)
+		require.Equal(t, pr, req.Body)
+	})
+
+	t.Run("no-body", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Content-Type", "application/json")
+		body, ok := ParseBody(req)
+		require.False(t, ok)
+		require.Nil(t, body)
+		require.Equal(t, http.NoBody, req.Body)
+	})
+}
+
+func TestMonitorRequestBody(t *testing.T) {
+	var bodies []interface{}
+	unregister := dyngo.Register(OnHandlerOperationStart(func(op *Operation, _ HandlerOperationArgs) {
+		op.On(OnSDKBodyOperationStart(func(_ *SDKBodyOperation, args SDKBodyOperationArgs) {
+			bodies = append(bodies, args.Body)
+		}))
+	}))
+	defer unregister()
+
+	t.Run("parsed", func(t *testing.T) {
+		bodies = nil
+		req := httptest.NewRequest("POST", "/", strings.NewReader(`{"a":"b"}`))
+		req.Header.Set("Content-Type", "application/json")
+		ctx, op := StartOperation(req.Context(), HandlerOperationArgs{})
+		defer op.Finish(HandlerOperationRes{})
+
+		MonitorRequestBody(ctx, req)
+		// The body was already monitored, so the handler's one is ignored
+		MonitorParsedBody(ctx, map[string]interface{}{"a": "b"})
+		require.Equal(t, []interface{}{map[string]interface{}{"a": "b"}}, bodies)
+	})
+
+	t.Run("not-parsed", func(t *testing.T) {
+		bodies = nil
+		req := httptest.NewRequest("POST", "/", strings.NewReader("a"))
+		req.Header.Set("Content-Type", "text/plain")
+		ctx, op := StartOperation(req.Context(), HandlerOperationArgs{})
+		defer op.Finish(HandlerOperationRes{})
+
+		MonitorRequestBody(ctx, req)
+		MonitorParsedBody(ctx, "a")
+		require.Equal(t, []interface{}{"a"}, bodies)
+	})
+}
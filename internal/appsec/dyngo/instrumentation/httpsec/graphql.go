@@ -0,0 +1,106 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022 Datadog, Inc.
+
+package httpsec
+
+import (
+	"context"
+	"reflect"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo"
+)
+
+type (
+	// GraphQLResolverOperationArgs is the GraphQL resolver operation arguments.
+	GraphQLResolverOperationArgs struct {
+		// Resolver is the name of the resolved field, and Args its arguments.
+		// They correspond to the address `graphql.server.resolver` as the
+		// map {Resolver: Args}.
+		Resolver string
+		Args     map[string]interface{}
+	}
+
+	// GraphQLResolverOperationRes is the GraphQL resolver operation results.
+	GraphQLResolverOperationRes struct{}
+
+	// GraphQLResolverOperation type representing the monitoring of the
+	// arguments of a GraphQL resolver called while handling a request. It must
+	// be created with StartGraphQLResolverOperation() and finished with its
+	// Finish() method.
+	GraphQLResolverOperation struct {
+		dyngo.Operation
+	}
+)
+
+// ProtectGraphQLResolverOperation starts and finishes the GraphQL resolver
+// operation of the HTTP handler operation held by the given context, so that
+// the attacks through the arguments of the resolver can be detected and blocked
+// before it gets called. It returns dyngo.ErrBlocked when the request was
+// blocked, including by a previous operation, in which case the resolver must
+// not be called and the GraphQL library is expected to return a GraphQL error
+// instead. It is a no-op returning nil when ctx holds no HTTP handler operation.
+func ProtectGraphQLResolverOperation(ctx context.Context, resolver string, args map[string]interface{}) error {
+	parent := fromContext(ctx)
+	if parent == nil {
+		return nil
+	}
+	if len(args) > 0 {
+		op := StartGraphQLResolverOperation(parent, GraphQLResolverOperationArgs{Resolver: resolver, Args: args})
+		op.Finish()
+	}
+	return parent.Err()
+}
+
+// StartGraphQLResolverOperation starts the GraphQL resolver operation and emits
+// a start event
+func StartGraphQLResolverOperation(parent *Operation, args GraphQLResolverOperationArgs) *GraphQLResolverOperation {
+	op := &GraphQLResolverOperation{Operation: dyngo.NewOperation(parent)}
+	dyngo.StartOperation(op, args)
+	return op
+}
+
+// Finish finishes the GraphQL resolver operation and emits a finish event
+func (op *GraphQLResolverOperation) Finish() {
+	dyngo.FinishOperation(op, GraphQLResolverOperationRes{})
+}
+
+// GraphQL resolver operation's start and finish event callback function types.
+type (
+	// OnGraphQLResolverOperationStart function type, called when a GraphQL
+	// resolver operation starts.
+	OnGraphQLResolverOperationStart func(*GraphQLResolverOperation, GraphQLResolverOperationArgs)
+	// OnGraphQLResolverOperationFinish function type, called when a GraphQL
+	// resolver operation finishes.
+	OnGraphQLResolverOperationFinish func(*GraphQLResolverOperation, GraphQLResolverOperationRes)
+)
+
+var (
+	graphQLResolverOperationArgsType = reflect.TypeOf((*GraphQLResolverOperationArgs)(nil)).Elem()
+	graphQLResolverOperationResType  = reflect.TypeOf((*GraphQLResolverOperationRes)(nil)).Elem()
+)
+
+// ListenedType returns the type a OnGraphQLResolverOperationStart event
+// listener listens to, which is the GraphQLResolverOperationArgs type.
+func (OnGraphQLResolverOperationStart) ListenedType() reflect.Type {
+	return graphQLResolverOperationArgsType
+}
+
+// Call calls the underlying event listener function by performing the
+// type-assertion on v whose type is the one returned by ListenedType().
+func (f OnGraphQLResolverOperationStart) Call(op dyngo.Operation, v interface{}) {
+	f(op.(*GraphQLResolverOperation), v.(GraphQLResolverOperationArgs))
+}
+
+// ListenedType returns the type a OnGraphQLResolverOperationFinish event
+// listener listens to, which is the GraphQLResolverOperationRes type.
+func (OnGraphQLResolverOperationFinish) ListenedType() reflect.Type {
+	return graphQLResolverOperationResType
+}
+
+// Call calls the underlying event listener function by performing the
+// type-assertion on v whose type is the one returned by ListenedType().
+func (f OnGraphQLResolverOperationFinish) Call(op dyngo.Operation, v interface{}) {
+	f(op.(*GraphQLResolverOperation), v.(GraphQLResolverOperationRes))
+}
@@ -38,12 +38,16 @@ type (
 		Query map[string][]string
 		// PathParams corresponds to the address `server.request.path_params`
 		PathParams map[string]string
+		// ClientIP corresponds to the address `http.client_ip`
+		ClientIP string
 	}
 
 	// HandlerOperationRes is the HTTP handler operation results.
 	HandlerOperationRes struct {
 		// Status corresponds to the address `server.response.status`.
 		Status int
+		// Headers corresponds to the address `server.response.headers.no_cookies`
+		Headers map[string][]string
 	}
 
 	// SDKBodyOperationArgs is the SDK body operation arguments.
@@ -61,6 +65,10 @@ type (
 // get preciser error logs.
 func MonitorParsedBody(ctx context.Context, body interface{}) {
 	if parent := fromContext(ctx); parent != nil {
+		if parent.bodyMonitored {
+			log.Debug("appsec: parsed http body monitoring ignored: the request body was already monitored")
+			return
+		}
 		op := StartSDKBodyOperation(parent, SDKBodyOperationArgs{Body: body})
 		op.Finish()
 	} else {
@@ -69,23 +77,23 @@ func MonitorParsedBody(ctx context.Context, body interface{}) {
 }
 
 // WrapHandler wraps the given HTTP handler with the abstract HTTP operation defined by HandlerOperationArgs and
-// HandlerOperationRes.
+// HandlerOperationRes. The handler is not called when the operation gets blocked, in which case the request is
+// answered with WriteBlockingResponse.
 func WrapHandler(handler http.Handler, span ddtrace.Span, pathParams map[string]string) http.Handler {
 	instrumentation.SetAppSecEnabledTags(span)
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		SetIPTags(span, r)
-
 		args := MakeHandlerOperationArgs(r, pathParams)
 		ctx, op := StartOperation(r.Context(), args)
 		r = r.WithContext(ctx)
+		MonitorRequestBody(ctx, r)
 		defer func() {
 			var status int
 			if mw, ok := w.(interface{ Status() int }); ok {
 				status = mw.Status()
 			}
 
-			events := op.Finish(HandlerOperationRes{Status: status})
+			events := op.Finish(HandlerOperationRes{Status: status, Headers: MakeResponseHeaders(w.Header())})
 			instrumentation.SetTags(span, op.Tags())
 			if len(events) == 0 {
 				return
@@ -98,7 +106,18 @@ func WrapHandler(handler http.Handler, span ddtrace.Span, pathParams map[string]
 			SetSecurityEventTags(span, events, remoteIP, args.Headers, w.Header())
 		}()
 
+		if op.Err() != nil {
+			WriteBlockingResponse(w, r)
+			return
+		}
 		handler.ServeHTTP(w, r)
+		// The request can also be blocked while being handled, e.g. by the ID of its user, in which case the handler is
+		// expected to return without writing the response.
+		if op.Err() != nil {
+			if mw, ok := w.(interface{ Status() int }); ok && mw.Status() == 0 {
+				WriteBlockingResponse(w, r)
+			}
+		}
 	})
 }
 
@@ -117,13 +136,30 @@ func MakeHandlerOperationArgs(r *http.Request, pathParams map[string]string) Han
 	}
 	cookies := makeCookies(r) // TODO(Julio-Guerra): avoid actively parsing the cookies thanks to dynamic instrumentation
 	headers["host"] = []string{r.Host}
+	clientIP, _ := ClientIP(r.Header, r.RemoteAddr)
 	return HandlerOperationArgs{
 		RequestURI: r.RequestURI,
 		Headers:    headers,
 		Cookies:    cookies,
 		Query:      r.URL.Query(), // TODO(Julio-Guerra): avoid actively parsing the query values thanks to dynamic instrumentation
 		PathParams: pathParams,
+		ClientIP:   clientIP,
+	}
+}
+
+// MakeResponseHeaders returns the given response headers with lower-cased
+// names and without the Set-Cookie headers, following the specification of the
+// rule address `server.response.headers.no_cookies`.
+func MakeResponseHeaders(h http.Header) map[string][]string {
+	headers := make(map[string][]string, len(h))
+	for k, v := range h {
+		k := strings.ToLower(k)
+		if k == "set-cookie" {
+			continue
+		}
+		headers[k] = v
 	}
+	return headers
 }
 
 // Return the map of parsed cookies if any and following the specification of
@@ -149,6 +185,11 @@ type (
 		dyngo.Operation
 		instrumentation.TagsHolder
 		instrumentation.SecurityEventsHolder
+		// bodyMonitored is set once the request body was automatically parsed
+		// and monitored by MonitorRequestBody.
+		bodyMonitored bool
+		// blocked is set to 1 once a listener decided to block the request.
+		blocked uint32
 	}
 
 	// SDKBodyOperation type representing an SDK body. It must be created with
@@ -0,0 +1,97 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022 Datadog, Inc.
+
+package httpsec
+
+import (
+	"context"
+	"reflect"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo"
+)
+
+type (
+	// OpenOperationArgs is the file opening operation arguments.
+	OpenOperationArgs struct {
+		// Path corresponds to the address `server.io.fs.file`.
+		Path string
+	}
+
+	// OpenOperationRes is the file opening operation results.
+	OpenOperationRes struct{}
+
+	// OpenOperation type representing the monitoring of a file opened while
+	// handling a request, in order to detect and block path traversals, or
+	// local file inclusions (LFI), at the call site (RASP). It must be created
+	// with StartOpenOperation() and finished with its Finish() method.
+	OpenOperation struct {
+		dyngo.Operation
+	}
+)
+
+// ProtectOpenOperation starts and finishes the file opening operation of the
+// HTTP handler operation held by the given context, so that the path traversals
+// through the path of the file can be detected and blocked before it gets
+// opened. It returns dyngo.ErrBlocked when the request was blocked, in which
+// case the file must not be opened and the handler is expected to return
+// without writing the response, which is then written by WrapHandler. It is a
+// no-op returning nil when ctx holds no HTTP handler operation, such as the
+// files opened by background jobs.
+func ProtectOpenOperation(ctx context.Context, path string) error {
+	parent := fromContext(ctx)
+	if parent == nil {
+		return nil
+	}
+	op := StartOpenOperation(parent, OpenOperationArgs{Path: path})
+	op.Finish()
+	return parent.Err()
+}
+
+// StartOpenOperation starts the file opening operation and emits a start event
+func StartOpenOperation(parent *Operation, args OpenOperationArgs) *OpenOperation {
+	op := &OpenOperation{Operation: dyngo.NewOperation(parent)}
+	dyngo.StartOperation(op, args)
+	return op
+}
+
+// Finish finishes the file opening operation and emits a finish event
+func (op *OpenOperation) Finish() {
+	dyngo.FinishOperation(op, OpenOperationRes{})
+}
+
+// File opening operation's start and finish event callback function types.
+type (
+	// OnOpenOperationStart function type, called when a file opening
+	// operation starts.
+	OnOpenOperationStart func(*OpenOperation, OpenOperationArgs)
+	// OnOpenOperationFinish function type, called when a file opening
+	// operation finishes.
+	OnOpenOperationFinish func(*OpenOperation, OpenOperationRes)
+)
+
+var (
+	openOperationArgsType = reflect.TypeOf((*OpenOperationArgs)(nil)).Elem()
+	openOperationResType  = reflect.TypeOf((*OpenOperationRes)(nil)).Elem()
+)
+
+// ListenedType returns the type a OnOpenOperationStart event listener listens
+// to, which is the OpenOperationArgs type.
+func (OnOpenOperationStart) ListenedType() reflect.Type { return openOperationArgsType }
+
+// Call calls the underlying event listener function by performing the
+// type-assertion on v whose type is the one returned by ListenedType().
+func (f OnOpenOperationStart) Call(op dyngo.Operation, v interface{}) {
+	f(op.(*OpenOperation), v.(OpenOperationArgs))
+}
+
+// ListenedType returns the type a OnOpenOperationFinish event listener listens
+// to, which is the OpenOperationRes type.
+func (OnOpenOperationFinish) ListenedType() reflect.Type { return openOperationResType }
+
+// Call calls the underlying event listener function by performing the
+// type-assertion on v whose type is the one returned by ListenedType().
+func (f OnOpenOperationFinish) Call(op dyngo.Operation, v interface{}) {
+	f(op.(*OpenOperation), v.(OpenOperationRes))
+}
@@ -0,0 +1,98 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022 Datadog, Inc.
+
+package httpsec
+
+import (
+	"context"
+	"reflect"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo"
+)
+
+type (
+	// RoundTripOperationArgs is the round trip operation arguments.
+	RoundTripOperationArgs struct {
+		// URL corresponds to the address `server.io.net.url`.
+		URL string
+	}
+
+	// RoundTripOperationRes is the round trip operation results.
+	RoundTripOperationRes struct{}
+
+	// RoundTripOperation type representing the monitoring of an outgoing HTTP
+	// request sent while handling a request, in order to detect and block
+	// server-side request forgeries (SSRF) at the call site (RASP). It must be
+	// created with StartRoundTripOperation() and finished with its Finish()
+	// method.
+	RoundTripOperation struct {
+		dyngo.Operation
+	}
+)
+
+// ProtectRoundTripOperation starts and finishes the round trip operation of the
+// HTTP handler operation held by the given context, so that the server-side
+// request forgeries through the URL of the outgoing request can be detected and
+// blocked before it gets sent. It returns dyngo.ErrBlocked when the request was
+// blocked, in which case the outgoing request must not be sent and the handler
+// is expected to return without writing the response, which is then written by
+// WrapHandler. It is a no-op returning nil when ctx holds no HTTP handler
+// operation, such as the requests sent by background jobs.
+func ProtectRoundTripOperation(ctx context.Context, url string) error {
+	parent := fromContext(ctx)
+	if parent == nil {
+		return nil
+	}
+	op := StartRoundTripOperation(parent, RoundTripOperationArgs{URL: url})
+	op.Finish()
+	return parent.Err()
+}
+
+// StartRoundTripOperation starts the round trip operation and emits a start event
+func StartRoundTripOperation(parent *Operation, args RoundTripOperationArgs) *RoundTripOperation {
+	op := &RoundTripOperation{Operation: dyngo.NewOperation(parent)}
+	dyngo.StartOperation(op, args)
+	return op
+}
+
+// Finish finishes the round trip operation and emits a finish event
+func (op *RoundTripOperation) Finish() {
+	dyngo.FinishOperation(op, RoundTripOperationRes{})
+}
+
+// Round trip operation's start and finish event callback function types.
+type (
+	// OnRoundTripOperationStart function type, called when a round trip
+	// operation starts.
+	OnRoundTripOperationStart func(*RoundTripOperation, RoundTripOperationArgs)
+	// OnRoundTripOperationFinish function type, called when a round trip
+	// operation finishes.
+	OnRoundTripOperationFinish func(*RoundTripOperation, RoundTripOperationRes)
+)
+
+var (
+	roundTripOperationArgsType = reflect.TypeOf((*RoundTripOperationArgs)(nil)).Elem()
+	roundTripOperationResType  = reflect.TypeOf((*RoundTripOperationRes)(nil)).Elem()
+)
+
+// ListenedType returns the type a OnRoundTripOperationStart event listener listens
+// to, which is the RoundTripOperationArgs type.
+func (OnRoundTripOperationStart) ListenedType() reflect.Type { return roundTripOperationArgsType }
+
+// Call calls the underlying event listener function by performing the
+// type-assertion on v whose type is the one returned by ListenedType().
+func (f OnRoundTripOperationStart) Call(op dyngo.Operation, v interface{}) {
+	f(op.(*RoundTripOperation), v.(RoundTripOperationArgs))
+}
+
+// ListenedType returns the type a OnRoundTripOperationFinish event listener listens
+// to, which is the RoundTripOperationRes type.
+func (OnRoundTripOperationFinish) ListenedType() reflect.Type { return roundTripOperationResType }
+
+// Call calls the underlying event listener function by performing the
+// type-assertion on v whose type is the one returned by ListenedType().
+func (f OnRoundTripOperationFinish) Call(op dyngo.Operation, v interface{}) {
+	f(op.(*RoundTripOperation), v.(RoundTripOperationRes))
+}
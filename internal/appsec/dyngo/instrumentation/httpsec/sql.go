@@ -0,0 +1,99 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022 Datadog, Inc.
+
+package httpsec
+
+import (
+	"context"
+	"reflect"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo"
+)
+
+type (
+	// SQLOperationArgs is the SQL operation arguments.
+	SQLOperationArgs struct {
+		// Query corresponds to the address `server.db.statement`.
+		Query string
+		// DBSystem corresponds to the address `server.db.system`, e.g.
+		// "postgresql" or "mysql".
+		DBSystem string
+	}
+
+	// SQLOperationRes is the SQL operation results.
+	SQLOperationRes struct{}
+
+	// SQLOperation type representing the monitoring of a SQL query executed
+	// while handling a request, in order to detect and block SQL injections
+	// at the call site (RASP). It must be created with StartSQLOperation() and
+	// finished with its Finish() method.
+	SQLOperation struct {
+		dyngo.Operation
+	}
+)
+
+// ProtectSQLOperation starts and finishes the SQL operation of the HTTP
+// handler operation held by the given context, so that the SQL injections
+// through the query can be detected and blocked before it gets executed. It
+// returns dyngo.ErrBlocked when the request was blocked, in which case the
+// query must not be executed and the handler is expected to return without
+// writing the response, which is then written by WrapHandler. It is a no-op
+// returning nil when ctx holds no HTTP handler operation, such as the queries
+// executed by background jobs.
+func ProtectSQLOperation(ctx context.Context, query, dbSystem string) error {
+	parent := fromContext(ctx)
+	if parent == nil {
+		return nil
+	}
+	op := StartSQLOperation(parent, SQLOperationArgs{Query: query, DBSystem: dbSystem})
+	op.Finish()
+	return parent.Err()
+}
+
+// StartSQLOperation starts the SQL operation and emits a start event
+func StartSQLOperation(parent *Operation, args SQLOperationArgs) *SQLOperation {
+	op := &SQLOperation{Operation: dyngo.NewOperation(parent)}
+	dyngo.StartOperation(op, args)
+	return op
+}
+
+// Finish finishes the SQL operation and emits a finish event
+func (op *SQLOperation) Finish() {
+	dyngo.FinishOperation(op, SQLOperationRes{})
+}
+
+// SQL operation's start and finish event callback function types.
+type (
+	// OnSQLOperationStart function type, called when a SQL operation starts.
+	OnSQLOperationStart func(*SQLOperation, SQLOperationArgs)
+	// OnSQLOperationFinish function type, called when a SQL operation
+	// finishes.
+	OnSQLOperationFinish func(*SQLOperation, SQLOperationRes)
+)
+
+var (
+	sqlOperationArgsType = reflect.TypeOf((*SQLOperationArgs)(nil)).Elem()
+	sqlOperationResType  = reflect.TypeOf((*SQLOperationRes)(nil)).Elem()
+)
+
+// ListenedType returns the type a OnSQLOperationStart event listener listens
+// to, which is the SQLOperationArgs type.
+func (OnSQLOperationStart) ListenedType() reflect.Type { return sqlOperationArgsType }
+
+// Call calls the underlying event listener function by performing the
+// type-assertion on v whose type is the one returned by ListenedType().
+func (f OnSQLOperationStart) Call(op dyngo.Operation, v interface{}) {
+	f(op.(*SQLOperation), v.(SQLOperationArgs))
+}
+
+// ListenedType returns the type a OnSQLOperationFinish event listener listens
+// to, which is the SQLOperationRes type.
+func (OnSQLOperationFinish) ListenedType() reflect.Type { return sqlOperationResType }
+
+// Call calls the underlying event listener function by performing the
+// type-assertion on v whose type is the one returned by ListenedType().
+func (f OnSQLOperationFinish) Call(op dyngo.Operation, v interface{}) {
+	f(op.(*SQLOperation), v.(SQLOperationRes))
+}
@@ -9,21 +9,19 @@ import (
 	"encoding/json"
 	"net"
 	"net/http"
-	"os"
 	"sort"
 	"strings"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo/instrumentation"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
 )
 
 const (
-	// envClientIPHeader is the name of the env var used to specify the IP header to be used for client IP collection.
-	envClientIPHeader = "DD_TRACE_CLIENT_IP_HEADER"
-	// multipleIPHeaders sets the multiple ip header tag used internally to tell the backend an error occurred when
+	// MultipleIPHeaders sets the multiple ip header tag used internally to tell the backend an error occurred when
 	// retrieving an HTTP request client IP.
-	multipleIPHeaders = "_dd.multiple-ip-headers"
+	MultipleIPHeaders = "_dd.multiple-ip-headers"
 )
 
 var (
@@ -53,13 +51,11 @@ var (
 		"accept",
 		"accept-encoding",
 		"accept-language")
-	clientIPHeader string
 )
 
 func init() {
 	// Required by sort.SearchStrings
 	sort.Strings(collectedHTTPHeaders[:])
-	clientIPHeader = os.Getenv(envClientIPHeader)
 }
 
 // SetSecurityEventTags sets the AppSec-specific span tags when a security event occurred into the service entry span.
@@ -103,43 +99,103 @@ func ippref(s string) *netaddrIPPrefix {
 	return nil
 }
 
-// SetIPTags sets the IP related span tags for a given request
+// SetIPTags sets the IP related span tags for a given request. The client IP
+// header can be configured with tracer.WithClientIPHeader or the
+// DD_TRACE_CLIENT_IP_HEADER environment variable, a list of headers to look up
+// in order with tracer.WithClientIPHeaders or DD_TRACE_CLIENT_IP_HEADERS, and
+// the headers can be left out with tracer.WithClientIPHeaderDisabled or
+// DD_TRACE_CLIENT_IP_HEADER_DISABLED. Private IPs are only reported when allowed
+// with tracer.WithPrivateClientIPs or DD_TRACE_CLIENT_IP_ALLOW_PRIVATE.
 // See https://docs.datadoghq.com/tracing/configure_data_security#configuring-a-client-ip-header for more information.
 func SetIPTags(span instrumentation.TagSetter, r *http.Request) {
-	ipHeaders := defaultIPHeaders
-	if len(clientIPHeader) > 0 {
-		ipHeaders = []string{clientIPHeader}
-	}
-
-	var (
-		headers []string
-		ips     []string
-	)
-	for _, hdr := range ipHeaders {
-		if v := r.Header.Get(hdr); v != "" {
-			headers = append(headers, hdr)
+	clientIP, ipHeaders := ClientIP(r.Header, r.RemoteAddr)
+	if clientIP != "" {
+		span.SetTag(ext.HTTPClientIP, clientIP)
+	}
+	if len(ipHeaders) > 1 {
+		for _, hdr := range ipHeaders {
+			span.SetTag(ext.HTTPRequestHeaders+"."+hdr, r.Header.Get(hdr))
+		}
+		span.SetTag(MultipleIPHeaders, strings.Join(ipHeaders, ","))
+	}
+}
+
+// ClientIP resolves the client IP of a request out of its headers and remote
+// address, and returns the names of the IP headers which were found. The client
+// IP is empty when it cannot be resolved, in particular when more than one of
+// the default IP headers was found, as the client IP is then ambiguous. The
+// headers configured with tracer.WithClientIPHeaders are instead looked up in
+// order, the first one holding a valid IP being used. Non-HTTP integrations
+// such as gRPC use it to share the same client IP resolution logic.
+func ClientIP(hdrs http.Header, remoteAddr string) (clientIP string, ipHeaders []string) {
+	allowPrivate := globalconfig.ClientIPAllowPrivate()
+	if globalconfig.ClientIPHeaderDisabled() {
+		return resolveIP(remoteAddr, allowPrivate), nil
+	}
+
+	candidates := defaultIPHeaders
+	if clientIPHeader := globalconfig.ClientIPHeader(); len(clientIPHeader) > 0 {
+		candidates = []string{clientIPHeader}
+	} else if headers := globalconfig.ClientIPHeaders(); len(headers) > 0 {
+		return clientIPByPriority(hdrs, remoteAddr, headers, allowPrivate)
+	}
+
+	var ips []string
+	for _, hdr := range candidates {
+		if v := hdrs.Get(hdr); v != "" {
+			ipHeaders = append(ipHeaders, hdr)
 			ips = append(ips, v)
 		}
 	}
 
-	if l := len(ips); l == 0 {
-		if remoteIP := parseIP(r.RemoteAddr); remoteIP.IsValid() && isGlobal(remoteIP) {
-			span.SetTag(ext.HTTPClientIP, remoteIP.String())
+	switch len(ips) {
+	case 0:
+		return resolveIP(remoteAddr, allowPrivate), nil
+	case 1:
+		return resolveIP(ips[0], allowPrivate), ipHeaders
+	}
+	return "", ipHeaders
+}
+
+// clientIPByPriority resolves the client IP out of the first of the given
+// headers holding a valid IP, which is the only IP header returned, or out of
+// the remote address when none of the headers is found.
+func clientIPByPriority(hdrs http.Header, remoteAddr string, headers []string, allowPrivate bool) (string, []string) {
+	var found bool
+	for _, hdr := range headers {
+		v := hdrs.Get(hdr)
+		if v == "" {
+			continue
+		}
+		if ip := resolveIP(v, allowPrivate); ip != "" {
+			return ip, []string{hdr}
+		}
+		found = true
+	}
+	if found {
+		return "", nil
+	}
+	return resolveIP(remoteAddr, allowPrivate), nil
+}
+
+// resolveIP returns the first global IP of the comma-separated list of IPs v,
+// or, when allowPrivate is true and there is none, its first private IP.
+// Loopback and link-local IPs are never returned.
+func resolveIP(v string, allowPrivate bool) string {
+	var private string
+	for _, ipstr := range strings.Split(v, ",") {
+		ip := parseIP(strings.TrimSpace(ipstr))
+		if !ip.IsValid() {
+			continue
 		}
-	} else if l == 1 {
-		for _, ipstr := range strings.Split(ips[0], ",") {
-			ip := parseIP(strings.TrimSpace(ipstr))
-			if ip.IsValid() && isGlobal(ip) {
-				span.SetTag(ext.HTTPClientIP, ip.String())
-				break
-			}
+		if isGlobal(ip) {
+			return ip.String()
 		}
-	} else {
-		for i := range ips {
-			span.SetTag(ext.HTTPRequestHeaders+"."+headers[i], ips[i])
+		if allowPrivate && private == "" && ip.IsPrivate() {
+			private = ip.String()
 		}
-		span.SetTag(multipleIPHeaders, strings.Join(headers, ","))
 	}
+	return private
 }
 
 func parseIP(s string) netaddrIP {
@@ -11,6 +11,7 @@ import (
 	"testing"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
 
 	"github.com/stretchr/testify/require"
 )
@@ -61,6 +62,11 @@ type ipTestCase struct {
 	expectedIP     netaddrIP
 	multiHeaders   string
 	clientIPHeader string
+	// clientIPHeaders, headerDisabled and allowPrivate set the corresponding
+	// global configuration of the client IP resolution.
+	clientIPHeaders []string
+	headerDisabled  bool
+	allowPrivate    bool
 }
 
 func genIPTestCases() []ipTestCase {
@@ -187,6 +193,71 @@ func genIPTestCases() []ipTestCase {
 			headers:        map[string]string{"x-forwarded-for": ipv4Global},
 			clientIPHeader: "custom-header",
 		},
+		{
+			name:            "user-headers-priority",
+			expectedIP:      netaddrMustParseIP(ipv4Global),
+			headers:         map[string]string{"x-forwarded-for": ipv6Global, "true-client-ip": ipv4Global, "custom-header": ipv4Private},
+			clientIPHeaders: []string{"custom-header", "true-client-ip", "x-forwarded-for"},
+		},
+		{
+			name:            "user-headers-allow-private",
+			expectedIP:      netaddrMustParseIP(ipv4Private),
+			headers:         map[string]string{"x-forwarded-for": ipv6Global, "custom-header": ipv4Private},
+			clientIPHeaders: []string{"custom-header", "x-forwarded-for"},
+			allowPrivate:    true,
+		},
+		{
+			name:            "user-headers-invalid",
+			expectedIP:      netaddrIP{},
+			headers:         map[string]string{"x-forwarded-for": "127.0.0.1", "true-client-ip": ipv4Private},
+			clientIPHeaders: []string{"x-forwarded-for", "true-client-ip"},
+		},
+		{
+			name:            "user-headers-not-found",
+			expectedIP:      netaddrMustParseIP(ipv6Global),
+			remoteAddr:      ipv6Global,
+			headers:         map[string]string{"x-real-ip": ipv4Global},
+			clientIPHeaders: []string{"custom-header"},
+		},
+		{
+			name:         "allow-private-xff",
+			expectedIP:   netaddrMustParseIP(ipv4Private),
+			headers:      map[string]string{"x-forwarded-for": "127.0.0.1," + ipv4Private + ",10.0.0.1"},
+			allowPrivate: true,
+		},
+		{
+			name:         "allow-private-xff-global-first",
+			expectedIP:   netaddrMustParseIP(ipv4Global),
+			headers:      map[string]string{"x-forwarded-for": ipv4Private + "," + ipv4Global},
+			allowPrivate: true,
+		},
+		{
+			name:         "allow-private-remote-addr",
+			expectedIP:   netaddrMustParseIP(ipv4Private),
+			remoteAddr:   ipv4Private + ":8080",
+			allowPrivate: true,
+		},
+		{
+			name:         "allow-private-loopback",
+			expectedIP:   netaddrIP{},
+			remoteAddr:   "127.0.0.1:8080",
+			allowPrivate: true,
+		},
+		{
+			name:           "header-disabled",
+			expectedIP:     netaddrMustParseIP(ipv6Global),
+			remoteAddr:     "[" + ipv6Global + "]:8080",
+			headers:        map[string]string{"x-forwarded-for": ipv4Global, "custom-header": ipv4Global},
+			clientIPHeader: "custom-header",
+			headerDisabled: true,
+		},
+		{
+			name:           "header-disabled-multiple",
+			expectedIP:     netaddrMustParseIP(ipv4Global),
+			remoteAddr:     ipv4Global,
+			headers:        map[string]string{"x-forwarded-for": ipv4Global, "x-real-ip": ipv6Global},
+			headerDisabled: true,
+		},
 	}, tcs...)
 
 	return tcs
@@ -211,8 +282,11 @@ func (m *mockspan) Tag(tag string) interface{} {
 }
 
 func TestIPHeaders(t *testing.T) {
-	// Make sure to restore the real value of clientIPHeader at the end of the test
-	defer func(s string) { clientIPHeader = s }(clientIPHeader)
+	// Make sure to restore the real client IP configuration at the end of the test
+	defer globalconfig.SetClientIPHeader(globalconfig.ClientIPHeader())
+	defer globalconfig.SetClientIPHeaders(globalconfig.ClientIPHeaders())
+	defer globalconfig.SetClientIPHeaderDisabled(globalconfig.ClientIPHeaderDisabled())
+	defer globalconfig.SetClientIPAllowPrivate(globalconfig.ClientIPAllowPrivate())
 	for _, tc := range genIPTestCases() {
 		t.Run(tc.name, func(t *testing.T) {
 			header := http.Header{}
@@ -220,16 +294,19 @@ func TestIPHeaders(t *testing.T) {
 				header.Add(k, v)
 			}
 			r := http.Request{Header: header, RemoteAddr: tc.remoteAddr}
-			clientIPHeader = tc.clientIPHeader
+			globalconfig.SetClientIPHeader(tc.clientIPHeader)
+			globalconfig.SetClientIPHeaders(tc.clientIPHeaders)
+			globalconfig.SetClientIPHeaderDisabled(tc.headerDisabled)
+			globalconfig.SetClientIPAllowPrivate(tc.allowPrivate)
 			var span mockspan
 			SetIPTags(&span, &r)
 			if tc.expectedIP.IsValid() {
 				require.Equal(t, tc.expectedIP.String(), span.Tag(ext.HTTPClientIP))
-				require.Nil(t, span.Tag(multipleIPHeaders))
+				require.Nil(t, span.Tag(MultipleIPHeaders))
 			} else {
 				require.Nil(t, span.Tag(ext.HTTPClientIP))
 				if tc.multiHeaders != "" {
-					require.Equal(t, tc.multiHeaders, span.Tag(multipleIPHeaders))
+					require.Equal(t, tc.multiHeaders, span.Tag(MultipleIPHeaders))
 					for hdr, ip := range tc.headers {
 						require.Equal(t, ip, span.Tag(ext.HTTPRequestHeaders+"."+hdr))
 					}
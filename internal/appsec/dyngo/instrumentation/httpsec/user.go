@@ -0,0 +1,97 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022 Datadog, Inc.
+
+package httpsec
+
+import (
+	"context"
+	"reflect"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+)
+
+type (
+	// UserIDOperationArgs is the user ID operation arguments.
+	UserIDOperationArgs struct {
+		// UserID corresponds to the address `usr.id`.
+		UserID string
+	}
+
+	// UserIDOperationRes is the user ID operation results.
+	UserIDOperationRes struct{}
+
+	// UserIDOperation type representing the monitoring of the ID of the user
+	// of a request. It must be created with StartUserIDOperation() and
+	// finished with its Finish() method.
+	UserIDOperation struct {
+		dyngo.Operation
+	}
+)
+
+// MonitorUser starts and finishes the user ID operation of the HTTP handler
+// operation held by the given context, so that the request can be blocked
+// based on the ID of its authenticated user. It returns dyngo.ErrBlocked when
+// the request was blocked, in which case the handler is expected to return
+// without writing the response, which is then written by WrapHandler. This
+// function should not be called when AppSec is disabled in order to get
+// preciser error logs.
+func MonitorUser(ctx context.Context, id string) error {
+	parent := fromContext(ctx)
+	if parent == nil {
+		log.Error("appsec: user id monitoring ignored: could not find the http handler instrumentation metadata in the request context: the request handler is not being monitored by a middleware function or the provided context is not the expected request context")
+		return nil
+	}
+	op := StartUserIDOperation(parent, UserIDOperationArgs{UserID: id})
+	op.Finish()
+	return parent.Err()
+}
+
+// StartUserIDOperation starts the user ID operation and emits a start event
+func StartUserIDOperation(parent *Operation, args UserIDOperationArgs) *UserIDOperation {
+	op := &UserIDOperation{Operation: dyngo.NewOperation(parent)}
+	dyngo.StartOperation(op, args)
+	return op
+}
+
+// Finish finishes the user ID operation and emits a finish event
+func (op *UserIDOperation) Finish() {
+	dyngo.FinishOperation(op, UserIDOperationRes{})
+}
+
+// User ID operation's start and finish event callback function types.
+type (
+	// OnUserIDOperationStart function type, called when a user ID
+	// operation starts.
+	OnUserIDOperationStart func(*UserIDOperation, UserIDOperationArgs)
+	// OnUserIDOperationFinish function type, called when a user ID
+	// operation finishes.
+	OnUserIDOperationFinish func(*UserIDOperation, UserIDOperationRes)
+)
+
+var (
+	userIDOperationArgsType = reflect.TypeOf((*UserIDOperationArgs)(nil)).Elem()
+	userIDOperationResType  = reflect.TypeOf((*UserIDOperationRes)(nil)).Elem()
+)
+
+// ListenedType returns the type a OnUserIDOperationStart event listener
+// listens to, which is the UserIDOperationArgs type.
+func (OnUserIDOperationStart) ListenedType() reflect.Type { return userIDOperationArgsType }
+
+// Call calls the underlying event listener function by performing the
+// type-assertion on v whose type is the one returned by ListenedType().
+func (f OnUserIDOperationStart) Call(op dyngo.Operation, v interface{}) {
+	f(op.(*UserIDOperation), v.(UserIDOperationArgs))
+}
+
+// ListenedType returns the type a OnUserIDOperationFinish event listener
+// listens to, which is the UserIDOperationRes type.
+func (OnUserIDOperationFinish) ListenedType() reflect.Type { return userIDOperationResType }
+
+// Call calls the underlying event listener function by performing the
+// type-assertion on v whose type is the one returned by ListenedType().
+func (f OnUserIDOperationFinish) Call(op dyngo.Operation, v interface{}) {
+	f(op.(*UserIDOperation), v.(UserIDOperationRes))
+}
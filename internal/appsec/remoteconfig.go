@@ -11,6 +11,8 @@ package appsec
 import (
 	"encoding/json"
 	"fmt"
+	"os"
+	"sort"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/waf"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
@@ -84,66 +86,283 @@ func (a *appsec) asmFeaturesCallback(u remoteconfig.ProductUpdate) map[string]rc
 }
 
 func (a *appsec) startRC() {
-	if a.rc != nil {
+	if a.rc != nil && !a.rcShared {
 		a.rc.Start()
 	}
 }
 
 func (a *appsec) stopRC() {
-	if a.rc != nil {
+	if a.rc != nil && !a.rcShared {
 		a.rc.Stop()
 	}
 }
 
-func (a *appsec) registerRCProduct(product string) error {
+func (a *appsec) enableRemoteActivation() error {
 	if a.rc == nil {
 		return fmt.Errorf("no valid remote configuration client")
 	}
-	// Don't do anything if the product is already registered
-	for _, p := range a.rc.Products {
-		if p == product {
-			return nil
-		}
+	// First verify that the WAF is in good health. We perform this check in order not to falsely "allow" users to
+	// activate ASM through remote config if activation would fail when trying to register a WAF handle
+	// (ex: if the service runs on an unsupported platform).
+	if err := waf.Health(); err != nil {
+		log.Debug("appsec: WAF health check failed, remote activation will be disabled: %v", err)
+		return err
 	}
-	a.rc.Products = append(a.rc.Products, product)
+	a.rc.Subscribe(rc.ProductASMFeatures, a.asmFeaturesCallback, remoteconfig.ASMActivation)
 	return nil
 }
-func (a *appsec) registerRCCapability(c remoteconfig.Capability) error {
-	if a.rc == nil {
-		return fmt.Errorf("no valid remote configuration client")
+
+// rulesManager holds the security rules of the WAF, made of a base ruleset, the default one or the one of the ASM_DD
+// configuration, along with the rules data of the ASM_DATA configurations, such as the IP and user denylists, and the
+// rule overrides of the ASM configurations, by configuration path.
+type rulesManager struct {
+	base      []byte
+	data      map[string][]remoteconfig.ASMDataRuleData
+	overrides map[string][]ruleOverride
+}
+
+// ruleOverride enables or disables the rule of the given ID.
+type ruleOverride struct {
+	ID      string `json:"id"`
+	Enabled bool   `json:"enabled"`
+}
+
+// asmConfig is the content of the configurations of the ASM remote config product.
+type asmConfig struct {
+	RulesOverride []ruleOverride `json:"rules_override"`
+}
+
+func newRulesManager(base []byte) *rulesManager {
+	return &rulesManager{
+		base:      base,
+		data:      make(map[string][]remoteconfig.ASMDataRuleData),
+		overrides: make(map[string][]ruleOverride),
+	}
+}
+
+// clone returns a copy of r which can be updated without affecting r.
+func (r *rulesManager) clone() *rulesManager {
+	c := newRulesManager(r.base)
+	for path, data := range r.data {
+		c.data[path] = data
 	}
-	// Don't do anything if the capability is already registered
-	for _, cap := range a.rc.Capabilities {
-		if cap == c {
-			return nil
+	for path, overrides := range r.overrides {
+		c.overrides[path] = overrides
+	}
+	return c
+}
+
+// rulesData returns the rules data of the ASM_DATA configurations merged by rule data ID and type, sorted by ID. The
+// values found in several configurations are kept with their latest expiration, 0 meaning that they never expire.
+func (r *rulesManager) rulesData() []remoteconfig.ASMDataRuleData {
+	type key struct{ id, typ string }
+	merged := make(map[key]map[string]int64)
+	for _, data := range r.data {
+		for _, d := range data {
+			k := key{d.ID, d.Type}
+			values := merged[k]
+			if values == nil {
+				values = make(map[string]int64)
+				merged[k] = values
+			}
+			for _, e := range d.Data {
+				if exp, ok := values[e.Value]; !ok || (exp != 0 && (e.Expiration == 0 || e.Expiration > exp)) {
+					values[e.Value] = e.Expiration
+				}
+			}
 		}
 	}
-	a.rc.Capabilities = append(a.rc.Capabilities, c)
-	return nil
+	data := make([]remoteconfig.ASMDataRuleData, 0, len(merged))
+	for k, values := range merged {
+		d := remoteconfig.ASMDataRuleData{ID: k.id, Type: k.typ, Data: make([]remoteconfig.ASMDataRuleDataEntry, 0, len(values))}
+		for v, exp := range values {
+			d.Data = append(d.Data, remoteconfig.ASMDataRuleDataEntry{Value: v, Expiration: exp})
+		}
+		sort.Slice(d.Data, func(i, j int) bool { return d.Data[i].Value < d.Data[j].Value })
+		data = append(data, d)
+	}
+	sort.Slice(data, func(i, j int) bool {
+		if data[i].ID != data[j].ID {
+			return data[i].ID < data[j].ID
+		}
+		return data[i].Type < data[j].Type
+	})
+	return data
 }
 
-func (a *appsec) registerRCCallback(c remoteconfig.Callback, product string) error {
-	if a.rc != nil {
-		return fmt.Errorf("no valid remote configuration client")
+// toggles returns the states of the rules overridden by the ASM configurations, applied in the order of their paths.
+func (r *rulesManager) toggles() map[string]bool {
+	paths := make([]string, 0, len(r.overrides))
+	for path := range r.overrides {
+		paths = append(paths, path)
 	}
-	a.rc.RegisterCallback(c, product)
-	return nil
+	sort.Strings(paths)
+	toggles := make(map[string]bool)
+	for _, path := range paths {
+		for _, o := range r.overrides[path] {
+			toggles[o.ID] = o.Enabled
+		}
+	}
+	return toggles
+}
 
+// newHandle returns a new WAF handle made of the rules of r.
+func (r *rulesManager) newHandle(obfCfg *ObfuscatorConfig) (handle *waf.Handle, err error) {
+	handle, err = waf.NewHandle(r.base, obfCfg.KeyRegex, obfCfg.ValueRegex)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			handle.Close()
+			handle = nil
+		}
+	}()
+	if data := r.rulesData(); len(data) > 0 {
+		buf, err := json.Marshal(data)
+		if err != nil {
+			return nil, err
+		}
+		if err := handle.UpdateRuleData(buf); err != nil {
+			return nil, err
+		}
+	}
+	if toggles := r.toggles(); len(toggles) > 0 {
+		if err := handle.ToggleRules(toggles); err != nil {
+			return nil, err
+		}
+	}
+	return handle, nil
 }
 
-func (a *appsec) enableRemoteActivation() error {
+// asmDDCallback replaces the base ruleset with the one of the ASM_DD configuration received through remote config, or
+// with the default one when the configuration is removed. Used as a callback for the ASM_DD remote config product.
+func (a *appsec) asmDDCallback(u remoteconfig.ProductUpdate) map[string]rc.ApplyStatus {
+	statuses := defaultStatusesFromUpdate(u, false)
+	if l := len(u); l == 0 {
+		return statuses
+	} else if l > 1 {
+		log.Error("appsec: Remote config: %d configs received for ASM_DD. Expected one at most, returning early", l)
+		return statuses
+	}
+	rules := a.rules.clone()
+	for path, raw := range u {
+		log.Debug("appsec: Remote config: processing %s", path)
+		if raw == nil {
+			log.Debug("appsec: Remote config: reverting to the default security rules")
+			rules.base = a.cfg.rules
+		} else {
+			rules.base = raw
+		}
+	}
+	return a.applyRulesUpdate(u, rules, statuses)
+}
+
+// asmDataCallback updates the rules data, such as the IP and user denylists, with the ones of the ASM_DATA
+// configurations received through remote config. Used as a callback for the ASM_DATA remote config product.
+func (a *appsec) asmDataCallback(u remoteconfig.ProductUpdate) map[string]rc.ApplyStatus {
+	statuses := defaultStatusesFromUpdate(u, false)
+	if len(u) == 0 {
+		return statuses
+	}
+	rules := a.rules.clone()
+	for path, raw := range u {
+		log.Debug("appsec: Remote config: processing %s", path)
+		if raw == nil {
+			delete(rules.data, path)
+			continue
+		}
+		var data remoteconfig.ASMDataRulesData
+		if err := json.Unmarshal(raw, &data); err != nil {
+			log.Error("appsec: Remote config: error while unmarshalling %s: %v. Configuration won't be applied.", path, err)
+			statuses[path] = genApplyStatus(false, err)
+			continue
+		}
+		rules.data[path] = data.RulesData
+	}
+	return a.applyRulesUpdate(u, rules, statuses)
+}
+
+// asmCallback updates the rule overrides with the ones of the ASM configurations received through remote config.
+// Used as a callback for the ASM remote config product.
+func (a *appsec) asmCallback(u remoteconfig.ProductUpdate) map[string]rc.ApplyStatus {
+	statuses := defaultStatusesFromUpdate(u, false)
+	if len(u) == 0 {
+		return statuses
+	}
+	rules := a.rules.clone()
+	for path, raw := range u {
+		log.Debug("appsec: Remote config: processing %s", path)
+		if raw == nil {
+			delete(rules.overrides, path)
+			continue
+		}
+		var cfg asmConfig
+		if err := json.Unmarshal(raw, &cfg); err != nil {
+			log.Error("appsec: Remote config: error while unmarshalling %s: %v. Configuration won't be applied.", path, err)
+			statuses[path] = genApplyStatus(false, err)
+			continue
+		}
+		rules.overrides[path] = cfg.RulesOverride
+	}
+	return a.applyRulesUpdate(u, rules, statuses)
+}
+
+// applyRulesUpdate replaces the security rules with the given ones, updated with the configurations of u, and returns
+// the statuses of these configurations, whose ones without an error status are acknowledged when the rules could be
+// updated.
+func (a *appsec) applyRulesUpdate(u remoteconfig.ProductUpdate, rules *rulesManager, statuses map[string]rc.ApplyStatus) map[string]rc.ApplyStatus {
+	err := a.updateRules(rules)
+	if err != nil {
+		log.Error("appsec: Remote config: could not update the security rules: %v. Configuration won't be applied.", err)
+	}
+	for path, raw := range u {
+		if raw == nil || statuses[path].State == rc.ApplyStateError {
+			continue
+		}
+		statuses[path] = genApplyStatus(true, err)
+	}
+	return statuses
+}
+
+// updateRules replaces the security rules with the given ones. When AppSec is started, the WAF is swapped with a new
+// one made of these rules: the new requests are monitored by the new WAF, while the requests in flight keep using the
+// previous one, released once they are done. The rules are left unchanged when the new WAF can't be created.
+func (a *appsec) updateRules(rules *rulesManager) error {
+	if a.started {
+		unregisterWAF, err := registerWAF(rules, a.cfg.wafTimeout, a.limiter, &a.cfg.obfuscator, a.cfg.apiSec)
+		if err != nil {
+			return err
+		}
+		a.unregisterWAF()
+		a.unregisterWAF = unregisterWAF
+	} else {
+		// Only check the rules, which will be used once AppSec is started
+		handle, err := rules.newHandle(&a.cfg.obfuscator)
+		if err != nil {
+			return err
+		}
+		handle.Close()
+	}
+	a.rules = rules
+	return nil
+}
+
+// enableRulesUpdates subscribes to the remote config products updating the security rules: ASM_DD for the ruleset,
+// ASM_DATA for the rules data, such as the IP and user denylists, and ASM for the rule overrides. The rules loaded
+// from the file set by DD_APPSEC_RULES are never updated.
+func (a *appsec) enableRulesUpdates() error {
 	if a.rc == nil {
 		return fmt.Errorf("no valid remote configuration client")
 	}
-	// First verify that the WAF is in good health. We perform this check in order not to falsely "allow" users to
-	// activate ASM through remote config if activation would fail when trying to register a WAF handle
-	// (ex: if the service runs on an unsupported platform).
+	if os.Getenv(rulesEnvVar) != "" {
+		return fmt.Errorf("the security rules are set by %s", rulesEnvVar)
+	}
 	if err := waf.Health(); err != nil {
-		log.Debug("appsec: WAF health check failed, remote activation will be disabled: %v", err)
 		return err
 	}
-	a.registerRCProduct(rc.ProductASMFeatures)
-	a.registerRCCapability(remoteconfig.ASMActivation)
-	a.registerRCCallback(a.asmFeaturesCallback, rc.ProductASMFeatures)
+	a.rc.Subscribe(rc.ProductASMDD, a.asmDDCallback, remoteconfig.ASMDDRules)
+	a.rc.Subscribe(remoteconfig.ProductASMData, a.asmDataCallback, remoteconfig.ASMIPBlocking, remoteconfig.ASMUserBlocking)
+	a.rc.Subscribe(remoteconfig.ProductASM, a.asmCallback, remoteconfig.ASMRuleOverrides)
 	return nil
 }
@@ -9,12 +9,14 @@
 package appsec
 
 import (
+	"context"
 	"os"
 	"testing"
 
 	rc "github.com/DataDog/datadog-agent/pkg/remoteconfig/state"
 	"github.com/stretchr/testify/require"
 
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo/instrumentation/httpsec"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/waf"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/remoteconfig"
 )
@@ -146,4 +148,167 @@ func TestRemoteActivationScenarios(t *testing.T) {
 		require.Nil(t, activeAppSec)
 		require.False(t, Enabled())
 	})
+
+	t.Run("shared client", func(t *testing.T) {
+		t.Setenv(enabledEnvVar, "")
+		os.Unsetenv(enabledEnvVar)
+		client, err := remoteconfig.NewClient(remoteconfig.DefaultClientConfig())
+		require.NoError(t, err)
+		Start(WithRCClient(client))
+
+		require.NotNil(t, activeAppSec)
+		require.Same(t, client, activeAppSec.rc)
+		require.Contains(t, client.Capabilities, remoteconfig.ASMActivation)
+		require.Contains(t, client.Products, rc.ProductASMFeatures)
+		// Stopping AppSec must leave the shared client to its owner
+		Stop()
+		client.Start()
+		client.Stop()
+	})
+}
+
+func TestRulesManager(t *testing.T) {
+	r := newRulesManager([]byte("{}"))
+	r.data["path/1"] = []remoteconfig.ASMDataRuleData{
+		{ID: "blocked_ips", Type: "ip_with_expiration", Data: []remoteconfig.ASMDataRuleDataEntry{
+			{Value: "1.2.3.4", Expiration: 100},
+			{Value: "5.6.7.8", Expiration: 100},
+			{Value: "9.9.9.9"},
+		}},
+	}
+	r.data["path/2"] = []remoteconfig.ASMDataRuleData{
+		{ID: "blocked_ips", Type: "ip_with_expiration", Data: []remoteconfig.ASMDataRuleDataEntry{
+			{Value: "1.2.3.4", Expiration: 200},
+			{Value: "5.6.7.8"},
+			{Value: "9.9.9.9", Expiration: 300},
+		}},
+		{ID: "blocked_users", Type: "data_with_expiration", Data: []remoteconfig.ASMDataRuleDataEntry{
+			{Value: "bob"},
+		}},
+	}
+	r.overrides["path/1"] = []ruleOverride{{ID: "rule-1", Enabled: false}, {ID: "rule-2", Enabled: false}}
+	r.overrides["path/2"] = []ruleOverride{{ID: "rule-2", Enabled: true}}
+
+	require.Equal(t, []remoteconfig.ASMDataRuleData{
+		{ID: "blocked_ips", Type: "ip_with_expiration", Data: []remoteconfig.ASMDataRuleDataEntry{
+			{Value: "1.2.3.4", Expiration: 200},
+			{Value: "5.6.7.8"},
+			{Value: "9.9.9.9"},
+		}},
+		{ID: "blocked_users", Type: "data_with_expiration", Data: []remoteconfig.ASMDataRuleDataEntry{
+			{Value: "bob"},
+		}},
+	}, r.rulesData())
+	require.Equal(t, map[string]bool{"rule-1": false, "rule-2": true}, r.toggles())
+
+	c := r.clone()
+	delete(c.data, "path/1")
+	delete(c.overrides, "path/2")
+	require.Len(t, r.data, 2)
+	require.Len(t, r.overrides, 2)
+}
+
+func TestRulesUpdateCallbacks(t *testing.T) {
+	if waf.Health() != nil {
+		t.Skip("WAF cannot be used")
+	}
+	blockingRules := []byte(`{
+  "version": "2.1",
+  "metadata": {"rules_version": "1.2.3"},
+  "rules": [
+    {
+      "id": "blk-001-001",
+      "name": "Block IP addresses",
+      "tags": {"type": "block_ip", "category": "security_response"},
+      "conditions": [
+        {
+          "parameters": {"inputs": [{"address": "http.client_ip"}], "data": "blocked_ips"},
+          "operator": "ip_match"
+        }
+      ],
+      "transformers": [],
+      "on_match": ["block"]
+    }
+  ]
+}`)
+	blockedIPs := []byte(`{"rules_data": [{"id": "blocked_ips", "type": "ip_with_expiration", "data": [{"value": "1.2.3.4"}]}]}`)
+	disabled := []byte(`{"rules_override": [{"id": "blk-001-001", "enabled": false}]}`)
+
+	cfg, err := newConfig()
+	require.NoError(t, err)
+	a := newAppSec(cfg)
+	require.NoError(t, a.start())
+	defer a.stop()
+
+	blocked := func(ip string) bool {
+		_, op := httpsec.StartOperation(context.Background(), httpsec.HandlerOperationArgs{ClientIP: ip})
+		defer op.Finish(httpsec.HandlerOperationRes{})
+		return op.Err() != nil
+	}
+	acknowledged := map[string]rc.ApplyStatus{"path": {State: rc.ApplyStateAcknowledged}}
+
+	require.Equal(t, acknowledged, a.asmDDCallback(remoteconfig.ProductUpdate{"path": blockingRules}))
+	require.False(t, blocked("1.2.3.4"))
+
+	require.Equal(t, acknowledged, a.asmDataCallback(remoteconfig.ProductUpdate{"path": blockedIPs}))
+	require.True(t, blocked("1.2.3.4"))
+	require.False(t, blocked("5.6.7.8"))
+
+	require.Equal(t, acknowledged, a.asmCallback(remoteconfig.ProductUpdate{"path": disabled}))
+	require.False(t, blocked("1.2.3.4"))
+	a.asmCallback(remoteconfig.ProductUpdate{"path": nil})
+	require.True(t, blocked("1.2.3.4"))
+
+	t.Run("invalid", func(t *testing.T) {
+		statuses := a.asmDDCallback(remoteconfig.ProductUpdate{"path": []byte(`{"version": "2.1", "rules": []}`)})
+		require.Equal(t, rc.ApplyStateError, statuses["path"].State)
+		statuses = a.asmDataCallback(remoteconfig.ProductUpdate{"path": []byte("ImABadPayload")})
+		require.Equal(t, rc.ApplyStateError, statuses["path"].State)
+		// The previous rules are kept
+		require.True(t, blocked("1.2.3.4"))
+	})
+
+	t.Run("stopped", func(t *testing.T) {
+		// The rules updated while AppSec is stopped are used once it is started again
+		a.stop()
+		a.asmDataCallback(remoteconfig.ProductUpdate{"path": nil})
+		require.NoError(t, a.start())
+		require.False(t, blocked("1.2.3.4"))
+	})
+
+	t.Run("removed", func(t *testing.T) {
+		a.asmDDCallback(remoteconfig.ProductUpdate{"path": nil})
+		require.Equal(t, cfg.rules, a.rules.base)
+	})
+}
+
+func TestRulesUpdateSubscriptions(t *testing.T) {
+	if waf.Health() != nil {
+		t.Skip("WAF cannot be used")
+	}
+
+	t.Run("default", func(t *testing.T) {
+		t.Setenv(enabledEnvVar, "true")
+		Start(WithRCConfig(remoteconfig.DefaultClientConfig()))
+		defer Stop()
+
+		client := activeAppSec.rc
+		require.NotNil(t, client)
+		require.Subset(t, client.Products, []string{rc.ProductASMDD, remoteconfig.ProductASMData, remoteconfig.ProductASM})
+		require.Subset(t, client.Capabilities, []remoteconfig.Capability{
+			remoteconfig.ASMDDRules, remoteconfig.ASMIPBlocking, remoteconfig.ASMUserBlocking, remoteconfig.ASMRuleOverrides,
+		})
+	})
+
+	t.Run("rules-file", func(t *testing.T) {
+		t.Setenv(enabledEnvVar, "true")
+		t.Setenv(rulesEnvVar, "testdata/blocking.json")
+		Start(WithRCConfig(remoteconfig.DefaultClientConfig()))
+		defer Stop()
+
+		client := activeAppSec.rc
+		require.NotNil(t, client)
+		require.NotContains(t, client.Products, rc.ProductASMDD)
+		require.NotContains(t, client.Products, remoteconfig.ProductASMData)
+	})
 }
@@ -0,0 +1,111 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022 Datadog, Inc.
+
+//go:build appsec
+// +build appsec
+
+package appsec
+
+import (
+	"encoding/json"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+)
+
+const (
+	// stackTraceAction is the WAF action of the security rules whose matches
+	// must report the stack trace of their call site, such as the RASP rules.
+	stackTraceAction = "stack_trace"
+
+	// stackTag is the span tag holding the JSON encoding of the stack traces
+	// collected by a request.
+	stackTag = "_dd.stack"
+
+	// maxStackTraces is the maximum number of stack traces collected per
+	// request, and maxStackDepth the maximum number of frames per stack trace.
+	maxStackTraces = 2
+	maxStackDepth  = 32
+
+	// tracerPackagePrefix is the prefix of the functions of the tracer, which
+	// are not reported in the stack traces to only keep the call site.
+	tracerPackagePrefix = "gopkg.in/DataDog/dd-trace-go.v1/"
+)
+
+type (
+	// stackTraces holds the stack traces collected by a request, reported
+	// along with its security events.
+	stackTraces struct {
+		mu      sync.Mutex
+		Exploit []stackTrace `json:"exploit"`
+	}
+
+	stackTrace struct {
+		ID       string       `json:"id"`
+		Language string       `json:"language"`
+		Frames   []stackFrame `json:"frames"`
+	}
+
+	stackFrame struct {
+		ID       int    `json:"id"`
+		Function string `json:"function"`
+		File     string `json:"file"`
+		Line     int    `json:"line"`
+	}
+)
+
+// collect adds the stack trace of the calling goroutine, without the frames of
+// the tracer, unless maxStackTraces were already collected.
+func (s *stackTraces) collect() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.Exploit) >= maxStackTraces {
+		return
+	}
+	s.Exploit = append(s.Exploit, stackTrace{
+		ID:       strconv.Itoa(len(s.Exploit)),
+		Language: "go",
+		// skip runtime.Callers, callerStack and collect
+		Frames: callerStack(3),
+	})
+}
+
+// tag returns the JSON encoding of the collected stack traces, or an empty
+// string when there are none.
+func (s *stackTraces) tag() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.Exploit) == 0 {
+		return ""
+	}
+	b, err := json.Marshal(s)
+	if err != nil {
+		log.Error("appsec: could not marshal the stack traces to json: %v", err)
+		return ""
+	}
+	return string(b)
+}
+
+// callerStack returns the stack of the caller skip frames above, as reported
+// by runtime.Callers, without the frames of the tracer.
+func callerStack(skip int) []stackFrame {
+	pcs := make([]uintptr, maxStackDepth)
+	n := runtime.Callers(skip, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	var stack []stackFrame
+	for {
+		f, more := frames.Next()
+		if !strings.HasPrefix(f.Function, tracerPackagePrefix) {
+			stack = append(stack, stackFrame{ID: len(stack), Function: f.Function, File: f.File, Line: f.Line})
+		}
+		if !more {
+			break
+		}
+	}
+	return stack
+}
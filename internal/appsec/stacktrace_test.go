@@ -0,0 +1,44 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022 Datadog, Inc.
+
+//go:build appsec
+// +build appsec
+
+package appsec
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStackTraces(t *testing.T) {
+	var s stackTraces
+	require.Empty(t, s.tag())
+
+	for i := 0; i < maxStackTraces+1; i++ {
+		s.collect()
+	}
+	require.Len(t, s.Exploit, maxStackTraces)
+
+	var decoded struct {
+		Exploit []stackTrace `json:"exploit"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(s.tag()), &decoded))
+	require.Len(t, decoded.Exploit, maxStackTraces)
+	for i, st := range decoded.Exploit {
+		require.Equal(t, "go", st.Language)
+		require.NotEmpty(t, st.ID)
+		require.NotEmpty(t, st.Frames)
+		for j, f := range st.Frames {
+			// the frames of the tracer, such as this test's, are not reported
+			require.False(t, strings.HasPrefix(f.Function, tracerPackagePrefix), f.Function)
+			require.Equal(t, j, f.ID)
+		}
+		require.Equal(t, "testing.tRunner", st.Frames[0].Function, i)
+	}
+}
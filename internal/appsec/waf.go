@@ -35,17 +35,18 @@ const (
 	wafDurationExtTag    = "_dd.appsec.waf.duration_ext"
 	wafTimeoutTag        = "_dd.appsec.waf.timeouts"
 	wafVersionTag        = "_dd.appsec.waf.version"
+	raspRuleEvalTag      = "_dd.appsec.rasp.rule.eval"
 )
 
 // Register the WAF event listener.
-func registerWAF(rules []byte, timeout time.Duration, limiter Limiter, obfCfg *ObfuscatorConfig) (unreg dyngo.UnregisterFunc, err error) {
+func registerWAF(rules *rulesManager, timeout time.Duration, limiter Limiter, obfCfg *ObfuscatorConfig, apiSec APISecConfig) (unreg dyngo.UnregisterFunc, err error) {
 	// Check the WAF is healthy
 	if err := waf.Health(); err != nil {
 		return nil, err
 	}
 
 	// Instantiate the WAF
-	waf, err := waf.NewHandle(rules, obfCfg.KeyRegex, obfCfg.ValueRegex)
+	waf, err := rules.newHandle(obfCfg)
 	if err != nil {
 		return nil, err
 	}
@@ -73,7 +74,7 @@ func registerWAF(rules []byte, timeout time.Duration, limiter Limiter, obfCfg *O
 	var unregisterHTTP, unregisterGRPC dyngo.UnregisterFunc
 	if len(httpAddresses) > 0 {
 		log.Debug("appsec: registering http waf listening to addresses %v", httpAddresses)
-		unregisterHTTP = dyngo.Register(newHTTPWAFEventListener(waf, httpAddresses, timeout, limiter))
+		unregisterHTTP = dyngo.Register(newHTTPWAFEventListener(waf, httpAddresses, timeout, limiter, apiSec))
 	}
 	if len(grpcAddresses) > 0 {
 		log.Debug("appsec: registering grpc waf listening to addresses %v", grpcAddresses)
@@ -93,57 +94,159 @@ func registerWAF(rules []byte, timeout time.Duration, limiter Limiter, obfCfg *O
 }
 
 // newWAFEventListener returns the WAF event listener to register in order to enable it.
-func newHTTPWAFEventListener(handle *waf.Handle, addresses []string, timeout time.Duration, limiter Limiter) dyngo.EventListener {
+func newHTTPWAFEventListener(handle *waf.Handle, addresses []string, timeout time.Duration, limiter Limiter, apiSec APISecConfig) dyngo.EventListener {
 	var monitorRulesOnce sync.Once // per instantiation
 
 	return httpsec.OnHandlerOperationStart(func(op *httpsec.Operation, args httpsec.HandlerOperationArgs) {
-		var body interface{}
-
-		op.On(httpsec.OnSDKBodyOperationStart(func(op *httpsec.SDKBodyOperation, args httpsec.SDKBodyOperationArgs) {
-			body = args.Body
-		}))
+		wafCtx := waf.NewContext(handle)
+		if wafCtx == nil {
+			// The WAF event listener got concurrently released
+			return
+		}
 
-		// At the moment, AppSec doesn't block the requests, and so we can use the fact we are in monitoring-only mode
-		// to call the WAF only once at the end of the handler operation.
-		op.On(httpsec.OnHandlerOperationFinish(func(op *httpsec.Operation, res httpsec.HandlerOperationRes) {
-			wafCtx := waf.NewContext(handle)
-			if wafCtx == nil {
-				// The WAF event listener got concurrently released
+		var (
+			events []json.RawMessage
+			mu     sync.Mutex // events mutex
+			stacks stackTraces
+		)
+		// run runs the WAF on the given values, records the security events and blocks the operation when the
+		// WAF returns a block action. The stack trace of the caller is collected when the WAF returns a stack trace
+		// action, which is the call site of the monitored function for the addresses monitored while the request is
+		// being handled, such as the SQL queries.
+		run := func(values map[string]interface{}) {
+			if len(values) == 0 {
 				return
 			}
-			defer wafCtx.Close()
+			matches, actions := runWAF(wafCtx, values, timeout)
+			if len(matches) == 0 {
+				return
+			}
+			log.Debug("appsec: attack detected by the waf")
+			mu.Lock()
+			events = append(events, matches)
+			mu.Unlock()
+			if contains(actions, stackTraceAction) {
+				stacks.collect()
+			}
+			if isBlocking(actions) {
+				log.Debug("appsec: blocking the request")
+				op.Block()
+			}
+		}
 
-			// Run the WAF on the rule addresses available in the request args
-			values := make(map[string]interface{}, len(addresses))
-			for _, addr := range addresses {
-				switch addr {
-				case serverRequestRawURIAddr:
-					values[serverRequestRawURIAddr] = args.RequestURI
-				case serverRequestHeadersNoCookiesAddr:
-					if headers := args.Headers; headers != nil {
-						values[serverRequestHeadersNoCookiesAddr] = headers
-					}
-				case serverRequestCookiesAddr:
-					if cookies := args.Cookies; cookies != nil {
-						values[serverRequestCookiesAddr] = cookies
-					}
-				case serverRequestQueryAddr:
-					if query := args.Query; query != nil {
-						values[serverRequestQueryAddr] = query
-					}
-				case serverRequestPathParams:
-					if pathParams := args.PathParams; pathParams != nil {
-						values[serverRequestPathParams] = pathParams
-					}
-				case serverRequestBody:
-					if body != nil {
-						values[serverRequestBody] = body
-					}
-				case serverResponseStatusAddr:
-					values[serverResponseStatusAddr] = res.Status
+		// Run the WAF on the request addresses as soon as the handler operation starts so that the request can be
+		// blocked before being handled.
+		values := make(map[string]interface{}, len(addresses))
+		for _, addr := range addresses {
+			switch addr {
+			case serverRequestRawURIAddr:
+				values[serverRequestRawURIAddr] = args.RequestURI
+			case serverRequestHeadersNoCookiesAddr:
+				if headers := args.Headers; headers != nil {
+					values[serverRequestHeadersNoCookiesAddr] = headers
+				}
+			case serverRequestCookiesAddr:
+				if cookies := args.Cookies; cookies != nil {
+					values[serverRequestCookiesAddr] = cookies
+				}
+			case serverRequestQueryAddr:
+				if query := args.Query; query != nil {
+					values[serverRequestQueryAddr] = query
+				}
+			case serverRequestPathParams:
+				if pathParams := args.PathParams; pathParams != nil {
+					values[serverRequestPathParams] = pathParams
+				}
+			case httpClientIPAddr:
+				if args.ClientIP != "" {
+					values[httpClientIPAddr] = args.ClientIP
 				}
 			}
-			matches := runWAF(wafCtx, values, timeout)
+		}
+		run(values)
+
+		// Extract the request and response schemas of the sampled requests for API Security
+		sampled := apiSec.sample()
+		if sampled {
+			addRequestSchemaTags(op, args)
+			op.On(httpsec.OnSDKBodyOperationStart(func(_ *httpsec.SDKBodyOperation, args httpsec.SDKBodyOperationArgs) {
+				if args.Body != nil {
+					addSchemaTag(op, schemaReqBodyTag, args.Body)
+				}
+			}))
+		}
+
+		if contains(addresses, serverRequestBody) {
+			// The request body can still block the request when it is monitored before calling the handler, as done
+			// by httpsec.MonitorRequestBody.
+			op.On(httpsec.OnSDKBodyOperationStart(func(_ *httpsec.SDKBodyOperation, args httpsec.SDKBodyOperationArgs) {
+				if args.Body != nil {
+					run(map[string]interface{}{serverRequestBody: args.Body})
+				}
+			}))
+		}
+
+		if contains(addresses, userIDAddr) {
+			op.On(httpsec.OnUserIDOperationStart(func(_ *httpsec.UserIDOperation, args httpsec.UserIDOperationArgs) {
+				if args.UserID != "" {
+					run(map[string]interface{}{userIDAddr: args.UserID})
+				}
+			}))
+		}
+
+		var raspEvals uint32
+		if contains(addresses, serverDBStatementAddr) {
+			// The SQL queries are monitored before being executed so that the SQL injections can be blocked at
+			// their call site (RASP).
+			op.On(httpsec.OnSQLOperationStart(func(_ *httpsec.SQLOperation, args httpsec.SQLOperationArgs) {
+				atomic.AddUint32(&raspEvals, 1)
+				run(map[string]interface{}{
+					serverDBStatementAddr: args.Query,
+					serverDBSystemAddr:    args.DBSystem,
+				})
+			}))
+		}
+		if contains(addresses, serverIONetURLAddr) {
+			// The outgoing HTTP requests are monitored before being sent so that the server-side request forgeries
+			// can be blocked at their call site (RASP).
+			op.On(httpsec.OnRoundTripOperationStart(func(_ *httpsec.RoundTripOperation, args httpsec.RoundTripOperationArgs) {
+				atomic.AddUint32(&raspEvals, 1)
+				run(map[string]interface{}{serverIONetURLAddr: args.URL})
+			}))
+		}
+		if contains(addresses, serverIOFSFileAddr) {
+			// The files opened through the appsec/ossec package are monitored before being opened so that the path
+			// traversals can be blocked at their call site (RASP).
+			op.On(httpsec.OnOpenOperationStart(func(_ *httpsec.OpenOperation, args httpsec.OpenOperationArgs) {
+				atomic.AddUint32(&raspEvals, 1)
+				run(map[string]interface{}{serverIOFSFileAddr: args.Path})
+			}))
+		}
+
+		if contains(addresses, graphqlServerResolverAddr) {
+			// The arguments of the GraphQL resolvers are monitored before the resolvers get called so that the
+			// GraphQL libraries can answer blocked requests with GraphQL errors.
+			op.On(httpsec.OnGraphQLResolverOperationStart(func(_ *httpsec.GraphQLResolverOperation, args httpsec.GraphQLResolverOperationArgs) {
+				run(map[string]interface{}{
+					graphqlServerResolverAddr: map[string]interface{}{args.Resolver: args.Args},
+				})
+			}))
+		}
+
+		op.On(httpsec.OnHandlerOperationFinish(func(op *httpsec.Operation, res httpsec.HandlerOperationRes) {
+			defer wafCtx.Close()
+			if contains(addresses, serverResponseStatusAddr) {
+				run(map[string]interface{}{serverResponseStatusAddr: res.Status})
+			}
+			if n := atomic.LoadUint32(&raspEvals); n > 0 {
+				op.AddTag(raspRuleEvalTag, float64(n))
+			}
+			if tag := stacks.tag(); tag != "" {
+				op.AddTag(stackTag, tag)
+			}
+			if sampled && len(res.Headers) > 0 {
+				addSchemaTag(op, schemaResHeadersTag, res.Headers)
+			}
 
 			// Add WAF metrics.
 			rInfo := handle.RulesetInfo()
@@ -157,12 +260,10 @@ func newHTTPWAFEventListener(handle *waf.Handle, addresses []string, timeout tim
 			})
 
 			// Log the attacks if any
-			if len(matches) == 0 {
-				return
-			}
-			log.Debug("appsec: attack detected by the waf")
-			if limiter.Allow() {
-				op.AddSecurityEvents(matches)
+			mu.Lock()
+			defer mu.Unlock()
+			if len(events) > 0 && limiter.Allow() {
+				op.AddSecurityEvents(events...)
 			}
 		}))
 	})
@@ -217,7 +318,7 @@ func newGRPCWAFEventListener(handle *waf.Handle, _ []string, timeout time.Durati
 			if md := handlerArgs.Metadata; len(md) > 0 {
 				values[grpcServerRequestMetadata] = md
 			}
-			event := runWAF(wafCtx, values, timeout)
+			event, _ := runWAF(wafCtx, values, timeout)
 
 			// WAF run durations are WAF context bound. As of now we need to keep track of those externally since
 			// we use a new WAF context for each callback. When we are able to re-use the same WAF context across
@@ -255,17 +356,34 @@ func newGRPCWAFEventListener(handle *waf.Handle, _ []string, timeout time.Durati
 	})
 }
 
-func runWAF(wafCtx *waf.Context, values map[string]interface{}, timeout time.Duration) []byte {
-	matches, _, err := wafCtx.Run(values, timeout)
+func runWAF(wafCtx *waf.Context, values map[string]interface{}, timeout time.Duration) (matches []byte, actions []string) {
+	matches, actions, err := wafCtx.Run(values, timeout)
 	if err != nil {
 		if err == waf.ErrTimeout {
 			log.Debug("appsec: waf timeout value of %s reached", timeout)
 		} else {
 			log.Error("appsec: unexpected waf error: %v", err)
-			return nil
+			return nil, nil
+		}
+	}
+	return matches, actions
+}
+
+// blockAction is the WAF action of the security rules whose matches must block the request.
+const blockAction = "block"
+
+// isBlocking returns true when the given WAF actions include blockAction.
+func isBlocking(actions []string) bool {
+	return contains(actions, blockAction)
+}
+
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
 		}
 	}
-	return matches
+	return false
 }
 
 // HTTP rule addresses currently supported by the WAF
@@ -277,6 +395,13 @@ const (
 	serverRequestPathParams           = "server.request.path_params"
 	serverRequestBody                 = "server.request.body"
 	serverResponseStatusAddr          = "server.response.status"
+	userIDAddr                        = "usr.id"
+	serverDBStatementAddr             = "server.db.statement"
+	serverDBSystemAddr                = "server.db.system"
+	serverIONetURLAddr                = "server.io.net.url"
+	serverIOFSFileAddr                = "server.io.fs.file"
+	graphqlServerResolverAddr         = "graphql.server.resolver"
+	httpClientIPAddr                  = "http.client_ip"
 )
 
 // List of HTTP rule addresses currently supported by the WAF
@@ -288,6 +413,13 @@ var httpAddresses = []string{
 	serverRequestPathParams,
 	serverRequestBody,
 	serverResponseStatusAddr,
+	userIDAddr,
+	serverDBStatementAddr,
+	serverDBSystemAddr,
+	serverIONetURLAddr,
+	serverIOFSFileAddr,
+	graphqlServerResolverAddr,
+	httpClientIPAddr,
 }
 
 // gRPC rule addresses currently supported by the WAF
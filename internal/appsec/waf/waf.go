@@ -261,6 +261,37 @@ func (h *Handle) updateRuleData(data *wafObject) error {
 	return nil
 }
 
+// ToggleRules enables or disables the rules of the given IDs, according to the
+// given booleans. The rules left out keep their current state.
+func (h *Handle) ToggleRules(toggles map[string]bool) error {
+	wo := &wafObject{}
+	if err := wo.setMapContainer(C.size_t(len(toggles))); err != nil {
+		return err
+	}
+	defer freeWO(wo)
+	// The booleans are encoded as WAF booleans here, whereas the encoder encodes
+	// them as strings for the values passed to the rules.
+	var i C.uint64_t
+	for id, enabled := range toggles {
+		ckey, length, err := cstring(id, len(id))
+		if err != nil {
+			return err
+		}
+		entry := wo.index(i)
+		entry.setMapKey(ckey, C.uint64_t(length))
+		entry.setBool(enabled)
+		i++
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	rc := C.ddwaf_toggle_rules(h.handle, wo.ctype())
+	if rc != C.DDWAF_OK {
+		return fmt.Errorf("unexpected error number `%d` while toggling the WAF rules", rc)
+	}
+	return nil
+}
+
 // Close the WAF handle. Note that this call doesn't block until the handle gets
 // released but instead let WAF contexts still use it until there's no more (eg.
 // when swapping the WAF handle with a new one).
@@ -762,6 +793,7 @@ const (
 	wafStringType  = C.DDWAF_OBJ_STRING
 	wafArrayType   = C.DDWAF_OBJ_ARRAY
 	wafMapType     = C.DDWAF_OBJ_MAP
+	wafBoolType    = C.DDWAF_OBJ_BOOL
 	wafInvalidType = C.DDWAF_OBJ_INVALID
 )
 
@@ -788,6 +820,11 @@ func (v *wafObject) setInt64(n C.int64_t) {
 	*v.int64ValuePtr() = n
 }
 
+func (v *wafObject) setBool(b bool) {
+	v._type = wafBoolType
+	*(*C.bool)(v.valuePtr()) = C.bool(b)
+}
+
 func (v *wafObject) setString(str *C.char, length C.uint64_t) {
 	v._type = wafStringType
 	v.nbEntries = C.uint64_t(length)
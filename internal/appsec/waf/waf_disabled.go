@@ -44,6 +44,10 @@ func (*Handle) RulesetInfo() RulesetInfo { return RulesetInfo{} }
 // [ {rule data #1}, ... {rule data #2} ]
 func (*Handle) UpdateRuleData([]byte) error { return errDisabledReason }
 
+// ToggleRules enables or disables the rules of the given IDs, according to the
+// given booleans. The rules left out keep their current state.
+func (*Handle) ToggleRules(map[string]bool) error { return errDisabledReason }
+
 // Close the WAF and release the underlying C memory as soon as there are
 // no more WAF contexts using the rule.
 func (*Handle) Close() {}
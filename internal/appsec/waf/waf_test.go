@@ -448,6 +448,37 @@ func TestUpdateRuleData(t *testing.T) {
 	}, []string{"block_user", "block_ip"})
 }
 
+func TestToggleRules(t *testing.T) {
+	defer requireZeroNBLiveCObjects(t)
+
+	waf, err := newDefaultHandle(newArachniTestRule([]ruleInput{{Address: "my.input"}}, []string{"block"}))
+	require.NoError(t, err)
+	defer waf.Close()
+
+	// Helper function running the WAF in a new context, as a rule can only
+	// match once per context.
+	run := func() []string {
+		wafCtx := NewContext(waf)
+		require.NotNil(t, wafCtx)
+		defer wafCtx.Close()
+		_, actions, err := wafCtx.Run(map[string]interface{}{"my.input": "Arachni"}, time.Second)
+		require.NoError(t, err)
+		return actions
+	}
+
+	require.Equal(t, []string{"block"}, run())
+
+	require.NoError(t, waf.ToggleRules(map[string]bool{"ua0-600-12x": false}))
+	require.Empty(t, run())
+
+	// The unknown rules are ignored
+	require.NoError(t, waf.ToggleRules(map[string]bool{"ua0-600-12x": true, "unknown": false}))
+	require.Equal(t, []string{"block"}, run())
+
+	require.NoError(t, waf.ToggleRules(nil))
+	require.Equal(t, []string{"block"}, run())
+}
+
 func TestAddresses(t *testing.T) {
 	defer requireZeroNBLiveCObjects(t)
 	expectedAddresses := []string{"my.first.input", "my.second.input", "my.third.input", "my.indexed.input"}
@@ -9,6 +9,9 @@
 package appsec_test
 
 import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -172,3 +175,149 @@ func TestWAF(t *testing.T) {
 		require.NotContains(t, event, sensitivePayloadValue)
 	})
 }
+
+// TestBlocking validates that requests matching security rules whose action is
+// to block are answered with a blocking response instead of being handled.
+func TestBlocking(t *testing.T) {
+	t.Setenv("DD_APPSEC_RULES", "testdata/blocking.json")
+	appsec.Start()
+	defer appsec.Stop()
+
+	if !appsec.Enabled() {
+		t.Skip("appsec disabled")
+	}
+
+	// Start and trace an HTTP server
+	mux := httptrace.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if user := r.URL.Query().Get("user"); user != "" {
+			if err := pAppsec.SetUser(r.Context(), user); err != nil {
+				return
+			}
+		}
+		w.Write([]byte("Hello World!\n"))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	for _, tc := range []struct {
+		name    string
+		query   string
+		headers map[string]string
+		body    string
+		status  int
+		blocked bool
+	}{
+		{
+			name:   "no-block",
+			status: http.StatusOK,
+		},
+		{
+			name:    "user-agent",
+			headers: map[string]string{"User-Agent": "dd-test-scanner-log-block"},
+			status:  http.StatusForbidden,
+			blocked: true,
+		},
+		{
+			name:    "body",
+			headers: map[string]string{"Content-Type": "application/json"},
+			body:    `"dd-test-body-block"`,
+			status:  http.StatusForbidden,
+			blocked: true,
+		},
+		{
+			name:   "user",
+			query:  "?user=allowed-user",
+			status: http.StatusOK,
+		},
+		{
+			name:    "blocked-user",
+			query:   "?user=blocked-user",
+			status:  http.StatusForbidden,
+			blocked: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mt := mocktracer.Start()
+			defer mt.Stop()
+
+			req, err := http.NewRequest("POST", srv.URL+tc.query, strings.NewReader(tc.body))
+			require.NoError(t, err)
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+			res, err := srv.Client().Do(req)
+			require.NoError(t, err)
+			defer res.Body.Close()
+			require.Equal(t, tc.status, res.StatusCode)
+			b, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+
+			finished := mt.FinishedSpans()
+			require.Len(t, finished, 1)
+			if !tc.blocked {
+				require.Equal(t, "Hello World!\n", string(b))
+				require.Nil(t, finished[0].Tag("appsec.blocked"))
+				return
+			}
+			require.Contains(t, string(b), "You've been blocked")
+			require.Equal(t, "application/json", res.Header.Get("Content-Type"))
+			require.Equal(t, true, finished[0].Tag("appsec.blocked"))
+			require.Equal(t, "403", finished[0].Tag("http.status_code"))
+			require.NotNil(t, finished[0].Tag("_dd.appsec.json"))
+		})
+	}
+}
+
+// TestAPISecurity validates that the request and response schemas of the sampled requests are added to their span.
+func TestAPISecurity(t *testing.T) {
+	t.Setenv("DD_API_SECURITY_ENABLED", "true")
+	t.Setenv("DD_API_SECURITY_REQUEST_SAMPLE_RATE", "1")
+	appsec.Start()
+	defer appsec.Stop()
+
+	if !appsec.Enabled() {
+		t.Skip("appsec disabled")
+	}
+
+	// Start and trace an HTTP server
+	mux := httptrace.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("Hello World!\n"))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	req, err := http.NewRequest("POST", srv.URL+"/?id=1", strings.NewReader(`{"name":"dd","age":13}`))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	res, err := srv.Client().Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	finished := mt.FinishedSpans()
+	require.Len(t, finished, 1)
+	span := finished[0]
+	require.JSONEq(t, `[{"age":[16],"name":[8]}]`, decodeSchema(t, span.Tag("_dd.appsec.s.req.body")))
+	require.JSONEq(t, `[{"id":[[[8]],{"len":1}]}]`, decodeSchema(t, span.Tag("_dd.appsec.s.req.query")))
+	require.Contains(t, decodeSchema(t, span.Tag("_dd.appsec.s.req.headers")), `"content-type":[[[8]],{"len":1}]`)
+	require.Contains(t, decodeSchema(t, span.Tag("_dd.appsec.s.res.headers")), `"content-type":[[[8]],{"len":1}]`)
+}
+
+// decodeSchema decodes the given schema span tag value into its JSON representation.
+func decodeSchema(t *testing.T, tag interface{}) string {
+	value, ok := tag.(string)
+	require.True(t, ok)
+	compressed, err := base64.StdEncoding.DecodeString(value)
+	require.NoError(t, err)
+	r, err := gzip.NewReader(bytes.NewReader(compressed))
+	require.NoError(t, err)
+	schema, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return string(schema)
+}
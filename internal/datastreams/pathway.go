@@ -0,0 +1,87 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022 Datadog, Inc.
+
+package datastreams
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+)
+
+// hashableEdgeTags are the edge tag keys which take part in the computation of
+// the pathway hash. Other edge tags are ignored.
+var hashableEdgeTags = map[string]struct{}{"event_type": {}, "exchange": {}, "group": {}, "topic": {}, "type": {}, "direction": {}}
+
+// Pathway is used to monitor how payloads are sent across different services.
+// An example Pathway would be:
+// service A -- produce to topic A --> kafka -- consume from topic A --> service B
+// Each edge is represented by a set of edge tags (e.g. "type:kafka", "topic:A")
+// and each checkpoint taken along the way updates the hash of the pathway.
+type Pathway struct {
+	// hash identifies the pathway, from its origin up to the last checkpoint.
+	hash uint64
+	// pathwayStart is the start of the pathway, i.e. the time of its first checkpoint.
+	pathwayStart time.Time
+	// edgeStart is the start of the current edge, i.e. the time of the last checkpoint.
+	edgeStart time.Time
+}
+
+// GetHash returns the hash identifying the pathway.
+func (p Pathway) GetHash() uint64 {
+	return p.hash
+}
+
+// PathwayStart returns the time of the first checkpoint of the pathway.
+func (p Pathway) PathwayStart() time.Time {
+	return p.pathwayStart
+}
+
+// EdgeStart returns the time of the last checkpoint of the pathway.
+func (p Pathway) EdgeStart() time.Time {
+	return p.edgeStart
+}
+
+// isWellFormedEdgeTag reports whether t is a key:value edge tag whose key is
+// one of hashableEdgeTags.
+func isWellFormedEdgeTag(t string) bool {
+	if i := strings.IndexByte(t, ':'); i != -1 && i != len(t)-1 {
+		_, ok := hashableEdgeTags[t[:i]]
+		return ok
+	}
+	return false
+}
+
+// nodeHash returns the hash of a node of a pathway, identified by the service
+// and environment it runs in, along with the edge tags leading to it.
+func nodeHash(service, env string, edgeTags []string) uint64 {
+	h := fnv.New64()
+	sort.Strings(edgeTags)
+	h.Write([]byte(service))
+	h.Write([]byte(env))
+	for _, t := range edgeTags {
+		if isWellFormedEdgeTag(t) {
+			h.Write([]byte(t))
+		} else {
+			log.Debug("Data Streams Monitoring: ignoring malformed edge tag %q", t)
+		}
+	}
+	return h.Sum64()
+}
+
+// pathwayHash returns the hash of a pathway made of the node identified by
+// nodeHash and the pathway leading to it, identified by parentHash.
+func pathwayHash(nodeHash, parentHash uint64) uint64 {
+	b := make([]byte, 16)
+	binary.LittleEndian.PutUint64(b, nodeHash)
+	binary.LittleEndian.PutUint64(b[8:], parentHash)
+	h := fnv.New64()
+	h.Write(b)
+	return h.Sum64()
+}
@@ -0,0 +1,44 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022 Datadog, Inc.
+
+//go:generate msgp -unexported -marshal=false -o=payload_msgp.go -tests=false
+
+package datastreams
+
+// StatsPayload stores client computed stats.
+type StatsPayload struct {
+	// Env specifies the env. of the application, as defined by the user.
+	Env string
+	// Service is the service of the application
+	Service string
+	// Stats holds all stats buckets computed within this payload.
+	Stats []StatsBucket
+	// TracerVersion is the version of the tracer
+	TracerVersion string
+	// Lang is the language of the tracer
+	Lang string
+}
+
+// StatsBucket specifies a set of stats computed over a duration.
+type StatsBucket struct {
+	// Start specifies the beginning of this bucket in unix nanoseconds.
+	Start uint64
+	// Duration specifies the duration of this bucket in nanoseconds.
+	Duration uint64
+	// Stats contains a set of statistics computed for the duration of this bucket.
+	Stats []StatsPoint
+}
+
+// StatsPoint contains a set of statistics grouped under various aggregation keys.
+type StatsPoint struct {
+	// These fields indicate the properties under which the stats were aggregated.
+	EdgeTags   []string
+	Hash       uint64
+	ParentHash uint64
+	// These fields specify the stats for the above aggregation.
+	// those are distributions of latency in seconds.
+	PathwayLatency []byte
+	EdgeLatency    []byte
+}
@@ -0,0 +1,327 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022 Datadog, Inc.
+
+// Package datastreams implements Data Streams Monitoring: it tracks the pathways
+// followed by payloads across services, such as messages produced to and consumed
+// from queues, and reports the latency of each of their edges to the agent.
+package datastreams
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/version"
+
+	"github.com/DataDog/sketches-go/ddsketch"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// bucketDuration is the duration covered by one stats bucket.
+	bucketDuration = 10 * time.Second
+	// defaultServiceName is the service name used when none is configured.
+	defaultServiceName = "unnamed-go-service"
+)
+
+// StatsdClient is the statsd client used by the processor to report its health metrics.
+type StatsdClient interface {
+	Incr(name string, tags []string, rate float64) error
+	Count(name string, value int64, tags []string, rate float64) error
+}
+
+// statsPoint holds the latencies measured by a single checkpoint.
+type statsPoint struct {
+	edgeTags       []string
+	hash           uint64
+	parentHash     uint64
+	timestamp      int64
+	pathwayLatency int64
+	edgeLatency    int64
+}
+
+// statsGroup aggregates the latencies of the checkpoints of a pathway within a bucket.
+type statsGroup struct {
+	edgeTags       []string
+	parentHash     uint64
+	pathwayLatency *ddsketch.DDSketch
+	edgeLatency    *ddsketch.DDSketch
+}
+
+// bucket holds the stats groups of all the pathways seen within a time window.
+type bucket struct {
+	points   map[uint64]statsGroup
+	start    uint64
+	duration uint64
+}
+
+func newBucket(start, duration uint64) bucket {
+	return bucket{
+		points:   make(map[uint64]statsGroup),
+		start:    start,
+		duration: duration,
+	}
+}
+
+// export transforms the bucket into its payload representation.
+func (b bucket) export() StatsBucket {
+	stats := make([]StatsPoint, 0, len(b.points))
+	for hash, s := range b.points {
+		pathwayLatency, err := proto.Marshal(s.pathwayLatency.ToProto())
+		if err != nil {
+			log.Error("Data Streams Monitoring: failed to serialize pathway latency: %v", err)
+			continue
+		}
+		edgeLatency, err := proto.Marshal(s.edgeLatency.ToProto())
+		if err != nil {
+			log.Error("Data Streams Monitoring: failed to serialize edge latency: %v", err)
+			continue
+		}
+		stats = append(stats, StatsPoint{
+			EdgeTags:       s.edgeTags,
+			Hash:           hash,
+			ParentHash:     s.parentHash,
+			PathwayLatency: pathwayLatency,
+			EdgeLatency:    edgeLatency,
+		})
+	}
+	return StatsBucket{
+		Start:    b.start,
+		Duration: b.duration,
+		Stats:    stats,
+	}
+}
+
+// newSketch returns a sketch suitable to aggregate latencies in seconds.
+func newSketch() *ddsketch.DDSketch {
+	const (
+		// relativeAccuracy is the value accuracy we have on the percentiles.
+		relativeAccuracy = 0.01
+		// maxNumBins is the maximum number of bins of the sketch.
+		maxNumBins = 2048
+	)
+	sketch, err := ddsketch.LogCollapsingLowestDenseDDSketch(relativeAccuracy, maxNumBins)
+	if err != nil {
+		log.Error("Data Streams Monitoring: error when creating ddsketch: %v", err)
+	}
+	return sketch
+}
+
+// Processor aggregates the checkpoints of Data Streams pathways into stats
+// buckets and periodically flushes them to the agent.
+type Processor struct {
+	in      chan statsPoint
+	buckets map[int64]bucket
+
+	// stopped reports whether the processor is stopped (when non-zero)
+	stopped uint32
+
+	wg           sync.WaitGroup       // waits for the processing goroutine
+	stop         chan struct{}        // closing this channel triggers shutdown
+	flushRequest chan chan<- struct{} // receives synchronous flush requests
+	service      string               // service of the checkpoints
+	env          string               // environment of the checkpoints
+	transport    *httpTransport       // transport used to send stats to the agent
+	statsd       StatsdClient         // statsd client reporting health metrics
+	timeSource   func() time.Time     // replaced in tests
+	send         func(p StatsPayload) error
+}
+
+// NewProcessor returns a processor reporting the checkpoints of the given
+// service and environment to the agent at agentAddr, using httpClient.
+func NewProcessor(statsd StatsdClient, env, service, agentAddr string, httpClient *http.Client) *Processor {
+	if service == "" {
+		service = defaultServiceName
+	}
+	p := &Processor{
+		in:           make(chan statsPoint, 10000),
+		buckets:      make(map[int64]bucket),
+		stopped:      1,
+		flushRequest: make(chan chan<- struct{}),
+		service:      service,
+		env:          env,
+		transport:    newHTTPTransport(agentAddr, httpClient),
+		statsd:       statsd,
+		timeSource:   time.Now,
+	}
+	p.send = p.transport.sendPipelineStats
+	return p
+}
+
+// alignTs returns the provided timestamp truncated to the bucket size.
+func alignTs(ts, bucketSize int64) int64 { return ts - ts%bucketSize }
+
+// add aggregates the given point into its bucket.
+func (p *Processor) add(point statsPoint) {
+	btime := alignTs(point.timestamp, bucketDuration.Nanoseconds())
+	b, ok := p.buckets[btime]
+	if !ok {
+		b = newBucket(uint64(btime), uint64(bucketDuration.Nanoseconds()))
+		p.buckets[btime] = b
+	}
+	group, ok := b.points[point.hash]
+	if !ok {
+		group = statsGroup{
+			edgeTags:       point.edgeTags,
+			parentHash:     point.parentHash,
+			pathwayLatency: newSketch(),
+			edgeLatency:    newSketch(),
+		}
+		b.points[point.hash] = group
+	}
+	if err := group.pathwayLatency.Add(float64(point.pathwayLatency) / float64(time.Second)); err != nil {
+		log.Error("Data Streams Monitoring: failed to add pathway latency: %v", err)
+	}
+	if err := group.edgeLatency.Add(float64(point.edgeLatency) / float64(time.Second)); err != nil {
+		log.Error("Data Streams Monitoring: failed to add edge latency: %v", err)
+	}
+}
+
+// run aggregates the incoming points and flushes the stats buckets every time
+// tick fires, until the processor is stopped.
+func (p *Processor) run(tick <-chan time.Time) {
+	for {
+		select {
+		case s := <-p.in:
+			p.add(s)
+		case now := <-tick:
+			p.sendToAgent(p.flush(now, false))
+		case done := <-p.flushRequest:
+			p.drain()
+			p.sendToAgent(p.flush(p.timeSource(), true))
+			close(done)
+		case <-p.stop:
+			p.drain()
+			p.sendToAgent(p.flush(p.timeSource(), true))
+			return
+		}
+	}
+}
+
+// drain aggregates all the points waiting to be processed.
+func (p *Processor) drain() {
+	for {
+		select {
+		case s := <-p.in:
+			p.add(s)
+		default:
+			return
+		}
+	}
+}
+
+// Start starts the processor. A started processor needs to be stopped in order
+// to gracefully shut down, using Stop.
+func (p *Processor) Start() {
+	if atomic.SwapUint32(&p.stopped, 0) == 0 {
+		log.Warn("(*Processor).Start called more than once. This is likely a programming error.")
+		return
+	}
+	p.stop = make(chan struct{})
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		tick := time.NewTicker(bucketDuration)
+		defer tick.Stop()
+		p.run(tick.C)
+	}()
+}
+
+// Stop stops the processor, flushing all the remaining stats, and blocks until
+// the operation completes.
+func (p *Processor) Stop() {
+	if atomic.SwapUint32(&p.stopped, 1) > 0 {
+		return
+	}
+	close(p.stop)
+	p.wg.Wait()
+}
+
+// Flush flushes all the stats buckets, including the current one, and blocks
+// until they were sent. It is a no-op if the processor is not started.
+func (p *Processor) Flush() {
+	if atomic.LoadUint32(&p.stopped) > 0 {
+		return
+	}
+	done := make(chan struct{})
+	select {
+	case p.flushRequest <- done:
+		<-done
+	case <-p.stop:
+	}
+}
+
+// flush returns the payload holding the buckets which ended before now. The
+// current bucket is only included if includeCurrent is true.
+func (p *Processor) flush(now time.Time, includeCurrent bool) StatsPayload {
+	nowNano := now.UnixNano()
+	sp := StatsPayload{
+		Service:       p.service,
+		Env:           p.env,
+		Lang:          "go",
+		TracerVersion: version.Tag,
+		Stats:         make([]StatsBucket, 0, len(p.buckets)),
+	}
+	for ts, b := range p.buckets {
+		if !includeCurrent && ts > nowNano-bucketDuration.Nanoseconds() {
+			// do not flush the current bucket
+			continue
+		}
+		sp.Stats = append(sp.Stats, b.export())
+		delete(p.buckets, ts)
+	}
+	return sp
+}
+
+// sendToAgent sends the given payload to the agent, unless it is empty.
+func (p *Processor) sendToAgent(payload StatsPayload) {
+	if len(payload.Stats) == 0 {
+		return
+	}
+	p.statsd.Incr("datadog.datastreams.processor.flush", nil, 1)
+	if err := p.send(payload); err != nil {
+		p.statsd.Incr("datadog.datastreams.processor.flush_errors", nil, 1)
+		log.Error("Data Streams Monitoring: error sending stats payload: %v", err)
+	}
+}
+
+// SetCheckpoint sets a checkpoint identified by the given edge tags on the
+// pathway held by ctx, or on a new pathway if ctx holds none. It returns the
+// updated pathway, along with a copy of ctx holding it.
+func (p *Processor) SetCheckpoint(ctx context.Context, edgeTags ...string) (Pathway, context.Context) {
+	now := p.timeSource()
+	parent, hasParent := PathwayFromContext(ctx)
+	pathwayStart, edgeStart := now, now
+	var parentHash uint64
+	if hasParent {
+		pathwayStart = parent.pathwayStart
+		edgeStart = parent.edgeStart
+		parentHash = parent.hash
+	}
+	// nodeHash sorts the edge tags, so a copy is hashed in order to keep the
+	// caller's slice untouched.
+	tags := append([]string(nil), edgeTags...)
+	child := Pathway{
+		hash:         pathwayHash(nodeHash(p.service, p.env, tags), parentHash),
+		pathwayStart: pathwayStart,
+		edgeStart:    now,
+	}
+	select {
+	case p.in <- statsPoint{
+		edgeTags:       tags,
+		hash:           child.hash,
+		parentHash:     parentHash,
+		timestamp:      now.UnixNano(),
+		pathwayLatency: now.Sub(pathwayStart).Nanoseconds(),
+		edgeLatency:    now.Sub(edgeStart).Nanoseconds(),
+	}:
+	default:
+		p.statsd.Incr("datadog.datastreams.processor.points_dropped", nil, 1)
+	}
+	return child, ContextWithPathway(ctx, child)
+}
@@ -0,0 +1,146 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022 Datadog, Inc.
+
+package datastreams
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testStatsdClient struct {
+	mu     sync.Mutex
+	counts map[string]int64
+}
+
+func (c *testStatsdClient) Incr(name string, tags []string, rate float64) error {
+	return c.Count(name, 1, tags, rate)
+}
+
+func (c *testStatsdClient) Count(name string, value int64, _ []string, _ float64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.counts == nil {
+		c.counts = make(map[string]int64)
+	}
+	c.counts[name] += value
+	return nil
+}
+
+func TestPathwayHash(t *testing.T) {
+	tags := []string{"type:kafka", "topic:topic1", "direction:in", "malformed", "unknown:tag"}
+	n1 := nodeHash("service-1", "env", tags)
+	assert.Equal(t, n1, nodeHash("service-1", "env", []string{"direction:in", "topic:topic1", "type:kafka"}))
+	assert.NotEqual(t, n1, nodeHash("service-2", "env", tags))
+	assert.NotEqual(t, n1, nodeHash("service-1", "env-2", tags))
+	assert.NotEqual(t, pathwayHash(n1, 0), pathwayHash(n1, 1))
+}
+
+func TestSetCheckpoint(t *testing.T) {
+	now := time.Now()
+	p := NewProcessor(&testStatsdClient{}, "env", "service", "localhost:8126", nil)
+	p.timeSource = func() time.Time { return now }
+
+	tags := []string{"type:kafka", "direction:out", "topic:topic1"}
+	parent, ctx := p.SetCheckpoint(context.Background(), tags...)
+	assert.Equal(t, []string{"type:kafka", "direction:out", "topic:topic1"}, tags)
+	assert.Equal(t, pathwayHash(nodeHash("service", "env", []string{"direction:out", "topic:topic1", "type:kafka"}), 0), parent.GetHash())
+	assert.Equal(t, now, parent.PathwayStart())
+	assert.Equal(t, now, parent.EdgeStart())
+
+	p.timeSource = func() time.Time { return now.Add(time.Second) }
+	child, ctx := p.SetCheckpoint(ctx, "type:kafka", "direction:in", "topic:topic1")
+	assert.Equal(t, pathwayHash(nodeHash("service", "env", []string{"direction:in", "topic:topic1", "type:kafka"}), parent.GetHash()), child.GetHash())
+	assert.Equal(t, now, child.PathwayStart())
+	assert.Equal(t, now.Add(time.Second), child.EdgeStart())
+	fromCtx, ok := PathwayFromContext(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, child, fromCtx)
+
+	first, second := <-p.in, <-p.in
+	assert.Equal(t, parent.GetHash(), first.hash)
+	assert.Equal(t, uint64(0), first.parentHash)
+	assert.Equal(t, int64(0), first.edgeLatency)
+	assert.Equal(t, child.GetHash(), second.hash)
+	assert.Equal(t, parent.GetHash(), second.parentHash)
+	assert.Equal(t, time.Second.Nanoseconds(), second.edgeLatency)
+	assert.Equal(t, time.Second.Nanoseconds(), second.pathwayLatency)
+}
+
+func TestProcessor(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		payloads []StatsPayload
+	)
+	statsd := &testStatsdClient{}
+	p := NewProcessor(statsd, "env", "service", "localhost:8126", nil)
+	p.send = func(sp StatsPayload) error {
+		mu.Lock()
+		defer mu.Unlock()
+		payloads = append(payloads, sp)
+		return nil
+	}
+	now := time.Unix(0, alignTs(time.Now().UnixNano(), bucketDuration.Nanoseconds()))
+	p.timeSource = func() time.Time { return now }
+
+	t.Run("flush", func(t *testing.T) {
+		p.Start()
+		defer p.Stop()
+		_, ctx := p.SetCheckpoint(context.Background(), "type:kafka", "direction:out", "topic:topic1")
+		p.SetCheckpoint(ctx, "type:kafka", "direction:in", "topic:topic1")
+		p.SetCheckpoint(context.Background(), "type:kafka", "direction:out", "topic:topic1")
+		p.Flush()
+
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Len(t, payloads, 1)
+		sp := payloads[0]
+		assert.Equal(t, "env", sp.Env)
+		assert.Equal(t, "service", sp.Service)
+		assert.Equal(t, "go", sp.Lang)
+		assert.Len(t, sp.Stats, 1)
+		b := sp.Stats[0]
+		assert.Equal(t, uint64(now.UnixNano()), b.Start)
+		assert.Equal(t, uint64(bucketDuration.Nanoseconds()), b.Duration)
+		// both checkpoints without parent share the same pathway
+		assert.Len(t, b.Stats, 2)
+		for _, s := range b.Stats {
+			assert.NotEmpty(t, s.PathwayLatency)
+			assert.NotEmpty(t, s.EdgeLatency)
+		}
+	})
+
+	t.Run("stopped", func(t *testing.T) {
+		p.Flush() // no-op on a stopped processor
+		p.SetCheckpoint(context.Background(), "type:kafka", "direction:out", "topic:topic2")
+		p.Start()
+		p.Stop()
+
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Len(t, payloads, 2)
+		assert.Equal(t, int64(2), statsd.counts["datadog.datastreams.processor.flush"])
+	})
+}
+
+func TestProcessorFlushCurrentBucket(t *testing.T) {
+	p := NewProcessor(&testStatsdClient{}, "env", "service", "localhost:8126", nil)
+	now := time.Unix(0, alignTs(time.Now().UnixNano(), bucketDuration.Nanoseconds()))
+	p.add(statsPoint{hash: 1, timestamp: now.UnixNano()})
+	p.add(statsPoint{hash: 2, timestamp: now.Add(-bucketDuration).UnixNano()})
+
+	sp := p.flush(now.Add(time.Second), false)
+	assert.Len(t, sp.Stats, 1)
+	assert.Equal(t, uint64(now.Add(-bucketDuration).UnixNano()), sp.Stats[0].Start)
+
+	sp = p.flush(now.Add(time.Second), true)
+	assert.Len(t, sp.Stats, 1)
+	assert.Equal(t, uint64(now.UnixNano()), sp.Stats[0].Start)
+	assert.Len(t, p.buckets, 0)
+}
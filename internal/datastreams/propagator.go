@@ -0,0 +1,138 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022 Datadog, Inc.
+
+package datastreams
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+)
+
+type contextKey struct{}
+
+var activePathwayKey = contextKey{}
+
+const (
+	// PropagationKey is the key to use to propagate the pathway between services,
+	// in binary form.
+	PropagationKey = "dd-pathway-ctx"
+	// PropagationKeyBase64 is the key to use to propagate the pathway between
+	// services, when the carrier only supports text values.
+	PropagationKeyBase64 = "dd-pathway-ctx-base64"
+)
+
+// Encode encodes the pathway as its hash followed by the varint encoded start
+// times of the pathway and of its current edge, in milliseconds.
+func (p Pathway) Encode() []byte {
+	data := make([]byte, 8, 8+2*binary.MaxVarintLen64)
+	binary.LittleEndian.PutUint64(data, p.hash)
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutVarint(buf[:], toMillis(p.pathwayStart))
+	data = append(data, buf[:n]...)
+	n = binary.PutVarint(buf[:], toMillis(p.edgeStart))
+	return append(data, buf[:n]...)
+}
+
+// Decode decodes a pathway encoded by Encode, and returns a copy of ctx holding it.
+func Decode(ctx context.Context, data []byte) (p Pathway, outCtx context.Context, err error) {
+	if len(data) < 8 {
+		return p, ctx, errors.New("hash smaller than 8 bytes")
+	}
+	p.hash = binary.LittleEndian.Uint64(data)
+	data = data[8:]
+	pathwayStart, n := binary.Varint(data)
+	if n <= 0 {
+		return p, ctx, errors.New("invalid pathway start")
+	}
+	edgeStart, n := binary.Varint(data[n:])
+	if n <= 0 {
+		return p, ctx, errors.New("invalid edge start")
+	}
+	p.pathwayStart = time.Unix(0, pathwayStart*int64(time.Millisecond))
+	p.edgeStart = time.Unix(0, edgeStart*int64(time.Millisecond))
+	return p, ContextWithPathway(ctx, p), nil
+}
+
+// EncodeBase64 encodes the pathway like Encode, in base64.
+func (p Pathway) EncodeBase64() string {
+	return base64.StdEncoding.EncodeToString(p.Encode())
+}
+
+// DecodeBase64 decodes a pathway encoded by EncodeBase64, and returns a copy
+// of ctx holding it.
+func DecodeBase64(ctx context.Context, str string) (p Pathway, outCtx context.Context, err error) {
+	data, err := base64.StdEncoding.DecodeString(str)
+	if err != nil {
+		return p, ctx, err
+	}
+	return Decode(ctx, data)
+}
+
+// ContextWithPathway returns a copy of the given context which includes the pathway p.
+func ContextWithPathway(ctx context.Context, p Pathway) context.Context {
+	return context.WithValue(ctx, activePathwayKey, p)
+}
+
+// PathwayFromContext returns the pathway contained in a Go context if present.
+func PathwayFromContext(ctx context.Context) (p Pathway, ok bool) {
+	if ctx == nil {
+		return p, false
+	}
+	p, ok = ctx.Value(activePathwayKey).(Pathway)
+	return p, ok
+}
+
+// TextMapWriter is implemented by the carriers the pathway can be injected into,
+// such as the message carriers of messaging integrations.
+type TextMapWriter interface {
+	Set(key, val string)
+}
+
+// TextMapReader is implemented by the carriers the pathway can be extracted from,
+// such as the message carriers of messaging integrations.
+type TextMapReader interface {
+	ForeachKey(handler func(key, val string) error) error
+}
+
+// InjectToCarrier injects the pathway held by ctx, if any, into the carrier,
+// under PropagationKey.
+func InjectToCarrier(ctx context.Context, carrier TextMapWriter) {
+	p, ok := PathwayFromContext(ctx)
+	if !ok {
+		return
+	}
+	carrier.Set(PropagationKey, string(p.Encode()))
+}
+
+// ExtractFromCarrier returns a copy of ctx holding the pathway found in the
+// carrier under PropagationKey. If the carrier holds no valid pathway, ctx is
+// returned unchanged.
+func ExtractFromCarrier(ctx context.Context, carrier TextMapReader) context.Context {
+	var data []byte
+	carrier.ForeachKey(func(key, val string) error {
+		if key == PropagationKey {
+			data = []byte(val)
+		}
+		return nil
+	})
+	if data == nil {
+		return ctx
+	}
+	_, outCtx, err := Decode(ctx, data)
+	if err != nil {
+		log.Debug("Data Streams Monitoring: failed to decode pathway: %v", err)
+		return ctx
+	}
+	return outCtx
+}
+
+func toMillis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
@@ -0,0 +1,87 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022 Datadog, Inc.
+
+package datastreams
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testPathway() Pathway {
+	now := time.Now().Local().Truncate(time.Millisecond)
+	return Pathway{
+		hash:         234,
+		pathwayStart: now.Add(-time.Hour),
+		edgeStart:    now,
+	}
+}
+
+func TestEncode(t *testing.T) {
+	p := testPathway()
+	decoded, ctx, err := Decode(context.Background(), p.Encode())
+	assert.Nil(t, err)
+	assert.Equal(t, p, decoded)
+	fromCtx, ok := PathwayFromContext(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, p, fromCtx)
+}
+
+func TestEncodeBase64(t *testing.T) {
+	p := testPathway()
+	decoded, _, err := DecodeBase64(context.Background(), p.EncodeBase64())
+	assert.Nil(t, err)
+	assert.Equal(t, p, decoded)
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	ctx := context.Background()
+	for name, data := range map[string][]byte{
+		"empty":      nil,
+		"short-hash": {1, 2, 3},
+		"no-start":   {1, 2, 3, 4, 5, 6, 7, 8},
+		"no-edge":    {1, 2, 3, 4, 5, 6, 7, 8, 2},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, outCtx, err := Decode(ctx, data)
+			assert.NotNil(t, err)
+			_, ok := PathwayFromContext(outCtx)
+			assert.False(t, ok)
+		})
+	}
+}
+
+type mapCarrier map[string]string
+
+func (c mapCarrier) Set(key, val string) { c[key] = val }
+
+func (c mapCarrier) ForeachKey(handler func(key, val string) error) error {
+	for k, v := range c {
+		if err := handler(k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestCarrier(t *testing.T) {
+	p := testPathway()
+	carrier := mapCarrier{}
+	InjectToCarrier(context.Background(), carrier)
+	assert.Len(t, carrier, 0)
+
+	InjectToCarrier(ContextWithPathway(context.Background(), p), carrier)
+	assert.Len(t, carrier, 1)
+	extracted, ok := PathwayFromContext(ExtractFromCarrier(context.Background(), carrier))
+	assert.True(t, ok)
+	assert.Equal(t, p, extracted)
+
+	carrier[PropagationKey] = "invalid"
+	_, ok = PathwayFromContext(ExtractFromCarrier(context.Background(), carrier))
+	assert.False(t, ok)
+}
@@ -0,0 +1,51 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022 Datadog, Inc.
+
+package datastreams
+
+import (
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tinylib/msgp/msgp"
+)
+
+func TestHTTPTransport(t *testing.T) {
+	p := StatsPayload{Env: "env", Service: "service", Lang: "go", Stats: []StatsBucket{{Start: 10, Duration: 10}}}
+
+	t.Run("ok", func(t *testing.T) {
+		var got StatsPayload
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/v0.1/pipeline_stats", r.URL.Path)
+			assert.Equal(t, "application/msgpack", r.Header.Get("Content-Type"))
+			assert.Equal(t, "gzip", r.Header.Get("Content-Encoding"))
+			assert.Equal(t, "go", r.Header.Get("Datadog-Meta-Lang"))
+			gzr, err := gzip.NewReader(r.Body)
+			assert.Nil(t, err)
+			assert.Nil(t, msgp.Decode(gzr, &got))
+		}))
+		defer srv.Close()
+
+		transport := newHTTPTransport(strings.TrimPrefix(srv.URL, "http://"), srv.Client())
+		assert.Nil(t, transport.sendPipelineStats(p))
+		assert.Equal(t, p, got)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("bad payload"))
+		}))
+		defer srv.Close()
+
+		transport := newHTTPTransport(strings.TrimPrefix(srv.URL, "http://"), srv.Client())
+		err := transport.sendPipelineStats(p)
+		assert.EqualError(t, err, "bad payload (Status: Bad Request)")
+	})
+}
@@ -0,0 +1,194 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022 Datadog, Inc.
+
+// Package debugger implements Dynamic Instrumentation: the probes received
+// through the LIVE_DEBUGGING remote configuration product are installed on the
+// functions instrumented with the hooks of the public debugger package, which
+// capture snapshots of their calls and send them to the debugger intake.
+package debugger
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/remoteconfig"
+
+	rc "github.com/DataDog/datadog-agent/pkg/remoteconfig/state"
+)
+
+const (
+	// enabledEnvVar is the environment variable enabling Dynamic Instrumentation.
+	enabledEnvVar = "DD_DYNAMIC_INSTRUMENTATION_ENABLED"
+
+	// defaultFlushInterval is the interval between two uploads of the captured
+	// snapshots.
+	defaultFlushInterval = time.Second
+)
+
+// StartOption customizes the configuration of Dynamic Instrumentation.
+type StartOption func(c *config)
+
+type config struct {
+	// agentAddr is the address of the agent the snapshots are sent to.
+	agentAddr string
+	// httpClient is the HTTP client used to send the snapshots.
+	httpClient *http.Client
+	// service, env and version identify the application in the snapshots.
+	service, env, version string
+	// rcClient is the remote configuration client the probes are received from.
+	rcClient *remoteconfig.Client
+	// flushInterval is the interval between two uploads of the snapshots.
+	flushInterval time.Duration
+}
+
+// WithAgentAddr sets the address of the agent the snapshots are sent to.
+func WithAgentAddr(addr string) StartOption {
+	return func(c *config) {
+		c.agentAddr = addr
+	}
+}
+
+// WithHTTPClient sets the HTTP client used to send the snapshots.
+func WithHTTPClient(client *http.Client) StartOption {
+	return func(c *config) {
+		c.httpClient = client
+	}
+}
+
+// WithService sets the service, environment and version of the application,
+// reported along with its snapshots.
+func WithService(service, env, version string) StartOption {
+	return func(c *config) {
+		c.service = service
+		c.env = env
+		c.version = version
+	}
+}
+
+// WithRCClient sets the remote configuration client the probes are received
+// from, which is shared with the caller. The caller remains responsible for
+// starting and stopping it.
+func WithRCClient(client *remoteconfig.Client) StartOption {
+	return func(c *config) {
+		c.rcClient = client
+	}
+}
+
+// Start starts Dynamic Instrumentation when the environment variable
+// DD_DYNAMIC_INSTRUMENTATION_ENABLED is set to true. It requires a remote
+// configuration client to receive the probes.
+func Start(opts ...StartOption) {
+	if !internal.BoolEnv(enabledEnvVar, false) {
+		return
+	}
+	cfg := &config{
+		agentAddr:     "localhost:8126",
+		httpClient:    &http.Client{Timeout: 10 * time.Second},
+		flushInterval: defaultFlushInterval,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if cfg.rcClient == nil {
+		log.Warn("debugger: Dynamic Instrumentation disabled: remote configuration is required to receive the probes")
+		return
+	}
+	d := newDebugger(cfg)
+	setActiveDebugger(d)
+	cfg.rcClient.Subscribe(remoteconfig.ProductLiveDebugging, d.onRemoteConfigUpdate)
+	log.Debug("debugger: Dynamic Instrumentation started")
+}
+
+// Stop stops Dynamic Instrumentation, sending the snapshots which remain to be
+// sent.
+func Stop() {
+	setActiveDebugger(nil)
+}
+
+// Enabled returns true when Dynamic Instrumentation is started.
+func Enabled() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return active != nil
+}
+
+var (
+	// mu guards active.
+	mu     sync.RWMutex
+	active *debugger
+)
+
+func activeDebugger() *debugger {
+	mu.RLock()
+	defer mu.RUnlock()
+	return active
+}
+
+func setActiveDebugger(d *debugger) {
+	mu.Lock()
+	old := active
+	active = d
+	mu.Unlock()
+	// the snapshots of the previous debugger are sent without blocking the
+	// calls of the instrumented functions
+	if old != nil {
+		old.stop()
+	}
+}
+
+type debugger struct {
+	cfg      *config
+	probes   *probeSet
+	uploader *uploader
+}
+
+func newDebugger(cfg *config) *debugger {
+	return &debugger{
+		cfg:      cfg,
+		probes:   newProbeSet(),
+		uploader: newUploader(cfg),
+	}
+}
+
+func (d *debugger) stop() {
+	// the probes can't be updated anymore once the active debugger is replaced,
+	// as the remote configuration callback only applies to the active debugger
+	d.probes.clear()
+	d.uploader.stop()
+}
+
+// onRemoteConfigUpdate installs the probes received through remote
+// configuration, and removes the ones which are not sent anymore. It is used as
+// a callback for the LIVE_DEBUGGING product.
+func (d *debugger) onRemoteConfigUpdate(u remoteconfig.ProductUpdate) map[string]rc.ApplyStatus {
+	statuses := make(map[string]rc.ApplyStatus, len(u))
+	if activeDebugger() != d {
+		return statuses
+	}
+	for path, raw := range u {
+		status := rc.ApplyStatus{State: rc.ApplyStateAcknowledged}
+		if raw == nil {
+			log.Debug("debugger: Remote config: removing the probe of %s", path)
+			d.probes.remove(path)
+			statuses[path] = status
+			continue
+		}
+		log.Debug("debugger: Remote config: processing %s", path)
+		p, err := parseProbe(raw)
+		if err != nil {
+			log.Error("debugger: Remote config: error while processing %s. The probe won't be installed: %v", path, err)
+			status.State = rc.ApplyStateError
+			status.Error = err.Error()
+			d.probes.remove(path)
+		} else {
+			d.probes.add(path, p)
+		}
+		statuses[path] = status
+	}
+	return statuses
+}
@@ -0,0 +1,179 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022 Datadog, Inc.
+
+package debugger
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/remoteconfig"
+
+	rc "github.com/DataDog/datadog-agent/pkg/remoteconfig/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const probePath = "datadog/2/LIVE_DEBUGGING/logProbe_1/config"
+
+// startTestDebugger starts a debugger sending its snapshots to a test server,
+// and returns the function returning the snapshots it received.
+func startTestDebugger(t *testing.T) func() []snapshotEvent {
+	var (
+		mu        sync.Mutex
+		snapshots []snapshotEvent
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, snapshotsPath, r.URL.Path)
+		var batch []snapshotEvent
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&batch))
+		mu.Lock()
+		snapshots = append(snapshots, batch...)
+		mu.Unlock()
+	}))
+	t.Cleanup(srv.Close)
+	d := newDebugger(&config{
+		agentAddr:     strings.TrimPrefix(srv.URL, "http://"),
+		httpClient:    srv.Client(),
+		service:       "test-service",
+		env:           "test-env",
+		flushInterval: time.Hour,
+	})
+	setActiveDebugger(d)
+	t.Cleanup(Stop)
+	return func() []snapshotEvent {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]snapshotEvent(nil), snapshots...)
+	}
+}
+
+func TestStart(t *testing.T) {
+	client, err := remoteconfig.NewClient(remoteconfig.DefaultClientConfig())
+	require.NoError(t, err)
+
+	Start(WithRCClient(client))
+	assert.False(t, Enabled())
+
+	t.Setenv(enabledEnvVar, "true")
+	Start()
+	assert.False(t, Enabled())
+
+	Start(WithRCClient(client))
+	defer Stop()
+	assert.True(t, Enabled())
+	assert.Contains(t, client.Products, remoteconfig.ProductLiveDebugging)
+	Stop()
+	assert.False(t, Enabled())
+}
+
+func TestParseProbe(t *testing.T) {
+	p, err := parseProbe([]byte(`{"id":"1","version":2,"type":"LOG_PROBE","language":"go","where":{"typeName":"main","methodName":"handle"}}`))
+	require.NoError(t, err)
+	assert.Equal(t, "1", p.ID)
+	assert.Equal(t, 2, p.Version)
+	assert.Equal(t, "main.handle", p.location())
+	assert.Equal(t, time.Second, p.sampler.interval)
+
+	p, err = parseProbe([]byte(`{"id":"1","where":{"methodName":"main.handle"},"sampling":{"snapshotsPerSecond":10}}`))
+	require.NoError(t, err)
+	assert.Equal(t, "main.handle", p.location())
+	assert.Equal(t, 100*time.Millisecond, p.sampler.interval)
+
+	for _, raw := range []string{
+		`{`,
+		`{"where":{"methodName":"main.handle"}}`,
+		`{"id":"1"}`,
+		`{"id":"1","type":"METRIC_PROBE","where":{"methodName":"main.handle"}}`,
+		`{"id":"1","language":"java","where":{"methodName":"main.handle"}}`,
+	} {
+		_, err := parseProbe([]byte(raw))
+		assert.Error(t, err, raw)
+	}
+}
+
+func TestOnRemoteConfigUpdate(t *testing.T) {
+	startTestDebugger(t)
+	d := activeDebugger()
+
+	statuses := d.onRemoteConfigUpdate(remoteconfig.ProductUpdate{
+		probePath: []byte(`{"id":"1","where":{"methodName":"main.handle"}}`),
+	})
+	assert.Equal(t, rc.ApplyStateAcknowledged, statuses[probePath].State)
+	assert.Len(t, d.probes.lookup("main.handle"), 1)
+
+	statuses = d.onRemoteConfigUpdate(remoteconfig.ProductUpdate{probePath: []byte(`{"id":"1"}`)})
+	assert.Equal(t, rc.ApplyStateError, statuses[probePath].State)
+	assert.NotEmpty(t, statuses[probePath].Error)
+	assert.True(t, d.probes.empty())
+
+	d.onRemoteConfigUpdate(remoteconfig.ProductUpdate{
+		probePath: []byte(`{"id":"1","where":{"methodName":"main.handle"}}`),
+	})
+	statuses = d.onRemoteConfigUpdate(remoteconfig.ProductUpdate{probePath: nil})
+	assert.Equal(t, rc.ApplyStateAcknowledged, statuses[probePath].State)
+	assert.True(t, d.probes.empty())
+}
+
+func TestSnapshots(t *testing.T) {
+	snapshots := startTestDebugger(t)
+	d := activeDebugger()
+
+	// no probe is installed
+	assert.Nil(t, Enter("main.handle", 0, 0, nil))
+	var c *Call
+	c.Exit(nil)
+
+	d.onRemoteConfigUpdate(remoteconfig.ProductUpdate{
+		probePath: []byte(`{"id":"1","version":3,"where":{"typeName":"main","methodName":"handle"},"template":"handling {id}: {err}","captureSnapshot":true}`),
+	})
+	assert.Nil(t, Enter("main.other", 0, 0, nil))
+	c = Enter("main.handle", 1, 2, []Capture{{Name: "id", Value: 42}})
+	require.NotNil(t, c)
+	c.Exit([]Capture{{Name: "err", Value: nil}})
+	// the snapshots are rate limited
+	assert.Nil(t, Enter("main.handle", 1, 2, nil))
+	Stop()
+
+	got := snapshots()
+	require.Len(t, got, 1)
+	s := got[0]
+	assert.Equal(t, "test-service", s.Service)
+	assert.Equal(t, "test-env", s.Env)
+	assert.Equal(t, "dd_debugger", s.Source)
+	assert.Equal(t, "handling 42: <nil>", s.Message)
+	assert.Equal(t, "1", s.TraceID)
+	assert.Equal(t, "2", s.SpanID)
+	snapshot := s.Debugger.Snapshot
+	assert.NotEmpty(t, snapshot.ID)
+	assert.Equal(t, "go", snapshot.Language)
+	assert.Equal(t, probeInfo{ID: "1", Version: 3, Location: probeLocation{Method: "handle", Type: "main"}}, snapshot.Probe)
+	require.NotEmpty(t, snapshot.Stack)
+	require.NotNil(t, snapshot.Captures)
+	assert.Equal(t, map[string]capturedValue{"id": {Type: "int", Value: "42"}}, snapshot.Captures.Entry.Arguments)
+	assert.Equal(t, map[string]capturedValue{"err": {Type: "nil", IsNull: true}}, snapshot.Captures.Return.Locals)
+}
+
+func TestMessage(t *testing.T) {
+	p := &probe{}
+	args := []Capture{{Name: "id", Value: "a"}, {Name: "n", Value: 1}}
+	assert.Equal(t, "main.handle(id=a, n=1)", message(p, "main.handle", args, nil))
+	p.Template = "{id} {n} {unknown}"
+	assert.Equal(t, "a 1 {unknown}", message(p, "main.handle", args, nil))
+	assert.Equal(t, strings.Repeat("x", maxValueLength)+"...", formatValue(strings.Repeat("x", 1000)))
+}
+
+func TestSampler(t *testing.T) {
+	s := newSampler(2)
+	now := time.Now()
+	assert.True(t, s.allow(now))
+	assert.False(t, s.allow(now.Add(100*time.Millisecond)))
+	assert.True(t, s.allow(now.Add(500*time.Millisecond)))
+}
@@ -0,0 +1,167 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022 Datadog, Inc.
+
+package debugger
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// logProbeType is the type of the probes capturing the snapshots of the
+	// calls of a function, the only type supported.
+	logProbeType = "LOG_PROBE"
+
+	// defaultSnapshotsPerSecond is the default maximum number of snapshots a
+	// probe captures per second.
+	defaultSnapshotsPerSecond = 1.0
+)
+
+// probe is a probe received through remote configuration.
+type probe struct {
+	ID       string `json:"id"`
+	Version  int    `json:"version"`
+	Type     string `json:"type"`
+	Language string `json:"language"`
+	Where    struct {
+		TypeName   string `json:"typeName"`
+		MethodName string `json:"methodName"`
+	} `json:"where"`
+	Template        string `json:"template"`
+	CaptureSnapshot bool   `json:"captureSnapshot"`
+	Sampling        struct {
+		SnapshotsPerSecond float64 `json:"snapshotsPerSecond"`
+	} `json:"sampling"`
+
+	sampler *sampler
+}
+
+// parseProbe parses the probe definition raw.
+func parseProbe(raw []byte) (*probe, error) {
+	var p probe
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return nil, err
+	}
+	if p.ID == "" {
+		return nil, fmt.Errorf("missing probe id")
+	}
+	if p.Type != "" && p.Type != logProbeType {
+		return nil, fmt.Errorf("unsupported probe type %q", p.Type)
+	}
+	if p.Language != "" && p.Language != "go" {
+		return nil, fmt.Errorf("unsupported probe language %q", p.Language)
+	}
+	if p.Where.MethodName == "" {
+		return nil, fmt.Errorf("missing probe location")
+	}
+	rate := p.Sampling.SnapshotsPerSecond
+	if rate <= 0 {
+		rate = defaultSnapshotsPerSecond
+	}
+	p.sampler = newSampler(rate)
+	return &p, nil
+}
+
+// location returns the name of the function the probe is installed on, e.g.
+// "main.handle" or "github.com/user/repo/pkg.(*Server).Serve", formatted as
+// reported by runtime.FuncForPC.
+func (p *probe) location() string {
+	if p.Where.TypeName == "" {
+		return p.Where.MethodName
+	}
+	return p.Where.TypeName + "." + p.Where.MethodName
+}
+
+// probeSet holds the installed probes, indexed by the path of the remote
+// configuration files they were received with, and by location.
+type probeSet struct {
+	mu     sync.Mutex
+	byPath map[string]*probe
+	// byLocation holds a map[string][]*probe, rebuilt on every update, so that
+	// the probes of a function can be looked up without locking.
+	byLocation atomic.Value
+}
+
+func newProbeSet() *probeSet {
+	s := &probeSet{byPath: make(map[string]*probe)}
+	s.byLocation.Store(map[string][]*probe(nil))
+	return s
+}
+
+// add installs p, replacing the probe received at the same path, if any.
+func (s *probeSet) add(path string, p *probe) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.byPath[path] = p
+	s.index()
+}
+
+// remove removes the probe received at path, if any.
+func (s *probeSet) remove(path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.byPath[path]; !ok {
+		return
+	}
+	delete(s.byPath, path)
+	s.index()
+}
+
+// clear removes all the probes.
+func (s *probeSet) clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.byPath = make(map[string]*probe)
+	s.index()
+}
+
+// index rebuilds the index by location. s.mu must be held.
+func (s *probeSet) index() {
+	if len(s.byPath) == 0 {
+		s.byLocation.Store(map[string][]*probe(nil))
+		return
+	}
+	m := make(map[string][]*probe)
+	for _, p := range s.byPath {
+		m[p.location()] = append(m[p.location()], p)
+	}
+	s.byLocation.Store(m)
+}
+
+// lookup returns the probes installed on the function with the given name.
+func (s *probeSet) lookup(function string) []*probe {
+	return s.byLocation.Load().(map[string][]*probe)[function]
+}
+
+// empty returns true when no probes are installed.
+func (s *probeSet) empty() bool {
+	return len(s.byLocation.Load().(map[string][]*probe)) == 0
+}
+
+// sampler limits the number of snapshots captured by a probe per second.
+type sampler struct {
+	interval time.Duration
+	mu       sync.Mutex
+	last     time.Time
+}
+
+func newSampler(perSecond float64) *sampler {
+	return &sampler{interval: time.Duration(float64(time.Second) / perSecond)}
+}
+
+// allow returns true when a snapshot can be captured at time now.
+func (s *sampler) allow(now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.last.IsZero() && now.Sub(s.last) < s.interval {
+		return false
+	}
+	s.last = now
+	return true
+}
@@ -0,0 +1,267 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022 Datadog, Inc.
+
+package debugger
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// maxValueLength is the maximum length of the captured values, which are
+	// truncated beyond it.
+	maxValueLength = 255
+
+	// maxStackDepth is the maximum number of frames of the captured stacks.
+	maxStackDepth = 32
+)
+
+// A Capture is a named value captured by a probe, such as an argument or a
+// return value of a function.
+type Capture struct {
+	Name  string
+	Value interface{}
+}
+
+// A Call is a call of a function on which probes are installed, started by
+// Enter and finished by its Exit method.
+type Call struct {
+	d        *debugger
+	function string
+	probes   []*probe
+	traceID  uint64
+	spanID   uint64
+	start    time.Time
+	args     []Capture
+	stack    []stackFrame
+}
+
+// Enter is called upon entering the function with the given name, called with
+// the given arguments in the span with the given IDs, if any. It returns the
+// call of the function when probes are installed on it, and nil otherwise, in
+// which case nothing is captured. It is cheap when there are no probes.
+func Enter(function string, traceID, spanID uint64, args []Capture) *Call {
+	d := activeDebugger()
+	if d == nil || d.probes.empty() {
+		return nil
+	}
+	var (
+		now    = time.Now()
+		probes []*probe
+	)
+	for _, p := range d.probes.lookup(function) {
+		if p.sampler.allow(now) {
+			probes = append(probes, p)
+		}
+	}
+	if len(probes) == 0 {
+		return nil
+	}
+	return &Call{
+		d:        d,
+		function: function,
+		probes:   probes,
+		traceID:  traceID,
+		spanID:   spanID,
+		start:    now,
+		args:     args,
+		// skip runtime.Callers, callerStack, Enter and the hook of the public package
+		stack: callerStack(4),
+	}
+}
+
+// Exit is called upon returning from the function of c, with the given return
+// values. It emits the snapshots of c captured by its probes.
+func (c *Call) Exit(returns []Capture) {
+	if c == nil {
+		return
+	}
+	duration := time.Since(c.start)
+	for _, p := range c.probes {
+		s := snapshotEvent{
+			Service: c.d.cfg.service,
+			Source:  "dd_debugger",
+			Message: message(p, c.function, c.args, returns),
+			TraceID: traceIDString(c.traceID),
+			SpanID:  traceIDString(c.spanID),
+			Env:     c.d.cfg.env,
+			Version: c.d.cfg.version,
+			Logger:  loggerInfo{Name: c.function, Method: c.function, Version: 2, ThreadName: "goroutine"},
+			Debugger: debuggerInfo{Snapshot: snapshot{
+				ID:        uuid.New().String(),
+				Timestamp: c.start.UnixNano() / int64(time.Millisecond),
+				Duration:  duration.Nanoseconds(),
+				Language:  "go",
+				Probe: probeInfo{
+					ID:       p.ID,
+					Version:  p.Version,
+					Location: probeLocation{Method: p.Where.MethodName, Type: p.Where.TypeName},
+				},
+				Stack: c.stack,
+			}},
+		}
+		if p.CaptureSnapshot {
+			s.Debugger.Snapshot.Captures = &captures{
+				Entry:  &capture{Arguments: captureValues(c.args)},
+				Return: &capture{Arguments: captureValues(c.args), Locals: captureValues(returns)},
+			}
+		}
+		c.d.uploader.add(s)
+	}
+}
+
+// message returns the message of the snapshot of the call of the given
+// function captured by p: its template with the references to the arguments
+// and return values replaced with their values, e.g. "{id}" for the id
+// argument, or the call of the function when p has no template.
+func message(p *probe, function string, args, returns []Capture) string {
+	if p.Template == "" {
+		var b strings.Builder
+		b.WriteString(function)
+		b.WriteByte('(')
+		for i, a := range args {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(a.Name + "=" + formatValue(a.Value))
+		}
+		b.WriteByte(')')
+		return b.String()
+	}
+	pairs := make([]string, 0, 2*(len(args)+len(returns)))
+	for _, captures := range [][]Capture{args, returns} {
+		for _, c := range captures {
+			pairs = append(pairs, "{"+c.Name+"}", formatValue(c.Value))
+		}
+	}
+	return strings.NewReplacer(pairs...).Replace(p.Template)
+}
+
+// captureValues returns the captured values of the given captures, indexed by
+// name.
+func captureValues(captures []Capture) map[string]capturedValue {
+	if len(captures) == 0 {
+		return nil
+	}
+	m := make(map[string]capturedValue, len(captures))
+	for _, c := range captures {
+		v := capturedValue{Type: "nil", IsNull: true}
+		if c.Value != nil {
+			v = capturedValue{Type: reflect.TypeOf(c.Value).String(), Value: formatValue(c.Value)}
+		}
+		m[c.Name] = v
+	}
+	return m
+}
+
+// formatValue returns the string representation of v, truncated to
+// maxValueLength.
+func formatValue(v interface{}) string {
+	s := fmt.Sprintf("%+v", v)
+	if len(s) > maxValueLength {
+		s = s[:maxValueLength] + "..."
+	}
+	return s
+}
+
+// callerStack returns the stack of the caller skip frames above, as reported
+// by runtime.Callers.
+func callerStack(skip int) []stackFrame {
+	pcs := make([]uintptr, maxStackDepth)
+	n := runtime.Callers(skip, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	stack := make([]stackFrame, 0, n)
+	for {
+		f, more := frames.Next()
+		stack = append(stack, stackFrame{Function: f.Function, FileName: f.File, LineNumber: f.Line})
+		if !more {
+			break
+		}
+	}
+	return stack
+}
+
+// traceIDString returns the decimal representation of id, or an empty string
+// when it is 0.
+func traceIDString(id uint64) string {
+	if id == 0 {
+		return ""
+	}
+	return strconv.FormatUint(id, 10)
+}
+
+// snapshotEvent is the payload of a snapshot sent to the debugger intake.
+type snapshotEvent struct {
+	Service  string       `json:"service"`
+	Source   string       `json:"ddsource"`
+	Message  string       `json:"message"`
+	TraceID  string       `json:"dd.trace_id,omitempty"`
+	SpanID   string       `json:"dd.span_id,omitempty"`
+	Env      string       `json:"env,omitempty"`
+	Version  string       `json:"version,omitempty"`
+	Logger   loggerInfo   `json:"logger"`
+	Debugger debuggerInfo `json:"debugger"`
+}
+
+type loggerInfo struct {
+	Name       string `json:"name"`
+	Method     string `json:"method"`
+	Version    int    `json:"version"`
+	ThreadName string `json:"thread_name"`
+}
+
+type debuggerInfo struct {
+	Snapshot snapshot `json:"snapshot"`
+}
+
+type snapshot struct {
+	ID        string       `json:"id"`
+	Timestamp int64        `json:"timestamp"`
+	Duration  int64        `json:"duration"`
+	Language  string       `json:"language"`
+	Probe     probeInfo    `json:"probe"`
+	Stack     []stackFrame `json:"stack,omitempty"`
+	Captures  *captures    `json:"captures,omitempty"`
+}
+
+type probeInfo struct {
+	ID       string        `json:"id"`
+	Version  int           `json:"version"`
+	Location probeLocation `json:"location"`
+}
+
+type probeLocation struct {
+	Method string `json:"method"`
+	Type   string `json:"type,omitempty"`
+}
+
+type stackFrame struct {
+	Function   string `json:"function"`
+	FileName   string `json:"fileName"`
+	LineNumber int    `json:"lineNumber"`
+}
+
+type captures struct {
+	Entry  *capture `json:"entry,omitempty"`
+	Return *capture `json:"return,omitempty"`
+}
+
+type capture struct {
+	Arguments map[string]capturedValue `json:"arguments,omitempty"`
+	Locals    map[string]capturedValue `json:"locals,omitempty"`
+}
+
+type capturedValue struct {
+	Type   string `json:"type"`
+	Value  string `json:"value,omitempty"`
+	IsNull bool   `json:"isNull,omitempty"`
+}
@@ -0,0 +1,127 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022 Datadog, Inc.
+
+package debugger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+)
+
+const (
+	// snapshotsPath is the path of the agent's proxy to the debugger intake.
+	snapshotsPath = "/debugger/v1/input"
+
+	// maxBufferedSnapshots is the maximum number of snapshots waiting to be
+	// sent. The snapshots captured beyond it are dropped.
+	maxBufferedSnapshots = 1000
+)
+
+// uploader periodically sends the captured snapshots to the debugger intake
+// through the agent.
+type uploader struct {
+	url    string
+	client *http.Client
+
+	mu        sync.Mutex
+	snapshots []snapshotEvent
+	dropped   int
+
+	stopOnce sync.Once
+	stopped  chan struct{}
+	done     chan struct{}
+}
+
+func newUploader(cfg *config) *uploader {
+	u := &uploader{
+		url:     fmt.Sprintf("http://%s%s", cfg.agentAddr, snapshotsPath),
+		client:  cfg.httpClient,
+		stopped: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go u.run(cfg.flushInterval)
+	return u
+}
+
+// add queues s to be sent with the next upload.
+func (u *uploader) add(s snapshotEvent) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if len(u.snapshots) >= maxBufferedSnapshots {
+		u.dropped++
+		return
+	}
+	u.snapshots = append(u.snapshots, s)
+}
+
+func (u *uploader) run(interval time.Duration) {
+	defer close(u.done)
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-tick.C:
+			u.flush()
+		case <-u.stopped:
+			u.flush()
+			return
+		}
+	}
+}
+
+// stop stops the uploader once the queued snapshots are sent. Subsequent calls
+// are no-op.
+func (u *uploader) stop() {
+	u.stopOnce.Do(func() {
+		close(u.stopped)
+	})
+	<-u.done
+}
+
+// flush sends the queued snapshots.
+func (u *uploader) flush() {
+	u.mu.Lock()
+	snapshots, dropped := u.snapshots, u.dropped
+	u.snapshots, u.dropped = nil, 0
+	u.mu.Unlock()
+	if dropped > 0 {
+		log.Warn("debugger: dropped %d snapshots: too many snapshots waiting to be sent", dropped)
+	}
+	if len(snapshots) == 0 {
+		return
+	}
+	if err := u.send(snapshots); err != nil {
+		log.Error("debugger: lost %d snapshots: %v", len(snapshots), err)
+	}
+}
+
+func (u *uploader) send(snapshots []snapshotEvent) error {
+	body, err := json.Marshal(snapshots)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, u.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("unexpected response status: %s", resp.Status)
+	}
+	return nil
+}
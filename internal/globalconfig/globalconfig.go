@@ -9,21 +9,38 @@ package globalconfig
 
 import (
 	"math"
+	"os"
+	"strings"
 	"sync"
 
+	"gopkg.in/DataDog/dd-trace-go.v1/internal"
+
 	"github.com/google/uuid"
 )
 
 var cfg = &config{
-	analyticsRate: math.NaN(),
-	runtimeID:     uuid.New().String(),
+	analyticsRate:          math.NaN(),
+	runtimeID:              uuid.New().String(),
+	clientIPHeader:         os.Getenv("DD_TRACE_CLIENT_IP_HEADER"),
+	clientIPHeaders:        ParseClientIPHeaders(os.Getenv("DD_TRACE_CLIENT_IP_HEADERS")),
+	clientIPHeaderDisabled: internal.BoolEnv("DD_TRACE_CLIENT_IP_HEADER_DISABLED", false),
+	clientIPAllowPrivate:   internal.BoolEnv("DD_TRACE_CLIENT_IP_ALLOW_PRIVATE", false),
+	env:                    os.Getenv("DD_ENV"),
+	serviceVersion:         os.Getenv("DD_VERSION"),
 }
 
 type config struct {
-	mu            sync.RWMutex
-	analyticsRate float64
-	serviceName   string
-	runtimeID     string
+	mu                     sync.RWMutex
+	analyticsRate          float64
+	serviceName            string
+	runtimeID              string
+	clientIPHeader         string
+	clientIPHeaders        []string
+	clientIPHeaderDisabled bool
+	clientIPAllowPrivate   bool
+	env                    string
+	serviceVersion         string
+	dogstatsdAddr          string
 }
 
 // AnalyticsRate returns the sampling rate at which events should be marked. It uses
@@ -62,3 +79,124 @@ func RuntimeID() string {
 	defer cfg.mu.RUnlock()
 	return cfg.runtimeID
 }
+
+// ClientIPHeader returns the header name used to resolve the client IP of HTTP
+// requests. An empty value means the default list of IP headers is used.
+func ClientIPHeader() string {
+	cfg.mu.RLock()
+	defer cfg.mu.RUnlock()
+	return cfg.clientIPHeader
+}
+
+// SetClientIPHeader sets the header name used to resolve the client IP of HTTP
+// requests.
+func SetClientIPHeader(header string) {
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+	cfg.clientIPHeader = header
+}
+
+// ClientIPHeaders returns the names of the headers used, in order of priority,
+// to resolve the client IP of HTTP requests when ClientIPHeader is empty.
+func ClientIPHeaders() []string {
+	cfg.mu.RLock()
+	defer cfg.mu.RUnlock()
+	return cfg.clientIPHeaders
+}
+
+// SetClientIPHeaders sets the names of the headers used, in order of priority,
+// to resolve the client IP of HTTP requests.
+func SetClientIPHeaders(headers []string) {
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+	cfg.clientIPHeaders = headers
+}
+
+// ParseClientIPHeaders returns the header names of the comma-separated list v,
+// as set by DD_TRACE_CLIENT_IP_HEADERS.
+func ParseClientIPHeaders(v string) []string {
+	var headers []string
+	for _, h := range strings.Split(v, ",") {
+		if h = strings.TrimSpace(h); h != "" {
+			headers = append(headers, h)
+		}
+	}
+	return headers
+}
+
+// ClientIPHeaderDisabled returns whether the headers of HTTP requests are left
+// out of the resolution of their client IP, which is then their remote address.
+func ClientIPHeaderDisabled() bool {
+	cfg.mu.RLock()
+	defer cfg.mu.RUnlock()
+	return cfg.clientIPHeaderDisabled
+}
+
+// SetClientIPHeaderDisabled sets whether the headers of HTTP requests are left
+// out of the resolution of their client IP.
+func SetClientIPHeaderDisabled(disabled bool) {
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+	cfg.clientIPHeaderDisabled = disabled
+}
+
+// ClientIPAllowPrivate returns whether private IPs can be resolved as the client
+// IP of HTTP requests, when no public IP is found.
+func ClientIPAllowPrivate() bool {
+	cfg.mu.RLock()
+	defer cfg.mu.RUnlock()
+	return cfg.clientIPAllowPrivate
+}
+
+// SetClientIPAllowPrivate sets whether private IPs can be resolved as the client
+// IP of HTTP requests.
+func SetClientIPAllowPrivate(allow bool) {
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+	cfg.clientIPAllowPrivate = allow
+}
+
+// Env returns the environment of the application, as configured by the tracer
+// or by the DD_ENV environment variable.
+func Env() string {
+	cfg.mu.RLock()
+	defer cfg.mu.RUnlock()
+	return cfg.env
+}
+
+// SetEnv sets the environment of the application.
+func SetEnv(env string) {
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+	cfg.env = env
+}
+
+// ServiceVersion returns the version of the application, as configured by the
+// tracer or by the DD_VERSION environment variable.
+func ServiceVersion() string {
+	cfg.mu.RLock()
+	defer cfg.mu.RUnlock()
+	return cfg.serviceVersion
+}
+
+// SetServiceVersion sets the version of the application.
+func SetServiceVersion(version string) {
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+	cfg.serviceVersion = version
+}
+
+// DogstatsdAddr returns the address of the Dogstatsd server the tracer reports
+// its metrics to. It is empty until the tracer is started.
+func DogstatsdAddr() string {
+	cfg.mu.RLock()
+	defer cfg.mu.RUnlock()
+	return cfg.dogstatsdAddr
+}
+
+// SetDogstatsdAddr sets the address of the Dogstatsd server.
+func SetDogstatsdAddr(addr string) {
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+	cfg.dogstatsdAddr = addr
+}
@@ -0,0 +1,95 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package internal
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// envDisabledIntegrations is the name of the env var holding the comma-separated
+// list of the integrations to disable.
+const envDisabledIntegrations = "DD_TRACE_DISABLED_INTEGRATIONS"
+
+// integrationNames caches the normalized name of each integration.
+var integrationNames sync.Map // map[string]string
+
+// Integrations holds the integrations enabled or disabled by the environment, as
+// it was when they were created. An integration is disabled by setting
+// DD_TRACE_<NAME>_ENABLED to false, where <NAME> is its upper-cased name with
+// non-alphanumeric characters replaced by underscores, e.g. DD_TRACE_GIN_ENABLED
+// or DD_TRACE_GRPC_GATEWAY_ENABLED, or by listing it in the comma-separated
+// DD_TRACE_DISABLED_INTEGRATIONS, the former taking precedence.
+type Integrations struct {
+	enabled  map[string]bool // by normalized name, from DD_TRACE_<NAME>_ENABLED
+	disabled map[string]bool // normalized names, from DD_TRACE_DISABLED_INTEGRATIONS
+}
+
+// NewIntegrations returns the Integrations configured by the environment.
+func NewIntegrations() *Integrations {
+	in := &Integrations{
+		enabled:  make(map[string]bool),
+		disabled: make(map[string]bool),
+	}
+	for _, kv := range os.Environ() {
+		i := strings.IndexByte(kv, '=')
+		if i < 0 {
+			continue
+		}
+		k, v := kv[:i], kv[i+1:]
+		if !strings.HasPrefix(k, "DD_TRACE_") || !strings.HasSuffix(k, "_ENABLED") {
+			continue
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(k, "DD_TRACE_"), "_ENABLED")
+		if name == "" {
+			continue
+		}
+		if enabled, err := strconv.ParseBool(v); err == nil {
+			in.enabled[name] = enabled
+		}
+	}
+	for _, n := range strings.Split(os.Getenv(envDisabledIntegrations), ",") {
+		if n = strings.TrimSpace(n); n != "" {
+			in.disabled[normalizeIntegration(n)] = true
+		}
+	}
+	return in
+}
+
+// Enabled reports whether the integration with the given name, which its spans
+// hold as their component tag, is enabled. A nil Integrations enables them all.
+func (in *Integrations) Enabled(name string) bool {
+	if in == nil || (len(in.enabled) == 0 && len(in.disabled) == 0) {
+		return true
+	}
+	v, ok := integrationNames.Load(name)
+	if !ok {
+		v = normalizeIntegration(name)
+		integrationNames.Store(name, v)
+	}
+	norm := v.(string)
+	if enabled, ok := in.enabled[norm]; ok {
+		return enabled
+	}
+	return !in.disabled[norm]
+}
+
+// normalizeIntegration returns the given integration name upper-cased, with its
+// non-alphanumeric characters replaced by underscores.
+func normalizeIntegration(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		default:
+			return '_'
+		}
+	}, name)
+}
@@ -0,0 +1,55 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIntegrationsEnabled(t *testing.T) {
+	t.Run("default", func(t *testing.T) {
+		assert.True(t, NewIntegrations().Enabled("gin"))
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		var in *Integrations
+		assert.True(t, in.Enabled("gin"))
+	})
+
+	t.Run("env", func(t *testing.T) {
+		t.Setenv("DD_TRACE_GRPC_GATEWAY_ENABLED", "false")
+		in := NewIntegrations()
+		assert.False(t, in.Enabled("grpc-gateway"))
+		assert.True(t, in.Enabled("grpc"))
+	})
+
+	t.Run("list", func(t *testing.T) {
+		t.Setenv("DD_TRACE_DISABLED_INTEGRATIONS", "redis, GRPC-gateway,,")
+		in := NewIntegrations()
+		assert.False(t, in.Enabled("redis"))
+		assert.False(t, in.Enabled("grpc-gateway"))
+		assert.True(t, in.Enabled("gin"))
+	})
+
+	t.Run("env-precedence", func(t *testing.T) {
+		t.Setenv("DD_TRACE_DISABLED_INTEGRATIONS", "redis,gin")
+		t.Setenv("DD_TRACE_REDIS_ENABLED", "true")
+		t.Setenv("DD_TRACE_GIN_ENABLED", "invalid")
+		in := NewIntegrations()
+		assert.True(t, in.Enabled("redis"))
+		assert.False(t, in.Enabled("gin"))
+	})
+
+	t.Run("resolved-once", func(t *testing.T) {
+		t.Setenv("DD_TRACE_DISABLED_INTEGRATIONS", "sql")
+		in := NewIntegrations()
+		t.Setenv("DD_TRACE_DISABLED_INTEGRATIONS", "redis")
+		assert.False(t, in.Enabled("sql"))
+		assert.True(t, in.Enabled("redis"))
+	})
+}
@@ -0,0 +1,163 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+// Package namingschema provides the span names and default service names the
+// integrations use, according to the naming schema selected with the
+// DD_TRACE_SPAN_ATTRIBUTE_SCHEMA environment variable.
+//
+// The v0 schema, which is the default, keeps the names each integration has
+// always used. The v1 schema gives the spans of the same type the same name,
+// e.g. "http.server.request" or "kafka.process", makes the integrations
+// inherit the global service name instead of having their own, and makes the
+// tracer give the client and producer spans a peer.service tag.
+package namingschema
+
+import (
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+)
+
+// Version is the version of a naming schema.
+type Version int32
+
+const (
+	// SchemaV0 is the naming schema the integrations have always used.
+	SchemaV0 Version = iota
+	// SchemaV1 is the naming schema with unified span names and inherited
+	// service names.
+	SchemaV1
+)
+
+// String implements fmt.Stringer.
+func (v Version) String() string {
+	switch v {
+	case SchemaV1:
+		return "v1"
+	default:
+		return "v0"
+	}
+}
+
+// envSchema is the name of the env var selecting the naming schema.
+const envSchema = "DD_TRACE_SPAN_ATTRIBUTE_SCHEMA"
+
+// version holds the Version currently in use.
+var version int32
+
+func init() {
+	v, ok := os.LookupEnv(envSchema)
+	if !ok {
+		return
+	}
+	sv, ok := ParseVersion(v)
+	if !ok {
+		log.Warn("Invalid value %q for %s, defaulting to %s", v, envSchema, SchemaV0)
+	}
+	SetVersion(sv)
+}
+
+// ParseVersion parses the given version, e.g. "v1". It returns SchemaV0 and
+// false when the version is unknown.
+func ParseVersion(v string) (Version, bool) {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "", "v0":
+		return SchemaV0, true
+	case "v1":
+		return SchemaV1, true
+	default:
+		return SchemaV0, false
+	}
+}
+
+// GetVersion returns the naming schema version currently in use.
+func GetVersion() Version {
+	return Version(atomic.LoadInt32(&version))
+}
+
+// SetVersion sets the naming schema version to use.
+func SetVersion(v Version) {
+	atomic.StoreInt32(&version, int32(v))
+}
+
+// ServiceName returns the default service name of an integration which has its
+// own in the v0 schema: v0 in the v0 schema and, in the v1 schema, the global
+// service name, or v0 when none is set.
+func ServiceName(v0 string) string {
+	if GetVersion() == SchemaV0 {
+		return v0
+	}
+	if svc := globalconfig.ServiceName(); svc != "" {
+		return svc
+	}
+	return v0
+}
+
+// opName returns v1 in the v1 schema, and v0 otherwise.
+func opName(v0, v1 string) string {
+	if GetVersion() == SchemaV0 {
+		return v0
+	}
+	return v1
+}
+
+// HTTPServerOp returns the name of the spans of the HTTP requests received by
+// a server. v0 is the name used in the v0 schema.
+func HTTPServerOp(v0 string) string {
+	return opName(v0, "http.server.request")
+}
+
+// HTTPClientOp returns the name of the spans of the HTTP requests sent by a
+// client. v0 is the name used in the v0 schema.
+func HTTPClientOp(v0 string) string {
+	return opName(v0, "http.client.request")
+}
+
+// GRPCServerOp returns the name of the spans of the gRPC calls received by a
+// server. v0 is the name used in the v0 schema.
+func GRPCServerOp(v0 string) string {
+	return opName(v0, "grpc.server.request")
+}
+
+// GRPCClientOp returns the name of the spans of the gRPC calls made by a
+// client. v0 is the name used in the v0 schema.
+func GRPCClientOp(v0 string) string {
+	return opName(v0, "grpc.client.request")
+}
+
+// GraphQLServerOp returns the name of the spans of the GraphQL requests
+// received by a server. v0 is the name used in the v0 schema.
+func GraphQLServerOp(v0 string) string {
+	return opName(v0, "graphql.server.request")
+}
+
+// MessagingOutboundOp returns the name of the spans of the messages sent to
+// the given messaging system, e.g. "kafka". v0 is the name used in the v0
+// schema.
+func MessagingOutboundOp(system, v0 string) string {
+	return opName(v0, system+".send")
+}
+
+// MessagingInboundOp returns the name of the spans of the messages received
+// from the given messaging system, e.g. "kafka". v0 is the name used in the v0
+// schema.
+func MessagingInboundOp(system, v0 string) string {
+	return opName(v0, system+".process")
+}
+
+// DBOp returns the name of the spans of the queries run on the given database
+// system, e.g. "postgresql". v0 is the name used in the v0 schema.
+func DBOp(system, v0 string) string {
+	return opName(v0, system+".query")
+}
+
+// CacheOp returns the name of the spans of the commands sent to the given
+// cache system, e.g. "redis". v0 is the name used in the v0 schema.
+func CacheOp(system, v0 string) string {
+	return opName(v0, system+".command")
+}
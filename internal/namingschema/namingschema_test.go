@@ -0,0 +1,64 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package namingschema
+
+import (
+	"testing"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseVersion(t *testing.T) {
+	for in, want := range map[string]Version{
+		"":    SchemaV0,
+		"v0":  SchemaV0,
+		"V1":  SchemaV1,
+		" v1": SchemaV1,
+	} {
+		v, ok := ParseVersion(in)
+		assert.True(t, ok, in)
+		assert.Equal(t, want, v, in)
+	}
+	v, ok := ParseVersion("v2")
+	assert.False(t, ok)
+	assert.Equal(t, SchemaV0, v)
+}
+
+func TestNames(t *testing.T) {
+	defer SetVersion(GetVersion())
+	defer func(svc string) { globalconfig.SetServiceName(svc) }(globalconfig.ServiceName())
+	globalconfig.SetServiceName("")
+
+	t.Run("v0", func(t *testing.T) {
+		SetVersion(SchemaV0)
+		assert.Equal(t, "http.request", HTTPServerOp("http.request"))
+		assert.Equal(t, "kafka.consume", MessagingInboundOp("kafka", "kafka.consume"))
+		assert.Equal(t, "redis.command", CacheOp("redis", "redis.command"))
+		globalconfig.SetServiceName("my-service")
+		defer globalconfig.SetServiceName("")
+		assert.Equal(t, "redis.client", ServiceName("redis.client"))
+	})
+
+	t.Run("v1", func(t *testing.T) {
+		SetVersion(SchemaV1)
+		assert.Equal(t, "http.server.request", HTTPServerOp("http.request"))
+		assert.Equal(t, "http.client.request", HTTPClientOp("http.request"))
+		assert.Equal(t, "grpc.server.request", GRPCServerOp("grpc.server"))
+		assert.Equal(t, "grpc.client.request", GRPCClientOp("grpc.client"))
+		assert.Equal(t, "graphql.server.request", GraphQLServerOp("graphql.request"))
+		assert.Equal(t, "kafka.send", MessagingOutboundOp("kafka", "kafka.produce"))
+		assert.Equal(t, "kafka.process", MessagingInboundOp("kafka", "kafka.consume"))
+		assert.Equal(t, "postgresql.query", DBOp("postgresql", "pgx.query"))
+		assert.Equal(t, "memcached.command", CacheOp("memcached", "memcached.query"))
+
+		assert.Equal(t, "redis.client", ServiceName("redis.client"))
+		globalconfig.SetServiceName("my-service")
+		defer globalconfig.SetServiceName("")
+		assert.Equal(t, "my-service", ServiceName("redis.client"))
+	})
+}
@@ -8,6 +8,7 @@ package remoteconfig
 import (
 	"bytes"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
@@ -16,11 +17,13 @@ import (
 	"net/http"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	rc "github.com/DataDog/datadog-agent/pkg/remoteconfig/state"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/version"
 )
 
@@ -42,8 +45,52 @@ const (
 	ASMIPBlocking
 	// ASMDDRules represents the capability to update the rules used by the ASM WAF for threat detection
 	ASMDDRules
+	// APMTracingSampleRate represents the capability to update the tracer's global sampling rate at runtime
+	APMTracingSampleRate
+	// APMTracingGlobalConfig represents the capability to update the tracer's env, version and service mappings at runtime
+	APMTracingGlobalConfig
+	// ASMUserBlocking represents the capability for ASM to block requests based on the user ID
+	ASMUserBlocking
+	// ASMRuleOverrides represents the capability to enable or disable the rules used by the ASM WAF
+	ASMRuleOverrides
 )
 
+const (
+	// ProductLiveDebugging is the remote config product of the Dynamic
+	// Instrumentation probes.
+	ProductLiveDebugging = "LIVE_DEBUGGING"
+	// ProductAPMTracing is the remote config product of the tracer settings,
+	// such as the sample rate and the global tags.
+	ProductAPMTracing = "APM_TRACING"
+	// ProductASMData is the remote config product of the ASM rules data, such
+	// as the IP and user denylists.
+	ProductASMData = "ASM_DATA"
+	// ProductASM is the remote config product of the ASM rule overrides.
+	ProductASM = "ASM"
+)
+
+// rawProducts are the products unknown to the repository, which rejects the
+// updates holding their configurations. Their configuration files are tracked
+// by the client itself instead, without TUF verification.
+var rawProducts = map[string]bool{
+	ProductLiveDebugging: true,
+	ProductAPMTracing:    true,
+	ProductASMData:       true,
+	ProductASM:           true,
+}
+
+// rawFile is the state of a configuration file of one of rawProducts.
+type rawFile struct {
+	product string
+	hash    [sha256.Size]byte
+	length  int
+	status  rc.ApplyStatus
+}
+
+// maxPollBackoff is the maximum delay between two polls of a client whose
+// previous polls failed.
+const maxPollBackoff = 5 * time.Minute
+
 // DefaultClientConfig returns the default remote config client configuration
 func DefaultClientConfig() ClientConfig {
 	return ClientConfig{
@@ -79,7 +126,8 @@ type ClientConfig struct {
 	ServiceName string
 	// The semantic version of the tracer
 	TracerVersion string
-	// The base TUF root metadata file
+	// The base TUF root metadata file. When set, the TUF metadata of the received
+	// configurations is verified against it.
 	TUFRoot string
 	// The capabilities of the client
 	Capabilities []Capability
@@ -96,15 +144,32 @@ type Client struct {
 	endpoint   string
 	repository *rc.Repository
 	stop       chan struct{}
+	stopOnce   sync.Once
 
+	// mu guards Products, Capabilities and callbacks, which can be updated
+	// while the client is polling.
+	mu        sync.RWMutex
 	callbacks map[string][]Callback
 
+	// rawFiles holds the configuration files of rawProducts, by path. Like
+	// repository, it is only accessed by the poll loop.
+	rawFiles map[string]*rawFile
+
 	lastError error
 }
 
-// NewClient creates a new remoteconfig Client
+// NewClient creates a new remoteconfig Client. The TUF metadata of the
+// configurations it receives are only verified when config.TUFRoot is set.
 func NewClient(config ClientConfig) (*Client, error) {
-	repo, err := rc.NewUnverifiedRepository()
+	var (
+		repo *rc.Repository
+		err  error
+	)
+	if config.TUFRoot != "" {
+		repo, err = rc.NewRepository([]byte(config.TUFRoot))
+	} else {
+		repo, err = rc.NewUnverifiedRepository()
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -120,90 +185,204 @@ func NewClient(config ClientConfig) (*Client, error) {
 		stop:         make(chan struct{}),
 		lastError:    nil,
 		callbacks:    map[string][]Callback{},
+		rawFiles:     map[string]*rawFile{},
 	}, nil
 }
 
-// Start starts the client's update poll loop in a fresh goroutine
+// Start starts the client's update poll loop in a fresh goroutine. Failed polls
+// are retried with an exponential backoff, up to maxPollBackoff.
 func (c *Client) Start() {
 	go func() {
-		ticker := time.NewTicker(c.PollInterval)
-		defer ticker.Stop()
+		timer := time.NewTimer(c.PollInterval)
+		defer timer.Stop()
 
+		failures := 0
 		for {
 			select {
 			case <-c.stop:
 				return
-			case <-ticker.C:
-				c.updateState()
+			case <-timer.C:
+				if err := c.updateState(); err != nil {
+					failures++
+					log.Debug("Remote config: update failed (%d consecutive failures): %v", failures, err)
+				} else {
+					failures = 0
+				}
+				timer.Reset(c.pollDelay(failures))
 			}
 		}
 	}()
 }
 
-// Stop stops the client's update poll loop
+// Stop stops the client's update poll loop. Subsequent calls are no-op.
 func (c *Client) Stop() {
-	close(c.stop)
+	c.stopOnce.Do(func() { close(c.stop) })
 }
 
-func (c *Client) updateState() {
+// pollDelay returns the delay before the next poll, given the number of
+// consecutive failed polls. The poll interval is doubled on each failure, up
+// to maxPollBackoff.
+func (c *Client) pollDelay(failures int) time.Duration {
+	d := c.PollInterval
+	for i := 0; i < failures && d < maxPollBackoff; i++ {
+		d *= 2
+		if d > maxPollBackoff {
+			d = maxPollBackoff
+		}
+	}
+	return d
+}
+
+// updateState polls the agent for configuration updates and applies them. The
+// returned error is reported to the agent with the next poll.
+func (c *Client) updateState() error {
+	c.lastError = c.fetchAndApply()
+	return c.lastError
+}
+
+func (c *Client) fetchAndApply() error {
 	data, err := c.newUpdateRequest()
 	if err != nil {
-		c.lastError = err
-		return
+		return err
 	}
 
 	req, err := http.NewRequest(http.MethodGet, c.endpoint, &data)
 	if err != nil {
-		c.lastError = err
-		return
+		return err
 	}
 
 	resp, err := c.HTTP.Do(req)
 	if err != nil {
-		c.lastError = err
-		return
+		return err
 	}
 	// Flush and close the response body when returning (cf. https://pkg.go.dev/net/http#Client.Do)
 	defer func() {
 		io.ReadAll(resp.Body)
 		resp.Body.Close()
 	}()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected response status: %s", resp.Status)
+	}
 
 	var update clientGetConfigsResponse
-	err = json.NewDecoder(resp.Body).Decode(&update)
-	if err != nil {
-		c.lastError = err
-		return
+	if err := json.NewDecoder(resp.Body).Decode(&update); err != nil {
+		return err
 	}
 
-	err = c.applyUpdate(&update)
-	c.lastError = err
+	return c.applyUpdate(&update)
 }
 
 // RegisterCallback allows registering a callback that will be invoked when the client
 // receives a configuration update for the specified product.
 func (c *Client) RegisterCallback(f Callback, product string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.callbacks[product] = append(c.callbacks[product], f)
 }
 
+// RegisterProduct adds product to the products the client requests
+// configurations for, unless it is already registered.
+func (c *Client) RegisterProduct(product string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.registerProduct(product)
+}
+
+// RegisterCapability adds capability to the capabilities the client reports
+// to the agent, unless it is already registered.
+func (c *Client) RegisterCapability(capability Capability) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.registerCapability(capability)
+}
+
+// Subscribe registers product and capabilities on the client, along with cb,
+// which is invoked every time the client receives configuration updates for
+// product. It can be called while the client is started.
+func (c *Client) Subscribe(product string, cb Callback, capabilities ...Capability) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.registerProduct(product)
+	for _, capability := range capabilities {
+		c.registerCapability(capability)
+	}
+	c.callbacks[product] = append(c.callbacks[product], cb)
+}
+
+func (c *Client) registerProduct(product string) {
+	for _, p := range c.Products {
+		if p == product {
+			return
+		}
+	}
+	c.Products = append(c.Products, product)
+}
+
+func (c *Client) registerCapability(capability Capability) {
+	for _, cap := range c.Capabilities {
+		if cap == capability {
+			return
+		}
+	}
+	c.Capabilities = append(c.Capabilities, capability)
+}
+
+// productFromPath returns the product of the configuration file at the given
+// path, formatted as datadog/<org_id>/<product>/<config_id>/<name> or
+// employee/<product>/<config_id>/<name>.
+func productFromPath(path string) string {
+	parts := strings.Split(path, "/")
+	switch {
+	case len(parts) > 2 && parts[0] == "datadog":
+		return parts[2]
+	case len(parts) > 1 && parts[0] == "employee":
+		return parts[1]
+	}
+	return ""
+}
+
+// configIDFromPath returns the config ID of the configuration file at the given
+// path, formatted as described by productFromPath.
+func configIDFromPath(path string) string {
+	parts := strings.Split(path, "/")
+	switch {
+	case len(parts) > 3 && parts[0] == "datadog":
+		return parts[3]
+	case len(parts) > 2 && parts[0] == "employee":
+		return parts[2]
+	}
+	return ""
+}
+
 func (c *Client) applyUpdate(pbUpdate *clientGetConfigsResponse) error {
-	fileMap := make(map[string][]byte, len(pbUpdate.TargetFiles))
+	c.mu.RLock()
 	productUpdates := make(map[string]ProductUpdate, len(c.Products))
+	for _, p := range c.Products {
+		productUpdates[p] = make(ProductUpdate)
+	}
+	c.mu.RUnlock()
+	fileMap := make(map[string][]byte, len(pbUpdate.TargetFiles))
 	for _, f := range pbUpdate.TargetFiles {
 		fileMap[f.Path] = f.Raw
-		for _, p := range c.Products {
-			productUpdates[p] = make(ProductUpdate)
-			if strings.Contains(f.Path, p) {
-				productUpdates[p][f.Path] = f.Raw
-			}
+		if u, ok := productUpdates[productFromPath(f.Path)]; ok {
+			u[f.Path] = f.Raw
 		}
 	}
 
+	// The configurations of rawProducts are kept out of the repository
+	var clientConfigs, rawConfigs []string
+	for _, path := range pbUpdate.ClientConfigs {
+		if rawProducts[productFromPath(path)] {
+			rawConfigs = append(rawConfigs, path)
+		} else {
+			clientConfigs = append(clientConfigs, path)
+		}
+	}
 	update := rc.Update{
 		TUFRoots:      pbUpdate.Roots,
 		TUFTargets:    pbUpdate.Targets,
 		TargetFiles:   fileMap,
-		ClientConfigs: pbUpdate.ClientConfigs,
+		ClientConfigs: clientConfigs,
 	}
 
 	mapify := func(s *rc.RepositoryState) map[string]string {
@@ -244,12 +423,24 @@ func (c *Client) applyUpdate(pbUpdate *clientGetConfigsResponse) error {
 	for _, p := range products {
 		updatedProducts[p] = true
 	}
+	// An empty response means that nothing changed, as for the repository
+	empty := len(pbUpdate.Roots) == 0 && len(pbUpdate.Targets) == 0 && len(pbUpdate.TargetFiles) == 0 && len(pbUpdate.ClientConfigs) == 0
+	if err == nil && !empty {
+		err = c.updateRawFiles(rawConfigs, fileMap, productUpdates, updatedProducts)
+	}
 
 	// Performs the callbacks registered for all updated products and update the application status in the repository
 	// (RCTE2)
 	for p := range updatedProducts {
-		for _, fn := range c.callbacks[p] {
+		c.mu.RLock()
+		callbacks := c.callbacks[p]
+		c.mu.RUnlock()
+		for _, fn := range callbacks {
 			for path, status := range fn(productUpdates[p]) {
+				if f, ok := c.rawFiles[path]; ok {
+					f.status = status
+					continue
+				}
 				c.repository.UpdateApplyStatus(path, status)
 			}
 		}
@@ -258,6 +449,51 @@ func (c *Client) applyUpdate(pbUpdate *clientGetConfigsResponse) error {
 	return err
 }
 
+// updateRawFiles updates the state of the configuration files of rawProducts
+// with the given client configs and target files. The new and modified files are
+// added to productUpdates, along with the removed ones, set to nil, and their
+// products are added to updatedProducts.
+func (c *Client) updateRawFiles(configs []string, files map[string][]byte, productUpdates map[string]ProductUpdate, updatedProducts map[string]bool) error {
+	var err error
+	update := func(product, path string, raw []byte) {
+		if productUpdates[product] == nil {
+			productUpdates[product] = make(ProductUpdate)
+		}
+		productUpdates[product][path] = raw
+		updatedProducts[product] = true
+	}
+	current := make(map[string]bool, len(configs))
+	for _, path := range configs {
+		current[path] = true
+		raw, ok := files[path]
+		if !ok {
+			if _, cached := c.rawFiles[path]; !cached {
+				err = fmt.Errorf("missing update file - %s", path)
+			}
+			continue
+		}
+		hash := sha256.Sum256(raw)
+		if f, ok := c.rawFiles[path]; ok && f.hash == hash {
+			continue
+		}
+		product := productFromPath(path)
+		c.rawFiles[path] = &rawFile{
+			product: product,
+			hash:    hash,
+			length:  len(raw),
+			status:  rc.ApplyStatus{State: rc.ApplyStateUnacknowledged},
+		}
+		update(product, path, raw)
+	}
+	for path, f := range c.rawFiles {
+		if !current[path] {
+			delete(c.rawFiles, path)
+			update(f.product, path, nil)
+		}
+	}
+	return err
+}
+
 func (c *Client) newUpdateRequest() (bytes.Buffer, error) {
 	state, err := c.repository.CurrentState()
 	if err != nil {
@@ -300,11 +536,27 @@ func (c *Client) newUpdateRequest() (bytes.Buffer, error) {
 			ApplyError: f.ApplyStatus.Error,
 		})
 	}
+	for path, f := range c.rawFiles {
+		pbCachedFiles = append(pbCachedFiles, &targetFileMeta{
+			Path:   path,
+			Length: int64(f.length),
+			Hashes: []*targetFileHash{{Algorithm: "sha256", Hash: hex.EncodeToString(f.hash[:])}},
+		})
+		pbConfigState = append(pbConfigState, &configState{
+			ID:         configIDFromPath(path),
+			Product:    f.product,
+			ApplyState: f.status.State,
+			ApplyError: f.status.Error,
+		})
+	}
 
+	c.mu.RLock()
+	products := append([]string(nil), c.Products...)
 	cap := big.NewInt(0)
 	for _, i := range c.Capabilities {
 		cap.SetBit(cap, int(i), 1)
 	}
+	c.mu.RUnlock()
 	req := clientGetConfigsRequest{
 		Client: &clientData{
 			State: &clientState{
@@ -315,7 +567,7 @@ func (c *Client) newUpdateRequest() (bytes.Buffer, error) {
 				Error:          errMsg,
 			},
 			ID:       c.clientID,
-			Products: c.Products,
+			Products: products,
 			IsTracer: true,
 			ClientTracer: &clientTracer{
 				RuntimeID:     c.RuntimeID,
@@ -340,6 +592,55 @@ func (c *Client) newUpdateRequest() (bytes.Buffer, error) {
 	return b, nil
 }
 
+var (
+	// sharedMu guards shared.
+	sharedMu sync.Mutex
+	// shared is the client started by Start, shared by all the products.
+	shared *Client
+)
+
+// Start creates and starts the remote configuration client shared by all the
+// products of the tracer, and returns it. When the shared client is already
+// started, it is returned as is and config is ignored.
+func Start(config ClientConfig) (*Client, error) {
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+	if shared != nil {
+		return shared, nil
+	}
+	client, err := NewClient(config)
+	if err != nil {
+		return nil, err
+	}
+	client.Start()
+	shared = client
+	return client, nil
+}
+
+// Stop stops the shared remote configuration client, if started.
+func Stop() {
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+	if shared != nil {
+		shared.Stop()
+		shared = nil
+	}
+}
+
+// Subscribe subscribes cb to the configuration updates of product received by
+// the shared client, and registers the given capabilities. This allows several
+// products, such as AppSec rules or sampling rates, to share a single poller.
+// It returns an error if the shared client is not started.
+func Subscribe(product string, cb Callback, capabilities ...Capability) error {
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+	if shared == nil {
+		return fmt.Errorf("remote configuration client not started")
+	}
+	shared.Subscribe(product, cb, capabilities...)
+	return nil
+}
+
 var (
 	idSize     = 21
 	idAlphabet = []rune("_-0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ")
@@ -9,8 +9,11 @@ import (
 	"crypto/sha256"
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	rc "github.com/DataDog/datadog-agent/pkg/remoteconfig/state"
 	"github.com/stretchr/testify/require"
@@ -60,6 +63,161 @@ func TestRCClient(t *testing.T) {
 	})
 }
 
+func TestSubscribe(t *testing.T) {
+	client, err := NewClient(DefaultClientConfig())
+	require.NoError(t, err)
+
+	const (
+		asmPath         = "datadog/2/ASM_DD/asm_rules/config"
+		asmFeaturesPath = "datadog/2/ASM_FEATURES/asm_features_activation/config"
+	)
+	updates := map[string]ProductUpdate{}
+	subscribe := func(product string, capabilities ...Capability) {
+		client.Subscribe(product, func(u ProductUpdate) map[string]rc.ApplyStatus {
+			updates[product] = u
+			return nil
+		}, capabilities...)
+	}
+	subscribe(rc.ProductASMDD, ASMDDRules)
+	subscribe(rc.ProductASMFeatures, ASMActivation)
+	subscribe(rc.ProductASMFeatures, ASMActivation)
+	require.Equal(t, []string{rc.ProductASMDD, rc.ProductASMFeatures}, client.Products)
+	require.Equal(t, []Capability{ASMDDRules, ASMActivation}, client.Capabilities)
+	require.Len(t, client.callbacks[rc.ProductASMFeatures], 2)
+
+	err = client.applyUpdate(genMultiUpdateResponse(map[string][]byte{
+		asmPath:         []byte("rules"),
+		asmFeaturesPath: []byte("features"),
+	}))
+	require.NoError(t, err)
+	// each product is only provided with its own configurations
+	require.Equal(t, ProductUpdate{asmPath: []byte("rules")}, updates[rc.ProductASMDD])
+	require.Equal(t, ProductUpdate{asmFeaturesPath: []byte("features")}, updates[rc.ProductASMFeatures])
+}
+
+func TestRawProducts(t *testing.T) {
+	client, err := NewClient(DefaultClientConfig())
+	require.NoError(t, err)
+
+	const (
+		asmPath   = "datadog/2/ASM_DD/asm_rules/config"
+		probePath = "datadog/2/LIVE_DEBUGGING/logProbe_1/config"
+	)
+	var updates []ProductUpdate
+	client.Subscribe(ProductLiveDebugging, func(u ProductUpdate) map[string]rc.ApplyStatus {
+		updates = append(updates, u)
+		return map[string]rc.ApplyStatus{probePath: {State: rc.ApplyStateAcknowledged}}
+	})
+	client.Subscribe(rc.ProductASMDD, func(u ProductUpdate) map[string]rc.ApplyStatus { return nil })
+
+	// the configurations unknown to the repository don't fail the update
+	err = client.applyUpdate(genMultiUpdateResponse(map[string][]byte{
+		asmPath:   []byte("rules"),
+		probePath: []byte("probe"),
+	}))
+	require.NoError(t, err)
+	require.Equal(t, []ProductUpdate{{probePath: []byte("probe")}}, updates)
+
+	// and are reported as cached
+	b, err := client.newUpdateRequest()
+	require.NoError(t, err)
+	var req clientGetConfigsRequest
+	require.NoError(t, json.Unmarshal(b.Bytes(), &req))
+	var cached []string
+	for _, f := range req.CachedTargetFiles {
+		cached = append(cached, f.Path)
+	}
+	require.ElementsMatch(t, []string{asmPath, probePath}, cached)
+	var probeState *configState
+	for _, s := range req.Client.State.ConfigStates {
+		if s.Product == ProductLiveDebugging {
+			probeState = s
+		}
+	}
+	require.NotNil(t, probeState)
+	require.Equal(t, "logProbe_1", probeState.ID)
+	require.Equal(t, rc.ApplyStateAcknowledged, probeState.ApplyState)
+
+	// unchanged files are not provided again
+	resp := genMultiUpdateResponse(map[string][]byte{probePath: []byte("probe")})
+	require.NoError(t, client.applyUpdate(resp))
+	require.Len(t, updates, 1)
+
+	// removed files are provided as nil
+	require.NoError(t, client.applyUpdate(genMultiUpdateResponse(map[string][]byte{asmPath: []byte("rules")})))
+	require.Equal(t, ProductUpdate{probePath: nil}, updates[1])
+	require.Empty(t, client.rawFiles)
+}
+
+func TestSharedClient(t *testing.T) {
+	noop := func(ProductUpdate) map[string]rc.ApplyStatus { return nil }
+	require.Error(t, Subscribe(rc.ProductASMFeatures, noop))
+
+	client, err := Start(DefaultClientConfig())
+	require.NoError(t, err)
+	defer Stop()
+	again, err := Start(DefaultClientConfig())
+	require.NoError(t, err)
+	require.Same(t, client, again)
+
+	require.NoError(t, Subscribe(ProductAPMTracing, noop, APMTracingSampleRate))
+	require.NoError(t, Subscribe(rc.ProductASMFeatures, noop, ASMActivation))
+	client.mu.RLock()
+	require.Equal(t, []string{ProductAPMTracing, rc.ProductASMFeatures}, client.Products)
+	client.mu.RUnlock()
+
+	Stop()
+	require.Error(t, Subscribe(rc.ProductASMFeatures, noop))
+}
+
+func TestPollDelay(t *testing.T) {
+	client := &Client{ClientConfig: ClientConfig{PollInterval: time.Second}}
+	require.Equal(t, time.Second, client.pollDelay(0))
+	require.Equal(t, 2*time.Second, client.pollDelay(1))
+	require.Equal(t, 8*time.Second, client.pollDelay(3))
+	require.Equal(t, maxPollBackoff, client.pollDelay(100))
+
+	client.PollInterval = 2 * maxPollBackoff
+	require.Equal(t, 2*maxPollBackoff, client.pollDelay(3))
+}
+
+func TestUpdateStateError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+	cfg := DefaultClientConfig()
+	cfg.AgentAddr = strings.TrimPrefix(srv.URL, "http://")
+	client, err := NewClient(cfg)
+	require.NoError(t, err)
+
+	err = client.updateState()
+	require.Error(t, err)
+	require.Equal(t, err, client.lastError)
+}
+
+func TestProductFromPath(t *testing.T) {
+	for path, product := range map[string]string{
+		"datadog/2/ASM_FEATURES/asm_features_activation/config": rc.ProductASMFeatures,
+		"datadog/2/ASM/asm_rules/config":                        ProductASM,
+		"employee/ASM_DD/rules/config":                          rc.ProductASMDD,
+		"invalid":                                               "",
+		"datadog/2":                                             "",
+	} {
+		require.Equal(t, product, productFromPath(path), path)
+	}
+}
+
+func TestConfigIDFromPath(t *testing.T) {
+	for path, id := range map[string]string{
+		"datadog/2/ASM_FEATURES/asm_features_activation/config": "asm_features_activation",
+		"employee/ASM_DD/rules/config":                          "rules",
+		"datadog/2/ASM":                                         "",
+	} {
+		require.Equal(t, id, configIDFromPath(path), path)
+	}
+}
+
 func TestPayloads(t *testing.T) {
 	t.Run("getConfigResponse", func(t *testing.T) {
 
@@ -166,14 +324,27 @@ func TestPayloads(t *testing.T) {
 }
 
 func genUpdateResponse(payload []byte, cfgPath string) *clientGetConfigsResponse {
-	var targets string
-	targetsFmt := `{"signed":{"_type":"targets","custom":{"agent_refresh_interval":0,"opaque_backend_state":"test"},"expires":"2023-01-12T08:46:28Z","spec_version":"1.0.0","targets":{"%s":{"custom":{"c":["HX4ZhCZRs74V1_XaalnCY"],"tracer-predicates":{"tracer_predicates_v1":[{"clientID":"HX4ZhCZRs74V1_XaalnCY"}]},"v":87},"hashes":{"sha256":"%x"},"length":%d}},"version":33431626}}`
-	sum := sha256.Sum256(payload)
-	targets = fmt.Sprintf(targetsFmt, cfgPath, sum, len(payload))
+	return genMultiUpdateResponse(map[string][]byte{cfgPath: payload})
+}
+
+// genMultiUpdateResponse returns an update response holding the given files, indexed by path.
+func genMultiUpdateResponse(files map[string][]byte) *clientGetConfigsResponse {
+	const targetFmt = `"%s":{"custom":{"c":["HX4ZhCZRs74V1_XaalnCY"],"tracer-predicates":{"tracer_predicates_v1":[{"clientID":"HX4ZhCZRs74V1_XaalnCY"}]},"v":87},"hashes":{"sha256":"%x"},"length":%d}`
+	var (
+		targets     []string
+		targetFiles []*file
+		configs     []string
+	)
+	for path, payload := range files {
+		targets = append(targets, fmt.Sprintf(targetFmt, path, sha256.Sum256(payload), len(payload)))
+		targetFiles = append(targetFiles, &file{Path: path, Raw: payload})
+		configs = append(configs, path)
+	}
+	signed := fmt.Sprintf(`{"signed":{"_type":"targets","custom":{"agent_refresh_interval":0,"opaque_backend_state":"test"},"expires":"2023-01-12T08:46:28Z","spec_version":"1.0.0","targets":{%s},"version":33431626}}`, strings.Join(targets, ","))
 
 	return &clientGetConfigsResponse{
-		Targets:       []byte(targets),
-		TargetFiles:   []*file{{Path: cfgPath, Raw: payload}},
-		ClientConfigs: []string{cfgPath},
+		Targets:       []byte(signed),
+		TargetFiles:   targetFiles,
+		ClientConfigs: configs,
 	}
 }
@@ -81,3 +81,24 @@ type fileMetaState struct {
 	Version uint64 `json:"version,omitempty"`
 	Hash    string `json:"hash,omitempty"`
 }
+
+// ASMDataRulesData is the content of the configurations of the ASM_DATA remote
+// config product.
+type ASMDataRulesData struct {
+	RulesData []ASMDataRuleData `json:"rules_data"`
+}
+
+// ASMDataRuleData is a rule data entry of an ASM_DATA configuration, such as
+// the IP denylist.
+type ASMDataRuleData struct {
+	ID   string                 `json:"id"`
+	Type string                 `json:"type"`
+	Data []ASMDataRuleDataEntry `json:"data"`
+}
+
+// ASMDataRuleDataEntry is a value of a rule data entry, which expires at the
+// given Unix timestamp in seconds, 0 meaning that it never expires.
+type ASMDataRuleDataEntry struct {
+	Expiration int64  `json:"expiration,omitempty"`
+	Value      string `json:"value"`
+}
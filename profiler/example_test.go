@@ -7,6 +7,8 @@ package profiler_test
 
 import (
 	"log"
+	"net/http"
+	"time"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/profiler"
 )
@@ -25,3 +27,22 @@ func Example() {
 
 	// ...
 }
+
+// This example illustrates how to collect profiles on demand, from an admin
+// endpoint, with a CPU profile shorter than the profiling period.
+func ExampleTriggerProfile() {
+	err := profiler.Start(
+		profiler.WithService("users-db"),
+		profiler.CPUDuration(5*time.Second),
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer profiler.Stop()
+
+	http.HandleFunc("/admin/profile", func(w http.ResponseWriter, r *http.Request) {
+		if err := profiler.TriggerProfile(r.Context()); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
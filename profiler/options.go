@@ -109,6 +109,10 @@ type config struct {
 	logStartup        bool
 	cmemprofEnabled   bool
 	cmemprofRate      int
+
+	// contentionOverheadLimit is the share of the profiling period, in percent, which
+	// collecting the mutex or block profile may take before it is disabled.
+	contentionOverheadLimit float64
 }
 
 // logStartup records the configuration to the configured logger in JSON format
@@ -133,6 +137,7 @@ func logStartup(c *config) {
 		CPUProfileRate       int      `json:"cpu_profile_rate"`
 		BlockProfileRate     int      `json:"block_profile_rate"`
 		MutexProfileFraction int      `json:"mutex_profile_fraction"`
+		ContentionOverhead   float64  `json:"contention_overhead_limit"`
 		MaxGoroutinesWait    int      `json:"max_goroutines_wait"`
 		UploadTimeout        string   `json:"upload_timeout"`
 	}{
@@ -154,6 +159,7 @@ func logStartup(c *config) {
 		CPUProfileRate:       c.cpuProfileRate,
 		BlockProfileRate:     c.blockRate,
 		MutexProfileFraction: c.mutexFraction,
+		ContentionOverhead:   c.contentionOverheadLimit,
 		MaxGoroutinesWait:    c.maxGoroutinesWait,
 		UploadTimeout:        c.uploadTimeout.String(),
 	}
@@ -398,6 +404,36 @@ func BlockProfileRate(rate int) Option {
 	}
 }
 
+// WithMutexProfileFraction is equivalent to MutexProfileFraction: it turns on
+// mutex profiles, reporting 1/rate mutex contention events on average. The
+// fraction is only set while the profiler is running: the previous fraction is
+// restored when it stops.
+func WithMutexProfileFraction(rate int) Option {
+	return MutexProfileFraction(rate)
+}
+
+// WithBlockProfileRate is equivalent to BlockProfileRate: it turns on block
+// profiles with the given rate, in nanoseconds. The rate is only set while the
+// profiler is running. As the runtime does not expose the previous rate, block
+// profiling is disabled when the profiler stops.
+func WithBlockProfileRate(rate int) Option {
+	return BlockProfileRate(rate)
+}
+
+// WithContentionOverheadLimit sets the share of the profiling period, in
+// percent, which collecting the mutex or block profile may take. A profile
+// exceeding it is disabled until the profiler is restarted, and its runtime
+// rate is reset as when the profiler stops. The time spent collecting these
+// profiles grows with the number of events they sampled, making it an estimate
+// of the overhead of their rate. As this estimate is only meaningful for
+// periods long enough to amortize the collection, the guardrail is disabled by
+// default, and a limit of 0 or less disables it.
+func WithContentionOverheadLimit(percent float64) Option {
+	return func(cfg *config) {
+		cfg.contentionOverheadLimit = percent
+	}
+}
+
 // WithProfileTypes specifies the profile types to be collected by the profiler.
 func WithProfileTypes(types ...ProfileType) Option {
 	return func(cfg *config) {
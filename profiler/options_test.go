@@ -121,6 +121,28 @@ func TestOptions(t *testing.T) {
 		assert.Contains(t, cfg.types, BlockProfile)
 	})
 
+	t.Run("WithMutexProfileFraction", func(t *testing.T) {
+		var cfg config
+		WithMutexProfileFraction(5)(&cfg)
+		assert.Equal(t, 5, cfg.mutexFraction)
+		assert.Contains(t, cfg.types, MutexProfile)
+	})
+
+	t.Run("WithBlockProfileRate", func(t *testing.T) {
+		var cfg config
+		WithBlockProfileRate(5)(&cfg)
+		assert.Equal(t, 5, cfg.blockRate)
+		assert.Contains(t, cfg.types, BlockProfile)
+	})
+
+	t.Run("WithContentionOverheadLimit", func(t *testing.T) {
+		cfg, err := defaultConfig()
+		require.NoError(t, err)
+		assert.Zero(t, cfg.contentionOverheadLimit)
+		WithContentionOverheadLimit(5)(cfg)
+		assert.Equal(t, 5.0, cfg.contentionOverheadLimit)
+	})
+
 	t.Run("WithProfileTypes", func(t *testing.T) {
 		var cfg config
 		WithProfileTypes(HeapProfile)(&cfg)
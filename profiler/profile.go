@@ -13,6 +13,7 @@ import (
 	"runtime"
 	"time"
 
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
 	"gopkg.in/DataDog/dd-trace-go.v1/profiler/internal/pprofutils"
 
 	"github.com/DataDog/gostackparse"
@@ -37,7 +38,10 @@ const (
 	// MutexProfile reports the lock contentions. When you think your CPU is not fully utilized due
 	// to a mutex contention, use this profile. Mutex profile is not enabled by default.
 	MutexProfile
-	// GoroutineProfile reports stack traces of all current goroutines
+	// GoroutineProfile reports stack traces of all current goroutines, along
+	// with their profiler labels. It is complemented by a summary of the
+	// goroutines aggregated by creation site and state, which helps to track
+	// down goroutine leaks. The goroutine profile is not enabled by default.
 	GoroutineProfile
 	// expGoroutineWaitProfile reports stack traces and wait durations for
 	// goroutines that have been waiting or blocked by a syscall for > 1 minute
@@ -70,6 +74,11 @@ type profileType struct {
 	// when delta profiling is enabled. Empty DeltaValues means delta profiling is
 	// not supported for this profile type
 	DeltaValues []pprofutils.ValueType
+	// Summarize, if set, collects a summary of the profile after Collect,
+	// uploaded along with it as SummaryFilename. Failing to collect the
+	// summary does not fail the collection of the profile.
+	Summarize       func(p *profiler) ([]byte, error)
+	SummaryFilename string
 }
 
 // profileTypes maps every ProfileType to its implementation.
@@ -81,7 +90,17 @@ var profileTypes = map[ProfileType]profileType{
 			// Start the CPU profiler at the end of the profiling
 			// period so that we're sure to capture the CPU usage of
 			// this library, which mostly happens at the end
-			p.interruptibleSleep(p.cfg.period - p.cfg.cpuDuration)
+			c := p.currentCycle()
+			p.cycleSleep(p.cfg.period - p.cfg.cpuDuration)
+			// When the cycle was ended early before the CPU profiler
+			// started, the profile is collected for its whole duration
+			// anyway, otherwise it is stopped as soon as the cycle ends.
+			sleep := p.cycleSleep
+			select {
+			case <-c.interrupt:
+				sleep = p.interruptibleSleep
+			default:
+			}
 			if p.cfg.cpuProfileRate != 0 {
 				// The profile has to be set each time before
 				// profiling is started. Otherwise,
@@ -92,7 +111,7 @@ var profileTypes = map[ProfileType]profileType{
 			if err := p.startCPUProfile(&buf); err != nil {
 				return nil, err
 			}
-			p.interruptibleSleep(p.cfg.cpuDuration)
+			sleep(p.cfg.cpuDuration)
 			// We want the CPU profiler to finish last so that it can
 			// properly record all of our profile processing work for
 			// the other profile types
@@ -137,6 +156,23 @@ var profileTypes = map[ProfileType]profileType{
 		Name:     "goroutine",
 		Filename: "goroutines.pprof",
 		Collect:  collectGenericProfile("goroutine", GoroutineProfile),
+		Summarize: func(p *profiler) ([]byte, error) {
+			// the debug=2 goroutine profile stops the world for as long as the
+			// goroutines wait profile does, so it is subject to the same limit
+			if n := runtime.NumGoroutine(); n > p.cfg.maxGoroutinesWait {
+				return nil, fmt.Errorf("skipping goroutines creation site summary: %d goroutines exceeds DD_PROFILING_WAIT_PROFILE_MAX_GOROUTINES limit of %d", n, p.cfg.maxGoroutinesWait)
+			}
+			var (
+				text  = &bytes.Buffer{}
+				pprof = &bytes.Buffer{}
+			)
+			if err := p.lookupProfile("goroutine", text, 2); err != nil {
+				return nil, err
+			}
+			err := goroutineCreationSitesToPprof(text, pprof, now())
+			return pprof.Bytes(), err
+		},
+		SummaryFilename: "goroutinescreatedby.pprof",
 	},
 	expGoroutineWaitProfile: {
 		Name:     "goroutinewait",
@@ -146,7 +182,7 @@ var profileTypes = map[ProfileType]profileType{
 				return nil, fmt.Errorf("skipping goroutines wait profile: %d goroutines exceeds DD_PROFILING_WAIT_PROFILE_MAX_GOROUTINES limit of %d", n, p.cfg.maxGoroutinesWait)
 			}
 
-			p.interruptibleSleep(p.cfg.period)
+			p.cycleSleep(p.cfg.period)
 
 			var (
 				now   = now()
@@ -165,7 +201,7 @@ var profileTypes = map[ProfileType]profileType{
 		Filename: "metrics.json",
 		Collect: func(p *profiler) ([]byte, error) {
 			var buf bytes.Buffer
-			p.interruptibleSleep(p.cfg.period)
+			p.cycleSleep(p.cfg.period)
 			err := p.met.report(now(), &buf)
 			return buf.Bytes(), err
 		},
@@ -174,13 +210,15 @@ var profileTypes = map[ProfileType]profileType{
 
 func collectGenericProfile(name string, pt ProfileType) func(p *profiler) ([]byte, error) {
 	return func(p *profiler) ([]byte, error) {
-		p.interruptibleSleep(p.cfg.period)
+		p.cycleSleep(p.cfg.period)
 
 		var buf bytes.Buffer
+		lookupStart := time.Now()
 		err := p.lookupProfile(name, &buf, 0)
 		data := buf.Bytes()
 		dp, ok := p.deltas[pt]
 		if !ok || !p.cfg.deltaProfiles {
+			p.checkContentionOverhead(pt, time.Since(lookupStart))
 			return data, err
 		}
 
@@ -188,6 +226,7 @@ func collectGenericProfile(name string, pt ProfileType) func(p *profiler) ([]byt
 		delta, err := dp.Delta(data)
 		tags := append(p.cfg.tags.Slice(), fmt.Sprintf("profile_type:%s", name))
 		p.cfg.statsd.Timing("datadog.profiling.go.delta_time", time.Since(start), tags, 1)
+		p.checkContentionOverhead(pt, time.Since(lookupStart))
 		if err != nil {
 			return nil, fmt.Errorf("delta profile error: %s", err)
 		}
@@ -262,7 +301,16 @@ func (p *profiler) runProfile(pt ProfileType) ([]*profile, error) {
 		filename = "delta-" + filename
 	}
 	p.cfg.statsd.Timing("datadog.profiling.go.collect_time", end.Sub(start), tags, 1)
-	return []*profile{{name: filename, data: data}}, nil
+	profs := []*profile{{name: filename, data: data}}
+	if t.Summarize != nil {
+		summary, err := t.Summarize(p)
+		if err != nil {
+			log.Error("Error summarizing %s profile: %v; skipping.", pt, err)
+		} else {
+			profs = append(profs, &profile{name: t.SummaryFilename, data: summary})
+		}
+	}
+	return profs, nil
 }
 
 type deltaProfiler struct {
@@ -420,3 +468,94 @@ func goroutineDebug2ToPprof(r io.Reader, w io.Writer, t time.Time) (err error) {
 func now() time.Time {
 	return time.Now().UTC()
 }
+
+// goroutineCreationSitesToPprof converts the debug=2 goroutine profile read
+// from r into a pprof profile counting the goroutines by creation site and
+// state, written to w. The stack of each sample holds the single frame which
+// created its goroutines, so that the profile reads as a table of the sites
+// sorted by the number of goroutines they created, e.g. to spot leaks.
+func goroutineCreationSitesToPprof(r io.Reader, w io.Writer, t time.Time) (err error) {
+	// see goroutineDebug2ToPprof
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	goroutines, errs := gostackparse.Parse(r)
+
+	type site struct {
+		fn, file string
+		line     int
+		state    string
+	}
+	p := &pprofile.Profile{
+		TimeNanos: t.UnixNano(),
+	}
+	m := &pprofile.Mapping{ID: 1, HasFunctions: true}
+	p.Mapping = []*pprofile.Mapping{m}
+	p.SampleType = []*pprofile.ValueType{
+		{
+			Type: "goroutines",
+			Unit: "count",
+		},
+	}
+	functions := make(map[site]*pprofile.Function)
+	locations := make(map[site]*pprofile.Location)
+	samples := make(map[site]*pprofile.Sample)
+	for _, g := range goroutines {
+		// goroutines not created by other goroutines, such as the main
+		// goroutine, are grouped under a virtual frame
+		s := site{fn: "...no creation site...", state: g.State}
+		if g.CreatedBy != nil {
+			s.fn, s.file, s.line = g.CreatedBy.Func, g.CreatedBy.File, g.CreatedBy.Line
+		}
+		if sample, ok := samples[s]; ok {
+			sample.Value[0]++
+			continue
+		}
+		loc := site{fn: s.fn, file: s.file, line: s.line}
+		location, ok := locations[loc]
+		if !ok {
+			fn := site{fn: s.fn, file: s.file}
+			function, ok := functions[fn]
+			if !ok {
+				function = &pprofile.Function{
+					ID:       uint64(len(p.Function) + 1),
+					Name:     s.fn,
+					Filename: s.file,
+				}
+				functions[fn] = function
+				p.Function = append(p.Function, function)
+			}
+			location = &pprofile.Location{
+				ID:      uint64(len(p.Location) + 1),
+				Mapping: m,
+				Line: []pprofile.Line{{
+					Function: function,
+					Line:     int64(s.line),
+				}},
+			}
+			locations[loc] = location
+			p.Location = append(p.Location, location)
+		}
+		sample := &pprofile.Sample{
+			Value:    []int64{1},
+			Location: []*pprofile.Location{location},
+			Label:    map[string][]string{"state": {g.State}},
+		}
+		samples[s] = sample
+		p.Sample = append(p.Sample, sample)
+	}
+
+	for _, err := range errs {
+		p.Comments = append(p.Comments, "error: "+err.Error())
+	}
+
+	if err := p.CheckValid(); err != nil {
+		return fmt.Errorf("marshalGoroutineCreationSites: %s", err)
+	} else if err := p.Write(w); err != nil {
+		return fmt.Errorf("marshalGoroutineCreationSites: %s", err)
+	}
+	return nil
+}
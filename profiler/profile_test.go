@@ -188,16 +188,81 @@ main;bar 0 0 8 16
 	})
 
 	t.Run("goroutine", func(t *testing.T) {
+		const sample = `
+goroutine 1 [running]:
+main.main()
+	/example/main.go:152 +0x3d2
+
+goroutine 2 [chan receive]:
+main.worker()
+	/example/main.go:20 +0x25
+created by main.startWorkers
+	/example/main.go:12 +0x35
+
+goroutine 3 [chan receive]:
+main.worker()
+	/example/main.go:20 +0x25
+created by main.startWorkers
+	/example/main.go:12 +0x35
+
+goroutine 4 [running]:
+main.worker()
+	/example/main.go:21 +0x2a
+created by main.startWorkers
+	/example/main.go:12 +0x35
+`
+
 		p, err := unstartedProfiler(WithPeriod(time.Millisecond))
-		p.testHooks.lookupProfile = func(name string, w io.Writer, _ int) error {
+		p.testHooks.lookupProfile = func(name string, w io.Writer, debug int) error {
+			if debug == 2 {
+				_, err := w.Write([]byte(sample))
+				return err
+			}
 			_, err := w.Write([]byte(name))
 			return err
 		}
 		require.NoError(t, err)
 		profs, err := p.runProfile(GoroutineProfile)
 		require.NoError(t, err)
+		require.Len(t, profs, 2)
 		assert.Equal(t, "goroutines.pprof", profs[0].name)
 		assert.Equal(t, []byte("goroutine"), profs[0].data)
+		assert.Equal(t, "goroutinescreatedby.pprof", profs[1].name)
+
+		pp, err := pprofile.Parse(bytes.NewReader(profs[1].data))
+		require.NoError(t, err)
+		require.Equal(t, []*pprofile.ValueType{{Type: "goroutines", Unit: "count"}}, pp.SampleType)
+		// the goroutines are aggregated by creation site and state
+		require.Len(t, pp.Sample, 3)
+		counts := make(map[string]int64)
+		for _, s := range pp.Sample {
+			require.Len(t, s.Location, 1)
+			line := s.Location[0].Line[0]
+			key := fmt.Sprintf("%s:%d %s", line.Function.Name, line.Line, s.Label["state"][0])
+			counts[key] = s.Value[0]
+		}
+		assert.Equal(t, map[string]int64{
+			"...no creation site...:0 running":  1,
+			"main.startWorkers:12 chan receive": 2,
+			"main.startWorkers:12 running":      1,
+		}, counts)
+		// the creation sites share their locations
+		assert.Len(t, pp.Location, 2)
+	})
+
+	t.Run("goroutine-summary-limit", func(t *testing.T) {
+		t.Setenv("DD_PROFILING_WAIT_PROFILE_MAX_GOROUTINES", "0")
+		p, err := unstartedProfiler(WithPeriod(time.Millisecond))
+		p.testHooks.lookupProfile = func(name string, w io.Writer, _ int) error {
+			_, err := w.Write([]byte(name))
+			return err
+		}
+		require.NoError(t, err)
+		// the goroutine profile is collected without its summary
+		profs, err := p.runProfile(GoroutineProfile)
+		require.NoError(t, err)
+		require.Len(t, profs, 1)
+		assert.Equal(t, "goroutines.pprof", profs[0].name)
 	})
 
 	t.Run("goroutinewait", func(t *testing.T) {
@@ -325,6 +390,12 @@ func Test_goroutineDebug2ToPprof_CrashSafety(t *testing.T) {
 	require.Equal(t, "panic: 42", err.Error())
 }
 
+func Test_goroutineCreationSitesToPprof_CrashSafety(t *testing.T) {
+	err := goroutineCreationSitesToPprof(panicReader{}, io.Discard, time.Time{})
+	require.NotNil(t, err)
+	require.Equal(t, "panic: 42", err.Error())
+}
+
 // panicReader is used to create a panic inside of stackparse.Parse()
 type panicReader struct{}
 
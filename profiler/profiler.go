@@ -6,6 +6,7 @@
 package profiler
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -59,6 +60,26 @@ func Stop() {
 	mu.Unlock()
 }
 
+// TriggerProfile ends the current profiling cycle of the running profiler
+// early, so that profiles are collected and uploaded right away instead of at
+// the end of the profiling period, e.g. from a signal handler or an admin
+// endpoint during an incident. A CPU profile which is running is stopped right
+// away, and one which has not started yet, when its duration set with
+// CPUDuration is shorter than the period, is collected right away for this
+// duration. The next profiling cycle starts at the end of the period, as usual.
+//
+// TriggerProfile returns once the profiles are queued for upload, or with an
+// error when ctx is done first, or when the profiler is not running.
+func TriggerProfile(ctx context.Context) error {
+	mu.Lock()
+	p := activeProfiler
+	mu.Unlock()
+	if p == nil {
+		return errors.New("profiler: not running")
+	}
+	return p.trigger(ctx)
+}
+
 // profiler collects and sends preset profiles to the Datadog API at a given frequency
 // using a given configuration.
 type profiler struct {
@@ -74,6 +95,16 @@ type profiler struct {
 	seq             uint64         // seq is the value of the profile_seq tag
 	pendingProfiles sync.WaitGroup // signal that profile collection is done, for stopping CPU profiling
 
+	cycleMu sync.Mutex // guards cycle
+	cycle   *cycle     // the current profiling cycle
+
+	// prevMutexFraction is the mutex profile fraction in effect before the profiler
+	// started, restored when it stops.
+	prevMutexFraction int
+
+	disabledMu sync.Mutex               // guards disabled
+	disabled   map[ProfileType]struct{} // profile types disabled at runtime because of their overhead
+
 	testHooks testHooks
 }
 
@@ -184,6 +215,7 @@ func newProfiler(opts ...Option) (*profiler, error) {
 		exit:   make(chan struct{}),
 		met:    newMetrics(),
 		deltas: make(map[ProfileType]*deltaProfiler),
+		cycle:  newCycle(),
 	}
 	for pt := range cfg.types {
 		if d := profileTypes[pt].DeltaValues; len(d) > 0 {
@@ -252,6 +284,7 @@ func (p *profiler) run() {
 			{Name: "cpu_profile_rate", Value: p.cfg.cpuProfileRate},
 			{Name: "block_profile_rate", Value: p.cfg.blockRate},
 			{Name: "mutex_profile_fraction", Value: p.cfg.mutexFraction},
+			{Name: "contention_overhead_limit", Value: p.cfg.contentionOverheadLimit},
 			{Name: "max_goroutines_wait", Value: p.cfg.maxGoroutinesWait},
 			{Name: "cpu_profile_enabled", Value: profileEnabled(CPUProfile)},
 			{Name: "heap_profile_enabled", Value: profileEnabled(HeapProfile)},
@@ -264,7 +297,7 @@ func (p *profiler) run() {
 	)
 
 	if profileEnabled(MutexProfile) {
-		runtime.SetMutexProfileFraction(p.cfg.mutexFraction)
+		p.prevMutexFraction = runtime.SetMutexProfileFraction(p.cfg.mutexFraction)
 	}
 	if profileEnabled(BlockProfile) {
 		runtime.SetBlockProfileRate(p.cfg.blockRate)
@@ -295,6 +328,7 @@ func (p *profiler) collect(ticker <-chan time.Time) {
 		wg        sync.WaitGroup
 	)
 	for {
+		cur := p.currentCycle()
 		now := now()
 		bat := batch{
 			seq:   p.seq,
@@ -343,14 +377,63 @@ func (p *profiler) collect(ticker <-chan time.Time) {
 			bat.addProfile(prof)
 		}
 		p.enqueueUpload(bat)
+		next := p.nextCycle()
+		close(cur.done)
 		select {
 		case <-ticker:
+		case <-next.interrupt:
+			// TriggerProfile was called in between two cycles, start the
+			// next one right away so that it is cut short.
 		case <-p.exit:
 			return
 		}
 	}
 }
 
+// cycle is a profiling cycle, collecting one batch of profiles.
+type cycle struct {
+	interrupt     chan struct{} // interrupt is closed to end the cycle early
+	interruptOnce sync.Once     // interruptOnce ensures interrupt is closed exactly once
+	done          chan struct{} // done is closed once the batch is queued for upload
+}
+
+func newCycle() *cycle {
+	return &cycle{
+		interrupt: make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+// currentCycle returns the current profiling cycle.
+func (p *profiler) currentCycle() *cycle {
+	p.cycleMu.Lock()
+	defer p.cycleMu.Unlock()
+	return p.cycle
+}
+
+// nextCycle replaces the current profiling cycle with a new one, and returns it.
+func (p *profiler) nextCycle() *cycle {
+	p.cycleMu.Lock()
+	defer p.cycleMu.Unlock()
+	p.cycle = newCycle()
+	return p.cycle
+}
+
+// trigger ends the current profiling cycle early and waits until its batch of
+// profiles is queued for upload. See TriggerProfile.
+func (p *profiler) trigger(ctx context.Context) error {
+	c := p.currentCycle()
+	c.interruptOnce.Do(func() { close(c.interrupt) })
+	select {
+	case <-c.done:
+		return nil
+	case <-p.exit:
+		return errors.New("profiler: stopped")
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // enabledProfileTypes returns the enabled profile types in a deterministic
 // order. The CPU profile always comes first because people might spot
 // interesting events in there and then try to look for the counter-part event
@@ -366,15 +449,60 @@ func (p *profiler) enabledProfileTypes() []ProfileType {
 		expGoroutineWaitProfile,
 		MetricsProfile,
 	}
+	p.disabledMu.Lock()
+	defer p.disabledMu.Unlock()
 	enabled := []ProfileType{}
 	for _, t := range order {
-		if _, ok := p.cfg.types[t]; ok {
-			enabled = append(enabled, t)
+		if _, ok := p.cfg.types[t]; !ok {
+			continue
+		}
+		if _, ok := p.disabled[t]; ok {
+			continue
 		}
+		enabled = append(enabled, t)
 	}
 	return enabled
 }
 
+// resetContentionRate restores the runtime rate of the mutex or block profile
+// to its value before the profiler started. As the runtime does not expose the
+// block profile rate, it is reset to 0, disabling block profiling.
+func (p *profiler) resetContentionRate(t ProfileType) {
+	switch t {
+	case MutexProfile:
+		runtime.SetMutexProfileFraction(p.prevMutexFraction)
+	case BlockProfile:
+		runtime.SetBlockProfileRate(0)
+	}
+}
+
+// checkContentionOverhead disables the mutex or block profile t when the time
+// spent collecting it, as a share of the profiling period, exceeds the limit
+// set with WithContentionOverheadLimit. The time spent collecting these
+// profiles grows with the number of contention events they sample, so it is
+// used as an estimate of the overhead of their sampling rate. Other profile
+// types are never disabled.
+func (p *profiler) checkContentionOverhead(t ProfileType, collectTime time.Duration) {
+	limit := p.cfg.contentionOverheadLimit
+	if limit <= 0 || (t != MutexProfile && t != BlockProfile) {
+		return
+	}
+	overhead := 100 * float64(collectTime) / float64(p.cfg.period)
+	if overhead <= limit {
+		return
+	}
+	p.disabledMu.Lock()
+	if p.disabled == nil {
+		p.disabled = make(map[ProfileType]struct{})
+	}
+	p.disabled[t] = struct{}{}
+	p.disabledMu.Unlock()
+	p.resetContentionRate(t)
+	log.Warn("Profiler: disabling the %s profile, its overhead (%.2f%%) exceeds the limit (%.2f%%)", t, overhead, limit)
+	tags := append(p.cfg.tags.Slice(), t.Tag())
+	p.cfg.statsd.Count("datadog.profiling.go.profile_disabled", 1, tags, 1)
+}
+
 // enqueueUpload pushes a batch of profiles onto the queue to be uploaded. If there is no room, it will
 // evict the oldest profile to make some. Typically a batch would be one of each enabled profile.
 func (p *profiler) enqueueUpload(bat batch) {
@@ -445,6 +573,16 @@ func (p *profiler) interruptibleSleep(d time.Duration) {
 	}
 }
 
+// cycleSleep sleeps for the given duration or until interrupted by the p.exit
+// channel being closed, or by the current profiling cycle being ended early.
+func (p *profiler) cycleSleep(d time.Duration) {
+	select {
+	case <-p.exit:
+	case <-p.currentCycle().interrupt:
+	case <-time.After(d):
+	}
+}
+
 // stop stops the profiler.
 func (p *profiler) stop() {
 	p.stopOnce.Do(func() {
@@ -452,6 +590,11 @@ func (p *profiler) stop() {
 		p.telemetry.Stop()
 	})
 	p.wg.Wait()
+	for _, t := range []ProfileType{MutexProfile, BlockProfile} {
+		if _, ok := p.cfg.types[t]; ok {
+			p.resetContentionRate(t)
+		}
+	}
 	if p.cfg.logStartup {
 		log.Info("Profiling stopped")
 	}
@@ -6,6 +6,7 @@
 package profiler
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -233,8 +234,20 @@ func TestSetProfileFraction(t *testing.T) {
 		p, err := unstartedProfiler(WithProfileTypes(MutexProfile))
 		require.NoError(t, err)
 		p.run()
-		p.stop()
 		assert.Equal(t, DefaultMutexFraction, runtime.SetMutexProfileFraction(-1))
+		p.stop()
+		assert.Zero(t, runtime.SetMutexProfileFraction(-1))
+	})
+
+	t.Run("restore", func(t *testing.T) {
+		start := runtime.SetMutexProfileFraction(3)
+		defer runtime.SetMutexProfileFraction(start)
+		p, err := unstartedProfiler(WithMutexProfileFraction(7))
+		require.NoError(t, err)
+		p.run()
+		assert.Equal(t, 7, runtime.SetMutexProfileFraction(-1))
+		p.stop()
+		assert.Equal(t, 3, runtime.SetMutexProfileFraction(-1))
 	})
 
 	t.Run("off", func(t *testing.T) {
@@ -248,6 +261,31 @@ func TestSetProfileFraction(t *testing.T) {
 	})
 }
 
+func TestContentionOverheadLimit(t *testing.T) {
+	start := runtime.SetMutexProfileFraction(0)
+	defer runtime.SetMutexProfileFraction(start)
+	p, err := unstartedProfiler(
+		WithProfileTypes(HeapProfile, MutexProfile),
+		WithPeriod(time.Second),
+		WithContentionOverheadLimit(1),
+	)
+	require.NoError(t, err)
+	p.run()
+	defer p.stop()
+	require.Equal(t, DefaultMutexFraction, runtime.SetMutexProfileFraction(-1))
+
+	p.checkContentionOverhead(MutexProfile, 5*time.Millisecond)
+	assert.Contains(t, p.enabledProfileTypes(), MutexProfile)
+	assert.Equal(t, DefaultMutexFraction, runtime.SetMutexProfileFraction(-1))
+
+	p.checkContentionOverhead(HeapProfile, time.Second)
+	assert.Contains(t, p.enabledProfileTypes(), HeapProfile)
+
+	p.checkContentionOverhead(MutexProfile, 20*time.Millisecond)
+	assert.NotContains(t, p.enabledProfileTypes(), MutexProfile)
+	assert.Zero(t, runtime.SetMutexProfileFraction(-1))
+}
+
 func TestProfilerPassthrough(t *testing.T) {
 	if testing.Short() {
 		return
@@ -389,6 +427,7 @@ func TestAllUploaded(t *testing.T) {
 			"delta-heap.pprof",
 			"delta-mutex.pprof",
 			"goroutines.pprof",
+			"goroutinescreatedby.pprof",
 			"goroutineswait.pprof",
 		}
 		assert.ElementsMatch(t, expected, profile.event.Attachments)
@@ -521,3 +560,38 @@ func TestImmediateProfile(t *testing.T) {
 	case <-received:
 	}
 }
+
+func TestTriggerProfile(t *testing.T) {
+	require.Error(t, TriggerProfile(context.Background()))
+
+	received := make(chan profileMeta, 2)
+	server := httptest.NewServer(&mockBackend{t: t, profiles: received})
+	defer server.Close()
+
+	err := Start(
+		WithAgentAddr(server.Listener.Addr().String()),
+		WithProfileTypes(CPUProfile, HeapProfile),
+		WithPeriod(time.Hour),
+		CPUDuration(10*time.Millisecond),
+	)
+	require.NoError(t, err)
+	defer Stop()
+
+	for seq := 0; seq < 2; seq++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		require.NoError(t, TriggerProfile(ctx))
+		cancel()
+		select {
+		case profile := <-received:
+			assert.ElementsMatch(t, []string{"cpu.pprof", "delta-heap.pprof"}, profile.event.Attachments)
+			assert.Contains(t, profile.tags, fmt.Sprintf("profile_seq:%d", seq))
+		case <-time.After(5 * time.Second):
+			t.Fatal("the triggered profiles were not uploaded")
+		}
+	}
+
+	// the profiles of the cycle take at least the CPU duration to be collected
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, TriggerProfile(ctx))
+}